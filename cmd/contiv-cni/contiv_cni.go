@@ -18,11 +18,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"os"
 
+	"github.com/containernetworking/cni/pkg/invoke"
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
-	"github.com/containernetworking/cni/pkg/version"
 	"google.golang.org/grpc"
 
 	cnisb "github.com/containernetworking/cni/pkg/types/current"
@@ -36,13 +39,23 @@ type cniConfig struct {
 	types.NetConf
 
 	// PrevResult contains previous plugin's result, used only when called in the context of a chained plugin.
-	PrevResult *map[string]interface{} `json:"prevResult"`
+	PrevResult *json.RawMessage `json:"prevResult"`
 
 	// GrpcServer is a plugin-specific config, contains location of the gRPC server
 	// where the CNI requests are being forwarded to (server:port tuple, e.g. "localhost:9111").
 	GrpcServer string `json:"grpcServer"`
 }
 
+// checkCommand is the value of CNI_COMMAND for the CHECK command.
+const checkCommand = "CHECK"
+
+// delegateAdd and delegateDel execute the external IPAM plugin according to the CNI IPAM contract
+// (replaceable in tests).
+var (
+	delegateAdd = invoke.DelegateAdd
+	delegateDel = invoke.DelegateDel
+)
+
 // parseCNIConfig parses CNI config from JSON (in bytes) to cniConfig struct.
 func parseCNIConfig(bytes []byte) (*cniConfig, error) {
 	// unmarshal the config
@@ -51,11 +64,6 @@ func parseCNIConfig(bytes []byte) (*cniConfig, error) {
 		return nil, fmt.Errorf("failed to load plugin config: %v", err)
 	}
 
-	// CNI chaining is not supported by this plugin, print out an error in case it was chained
-	if conf.PrevResult != nil {
-		return nil, fmt.Errorf("CNI chaining is not supported by this plugin")
-	}
-
 	// grpcServer is mandatory
 	if conf.GrpcServer == "" {
 		return nil, fmt.Errorf(`"grpcServer" field is required. It specifies where the CNI requests should be forwarded to`)
@@ -90,16 +98,27 @@ func cmdAdd(args *skel.CmdArgs) error {
 	}
 	defer conn.Close()
 
+	// assign IP addresses by the external IPAM plugin (if configured)
+	ipamAddrs, err := ipamAdd(cfg, args.StdinData)
+	if err != nil {
+		return err
+	}
+
 	// execute the ADD request
 	r, err := c.Add(context.Background(), &cninb.CNIRequest{
-		Version:          cfg.CNIVersion,
-		ContainerId:      args.ContainerID,
-		InterfaceName:    args.IfName,
-		NetworkNamespace: args.Netns,
-		ExtraArguments:   args.Args,
-		ExtraNwConfig:    string(args.StdinData),
+		Version:               cfg.CNIVersion,
+		ContainerId:           args.ContainerID,
+		InterfaceName:         args.IfName,
+		NetworkNamespace:      args.Netns,
+		ExtraArguments:        args.Args,
+		ExtraNwConfig:         string(args.StdinData),
+		ExternalIpamAddresses: ipamAddrs,
 	})
 	if err != nil {
+		if cfg.IPAM.Type != "" {
+			// release the addresses assigned by the external IPAM plugin
+			delegateDel(cfg.IPAM.Type, args.StdinData)
+		}
 		return err
 	}
 
@@ -109,13 +128,19 @@ func cmdAdd(args *skel.CmdArgs) error {
 	}
 
 	// process interfaces
-	for ifidx, iface := range r.Interfaces {
+	mtus := map[*cnisb.Interface]uint32{}
+	for i, iface := range r.Interfaces {
+		ifidx := i
 		// append interface info
-		result.Interfaces = append(result.Interfaces, &cnisb.Interface{
+		cniIface := &cnisb.Interface{
 			Name:    iface.Name,
 			Mac:     iface.Mac,
 			Sandbox: iface.Sandbox,
-		})
+		}
+		result.Interfaces = append(result.Interfaces, cniIface)
+		if iface.Mtu != 0 {
+			mtus[cniIface] = iface.Mtu
+		}
 		for _, ip := range iface.IpAddresses {
 			// append interface ip address info
 			_, ipAddr, err := net.ParseCIDR(ip.Address)
@@ -166,7 +191,15 @@ func cmdAdd(args *skel.CmdArgs) error {
 		result.DNS.Options = dns.Options
 	}
 
-	return result.Print()
+	// include the result of the previous plugin in the chain
+	if cfg.PrevResult != nil {
+		result, err = mergePrevResult(*cfg.PrevResult, result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return printResult(result, mtus, cfg.CNIVersion)
 }
 
 // cmdDel implements the CNI request to delete a container from network.
@@ -198,11 +231,151 @@ func cmdDel(args *skel.CmdArgs) error {
 		return err
 	}
 
+	// release the IP addresses assigned by the external IPAM plugin (if configured)
+	if n.IPAM.Type != "" {
+		return delegateDel(n.IPAM.Type, args.StdinData)
+	}
 	return nil
 }
 
+// cmdCheck implements the CNI request to check that a container is still connected to network.
+// It forwards the request to the remote gRPC server and returns an error if the networking
+// of the container is not configured as expected.
+func cmdCheck(args *skel.CmdArgs) error {
+	// parse CNI config
+	n, err := parseCNIConfig(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	// CHECK was introduced in the spec version 0.4.0
+	supported, err := versionAtLeast(n.CNIVersion, "0.4.0")
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return fmt.Errorf("the %s command is not supported by CNI version %q", checkCommand, n.CNIVersion)
+	}
+
+	// connect to remote CNI handler over gRPC
+	conn, c, err := grpcConnect(n.GrpcServer)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// execute the CHECK request
+	_, err = c.Check(context.Background(), &cninb.CNIRequest{
+		Version:          n.CNIVersion,
+		ContainerId:      args.ContainerID,
+		InterfaceName:    args.IfName,
+		NetworkNamespace: args.Netns,
+		ExtraArguments:   args.Args,
+		ExtraNwConfig:    string(args.StdinData),
+	})
+	return err
+}
+
+// checkCmdArgs reads the arguments of the CHECK command from the environment variables and the standard input
+// as defined by the CNI specification (the vendored skel package supports only ADD and DEL).
+func checkCmdArgs(getenv func(string) string, stdin io.Reader) (*skel.CmdArgs, error) {
+	args := &skel.CmdArgs{
+		ContainerID: getenv("CNI_CONTAINERID"),
+		Netns:       getenv("CNI_NETNS"),
+		IfName:      getenv("CNI_IFNAME"),
+		Args:        getenv("CNI_ARGS"),
+		Path:        getenv("CNI_PATH"),
+	}
+	if args.ContainerID == "" || args.Netns == "" || args.IfName == "" {
+		return nil, fmt.Errorf("CNI_CONTAINERID, CNI_NETNS and CNI_IFNAME are required by the %s command", checkCommand)
+	}
+	stdinData, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return nil, fmt.Errorf("error reading from stdin: %v", err)
+	}
+	args.StdinData = stdinData
+	return args, nil
+}
+
+// mergePrevResult merges the result of the previous plugin in the chain with the result of this plugin.
+// The interfaces, IP addresses and routes of the previous plugin go first, DNS of this plugin
+// takes precedence if set.
+func mergePrevResult(prevResult []byte, result *cnisb.Result) (*cnisb.Result, error) {
+	prev := &cnisb.Result{}
+	if err := json.Unmarshal(prevResult, prev); err != nil {
+		return nil, fmt.Errorf("failed to parse prevResult: %v", err)
+	}
+
+	merged := &cnisb.Result{
+		CNIVersion: result.CNIVersion,
+		Interfaces: append(prev.Interfaces, result.Interfaces...),
+		IPs:        prev.IPs,
+		Routes:     append(prev.Routes, result.Routes...),
+		DNS:        prev.DNS,
+	}
+	for _, ip := range result.IPs {
+		// interface indexes are shifted behind the interfaces of the previous result
+		if ip.Interface != nil {
+			ifidx := *ip.Interface + len(prev.Interfaces)
+			ip.Interface = &ifidx
+		}
+		merged.IPs = append(merged.IPs, ip)
+	}
+	if len(result.DNS.Nameservers) > 0 || result.DNS.Domain != "" {
+		merged.DNS = result.DNS
+	}
+	return merged, nil
+}
+
+// ipamAdd executes the external IPAM plugin configured in the ipam section of the CNI config
+// and returns the assigned IP addresses. Nil is returned if no IPAM plugin is configured,
+// the addresses are then assigned by the remote CNI handler.
+func ipamAdd(cfg *cniConfig, stdinData []byte) ([]*cninb.CNIReply_Interface_IP, error) {
+	if cfg.IPAM.Type == "" {
+		return nil, nil
+	}
+	r, err := delegateAdd(cfg.IPAM.Type, stdinData)
+	if err != nil {
+		return nil, fmt.Errorf("IPAM plugin %s failed: %v", cfg.IPAM.Type, err)
+	}
+	result, err := cnisb.NewResultFromResult(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []*cninb.CNIReply_Interface_IP
+	for _, ip := range result.IPs {
+		addr := &cninb.CNIReply_Interface_IP{
+			Version: cninb.CNIReply_Interface_IP_IPV4,
+			Address: ip.Address.String(),
+		}
+		if ip.Version == "6" {
+			addr.Version = cninb.CNIReply_Interface_IP_IPV6
+		}
+		if ip.Gateway != nil {
+			addr.Gateway = ip.Gateway.String()
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
 // main routine of the CNI plugin
 func main() {
+	// CHECK is dispatched here, the other commands are handled by skel
+	if os.Getenv("CNI_COMMAND") == checkCommand {
+		args, err := checkCmdArgs(os.Getenv, os.Stdin)
+		if err == nil {
+			err = cmdCheck(args)
+		}
+		if err != nil {
+			e := &types.Error{Code: types.ErrUnknown, Msg: err.Error()}
+			e.Print()
+			os.Exit(1)
+		}
+		return
+	}
+
 	// execute the CNI plugin logic
-	skel.PluginMain(cmdAdd, cmdDel, version.All)
+	skel.PluginMain(cmdAdd, cmdDel, supportedVersions)
 }
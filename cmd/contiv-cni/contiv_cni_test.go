@@ -15,12 +15,19 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	cnisb "github.com/containernetworking/cni/pkg/types/current"
 	"github.com/contiv/vpp/plugins/contiv/model/cni"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -32,12 +39,18 @@ const (
 	testServerPort = 59111 // port where the testing gRPC server is running
 )
 
-// testCNIServer represents testing CNI gRPC server. Implements CNI Add and Delete operations.
+// testCNIServer represents testing CNI gRPC server. Implements CNI Add, Delete and Check operations.
 type testCNIServer struct{}
 
+// lastAddRequest is the last request received by testCNIServer.Add
+var lastAddRequest *cni.CNIRequest
+
+var testServerOnce sync.Once
+
 // Add implements the CNI request to add a container to network.
-func (s *testCNIServer) Add(context.Context, *cni.CNIRequest) (*cni.CNIReply, error) {
+func (s *testCNIServer) Add(ctx context.Context, request *cni.CNIRequest) (*cni.CNIReply, error) {
 	fmt.Println("ADD called")
+	lastAddRequest = request
 
 	// return a mocked reply
 	return &cni.CNIReply{
@@ -46,6 +59,7 @@ func (s *testCNIServer) Add(context.Context, *cni.CNIRequest) (*cni.CNIReply, er
 		Interfaces: []*cni.CNIReply_Interface{
 			{
 				Name: "eth0",
+				Mtu:  1450,
 				IpAddresses: []*cni.CNIReply_Interface_IP{
 					{
 						Address: "192.168.1.53/24",
@@ -89,8 +103,27 @@ func (s *testCNIServer) Delete(context.Context, *cni.CNIRequest) (*cni.CNIReply,
 	}, nil
 }
 
-// runTestGrpcServer starts a testing gRPC server with testCNIServer implementation.
-func runTestGrpcServer() *grpc.Server {
+// Check implements the CNI request to check that a container is still connected to network.
+// Containers with ID "missing" are reported as not connected.
+func (s *testCNIServer) Check(ctx context.Context, request *cni.CNIRequest) (*cni.CNIReply, error) {
+	fmt.Println("CHECK called")
+
+	if request.ContainerId == "missing" {
+		return nil, fmt.Errorf("container %s is not connected to the network", request.ContainerId)
+	}
+	return &cni.CNIReply{
+		Result: 0,
+		Error:  "",
+	}, nil
+}
+
+// runTestGrpcServer starts a testing gRPC server with testCNIServer implementation (only once).
+func runTestGrpcServer() {
+	testServerOnce.Do(func() { startTestGrpcServer() })
+}
+
+// startTestGrpcServer starts a testing gRPC server with testCNIServer implementation.
+func startTestGrpcServer() *grpc.Server {
 	// initialize the gRPC server
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", testServerPort))
 	if err != nil {
@@ -134,3 +167,197 @@ func TestCNIAddDelete(t *testing.T) {
 	err = cmdDel(&skel.CmdArgs{StdinData: []byte(conf)})
 	Expect(err).ShouldNot(HaveOccurred())
 }
+
+// TestCNIAddDeleteExternalIPAM tests that the external IPAM plugin is executed if configured
+// and the assigned addresses are passed to the remote CNI handler.
+func TestCNIAddDeleteExternalIPAM(t *testing.T) {
+	RegisterTestingT(t)
+
+	// start testing gRPC server
+	runTestGrpcServer()
+
+	// mock the IPAM plugin
+	delegated := []string{}
+	delegateAdd = func(plugin string, netconf []byte) (types.Result, error) {
+		delegated = append(delegated, "ADD "+plugin)
+		ip, ipNet, _ := net.ParseCIDR("10.1.1.20/24")
+		ipNet.IP = ip
+		return &cnisb.Result{
+			CNIVersion: "0.3.1",
+			IPs:        []*cnisb.IPConfig{{Version: "4", Address: *ipNet, Gateway: net.ParseIP("10.1.1.1")}},
+		}, nil
+	}
+	delegateDel = func(plugin string, netconf []byte) error {
+		delegated = append(delegated, "DEL "+plugin)
+		return nil
+	}
+
+	// prepare CNI config
+	conf := `{
+	"cniVersion": "0.3.1",
+	"type": "contiv-cni",
+	"grpcServer": "localhost:%d",
+	"ipam": {
+		"type": "host-local",
+		"subnet": "10.1.1.0/24"
+	}
+}`
+	conf = fmt.Sprintf(conf, testServerPort)
+
+	// test ADD operation
+	err := cmdAdd(&skel.CmdArgs{StdinData: []byte(conf)})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(lastAddRequest.ExternalIpamAddresses).To(HaveLen(1))
+	Expect(lastAddRequest.ExternalIpamAddresses[0].Address).To(Equal("10.1.1.20/24"))
+	Expect(lastAddRequest.ExternalIpamAddresses[0].Gateway).To(Equal("10.1.1.1"))
+
+	// test DEL operation
+	err = cmdDel(&skel.CmdArgs{StdinData: []byte(conf)})
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(delegated).To(Equal([]string{"ADD host-local", "DEL host-local"}))
+}
+
+// TestCNICheck tests CNI Check operation of the CNI plugin.
+func TestCNICheck(t *testing.T) {
+	RegisterTestingT(t)
+
+	// start testing gRPC server
+	runTestGrpcServer()
+
+	// prepare CNI config
+	conf := `{
+	"cniVersion": "0.4.0",
+	"type": "contiv-cni",
+	"grpcServer": "localhost:%d"
+}`
+	conf = fmt.Sprintf(conf, testServerPort)
+
+	// CHECK arguments are read from the environment and stdin
+	env := map[string]string{
+		"CNI_CONTAINERID": "container1",
+		"CNI_NETNS":       "/var/run/netns/1",
+		"CNI_IFNAME":      "eth0",
+	}
+	args, err := checkCmdArgs(func(key string) string { return env[key] }, strings.NewReader(conf))
+	Expect(err).ShouldNot(HaveOccurred())
+	Expect(args.ContainerID).To(Equal("container1"))
+	Expect(string(args.StdinData)).To(Equal(conf))
+
+	// test CHECK operation of a connected container
+	err = cmdCheck(args)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	// test CHECK operation of a container which is not connected
+	args.ContainerID = "missing"
+	err = cmdCheck(args)
+	Expect(err).Should(HaveOccurred())
+
+	// CHECK is not supported by the spec versions preceding 0.4.0
+	args.ContainerID = "container1"
+	args.StdinData = []byte(strings.Replace(conf, "0.4.0", "0.3.1", 1))
+	err = cmdCheck(args)
+	Expect(err).Should(HaveOccurred())
+
+	// the mandatory arguments must be present
+	delete(env, "CNI_NETNS")
+	_, err = checkCmdArgs(func(key string) string { return env[key] }, strings.NewReader(conf))
+	Expect(err).Should(HaveOccurred())
+}
+
+// TestCNIResultVersions tests that the CNI result is printed in the format of the requested spec version.
+func TestCNIResultVersions(t *testing.T) {
+	RegisterTestingT(t)
+
+	// start testing gRPC server
+	runTestGrpcServer()
+
+	output := &bytes.Buffer{}
+	resultOutput = output
+	defer func() { resultOutput = os.Stdout }()
+
+	conf := `{
+	"cniVersion": "%s",
+	"type": "contiv-cni",
+	"grpcServer": "localhost:%d"
+}`
+	for _, cniVersion := range []string{"0.3.1", "0.4.0", "1.0.0", "0.2.0"} {
+		output.Reset()
+		err := cmdAdd(&skel.CmdArgs{StdinData: []byte(fmt.Sprintf(conf, cniVersion, testServerPort))})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		result := map[string]interface{}{}
+		Expect(json.Unmarshal(output.Bytes(), &result)).To(Succeed())
+		Expect(result["cniVersion"]).To(Equal(cniVersion))
+
+		switch cniVersion {
+		case "0.2.0":
+			// the pre-0.3.0 results carry one IP address per version
+			Expect(result).To(HaveKey("ip4"))
+			Expect(result).To(HaveKey("ip6"))
+		case "1.0.0":
+			ips := result["ips"].([]interface{})
+			Expect(ips).To(HaveLen(2))
+			Expect(ips[0]).NotTo(HaveKey("version"))
+			Expect(ips[0]).To(HaveKeyWithValue("gateway", "192.168.1.1"))
+			Expect(result["interfaces"]).To(HaveLen(1))
+			Expect(result["interfaces"].([]interface{})[0]).To(HaveKeyWithValue("mtu", BeNumerically("==", 1450)))
+		default:
+			ips := result["ips"].([]interface{})
+			Expect(ips).To(HaveLen(2))
+			Expect(ips[0]).To(HaveKeyWithValue("version", "4"))
+			Expect(result["interfaces"]).To(HaveLen(1))
+		}
+	}
+
+	// comparison of spec versions
+	_, err := versionAtLeast("x.y", "0.4.0")
+	Expect(err).Should(HaveOccurred())
+	Expect(versionAtLeast("1.0.0", "0.4.0")).To(BeTrue())
+	Expect(versionAtLeast("0.3.1", "0.4.0")).To(BeFalse())
+}
+
+// TestCNIAddChained tests CNI Add operation of the plugin chained after another plugin.
+func TestCNIAddChained(t *testing.T) {
+	RegisterTestingT(t)
+
+	// start testing gRPC server
+	runTestGrpcServer()
+
+	output := &bytes.Buffer{}
+	resultOutput = output
+	defer func() { resultOutput = os.Stdout }()
+
+	// prepare CNI config with the result of the previous plugin
+	conf := `{
+	"cniVersion": "0.4.0",
+	"type": "contiv-cni",
+	"grpcServer": "localhost:%d",
+	"prevResult": {
+		"cniVersion": "0.4.0",
+		"interfaces": [{"name": "net1", "sandbox": "/var/run/netns/1"}],
+		"ips": [{"version": "4", "address": "10.10.0.5/24", "interface": 0}],
+		"routes": [{"dst": "10.10.0.0/16"}]
+	}
+}`
+	conf = fmt.Sprintf(conf, testServerPort)
+
+	// test ADD operation
+	err := cmdAdd(&skel.CmdArgs{StdinData: []byte(conf)})
+	Expect(err).ShouldNot(HaveOccurred())
+
+	// the previous result goes first, followed by the result of this plugin
+	result := &cnisb.Result{}
+	Expect(json.Unmarshal(output.Bytes(), result)).To(Succeed())
+	Expect(result.Interfaces).To(HaveLen(2))
+	Expect(result.Interfaces[0].Name).To(Equal("net1"))
+	Expect(result.IPs).To(HaveLen(3))
+	Expect(*result.IPs[0].Interface).To(Equal(0))
+	Expect(*result.IPs[1].Interface).To(Equal(1))
+	Expect(*result.IPs[2].Interface).To(Equal(1))
+	Expect(result.Routes).To(HaveLen(3))
+	Expect(result.DNS.Nameservers).To(Equal([]string{"8.8.8.8"}))
+
+	// test DEL operation
+	err = cmdDel(&skel.CmdArgs{StdinData: []byte(conf)})
+	Expect(err).ShouldNot(HaveOccurred())
+}
@@ -15,6 +15,16 @@
 // Contiv-cni is a CNI plugin (binary) that forwards the CNI requests to the
 // gRPC server specified in the CNI config file. The response from gRPC server
 // is then processed back into the standard output of the CNI plugin.
-// This plugin implements the CNI specification version 0.3.1
-// (https://github.com/containernetworking/cni/blob/spec-v0.3.1/SPEC.md).
+// The result is printed in the format of the CNI specification version requested
+// by cniVersion of the network config: 0.4.0 and 1.0.0 are supported
+// (https://github.com/containernetworking/cni/blob/spec-v1.0.0/SPEC.md)
+// as well as the older 0.1.0 - 0.3.1 versions.
+// If the ipam section is present in the CNI config, the configured IPAM plugin
+// is executed and the assigned addresses are passed to the gRPC server.
+// The plugin can be chained with other CNI plugins: the result of the previous plugin
+// (prevResult) is merged into the result of this plugin.
+// The CHECK command (CNI_COMMAND=CHECK, spec version 0.4.0 or later) is forwarded
+// to the gRPC server as well, which verifies that the pod interface, its routes and
+// the VPP-side configuration still exist. A missing piece is reported as an error,
+// so that the container runtime re-creates the pod networking.
 package main
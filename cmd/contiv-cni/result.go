@@ -0,0 +1,137 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+
+	cnisb "github.com/containernetworking/cni/pkg/types/current"
+)
+
+// supportedVersions lists the versions of the CNI spec the plugin is able to negotiate.
+var supportedVersions = version.PluginSupports("0.1.0", "0.2.0", "0.3.0", "0.3.1", "0.4.0", "1.0.0")
+
+// resultOutput is where the CNI result is printed to (replaceable in tests).
+var resultOutput io.Writer = os.Stdout
+
+// result100 is the CNI result in the format of the spec version 1.0.0.
+type result100 struct {
+	CNIVersion string          `json:"cniVersion,omitempty"`
+	Interfaces []*interface100 `json:"interfaces,omitempty"`
+	IPs        []*ipConfig100  `json:"ips,omitempty"`
+	Routes     []*types.Route  `json:"routes,omitempty"`
+	DNS        types.DNS       `json:"dns,omitempty"`
+}
+
+// interface100 is the interface in the format of the spec version 1.0.0,
+// extended with the MTU (standardized by later versions of the spec).
+type interface100 struct {
+	Name    string `json:"name"`
+	Mac     string `json:"mac,omitempty"`
+	Mtu     uint32 `json:"mtu,omitempty"`
+	Sandbox string `json:"sandbox,omitempty"`
+}
+
+// ipConfig100 is the IP address in the format of the spec version 1.0.0,
+// which no longer carries the IP version.
+type ipConfig100 struct {
+	Interface *int   `json:"interface,omitempty"`
+	Address   string `json:"address"`
+	Gateway   string `json:"gateway,omitempty"`
+}
+
+// printResult prints the result in the format of the spec version requested by the network config.
+// The MTUs of the interfaces are included only in the results of the spec versions supporting them.
+func printResult(result *cnisb.Result, mtus map[*cnisb.Interface]uint32, cniVersion string) error {
+	var out interface{}
+
+	switch cniVersion {
+	case "", "0.3.0", "0.3.1", "0.4.0":
+		// 0.4.0 shares the result schema with 0.3.x
+		result.CNIVersion = cniVersion
+		out = result
+	case "1.0.0":
+		out = convertTo100(result, mtus)
+	default:
+		// convert to the pre-0.3.0 format
+		r, err := result.GetAsVersion(cniVersion)
+		if err != nil {
+			return err
+		}
+		out = r
+	}
+
+	data, err := json.MarshalIndent(out, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the result: %v", err)
+	}
+	_, err = resultOutput.Write(data)
+	return err
+}
+
+// convertTo100 converts the result into the format of the spec version 1.0.0.
+func convertTo100(result *cnisb.Result, mtus map[*cnisb.Interface]uint32) *result100 {
+	r := &result100{
+		CNIVersion: "1.0.0",
+		Routes:     result.Routes,
+		DNS:        result.DNS,
+	}
+	for _, iface := range result.Interfaces {
+		r.Interfaces = append(r.Interfaces, &interface100{
+			Name:    iface.Name,
+			Mac:     iface.Mac,
+			Mtu:     mtus[iface],
+			Sandbox: iface.Sandbox,
+		})
+	}
+	for _, ip := range result.IPs {
+		ipConfig := &ipConfig100{
+			Interface: ip.Interface,
+			Address:   ip.Address.String(),
+		}
+		if ip.Gateway != nil {
+			ipConfig.Gateway = ip.Gateway.String()
+		}
+		r.IPs = append(r.IPs, ipConfig)
+	}
+	return r
+}
+
+// versionAtLeast returns true if the CNI spec version is equal or later than the minimal one.
+func versionAtLeast(cniVersion, minVersion string) (bool, error) {
+	if cniVersion == "" {
+		return false, nil
+	}
+	var major, minor, micro, minMajor, minMinor, minMicro int
+	if _, err := fmt.Sscanf(cniVersion, "%d.%d.%d", &major, &minor, &micro); err != nil {
+		return false, fmt.Errorf("invalid CNI version %q: %v", cniVersion, err)
+	}
+	if _, err := fmt.Sscanf(minVersion, "%d.%d.%d", &minMajor, &minMinor, &minMicro); err != nil {
+		return false, fmt.Errorf("invalid CNI version %q: %v", minVersion, err)
+	}
+	if major != minMajor {
+		return major > minMajor, nil
+	}
+	if minor != minMinor {
+		return minor > minMinor, nil
+	}
+	return micro >= minMicro, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// REST API of the contiv agent
+const (
+	nodeIDsURL = "/contiv/v1/nodeids"
+	ipamURL    = "/contiv/v1/ipam"
+	podsURL    = "/contiv/v1/pods"
+	vxlanURL   = "/contiv/v1/vxlan"
+	aclsURL    = "/contiv/v1/acls"
+	vppCLIURL  = "/contiv/v1/vppcli"
+
+	connectivityURL = "/contiv/v1/connectivity"
+	captureURL      = "/contiv/v1/capture"
+	capturePcapURL  = "/contiv/v1/capture/pcap"
+	traceURL        = "/contiv/v1/trace"
+)
+
+// agentClient sends requests to the REST API of contiv agents.
+type agentClient struct {
+	// agent is the address of the agent used to look up the nodes of the cluster
+	agent string
+	// port is the HTTP port of the agents on the nodes
+	port       string
+	httpClient *http.Client
+}
+
+// newAgentClient creates a client looking up the nodes via the agent with the given address.
+func newAgentClient(agent string, port int, timeout time.Duration) *agentClient {
+	return &agentClient{
+		agent:      agent,
+		port:       strconv.Itoa(port),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// nodeIDs returns the allocated node IDs of the cluster.
+func (c *agentClient) nodeIDs() ([]*nodeIDAllocation, error) {
+	nodes := []*nodeIDAllocation{}
+	err := c.get(c.agent, nodeIDsURL, &nodes)
+	return nodes, err
+}
+
+// resolveNode returns the address of the agent running on the node. The node is looked up by its name
+// or ID in the node ID allocations, other values are used as the address of the agent (with the default
+// port if not set).
+func (c *agentClient) resolveNode(node string) (string, error) {
+	nodes, err := c.nodeIDs()
+	if err != nil {
+		return "", fmt.Errorf("can't look up node %s: %v", node, err)
+	}
+	for _, allocation := range nodes {
+		if allocation.NodeName != node && strconv.FormatUint(uint64(allocation.ID), 10) != node {
+			continue
+		}
+		ip := allocation.ManagementIP
+		if ip == "" {
+			ip = allocation.NodeIP
+		}
+		if ip == "" {
+			return "", fmt.Errorf("IP address of node %s is not known", node)
+		}
+		ip = strings.Split(ip, "/")[0]
+		return net.JoinHostPort(ip, c.port), nil
+	}
+	if _, _, err := net.SplitHostPort(node); err == nil {
+		return node, nil
+	}
+	return net.JoinHostPort(node, c.port), nil
+}
+
+// get sends GET request to the agent and decodes the JSON reply into the result.
+func (c *agentClient) get(agent, url string, result interface{}) error {
+	resp, err := c.httpClient.Get("http://" + agent + url)
+	if err != nil {
+		return err
+	}
+	return decodeReply(resp, result)
+}
+
+// post sends POST request with the JSON-encoded body to the agent and decodes the JSON reply into the result.
+func (c *agentClient) post(agent, url string, body interface{}, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Post("http://"+agent+url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return decodeReply(resp, result)
+}
+
+// delete sends DELETE request to the agent.
+func (c *agentClient) delete(agent, url string) error {
+	req, err := http.NewRequest(http.MethodDelete, "http://"+agent+url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return decodeReply(resp, &map[string]interface{}{})
+}
+
+// download sends GET request to the agent and copies the reply into the writer.
+func (c *agentClient) download(agent, url string, out io.Writer) error {
+	resp, err := c.httpClient.Get("http://" + agent + url)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return decodeReply(resp, nil)
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+// decodeReply decodes the JSON reply of the agent, error replies are returned as errors.
+func decodeReply(resp *http.Response, result interface{}) error {
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		errReply := struct{ Error string }{}
+		if json.Unmarshal(data, &errReply) == nil && errReply.Error != "" {
+			return fmt.Errorf("%s: %s", resp.Status, errReply.Error)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, result)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/contiv/vpp/plugins/contiv/model/connectivity"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
+)
+
+// capturePollInterval is the period of polling of the state of the running capture.
+var capturePollInterval = 500 * time.Millisecond
+
+// nodeIDAllocation is the node ID allocation as returned by the agent.
+type nodeIDAllocation struct {
+	ID           uint32 `json:"id"`
+	NodeName     string `json:"nodeName"`
+	NodeIP       string `json:"nodeIP,omitempty"`
+	ManagementIP string `json:"managementIP,omitempty"`
+	PodSubnet    string `json:"podSubnet,omitempty"`
+	AgentVersion string `json:"agentVersion,omitempty"`
+}
+
+// ipamInfo is IPAM of the node as returned by the agent.
+type ipamInfo struct {
+	NodeID         uint8  `json:"nodeId"`
+	NodeName       string `json:"nodeName"`
+	NodeIP         string `json:"nodeIP,omitempty"`
+	PodNetwork     string `json:"podNetwork"`
+	VPPHostNetwork string `json:"vppHostNetwork"`
+	Pools          []struct {
+		Pool     string `json:"pool,omitempty"`
+		Assigned int    `json:"assigned"`
+		Capacity int    `json:"capacity"`
+	} `json:"pools"`
+}
+
+// podInfo is a pod connected on the node as returned by the agent.
+type podInfo struct {
+	ContainerID         string `json:"containerId"`
+	PodNamespace        string `json:"podNamespace"`
+	PodName             string `json:"podName"`
+	PodIP               string `json:"podIP,omitempty"`
+	VppInterface        string `json:"vppInterface,omitempty"`
+	SwIfIndex           uint32 `json:"swIfIndex,omitempty"`
+	SecondaryInterfaces []struct {
+		PodInterface string `json:"podInterface"`
+		Network      string `json:"network"`
+		IPAddress    string `json:"ipAddress,omitempty"`
+		VppInterface string `json:"vppInterface,omitempty"`
+	} `json:"secondaryInterfaces,omitempty"`
+}
+
+// vxlanTunnelInfo is a VXLAN tunnel of the node as returned by the agent.
+type vxlanTunnelInfo struct {
+	Interface  string `json:"interface,omitempty"`
+	SwIfIndex  uint32 `json:"swIfIndex"`
+	SrcAddress string `json:"srcAddress"`
+	DstAddress string `json:"dstAddress"`
+	Vni        uint32 `json:"vni"`
+	AdminUp    bool   `json:"adminUp"`
+	LinkUp     bool   `json:"linkUp"`
+}
+
+// vppCLIRequest and vppCLIReply are the body and the reply of the VPP CLI request.
+type vppCLIRequest struct {
+	Command string `json:"command"`
+}
+
+type vppCLIReply struct {
+	Output string `json:"output"`
+}
+
+// showNodes prints the node ID allocations of the cluster.
+func showNodes(c *agentClient, out io.Writer) error {
+	nodes, err := c.nodeIDs()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tNODE IP\tMANAGEMENT IP\tPOD SUBNET\tVERSION")
+	for _, node := range nodes {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", node.ID, node.NodeName, node.NodeIP, node.ManagementIP,
+			node.PodSubnet, node.AgentVersion)
+	}
+	return w.Flush()
+}
+
+// showIPAM prints IPAM of the node.
+func showIPAM(c *agentClient, agent string, out io.Writer) error {
+	info := &ipamInfo{}
+	if err := c.get(agent, ipamURL, info); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Node ID:\t%d\n", info.NodeID)
+	fmt.Fprintf(w, "Node name:\t%s\n", info.NodeName)
+	fmt.Fprintf(w, "Node IP:\t%s\n", info.NodeIP)
+	fmt.Fprintf(w, "Pod network:\t%s\n", info.PodNetwork)
+	fmt.Fprintf(w, "VPP-host network:\t%s\n", info.VPPHostNetwork)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "POOL\tASSIGNED\tCAPACITY")
+	for _, pool := range info.Pools {
+		name := pool.Pool
+		if name == "" {
+			name = "pod-network"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\n", name, pool.Assigned, pool.Capacity)
+	}
+	return w.Flush()
+}
+
+// showPods prints the pods connected on the node with their interfaces.
+func showPods(c *agentClient, agent string, out io.Writer) error {
+	pods := []*podInfo{}
+	if err := c.get(agent, podsURL, &pods); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tPOD\tIP\tVPP INTERFACE\tSW IF INDEX\tCONTAINER")
+	for _, pod := range pods {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", pod.PodNamespace, pod.PodName, pod.PodIP, pod.VppInterface,
+			pod.SwIfIndex, shortContainerID(pod.ContainerID))
+		for _, secondaryIf := range pod.SecondaryInterfaces {
+			fmt.Fprintf(w, "\t  %s (%s)\t%s\t%s\t\t\n", secondaryIf.PodInterface, secondaryIf.Network,
+				secondaryIf.IPAddress, secondaryIf.VppInterface)
+		}
+	}
+	return w.Flush()
+}
+
+// showVxlan prints the VXLAN tunnels of the node.
+func showVxlan(c *agentClient, agent string, out io.Writer) error {
+	tunnels := []*vxlanTunnelInfo{}
+	if err := c.get(agent, vxlanURL, &tunnels); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "INTERFACE\tSW IF INDEX\tSRC\tDST\tVNI\tSTATE")
+	for _, tunnel := range tunnels {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%d\t%s\n", tunnel.Interface, tunnel.SwIfIndex, tunnel.SrcAddress,
+			tunnel.DstAddress, tunnel.Vni, linkState(tunnel.AdminUp, tunnel.LinkUp))
+	}
+	return w.Flush()
+}
+
+// showACLs prints the ACLs configured in VPP of the node.
+func showACLs(c *agentClient, agent string, out io.Writer) error {
+	acls := []*acl.AccessLists_Acl{}
+	if err := c.get(agent, aclsURL, &acls); err != nil {
+		return err
+	}
+	for _, list := range acls {
+		fmt.Fprintf(out, "ACL %s\n", list.AclName)
+		if list.Interfaces != nil {
+			fmt.Fprintf(out, "  ingress: %s\n", strings.Join(list.Interfaces.Ingress, ", "))
+			fmt.Fprintf(out, "  egress:  %s\n", strings.Join(list.Interfaces.Egress, ", "))
+		}
+		for _, rule := range list.Rules {
+			action := ""
+			if rule.Actions != nil {
+				action = rule.Actions.AclAction.String()
+			}
+			fmt.Fprintf(out, "  %s %s %s\n", rule.RuleName, action, rule.Matches.String())
+		}
+	}
+	return nil
+}
+
+// vppCLI executes the VPP CLI command on the node and prints its output.
+func vppCLI(c *agentClient, agent string, command string, out io.Writer) error {
+	reply := &vppCLIReply{}
+	if err := c.post(agent, vppCLIURL, &vppCLIRequest{Command: command}, reply); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(out, reply.Output)
+	return err
+}
+
+// showConnectivity prints the connectivity matrix of the cluster as recorded by the connectivity checks of all nodes.
+func showConnectivity(c *agentClient, out io.Writer) error {
+	matrix := []*connectivity.NodeConnectivity{}
+	if err := c.get(c.agent, connectivityURL, &matrix); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "FROM\tTO\tKIND\tADDRESS\tSTATE\tRTT\tCHECKED")
+	for _, results := range matrix {
+		checked := time.Unix(results.Timestamp, 0).Format(time.RFC3339)
+		for _, target := range results.Target {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", results.NodeName, target.NodeName, target.Kind,
+				target.Address, reachability(target), time.Duration(target.RttUs)*time.Microsecond, checked)
+		}
+	}
+	return w.Flush()
+}
+
+// reachability describes the result of the probes of the target.
+func reachability(target *connectivity.NodeConnectivity_Target) string {
+	switch {
+	case target.Error != "":
+		return "error: " + target.Error
+	case !target.Reachable:
+		return "unreachable"
+	case !target.LargeProbeReachable:
+		return "MTU blackhole"
+	default:
+		return "ok"
+	}
+}
+
+// packetCapture is the state of the packet capture as returned by the agent.
+type packetCapture struct {
+	Interface       string `json:"interface"`
+	State           string `json:"state"`
+	CapturedPackets uint32 `json:"capturedPackets"`
+	Error           string `json:"error,omitempty"`
+}
+
+// capturePod captures the packets sent to the pod on the node and writes them into the pcap file.
+func capturePod(c *agentClient, agent string, podNamespace, podName string, maxPackets uint, duration time.Duration,
+	file string, out io.Writer) error {
+	request := map[string]interface{}{
+		"podNamespace": podNamespace,
+		"podName":      podName,
+		"maxPackets":   maxPackets,
+		"duration":     uint32(duration / time.Second),
+	}
+	capture := &packetCapture{}
+	if err := c.post(agent, captureURL, request, capture); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Capturing up to %d packets on %s for %v...\n", maxPackets, capture.Interface, duration)
+	for capture.State == "running" {
+		time.Sleep(capturePollInterval)
+		if err := c.get(agent, captureURL, capture); err != nil {
+			return err
+		}
+	}
+	if capture.State != "finished" {
+		return fmt.Errorf("capture failed: %s", capture.Error)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := c.download(agent, capturePcapURL, f); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Captured %d packets into %s\n", capture.CapturedPackets, file)
+	return nil
+}
+
+// packetTrace is a packet traced by VPP as returned by the agent.
+type packetTrace struct {
+	Thread int `json:"thread"`
+	ID     int `json:"id"`
+	Nodes  []struct {
+		Time string   `json:"time"`
+		Name string   `json:"name"`
+		Info []string `json:"info,omitempty"`
+	} `json:"nodes"`
+	Dropped    bool   `json:"dropped"`
+	DropNode   string `json:"dropNode,omitempty"`
+	DropReason string `json:"dropReason,omitempty"`
+}
+
+// tracePod starts tracing of the packets of the pod on the node, prints the traced packets
+// or clears the trace, depending on the action.
+func tracePod(c *agentClient, agent string, podNamespace, podName string, action string, verbose bool,
+	out io.Writer) error {
+	switch action {
+	case "start":
+		request := map[string]interface{}{"podNamespace": podNamespace, "podName": podName}
+		if err := c.post(agent, traceURL, request, &map[string]interface{}{}); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Tracing packets of %s/%s\n", podNamespace, podName)
+		return nil
+	case "clear":
+		return c.delete(agent, traceURL)
+	case "show":
+	default:
+		return fmt.Errorf("unknown trace action %s, expected start, show or clear", action)
+	}
+
+	traces := []*packetTrace{}
+	query := url.Values{"podNamespace": {podNamespace}, "podName": {podName}}
+	if err := c.get(agent, traceURL+"?"+query.Encode(), &traces); err != nil {
+		return err
+	}
+	for _, trace := range traces {
+		path := []string{}
+		for _, node := range trace.Nodes {
+			path = append(path, node.Name)
+		}
+		fmt.Fprintf(out, "Packet %d (thread %d): %s\n", trace.ID, trace.Thread, strings.Join(path, " -> "))
+		if trace.Dropped {
+			fmt.Fprintf(out, "  dropped by %s: %s\n", trace.DropNode, trace.DropReason)
+		}
+		if verbose {
+			for _, node := range trace.Nodes {
+				fmt.Fprintf(out, "  %s %s\n", node.Time, node.Name)
+				for _, info := range node.Info {
+					fmt.Fprintf(out, "    %s\n", info)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// shortContainerID shortens the container ID the way docker prints it.
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// linkState returns the state of the interface as printed by VPP.
+func linkState(adminUp, linkUp bool) string {
+	switch {
+	case !adminUp:
+		return "down"
+	case !linkUp:
+		return "up (no link)"
+	default:
+		return "up"
+	}
+}
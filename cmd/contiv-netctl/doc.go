@@ -0,0 +1,8 @@
+// Package contiv-netctl implements a command-line tool for diagnostics of Contiv-VPP
+// networking. The tool talks to the REST API of the contiv agents running on the nodes
+// of the cluster and shows node IDs, IPAM, pods connected to VPP with their interfaces,
+// VXLAN tunnels between the nodes and ACLs rendered from the network policies in one place.
+// VPP CLI commands can be executed on any node as well ("contiv-netctl vppcli <node> <cmd>").
+// Nodes are looked up by their name or ID in the node ID allocations of the cluster,
+// IP addresses and host names can be used instead.
+package main
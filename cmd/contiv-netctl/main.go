@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// command line flags
+	agent    = flag.String("agent", "localhost:9999", "Address of the contiv agent used to look up the nodes")
+	port     = flag.Int("port", 9999, "HTTP port of the contiv agents on the nodes")
+	timeout  = flag.Duration("timeout", 10*time.Second, "Timeout of requests to the contiv agents")
+	packets  = flag.Uint("packets", 100, "Max. number of packets captured by the capture command")
+	duration = flag.Duration("duration", 10*time.Second, "Max. duration of the capture command")
+	verbose  = flag.Bool("v", false, "Switch to print the details of the traced packets")
+	help     = flag.Bool("h", false, "Switch to show help")
+)
+
+const helpContent = `contiv-netctl shows the state of Contiv-VPP networking as seen by the contiv agents.
+Usage:
+  contiv-netctl [flags] <command> [arguments]
+
+Commands:
+  nodes                 Lists the nodes of the cluster with their IDs and IP addresses
+  ipam <node>           Shows IPAM of the node: node ID, networks and utilization of the IP pools
+  pods <node>           Lists the pods connected on the node with their VPP interfaces
+  vxlan <node>          Lists VXLAN tunnels from the node to other nodes with their state
+  acls <node>           Lists ACLs rendered from the network policies into VPP of the node
+  vppcli <node> <cmd>   Executes VPP CLI command on the node, e.g. "vppcli k8s-master show interface"
+  connectivity          Shows the connectivity matrix of the cluster (requires ConnectivityCheck enabled)
+  capture <node> <namespace>/<pod> <file>
+                        Captures the packets sent to the pod by VPP into the pcap file
+  trace <node> <namespace>/<pod> [start|show|clear]
+                        Starts tracing of the packets entering VPP, shows the traced packets of the pod
+                        with the nodes that dropped them or clears the trace (show by default)
+
+The node is given by its name or ID, an IP address or a host name of the node can be used as well.
+
+Flags:
+  -agent [host:port]    Sets the contiv agent used to look up the nodes (default localhost:9999)
+  -port [port]          Sets the HTTP port of the contiv agents on the nodes (default 9999)
+  -timeout [duration]   Sets the timeout of requests to the contiv agents (default 10s)
+  -packets [count]      Sets the max. number of packets captured by the capture command (default 100)
+  -duration [duration]  Sets the max. duration of the capture command (default 10s)
+  -v                    Prints the details of the packets shown by the trace command
+  -h                    Prints this help
+`
+
+// main is the main method of contiv-netctl
+func main() {
+	flag.Parse()
+	if *help || flag.NArg() == 0 {
+		fmt.Print(helpContent)
+		return
+	}
+	client := newAgentClient(*agent, *port, *timeout)
+	if err := run(client, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// splitPod splits "<namespace>/<pod>" into the namespace and the name of the pod, the default namespace is used
+// if not set.
+func splitPod(pod string) (podNamespace, podName string) {
+	if parts := strings.SplitN(pod, "/", 2); len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return "default", pod
+}
+
+// run executes the command given by the arguments of the tool.
+func run(client *agentClient, args []string) error {
+	command := args[0]
+	switch command {
+	case "nodes":
+		return showNodes(client, os.Stdout)
+	case "connectivity":
+		return showConnectivity(client, os.Stdout)
+	}
+
+	if len(args) < 2 {
+		return fmt.Errorf("node expected, usage:\n" + helpContent)
+	}
+	node, err := client.resolveNode(args[1])
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "ipam":
+		return showIPAM(client, node, os.Stdout)
+	case "pods":
+		return showPods(client, node, os.Stdout)
+	case "vxlan":
+		return showVxlan(client, node, os.Stdout)
+	case "acls":
+		return showACLs(client, node, os.Stdout)
+	case "vppcli":
+		if len(args) < 3 {
+			return fmt.Errorf("VPP CLI command expected, usage:\n" + helpContent)
+		}
+		return vppCLI(client, node, strings.Join(args[2:], " "), os.Stdout)
+	case "capture":
+		if len(args) < 4 {
+			return fmt.Errorf("pod and file expected, usage:\n" + helpContent)
+		}
+		podNamespace, podName := splitPod(args[2])
+		return capturePod(client, node, podNamespace, podName, *packets, *duration, args[3], os.Stdout)
+	case "trace":
+		if len(args) < 3 {
+			return fmt.Errorf("pod expected, usage:\n" + helpContent)
+		}
+		action := "show"
+		if len(args) > 3 {
+			action = args[3]
+		}
+		podNamespace, podName := splitPod(args[2])
+		return tracePod(client, node, podNamespace, podName, action, *verbose, os.Stdout)
+	}
+	return fmt.Errorf("unknown command %s, usage:\n%s", command, helpContent)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// newAgentMock starts HTTP server replying to the requests of the tool as the contiv agent.
+func newAgentMock() *httptest.Server {
+	mux := http.NewServeMux()
+	reply := func(path string, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(body))
+		})
+	}
+	reply(nodeIDsURL, `[{"id":1,"nodeName":"k8s-master","nodeIP":"192.168.16.1/24","managementIP":"10.20.0.2"},
+		{"id":2,"nodeName":"k8s-worker1","nodeIP":"192.168.16.2/24"}]`)
+	reply(podsURL, `[{"containerId":"0123456789abcdef","podNamespace":"default","podName":"nginx",
+		"podIP":"10.1.1.2","vppInterface":"tap1","swIfIndex":3}]`)
+	mux.HandleFunc(vppCLIURL, func(w http.ResponseWriter, req *http.Request) {
+		cliReq := &vppCLIRequest{}
+		json.NewDecoder(req.Body).Decode(cliReq)
+		if cliReq.Command != "show version" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"Error":"unknown command"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(&vppCLIReply{Output: "vpp v18.01\n"})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestResolveNode(t *testing.T) {
+	RegisterTestingT(t)
+
+	agent := newAgentMock()
+	defer agent.Close()
+	client := newAgentClient(strings.TrimPrefix(agent.URL, "http://"), 9999, time.Second)
+
+	// management IP is preferred
+	addr, err := client.resolveNode("k8s-master")
+	Expect(err).To(BeNil())
+	Expect(addr).To(BeEquivalentTo("10.20.0.2:9999"))
+
+	// node ID, node IP is used without the prefix
+	addr, err = client.resolveNode("2")
+	Expect(err).To(BeNil())
+	Expect(addr).To(BeEquivalentTo("192.168.16.2:9999"))
+
+	// unknown nodes are used as addresses
+	addr, err = client.resolveNode("10.20.0.3")
+	Expect(err).To(BeNil())
+	Expect(addr).To(BeEquivalentTo("10.20.0.3:9999"))
+	addr, err = client.resolveNode("10.20.0.3:8080")
+	Expect(err).To(BeNil())
+	Expect(addr).To(BeEquivalentTo("10.20.0.3:8080"))
+}
+
+func TestCommands(t *testing.T) {
+	RegisterTestingT(t)
+
+	agent := newAgentMock()
+	defer agent.Close()
+	addr := strings.TrimPrefix(agent.URL, "http://")
+	client := newAgentClient(addr, 9999, time.Second)
+
+	out := &bytes.Buffer{}
+	Expect(showNodes(client, out)).To(BeNil())
+	Expect(out.String()).To(ContainSubstring("k8s-worker1"))
+
+	out.Reset()
+	Expect(showPods(client, addr, out)).To(BeNil())
+	Expect(out.String()).To(ContainSubstring("nginx"))
+	Expect(out.String()).To(ContainSubstring("0123456789ab\n"))
+
+	out.Reset()
+	Expect(vppCLI(client, addr, "show version", out)).To(BeNil())
+	Expect(out.String()).To(BeEquivalentTo("vpp v18.01\n"))
+
+	// errors of the agent are returned
+	err := vppCLI(client, addr, "show foo", out)
+	Expect(err).ToNot(BeNil())
+	Expect(err.Error()).To(ContainSubstring("unknown command"))
+}
+
+func TestCapture(t *testing.T) {
+	RegisterTestingT(t)
+
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc(captureURL, func(w http.ResponseWriter, req *http.Request) {
+		state := "running"
+		if req.Method == "GET" {
+			polls++
+			state = "finished"
+		}
+		w.Write([]byte(`{"interface":"tap-1","state":"` + state + `","capturedPackets":7}`))
+	})
+	mux.HandleFunc(capturePcapURL, func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("pcap"))
+	})
+	agent := httptest.NewServer(mux)
+	defer agent.Close()
+	addr := strings.TrimPrefix(agent.URL, "http://")
+	client := newAgentClient(addr, 9999, time.Second)
+
+	dir, err := ioutil.TempDir("", "netctl")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	capturePollInterval = time.Millisecond
+
+	out := &bytes.Buffer{}
+	file := filepath.Join(dir, "nginx.pcap")
+	Expect(capturePod(client, addr, "default", "nginx", 10, time.Second, file, out)).To(BeNil())
+	Expect(polls).To(BeEquivalentTo(1))
+	Expect(out.String()).To(ContainSubstring("Captured 7 packets"))
+	data, err := ioutil.ReadFile(file)
+	Expect(err).To(BeNil())
+	Expect(string(data)).To(BeEquivalentTo("pcap"))
+}
+
+func TestTrace(t *testing.T) {
+	RegisterTestingT(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(traceURL, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "GET" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		Expect(req.URL.Query().Get("podName")).To(BeEquivalentTo("nginx"))
+		w.Write([]byte(`[{"thread":0,"id":1,"nodes":[{"name":"af-packet-input"},{"name":"ip4-input"},
+			{"name":"error-drop","info":["ip4-input: ip4 adjacency drop"]}],
+			"dropped":true,"dropNode":"ip4-input","dropReason":"ip4 adjacency drop"}]`))
+	})
+	agent := httptest.NewServer(mux)
+	defer agent.Close()
+	addr := strings.TrimPrefix(agent.URL, "http://")
+	client := newAgentClient(addr, 9999, time.Second)
+
+	out := &bytes.Buffer{}
+	Expect(tracePod(client, addr, "default", "nginx", "start", false, out)).To(BeNil())
+	Expect(tracePod(client, addr, "default", "nginx", "clear", false, out)).To(BeNil())
+	Expect(tracePod(client, addr, "default", "nginx", "foo", false, out)).ToNot(BeNil())
+
+	out.Reset()
+	Expect(tracePod(client, addr, "default", "nginx", "show", true, out)).To(BeNil())
+	Expect(out.String()).To(ContainSubstring("Packet 1 (thread 0): af-packet-input -> ip4-input -> error-drop"))
+	Expect(out.String()).To(ContainSubstring("dropped by ip4-input: ip4 adjacency drop"))
+}
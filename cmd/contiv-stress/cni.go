@@ -0,0 +1,89 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/contiv/vpp/plugins/contiv/model/cni"
+)
+
+const (
+	// cniVersion is the version of CNI spec sent in the requests, the same as used by contiv-cni.
+	cniVersion = "0.3.1"
+	// resultOk is the result of successfully processed CNI request.
+	resultOk = 0
+)
+
+// cniClient sends CNI requests to the contiv agent the same way as contiv-cni does.
+type cniClient struct {
+	client    cni.RemoteCNIClient
+	netns     string
+	namespace string
+}
+
+// cniRequest returns the CNI request for the given pod.
+func (c *cniClient) cniRequest(pod *simPod) *cni.CNIRequest {
+	return &cni.CNIRequest{
+		Version:          cniVersion,
+		ContainerId:      pod.containerID,
+		NetworkNamespace: c.netns,
+		InterfaceName:    pod.ifName,
+		ExtraArguments:   fmt.Sprintf("IgnoreUnknown=1;K8S_POD_NAMESPACE=%s;K8S_POD_NAME=%s", c.namespace, pod.name),
+	}
+}
+
+// add connects the pod and stores the IP address assigned to it.
+func (c *cniClient) add(pod *simPod) error {
+	reply, err := c.client.Add(context.Background(), c.cniRequest(pod))
+	if err != nil {
+		return err
+	}
+	if reply.Result != resultOk {
+		return fmt.Errorf("failed to add pod %s: %s", pod.name, reply.Error)
+	}
+	pod.ip = podIP(reply)
+	return nil
+}
+
+// del disconnects the pod.
+func (c *cniClient) del(pod *simPod) error {
+	reply, err := c.client.Delete(context.Background(), c.cniRequest(pod))
+	if err != nil {
+		return err
+	}
+	if reply.Result != resultOk {
+		return fmt.Errorf("failed to delete pod %s: %s", pod.name, reply.Error)
+	}
+	pod.ip = ""
+	return nil
+}
+
+// podIP returns the first IP address from the CNI reply, without the prefix length.
+func podIP(reply *cni.CNIReply) string {
+	for _, iface := range reply.Interfaces {
+		for _, addr := range iface.IpAddresses {
+			if ip, _, err := net.ParseCIDR(addr.Address); err == nil {
+				return ip.String()
+			}
+			if ip := net.ParseIP(addr.Address); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,6 @@
+// Package contiv-stress implements a tool generating churn of pods, services, endpoints
+// and policies against a running contiv agent (with a real VPP or with the VPP mock).
+// Pods are added and deleted by CNI requests sent to the GRPC server of the agent
+// the same way as contiv-cni does, the K8s state is written into ETCD the same way
+// as contiv-ksr does. Latency percentiles of all operations are reported at the end.
+package main
@@ -0,0 +1,208 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/ligato/cn-infra/config"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/db/keyval/etcdv3"
+	"github.com/ligato/cn-infra/db/keyval/kvproto"
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/ligato/cn-infra/servicelabel"
+
+	"github.com/contiv/vpp/flavors/ksr"
+	epmodel "github.com/contiv/vpp/plugins/ksr/model/endpoints"
+	nsmodel "github.com/contiv/vpp/plugins/ksr/model/namespace"
+	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
+	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
+	svcmodel "github.com/contiv/vpp/plugins/ksr/model/service"
+)
+
+const (
+	// servicePort is the port of the simulated services.
+	servicePort = 80
+	// backendPort is the port of the simulated pods the services are translated to.
+	backendPort = 8080
+)
+
+// simPod is a pod simulated by the stress test.
+type simPod struct {
+	index       int
+	name        string
+	containerID string
+	ifName      string
+	ip          string
+}
+
+// newSimPod returns the pod with the given index.
+func newSimPod(index int) *simPod {
+	return &simPod{
+		index:       index,
+		name:        fmt.Sprintf("stress-pod-%d", index),
+		containerID: fmt.Sprintf("contiv-stress-%d", index),
+		// all pods share one network namespace, interface names have to be unique (max. 15 characters)
+		ifName: fmt.Sprintf("stress%d", index),
+	}
+}
+
+// appLabel returns the value of the label "app" of the pods in the given group,
+// each group of pods is selected by one service.
+func appLabel(group int) string {
+	return fmt.Sprintf("stress-%d", group)
+}
+
+// ksrStore writes the K8s state into the data store the same way as KSR does.
+type ksrStore struct {
+	conn   *etcdv3.BytesConnectionEtcd
+	broker keyval.ProtoBroker
+}
+
+// newKsrStore connects to ETCD configured by the given config file.
+func newKsrStore(configFile string) (*ksrStore, error) {
+	etcdConfig := &etcdv3.Config{}
+	if err := config.ParseConfigFromYamlFile(configFile, etcdConfig); err != nil {
+		return nil, fmt.Errorf("failed to read ETCD config %s: %v", configFile, err)
+	}
+	clientConfig, err := etcdv3.ConfigToClientv3(etcdConfig)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := etcdv3.NewEtcdConnectionWithBytes(*clientConfig, logrus.DefaultLogger())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ETCD: %v", err)
+	}
+	broker := kvproto.NewProtoWrapper(conn).NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel))
+	return &ksrStore{conn: conn, broker: broker}, nil
+}
+
+// close disconnects from ETCD.
+func (s *ksrStore) close() error {
+	return s.conn.Close()
+}
+
+// namespaceModel returns the namespace of the simulated pods.
+func namespaceModel(namespace string) *nsmodel.Namespace {
+	return &nsmodel.Namespace{
+		Name:  namespace,
+		Label: []*nsmodel.Namespace_Label{{Key: "contiv-stress", Value: "true"}},
+	}
+}
+
+// podModel returns the pod as reflected by KSR, labeled by the group of the pod.
+func podModel(namespace string, pod *simPod, group int) *podmodel.Pod {
+	return &podmodel.Pod{
+		Name:      pod.name,
+		Namespace: namespace,
+		Label:     []*podmodel.Pod_Label{{Key: "app", Value: appLabel(group)}},
+		IpAddress: pod.ip,
+	}
+}
+
+// serviceModel returns the service selecting the pods of the given group.
+func serviceModel(namespace string, group int, clusterIP net.IP) *svcmodel.Service {
+	return &svcmodel.Service{
+		Name:      fmt.Sprintf("stress-svc-%d", group),
+		Namespace: namespace,
+		Port: []*svcmodel.Service_ServicePort{
+			{
+				Name:     "http",
+				Protocol: "TCP",
+				Port:     servicePort,
+				TargetPort: &svcmodel.Service_ServicePort_IntOrString{
+					Type:   svcmodel.Service_ServicePort_IntOrString_NUMBER,
+					IntVal: backendPort,
+				},
+			},
+		},
+		Selector:    map[string]string{"app": appLabel(group)},
+		ClusterIp:   clusterIP.String(),
+		ServiceType: "ClusterIP",
+	}
+}
+
+// endpointsModel returns the endpoints of the service of the given group with the given pods
+// (on the given node) as backends.
+func endpointsModel(namespace string, group int, node string, backends []*simPod) *epmodel.Endpoints {
+	subset := &epmodel.EndpointSubset{
+		Ports: []*epmodel.EndpointSubset_EndpointPort{{Name: "http", Port: backendPort, Protocol: "TCP"}},
+	}
+	for _, pod := range backends {
+		if pod.ip == "" {
+			continue
+		}
+		subset.Addresses = append(subset.Addresses, &epmodel.EndpointSubset_EndpointAddress{
+			Ip:       pod.ip,
+			NodeName: node,
+			TargetRef: &epmodel.ObjectReference{
+				Kind:      "Pod",
+				Namespace: namespace,
+				Name:      pod.name,
+			},
+		})
+	}
+	return &epmodel.Endpoints{
+		Name:            fmt.Sprintf("stress-svc-%d", group),
+		Namespace:       namespace,
+		EndpointSubsets: []*epmodel.EndpointSubset{subset},
+	}
+}
+
+// policyModel returns the policy allowing the pods of the group following the given one to access
+// the given port of the pods of the group.
+func policyModel(namespace string, index, group, groups int, port int32) *policymodel.Policy {
+	selector := func(group int) *policymodel.Policy_LabelSelector {
+		return &policymodel.Policy_LabelSelector{
+			MatchLabel: []*policymodel.Policy_Label{{Key: "app", Value: appLabel(group)}},
+		}
+	}
+	return &policymodel.Policy{
+		Name:       fmt.Sprintf("stress-policy-%d", index),
+		Namespace:  namespace,
+		Pods:       selector(group),
+		PolicyType: policymodel.Policy_INGRESS,
+		IngressRule: []*policymodel.Policy_IngressRule{
+			{
+				Port: []*policymodel.Policy_Port{
+					{
+						Protocol: policymodel.Policy_Port_TCP,
+						Port: &policymodel.Policy_Port_PortNameOrNumber{
+							Type:   policymodel.Policy_Port_PortNameOrNumber_NUMBER,
+							Number: port,
+						},
+					},
+				},
+				From: []*policymodel.Policy_Peer{{Pods: selector((group + 1) % groups)}},
+			},
+		},
+	}
+}
+
+// nthIP returns the n-th address of the given IPv4 network, nil if out of the network.
+func nthIP(network *net.IPNet, n int) net.IP {
+	base := network.IP.To4()
+	if base == nil {
+		return nil
+	}
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(base)+uint32(n))
+	if !network.Contains(ip) {
+		return nil
+	}
+	return ip
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// latencies collects the durations of one kind of operation.
+type latencies struct {
+	sync.Mutex
+	samples []time.Duration
+	errors  int
+}
+
+// record adds the duration of one operation, failed operations are only counted.
+func (l *latencies) record(duration time.Duration, err error) {
+	l.Lock()
+	defer l.Unlock()
+	if err != nil {
+		l.errors++
+		return
+	}
+	l.samples = append(l.samples, duration)
+}
+
+// percentile returns the given percentile (0-100) of the recorded durations
+// using the nearest-rank method, 0 if nothing was recorded.
+func (l *latencies) percentile(p float64) time.Duration {
+	l.Lock()
+	defer l.Unlock()
+	if len(l.samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), l.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// report collects the latencies of all kinds of operations in the order
+// in which they were first recorded.
+type report struct {
+	sync.Mutex
+	ops   []string
+	stats map[string]*latencies
+}
+
+// newReport returns an empty report.
+func newReport() *report {
+	return &report{stats: make(map[string]*latencies)}
+}
+
+// op returns the latencies of the given kind of operation.
+func (r *report) op(name string) *latencies {
+	r.Lock()
+	defer r.Unlock()
+	stats, exists := r.stats[name]
+	if !exists {
+		stats = &latencies{}
+		r.stats[name] = stats
+		r.ops = append(r.ops, name)
+	}
+	return stats
+}
+
+// measure runs the given operation and records its duration.
+func (r *report) measure(name string, operation func() error) error {
+	start := time.Now()
+	err := operation()
+	r.op(name).record(time.Since(start), err)
+	return err
+}
+
+// print writes the table with latency percentiles of all operations.
+func (r *report) print(w io.Writer) {
+	r.Lock()
+	ops := append([]string(nil), r.ops...)
+	r.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPERATION\tCOUNT\tERRORS\tP50\tP90\tP99\tMAX")
+	for _, name := range ops {
+		stats := r.op(name)
+		stats.Lock()
+		count, errors := len(stats.samples), stats.errors
+		stats.Unlock()
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\t%v\t%v\n", name, count, errors,
+			stats.percentile(50), stats.percentile(90), stats.percentile(99), stats.percentile(100))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/contiv/vpp/plugins/contiv/model/cni"
+)
+
+var (
+	// command line flags
+	cniServer   = flag.String("cni", "localhost:9111", "Address of the CNI GRPC server of the contiv agent")
+	etcdConfig  = flag.String("etcd-config", "", "ETCD config file, K8s state churn is not generated if not set")
+	netns       = flag.String("netns", "/var/run/netns/contiv-stress", "Network namespace shared by the simulated pods")
+	pods        = flag.Int("pods", 1000, "Number of simulated pods")
+	concurrency = flag.Int("concurrency", 10, "Number of concurrently processed pods")
+	services    = flag.Int("services", 100, "Number of simulated services")
+	policies    = flag.Int("policies", 50, "Number of simulated policies")
+	rounds      = flag.Int("rounds", 1, "Number of rounds of adding and deleting the pods")
+	namespace   = flag.String("namespace", "contiv-stress", "K8s namespace of the simulated pods, services and policies")
+	node        = flag.String("node", "", "Name of the node the simulated pods are deployed on (host name by default)")
+	serviceNet  = flag.String("service-net", "10.96.128.0/20", "Network of the cluster IPs of the simulated services")
+	help        = flag.Bool("h", false, "Switch to show help")
+)
+
+const helpContent = `contiv-stress generates churn of pods, services, endpoints and policies against a running contiv agent
+and reports latency percentiles of the operations.
+Usage:
+  contiv-stress [flags]
+
+In each round all pods are added with CNI Add requests, services with endpoints selecting the pods and policies
+are created and updated, then everything is deleted again. Pods, services, endpoints and policies are written
+into ETCD the same way as contiv-ksr does, only if ETCD config is given. The agent can run with a real VPP
+or with the VPP mock.
+
+The CNI latencies include the configuration of the pod in VPP, the latencies of the K8s state changes
+only measure the writes into ETCD, the agent processes them asynchronously.
+
+Flags:
+  -cni [host:port]        Sets the CNI GRPC server of the contiv agent (default localhost:9111)
+  -etcd-config [file]     Sets the ETCD config file, e.g. cmd/contiv-ksr/etcdv3.conf (K8s churn is skipped if not set)
+  -netns [path]           Sets the network namespace shared by the pods (default /var/run/netns/contiv-stress)
+  -pods [count]           Sets the number of pods (default 1000)
+  -concurrency [count]    Sets the number of concurrently processed pods (default 10)
+  -services [count]       Sets the number of services (default 100)
+  -policies [count]       Sets the number of policies (default 50)
+  -rounds [count]         Sets the number of rounds (default 1)
+  -namespace [name]       Sets the K8s namespace of the simulated objects (default contiv-stress)
+  -node [name]            Sets the name of the node of the pods (host name by default)
+  -service-net [cidr]     Sets the network of the cluster IPs (default 10.96.128.0/20)
+  -h                      Prints this help
+`
+
+// main is the main method of contiv-stress
+func main() {
+	flag.Parse()
+	if *help {
+		fmt.Print(helpContent)
+		return
+	}
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run connects to the agent (and ETCD), generates the churn and prints the report.
+func run() error {
+	if *pods <= 0 || *concurrency <= 0 || *services < 0 || *policies < 0 || *rounds <= 0 {
+		return fmt.Errorf("pods, concurrency and rounds must be positive, services and policies must not be negative")
+	}
+	_, svcNet, err := net.ParseCIDR(*serviceNet)
+	if err != nil {
+		return fmt.Errorf("invalid service network %s: %v", *serviceNet, err)
+	}
+	nodeName := *node
+	if nodeName == "" {
+		if nodeName, err = os.Hostname(); err != nil {
+			return err
+		}
+	}
+
+	conn, err := grpc.Dial(*cniServer, grpc.WithInsecure())
+	if err != nil {
+		return fmt.Errorf("failed to connect to the CNI server %s: %v", *cniServer, err)
+	}
+	defer conn.Close()
+
+	s := &stress{
+		cni: &cniClient{
+			client:    cni.NewRemoteCNIClient(conn),
+			netns:     *netns,
+			namespace: *namespace,
+		},
+		namespace:   *namespace,
+		node:        nodeName,
+		serviceNet:  svcNet,
+		services:    *services,
+		policies:    *policies,
+		concurrency: *concurrency,
+		report:      newReport(),
+	}
+	for i := 0; i < *pods; i++ {
+		s.pods = append(s.pods, newSimPod(i))
+	}
+	if *etcdConfig != "" {
+		if s.store, err = newKsrStore(*etcdConfig); err != nil {
+			return err
+		}
+		defer s.store.close()
+	}
+
+	start := time.Now()
+	for round := 1; round <= *rounds; round++ {
+		if err := s.runRound(); err != nil {
+			return fmt.Errorf("round %d failed: %v", round, err)
+		}
+	}
+	fmt.Printf("%d round(s) with %d pods, %d services and %d policies finished in %v\n\n",
+		*rounds, *pods, *services, *policies, time.Since(start))
+	s.report.print(os.Stdout)
+	return nil
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	epmodel "github.com/contiv/vpp/plugins/ksr/model/endpoints"
+	nsmodel "github.com/contiv/vpp/plugins/ksr/model/namespace"
+	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
+	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
+	svcmodel "github.com/contiv/vpp/plugins/ksr/model/service"
+)
+
+// Names of the measured operations. The K8s operations include both the puts
+// and the deletes of the objects.
+const (
+	opCNIAdd       = "cni-add"
+	opCNIDel       = "cni-del"
+	opKsrPod       = "ksr-pod"
+	opKsrService   = "ksr-service"
+	opKsrEndpoints = "ksr-endpoints"
+	opKsrPolicy    = "ksr-policy"
+)
+
+// stress generates the churn of pods, services, endpoints and policies.
+// Failed operations are counted in the report, they do not stop the test.
+type stress struct {
+	cni   *cniClient
+	store *ksrStore // nil if K8s state churn is not generated
+
+	namespace   string
+	node        string
+	serviceNet  *net.IPNet
+	services    int
+	policies    int
+	concurrency int
+
+	pods   []*simPod
+	report *report
+}
+
+// groups returns the number of groups of pods, each group is selected by one service.
+func (s *stress) groups() int {
+	if s.services > 0 {
+		return s.services
+	}
+	return 1
+}
+
+// runRound adds all pods, creates and updates services, endpoints and policies,
+// then deletes all of them.
+func (s *stress) runRound() error {
+	if s.store != nil {
+		ns := namespaceModel(s.namespace)
+		if err := s.store.broker.Put(nsmodel.Key(ns.Name), ns); err != nil {
+			return fmt.Errorf("failed to put namespace %s: %v", ns.Name, err)
+		}
+	}
+
+	s.forEachPod(func(pod *simPod) {
+		if s.report.measure(opCNIAdd, func() error { return s.cni.add(pod) }) != nil || s.store == nil {
+			return
+		}
+		model := podModel(s.namespace, pod, pod.index%s.groups())
+		s.report.measure(opKsrPod, func() error {
+			return s.store.broker.Put(podmodel.Key(model.Name, model.Namespace), model)
+		})
+	})
+
+	if s.store != nil {
+		if err := s.putServices(); err != nil {
+			return err
+		}
+		s.putPolicies()
+	}
+
+	s.forEachPod(func(pod *simPod) {
+		s.report.measure(opCNIDel, func() error { return s.cni.del(pod) })
+		if s.store == nil {
+			return
+		}
+		s.report.measure(opKsrPod, func() error {
+			_, err := s.store.broker.Delete(podmodel.Key(pod.name, s.namespace))
+			return err
+		})
+	})
+
+	if s.store != nil {
+		s.deleteServicesAndPolicies()
+	}
+	return nil
+}
+
+// forEachPod runs the given function for all pods using the configured number of workers.
+func (s *stress) forEachPod(f func(pod *simPod)) {
+	queue := make(chan *simPod)
+	var wg sync.WaitGroup
+	for i := 0; i < s.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pod := range queue {
+				f(pod)
+			}
+		}()
+	}
+	for _, pod := range s.pods {
+		queue <- pod
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// putServices creates the services with the endpoints pointing to the pods of their groups.
+func (s *stress) putServices() error {
+	backends := make([][]*simPod, s.groups())
+	for _, pod := range s.pods {
+		group := pod.index % s.groups()
+		backends[group] = append(backends[group], pod)
+	}
+	for group := 0; group < s.services; group++ {
+		clusterIP := nthIP(s.serviceNet, group+1)
+		if clusterIP == nil {
+			return fmt.Errorf("service network %v is too small for %d services", s.serviceNet, s.services)
+		}
+		service := serviceModel(s.namespace, group, clusterIP)
+		s.report.measure(opKsrService, func() error {
+			return s.store.broker.Put(svcmodel.Key(service.Name, service.Namespace), service)
+		})
+		endpoints := endpointsModel(s.namespace, group, s.node, backends[group])
+		s.report.measure(opKsrEndpoints, func() error {
+			return s.store.broker.Put(epmodel.Key(endpoints.Name, endpoints.Namespace), endpoints)
+		})
+	}
+	return nil
+}
+
+// putPolicies creates the policies and then updates them to allow a different port.
+func (s *stress) putPolicies() {
+	for _, port := range []int32{backendPort, backendPort + 1} {
+		for index := 0; index < s.policies; index++ {
+			policy := policyModel(s.namespace, index, index%s.groups(), s.groups(), port)
+			s.report.measure(opKsrPolicy, func() error {
+				return s.store.broker.Put(policymodel.Key(policy.Name, policy.Namespace), policy)
+			})
+		}
+	}
+}
+
+// deleteServicesAndPolicies deletes the endpoints, services and policies created by the round.
+func (s *stress) deleteServicesAndPolicies() {
+	for group := 0; group < s.services; group++ {
+		name := serviceModel(s.namespace, group, nil).Name
+		s.report.measure(opKsrEndpoints, func() error {
+			_, err := s.store.broker.Delete(epmodel.Key(name, s.namespace))
+			return err
+		})
+		s.report.measure(opKsrService, func() error {
+			_, err := s.store.broker.Delete(svcmodel.Key(name, s.namespace))
+			return err
+		})
+	}
+	for index := 0; index < s.policies; index++ {
+		name := policyModel(s.namespace, index, 0, s.groups(), backendPort).Name
+		s.report.measure(opKsrPolicy, func() error {
+			_, err := s.store.broker.Delete(policymodel.Key(name, s.namespace))
+			return err
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/contiv/vpp/plugins/contiv/model/cni"
+)
+
+func TestLatencies(t *testing.T) {
+	RegisterTestingT(t)
+
+	stats := &latencies{}
+	Expect(stats.percentile(50)).To(BeZero())
+	for i := 100; i >= 1; i-- {
+		stats.record(time.Duration(i)*time.Millisecond, nil)
+	}
+	stats.record(time.Hour, errors.New("failed"))
+
+	Expect(stats.errors).To(Equal(1))
+	Expect(stats.percentile(50)).To(Equal(50 * time.Millisecond))
+	Expect(stats.percentile(99)).To(Equal(99 * time.Millisecond))
+	Expect(stats.percentile(100)).To(Equal(100 * time.Millisecond))
+	Expect(stats.percentile(0)).To(Equal(time.Millisecond))
+}
+
+func TestReport(t *testing.T) {
+	RegisterTestingT(t)
+
+	r := newReport()
+	Expect(r.measure(opCNIAdd, func() error { return nil })).To(Succeed())
+	Expect(r.measure(opCNIDel, func() error { return errors.New("failed") })).ToNot(Succeed())
+	r.measure(opCNIAdd, func() error { return nil })
+
+	out := &bytes.Buffer{}
+	r.print(out)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	Expect(lines).To(HaveLen(3))
+	Expect(strings.Fields(lines[0])).To(Equal([]string{"OPERATION", "COUNT", "ERRORS", "P50", "P90", "P99", "MAX"}))
+	Expect(strings.Fields(lines[1])[:3]).To(Equal([]string{opCNIAdd, "2", "0"}))
+	Expect(strings.Fields(lines[2])[:3]).To(Equal([]string{opCNIDel, "0", "1"}))
+}
+
+func TestModels(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, network, _ := net.ParseCIDR("10.96.0.0/30")
+	Expect(nthIP(network, 1).String()).To(Equal("10.96.0.1"))
+	Expect(nthIP(network, 4)).To(BeNil())
+
+	pods := []*simPod{newSimPod(0), newSimPod(2)}
+	pods[0].ip = "10.1.1.2"
+	Expect(pods[1].ifName).To(Equal("stress2"))
+
+	service := serviceModel("stress", 0, nthIP(network, 1))
+	endpoints := endpointsModel("stress", 0, "node1", pods)
+	Expect(endpoints.Name).To(Equal(service.Name))
+	Expect(endpoints.EndpointSubsets[0].Addresses).To(HaveLen(1))
+	Expect(endpoints.EndpointSubsets[0].Addresses[0].Ip).To(Equal("10.1.1.2"))
+	Expect(podModel("stress", pods[0], 0).Label[0].Value).To(Equal(service.Selector["app"]))
+
+	policy := policyModel("stress", 3, 1, 2, backendPort)
+	Expect(policy.Pods.MatchLabel[0].Value).To(Equal(appLabel(1)))
+	Expect(policy.IngressRule[0].From[0].Pods.MatchLabel[0].Value).To(Equal(appLabel(0)))
+}
+
+func TestPodIP(t *testing.T) {
+	RegisterTestingT(t)
+
+	reply := &cni.CNIReply{
+		Interfaces: []*cni.CNIReply_Interface{
+			{IpAddresses: []*cni.CNIReply_Interface_IP{{Address: "10.1.1.5/32"}}},
+		},
+	}
+	Expect(podIP(reply)).To(Equal("10.1.1.5"))
+	Expect(podIP(&cni.CNIReply{})).To(BeEmpty())
+}
@@ -20,8 +20,11 @@ import (
 	"github.com/ligato/cn-infra/flavors/local"
 
 	"github.com/contiv/vpp/flavors/ksr"
+	"github.com/contiv/vpp/plugins/consul"
 	"github.com/contiv/vpp/plugins/contiv"
+	ksrplugin "github.com/contiv/vpp/plugins/ksr"
 	"github.com/contiv/vpp/plugins/kvdbproxy"
+	"github.com/contiv/vpp/plugins/kvstore"
 	"github.com/contiv/vpp/plugins/policy"
 	"github.com/contiv/vpp/plugins/service"
 	"github.com/contiv/vpp/plugins/statscollector"
@@ -31,7 +34,6 @@ import (
 	"github.com/ligato/cn-infra/datasync/kvdbsync"
 	local_sync "github.com/ligato/cn-infra/datasync/kvdbsync/local"
 	"github.com/ligato/cn-infra/datasync/resync"
-	"github.com/ligato/cn-infra/db/keyval/etcdv3"
 	"github.com/ligato/cn-infra/flavors/connectors"
 	"github.com/ligato/cn-infra/health/probe"
 	"github.com/ligato/cn-infra/rpc/grpc"
@@ -50,6 +52,41 @@ const (
 
 	// ContivConfigPathUsage explains the purpose of 'kube-config' flag.
 	ContivConfigPathUsage = "Path to the Agent's Contiv plugin configuration yaml file."
+
+	// ServiceConfigPath is the default location of Agent's Service plugin configuration. This path reflects
+	// configuration in k8s/contiv-vpp.yaml.
+	ServiceConfigPath = "/etc/agent/service.yaml"
+
+	// ServiceConfigPathUsage explains the purpose of 'service-config' flag.
+	ServiceConfigPathUsage = "Path to the Agent's Service plugin configuration yaml file."
+
+	// PolicyConfigPath is the default location of Agent's Policy plugin configuration. This path reflects
+	// configuration in k8s/contiv-vpp.yaml.
+	PolicyConfigPath = "/etc/agent/policy.yaml"
+
+	// PolicyConfigPathUsage explains the purpose of 'policy-config' flag.
+	PolicyConfigPathUsage = "Path to the Agent's Policy plugin configuration yaml file."
+
+	// KVStoreConfigPath is the default location of Agent's KVStore plugin configuration. This path reflects
+	// configuration in k8s/contiv-vpp.yaml.
+	KVStoreConfigPath = "/etc/agent/kvstore.yaml"
+
+	// KVStoreConfigPathUsage explains the purpose of 'kvstore-config' flag.
+	KVStoreConfigPathUsage = "Path to the Agent's KVStore plugin configuration yaml file."
+
+	// K8sStateConfigPath is the default location of the configuration of the KSR running inside the Agent.
+	// The agent runs in the direct K8s API mode (without contiv-ksr) only if the file is present.
+	K8sStateConfigPath = "/etc/agent/ksr.yaml"
+
+	// K8sStateConfigPathUsage explains the purpose of 'ksr-config' flag.
+	K8sStateConfigPathUsage = "Path to the configuration yaml file of the KSR running inside the Agent, " +
+		"enables the direct K8s API mode."
+
+	// KubeConfigPath is the default location of the kubeconfig used in the direct K8s API mode.
+	KubeConfigPath = "/etc/agent/kubeconfig"
+
+	// KubeConfigPathUsage explains the purpose of 'kube-config' flag.
+	KubeConfigPathUsage = "Path to the kubeconfig used in the direct K8s API mode, in-cluster config is used if not found."
 )
 
 // NewAgent returns a new instance of the Agent with plugins.
@@ -73,8 +110,18 @@ type FlavorContiv struct {
 	HealthRPC  probe.Plugin
 	Prometheus prometheus.Plugin
 
-	ETCD            etcdv3.Plugin
-	ETCDDataSync    kvdbsync.Plugin
+	// Either etcd or Consul is used as the key-value data store,
+	// depending on which of them has the configuration file present.
+	ETCD    kvstore.ETCDPlugin
+	Consul  consul.Plugin
+	KVStore kvstore.Plugin
+
+	// K8s state is read from the data store (reflected by contiv-ksr),
+	// or in the direct K8s API mode reflected into memory by KSR running
+	// inside the agent. K8sState has to be listed before the watchers.
+	K8sState ksrplugin.StateStore
+
+	KVDataSync      kvdbsync.Plugin
 	NodeIDDataSync  kvdbsync.Plugin
 	ServiceDataSync kvdbsync.Plugin
 	PolicyDataSync  kvdbsync.Plugin
@@ -126,23 +173,36 @@ func (f *FlavorContiv) Inject() bool {
 	f.HealthRPC.Deps.StatusCheck = &f.StatusCheck
 
 	f.ETCD.Deps.PluginInfraDeps = *f.InfraDeps("etcdv3", local.WithConf())
-	connectors.InjectKVDBSync(&f.ETCDDataSync, &f.ETCD, f.ETCD.PluginName, f.FlavorLocal, &f.ResyncOrch)
-	f.NodeIDDataSync = f.ETCDDataSync
+	f.Consul.Deps.PluginInfraDeps = *f.InfraDeps("consul", local.WithConf())
+	f.KVStore.Deps.PluginInfraDeps = *f.InfraDeps("kvstore",
+		local.WithConf(KVStoreConfigPath, KVStoreConfigPathUsage))
+	f.KVStore.Deps.Stores = []kvstore.KvStore{&f.ETCD, &f.Consul}
+	f.K8sState.StateStoreDeps.PluginInfraDeps = *f.InfraDeps("ksr",
+		local.WithConf(K8sStateConfigPath, K8sStateConfigPathUsage))
+	f.K8sState.StateStoreDeps.PluginInfraDeps.ServiceLabel = servicelabel.OfDifferentAgent(ksr.MicroserviceLabel)
+	f.K8sState.StateStoreDeps.KubeConfig = config.ForPlugin("kube", KubeConfigPath, KubeConfigPathUsage)
+	f.K8sState.StateStoreDeps.DataStore = &f.KVStore
+	connectors.InjectKVDBSync(&f.KVDataSync, &f.KVStore, f.KVStore.PluginName, f.FlavorLocal, &f.ResyncOrch)
+	f.NodeIDDataSync = f.KVDataSync
 	f.NodeIDDataSync.PluginInfraDeps = *f.InfraDeps("nodeid-datasync")
 	f.NodeIDDataSync.Deps.PluginInfraDeps.ServiceLabel = servicelabel.OfDifferentAgent(ksr.MicroserviceLabel)
-	f.PolicyDataSync = f.ETCDDataSync
+	f.NodeIDDataSync.Deps.KvPlugin = &f.K8sState
+	f.PolicyDataSync = f.KVDataSync
 	f.PolicyDataSync.PluginInfraDeps = *f.InfraDeps("policy-datasync")
 	f.PolicyDataSync.Deps.PluginInfraDeps.ServiceLabel = servicelabel.OfDifferentAgent(ksr.MicroserviceLabel)
-	f.ServiceDataSync = f.ETCDDataSync
+	f.PolicyDataSync.Deps.KvPlugin = &f.K8sState
+	f.ServiceDataSync = f.KVDataSync
 	f.ServiceDataSync.PluginInfraDeps = *f.InfraDeps("service-datasync")
 	f.ServiceDataSync.Deps.PluginInfraDeps.ServiceLabel = servicelabel.OfDifferentAgent(ksr.MicroserviceLabel)
+	f.ServiceDataSync.Deps.KvPlugin = &f.K8sState
 
-	f.KVProxy.Deps.PluginInfraDeps = *f.FlavorLocal.InfraDeps("kvproxy")
-	f.KVProxy.Deps.KVDB = &f.ETCDDataSync
+	f.KVProxy.Deps.PluginInfraDeps = *f.FlavorLocal.InfraDeps("kvproxy", local.WithConf())
+	f.KVProxy.Deps.KVDB = &f.KVDataSync
 
 	f.Stats.Deps.PluginInfraDeps = *f.FlavorLocal.InfraDeps("stats")
 	f.Stats.Deps.Contiv = &f.Contiv
 	f.Stats.Deps.Prometheus = &f.Prometheus
+	f.Stats.Deps.GoVPP = &f.GoVPP
 
 	f.GoVPP.Deps.PluginInfraDeps = *f.FlavorLocal.InfraDeps("govpp", local.WithConf())
 	f.Linux.Watcher = &datasync.CompositeKVProtoWatcher{Adapters: []datasync.KeyValProtoWatcher{&f.KVProxy, local_sync.Get()}}
@@ -167,18 +227,25 @@ func (f *FlavorContiv) Inject() bool {
 	f.Contiv.Deps.GoVPP = &f.GoVPP
 	f.Contiv.Deps.VPP = &f.VPP
 	f.Contiv.Deps.Resync = &f.ResyncOrch
-	f.Contiv.Deps.ETCD = &f.ETCD
+	f.Contiv.Deps.KVStore = &f.K8sState
 	f.Contiv.Deps.Watcher = &f.NodeIDDataSync
+	f.Contiv.Deps.HTTPHandlers = &f.HTTP
+	f.Contiv.Deps.Prometheus = &f.Prometheus
 	f.Contiv.Deps.PluginConfig = config.ForPlugin("contiv", ContivConfigPath, ContivConfigPathUsage)
 
-	f.Policy.Deps.PluginInfraDeps = *f.FlavorLocal.InfraDeps("policy")
+	f.Policy.Deps.PluginInfraDeps = *f.FlavorLocal.InfraDeps("policy",
+		local.WithConf(PolicyConfigPath, PolicyConfigPathUsage))
 	f.Policy.Deps.Resync = &f.ResyncOrch
 	f.Policy.Deps.Watcher = &f.PolicyDataSync
 	f.Policy.Deps.Contiv = &f.Contiv
 	f.Policy.Deps.GoVPP = &f.GoVPP
 	f.Policy.Deps.VPP = &f.VPP
+	f.Policy.Deps.Prometheus = &f.Prometheus
+	f.Policy.Deps.HTTPHandlers = &f.HTTP
+	f.Policy.Deps.KVStore = &f.K8sState
 
-	f.Service.Deps.PluginInfraDeps = *f.FlavorLocal.InfraDeps("service")
+	f.Service.Deps.PluginInfraDeps = *f.FlavorLocal.InfraDeps("service",
+		local.WithConf(ServiceConfigPath, ServiceConfigPathUsage))
 	f.Service.Deps.Resync = &f.ResyncOrch
 	f.Service.Deps.Watcher = &f.ServiceDataSync
 	f.Service.Deps.Contiv = &f.Contiv
@@ -16,11 +16,12 @@
 package ksr
 
 import (
+	"github.com/contiv/vpp/plugins/consul"
 	"github.com/contiv/vpp/plugins/ksr"
+	"github.com/contiv/vpp/plugins/kvstore"
 	"github.com/ligato/cn-infra/config"
 	"github.com/ligato/cn-infra/core"
 	"github.com/ligato/cn-infra/datasync/kvdbsync"
-	"github.com/ligato/cn-infra/db/keyval/etcdv3"
 	"github.com/ligato/cn-infra/flavors/connectors"
 	"github.com/ligato/cn-infra/flavors/local"
 	"github.com/ligato/cn-infra/flavors/rpc"
@@ -57,9 +58,12 @@ type FlavorKsr struct {
 	*local.FlavorLocal
 	// RPC flavor for REST-based management.
 	*rpc.FlavorRPC
-	// Plugins for access to ETCD data store.
-	ETCD         etcdv3.Plugin
-	ETCDDataSync kvdbsync.Plugin
+	// Plugins for access to the key-value data store - either etcd or Consul,
+	// depending on which of them has the configuration file present.
+	ETCD       kvstore.ETCDPlugin
+	Consul     consul.Plugin
+	KVStore    kvstore.Plugin
+	KVDataSync kvdbsync.Plugin
 	// Kubernetes State Reflector plugin works as a reflector for policies, pods
 	// and namespaces.
 	Ksr ksr.Plugin
@@ -85,12 +89,16 @@ func (f *FlavorKsr) Inject() (allReadyInjected bool) {
 	f.FlavorRPC.Inject()
 
 	f.ETCD.Deps.PluginInfraDeps = *f.InfraDeps("etcdv3", local.WithConf())
-	connectors.InjectKVDBSync(&f.ETCDDataSync, &f.ETCD, f.ETCD.PluginName, f.FlavorLocal, nil)
+	f.Consul.Deps.PluginInfraDeps = *f.InfraDeps("consul", local.WithConf())
+	f.KVStore.Deps.PluginInfraDeps = *f.InfraDeps("kvstore", local.WithConf())
+	f.KVStore.Deps.Stores = []kvstore.KvStore{&f.ETCD, &f.Consul}
+	connectors.InjectKVDBSync(&f.KVDataSync, &f.KVStore, f.KVStore.PluginName, f.FlavorLocal, nil)
 
-	f.Ksr.Deps.PluginInfraDeps = *f.FlavorLocal.InfraDeps("ksr")
+	f.Ksr.Deps.PluginInfraDeps = *f.FlavorLocal.InfraDeps("ksr", local.WithConf())
 	// Reuse ForPlugin to define configuration file for 3rd party library (k8s client).
 	f.Ksr.Deps.KubeConfig = config.ForPlugin("kube", KubeConfigAdmin, KubeConfigUsage)
-	f.Ksr.Deps.Publish = &f.ETCDDataSync
+	f.Ksr.Deps.Publish = &f.KVDataSync
+	f.Ksr.Deps.Prometheus = &f.Prometheus
 	f.Ksr.StatusMonitor = &f.StatusCheck // Inject status check
 
 	return true
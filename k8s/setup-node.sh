@@ -88,6 +88,12 @@ unix {
 dpdk {
    dev $pciAddr
 }
+nat {
+   translation hash buckets 1048576
+   translation hash memory 268435456
+   user hash buckets 1024
+   max translations per user 10000
+}
 "
    echo "$startup"
 
@@ -19,6 +19,8 @@ type MockContiv struct {
 	hostInterconnect string
 	vxlanBVIIfName   string
 	containerIndex   *containeridx.ConfigIndex
+	advertisedRoutes map[string]*net.IPNet
+	reportedEvents   []string
 }
 
 // NewMockContiv is a constructor for MockContiv.
@@ -28,6 +30,8 @@ func NewMockContiv() *MockContiv {
 		podIf:          make(map[podmodel.ID]string),
 		podNs:          make(map[podmodel.ID]uint32),
 		containerIndex: ci,
+
+		advertisedRoutes: make(map[string]*net.IPNet),
 	}
 }
 
@@ -127,3 +131,27 @@ func (mc *MockContiv) GetHostInterconnectIfName() string {
 func (mc *MockContiv) GetVxlanBVIIfName() string {
 	return mc.vxlanBVIIfName
 }
+
+// AdvertiseRoute remembers the advertised routes, they can be read using GetAdvertisedRoutes.
+func (mc *MockContiv) AdvertiseRoute(prefix *net.IPNet, advertise bool) {
+	if advertise {
+		mc.advertisedRoutes[prefix.String()] = prefix
+	} else {
+		delete(mc.advertisedRoutes, prefix.String())
+	}
+}
+
+// ReportK8sEvent remembers the reported events, they can be read using GetReportedEvents.
+func (mc *MockContiv) ReportK8sEvent(podNamespace, podName, reason, message string) {
+	mc.reportedEvents = append(mc.reportedEvents, reason+": "+message)
+}
+
+// GetReportedEvents returns the reasons and messages of the events reported using ReportK8sEvent.
+func (mc *MockContiv) GetReportedEvents() []string {
+	return mc.reportedEvents
+}
+
+// GetAdvertisedRoutes returns the prefixes advertised using AdvertiseRoute.
+func (mc *MockContiv) GetAdvertisedRoutes() map[string]*net.IPNet {
+	return mc.advertisedRoutes
+}
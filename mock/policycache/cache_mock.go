@@ -3,7 +3,9 @@ package policycache
 import (
 	"github.com/ligato/cn-infra/datasync"
 
+	"github.com/contiv/vpp/plugins/ksr/model/custompolicy"
 	nsmodel "github.com/contiv/vpp/plugins/ksr/model/namespace"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
 	"github.com/contiv/vpp/plugins/policy/cache"
@@ -105,3 +107,18 @@ func (mpc *MockPolicyCache) LookupNamespacesByLabelSelector(nsLabelSelector stri
 func (mpc *MockPolicyCache) ListAllNamespaces() (namespaces []nsmodel.ID) {
 	return nil
 }
+
+// LookupCustomPolicy is not implemented by the mock.
+func (mpc *MockPolicyCache) LookupCustomPolicy(policy custompolicy.ID) (found bool, data *custompolicy.CustomNetworkPolicy) {
+	return false, nil
+}
+
+// ListAllCustomPolicies is not implemented by the mock.
+func (mpc *MockPolicyCache) ListAllCustomPolicies() (policies []custompolicy.ID) {
+	return nil
+}
+
+// LookupNode is not implemented by the mock.
+func (mpc *MockPolicyCache) LookupNode(node nodemodel.ID) (found bool, data *nodemodel.Node) {
+	return false, nil
+}
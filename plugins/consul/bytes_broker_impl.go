@@ -0,0 +1,592 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/logging"
+)
+
+const (
+	// minSessionTTL is the lowest session TTL accepted by Consul.
+	minSessionTTL = 10 * time.Second
+)
+
+// BytesConnectionConsul allows to store, read and watch values from Consul.
+type BytesConnectionConsul struct {
+	logging.Logger
+	client    *http.Client
+	baseURL   *url.URL
+	token     string
+	dc        string
+	opTimeout time.Duration
+
+	// sessions maps keys put with TTL to the Consul sessions they are bound to.
+	sessions     map[string]string
+	sessionsLock sync.Mutex
+
+	// closeCh is closed when the connection is closed to stop the watchers.
+	closeCh chan struct{}
+	closed  bool
+}
+
+// kvEntry is a single key-value pair as returned by the Consul KV API.
+type kvEntry struct {
+	Key         string
+	Value       []byte
+	ModifyIndex uint64
+	Session     string
+}
+
+// NewConsulConnectionWithBytes creates a new connection to Consul with the given
+// configuration.
+func NewConsulConnectionWithBytes(config *Config, log logging.Logger) (*BytesConnectionConsul, error) {
+	baseURL, err := config.baseURL()
+	if err != nil {
+		return nil, err
+	}
+	opTimeout := config.OpTimeout
+	if opTimeout == 0 {
+		opTimeout = defaultOpTimeout
+	}
+	return &BytesConnectionConsul{
+		Logger:    log,
+		client:    &http.Client{},
+		baseURL:   baseURL,
+		token:     config.Token,
+		dc:        config.Datacenter,
+		opTimeout: opTimeout,
+		sessions:  make(map[string]string),
+		closeCh:   make(chan struct{}),
+	}, nil
+}
+
+// Close stops all watchers and destroys the sessions created for keys with TTL.
+// The keys bound to the sessions are removed by Consul.
+func (db *BytesConnectionConsul) Close() error {
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+	close(db.closeCh)
+
+	db.sessionsLock.Lock()
+	defer db.sessionsLock.Unlock()
+	for key, session := range db.sessions {
+		if _, err := db.request(http.MethodPut, "/v1/session/destroy/"+session, nil, nil); err != nil {
+			db.Warnf("Failed to destroy session of the key %s: %v", key, err)
+		}
+	}
+	db.sessions = make(map[string]string)
+	return nil
+}
+
+// NewBroker creates a new instance of a proxy that provides access to Consul.
+// The given prefix will be prepended to the key argument in all calls.
+func (db *BytesConnectionConsul) NewBroker(prefix string) keyval.BytesBroker {
+	return &BytesBrokerWatcherConsul{prefix: prefix, delegate: db}
+}
+
+// NewWatcher creates a new instance of a proxy that provides access to Consul.
+// The given prefix will be prepended to the key argument in all calls.
+func (db *BytesConnectionConsul) NewWatcher(prefix string) keyval.BytesWatcher {
+	return &BytesBrokerWatcherConsul{prefix: prefix, delegate: db}
+}
+
+// Put writes the provided key-value item into the data store.
+// With datasync.WithTTL option the item is removed once TTL elapses,
+// unless it is put again.
+func (db *BytesConnectionConsul) Put(key string, data []byte, opts ...datasync.PutOption) error {
+	if db.closed {
+		return fmt.Errorf("Put(%s) called on a closed connection", key)
+	}
+	for _, opt := range opts {
+		if ttlOpt, ok := opt.(*datasync.WithTTLOpt); ok && ttlOpt.TTL > 0 {
+			return db.putWithTTL(key, data, ttlOpt.TTL)
+		}
+	}
+	_, err := db.putKV(key, data, nil)
+	return err
+}
+
+// putWithTTL writes the item bound to a session with the given TTL.
+func (db *BytesConnectionConsul) putWithTTL(key string, data []byte, ttl time.Duration) error {
+	db.sessionsLock.Lock()
+	defer db.sessionsLock.Unlock()
+
+	session, hasSession := db.sessions[key]
+	if hasSession {
+		renewed, err := db.renewSession(session)
+		if err != nil {
+			return err
+		}
+		if !renewed {
+			// Session has expired in the meantime.
+			hasSession = false
+		}
+	}
+	if !hasSession {
+		var err error
+		session, err = db.createSession(ttl)
+		if err != nil {
+			return err
+		}
+		db.sessions[key] = session
+	}
+
+	acquired, err := db.putKV(key, data, url.Values{"acquire": {session}})
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return fmt.Errorf("key %s is bound to a session of another client", key)
+	}
+	return nil
+}
+
+// createSession creates a new session that deletes its keys once TTL elapses.
+func (db *BytesConnectionConsul) createSession(ttl time.Duration) (session string, err error) {
+	if ttl < minSessionTTL {
+		ttl = minSessionTTL
+	}
+	body, err := json.Marshal(map[string]string{
+		"TTL":       ttl.String(),
+		"Behavior":  "delete",
+		"LockDelay": "0s",
+	})
+	if err != nil {
+		return "", err
+	}
+	resp, err := db.request(http.MethodPut, "/v1/session/create", nil, body)
+	if err != nil {
+		return "", err
+	}
+	created := struct{ ID string }{}
+	if err := json.Unmarshal(resp, &created); err != nil {
+		return "", fmt.Errorf("invalid response to session create: %v", err)
+	}
+	return created.ID, nil
+}
+
+// renewSession resets TTL of the session. Returns false if the session no longer exists.
+func (db *BytesConnectionConsul) renewSession(session string) (renewed bool, err error) {
+	_, err = db.request(http.MethodPut, "/v1/session/renew/"+session, nil, nil)
+	if err == errNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// KeepAlive renews the session the given key is bound to, the key is not rewritten.
+// Returns false if the key does not exist or its session has expired.
+func (db *BytesConnectionConsul) KeepAlive(key string) (found bool, err error) {
+	if db.closed {
+		return false, fmt.Errorf("KeepAlive(%s) called on a closed connection", key)
+	}
+	db.sessionsLock.Lock()
+	defer db.sessionsLock.Unlock()
+
+	session, hasSession := db.sessions[key]
+	if !hasSession {
+		// the key does not expire
+		_, found, _, err = db.GetValue(key)
+		return found, err
+	}
+	renewed, err := db.renewSession(session)
+	if err == nil && !renewed {
+		// the key was deleted with the session
+		delete(db.sessions, key)
+	}
+	return renewed, err
+}
+
+// PutIfNotExists puts the given key-value item if the key does not exist yet.
+func (db *BytesConnectionConsul) PutIfNotExists(key string, data []byte) (succeeded bool, err error) {
+	if db.closed {
+		return false, fmt.Errorf("PutIfNotExists(%s) called on a closed connection", key)
+	}
+	return db.putKV(key, data, url.Values{"cas": {"0"}})
+}
+
+// DeleteIfNotChanged removes the key only if its modify index equals the given revision.
+func (db *BytesConnectionConsul) DeleteIfNotChanged(key string, revision int64) (deleted bool, err error) {
+	if db.closed {
+		return false, fmt.Errorf("DeleteIfNotChanged(%s) called on a closed connection", key)
+	}
+	resp, err := db.request(http.MethodDelete, kvPath(key), url.Values{"cas": {strconv.FormatInt(revision, 10)}}, nil)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(resp)) == "true", nil
+}
+
+// putKV writes the value of the key with the given query parameters.
+// Returns the boolean result of the (conditional) write.
+func (db *BytesConnectionConsul) putKV(key string, data []byte, query url.Values) (bool, error) {
+	resp, err := db.request(http.MethodPut, kvPath(key), query, data)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(resp)) == "true", nil
+}
+
+// GetValue retrieves one item under the provided key.
+func (db *BytesConnectionConsul) GetValue(key string) (data []byte, found bool, revision int64, err error) {
+	if db.closed {
+		return nil, false, 0, fmt.Errorf("GetValue(%s) called on a closed connection", key)
+	}
+	entries, err := db.getKVWithTimeout(key, nil)
+	if err != nil || len(entries) == 0 {
+		return nil, false, 0, err
+	}
+	return entries[0].Value, true, int64(entries[0].ModifyIndex), nil
+}
+
+// ListValues returns an iterator that enables to traverse all items stored
+// under the provided <key>.
+func (db *BytesConnectionConsul) ListValues(key string) (keyval.BytesKeyValIterator, error) {
+	return db.listValues(key, nil)
+}
+
+func (db *BytesConnectionConsul) listValues(key string, trimPrefix func(string) string) (keyval.BytesKeyValIterator, error) {
+	if db.closed {
+		return nil, fmt.Errorf("ListValues(%s) called on a closed connection", key)
+	}
+	entries, err := db.getKVWithTimeout(key, url.Values{"recurse": {""}})
+	if err != nil {
+		return nil, err
+	}
+	it := &bytesKeyValIterator{}
+	for _, entry := range entries {
+		entryKey := fromConsulKey(key, entry.Key)
+		if trimPrefix != nil {
+			entryKey = trimPrefix(entryKey)
+		}
+		it.values = append(it.values, &bytesKeyVal{key: entryKey, value: entry.Value, rev: int64(entry.ModifyIndex)})
+	}
+	return it, nil
+}
+
+// ListKeys returns an iterator that allows to traverse all keys from data
+// store that share the given <prefix>.
+func (db *BytesConnectionConsul) ListKeys(prefix string) (keyval.BytesKeyIterator, error) {
+	return db.listKeys(prefix, nil)
+}
+
+func (db *BytesConnectionConsul) listKeys(prefix string, trimPrefix func(string) string) (keyval.BytesKeyIterator, error) {
+	if db.closed {
+		return nil, fmt.Errorf("ListKeys(%s) called on a closed connection", prefix)
+	}
+	entries, err := db.getKVWithTimeout(prefix, url.Values{"recurse": {""}})
+	if err != nil {
+		return nil, err
+	}
+	it := &bytesKeyIterator{}
+	for _, entry := range entries {
+		entryKey := fromConsulKey(prefix, entry.Key)
+		if trimPrefix != nil {
+			entryKey = trimPrefix(entryKey)
+		}
+		it.keys = append(it.keys, entryKey)
+		it.revs = append(it.revs, int64(entry.ModifyIndex))
+	}
+	return it, nil
+}
+
+// Delete removes data stored under the <key>.
+// With datasync.WithPrefix option all items with the given prefix are removed.
+func (db *BytesConnectionConsul) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	if db.closed {
+		return false, fmt.Errorf("Delete(%s) called on a closed connection", key)
+	}
+	query := url.Values{}
+	for _, opt := range opts {
+		if _, ok := opt.(*datasync.WithPrefixOpt); ok {
+			query.Set("recurse", "")
+		}
+	}
+	entries, err := db.getKVWithTimeout(key, query)
+	if err != nil {
+		return false, err
+	}
+	if len(entries) == 0 {
+		return false, nil
+	}
+	if _, err = db.request(http.MethodDelete, kvPath(key), query, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NewTxn creates a new transaction.
+func (db *BytesConnectionConsul) NewTxn() keyval.BytesTxn {
+	return &bytesTxn{db: db}
+}
+
+// Watch starts subscription for changes associated with the selected keys.
+// Watch events will be delivered to the <resp> callback.
+func (db *BytesConnectionConsul) Watch(resp func(keyval.BytesWatchResp), closeChan chan string, keys ...string) error {
+	if db.closed {
+		return fmt.Errorf("Watch(%v) called on a closed connection", keys)
+	}
+	for _, key := range keys {
+		db.watch(key, key, resp, closeChan, nil)
+	}
+	return nil
+}
+
+// getKV reads the entries of the key using the given query parameters.
+// Returns the index of the KV store, which can be used for a blocking query.
+func (db *BytesConnectionConsul) getKV(ctx context.Context, key string, query url.Values) (entries []*kvEntry, index uint64, err error) {
+	respBody, header, err := db.do(ctx, http.MethodGet, kvPath(key), query, nil)
+	if header != nil {
+		index, _ = strconv.ParseUint(header.Get("X-Consul-Index"), 10, 64)
+	}
+	if err == errNotFound {
+		return nil, index, nil
+	}
+	if err != nil {
+		return nil, index, err
+	}
+	if err = json.Unmarshal(respBody, &entries); err != nil {
+		return nil, index, fmt.Errorf("invalid response to KV get: %v", err)
+	}
+	return entries, index, nil
+}
+
+// getKVWithTimeout reads the entries of the key with the operation timeout.
+func (db *BytesConnectionConsul) getKVWithTimeout(key string, query url.Values) ([]*kvEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.opTimeout)
+	defer cancel()
+	entries, _, err := db.getKV(ctx, key, query)
+	return entries, err
+}
+
+// errNotFound is returned by request when Consul responds with 404.
+var errNotFound = fmt.Errorf("not found")
+
+// request sends a request to Consul with the operation timeout and returns
+// the body of the response.
+func (db *BytesConnectionConsul) request(method, path string, query url.Values, body []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), db.opTimeout)
+	defer cancel()
+	respBody, _, err := db.do(ctx, method, path, query, body)
+	return respBody, err
+}
+
+// do sends a request to Consul and returns the body and the header of the response.
+func (db *BytesConnectionConsul) do(ctx context.Context, method, path string, query url.Values,
+	body []byte) ([]byte, http.Header, error) {
+
+	reqURL := *db.baseURL
+	reqURL.Path = strings.TrimSuffix(reqURL.Path, "/") + path
+	if query == nil {
+		query = url.Values{}
+	}
+	if db.dc != "" {
+		query.Set("dc", db.dc)
+	}
+	reqURL.RawQuery = query.Encode()
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, reqURL.String(), reqBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(ctx)
+	if db.token != "" {
+		req.Header.Set("X-Consul-Token", db.token)
+	}
+
+	resp, err := db.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.Header, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, resp.Header, errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.Header, fmt.Errorf("%s %s failed with status %d: %s",
+			method, path, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, resp.Header, nil
+}
+
+// kvPath returns the path of the KV API for the given key.
+func kvPath(key string) string {
+	return "/v1/kv/" + toConsulKey(key)
+}
+
+// toConsulKey strips the leading slash not allowed by Consul.
+func toConsulKey(key string) string {
+	return strings.TrimPrefix(key, "/")
+}
+
+// fromConsulKey restores the leading slash of the key received from Consul
+// for the given key (prefix) of the request.
+func fromConsulKey(reqKey, key string) string {
+	if strings.HasPrefix(reqKey, "/") {
+		return "/" + key
+	}
+	return key
+}
+
+// bytesKeyValIterator is an iterator returned by ListValues call.
+type bytesKeyValIterator struct {
+	index  int
+	values []*bytesKeyVal
+}
+
+// GetNext returns the following item from the result set.
+// When there are no more items to get, <stop> is returned as *true*.
+func (it *bytesKeyValIterator) GetNext() (val keyval.BytesKeyVal, stop bool) {
+	if it.index >= len(it.values) {
+		return nil, true
+	}
+	val = it.values[it.index]
+	it.index++
+	return val, false
+}
+
+// bytesKeyIterator is an iterator returned by ListKeys call.
+type bytesKeyIterator struct {
+	index int
+	keys  []string
+	revs  []int64
+}
+
+// GetNext returns the following key from the result set.
+// When there are no more keys to get, <stop> is returned as *true*.
+func (it *bytesKeyIterator) GetNext() (key string, rev int64, stop bool) {
+	if it.index >= len(it.keys) {
+		return "", 0, true
+	}
+	key, rev = it.keys[it.index], it.revs[it.index]
+	it.index++
+	return key, rev, false
+}
+
+// bytesKeyVal represents a single key-value pair.
+type bytesKeyVal struct {
+	key       string
+	value     []byte
+	prevValue []byte
+	rev       int64
+}
+
+// GetValue returns the value of the pair.
+func (kv *bytesKeyVal) GetValue() []byte {
+	return kv.value
+}
+
+// GetPrevValue returns the previous value of the pair.
+func (kv *bytesKeyVal) GetPrevValue() []byte {
+	return kv.prevValue
+}
+
+// GetKey returns the key of the pair.
+func (kv *bytesKeyVal) GetKey() string {
+	return kv.key
+}
+
+// GetRevision returns the revision associated with the pair.
+func (kv *bytesKeyVal) GetRevision() int64 {
+	return kv.rev
+}
+
+// BytesBrokerWatcherConsul uses BytesConnectionConsul to access the datastore.
+// The connection can be shared among multiple BytesBrokerWatcherConsul.
+// BytesBrokerWatcherConsul allows to define a keyPrefix that is prepended to
+// all keys in its methods in order to shorten keys used in arguments.
+type BytesBrokerWatcherConsul struct {
+	prefix   string
+	delegate *BytesConnectionConsul
+}
+
+func (pdb *BytesBrokerWatcherConsul) addPrefix(key string) string {
+	return pdb.prefix + key
+}
+
+func (pdb *BytesBrokerWatcherConsul) trimPrefix(key string) string {
+	return strings.TrimPrefix(key, pdb.prefix)
+}
+
+// Put calls Put function of BytesConnectionConsul. Prefix will be prepended to the key argument.
+func (pdb *BytesBrokerWatcherConsul) Put(key string, data []byte, opts ...datasync.PutOption) error {
+	return pdb.delegate.Put(pdb.addPrefix(key), data, opts...)
+}
+
+// NewTxn creates new transaction. Prefix will be prepended to the key argument.
+func (pdb *BytesBrokerWatcherConsul) NewTxn() keyval.BytesTxn {
+	return &bytesTxn{db: pdb.delegate, addPrefix: pdb.addPrefix}
+}
+
+// GetValue calls GetValue function of BytesConnectionConsul.
+// Prefix will be prepended to the key argument.
+func (pdb *BytesBrokerWatcherConsul) GetValue(key string) (data []byte, found bool, revision int64, err error) {
+	return pdb.delegate.GetValue(pdb.addPrefix(key))
+}
+
+// ListValues calls ListValues function of BytesConnectionConsul.
+// Prefix will be prepended to the key argument, the returned keys have the prefix trimmed.
+func (pdb *BytesBrokerWatcherConsul) ListValues(key string) (keyval.BytesKeyValIterator, error) {
+	return pdb.delegate.listValues(pdb.addPrefix(key), pdb.trimPrefix)
+}
+
+// ListKeys calls ListKeys function of BytesConnectionConsul.
+// Prefix will be prepended to the key argument, the returned keys have the prefix trimmed.
+func (pdb *BytesBrokerWatcherConsul) ListKeys(prefix string) (keyval.BytesKeyIterator, error) {
+	return pdb.delegate.listKeys(pdb.addPrefix(prefix), pdb.trimPrefix)
+}
+
+// Delete calls Delete function of BytesConnectionConsul.
+// Prefix will be prepended to the key argument.
+func (pdb *BytesBrokerWatcherConsul) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	return pdb.delegate.Delete(pdb.addPrefix(key), opts...)
+}
+
+// Watch starts subscription for changes associated with the selected keys.
+// Prefix will be prepended to the keys, the keys of the events have the prefix trimmed.
+func (pdb *BytesBrokerWatcherConsul) Watch(resp func(keyval.BytesWatchResp), closeChan chan string, keys ...string) error {
+	if pdb.delegate.closed {
+		return fmt.Errorf("Watch(%v) called on a closed connection", keys)
+	}
+	for _, key := range keys {
+		pdb.delegate.watch(pdb.addPrefix(key), key, resp, closeChan, pdb.trimPrefix)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ligato/cn-infra/db/keyval"
+)
+
+// txnOp is a single KV operation of the Consul transaction API.
+type txnOp struct {
+	KV txnKVOp
+}
+
+// txnKVOp describes the KV operation.
+type txnKVOp struct {
+	Verb  string
+	Key   string
+	Value []byte `json:",omitempty"`
+}
+
+// bytesTxn allows to group operations into the transaction. Transaction
+// executes multiple operations in a single Consul transaction.
+type bytesTxn struct {
+	db        *BytesConnectionConsul
+	addPrefix func(key string) string
+	ops       []txnOp
+}
+
+// Put adds a new 'put' operation to a previously created transaction.
+func (tx *bytesTxn) Put(key string, value []byte) keyval.BytesTxn {
+	tx.ops = append(tx.ops, txnOp{KV: txnKVOp{Verb: "set", Key: toConsulKey(tx.key(key)), Value: value}})
+	return tx
+}
+
+// Delete adds a new 'delete' operation to a previously created transaction.
+func (tx *bytesTxn) Delete(key string) keyval.BytesTxn {
+	tx.ops = append(tx.ops, txnOp{KV: txnKVOp{Verb: "delete", Key: toConsulKey(tx.key(key))}})
+	return tx
+}
+
+// Commit tries to commit the transaction.
+func (tx *bytesTxn) Commit() error {
+	if tx.db.closed {
+		return fmt.Errorf("Commit() called on a closed connection")
+	}
+	if len(tx.ops) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(tx.ops)
+	if err != nil {
+		return err
+	}
+	_, err = tx.db.request(http.MethodPut, "/v1/txn", nil, body)
+	return err
+}
+
+func (tx *bytesTxn) key(key string) string {
+	if tx.addPrefix != nil {
+		return tx.addPrefix(key)
+	}
+	return key
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+)
+
+const (
+	// watchWait is the maximum duration of a blocking query.
+	watchWait = "5m"
+
+	// watchRetryPeriod is the delay before a failed blocking query is repeated.
+	watchRetryPeriod = 1 * time.Second
+)
+
+// BytesWatchPutResp is sent when new key-value pair has been inserted
+// or the value has been updated.
+type BytesWatchPutResp struct {
+	key       string
+	value     []byte
+	prevValue []byte
+	rev       int64
+}
+
+// NewBytesWatchPutResp creates an instance of BytesWatchPutResp.
+func NewBytesWatchPutResp(key string, value []byte, prevValue []byte, revision int64) *BytesWatchPutResp {
+	return &BytesWatchPutResp{key: key, value: value, prevValue: prevValue, rev: revision}
+}
+
+// GetChangeType returns "Put" for BytesWatchPutResp.
+func (resp *BytesWatchPutResp) GetChangeType() datasync.PutDel {
+	return datasync.Put
+}
+
+// GetKey returns the key that the value has been inserted under.
+func (resp *BytesWatchPutResp) GetKey() string {
+	return resp.key
+}
+
+// GetValue returns the value that has been inserted.
+func (resp *BytesWatchPutResp) GetValue() []byte {
+	return resp.value
+}
+
+// GetPrevValue returns the previous value that has been inserted.
+func (resp *BytesWatchPutResp) GetPrevValue() []byte {
+	return resp.prevValue
+}
+
+// GetRevision returns the revision associated with the 'put' operation.
+func (resp *BytesWatchPutResp) GetRevision() int64 {
+	return resp.rev
+}
+
+// BytesWatchDelResp is sent when a key-value pair has been removed.
+type BytesWatchDelResp struct {
+	key       string
+	prevValue []byte
+	rev       int64
+}
+
+// NewBytesWatchDelResp creates an instance of BytesWatchDelResp.
+func NewBytesWatchDelResp(key string, prevValue []byte, revision int64) *BytesWatchDelResp {
+	return &BytesWatchDelResp{key: key, prevValue: prevValue, rev: revision}
+}
+
+// GetChangeType returns "Delete" for BytesWatchDelResp.
+func (resp *BytesWatchDelResp) GetChangeType() datasync.PutDel {
+	return datasync.Delete
+}
+
+// GetKey returns the key that a value has been deleted from.
+func (resp *BytesWatchDelResp) GetKey() string {
+	return resp.key
+}
+
+// GetValue returns nil for BytesWatchDelResp.
+func (resp *BytesWatchDelResp) GetValue() []byte {
+	return nil
+}
+
+// GetPrevValue returns the value that has been deleted.
+func (resp *BytesWatchDelResp) GetPrevValue() []byte {
+	return resp.prevValue
+}
+
+// GetRevision returns the revision associated with the 'delete' operation.
+func (resp *BytesWatchDelResp) GetRevision() int64 {
+	return resp.rev
+}
+
+// watch starts watching the given key prefix in a separate go routine.
+// The watch ends when <closeKey> is sent to or <closeChan> is closed,
+// or when the connection is closed.
+func (db *BytesConnectionConsul) watch(key string, closeKey string, resp func(keyval.BytesWatchResp),
+	closeChan chan string, trimPrefix func(string) string) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer cancel()
+		for {
+			select {
+			case <-db.closeCh:
+				return
+			case closeVal, ok := <-closeChan:
+				if !ok || closeVal == closeKey {
+					db.WithField("key", key).Debug("Watch ended")
+					return
+				}
+			}
+		}
+	}()
+	go db.watchLoop(ctx, key, resp, trimPrefix)
+}
+
+// watchLoop repeats blocking queries for the given key prefix and notifies
+// about the differences between the consecutive results.
+func (db *BytesConnectionConsul) watchLoop(ctx context.Context, key string, resp func(keyval.BytesWatchResp),
+	trimPrefix func(string) string) {
+
+	var (
+		index uint64
+		known map[string]*kvEntry // nil until the initial state is read
+	)
+	for {
+		query := url.Values{"recurse": {""}}
+		if known != nil {
+			query.Set("index", strconv.FormatUint(index, 10))
+			query.Set("wait", watchWait)
+		}
+		entries, newIndex, err := db.getKV(ctx, key, query)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			db.WithField("key", key).Warnf("Watch query failed: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchRetryPeriod):
+			}
+			continue
+		}
+
+		current := make(map[string]*kvEntry)
+		for _, entry := range entries {
+			current[entry.Key] = entry
+		}
+		if known != nil {
+			db.notifyChanges(key, known, current, newIndex, resp, trimPrefix)
+		}
+		known = current
+
+		// Sanity checks recommended for blocking queries by Consul.
+		if newIndex < index {
+			newIndex = 0
+		}
+		if newIndex == 0 {
+			newIndex = 1
+		}
+		index = newIndex
+	}
+}
+
+// notifyChanges sends watch response for every key created, updated
+// or removed between the two states of the watched key prefix.
+func (db *BytesConnectionConsul) notifyChanges(reqKey string, prevState, state map[string]*kvEntry, index uint64,
+	resp func(keyval.BytesWatchResp), trimPrefix func(string) string) {
+
+	eventKey := func(key string) string {
+		key = fromConsulKey(reqKey, key)
+		if trimPrefix != nil {
+			key = trimPrefix(key)
+		}
+		return key
+	}
+
+	for _, key := range sortedKeys(state) {
+		entry := state[key]
+		prevEntry, existed := prevState[key]
+		if existed && prevEntry.ModifyIndex == entry.ModifyIndex {
+			continue
+		}
+		var prevValue []byte
+		if existed {
+			prevValue = prevEntry.Value
+		}
+		resp(NewBytesWatchPutResp(eventKey(key), entry.Value, prevValue, int64(entry.ModifyIndex)))
+	}
+	for _, key := range sortedKeys(prevState) {
+		if _, exists := state[key]; !exists {
+			resp(NewBytesWatchDelResp(eventKey(key), prevState[key].Value, int64(index)))
+		}
+	}
+}
+
+// sortedKeys returns the keys of the given state in the lexicographical order.
+func sortedKeys(state map[string]*kvEntry) []string {
+	keys := make([]string, 0, len(state))
+	for key := range state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
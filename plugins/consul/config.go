@@ -0,0 +1,61 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultAddress is the address of the local Consul agent.
+	defaultAddress = "127.0.0.1:8500"
+
+	// defaultOpTimeout is the default timeout of a single (non-blocking) request.
+	defaultOpTimeout = 3 * time.Second
+)
+
+// Config represents configuration for the Consul plugin.
+type Config struct {
+	// Address of the Consul agent HTTP API, "host:port" or URL with scheme.
+	Address string `json:"address"`
+
+	// Token is the ACL token sent with every request (optional).
+	Token string `json:"token"`
+
+	// Datacenter to use instead of the datacenter of the agent (optional).
+	Datacenter string `json:"datacenter"`
+
+	// OpTimeout is the timeout of a single request (blocking queries excluded).
+	OpTimeout time.Duration `json:"op-timeout"`
+}
+
+// baseURL returns the URL of the Consul HTTP API from the configuration.
+func (cfg *Config) baseURL() (*url.URL, error) {
+	address := cfg.Address
+	if address == "" {
+		address = defaultAddress
+	}
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	baseURL, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Consul address %s: %v", cfg.Address, err)
+	}
+	return baseURL, nil
+}
@@ -0,0 +1,392 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/logging/logrus"
+	. "github.com/onsi/gomega"
+)
+
+// fakeConsul implements the subset of the Consul HTTP API used by the plugin.
+type fakeConsul struct {
+	sync.Mutex
+	index    uint64
+	kv       map[string]*kvEntry
+	sessions map[string]bool
+	changed  chan struct{}
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{
+		index:    1,
+		kv:       make(map[string]*kvEntry),
+		sessions: make(map[string]bool),
+		changed:  make(chan struct{}),
+	}
+}
+
+// modified bumps the index and wakes up the blocking queries. Called with the lock held.
+func (fc *fakeConsul) modified() uint64 {
+	fc.index++
+	close(fc.changed)
+	fc.changed = make(chan struct{})
+	return fc.index
+}
+
+func (fc *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	_, recurse := query["recurse"]
+	body, _ := ioutil.ReadAll(r.Body)
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		switch r.Method {
+		case http.MethodGet:
+			fc.get(w, key, recurse, query.Get("index"))
+		case http.MethodPut:
+			fc.Lock()
+			defer fc.Unlock()
+			if query.Get("cas") == "0" && fc.kv[key] != nil {
+				fmt.Fprint(w, "false")
+				return
+			}
+			session := query.Get("acquire")
+			if session != "" && !fc.sessions[session] {
+				fmt.Fprint(w, "false")
+				return
+			}
+			fc.kv[key] = &kvEntry{Key: key, Value: body, ModifyIndex: fc.modified(), Session: session}
+			fmt.Fprint(w, "true")
+		case http.MethodDelete:
+			fc.Lock()
+			defer fc.Unlock()
+			if cas := query.Get("cas"); cas != "" && (fc.kv[key] == nil || cas != fmt.Sprint(fc.kv[key].ModifyIndex)) {
+				fmt.Fprint(w, "false")
+				return
+			}
+			for k := range fc.kv {
+				if k == key || (recurse && strings.HasPrefix(k, key)) {
+					delete(fc.kv, k)
+				}
+			}
+			fc.modified()
+			fmt.Fprint(w, "true")
+		}
+
+	case r.URL.Path == "/v1/session/create":
+		fc.Lock()
+		defer fc.Unlock()
+		id := "session-" + strconv.Itoa(len(fc.sessions)+1)
+		fc.sessions[id] = true
+		fmt.Fprintf(w, `{"ID": "%s"}`, id)
+
+	case strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+		fc.Lock()
+		defer fc.Unlock()
+		if !fc.sessions[strings.TrimPrefix(r.URL.Path, "/v1/session/renew/")] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "[]")
+
+	case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+		fc.Lock()
+		defer fc.Unlock()
+		fc.expireSession(strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/"))
+		fmt.Fprint(w, "true")
+
+	case r.URL.Path == "/v1/txn":
+		var ops []txnOp
+		if err := json.Unmarshal(body, &ops); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fc.Lock()
+		defer fc.Unlock()
+		index := fc.modified()
+		for _, op := range ops {
+			switch op.KV.Verb {
+			case "set":
+				fc.kv[op.KV.Key] = &kvEntry{Key: op.KV.Key, Value: op.KV.Value, ModifyIndex: index}
+			case "delete":
+				delete(fc.kv, op.KV.Key)
+			}
+		}
+		fmt.Fprint(w, "{}")
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// get serves KV read, blocking until a change if the index of the request is current.
+func (fc *fakeConsul) get(w http.ResponseWriter, key string, recurse bool, reqIndex string) {
+	fc.Lock()
+	if index, _ := strconv.ParseUint(reqIndex, 10, 64); index >= fc.index {
+		changed := fc.changed
+		fc.Unlock()
+		select {
+		case <-changed:
+		case <-time.After(time.Second):
+		}
+		fc.Lock()
+	}
+	defer fc.Unlock()
+
+	entries := []*kvEntry{}
+	for k, entry := range fc.kv {
+		if k == key || (recurse && strings.HasPrefix(k, key)) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(fc.index, 10))
+	if len(entries) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+// expireSession removes the session together with its keys. Called with the lock held.
+func (fc *fakeConsul) expireSession(session string) {
+	delete(fc.sessions, session)
+	for k, entry := range fc.kv {
+		if entry.Session == session {
+			delete(fc.kv, k)
+		}
+	}
+	fc.modified()
+}
+
+func newTestConnection(server *httptest.Server) *BytesConnectionConsul {
+	conn, err := NewConsulConnectionWithBytes(&Config{Address: server.URL}, logrus.DefaultLogger())
+	Expect(err).To(BeNil())
+	return conn
+}
+
+func TestPutGetDelete(t *testing.T) {
+	RegisterTestingT(t)
+	fc := newFakeConsul()
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	conn := newTestConnection(server)
+	defer conn.Close()
+	broker := conn.NewBroker("/vnf-agent/contiv-ksr/")
+
+	Expect(broker.Put("k8s/pod/a", []byte("A"))).To(BeNil())
+	Expect(broker.Put("k8s/pod/b", []byte("B"))).To(BeNil())
+	Expect(broker.Put("k8s/node/n", []byte("N"))).To(BeNil())
+	// Consul keys are without the leading slash.
+	Expect(fc.kv).To(HaveKey("vnf-agent/contiv-ksr/k8s/pod/a"))
+
+	data, found, rev, err := broker.GetValue("k8s/pod/a")
+	Expect(err).To(BeNil())
+	Expect(found).To(BeTrue())
+	Expect(data).To(BeEquivalentTo("A"))
+	Expect(rev).To(BeNumerically(">", 0))
+	_, found, _, err = broker.GetValue("k8s/pod/c")
+	Expect(err).To(BeNil())
+	Expect(found).To(BeFalse())
+
+	// Prefix of the broker is trimmed from the listed keys.
+	it, err := broker.ListValues("k8s/pod/")
+	Expect(err).To(BeNil())
+	kv, stop := it.GetNext()
+	Expect(stop).To(BeFalse())
+	Expect(kv.GetKey()).To(Equal("k8s/pod/a"))
+	Expect(kv.GetValue()).To(BeEquivalentTo("A"))
+	kv, stop = it.GetNext()
+	Expect(stop).To(BeFalse())
+	Expect(kv.GetKey()).To(Equal("k8s/pod/b"))
+	_, stop = it.GetNext()
+	Expect(stop).To(BeTrue())
+
+	keys, err := conn.ListKeys("/vnf-agent/contiv-ksr/k8s/node/")
+	Expect(err).To(BeNil())
+	key, _, stop := keys.GetNext()
+	Expect(stop).To(BeFalse())
+	Expect(key).To(Equal("/vnf-agent/contiv-ksr/k8s/node/n"))
+	_, _, stop = keys.GetNext()
+	Expect(stop).To(BeTrue())
+
+	succeeded, err := conn.PutIfNotExists("/vnf-agent/contiv-ksr/k8s/pod/a", []byte("X"))
+	Expect(err).To(BeNil())
+	Expect(succeeded).To(BeFalse())
+	succeeded, err = conn.PutIfNotExists("/vnf-agent/contiv-ksr/k8s/pod/c", []byte("C"))
+	Expect(err).To(BeNil())
+	Expect(succeeded).To(BeTrue())
+
+	// Delete only if the revision has not changed.
+	_, _, rev, err = broker.GetValue("k8s/pod/c")
+	Expect(err).To(BeNil())
+	Expect(broker.Put("k8s/pod/c", []byte("C2"))).To(BeNil())
+	deleted, err := conn.DeleteIfNotChanged("/vnf-agent/contiv-ksr/k8s/pod/c", rev)
+	Expect(err).To(BeNil())
+	Expect(deleted).To(BeFalse())
+	_, _, rev, err = broker.GetValue("k8s/pod/c")
+	Expect(err).To(BeNil())
+	deleted, err = conn.DeleteIfNotChanged("/vnf-agent/contiv-ksr/k8s/pod/c", rev)
+	Expect(err).To(BeNil())
+	Expect(deleted).To(BeTrue())
+	Expect(fc.kv).ToNot(HaveKey("vnf-agent/contiv-ksr/k8s/pod/c"))
+
+	existed, err := broker.Delete("k8s/node/n")
+	Expect(err).To(BeNil())
+	Expect(existed).To(BeTrue())
+	existed, err = broker.Delete("k8s/node/n")
+	Expect(err).To(BeNil())
+	Expect(existed).To(BeFalse())
+	existed, err = broker.Delete("k8s/pod/", datasync.WithPrefix())
+	Expect(err).To(BeNil())
+	Expect(existed).To(BeTrue())
+	Expect(fc.kv).To(BeEmpty())
+}
+
+func TestTxn(t *testing.T) {
+	RegisterTestingT(t)
+	fc := newFakeConsul()
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	conn := newTestConnection(server)
+	defer conn.Close()
+	broker := conn.NewBroker("/prefix/")
+
+	Expect(broker.Put("old", []byte("old"))).To(BeNil())
+	err := broker.NewTxn().Put("new1", []byte("1")).Put("new2", []byte("2")).Delete("old").Commit()
+	Expect(err).To(BeNil())
+	Expect(fc.kv).To(HaveLen(2))
+	Expect(fc.kv["prefix/new1"].Value).To(BeEquivalentTo("1"))
+	Expect(fc.kv["prefix/new2"].Value).To(BeEquivalentTo("2"))
+}
+
+func TestPutWithTTL(t *testing.T) {
+	RegisterTestingT(t)
+	fc := newFakeConsul()
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	conn := newTestConnection(server)
+
+	// Repeated put renews the session.
+	Expect(conn.Put("/id/1", []byte("A"), datasync.WithTTL(time.Second))).To(BeNil())
+	Expect(conn.Put("/id/1", []byte("B"), datasync.WithTTL(time.Second))).To(BeNil())
+	Expect(fc.sessions).To(HaveLen(1))
+	Expect(fc.kv["id/1"].Value).To(BeEquivalentTo("B"))
+
+	// Expired session is replaced.
+	fc.Lock()
+	fc.expireSession(fc.kv["id/1"].Session)
+	fc.Unlock()
+	Expect(fc.kv).To(BeEmpty())
+	Expect(conn.Put("/id/1", []byte("C"), datasync.WithTTL(time.Second))).To(BeNil())
+	Expect(fc.sessions).To(HaveLen(1))
+	Expect(fc.kv["id/1"].Value).To(BeEquivalentTo("C"))
+
+	// Close releases the sessions and thus also the keys.
+	Expect(conn.Close()).To(BeNil())
+	Expect(fc.sessions).To(BeEmpty())
+	Expect(fc.kv).To(BeEmpty())
+}
+
+func TestKeepAlive(t *testing.T) {
+	RegisterTestingT(t)
+	fc := newFakeConsul()
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	conn := newTestConnection(server)
+	defer conn.Close()
+
+	// The session is renewed, the key is not rewritten.
+	Expect(conn.Put("/id/1", []byte("A"), datasync.WithTTL(time.Second))).To(BeNil())
+	index := fc.kv["id/1"].ModifyIndex
+	found, err := conn.KeepAlive("/id/1")
+	Expect(err).To(BeNil())
+	Expect(found).To(BeTrue())
+	Expect(fc.kv["id/1"].ModifyIndex).To(Equal(index))
+
+	// Keys without TTL are only looked up.
+	Expect(conn.Put("/id/2", []byte("B"))).To(BeNil())
+	found, err = conn.KeepAlive("/id/2")
+	Expect(err).To(BeNil())
+	Expect(found).To(BeTrue())
+	found, err = conn.KeepAlive("/id/3")
+	Expect(err).To(BeNil())
+	Expect(found).To(BeFalse())
+
+	// Expired key is not found.
+	fc.Lock()
+	fc.expireSession(fc.kv["id/1"].Session)
+	fc.Unlock()
+	found, err = conn.KeepAlive("/id/1")
+	Expect(err).To(BeNil())
+	Expect(found).To(BeFalse())
+}
+
+func TestWatch(t *testing.T) {
+	RegisterTestingT(t)
+	fc := newFakeConsul()
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	conn := newTestConnection(server)
+	defer conn.Close()
+	broker := conn.NewBroker("/prefix/")
+	Expect(broker.Put("watched/existing", []byte("E"))).To(BeNil())
+
+	respChan := make(chan keyval.BytesWatchResp, 10)
+	closeChan := make(chan string)
+	err := conn.NewWatcher("/prefix/").Watch(keyval.ToChan(respChan), closeChan, "watched/")
+	Expect(err).To(BeNil())
+
+	// Wait for the initial state to be read, changes of other keys are not reported.
+	time.Sleep(200 * time.Millisecond)
+	Expect(broker.Put("other", []byte("O"))).To(BeNil())
+	Consistently(respChan, 200*time.Millisecond).ShouldNot(Receive())
+
+	Expect(broker.Put("watched/existing", []byte("E2"))).To(BeNil())
+	var resp keyval.BytesWatchResp
+	Eventually(respChan, 2*time.Second).Should(Receive(&resp))
+	Expect(resp.GetChangeType()).To(Equal(datasync.Put))
+	Expect(resp.GetKey()).To(Equal("watched/existing"))
+	Expect(resp.GetValue()).To(BeEquivalentTo("E2"))
+	Expect(resp.GetPrevValue()).To(BeEquivalentTo("E"))
+
+	_, err = broker.Delete("watched/existing")
+	Expect(err).To(BeNil())
+	Eventually(respChan, 2*time.Second).Should(Receive(&resp))
+	Expect(resp.GetChangeType()).To(Equal(datasync.Delete))
+	Expect(resp.GetKey()).To(Equal("watched/existing"))
+
+	// No more events once the watch is closed.
+	close(closeChan)
+	time.Sleep(100 * time.Millisecond)
+	Expect(broker.Put("watched/new", []byte("N"))).To(BeNil())
+	Consistently(respChan, 1500*time.Millisecond).ShouldNot(Receive())
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package consul implements key-value data store plugin backed by the KV store
+// of Consul, accessed through the Consul HTTP API.
+//
+// The plugin is an alternative to the etcdv3 plugin of cn-infra - it provides
+// the same proto broker & watcher API (keyval.KvProtoPlugin) and the same
+// PutIfNotExists method. Just like with etcd, the plugin is disabled if its
+// configuration file (consul.conf) is not found.
+//
+// Consul specifics:
+//   - Consul keys must not start with '/'. The leading slash present in all
+//     agent keys is therefore stripped before the key is sent to Consul
+//     and added back to the keys received from Consul.
+//   - Put with datasync.WithTTL binds the key to a Consul session with the
+//     "delete" behaviour. Repeated Put of the same key renews the session.
+//     Consul enforces the minimal TTL of 10 seconds and may remove the key
+//     up to twice the TTL after the last renewal.
+//   - Watch is implemented using blocking queries, i.e. the changes that occur
+//     between two queries are merged into one event per key.
+//   - Transactions are limited by Consul to 64 operations.
+package consul
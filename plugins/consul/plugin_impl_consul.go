@@ -0,0 +1,136 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"fmt"
+
+	"github.com/ligato/cn-infra/core"
+	"github.com/ligato/cn-infra/db/keyval/plugin"
+	"github.com/ligato/cn-infra/flavors/local"
+	"github.com/ligato/cn-infra/health/statuscheck"
+	"github.com/ligato/cn-infra/utils/safeclose"
+)
+
+const (
+	// healthCheckProbeKey is a key used to probe Consul state.
+	healthCheckProbeKey = "/probe-consul-connection"
+)
+
+// Plugin implements Consul key-value data store plugin.
+type Plugin struct {
+	Deps
+	*plugin.Skeleton
+	disabled   bool
+	connection *BytesConnectionConsul
+}
+
+// Deps lists dependencies of the Consul plugin.
+type Deps struct {
+	local.PluginInfraDeps //inject
+}
+
+// Init retrieves Consul configuration and prepares the connection
+// to the Consul agent. The plugin is disabled if the configuration
+// file is not found.
+func (p *Plugin) Init() (err error) {
+	config := &Config{}
+	found, err := p.PluginConfig.GetValue(config)
+	if err != nil {
+		return fmt.Errorf("failed to load Consul configuration: %v", err)
+	}
+	if !found {
+		p.Log.Info("Consul config not found ", p.PluginConfig.GetConfigName(), " - skip loading this plugin")
+		p.disabled = true
+		return nil
+	}
+
+	p.connection, err = NewConsulConnectionWithBytes(config, p.Log)
+	if err != nil {
+		return err
+	}
+	p.Log.Infof("Using Consul at %s", p.connection.baseURL)
+
+	p.Skeleton = plugin.NewSkeleton(p.String(), p.ServiceLabel, p.connection)
+	if err = p.Skeleton.Init(); err != nil {
+		return err
+	}
+
+	// Register for providing status reports (polling mode).
+	if p.StatusCheck != nil {
+		p.StatusCheck.Register(core.PluginName(p.String()), func() (statuscheck.PluginState, error) {
+			_, _, _, err := p.connection.GetValue(healthCheckProbeKey)
+			if err == nil {
+				return statuscheck.OK, nil
+			}
+			return statuscheck.Error, err
+		})
+	} else {
+		p.Log.Warn("Unable to start status check for Consul")
+	}
+	return nil
+}
+
+// AfterInit is called by the Agent Core after all plugins have been initialized.
+func (p *Plugin) AfterInit() error {
+	return nil
+}
+
+// Close stops the watchers and releases the sessions of the keys with TTL.
+func (p *Plugin) Close() error {
+	if p.disabled {
+		return nil
+	}
+	_, err := safeclose.CloseAll(p.Skeleton)
+	return err
+}
+
+// String returns Deps.PluginName if set, "consul" otherwise.
+func (p *Plugin) String() string {
+	if len(p.Deps.PluginName) == 0 {
+		return "consul"
+	}
+	return string(p.Deps.PluginName)
+}
+
+// Disabled returns *true* if the plugin is not in use due to missing
+// Consul configuration.
+func (p *Plugin) Disabled() (disabled bool) {
+	return p.disabled
+}
+
+// PutIfNotExists puts the given key-value item if the key does not exist yet.
+func (p *Plugin) PutIfNotExists(key string, value []byte) (succeeded bool, err error) {
+	if p.connection != nil {
+		return p.connection.PutIfNotExists(key, value)
+	}
+	return false, fmt.Errorf("the connection to Consul is not established")
+}
+
+// KeepAlive refreshes TTL of the given key without rewriting it.
+func (p *Plugin) KeepAlive(key string) (found bool, err error) {
+	if p.connection != nil {
+		return p.connection.KeepAlive(key)
+	}
+	return false, fmt.Errorf("the connection to Consul is not established")
+}
+
+// DeleteIfNotChanged removes the given key if it has not been modified since the given revision.
+func (p *Plugin) DeleteIfNotChanged(key string, revision int64) (deleted bool, err error) {
+	if p.connection != nil {
+		return p.connection.DeleteIfNotChanged(key, revision)
+	}
+	return false, fmt.Errorf("the connection to Consul is not established")
+}
@@ -0,0 +1,20 @@
+// Package bgp implements a minimal BGP-4 speaker advertising routes via the node to upstream routers.
+//
+// The speaker initiates the sessions with the configured peers (section BGP of the contiv-agent-cfg ConfigMap
+// in ../../k8s/contiv-vpp.yaml), announces the 4-octet AS and IPv4 unicast capabilities and originates
+// the advertised IPv4 prefixes with the node IP as the next hop. The local AS is prepended to the AS path
+// for external peers, internal peers receive an empty AS path with the default local preference.
+// The routes received from the peers are ignored, the speaker does not install any routes into VPP.
+//
+// Example:
+//
+//	    BGP:
+//	      LocalAS: 65000
+//	      Peers:
+//	        - Address: 192.168.16.254
+//	          AS: 65001
+//
+// With the pod network of every node advertised, the upstream routers route the traffic destined
+// to pods directly to their nodes, which allows clusters without overlay (InterNodeTransport: nooverlay)
+// and pod IPs reachable from outside of the cluster.
+package bgp
@@ -0,0 +1,300 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// BGP message types (RFC 4271)
+const (
+	msgOpen         = 1
+	msgUpdate       = 2
+	msgNotification = 3
+	msgKeepalive    = 4
+)
+
+const (
+	bgpVersion    = 4
+	headerLen     = 19
+	maxMessageLen = 4096
+
+	// asTrans is announced in the 2-octet AS field of OPEN by speakers with 4-octet AS numbers (RFC 6793)
+	asTrans = 23456
+
+	// optional parameter and capabilities (RFC 5492, RFC 4760, RFC 6793)
+	optParamCapabilities = 2
+	capMultiprotocol     = 1
+	capFourOctetAS       = 65
+	afiIPv4              = 1
+	safiUnicast          = 1
+
+	// path attributes
+	attrFlagOptional   = 0x80
+	attrFlagTransitive = 0x40
+	attrOrigin         = 1
+	attrASPath         = 2
+	attrNextHop        = 3
+	attrLocalPref      = 5
+	attrAS4Path        = 17
+	originIGP          = 0
+	asPathSequence     = 2
+	defaultLocalPref   = 100
+
+	// notification error codes
+	errCodeOpenMessage = 2
+	errCodeHoldTimer   = 4
+	errCodeCease       = 6
+	errSubcodeBadAS    = 2
+)
+
+// openMessage is the content of the BGP OPEN message.
+type openMessage struct {
+	as          uint32 // 4-octet AS if announced in capabilities, the AS field otherwise
+	holdTime    uint16
+	routerID    net.IP
+	fourOctetAS bool
+}
+
+// encodeMessage prepends the BGP header to the message body.
+func encodeMessage(msgType uint8, body []byte) []byte {
+	msg := make([]byte, headerLen, headerLen+len(body))
+	for i := 0; i < 16; i++ {
+		msg[i] = 0xff
+	}
+	binary.BigEndian.PutUint16(msg[16:], uint16(headerLen+len(body)))
+	msg[18] = msgType
+	return append(msg, body...)
+}
+
+// readMessage reads one BGP message and returns its type and body.
+func readMessage(r io.Reader) (msgType uint8, body []byte, err error) {
+	header := make([]byte, headerLen)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	for i := 0; i < 16; i++ {
+		if header[i] != 0xff {
+			return 0, nil, fmt.Errorf("invalid BGP message marker")
+		}
+	}
+	length := int(binary.BigEndian.Uint16(header[16:]))
+	if length < headerLen || length > maxMessageLen {
+		return 0, nil, fmt.Errorf("invalid BGP message length %d", length)
+	}
+	body = make([]byte, length-headerLen)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header[18], body, nil
+}
+
+// encodeOpen encodes OPEN message announcing the 4-octet AS and IPv4 unicast capabilities.
+func encodeOpen(open *openMessage) []byte {
+	as2 := uint16(asTrans)
+	if open.as <= 0xffff {
+		as2 = uint16(open.as)
+	}
+	capabilities := []byte{
+		capMultiprotocol, 4, 0, afiIPv4, 0, safiUnicast,
+		capFourOctetAS, 4, 0, 0, 0, 0,
+	}
+	binary.BigEndian.PutUint32(capabilities[8:], open.as)
+
+	body := make([]byte, 10, 10+2+len(capabilities))
+	body[0] = bgpVersion
+	binary.BigEndian.PutUint16(body[1:], as2)
+	binary.BigEndian.PutUint16(body[3:], open.holdTime)
+	copy(body[5:9], open.routerID.To4())
+	body[9] = byte(2 + len(capabilities))
+	body = append(body, optParamCapabilities, byte(len(capabilities)))
+	body = append(body, capabilities...)
+	return encodeMessage(msgOpen, body)
+}
+
+// decodeOpen decodes the body of OPEN message.
+func decodeOpen(body []byte) (*openMessage, error) {
+	if len(body) < 10 || len(body) < 10+int(body[9]) {
+		return nil, fmt.Errorf("malformed OPEN message")
+	}
+	if body[0] != bgpVersion {
+		return nil, fmt.Errorf("unsupported BGP version %d", body[0])
+	}
+	open := &openMessage{
+		as:       uint32(binary.BigEndian.Uint16(body[1:])),
+		holdTime: binary.BigEndian.Uint16(body[3:]),
+		routerID: net.IP(append([]byte{}, body[5:9]...)),
+	}
+	params := body[10 : 10+int(body[9])]
+	for len(params) >= 2 {
+		paramType, paramLen := params[0], int(params[1])
+		if len(params) < 2+paramLen {
+			return nil, fmt.Errorf("malformed OPEN optional parameter")
+		}
+		if paramType == optParamCapabilities {
+			caps := params[2 : 2+paramLen]
+			for len(caps) >= 2 {
+				capCode, capLen := caps[0], int(caps[1])
+				if len(caps) < 2+capLen {
+					return nil, fmt.Errorf("malformed OPEN capability")
+				}
+				if capCode == capFourOctetAS && capLen == 4 {
+					open.as = binary.BigEndian.Uint32(caps[2:])
+					open.fourOctetAS = true
+				}
+				caps = caps[2+capLen:]
+			}
+		}
+		params = params[2+paramLen:]
+	}
+	return open, nil
+}
+
+// encodeKeepalive encodes KEEPALIVE message.
+func encodeKeepalive() []byte {
+	return encodeMessage(msgKeepalive, nil)
+}
+
+// encodeNotification encodes NOTIFICATION message with the given error code and subcode.
+func encodeNotification(code, subcode uint8) []byte {
+	return encodeMessage(msgNotification, []byte{code, subcode})
+}
+
+// encodePrefix encodes IPv4 prefix in the NLRI format.
+func encodePrefix(prefix *net.IPNet) []byte {
+	ones, _ := prefix.Mask.Size()
+	return append([]byte{byte(ones)}, prefix.IP.To4()[:(ones+7)/8]...)
+}
+
+// decodePrefixes decodes the list of IPv4 prefixes in the NLRI format.
+func decodePrefixes(data []byte) ([]*net.IPNet, error) {
+	var prefixes []*net.IPNet
+	for len(data) > 0 {
+		ones := int(data[0])
+		octets := (ones + 7) / 8
+		if ones > 32 || len(data) < 1+octets {
+			return nil, fmt.Errorf("malformed NLRI")
+		}
+		ip := make(net.IP, net.IPv4len)
+		copy(ip, data[1:1+octets])
+		prefixes = append(prefixes, &net.IPNet{IP: ip, Mask: net.CIDRMask(ones, 32)})
+		data = data[1+octets:]
+	}
+	return prefixes, nil
+}
+
+// encodeUpdate encodes UPDATE message withdrawing and announcing the given IPv4 prefixes.
+// The announced prefixes are originated by the local AS with the given next hop. The local AS
+// is prepended to the AS path only for external peers. A 4-octet local AS is sent to 2-octet peers
+// as AS_TRANS in AS_PATH together with the AS4_PATH attribute carrying the real AS (RFC 6793).
+func encodeUpdate(withdrawn, announced []*net.IPNet, nextHop net.IP, localAS uint32, external, fourOctetAS bool) []byte {
+	var withdrawnRoutes, attrs, nlri bytes.Buffer
+	for _, prefix := range withdrawn {
+		withdrawnRoutes.Write(encodePrefix(prefix))
+	}
+	if len(announced) > 0 {
+		attrs.Write([]byte{attrFlagTransitive, attrOrigin, 1, originIGP})
+
+		var asPath, as4Path []byte
+		if external {
+			as4 := []byte{asPathSequence, 1, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(as4[2:], localAS)
+			if fourOctetAS {
+				asPath = as4
+			} else {
+				as2 := uint16(asTrans)
+				if localAS <= 0xffff {
+					as2 = uint16(localAS)
+				} else {
+					as4Path = as4
+				}
+				asPath = []byte{asPathSequence, 1, 0, 0}
+				binary.BigEndian.PutUint16(asPath[2:], as2)
+			}
+		}
+		attrs.Write([]byte{attrFlagTransitive, attrASPath, byte(len(asPath))})
+		attrs.Write(asPath)
+		if as4Path != nil {
+			attrs.Write([]byte{attrFlagOptional | attrFlagTransitive, attrAS4Path, byte(len(as4Path))})
+			attrs.Write(as4Path)
+		}
+
+		attrs.Write([]byte{attrFlagTransitive, attrNextHop, net.IPv4len})
+		attrs.Write(nextHop.To4())
+
+		if !external {
+			localPref := make([]byte, 4)
+			binary.BigEndian.PutUint32(localPref, defaultLocalPref)
+			attrs.Write([]byte{attrFlagTransitive, attrLocalPref, 4})
+			attrs.Write(localPref)
+		}
+		for _, prefix := range announced {
+			nlri.Write(encodePrefix(prefix))
+		}
+	}
+
+	body := make([]byte, 2, 4+withdrawnRoutes.Len()+attrs.Len()+nlri.Len())
+	binary.BigEndian.PutUint16(body, uint16(withdrawnRoutes.Len()))
+	body = append(body, withdrawnRoutes.Bytes()...)
+	body = append(body, 0, 0)
+	binary.BigEndian.PutUint16(body[len(body)-2:], uint16(attrs.Len()))
+	body = append(body, attrs.Bytes()...)
+	body = append(body, nlri.Bytes()...)
+	return encodeMessage(msgUpdate, body)
+}
+
+// decodeUpdate decodes the withdrawn and announced IPv4 prefixes and the next hop of UPDATE message.
+func decodeUpdate(body []byte) (withdrawn, announced []*net.IPNet, nextHop net.IP, err error) {
+	if len(body) < 2 {
+		return nil, nil, nil, fmt.Errorf("malformed UPDATE message")
+	}
+	withdrawnLen := int(binary.BigEndian.Uint16(body))
+	if len(body) < 4+withdrawnLen {
+		return nil, nil, nil, fmt.Errorf("malformed UPDATE message")
+	}
+	if withdrawn, err = decodePrefixes(body[2 : 2+withdrawnLen]); err != nil {
+		return nil, nil, nil, err
+	}
+	body = body[2+withdrawnLen:]
+	attrsLen := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+attrsLen {
+		return nil, nil, nil, fmt.Errorf("malformed UPDATE message")
+	}
+	attrs := body[2 : 2+attrsLen]
+	for len(attrs) >= 3 {
+		flags, attrType := attrs[0], attrs[1]
+		attrLen, offset := int(attrs[2]), 3
+		if flags&0x10 != 0 {
+			// extended length
+			if len(attrs) < 4 {
+				break
+			}
+			attrLen, offset = int(binary.BigEndian.Uint16(attrs[2:])), 4
+		}
+		if len(attrs) < offset+attrLen {
+			return nil, nil, nil, fmt.Errorf("malformed UPDATE path attribute")
+		}
+		if attrType == attrNextHop && attrLen == net.IPv4len {
+			nextHop = net.IP(append([]byte{}, attrs[offset:offset+attrLen]...))
+		}
+		attrs = attrs[offset+attrLen:]
+	}
+	announced, err = decodePrefixes(body[2+attrsLen:])
+	return withdrawn, announced, nextHop, err
+}
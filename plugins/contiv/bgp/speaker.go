@@ -0,0 +1,434 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ligato/cn-infra/logging"
+)
+
+const (
+	defaultPort          = 179
+	defaultHoldTime      = 90 * time.Second
+	defaultConnectRetry  = 5 * time.Second
+	openTimeout          = 30 * time.Second
+	maxPrefixesPerUpdate = 500
+)
+
+// Config configures the BGP speaker of the node.
+type Config struct {
+	LocalAS  uint32       // AS number of the node
+	RouterID string       // BGP identifier of the node, the node IP by default
+	HoldTime uint32       // hold time proposed to the peers in seconds, 90 by default
+	Peers    []PeerConfig // upstream routers the routes are advertised to, BGP is disabled if empty
+}
+
+// PeerConfig configures one BGP peer.
+type PeerConfig struct {
+	Address string // IP address of the peer
+	AS      uint32 // AS number of the peer, equal to LocalAS for an internal peer
+	Port    uint16 // TCP port of the peer, 179 by default
+}
+
+// Enabled returns true if any BGP peer is configured.
+func (c *Config) Enabled() bool {
+	return len(c.Peers) > 0
+}
+
+// Speaker advertises IPv4 routes via the node to the configured BGP peers.
+// Sessions are initiated by the speaker only, the routes received from the peers are ignored.
+type Speaker struct {
+	logger       logging.Logger
+	config       Config
+	holdTime     time.Duration
+	connectRetry time.Duration
+	dial         func(address string) (net.Conn, error)
+	startOnce    sync.Once
+
+	sync.Mutex
+	routerID net.IP
+	nextHop  net.IP
+	prefixes map[string]*net.IPNet // advertised prefixes
+	peers    []*peer
+}
+
+// peer is the state of the session with one BGP peer.
+type peer struct {
+	config      PeerConfig
+	address     string
+	changes     chan struct{} // signals a change of the advertised routes
+	established bool
+}
+
+// NewSpeaker validates the config and creates new BGP speaker.
+func NewSpeaker(logger logging.Logger, config Config) (*Speaker, error) {
+	if config.LocalAS == 0 {
+		return nil, fmt.Errorf("local AS of the BGP speaker is not configured")
+	}
+	s := &Speaker{
+		logger:       logger,
+		config:       config,
+		holdTime:     time.Duration(config.HoldTime) * time.Second,
+		connectRetry: defaultConnectRetry,
+		dial: func(address string) (net.Conn, error) {
+			return net.DialTimeout("tcp", address, openTimeout)
+		},
+		prefixes: make(map[string]*net.IPNet),
+	}
+	if config.HoldTime == 0 {
+		s.holdTime = defaultHoldTime
+	} else if config.HoldTime < 3 {
+		return nil, fmt.Errorf("BGP hold time must be zero or at least 3 seconds")
+	}
+	if config.RouterID != "" {
+		s.routerID = net.ParseIP(config.RouterID).To4()
+		if s.routerID == nil {
+			return nil, fmt.Errorf("invalid BGP router ID %s", config.RouterID)
+		}
+	}
+	for _, peerConfig := range config.Peers {
+		if net.ParseIP(peerConfig.Address) == nil {
+			return nil, fmt.Errorf("invalid address of BGP peer %s", peerConfig.Address)
+		}
+		if peerConfig.AS == 0 {
+			return nil, fmt.Errorf("AS of BGP peer %s is not configured", peerConfig.Address)
+		}
+		port := peerConfig.Port
+		if port == 0 {
+			port = defaultPort
+		}
+		s.peers = append(s.peers, &peer{
+			config:  peerConfig,
+			address: net.JoinHostPort(peerConfig.Address, strconv.Itoa(int(port))),
+			changes: make(chan struct{}, 1),
+		})
+	}
+	return s, nil
+}
+
+// Start starts the sessions with the peers, they are kept until the context is cancelled.
+// The sessions are established once the next hop is known.
+func (s *Speaker) Start(ctx context.Context) {
+	s.startOnce.Do(func() {
+		for _, p := range s.peers {
+			go s.runPeer(ctx, p)
+		}
+	})
+}
+
+// SetNextHop sets the next hop of the advertised routes, i.e. the IP address of the node.
+func (s *Speaker) SetNextHop(nextHop net.IP) {
+	s.Lock()
+	defer s.Unlock()
+
+	if nextHop.To4() == nil {
+		s.logger.Warnf("IPv4 next hop is required for BGP, %v ignored", nextHop)
+		return
+	}
+	if s.nextHop.Equal(nextHop) {
+		return
+	}
+	s.nextHop = nextHop.To4()
+	s.notifyPeers()
+}
+
+// Advertise starts advertising the route to the given prefix via this node.
+func (s *Speaker) Advertise(prefix *net.IPNet) {
+	s.Lock()
+	defer s.Unlock()
+
+	if prefix.IP.To4() == nil {
+		s.logger.Warnf("Only IPv4 routes are advertised by BGP, %v ignored", prefix)
+		return
+	}
+	if _, advertised := s.prefixes[prefix.String()]; advertised {
+		return
+	}
+	s.prefixes[prefix.String()] = prefix
+	s.notifyPeers()
+}
+
+// Withdraw stops advertising the route to the given prefix.
+func (s *Speaker) Withdraw(prefix *net.IPNet) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, advertised := s.prefixes[prefix.String()]; !advertised {
+		return
+	}
+	delete(s.prefixes, prefix.String())
+	s.notifyPeers()
+}
+
+// AdvertisedPrefixes returns the advertised prefixes sorted by their string representation.
+func (s *Speaker) AdvertisedPrefixes() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	var prefixes []string
+	for prefix := range s.prefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// EstablishedPeers returns the addresses of the peers with established session.
+func (s *Speaker) EstablishedPeers() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	var peers []string
+	for _, p := range s.peers {
+		if p.established {
+			peers = append(peers, p.config.Address)
+		}
+	}
+	return peers
+}
+
+// notifyPeers signals the change of the advertised routes to all sessions.
+// The method must be called with acquired mutex.
+func (s *Speaker) notifyPeers() {
+	for _, p := range s.peers {
+		select {
+		case p.changes <- struct{}{}:
+		default:
+			// the change is already signalled
+		}
+	}
+}
+
+// snapshot returns the current next hop, router ID and advertised prefixes.
+func (s *Speaker) snapshot() (nextHop net.IP, routerID net.IP, prefixes map[string]*net.IPNet) {
+	s.Lock()
+	defer s.Unlock()
+
+	prefixes = make(map[string]*net.IPNet)
+	for key, prefix := range s.prefixes {
+		prefixes[key] = prefix
+	}
+	routerID = s.routerID
+	if routerID == nil {
+		routerID = s.nextHop
+	}
+	return s.nextHop, routerID, prefixes
+}
+
+// setEstablished updates the state of the session with the peer.
+func (s *Speaker) setEstablished(p *peer, established bool) {
+	s.Lock()
+	defer s.Unlock()
+	p.established = established
+}
+
+// runPeer keeps (re-)establishing the session with the peer until the context is cancelled.
+func (s *Speaker) runPeer(ctx context.Context, p *peer) {
+	for {
+		if nextHop, _, _ := s.snapshot(); nextHop != nil {
+			conn, err := s.dial(p.address)
+			if err == nil {
+				err = s.session(ctx, p, conn)
+				conn.Close()
+				s.setEstablished(p, false)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.WithFields(logging.Fields{"peer": p.address, "err": err}).Warn("BGP session is down")
+		}
+
+		select {
+		case <-time.After(s.connectRetry):
+		case <-p.changes:
+			// the next hop may have been set, all routes are advertised once the session is established
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// session opens the session over the connection and advertises the routes until the session
+// fails or the context is cancelled.
+func (s *Speaker) session(ctx context.Context, p *peer, conn net.Conn) error {
+	nextHop, routerID, _ := s.snapshot()
+	external := p.config.AS != s.config.LocalAS
+
+	// exchange OPEN messages
+	if _, err := conn.Write(encodeOpen(&openMessage{
+		as: s.config.LocalAS, holdTime: uint16(s.holdTime / time.Second), routerID: routerID})); err != nil {
+		return err
+	}
+	conn.SetReadDeadline(time.Now().Add(openTimeout))
+	msgType, body, err := readMessage(conn)
+	if err != nil {
+		return err
+	}
+	if msgType != msgOpen {
+		return fmt.Errorf("expected OPEN message, received message type %d", msgType)
+	}
+	open, err := decodeOpen(body)
+	if err != nil {
+		return err
+	}
+	if open.as != p.config.AS {
+		conn.Write(encodeNotification(errCodeOpenMessage, errSubcodeBadAS))
+		return fmt.Errorf("peer AS %d does not match the configured AS %d", open.as, p.config.AS)
+	}
+	holdTime := s.holdTime
+	if peerHoldTime := time.Duration(open.holdTime) * time.Second; peerHoldTime < holdTime {
+		holdTime = peerHoldTime
+	}
+	if _, err := conn.Write(encodeKeepalive()); err != nil {
+		return err
+	}
+
+	// read messages from the peer, the first one has to confirm OPEN with KEEPALIVE
+	readErr := make(chan error, 1)
+	keepaliveRecv := make(chan struct{}, 1)
+	go func() {
+		for {
+			if holdTime > 0 {
+				conn.SetReadDeadline(time.Now().Add(holdTime))
+			} else {
+				conn.SetReadDeadline(time.Time{})
+			}
+			msgType, body, err := readMessage(conn)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			switch msgType {
+			case msgKeepalive:
+				select {
+				case keepaliveRecv <- struct{}{}:
+				default:
+				}
+			case msgNotification:
+				if len(body) >= 2 {
+					err = fmt.Errorf("NOTIFICATION received, code %d, subcode %d", body[0], body[1])
+				} else {
+					err = fmt.Errorf("NOTIFICATION received")
+				}
+				readErr <- err
+				return
+			case msgUpdate:
+				withdrawn, announced, _, err := decodeUpdate(body)
+				s.logger.WithFields(logging.Fields{"peer": p.address, "withdrawn": len(withdrawn),
+					"announced": len(announced), "err": err}).Debug("BGP UPDATE from the peer ignored")
+			}
+		}
+	}()
+	select {
+	case <-keepaliveRecv:
+	case err := <-readErr:
+		return err
+	case <-ctx.Done():
+		conn.Write(encodeNotification(errCodeCease, 0))
+		return nil
+	}
+	s.setEstablished(p, true)
+	s.logger.WithFields(logging.Fields{"peer": p.address, "holdTime": holdTime}).Info("BGP session established")
+
+	var keepalive <-chan time.Time
+	if holdTime > 0 {
+		ticker := time.NewTicker(holdTime / 3)
+		defer ticker.Stop()
+		keepalive = ticker.C
+	}
+	advertised := make(map[string]*net.IPNet)
+	advertisedNextHop := nextHop
+	initial := make(chan struct{}, 1)
+	initial <- struct{}{}
+	for {
+		select {
+		case <-initial:
+		case <-p.changes:
+		case <-keepaliveRecv:
+			continue
+		case <-keepalive:
+			if _, err := conn.Write(encodeKeepalive()); err != nil {
+				return err
+			}
+			continue
+		case err := <-readErr:
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				conn.Write(encodeNotification(errCodeHoldTimer, 0))
+				return fmt.Errorf("hold timer expired")
+			}
+			return err
+		case <-ctx.Done():
+			conn.Write(encodeNotification(errCodeCease, 0))
+			return nil
+		}
+
+		// advertise the changes of the routes
+		nextHop, _, prefixes := s.snapshot()
+		var withdrawn, announced []*net.IPNet
+		for key, prefix := range advertised {
+			if _, keep := prefixes[key]; !keep {
+				withdrawn = append(withdrawn, prefix)
+				delete(advertised, key)
+			}
+		}
+		for key, prefix := range prefixes {
+			if _, done := advertised[key]; !done || !nextHop.Equal(advertisedNextHop) {
+				announced = append(announced, prefix)
+				advertised[key] = prefix
+			}
+		}
+		advertisedNextHop = nextHop
+		if err := s.sendUpdates(conn, withdrawn, announced, nextHop, external, open.fourOctetAS); err != nil {
+			return err
+		}
+		if len(withdrawn) > 0 || len(announced) > 0 {
+			s.logger.WithFields(logging.Fields{"peer": p.address, "withdrawn": withdrawn,
+				"announced": announced, "nextHop": nextHop}).Debug("BGP routes updated")
+		}
+	}
+}
+
+// sendUpdates sends the withdrawn and announced prefixes in UPDATE messages of limited size.
+func (s *Speaker) sendUpdates(conn net.Conn, withdrawn, announced []*net.IPNet, nextHop net.IP,
+	external, fourOctetAS bool) error {
+	for len(withdrawn) > 0 {
+		batch := withdrawn
+		if len(batch) > maxPrefixesPerUpdate {
+			batch = batch[:maxPrefixesPerUpdate]
+		}
+		withdrawn = withdrawn[len(batch):]
+		if _, err := conn.Write(encodeUpdate(batch, nil, nil, s.config.LocalAS, external, fourOctetAS)); err != nil {
+			return err
+		}
+	}
+	for len(announced) > 0 {
+		batch := announced
+		if len(batch) > maxPrefixesPerUpdate {
+			batch = batch[:maxPrefixesPerUpdate]
+		}
+		announced = announced[len(batch):]
+		if _, err := conn.Write(encodeUpdate(nil, batch, nextHop, s.config.LocalAS, external, fourOctetAS)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
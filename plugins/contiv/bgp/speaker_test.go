@@ -0,0 +1,197 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+)
+
+func network(cidr string) *net.IPNet {
+	_, ipNet, _ := net.ParseCIDR(cidr)
+	return ipNet
+}
+
+// readUpdate reads messages from the connection until UPDATE is received.
+func readUpdate(conn net.Conn) (withdrawn, announced []*net.IPNet, nextHop net.IP) {
+	for {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		msgType, body, err := readMessage(conn)
+		gomega.Expect(err).To(gomega.BeNil())
+		if msgType == msgUpdate {
+			withdrawn, announced, nextHop, err = decodeUpdate(body)
+			gomega.Expect(err).To(gomega.BeNil())
+			return withdrawn, announced, nextHop
+		}
+	}
+}
+
+// pathAttributes returns the path attributes of UPDATE message by their type.
+func pathAttributes(body []byte) map[uint8][]byte {
+	withdrawnLen := int(binary.BigEndian.Uint16(body))
+	body = body[2+withdrawnLen:]
+	attrs := body[2 : 2+int(binary.BigEndian.Uint16(body))]
+	attributes := make(map[uint8][]byte)
+	for len(attrs) >= 3 {
+		attrLen := int(attrs[2])
+		attributes[attrs[1]] = attrs[3 : 3+attrLen]
+		attrs = attrs[3+attrLen:]
+	}
+	return attributes
+}
+
+func TestMessages(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	// OPEN with 4-octet AS
+	msgType, body, err := readMessageFrom(encodeOpen(&openMessage{as: 4200000001, holdTime: 90, routerID: net.ParseIP("1.2.3.4")}))
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(msgType).To(gomega.BeEquivalentTo(msgOpen))
+	open, err := decodeOpen(body)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(open.as).To(gomega.BeEquivalentTo(4200000001))
+	gomega.Expect(open.fourOctetAS).To(gomega.BeTrue())
+	gomega.Expect(open.holdTime).To(gomega.BeEquivalentTo(90))
+	gomega.Expect(open.routerID.String()).To(gomega.BeEquivalentTo("1.2.3.4"))
+
+	// UPDATE
+	_, body, err = readMessageFrom(encodeUpdate([]*net.IPNet{network("10.1.2.0/24")},
+		[]*net.IPNet{network("10.1.1.0/24"), network("10.96.0.10/32"), network("0.0.0.0/0")},
+		net.ParseIP("192.168.16.1"), 65000, true, false))
+	gomega.Expect(err).To(gomega.BeNil())
+	withdrawn, announced, nextHop, err := decodeUpdate(body)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(withdrawn).To(gomega.HaveLen(1))
+	gomega.Expect(withdrawn[0].String()).To(gomega.BeEquivalentTo("10.1.2.0/24"))
+	gomega.Expect(announced).To(gomega.HaveLen(3))
+	gomega.Expect(announced[1].String()).To(gomega.BeEquivalentTo("10.96.0.10/32"))
+	gomega.Expect(announced[2].String()).To(gomega.BeEquivalentTo("0.0.0.0/0"))
+	gomega.Expect(nextHop.String()).To(gomega.BeEquivalentTo("192.168.16.1"))
+	attributes := pathAttributes(body)
+	gomega.Expect(attributes[attrASPath]).To(gomega.Equal([]byte{asPathSequence, 1, 0xfd, 0xe8}))
+	gomega.Expect(attributes).NotTo(gomega.HaveKey(uint8(attrAS4Path)))
+
+	// UPDATE with 4-octet local AS for 2-octet peer carries the real AS in AS4_PATH
+	_, body, err = readMessageFrom(encodeUpdate(nil, []*net.IPNet{network("10.1.1.0/24")},
+		net.ParseIP("192.168.16.1"), 4200000001, true, false))
+	gomega.Expect(err).To(gomega.BeNil())
+	attributes = pathAttributes(body)
+	gomega.Expect(attributes[attrASPath]).To(gomega.Equal([]byte{asPathSequence, 1, 0x5b, 0xa0}))
+	gomega.Expect(attributes[attrAS4Path]).To(gomega.Equal([]byte{asPathSequence, 1, 0xfa, 0x56, 0xea, 0x01}))
+
+	// 4-octet peer gets the real AS in AS_PATH
+	_, body, err = readMessageFrom(encodeUpdate(nil, []*net.IPNet{network("10.1.1.0/24")},
+		net.ParseIP("192.168.16.1"), 4200000001, true, true))
+	gomega.Expect(err).To(gomega.BeNil())
+	attributes = pathAttributes(body)
+	gomega.Expect(attributes[attrASPath]).To(gomega.Equal([]byte{asPathSequence, 1, 0xfa, 0x56, 0xea, 0x01}))
+	gomega.Expect(attributes).NotTo(gomega.HaveKey(uint8(attrAS4Path)))
+}
+
+func TestSpeaker(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	gomega.Expect(err).To(gomega.BeNil())
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	// invalid config
+	_, err = NewSpeaker(logrus.DefaultLogger(), Config{Peers: []PeerConfig{{Address: "127.0.0.1", AS: 65001}}})
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	_, err = NewSpeaker(logrus.DefaultLogger(), Config{LocalAS: 65000, Peers: []PeerConfig{{Address: "router", AS: 65001}}})
+	gomega.Expect(err).NotTo(gomega.BeNil())
+
+	speaker, err := NewSpeaker(logrus.DefaultLogger(), Config{
+		LocalAS: 65000,
+		Peers:   []PeerConfig{{Address: "127.0.0.1", AS: 65001, Port: uint16(port)}},
+	})
+	gomega.Expect(err).To(gomega.BeNil())
+	speaker.connectRetry = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	speaker.Start(ctx)
+
+	// the session is opened only once the next hop is known
+	speaker.Advertise(network("10.1.1.0/24"))
+	speaker.Advertise(network("fd00:10:1:1::/64"))
+	gomega.Expect(speaker.AdvertisedPrefixes()).To(gomega.Equal([]string{"10.1.1.0/24"}))
+	speaker.SetNextHop(net.ParseIP("192.168.16.1"))
+
+	conn, err := listener.Accept()
+	gomega.Expect(err).To(gomega.BeNil())
+	defer conn.Close()
+	msgType, body, err := readMessage(conn)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(msgType).To(gomega.BeEquivalentTo(msgOpen))
+	open, err := decodeOpen(body)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(open.as).To(gomega.BeEquivalentTo(65000))
+	gomega.Expect(open.routerID.String()).To(gomega.BeEquivalentTo("192.168.16.1"))
+	conn.Write(encodeOpen(&openMessage{as: 65001, holdTime: 30, routerID: net.ParseIP("192.168.16.254")}))
+	conn.Write(encodeKeepalive())
+
+	// the routes are advertised once the session is established
+	_, announced, nextHop := readUpdate(conn)
+	gomega.Expect(announced).To(gomega.HaveLen(1))
+	gomega.Expect(announced[0].String()).To(gomega.BeEquivalentTo("10.1.1.0/24"))
+	gomega.Expect(nextHop.String()).To(gomega.BeEquivalentTo("192.168.16.1"))
+	gomega.Eventually(speaker.EstablishedPeers).Should(gomega.Equal([]string{"127.0.0.1"}))
+
+	// changes of the routes
+	speaker.Advertise(network("10.96.0.10/32"))
+	_, announced, _ = readUpdate(conn)
+	gomega.Expect(announced).To(gomega.HaveLen(1))
+	gomega.Expect(announced[0].String()).To(gomega.BeEquivalentTo("10.96.0.10/32"))
+
+	speaker.Withdraw(network("10.96.0.10/32"))
+	withdrawn, _, _ := readUpdate(conn)
+	gomega.Expect(withdrawn).To(gomega.HaveLen(1))
+	gomega.Expect(withdrawn[0].String()).To(gomega.BeEquivalentTo("10.96.0.10/32"))
+
+	// all routes are re-advertised with the new next hop
+	speaker.SetNextHop(net.ParseIP("192.168.16.2"))
+	_, announced, nextHop = readUpdate(conn)
+	gomega.Expect(announced).To(gomega.HaveLen(1))
+	gomega.Expect(nextHop.String()).To(gomega.BeEquivalentTo("192.168.16.2"))
+
+	// the session is re-established after failure
+	conn.Close()
+	conn, err = listener.Accept()
+	gomega.Expect(err).To(gomega.BeNil())
+	defer conn.Close()
+	readMessage(conn)
+	conn.Write(encodeOpen(&openMessage{as: 65002, holdTime: 30, routerID: net.ParseIP("192.168.16.254")}))
+
+	// peer with unexpected AS is refused
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msgType, body, err = readMessage(conn)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(msgType).To(gomega.BeEquivalentTo(msgNotification))
+	gomega.Expect(body[0]).To(gomega.BeEquivalentTo(errCodeOpenMessage))
+	gomega.Expect(speaker.EstablishedPeers()).To(gomega.BeEmpty())
+}
+
+// readMessageFrom reads one message from the encoded bytes.
+func readMessageFrom(msg []byte) (uint8, []byte, error) {
+	return readMessage(bytes.NewReader(msg))
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/interfaces"
+)
+
+const (
+	// bondModeActiveBackup selects the active-backup bonding mode.
+	bondModeActiveBackup = "active-backup"
+
+	// bondModeLACP selects the 802.3ad (LACP) bonding mode.
+	bondModeLACP = "lacp"
+
+	// bondIfPrefix is the prefix of the names VPP gives to the bond interfaces.
+	bondIfPrefix = "BondEthernet"
+)
+
+// bondIfNameRegexp matches the name of a bond interface created by VPP.
+var bondIfNameRegexp = regexp.MustCompile(`^` + bondIfPrefix + `\d+$`)
+
+// BondConfig configures the bond interface aggregating multiple NICs, used as the main VPP interface.
+type BondConfig struct {
+	Mode        string   // active-backup or lacp (802.3ad), lacp by default
+	LoadBalance string   // hashing of the lacp mode: l2, l23 or l34, l2 by default
+	Members     []string // names of the aggregated NICs
+}
+
+// validateBond checks the bond config of the node.
+func validateBond(nodeConfig *OneNodeConfig) error {
+	if nodeConfig == nil || len(nodeConfig.Bond.Members) == 0 {
+		return nil
+	}
+	bond := nodeConfig.Bond
+	switch bond.Mode {
+	case "", bondModeLACP:
+		switch bond.LoadBalance {
+		case "", "l2", "l23", "l34":
+		default:
+			return fmt.Errorf("unknown load-balance %q of the bond", bond.LoadBalance)
+		}
+	case bondModeActiveBackup:
+		if bond.LoadBalance != "" {
+			return errors.New("load-balance of the bond is supported only in the lacp mode")
+		}
+	default:
+		return fmt.Errorf("unknown bond mode %q", bond.Mode)
+	}
+	if nodeConfig.MainVppInterface.InterfaceName != "" {
+		return errors.New("the main VPP interface is the bond, its name is assigned by VPP")
+	}
+	return nil
+}
+
+// configureBond creates the bond interface over the configured NICs. It is called before the resync
+// of the VPP agent, which then registers the bond the same way as the physical NICs, so that it can be
+// configured as the main VPP interface. The bond created by the previous run of the agent is reused.
+func (s *remoteCNIserver) configureBond() error {
+	if s.nodeConfig == nil || len(s.nodeConfig.Bond.Members) == 0 {
+		return nil
+	}
+	bond := s.nodeConfig.Bond
+
+	ifName, err := s.findBondInterface()
+	if err != nil {
+		return err
+	}
+	if ifName == "" {
+		mode := bond.Mode
+		if mode == "" {
+			mode = bondModeLACP
+		}
+		command := "create bond mode " + mode
+		if bond.LoadBalance != "" {
+			command += " load-balance " + bond.LoadBalance
+		}
+		output, err := s.execVppCLI(command)
+		if err != nil {
+			return fmt.Errorf("VPP CLI 'create bond' failed: %v", err)
+		}
+		// the name of the created interface is printed
+		ifName = strings.TrimSpace(output)
+		if !bondIfNameRegexp.MatchString(ifName) {
+			return fmt.Errorf("VPP CLI 'create bond' failed: %s", ifName)
+		}
+	}
+
+	for _, member := range bond.Members {
+		output, err := s.execVppCLI(fmt.Sprintf("enslave interface %s %s", ifName, member))
+		if err == nil && output != "" && !strings.Contains(output, "already") {
+			err = errors.New(strings.TrimSpace(output))
+		}
+		if err != nil {
+			return fmt.Errorf("Can't add %s into bond %s: %v", member, ifName, err)
+		}
+		if err := s.execConfigCLI("set interface state %s up", member); err != nil {
+			return err
+		}
+	}
+	s.bondIfName = ifName
+	if len(s.uplinks) > 0 {
+		s.uplinks[0].ifName = ifName
+	}
+	s.Logger.Infof("Bond %s aggregates %v", s.bondIfName, bond.Members)
+	return nil
+}
+
+// findBondInterface returns the name of the bond interface existing in VPP, empty if there is none.
+func (s *remoteCNIserver) findBondInterface() (string, error) {
+	req := &interfaces.SwInterfaceDump{
+		NameFilterValid: 1,
+		NameFilter:      []byte(bondIfPrefix),
+	}
+	reqCtx := s.govppChan.SendMultiRequest(req)
+	ifName := ""
+	for {
+		details := &interfaces.SwInterfaceDetails{}
+		stop, err := reqCtx.ReceiveReply(details)
+		if stop {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		name := strings.TrimRight(string(details.InterfaceName), "\x00")
+		if ifName == "" && bondIfNameRegexp.MatchString(name) {
+			ifName = name
+		}
+	}
+	return ifName, nil
+}
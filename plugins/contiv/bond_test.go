@@ -0,0 +1,99 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestBond(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	bondNodeConfig := OneNodeConfig{
+		NodeName: "test-node",
+		MainVppInterface: InterfaceWithIP{
+			IP: "192.168.1.1/24",
+		},
+		Bond: BondConfig{
+			Mode:        bondModeLACP,
+			LoadBalance: "l34",
+			Members:     []string{"GigabitEthernet0/8/0", "GigabitEthernet0/9/0"},
+		},
+	}
+	server, txns, _, conn := setupTestCNIServer(&configTapVxlanTCP, &bondNodeConfig,
+		"GigabitEthernet0/8/0", "GigabitEthernet0/9/0", "BondEthernet0")
+	defer conn.Disconnect()
+
+	// the bond is created over the member NICs
+	var commands []string
+	server.execVppCLI = func(command string) (string, error) {
+		commands = append(commands, command)
+		if strings.HasPrefix(command, "create bond") {
+			return "BondEthernet0\n", nil
+		}
+		return "", nil
+	}
+	err := server.configureBond()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(commands).To(gomega.Equal([]string{
+		"create bond mode lacp load-balance l34",
+		"enslave interface BondEthernet0 GigabitEthernet0/8/0",
+		"set interface state GigabitEthernet0/8/0 up",
+		"enslave interface BondEthernet0 GigabitEthernet0/9/0",
+		"set interface state GigabitEthernet0/9/0 up",
+	}))
+
+	// the bond is the main VPP interface
+	err = server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.mainPhysicalIf).To(gomega.Equal("BondEthernet0"))
+	bond := interfaceInSnapshot(txns.AppliedConfig, "BondEthernet0")
+	gomega.Expect(bond).NotTo(gomega.BeNil())
+	gomega.Expect(bond.IpAddresses).To(gomega.ConsistOf("192.168.1.1/24"))
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, "GigabitEthernet0/8/0")).To(gomega.BeNil())
+
+	// members already in the bond are accepted, other errors are reported
+	server.execVppCLI = func(command string) (string, error) {
+		if strings.HasPrefix(command, "create bond") {
+			return "BondEthernet1\n", nil
+		}
+		if strings.HasPrefix(command, "enslave") {
+			return "interface is already a slave\n", nil
+		}
+		return "", nil
+	}
+	gomega.Expect(server.configureBond()).To(gomega.Succeed())
+	server.execVppCLI = func(command string) (string, error) {
+		if strings.HasPrefix(command, "create bond") {
+			return "create bond: unknown input `mode lacp'\n", nil
+		}
+		return "", nil
+	}
+	gomega.Expect(server.configureBond()).NotTo(gomega.Succeed())
+
+	// invalid configs are refused
+	bondNodeConfig.Bond.Mode = bondModeActiveBackup
+	gomega.Expect(validateBond(&bondNodeConfig)).NotTo(gomega.Succeed())
+	bondNodeConfig.Bond.LoadBalance = ""
+	gomega.Expect(validateBond(&bondNodeConfig)).To(gomega.Succeed())
+	bondNodeConfig.Bond.Mode = "round-robin"
+	gomega.Expect(validateBond(&bondNodeConfig)).NotTo(gomega.Succeed())
+	bondNodeConfig.Bond.Mode = ""
+	bondNodeConfig.MainVppInterface.InterfaceName = "GigabitEthernet0/8/0"
+	gomega.Expect(validateBond(&bondNodeConfig)).NotTo(gomega.Succeed())
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	"github.com/gogo/protobuf/proto"
+	linux_l3 "github.com/ligato/vpp-agent/plugins/linuxplugin/l3plugin/model/l3"
+)
+
+// nonReloadableConfig returns the given config without the settings applied at runtime by reloadConfig.
+func nonReloadableConfig(config Config) Config {
+	config.IPAMConfig.ServiceCIDR = ""
+	config.FlowExport = FlowExportConfig{}
+	config.NodeConfig = nil
+	return config
+}
+
+// watchConfigReload reloads the config file on SIGHUP until the context of the plugin is cancelled.
+func (plugin *Plugin) watchConfigReload() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+	for {
+		select {
+		case <-sigChan:
+			if err := plugin.reloadConfig(); err != nil {
+				plugin.Log.Errorf("Reload of the configuration failed: %v", err)
+			}
+		case <-plugin.ctx.Done():
+			return
+		}
+	}
+}
+
+// reloadConfig re-reads the config file and applies the changes of the service CIDR, of the flow export
+// and of the node config without restart of the agent (and of the pods). Other changes take effect
+// after the restart.
+func (plugin *Plugin) reloadConfig() error {
+	newConfig, err := plugin.readExternalConfig()
+	if err != nil {
+		return err
+	}
+	plugin.Log.Info("Reloading configuration")
+	if !reflect.DeepEqual(nonReloadableConfig(*plugin.fileConfig), nonReloadableConfig(*newConfig)) {
+		plugin.Log.Warn("Changes of the configuration other than ServiceCIDR, FlowExport and NodeConfig " +
+			"take effect after the restart of the agent")
+	}
+	err = plugin.cniServer.reloadConfig(newConfig.IPAMConfig.ServiceCIDR, newConfig.FlowExport,
+		loadNodeSpecificConfig(newConfig, plugin.ServiceLabel.GetAgentLabel()))
+	plugin.fileConfig.IPAMConfig.ServiceCIDR = newConfig.IPAMConfig.ServiceCIDR
+	plugin.fileConfig.FlowExport = newConfig.FlowExport
+	plugin.fileConfig.NodeConfig = newConfig.NodeConfig
+	return err
+}
+
+// reloadConfig applies the settings of the reloaded config file that can change at runtime. The node config
+// is applied unless it is overridden by the entry of the node in the data store. The settings are applied
+// by the event loop once the base vswitch config is applied.
+func (s *remoteCNIserver) reloadConfig(serviceCIDR string, flowExport FlowExportConfig, nodeConfig *OneNodeConfig) error {
+	return s.loop.process("reload of the config", nodePriority, func() error {
+		return s.applyReloadedConfig(serviceCIDR, flowExport, nodeConfig)
+	})
+}
+
+// applyReloadedConfig applies the settings of the reloaded config file, the method is run by the event loop.
+func (s *remoteCNIserver) applyReloadedConfig(serviceCIDR string, flowExport FlowExportConfig, nodeConfig *OneNodeConfig) error {
+	var wasErr error
+	if err := s.changeServiceNetwork(serviceCIDR); err != nil {
+		s.Logger.Error(err)
+		wasErr = err
+	}
+
+	if flowExport != s.flowExport {
+		if (flowExport.CollectorAddress != "") != s.flowExportEnabled() {
+			s.Logger.Warn("Enabling or disabling of the flow export takes effect after the restart of the agent")
+		} else {
+			s.flowExport = flowExport
+			if err := s.configureFlowExport(); err != nil {
+				s.Logger.Error(err)
+				wasErr = err
+			}
+		}
+	}
+
+	s.fileNodeConfig = nodeConfig
+	if s.nodeConfigStored {
+		s.Logger.Info("Configuration of the node stored in the data store overrides the config file")
+	} else if err := s.applyNodeConfig(nodeConfig); err != nil {
+		s.Logger.Error(err)
+		wasErr = err
+	}
+	return wasErr
+}
+
+// changeServiceNetwork changes the range allocated for services and the route of the services from the host.
+// The method must be called with acquired mutex guarding remoteCNI server.
+func (s *remoteCNIserver) changeServiceNetwork(serviceCIDR string) error {
+	oldServiceNetwork := s.ipam.ServiceNetwork().String()
+	if err := s.ipam.SetServiceNetwork(serviceCIDR); err != nil {
+		return fmt.Errorf("invalid service CIDR %s: %v", serviceCIDR, err)
+	}
+	if s.ipam.ServiceNetwork().String() == oldServiceNetwork {
+		return nil
+	}
+	s.Logger.Infof("Service network changed from %s to %s", oldServiceNetwork, s.ipam.ServiceNetwork())
+
+	route := s.routeServicesFromHost()
+	err := s.vppTxnFactory().Put().LinuxRoute(route).Send().ReceiveReply()
+	if err != nil {
+		return fmt.Errorf("Can't configure the route of the services from the host: %v", err)
+	}
+	return s.persistChanges(nil, map[string]proto.Message{linux_l3.StaticRouteKey(route.Name): route})
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"testing"
+
+	linux_l3 "github.com/ligato/vpp-agent/plugins/linuxplugin/l3plugin/model/l3"
+	"github.com/onsi/gomega"
+)
+
+func TestReloadConfig(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, txns, _, conn := setupTestCNIServer(&configTapVxlanTCP, &nodeConfig,
+		"GigabitEthernet0/0/0/1", "GigabitEthernet0/0/0/10")
+	defer conn.Disconnect()
+
+	err := server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+	serviceRoute := txns.AppliedConfig[linux_l3.StaticRouteKey("service-to-vpp")].(*linux_l3.LinuxStaticRoutes_Route)
+	gomega.Expect(serviceRoute.DstIpAddr).To(gomega.Equal("10.96.0.0/12"))
+
+	// the new service CIDR and node config are applied
+	reloadedNodeConfig := nodeConfig
+	reloadedNodeConfig.Gateway = "192.168.1.254"
+	err = server.reloadConfig("10.100.0.0/16", FlowExportConfig{}, &reloadedNodeConfig)
+	gomega.Expect(err).To(gomega.BeNil())
+	serviceRoute = txns.AppliedConfig[linux_l3.StaticRouteKey("service-to-vpp")].(*linux_l3.LinuxStaticRoutes_Route)
+	gomega.Expect(serviceRoute.DstIpAddr).To(gomega.Equal("10.100.0.0/16"))
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "192.168.1.254")).To(gomega.HaveLen(1))
+
+	// invalid service CIDR is refused
+	err = server.reloadConfig("10.100.0.0/33", FlowExportConfig{}, &reloadedNodeConfig)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	gomega.Expect(server.ipam.ServiceNetwork().String()).To(gomega.Equal("10.100.0.0/16"))
+
+	// the node config stored in the data store has priority over the config file
+	server.nodeConfigStored = true
+	err = server.reloadConfig("10.100.0.0/16", FlowExportConfig{}, &nodeConfig)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "192.168.1.254")).To(gomega.HaveLen(1))
+	gomega.Expect(server.fileNodeConfig).To(gomega.Equal(&nodeConfig))
+}
@@ -0,0 +1,446 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.fd.io/govpp.git/api"
+	"github.com/contiv/vpp/flavors/ksr"
+	"github.com/contiv/vpp/plugins/contiv/ipam"
+	"github.com/contiv/vpp/plugins/contiv/model/connectivity"
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
+	"github.com/contiv/vpp/plugins/kvstore"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/cn-infra/servicelabel"
+	"github.com/unrolled/render"
+)
+
+const (
+	// connectivityKeyPrefix is the prefix of the keys under which the results of the connectivity check
+	// are stored for each node.
+	connectivityKeyPrefix = "connectivity/"
+
+	// connectivityURL is the URL of the REST API exposing the connectivity matrix of the cluster.
+	connectivityURL = "/contiv/v1/connectivity"
+
+	// defaultConnectivityProbeSize is the default size of the large probes, close to the MTU of pod interfaces
+	// with the VXLAN overhead.
+	defaultConnectivityProbeSize = 1400
+
+	// connectivityProbeCount is the number of ICMP echo requests sent to each target per size.
+	connectivityProbeCount = 3
+
+	// connectivityPingTimeout is the reply timeout of the channel used to ping, the ping CLI replies
+	// only after all the requests are sent.
+	connectivityPingTimeout = 5 * time.Second
+
+	// kinds of the probed targets
+	nodeTargetKind = "node"
+	podTargetKind  = "pod"
+)
+
+// ConnectivityCheckConfig configures the periodic check of the connectivity to other nodes of the cluster.
+type ConnectivityCheckConfig struct {
+	Interval     uint32 // period of the check in seconds, the check is disabled if 0
+	ProbeSize    uint32 // size of the large probes detecting MTU blackholes in bytes, 1400 by default
+	TestPodLabel string // label (key=value) of the test pods probed on other nodes, pods are not probed if empty
+}
+
+// pingResult is the outcome of ICMP echo requests sent to a target.
+type pingResult struct {
+	sent     int
+	received int
+	rtt      time.Duration // average round-trip time of the received replies
+}
+
+// connectivityStore persists the results of the connectivity checks of all nodes.
+type connectivityStore interface {
+	// PutConnectivity creates or overwrites the results of the node.
+	PutConnectivity(results *connectivity.NodeConnectivity) error
+
+	// ListConnectivity returns the results of all nodes.
+	ListConnectivity() ([]*connectivity.NodeConnectivity, error)
+}
+
+// connectivityChecker periodically probes the BVI/VXLAN endpoint of every other node and the test pods
+// running on them by ICMP echo requests sent from VPP. Besides the small probes, probes of the configured
+// size are sent to detect MTU blackholes - paths passing small packets only. The results are stored
+// in the key-value data store, so that the connectivity matrix of the whole cluster can be read
+// from any node.
+type connectivityChecker struct {
+	logger    logging.Logger
+	store     connectivityStore
+	nodeName  string
+	probeSize uint32
+
+	// listNodes returns the nodes of the cluster with allocated IDs
+	listNodes func() ([]*node.NodeInfo, error)
+
+	// nodeEndpoint returns the address of the BVI/VXLAN endpoint of the node
+	nodeEndpoint func(nodeInfo *node.NodeInfo) (net.IP, error)
+
+	// listTestPods returns the test pods of the cluster with their node IDs, nil if pods are not probed
+	listTestPods func() (map[string]uint32, error)
+
+	// ping sends ICMP echo requests of the given size to the address
+	ping func(address string, size uint32) (*pingResult, error)
+}
+
+// newConnectivityChecker creates new instance of connectivityChecker.
+func newConnectivityChecker(logger logging.Logger, store connectivityStore, nodeName string, probeSize uint32,
+	listNodes func() ([]*node.NodeInfo, error), nodeEndpoint func(nodeInfo *node.NodeInfo) (net.IP, error),
+	listTestPods func() (map[string]uint32, error),
+	ping func(address string, size uint32) (*pingResult, error)) *connectivityChecker {
+	if probeSize == 0 {
+		probeSize = defaultConnectivityProbeSize
+	}
+	return &connectivityChecker{
+		logger:       logger,
+		store:        store,
+		nodeName:     nodeName,
+		probeSize:    probeSize,
+		listNodes:    listNodes,
+		nodeEndpoint: nodeEndpoint,
+		listTestPods: listTestPods,
+		ping:         ping,
+	}
+}
+
+// run periodically checks the connectivity until the context is cancelled.
+func (c *connectivityChecker) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := c.check()
+			if err != nil {
+				c.logger.Errorf("Connectivity check failed: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check probes all targets on other nodes and stores the results.
+func (c *connectivityChecker) check() error {
+	nodes, err := c.listNodes()
+	if err != nil {
+		return err
+	}
+	results := &connectivity.NodeConnectivity{
+		NodeName:  c.nodeName,
+		Timestamp: time.Now().Unix(),
+	}
+	nodeNames := map[uint32]string{}
+	for _, nodeInfo := range nodes {
+		nodeNames[nodeInfo.Id] = nodeInfo.Name
+		if nodeInfo.Name == c.nodeName {
+			results.NodeId = nodeInfo.Id
+		}
+	}
+
+	for _, nodeInfo := range nodes {
+		if nodeInfo.Name == c.nodeName {
+			continue
+		}
+		target := &connectivity.NodeConnectivity_Target{
+			NodeName: nodeInfo.Name,
+			NodeId:   nodeInfo.Id,
+			Kind:     nodeTargetKind,
+		}
+		endpoint, err := c.nodeEndpoint(nodeInfo)
+		if err != nil {
+			target.Error = err.Error()
+		} else {
+			target.Address = endpoint.String()
+			c.probe(target)
+		}
+		results.Target = append(results.Target, target)
+	}
+
+	if c.listTestPods != nil {
+		pods, err := c.listTestPods()
+		if err != nil {
+			return err
+		}
+		for podIP, nodeID := range pods {
+			if nodeID == results.NodeId {
+				continue
+			}
+			target := &connectivity.NodeConnectivity_Target{
+				NodeName: nodeNames[nodeID],
+				NodeId:   nodeID,
+				Kind:     podTargetKind,
+				Address:  podIP,
+			}
+			c.probe(target)
+			results.Target = append(results.Target, target)
+		}
+	}
+
+	sort.Slice(results.Target, func(i, j int) bool {
+		if results.Target[i].NodeId != results.Target[j].NodeId {
+			return results.Target[i].NodeId < results.Target[j].NodeId
+		}
+		return results.Target[i].Kind < results.Target[j].Kind
+	})
+	for _, target := range results.Target {
+		switch {
+		case target.Error != "":
+			c.logger.Warnf("Unable to probe %s %s of the node %s: %s", target.Kind, target.Address, target.NodeName,
+				target.Error)
+		case !target.Reachable:
+			c.logger.Warnf("%s %s of the node %s is not reachable", target.Kind, target.Address, target.NodeName)
+		case !target.LargeProbeReachable:
+			c.logger.Warnf("Probes of %d bytes to %s %s of the node %s are lost, MTU of the path is lower",
+				c.probeSize, target.Kind, target.Address, target.NodeName)
+		}
+	}
+	return c.store.PutConnectivity(results)
+}
+
+// probe sends the small and the large probes to the target and records the results.
+func (c *connectivityChecker) probe(target *connectivity.NodeConnectivity_Target) {
+	result, err := c.ping(target.Address, 0)
+	if err != nil {
+		target.Error = err.Error()
+		return
+	}
+	target.Reachable = result.received > 0
+	target.RttUs = uint32(result.rtt / time.Microsecond)
+	if !target.Reachable {
+		return
+	}
+
+	result, err = c.ping(target.Address, c.probeSize)
+	if err != nil {
+		target.Error = err.Error()
+		return
+	}
+	target.LargeProbeReachable = result.received > 0
+}
+
+// pingStatsRegexp matches the statistics printed by the VPP ping CLI, e.g. "Statistics: 3 sent, 3 received, 0% packet loss".
+var pingStatsRegexp = regexp.MustCompile(`Statistics: (\d+) sent, (\d+) received`)
+
+// pingRTTRegexp matches the round-trip time of a reply printed by the VPP ping CLI, e.g. "time=.2683 ms".
+var pingRTTRegexp = regexp.MustCompile(`time=([0-9.]+) ms`)
+
+// vppPing sends ICMP echo requests from VPP using the ping CLI. Size 0 selects the default size of VPP.
+func vppPing(ch *api.Channel, address string, size uint32) (*pingResult, error) {
+	command := fmt.Sprintf("ping %s repeat %d interval 0.1", address, connectivityProbeCount)
+	if size > 0 {
+		command += fmt.Sprintf(" size %d", size)
+	}
+	output, err := vppCLI(ch, command)
+	if err != nil {
+		return nil, err
+	}
+	return parsePingOutput(output)
+}
+
+// parsePingOutput parses the output of the VPP ping CLI.
+func parsePingOutput(output string) (*pingResult, error) {
+	stats := pingStatsRegexp.FindStringSubmatch(output)
+	if stats == nil {
+		return nil, fmt.Errorf("unexpected output of ping: %s", strings.TrimSpace(output))
+	}
+	result := &pingResult{}
+	result.sent, _ = strconv.Atoi(stats[1])
+	result.received, _ = strconv.Atoi(stats[2])
+
+	var total float64
+	rtts := pingRTTRegexp.FindAllStringSubmatch(output, -1)
+	for _, rtt := range rtts {
+		ms, _ := strconv.ParseFloat(rtt[1], 64)
+		total += ms
+	}
+	if len(rtts) > 0 {
+		result.rtt = time.Duration(total / float64(len(rtts)) * float64(time.Millisecond))
+	}
+	return result, nil
+}
+
+// connectivityEndpoint returns the address of the node probed by the connectivity check: the BVI
+// of the VXLAN bridge domain, or the node IP with L2 interconnect.
+func (s *remoteCNIserver) connectivityEndpoint(nodeInfo *node.NodeInfo) (net.IP, error) {
+	if s.useL2Interconnect {
+		ip := net.ParseIP(s.otherHostIP(uint8(nodeInfo.Id), nodeInfo.IpAddress))
+		if ip == nil {
+			return nil, fmt.Errorf("IP address of the node is not known")
+		}
+		return ip, nil
+	}
+	return s.ipam.VxlanIPAddress(uint8(nodeInfo.Id))
+}
+
+// kvConnectivityStore persists the results of the connectivity checks in the key-value data store
+// under connectivityKeyPrefix of the KSR microservice.
+type kvConnectivityStore struct {
+	broker keyval.ProtoBroker
+}
+
+// newKVConnectivityStore creates new instance of kvConnectivityStore.
+func newKVConnectivityStore(store kvstore.KvStore) *kvConnectivityStore {
+	return &kvConnectivityStore{
+		broker: store.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel)),
+	}
+}
+
+// PutConnectivity overwrites the results of the node.
+func (s *kvConnectivityStore) PutConnectivity(results *connectivity.NodeConnectivity) error {
+	return s.broker.Put(connectivityKeyPrefix+results.NodeName, results)
+}
+
+// ListConnectivity returns the results of all nodes.
+func (s *kvConnectivityStore) ListConnectivity() ([]*connectivity.NodeConnectivity, error) {
+	var results []*connectivity.NodeConnectivity
+	it, err := s.broker.ListValues(connectivityKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+		nodeResults := &connectivity.NodeConnectivity{}
+		err = kv.GetValue(nodeResults)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, nodeResults)
+	}
+	return results, nil
+}
+
+// startConnectivityCheck starts the periodic connectivity check if enabled by the config.
+func (plugin *Plugin) startConnectivityCheck() error {
+	config := plugin.Config.ConnectivityCheck
+	if config.Interval == 0 {
+		return nil
+	}
+	var err error
+	plugin.connectivityChan, err = plugin.GoVPP.NewAPIChannel()
+	if err != nil {
+		return err
+	}
+	plugin.connectivityChan.SetReplyTimeout(connectivityPingTimeout)
+	plugin.connectivityStore = newKVConnectivityStore(plugin.KVStore)
+
+	var listTestPods func() (map[string]uint32, error)
+	if config.TestPodLabel != "" {
+		listTestPods = plugin.listTestPods
+	}
+	ping := func(address string, size uint32) (*pingResult, error) {
+		return vppPing(plugin.connectivityChan, address, size)
+	}
+	checker := newConnectivityChecker(plugin.Log, plugin.connectivityStore, plugin.ServiceLabel.GetAgentLabel(),
+		config.ProbeSize, plugin.NodeIDStore.ListEntries, plugin.cniServer.connectivityEndpoint, listTestPods, ping)
+	go checker.run(plugin.ctx, time.Duration(config.Interval)*time.Second)
+	return nil
+}
+
+// listTestPods returns the IP addresses of the pods with the test pod label reflected by KSR together
+// with the IDs of their nodes, derived from the pod networks the addresses belong to.
+func (plugin *Plugin) listTestPods() (map[string]uint32, error) {
+	label := strings.SplitN(plugin.Config.ConnectivityCheck.TestPodLabel, "=", 2)
+	if len(label) == 1 {
+		label = append(label, "")
+	}
+	broker := plugin.KVStore.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel))
+	it, err := broker.ListValues(podmodel.KeyPrefix())
+	if err != nil {
+		return nil, err
+	}
+	pods := map[string]uint32{}
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+		k8sPod := &podmodel.Pod{}
+		err = kv.GetValue(k8sPod)
+		if err != nil {
+			return nil, err
+		}
+		if k8sPod.IpAddress == "" || !hasPodLabel(k8sPod, label[0], label[1]) {
+			continue
+		}
+		nodeInfo, found, err := plugin.nodeCache.lookupPodIP(net.ParseIP(k8sPod.IpAddress))
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			pods[k8sPod.IpAddress] = nodeInfo.Id
+		}
+	}
+	return pods, nil
+}
+
+// nodePodNetwork returns the pod network of the node, either assigned by k8s or derived from the node ID.
+func nodePodNetwork(ipamInst *ipam.IPAM, nodeInfo *node.NodeInfo) *net.IPNet {
+	if nodeInfo.PodNetwork != "" {
+		if _, podNetwork, err := net.ParseCIDR(nodeInfo.PodNetwork); err == nil {
+			return podNetwork
+		}
+	}
+	podNetwork, err := ipamInst.OtherNodePodNetwork(uint8(nodeInfo.Id))
+	if err != nil {
+		return &net.IPNet{}
+	}
+	return podNetwork
+}
+
+// hasPodLabel returns true if the pod carries the label with the given key and value.
+func hasPodLabel(k8sPod *podmodel.Pod, key, value string) bool {
+	for _, label := range k8sPod.Label {
+		if label.Key == key && label.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// connectivityHandler processes requests to read the connectivity matrix of the cluster.
+func (plugin *Plugin) connectivityHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		results, err := plugin.connectivityStore.ListConnectivity()
+		if err != nil {
+			plugin.Log.Errorf("Unable to read results of the connectivity check: %v", err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		if results == nil {
+			results = []*connectivity.NodeConnectivity{}
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].NodeId < results[j].NodeId })
+		formatter.JSON(w, http.StatusOK, results)
+	}
+}
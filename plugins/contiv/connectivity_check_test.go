@@ -0,0 +1,144 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/contiv/vpp/plugins/contiv/model/connectivity"
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/gorilla/mux"
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+	"github.com/unrolled/render"
+)
+
+// memConnectivityStore is connectivityStore keeping the results in memory.
+type memConnectivityStore struct {
+	results map[string]*connectivity.NodeConnectivity
+}
+
+func (s *memConnectivityStore) PutConnectivity(results *connectivity.NodeConnectivity) error {
+	s.results[results.NodeName] = results
+	return nil
+}
+
+func (s *memConnectivityStore) ListConnectivity() ([]*connectivity.NodeConnectivity, error) {
+	var results []*connectivity.NodeConnectivity
+	for _, nodeResults := range s.results {
+		results = append(results, nodeResults)
+	}
+	return results, nil
+}
+
+func TestConnectivityCheck(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	nodes := []*node.NodeInfo{
+		{Id: 1, Name: "node1"},
+		{Id: 2, Name: "node2"},
+		{Id: 3, Name: "node3"},
+		{Id: 4, Name: "node4"},
+	}
+	listNodes := func() ([]*node.NodeInfo, error) { return nodes, nil }
+	nodeEndpoint := func(nodeInfo *node.NodeInfo) (net.IP, error) {
+		return net.IPv4(192, 168, 30, byte(nodeInfo.Id)), nil
+	}
+	listTestPods := func() (map[string]uint32, error) {
+		return map[string]uint32{"10.1.1.5": 1, "10.1.2.5": 2}, nil
+	}
+
+	// node3 is down, the path to node4 drops large packets
+	pinged := []string{}
+	ping := func(address string, size uint32) (*pingResult, error) {
+		pinged = append(pinged, fmt.Sprintf("%s/%d", address, size))
+		switch {
+		case address == "192.168.30.3":
+			return &pingResult{sent: 3}, nil
+		case address == "192.168.30.4" && size > 0:
+			return &pingResult{sent: 3}, nil
+		}
+		return &pingResult{sent: 3, received: 3, rtt: 250 * time.Microsecond}, nil
+	}
+
+	store := &memConnectivityStore{results: map[string]*connectivity.NodeConnectivity{}}
+	checker := newConnectivityChecker(logrus.DefaultLogger(), store, "node1", 0, listNodes, nodeEndpoint,
+		listTestPods, ping)
+	gomega.Expect(checker.check()).To(gomega.BeNil())
+
+	results := store.results["node1"]
+	gomega.Expect(results).ToNot(gomega.BeNil())
+	gomega.Expect(results.NodeId).To(gomega.BeEquivalentTo(1))
+	// the test pod of this node is not probed
+	gomega.Expect(results.Target).To(gomega.HaveLen(4))
+	gomega.Expect(pinged).ToNot(gomega.ContainElement("10.1.1.5/0"))
+
+	node2, pod2, node3, node4 := results.Target[0], results.Target[1], results.Target[2], results.Target[3]
+	gomega.Expect(node2.Kind).To(gomega.BeEquivalentTo(nodeTargetKind))
+	gomega.Expect(node2.Reachable).To(gomega.BeTrue())
+	gomega.Expect(node2.LargeProbeReachable).To(gomega.BeTrue())
+	gomega.Expect(node2.RttUs).To(gomega.BeEquivalentTo(250))
+	gomega.Expect(pod2.Kind).To(gomega.BeEquivalentTo(podTargetKind))
+	gomega.Expect(pod2.NodeName).To(gomega.BeEquivalentTo("node2"))
+	gomega.Expect(pod2.Address).To(gomega.BeEquivalentTo("10.1.2.5"))
+	gomega.Expect(pod2.Reachable).To(gomega.BeTrue())
+	gomega.Expect(node3.Reachable).To(gomega.BeFalse())
+	gomega.Expect(node4.Reachable).To(gomega.BeTrue())
+	gomega.Expect(node4.LargeProbeReachable).To(gomega.BeFalse())
+	// large probes of the default size are sent only to reachable targets
+	gomega.Expect(pinged).To(gomega.ContainElement(fmt.Sprintf("192.168.30.4/%d", defaultConnectivityProbeSize)))
+	gomega.Expect(pinged).ToNot(gomega.ContainElement(fmt.Sprintf("192.168.30.3/%d", defaultConnectivityProbeSize)))
+
+	// connectivity matrix over REST
+	store.results["node2"] = &connectivity.NodeConnectivity{NodeName: "node2", NodeId: 2}
+	plugin := &Plugin{connectivityStore: store}
+	plugin.Log = logging.ForPlugin("contiv", logrus.NewLogRegistry())
+	router := mux.NewRouter()
+	router.HandleFunc(connectivityURL, plugin.connectivityHandler(render.New()))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", connectivityURL, nil))
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	matrix := []*connectivity.NodeConnectivity{}
+	gomega.Expect(json.Unmarshal(rec.Body.Bytes(), &matrix)).To(gomega.BeNil())
+	gomega.Expect(matrix).To(gomega.HaveLen(2))
+	gomega.Expect(matrix[0].NodeName).To(gomega.BeEquivalentTo("node1"))
+	gomega.Expect(matrix[0].Target).To(gomega.HaveLen(4))
+}
+
+func TestParsePingOutput(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	output := `64 bytes from 192.168.30.2: icmp_seq=1 ttl=64 time=.2000 ms
+64 bytes from 192.168.30.2: icmp_seq=3 ttl=64 time=.4000 ms
+
+Statistics: 3 sent, 2 received, 33% packet loss
+`
+	result, err := parsePingOutput(output)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(result.sent).To(gomega.BeEquivalentTo(3))
+	gomega.Expect(result.received).To(gomega.BeEquivalentTo(2))
+	gomega.Expect(result.rtt).To(gomega.BeEquivalentTo(300 * time.Microsecond))
+
+	_, err = parsePingOutput("unknown input: ping foo")
+	gomega.Expect(err).ToNot(gomega.BeNil())
+}
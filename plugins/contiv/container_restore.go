@@ -0,0 +1,158 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"encoding/json"
+	"net"
+	"sync/atomic"
+
+	"github.com/contiv/vpp/plugins/contiv/containeridx"
+	"github.com/contiv/vpp/plugins/contiv/model/cni"
+	containermodel "github.com/contiv/vpp/plugins/contiv/model/container"
+	"github.com/vishvananda/netlink"
+)
+
+// tapRecreatedInHost returns true if the TAP interface with the given name exists in the default namespace
+// of the host, i.e. the TAP was re-created by VPP after restart and it was not moved into the namespace
+// of the container yet. Can be replaced by tests.
+var tapRecreatedInHost = func(tapTmpHostIfName string) bool {
+	_, err := netlink.LinkByName(tapTmpHostIfName)
+	return err == nil
+}
+
+// restoreContainer restores the configuration of the container connected before the restart of the agent,
+// so that the container does not need to be re-created. The configuration persisted through kvdbproxy
+// is resynced to VPP and to the host by the VPP agent itself, what remains to be re-applied here are the parts
+// configured directly: TAP interfaces re-created by VPP are moved back into the namespace of the container
+// and the missing vhost-user interfaces are re-created. Returns false if the configuration is not known.
+func (s *remoteCNIserver) restoreContainer(container *containermodel.Container) bool {
+	if len(container.Config) == 0 || s.configuredContainers == nil {
+		return false
+	}
+	config := &containeridx.Config{}
+	if err := json.Unmarshal(container.Config, config); err != nil {
+		s.Logger.Warnf("Failed to decode the configuration of the container %s: %v", container.Id, err)
+		return false
+	}
+	if config.NetworkNamespace == "" {
+		// recorded before the network namespace was kept in the configuration
+		config.NetworkNamespace = container.NetworkNamespace
+		config.InterfaceName = container.InterfaceName
+	}
+
+	// the VPP ends of the new POD interfaces must not get the addresses of the restored ones
+	if container.LastPodIfIdx > atomic.LoadInt32(&s.counter) {
+		atomic.StoreInt32(&s.counter, container.LastPodIfIdx)
+	}
+
+	request := &cni.CNIRequest{
+		ContainerId:      container.Id,
+		NetworkNamespace: container.NetworkNamespace,
+		InterfaceName:    container.InterfaceName,
+	}
+	changed, err := s.reconnectContainer(request, config)
+	if err != nil {
+		// the container stays connected at least partially, CNI Check reports the missing config
+		s.Logger.Warnf("Failed to re-apply the configuration of the container %s: %v", container.Id, err)
+	}
+	if changed {
+		err = s.storeContainer(request, config, container.PersistedKeys)
+		if err != nil {
+			s.Logger.Warnf("Failed to update the record of the container %s: %v", container.Id, err)
+		}
+	}
+
+	s.configuredContainers.RegisterContainer(container.Id, config)
+	s.Logger.Infof("Restored configuration of the container %s (pod %s/%s)", container.Id, config.PodNamespace, config.PodName)
+	return true
+}
+
+// reconnectContainer re-applies the configuration of the container which is not resynced by the VPP agent.
+// Returns true if the configuration has changed (VPP assigned new indexes to the re-created interfaces).
+func (s *remoteCNIserver) reconnectContainer(request *cni.CNIRequest, config *containeridx.Config) (changed bool, err error) {
+	// POD interface
+	if config.VppIf != nil && config.VppIf.Tap != nil && tapRecreatedInHost(s.tapTmpHostNameFromRequest(request)) {
+		s.Logger.Infof("Moving re-created TAP interface of the container %s into its namespace", request.ContainerId)
+		podIPNet := &net.IPNet{
+			IP:   net.ParseIP(config.VppARPEntry.IpAddress),
+			Mask: net.CIDRMask(net.IPv4len*8, net.IPv4len*8),
+		}
+		var podIPv6Net *net.IPNet
+		if config.PodIPv6 != "" {
+			podIPv6Net = &net.IPNet{
+				IP:   net.ParseIP(config.PodIPv6),
+				Mask: net.CIDRMask(net.IPv6len*8, net.IPv6len*8),
+			}
+		}
+		err = s.configureHostTAP(request, podIPNet, podIPv6Net, config.VppIf.PhysAddress)
+		if err == nil && s.tcpChecksumOffloadDisabled {
+			err = s.disableTCPChecksumOffload(request)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// flowprobe is lost with restart of VPP, enabling it again fails if it was not restarted
+	if s.flowExportEnabled() && config.VppIf != nil {
+		if err := s.enableFlowProbe(config.VppIf.Name); err != nil {
+			s.Logger.Debugf("Flowprobe not enabled on %s: %v", config.VppIf.Name, err)
+		}
+	}
+
+	// the policer as well, configurePodPolicer skips the interfaces already policed
+	if config.VppIf != nil {
+		if err := s.configurePodPolicer(config); err != nil {
+			s.Logger.Warnf("Policer of %s not restored: %v", config.VppIf.Name, err)
+		}
+	}
+
+	// secondary interfaces
+	for _, secondaryIf := range config.SecondaryIfs {
+		ifRequest := s.secondaryIfRequest(request, secondaryIf.PodIfName)
+		switch {
+		case secondaryIf.VhostUserSocket != "":
+			swIfIndex := secondaryIf.VhostUserSwIfIndex
+			err = s.restoreVhostUserIf(secondaryIf)
+			changed = changed || swIfIndex != secondaryIf.VhostUserSwIfIndex
+		case secondaryIf.VppIf != nil && secondaryIf.VppIf.Tap != nil && tapRecreatedInHost(s.tapTmpHostNameFromRequest(ifRequest)):
+			err = s.reconnectSecondaryTAP(ifRequest, secondaryIf)
+		}
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// reconnectSecondaryTAP moves the re-created TAP interface of a secondary network back into the namespace
+// of the container.
+func (s *remoteCNIserver) reconnectSecondaryTAP(ifRequest *cni.CNIRequest, secondaryIf *containeridx.SecondaryIf) error {
+	network := s.secondaryNetworks[secondaryIf.Network]
+	gateway, err := s.ipam.PoolGatewayIP(network.IPPool)
+	if err != nil {
+		return err
+	}
+	subnet, err := s.ipam.PoolSubnet(network.IPPool)
+	if err != nil {
+		return err
+	}
+	ipNet := &net.IPNet{
+		IP:   net.ParseIP(secondaryIf.IPAddress),
+		Mask: net.CIDRMask(net.IPv4len*8, net.IPv4len*8),
+	}
+	return s.configureSecondaryHostTAP(ifRequest, ipNet, gateway, subnet, secondaryIf.VppIf.PhysAddress)
+}
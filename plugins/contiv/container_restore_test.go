@@ -0,0 +1,71 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+func TestRestoreContainers(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	netns, err := ioutil.TempFile("", "netns")
+	gomega.Expect(err).To(gomega.BeNil())
+	netns.Close()
+	defer os.Remove(netns.Name())
+
+	stores := newTestStores()
+
+	// connect the container
+	request := req
+	request.NetworkNamespace = netns.Name()
+	server, disconnect := startServer(stores)
+	defer disconnect()
+	server.setVswitchConfigured()
+	reply, err := server.Add(context.Background(), &request)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+	config, found := server.configuredContainers.LookupContainer(request.ContainerId)
+	gomega.Expect(found).To(gomega.BeTrue())
+
+	// the configuration of the container is restored after the restart
+	restarted, changes, disconnectRestarted := restartServer(stores, "default/"+podName)
+	defer disconnectRestarted()
+	restarted.cleanupOrphanedContainers()
+	restoredConfig, found := restarted.configuredContainers.LookupContainer(request.ContainerId)
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(restoredConfig).To(gomega.Equal(config))
+	gomega.Expect(restarted.restoredContainers).To(gomega.BeEmpty())
+
+	// new PODs do not get the addresses of the restored ones
+	gomega.Expect(atomic.LoadInt32(&restarted.counter)).To(gomega.Equal(atomic.LoadInt32(&server.counter)))
+
+	// the container is disconnected by CNI Delete as if no restart happened
+	persistedKeys := stores.containers.containers[request.ContainerId].PersistedKeys
+	restarted.setVswitchConfigured()
+	reply, err = restarted.Delete(context.Background(), &request)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+	gomega.Expect(deletedKeys(changes)).To(gomega.ConsistOf(persistedKeys))
+	_, found = restarted.configuredContainers.LookupContainer(request.ContainerId)
+	gomega.Expect(found).To(gomega.BeFalse())
+	gomega.Expect(stores.containers.containers).To(gomega.BeEmpty())
+}
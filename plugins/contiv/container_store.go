@@ -0,0 +1,91 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"github.com/contiv/vpp/flavors/ksr"
+	containermodel "github.com/contiv/vpp/plugins/contiv/model/container"
+	"github.com/contiv/vpp/plugins/kvstore"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/servicelabel"
+)
+
+const (
+	// connectedContainersKeyPrefix is the prefix of the keys under which the records of the containers
+	// connected to VPP are stored for each node.
+	connectedContainersKeyPrefix = "connectedContainers/"
+)
+
+// containerStore persists the records of the containers connected to VPP, so that the containers
+// and the configuration persisted for them are known after the restart of the agent.
+type containerStore interface {
+	// ListContainers returns the records of all connected containers.
+	ListContainers() ([]*containermodel.Container, error)
+
+	// PutContainer creates or overwrites the record of the container.
+	PutContainer(container *containermodel.Container) error
+
+	// DeleteContainer removes the record of the container with the given ID.
+	DeleteContainer(id string) error
+}
+
+// kvContainerStore persists the records of the containers connected on this node in the key-value
+// data store under connectedContainersKeyPrefix of the KSR microservice.
+type kvContainerStore struct {
+	broker keyval.ProtoBroker
+	prefix string
+}
+
+// newKVContainerStore creates new instance of kvContainerStore for the given node.
+func newKVContainerStore(store kvstore.KvStore, nodeName string) *kvContainerStore {
+	return &kvContainerStore{
+		broker: store.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel)),
+		prefix: connectedContainersKeyPrefix + nodeName + "/",
+	}
+}
+
+// ListContainers returns the records of all containers connected on this node.
+func (s *kvContainerStore) ListContainers() ([]*containermodel.Container, error) {
+	var containers []*containermodel.Container
+	it, err := s.broker.ListValues(s.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+		container := &containermodel.Container{}
+		err = kv.GetValue(container)
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// PutContainer creates or overwrites the record of the container.
+func (s *kvContainerStore) PutContainer(container *containermodel.Container) error {
+	return s.broker.Put(s.prefix+container.Id, container)
+}
+
+// DeleteContainer removes the record of the container with the given ID.
+func (s *kvContainerStore) DeleteContainer(id string) error {
+	_, err := s.broker.Delete(s.prefix + id)
+	return err
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/ligato/cn-infra/idxmap"
 	"github.com/ligato/cn-infra/idxmap/mem"
 	"github.com/ligato/cn-infra/logging"
+	vpp_acl "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
 	vpp_intf "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
 	vpp_l3 "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
@@ -61,6 +62,10 @@ type Config struct {
 	PodName string
 	// PodNamespace from the CNI request
 	PodNamespace string
+	// NetworkNamespace is the path of the network namespace of the container from the CNI request.
+	NetworkNamespace string
+	// InterfaceName is the name of the pod interface inside of the container from the CNI request.
+	InterfaceName string
 	// Veth1 one end end of veth pair that is in the given container namespace.
 	// Nil if TAPs are used instead.
 	Veth1 *linux_intf.LinuxInterfaces_Interface
@@ -88,6 +93,76 @@ type Config struct {
 	PodLinkRoute *linux_l3.LinuxStaticRoutes_Route
 	// PodDefaultRoute is the default gateway for the pod.
 	PodDefaultRoute *linux_l3.LinuxStaticRoutes_Route
+
+	// PodIPv6 is the IPv6 address assigned to the pod.
+	// Empty if IPv6 is not enabled in IPAM, the IPv6 fields below are nil in that case.
+	PodIPv6 string
+	// VppARPEntryIPv6 is IPv6 neighbor entry configured in VPP to route traffic from VPP to pod.
+	VppARPEntryIPv6 *vpp_l3.ArpTable_ArpTableEntry
+	// PodARPEntryIPv6 is IPv6 neighbor entry configured in the pod to route traffic from pod to VPP.
+	PodARPEntryIPv6 *linux_l3.LinuxStaticArpEntries_ArpEntry
+	// VppRouteIPv6 is the IPv6 route from VPP to the container
+	VppRouteIPv6 *l3.StaticRoutes_Route
+	// PodLinkRouteIPv6 is the IPv6 route from pod to the default gateway.
+	PodLinkRouteIPv6 *linux_l3.LinuxStaticRoutes_Route
+	// PodDefaultRouteIPv6 is the IPv6 default gateway for the pod.
+	PodDefaultRouteIPv6 *linux_l3.LinuxStaticRoutes_Route
+
+	// SecondaryIfs are the additional interfaces of the pod attached to secondary networks.
+	SecondaryIfs []*SecondaryIf
+
+	// ExtConfigs are the configurations applied for the pod out-of-band by external controllers.
+	ExtConfigs []*ExtConfig
+}
+
+// ExtConfig groups configuration applied for a running pod by an external controller
+// via the pod network config API. The interfaces added by the configuration are included
+// in SecondaryIfs of the pod.
+type ExtConfig struct {
+	// Name of the configuration, unique per pod.
+	Name string
+	// PodRoutes are the routes configured inside of the pod.
+	PodRoutes []*linux_l3.LinuxStaticRoutes_Route
+	// ACLs are the ACLs applied on the VPP ends of the interfaces added by the configuration.
+	ACLs []*vpp_acl.AccessLists_Acl
+}
+
+// SecondaryIf groups applied configuration of an additional pod interface attached to a secondary network.
+type SecondaryIf struct {
+	// Network is the name of the secondary network.
+	Network string
+	// ExtConfig is the name of the external configuration which has added the interface,
+	// empty for the interfaces requested by the pod annotation.
+	ExtConfig string
+	// PodIfName is the name of the interface inside of the pod.
+	PodIfName string
+	// IPAddress is the IP address assigned to the interface from the IP pool of the network.
+	IPAddress string
+	// Veth1 is the end of veth pair in the pod namespace, nil for other interface types than veth.
+	Veth1 *linux_intf.LinuxInterfaces_Interface
+	// Veth2 is the end of veth pair in the default namespace, nil for other interface types than veth.
+	Veth2 *linux_intf.LinuxInterfaces_Interface
+	// VppIf is AF_PACKET/TAP/memif interface connecting the interface to VPP, nil for vhost-user.
+	VppIf *vpp_intf.Interfaces_Interface
+	// VppARPEntry is ARP entry configured in VPP to route traffic from VPP to the interface, nil for vhost-user.
+	VppARPEntry *vpp_l3.ArpTable_ArpTableEntry
+	// VppRoute is the route from VPP to the interface, nil for vhost-user.
+	VppRoute *l3.StaticRoutes_Route
+	// VhostUserSocket is the path of the socket of the vhost-user interface, empty for other interface types.
+	// Vhost-user interfaces are configured on VPP directly via binary API, including their route and ARP entry.
+	VhostUserSocket string
+	// VhostUserSwIfIndex is the VPP index of the vhost-user interface.
+	VhostUserSwIfIndex uint32
+	// VhostUserPhysAddress is the MAC address of the vhost-user interface.
+	VhostUserPhysAddress string
+	// VhostUserIPAddress is the IP address of the vhost-user interface (the VPP end).
+	VhostUserIPAddress string
+	// PodARPEntry is ARP entry of the gateway of the network configured in the pod, nil for other interface types than veth.
+	PodARPEntry *linux_l3.LinuxStaticArpEntries_ArpEntry
+	// PodLinkRoute is the link route from the pod to the gateway of the network, nil for other interface types than veth.
+	PodLinkRoute *linux_l3.LinuxStaticRoutes_Route
+	// PodRoute is the route from the pod to the subnet of the network via its gateway, nil for other interface types than veth.
+	PodRoute *linux_l3.LinuxStaticRoutes_Route
 }
 
 // ChangeEvent represents a notification about change in ConfigIndex delivered to subscribers
@@ -174,6 +249,11 @@ func IndexFunction(data interface{}) map[string][]string {
 		if config.Loopback != nil {
 			res[podRelatedIfsKey] = append(res[podRelatedIfsKey], config.Loopback.Name)
 		}
+		for _, secondaryIf := range config.SecondaryIfs {
+			if secondaryIf.VppIf != nil {
+				res[podRelatedIfsKey] = append(res[podRelatedIfsKey], secondaryIf.VppIf.Name)
+			}
+		}
 	}
 	return res
 }
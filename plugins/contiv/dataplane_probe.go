@@ -0,0 +1,121 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+
+	"git.fd.io/govpp.git/api"
+	"github.com/ligato/cn-infra/health/statuscheck"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/vpe"
+)
+
+// pingHost sends a single ICMP echo request from the host stack to the given IP address.
+// Can be replaced by tests.
+var pingHost = func(ip net.IP) error {
+	output, err := exec.Command("ping", "-c", "1", "-W", "1", ip.String()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, output)
+	}
+	return nil
+}
+
+// dataplaneProbe checks the state of the dataplane. It is registered with StatusCheck, so that the state
+// of the dataplane is reflected by the readiness and liveness probes of the agent and k8s restarts
+// the vswitch on dataplane failure. The probe verifies that:
+//  - VPP responds to binary API requests,
+//  - the main physical NIC is up (with uplinks, at least one uplink is up),
+//  - the host stack reaches VPP via the host interconnect.
+// Until the vswitch connectivity is configured, the dataplane is reported as initializing.
+func (s *remoteCNIserver) dataplaneProbe() (statuscheck.PluginState, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if !s.vswitchConnectivityConfigured {
+		return statuscheck.Init, nil
+	}
+	if err := s.probeVPP(); err != nil {
+		return statuscheck.Error, fmt.Errorf("VPP is not responding: %v", err)
+	}
+	if len(s.uplinks) > 0 {
+		// the paths via the failed uplinks are withdrawn, the traffic is carried by the others
+		if !s.anyUplinkUp() {
+			return statuscheck.Error, errors.New("all uplinks are down")
+		}
+	} else if s.mainPhysicalIf != "" {
+		if err := s.probeNIC(s.mainPhysicalIf); err != nil {
+			return statuscheck.Error, err
+		}
+	}
+	if vppIP := s.ipam.VEthVPPEndIP(); vppIP != nil {
+		if err := pingHost(vppIP); err != nil {
+			return statuscheck.Error, fmt.Errorf("VPP is not reachable from the host via %s: %v", vppIP, err)
+		}
+	}
+	return statuscheck.OK, nil
+}
+
+// probeVPP sends control ping to VPP.
+func (s *remoteCNIserver) probeVPP() error {
+	reply := &vpe.ControlPingReply{}
+	err := s.vppProbeChan().SendRequest(&vpe.ControlPing{}).ReceiveReply(reply)
+	if err == nil && reply.Retval != 0 {
+		err = fmt.Errorf("%s returned %d", reply.GetMessageName(), reply.Retval)
+	}
+	if err != nil {
+		s.metrics.vppAPIError((&vpe.ControlPing{}).GetMessageName())
+	}
+	return err
+}
+
+// vppProbeChan returns the GoVPP channel used by the probe.
+func (s *remoteCNIserver) vppProbeChan() *api.Channel {
+	if s.probeChan != nil {
+		return s.probeChan
+	}
+	return s.govppChan
+}
+
+// probeNIC verifies that the physical interface with the given name is administratively and operationally up.
+func (s *remoteCNIserver) probeNIC(name string) error {
+	req := &interfaces.SwInterfaceDump{
+		NameFilterValid: 1,
+		NameFilter:      []byte(name),
+	}
+	reqCtx := s.vppProbeChan().SendMultiRequest(req)
+	found := false
+	for {
+		details := &interfaces.SwInterfaceDetails{}
+		stop, err := reqCtx.ReceiveReply(details)
+		if stop {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		if details.AdminUpDown == 0 || details.LinkUpDown == 0 {
+			return fmt.Errorf("interface %s is down", name)
+		}
+	}
+	if !found {
+		return fmt.Errorf("interface %s not found in VPP", name)
+	}
+	return nil
+}
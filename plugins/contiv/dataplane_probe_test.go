@@ -0,0 +1,65 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/ligato/cn-infra/health/statuscheck"
+	"github.com/onsi/gomega"
+)
+
+func TestDataplaneProbe(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, _, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	var pingErr error
+	var pinged net.IP
+	origPingHost := pingHost
+	pingHost = func(ip net.IP) error {
+		pinged = ip
+		return pingErr
+	}
+	defer func() { pingHost = origPingHost }()
+
+	// not ready until the vswitch connectivity is configured
+	state, err := server.dataplaneProbe()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(state).To(gomega.BeEquivalentTo(statuscheck.Init))
+
+	// VPP responds and the host reaches VPP
+	server.setVswitchConfigured()
+	state, err = server.dataplaneProbe()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(state).To(gomega.BeEquivalentTo(statuscheck.OK))
+	gomega.Expect(pinged).To(gomega.Equal(server.ipam.VEthVPPEndIP()))
+
+	// the host interconnect is broken
+	pingErr = errors.New("100% packet loss")
+	state, err = server.dataplaneProbe()
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	gomega.Expect(state).To(gomega.BeEquivalentTo(statuscheck.Error))
+	pingErr = nil
+
+	// the main NIC is down
+	server.mainPhysicalIf = "GigabitEthernet0/8/0"
+	state, err = server.dataplaneProbe()
+	gomega.Expect(err).To(gomega.MatchError("interface GigabitEthernet0/8/0 is down"))
+	gomega.Expect(state).To(gomega.BeEquivalentTo(statuscheck.Error))
+}
@@ -0,0 +1,296 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"git.fd.io/govpp.git/api"
+	"github.com/contiv/vpp/plugins/contiv/ipam"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/vpe"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/vxlan"
+	"github.com/unrolled/render"
+)
+
+const (
+	// ipamURL is the URL of the REST API inspecting IPAM of the node.
+	ipamURL = "/contiv/v1/ipam"
+
+	// podsURL is the URL of the REST API listing pods connected on the node.
+	podsURL = "/contiv/v1/pods"
+
+	// vxlanURL is the URL of the REST API listing VXLAN tunnels of the node.
+	vxlanURL = "/contiv/v1/vxlan"
+
+	// vppCLIURL is the URL of the REST API executing VPP CLI commands.
+	vppCLIURL = "/contiv/v1/vppcli"
+)
+
+// IPAMInfo describes IPAM of the node as exposed by the REST API.
+type IPAMInfo struct {
+	NodeID         uint8                  `json:"nodeId"`
+	NodeName       string                 `json:"nodeName"`
+	NodeIP         string                 `json:"nodeIP,omitempty"`
+	PodNetwork     string                 `json:"podNetwork"`
+	VPPHostNetwork string                 `json:"vppHostNetwork"`
+	Pools          []ipam.PoolUtilization `json:"pools"`
+}
+
+// PodInfo describes a pod connected on the node as exposed by the REST API.
+type PodInfo struct {
+	ContainerID         string                    `json:"containerId"`
+	PodNamespace        string                    `json:"podNamespace"`
+	PodName             string                    `json:"podName"`
+	PodIP               string                    `json:"podIP,omitempty"`
+	VppInterface        string                    `json:"vppInterface,omitempty"`
+	SwIfIndex           uint32                    `json:"swIfIndex,omitempty"`
+	SecondaryInterfaces []*SecondaryInterfaceInfo `json:"secondaryInterfaces,omitempty"`
+}
+
+// SecondaryInterfaceInfo describes an interface of a pod in a secondary network.
+type SecondaryInterfaceInfo struct {
+	PodInterface string `json:"podInterface"`
+	Network      string `json:"network"`
+	IPAddress    string `json:"ipAddress,omitempty"`
+	VppInterface string `json:"vppInterface,omitempty"`
+}
+
+// VxlanTunnelInfo describes a VXLAN tunnel to another node as exposed by the REST API.
+type VxlanTunnelInfo struct {
+	Interface  string `json:"interface,omitempty"`
+	SwIfIndex  uint32 `json:"swIfIndex"`
+	SrcAddress string `json:"srcAddress"`
+	DstAddress string `json:"dstAddress"`
+	Vni        uint32 `json:"vni"`
+	AdminUp    bool   `json:"adminUp"`
+	LinkUp     bool   `json:"linkUp"`
+}
+
+// VppCLIRequest is the body of the request executing VPP CLI command.
+type VppCLIRequest struct {
+	Command string `json:"command"`
+}
+
+// VppCLIReply is the output of the executed VPP CLI command.
+type VppCLIReply struct {
+	Output string `json:"output"`
+}
+
+// registerDiagnosticsHandlers registers REST handlers used by contiv-netctl to inspect the node:
+//   - IPAM of the node:
+//     > curl -X GET http://localhost:<port>/contiv/v1/ipam
+//   - Pods connected on the node with their interfaces:
+//     > curl -X GET http://localhost:<port>/contiv/v1/pods
+//   - VXLAN tunnels to other nodes with their state:
+//     > curl -X GET http://localhost:<port>/contiv/v1/vxlan
+//   - Execute VPP CLI command:
+//     > curl -X POST -d '{"command":"show interface"}' http://localhost:<port>/contiv/v1/vppcli
+func (plugin *Plugin) registerDiagnosticsHandlers() {
+	plugin.HTTPHandlers.RegisterHTTPHandler(ipamURL, plugin.ipamHandler, "GET")
+	plugin.HTTPHandlers.RegisterHTTPHandler(podsURL, plugin.podsHandler, "GET")
+	plugin.HTTPHandlers.RegisterHTTPHandler(vxlanURL, plugin.vxlanHandler, "GET")
+	plugin.HTTPHandlers.RegisterHTTPHandler(vppCLIURL, plugin.vppCLIHandler, "POST")
+}
+
+// ipamHandler processes requests to inspect IPAM of the node.
+func (plugin *Plugin) ipamHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ipamInst := plugin.cniServer.ipam
+		info := &IPAMInfo{
+			NodeID:         ipamInst.NodeID(),
+			NodeName:       plugin.cniServer.agentLabel,
+			PodNetwork:     ipamInst.PodNetwork().String(),
+			VPPHostNetwork: ipamInst.VPPHostNetwork().String(),
+			Pools:          ipamInst.PoolsUtilization(),
+		}
+		if nodeIP := plugin.cniServer.GetNodeIP(); nodeIP != nil {
+			info.NodeIP = nodeIP.String()
+		}
+		formatter.JSON(w, http.StatusOK, info)
+	}
+}
+
+// podsHandler processes requests to list the pods connected on the node.
+func (plugin *Plugin) podsHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		formatter.JSON(w, http.StatusOK, plugin.cniServer.podInfos())
+	}
+}
+
+// vxlanHandler processes requests to list the VXLAN tunnels of the node.
+func (plugin *Plugin) vxlanHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		plugin.diagLock.Lock()
+		tunnels, err := plugin.cniServer.vxlanTunnels(plugin.vppDiagChan())
+		plugin.diagLock.Unlock()
+		if err != nil {
+			plugin.Log.Errorf("Unable to dump VXLAN tunnels: %v", err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, tunnels)
+	}
+}
+
+// vppCLIHandler processes requests to execute VPP CLI command.
+func (plugin *Plugin) vppCLIHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		cliReq := &VppCLIRequest{}
+		if err := json.NewDecoder(req.Body).Decode(cliReq); err != nil || cliReq.Command == "" {
+			formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{"command expected"})
+			return
+		}
+		plugin.diagLock.Lock()
+		output, err := vppCLI(plugin.vppDiagChan(), cliReq.Command)
+		plugin.diagLock.Unlock()
+		if err != nil {
+			plugin.Log.Errorf("Unable to execute VPP CLI command %q: %v", cliReq.Command, err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, &VppCLIReply{Output: output})
+	}
+}
+
+// vppDiagChan returns the channel used to inspect VPP, the channel of the CNI server is used if the dedicated
+// channel was not opened.
+func (plugin *Plugin) vppDiagChan() *api.Channel {
+	if plugin.diagChan != nil {
+		return plugin.diagChan
+	}
+	return plugin.cniServer.govppChan
+}
+
+// podInfos returns the pods connected on the node ordered by namespace and name.
+func (s *remoteCNIserver) podInfos() []*PodInfo {
+	pods := []*PodInfo{}
+	if s.configuredContainers == nil {
+		return pods
+	}
+	for _, containerID := range s.configuredContainers.ListAll() {
+		config, found := s.configuredContainers.LookupContainer(containerID)
+		if !found {
+			continue
+		}
+		pod := &PodInfo{
+			ContainerID:  containerID,
+			PodNamespace: config.PodNamespace,
+			PodName:      config.PodName,
+		}
+		if config.VppARPEntry != nil {
+			pod.PodIP = config.VppARPEntry.IpAddress
+		}
+		if config.VppIf != nil {
+			pod.VppInterface = config.VppIf.Name
+			pod.SwIfIndex, _, _ = s.swIfIndex.LookupIdx(config.VppIf.Name)
+		}
+		for _, secondaryIf := range config.SecondaryIfs {
+			ifInfo := &SecondaryInterfaceInfo{
+				PodInterface: secondaryIf.PodIfName,
+				Network:      secondaryIf.Network,
+				IPAddress:    secondaryIf.IPAddress,
+			}
+			if secondaryIf.VppIf != nil {
+				ifInfo.VppInterface = secondaryIf.VppIf.Name
+			}
+			pod.SecondaryInterfaces = append(pod.SecondaryInterfaces, ifInfo)
+		}
+		pods = append(pods, pod)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].PodNamespace != pods[j].PodNamespace {
+			return pods[i].PodNamespace < pods[j].PodNamespace
+		}
+		return pods[i].PodName < pods[j].PodName
+	})
+	return pods
+}
+
+// vxlanTunnels dumps the VXLAN tunnels configured in VPP together with the state of their interfaces.
+func (s *remoteCNIserver) vxlanTunnels(ch *api.Channel) ([]*VxlanTunnelInfo, error) {
+	tunnels := []*VxlanTunnelInfo{}
+	reqCtx := ch.SendMultiRequest(&vxlan.VxlanTunnelDump{SwIfIndex: ^uint32(0)})
+	for {
+		details := &vxlan.VxlanTunnelDetails{}
+		stop, err := reqCtx.ReceiveReply(details)
+		if stop {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		tunnel := &VxlanTunnelInfo{
+			SwIfIndex: details.SwIfIndex,
+			Vni:       details.Vni,
+		}
+		if details.IsIpv6 == 1 {
+			tunnel.SrcAddress = net.IP(details.SrcAddress).To16().String()
+			tunnel.DstAddress = net.IP(details.DstAddress).To16().String()
+		} else {
+			tunnel.SrcAddress = net.IP(details.SrcAddress[:4]).To4().String()
+			tunnel.DstAddress = net.IP(details.DstAddress[:4]).To4().String()
+		}
+		tunnel.Interface, _, _ = s.swIfIndex.LookupName(details.SwIfIndex)
+		tunnels = append(tunnels, tunnel)
+	}
+	if len(tunnels) == 0 {
+		return tunnels, nil
+	}
+
+	// state of the tunnel interfaces
+	states := map[uint32]*interfaces.SwInterfaceDetails{}
+	reqCtx = ch.SendMultiRequest(&interfaces.SwInterfaceDump{})
+	for {
+		details := &interfaces.SwInterfaceDetails{}
+		stop, err := reqCtx.ReceiveReply(details)
+		if stop {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		states[details.SwIfIndex] = details
+	}
+	for _, tunnel := range tunnels {
+		if state, found := states[tunnel.SwIfIndex]; found {
+			tunnel.AdminUp = state.AdminUpDown == 1
+			tunnel.LinkUp = state.LinkUpDown == 1
+		}
+	}
+	sort.Slice(tunnels, func(i, j int) bool { return tunnels[i].SwIfIndex < tunnels[j].SwIfIndex })
+	return tunnels, nil
+}
+
+// vppCLI executes the VPP CLI command and returns its output.
+func vppCLI(ch *api.Channel, command string) (string, error) {
+	req := &vpe.CliInband{
+		Cmd:    []byte(command),
+		Length: uint32(len(command)),
+	}
+	reply := &vpe.CliInbandReply{}
+	if err := ch.SendRequest(req).ReceiveReply(reply); err != nil {
+		return "", err
+	}
+	if reply.Retval != 0 {
+		return "", fmt.Errorf("%s returned %d", reply.GetMessageName(), reply.Retval)
+	}
+	return strings.TrimRight(string(reply.Reply), "\x00"), nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+	"github.com/unrolled/render"
+	"golang.org/x/net/context"
+)
+
+func TestDiagnosticsREST(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, _, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+
+	plugin := &Plugin{cniServer: server}
+	plugin.Log = logging.ForPlugin("contiv", logrus.NewLogRegistry())
+
+	router := mux.NewRouter()
+	formatter := render.New()
+	router.HandleFunc(ipamURL, plugin.ipamHandler(formatter)).Methods("GET")
+	router.HandleFunc(podsURL, plugin.podsHandler(formatter)).Methods("GET")
+	router.HandleFunc(vxlanURL, plugin.vxlanHandler(formatter)).Methods("GET")
+	router.HandleFunc(vppCLIURL, plugin.vppCLIHandler(formatter)).Methods("POST")
+
+	// IPAM of the node
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", ipamURL, nil))
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	ipamInfo := IPAMInfo{}
+	gomega.Expect(json.Unmarshal(rec.Body.Bytes(), &ipamInfo)).To(gomega.BeNil())
+	gomega.Expect(ipamInfo.NodeID).To(gomega.BeEquivalentTo(1))
+	gomega.Expect(ipamInfo.NodeName).To(gomega.BeEquivalentTo("testLabel"))
+	gomega.Expect(ipamInfo.PodNetwork).To(gomega.BeEquivalentTo("10.1.1.0/24"))
+	gomega.Expect(ipamInfo.Pools).To(gomega.HaveLen(1))
+	gomega.Expect(ipamInfo.Pools[0].Assigned).To(gomega.BeEquivalentTo(1))
+
+	// pods connected on the node
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", podsURL, nil))
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	pods := []PodInfo{}
+	gomega.Expect(json.Unmarshal(rec.Body.Bytes(), &pods)).To(gomega.BeNil())
+	gomega.Expect(pods).To(gomega.HaveLen(1))
+	gomega.Expect(pods[0].ContainerID).To(gomega.BeEquivalentTo(containerID))
+	gomega.Expect(pods[0].PodIP).To(gomega.HavePrefix("10.1.1."))
+	gomega.Expect(pods[0].VppInterface).ToNot(gomega.BeEmpty())
+
+	// VXLAN tunnels, none are configured
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", vxlanURL, nil))
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	tunnels := []VxlanTunnelInfo{}
+	gomega.Expect(json.Unmarshal(rec.Body.Bytes(), &tunnels)).To(gomega.BeNil())
+	gomega.Expect(tunnels).To(gomega.BeEmpty())
+
+	// VPP CLI
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", vppCLIURL, strings.NewReader(`{"command":"show version"}`)))
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", vppCLIURL, strings.NewReader(`{}`)))
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusBadRequest))
+}
@@ -66,6 +66,11 @@
 // in the config file. Legacy and  the new virtio-based TAP interfaces are supported, the latter can be turned on
 // by setting the TAPInterfaceVersion: 2.
 //
+// Alternatively, PodInterconnect: auto|tapv1|tapv2|veth selects the interconnect based on the kernel support
+// (pod_interconnect.go). "auto" uses TAPv2 if the kernel provides /dev/vhost-net, veth + AF_PACKET otherwise.
+// Options not supported by the kernel fall back to a supported one, and if the kernel refuses to create
+// a TAPv2 interface at runtime, legacy TAPs are used for the following interfaces instead of failing the pod setup.
+//
 // +-------------------------------------------------+
 // |   vSwitch VPP                                 host.go
 // |                             +--------------+    |       +--------------+
@@ -146,15 +151,188 @@
 //		1. Plugin base:
 //			- plugin_*.go: plugin definition and setup
 //			- node_events.go: handler of changes in nodes within the k8s cluster (node add / delete)
+//			  configuring the routes to the other nodes; with VXLAN, the BVI of every other node is resolved
+//			  by static ARP and L2 FIB entries, so that traffic to remote pods is not flooded to all tunnels
+//			  (other nodes are discovered from the allocated IDs, but only those present in the k8s cluster
+//			  as reflected by KSR are routed, once a node is deleted from the cluster, its routes, static ARP
+//			  and FIB entries and the VXLAN tunnel are removed); InterNodeTransport: nooverlay (inter_node_transport.go) routes
+//			  the pod subnets of other nodes directly via their IP addresses, without VXLAN encapsulation;
+//			  InterNodeTransport: srv6 (srv6.go) steers the traffic destined to the networks of other nodes
+//			  into SRv6 policies encapsulating it towards the local SIDs of the nodes (derived from the node IDs),
+//			  optionally through waypoint SIDs, where it is decapsulated and looked up in the pod FIB table (End.DT4);
+//			  with IPSec configured (ipsec.go), the VXLAN traffic to other nodes is protected by ESP SAs whose keys
+//			  are derived from the pre-shared key and the current rekey interval, so that no key exchange is needed;
+//			  the MTU of the pod and host interconnect interfaces is the MTU of the node interconnect (MTUSize
+//			  or the link MTU of the main VPP interface) lowered by the overhead of the inter-node encapsulation
+//			  (mtu.go), the MTU is also returned in the CNI reply; with UplinkInterfaces in the node config,
+//			  the IPs of other nodes are routed via ECMP paths across the main interface and the uplinks,
+//			  the paths via uplinks whose link goes down are withdrawn until they recover (ecmp.go);
+//			  with Bond in the node config, the agent creates a bond of the listed NICs (lacp or active-backup)
+//			  before the resync of the VPP agent and uses it as the main VPP interface (bond.go); with VlanID
+//			  (global or per node), the 802.1Q sub-interface of the main NIC or of the bond is created the same way
+//			  and used as the main VPP interface (vlan.go); with UseDHCP, the node IP is leased by the DHCP client
+//			  of VPP and published with the allocated ID, a lease of a different address re-creates the connectivity
+//			  to other nodes with the new IP; the node config stored in etcd under nodeconfig/<node-name> overrides
+//			  the config file and is watched, changes of the main IP, the gateway and the other interfaces are
+//			  applied without restart (node_config.go); on SIGHUP, the config file is re-read and the changes
+//			  of ServiceCIDR, FlowExport and of the node config are applied without restart (config_reload.go)
 //
 //		2. Remote CNI Server - the main logic of the plugin that is in charge of wiring the PODs.
+//		Besides Add and Delete, it serves the CNI Check request verifying that the POD interface, its routes
+//		and the VPP-side configuration of the POD still exist.
+//		Requests of different PODs are processed in parallel, at most MaxParallelPodRequests (8 by default)
+//		at the same time, while the requests of the same POD (container) are processed one by one in the order
+//		of their arrival (pod_pipeline.go).
+//		All other changes of the vswitch config - the resync, DHCP leases, changes of other nodes and of the node
+//		config, reload of the config file and the periodic uplink probes and IPsec rekeys - are queued into a single
+//		event loop (event_loop.go) and handled one by one in the order of their priority. The handlers run with
+//		the write lock of the server, which excludes the CNI requests. The events other than the resync and DHCP
+//		leases wait in the queue until the base vswitch config is applied, so that neither a handler nor a watcher
+//		feeding the loop blocks waiting for it.
+//		The CNI requests themselves are not queued into the loop, they keep being processed in parallel
+//		by the pod pipeline with the read lock. The K8s state reflected by KSR is consumed through the loop
+//		only for the nodes, the changes of pods, namespaces, services and policies are handled by the watchers
+//		of the plugins consuming them (policy, service). Routing the CNI requests and the remaining KSR updates
+//		through the loop and dropping the RWMutex of the server is left to a separate change: the loop
+//		serializes its handlers, so it must first learn to run the requests of different PODs concurrently,
+//		and the getters of the plugin API and the dataplane probe must stop reading the state under the lock.
+//		The POD interface is configured together with the VPP-side config of the POD (routes, ARP entries,
+//		TCP stack) in one transaction. If any step of the Add request fails, the configuration applied
+//		so far is removed and the IP address of the POD is released, so that no half-configured POD is left.
+//		For each connected container a record with the keys of its persisted configuration is stored in etcd
+//		(container_store.go). Once the vswitch is configured after the start of the agent, the configuration
+//		of containers whose network namespace is gone or whose POD is no longer running is removed
+//		(orphan_cleanup.go). The configuration of the other containers is restored from the records, so that
+//		the PODs survive the restart of the agent or of VPP without being re-created: the VPP agent resyncs
+//		the persisted configuration and the contiv plugin re-applies the rest - TAP interfaces re-created
+//		by VPP are moved back into the namespaces of the containers and vhost-user interfaces are re-created
+//		(container_restore.go).
+//		PODs can be attached to secondary networks (SecondaryNetworks in the config file) by listing them
+//		in the k8s.v1.cni.cncf.io/networks annotation (Multus format, e.g. "dataplane@data0" or a JSON list).
+//		Each secondary network connects the POD with an additional veth or TAP interface to VPP
+//		and assigns its IP address from the IPAM pool of the network (secondary_ifs.go).
+//		For DPDK workloads, the network can use memif interfaces instead. VPP is the memif master
+//		with a socket in the host directory that the POD mounts (hostPath volume), the socket path
+//		and memif parameters are published in the contivpp.io/memif annotation of the POD
+//		(written by KSR on behalf of the agent). Similarly, VM-in-pod workloads (e.g. KubeVirt or Kata) can use
+//		vhost-user networks: VPP creates vhost-user interface in server mode directly via binary API
+//		(vhost_user.go), the socket is published in the contivpp.io/vhost-user annotation and the interface
+//		is deleted on CNI Del.
+//		The egress bandwidth of a POD can be limited with the standard kubernetes.io/egress-bandwidth annotation
+//		(e.g. "10M") and its traffic can be marked with a DSCP value given by the contivpp.io/dscp annotation
+//		(a number or a class name, e.g. "EF"): a VPP policer is applied on the VPP end of the POD interface via
+//		a policer classify table matching all packets (pod_policer.go). The annotations are read on CNI Add.
+//		The DSCP mark can not be limited to selected destinations, the traffic matched by a per-destination
+//		policer would not be counted by the policer limiting the bandwidth of the POD.
+//		The kubernetes.io/ingress-bandwidth annotation is not supported, policers of VPP apply only to the traffic
+//		received by VPP.
 //
 //		3. Node ID Allocator - manages allocation/deallocation of unique number identifying a node within the k8s cluster.
-//		Allocated identifier is used as an input of the IPAM calculations.
+//		Allocated identifier is used as an input of the IPAM calculations. The allocation is bound to an etcd lease
+//		or a Consul session (NodeIDLeaseTTL seconds in the config file) kept alive by the agent, so that IDs
+//		of crashed nodes are released automatically. Allocations are kept in a NodeIDStore - the key-value data store
+//		(KVStore dependency - etcd or Consul, whichever is configured) is used unless a different store is injected
+//		into the plugin (node_id_store.go). Optionally, NodeIDRanges can reserve ranges of IDs for nodes
+//		with matching labels (e.g. masters and workers) - the node then allocates the first free ID
+//		from the first range whose labels it carries. Labels of the node are read from the data reflected by KSR.
+//		With NodeIDDerivation set to "node-name" or "node-ip", the ID is derived from the hash of the node name
+//		or management IP instead (the next free ID is used on collision), which keeps the pod subnets stable
+//		across cluster rebuilds. The management IP is the internal (or external) IP of the K8s node reflected
+//		by KSR, the allocation fails if it is not known.
+//		Allocated IDs together with the derived pod subnets can be inspected via REST API
+//		(GET /contiv/v1/nodeids and /contiv/v1/nodeids/<id>, node_id_rest.go). The node with the lowest ID
+//		acts as the leader that periodically (NodeIDGCInterval seconds) and immediately after the deletion
+//		of a k8s node is observed removes IDs of the nodes deleted from the cluster (node_id_gc.go).
+//		Once the watch of the node IDs and k8s nodes is resynced, the allocations and the names of the k8s nodes
+//		are read from an in-memory cache fed by the watch, indexed by the ID, the node name and the pod network
+//		of the node (node_cache.go), so that the allocator, the GC, the connectivity check and the REST API
+//		do not list the data store. Writes go through to the NodeIDStore.
 //
 //		4. IPAM module (separate package, described in its own doc.go) - provides node-local IP address assignments.
+//		With UseK8sPodCIDR, the pod network of the node is not computed from the node ID, but the pod CIDR
+//		assigned to the node by k8s (Node.Spec.PodCIDR as reflected by KSR) is used instead. The pod network
+//		is published with the allocated ID, so that other nodes can route to it.
+//		If IPv6 is enabled in IPAM, pods are dual-stack: each pod gets both IPv4 and IPv6 address, both are
+//		returned in the CNI reply and routes for both families are configured in VPP and in the pod.
+//		A pod can request a specific IPv4 address from the pod network of the node with the annotation
+//		contivpp.io/ip-address (read from the pod reflected by KSR). The CNI Add request fails if the address
+//		is not available, e.g. already assigned to another pod or reserved for a pod with another name, and also
+//		if the pod is not reflected by KSR within a few seconds.
+//		Pods from namespaces bound to an IP pool (NamespacePools in IPAMConfig) get addresses from the network
+//		of the pool on the node, VPP routes the pool networks of the other nodes the same way as their pod networks.
+//		Assigned pod IP addresses are persisted in etcd (allocatedPodIPs/<node-name> under the KSR prefix,
+//		ipam_store.go) and restored on agent restart, so that running pods keep non-conflicting addresses.
+//
+//		5. Dataplane probe - registered with StatusCheck, so that the readiness and liveness probes of the agent
+//		(/readiness and /liveness) reflect the state of the dataplane: VPP responding to binary API requests,
+//		the main physical NIC being up and the host reaching VPP via the host interconnect (dataplane_probe.go).
+//		K8s then restarts the vswitch on dataplane failure. Connectivity to etcd is probed by the etcd plugin itself.
+//
+//		6. Metrics - if the Prometheus plugin is injected, metrics of the plugin internals are exposed
+//		on /metrics (metrics.go): latency of CNI Add/Delete/Check requests, assigned IPs and capacity
+//		of the IPAM pools, node ID allocation attempts and conflicts and errors of VPP binary API requests.
+//		The policy plugin adds the duration of the policy rendering.
+//
+//		7. Flow export - with FlowExport.CollectorAddress in the config file, the flowprobe plugin of VPP records
+//		the flows of the pod interfaces and of the main physical interface and the IPFIX exporter of VPP exports
+//		them to the collector (flow_export.go). IPFIX records carry the VPP interface and the IP addresses
+//		of the flow, /contiv/v1/flowmetadata maps the interfaces and pod IPs of the node to the pod namespace/name,
+//		so that collectors can label the flows by pods.
+//
+//		8. Tracing - the stages of CNI requests (waiting in the pipeline of the pod, IPAM, VPP and Linux
+//		transactions, secondary interfaces, persisting of the config) are recorded as timed spans of the trace
+//		the GRPC server creates for the request (tracing.go). The traces of the recent, slow and failed requests,
+//		including the policy rendering (contiv.policy family), can be browsed on /debug/requests of the agent
+//		HTTP server from localhost.
+//
+//		9. Diagnostics - IPAM of the node, the connected pods with their interfaces and the VXLAN tunnels
+//		to other nodes can be inspected via REST API, VPP CLI commands can be executed as well
+//		(diagnostics_rest.go). The API is used by the contiv-netctl tool (cmd/contiv-netctl).
+//
+//		10. Connectivity check - with ConnectivityCheck.Interval in the config file, VPP periodically pings
+//		the BVI/VXLAN endpoint of every other node and the pods with ConnectivityCheck.TestPodLabel running
+//		on them. Probes of ProbeSize bytes detect MTU blackholes - paths that pass small packets only.
+//		The results of each node are stored in ETCD, the connectivity matrix of the cluster is served
+//		on GET /contiv/v1/connectivity (connectivity_check.go).
+//
+//		11. Packet capture - the packets VPP sends to the interface of a pod can be captured into a pcap file
+//		via REST API (POST/GET/DELETE /contiv/v1/capture, the file is downloaded from /contiv/v1/capture/pcap).
+//		The capture uses the pcap tx trace of VPP, which supports a single capture at a time, and is stopped
+//		after the given number of packets or seconds, whichever comes first (packet_capture.go).
+//		The graph-node packet trace of VPP is exposed the same way (POST/GET/DELETE /contiv/v1/trace):
+//		the packets entering VPP are traced on the input nodes, the packets of the given pod are decoded
+//		into JSON together with the node that dropped them (packet_trace.go).
+//
+//		12. Pod network config API - external controllers (e.g. NFV orchestrators) can request additional
+//		interfaces attached to the secondary networks, routes inside of the pod and ACLs on the added interfaces
+//		for a running pod via GRPC (PodNetworkConfig service, model/podconfig). Each request applies a named
+//		configuration, which replaces the configuration of the same name and can be removed by the name
+//		(pod_config_api.go). The configuration is persisted and recorded with the container, so that it is
+//		restored after restart and removed together with the pod. The ACLs are named with PodConfigACLNamePrefix
+//		and left untouched by the policy renderer.
+//
+//		13. BGP - with BGP.Peers in the config file, a BGP speaker (separate package bgp, described in its own doc.go)
+//		advertises the pod network of the node with the node IP as the next hop to the upstream routers.
+//		Other plugins can advertise additional routes via the node using AdvertiseRoute of the plugin API,
+//		e.g. the service plugin advertises the external IPs of services owned by the node.
+//
+//		14. VPP supervision - the binary API of VPP is periodically probed with control pings (vpp_supervisor.go).
+//		An outage is logged and reported by the contiv_vpp_api_up and contiv_vpp_api_outages_total metrics, the probes
+//		back off while VPP is down. GoVPP re-establishes the connection itself once VPP responds again. A restart
+//		of VPP (changed PID in the ping reply) can not be handled within the running agent, since the VPP agent
+//		resyncs the configuration only once on start - the agent therefore exits and is restarted by supervisord.
+//		Disabled with VPPSupervision.Disabled in the config file.
+//
+//		15. K8s Events - failures of the pod setup (CNI Add) and of the base vswitch config are reported as Warning
+//		events of the pod or of the node (k8s_events.go), so that they are visible with kubectl describe. The agent
+//		cannot access K8s API, the events are published into etcd under the KSR prefix and recorded by KSR.
+//		Other plugins report their failures (policy rendering, service programming) via ReportK8sEvent of the plugin API.
+//
+//		The K8s state reflected by KSR (nodes, pods) is read through KVStore. In the direct K8s API mode, KVStore
+//		is injected with ksr.StateStore, which serves the K8s state (and the requests for KSR) from memory of the agent,
+//		reflected by the KSR reflectors running inside the agent, while the rest stays in etcd.
 //
-//		5. Helper functions:
+//		16. Helper functions:
 //			- host.go: provides host-related helper functions and VPP-Agent NB API builders
 //			- pod.go: provides POD-related helper functions and VPP-Agent NB API builders
 //
@@ -0,0 +1,182 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	vpp_l3 "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
+)
+
+// defaultUplinkProbeInterval is the default interval of the link state checks of the uplinks.
+const defaultUplinkProbeInterval = 2 * time.Second
+
+// UplinkInterface is an additional physical interface the traffic to other nodes is balanced across
+// together with the main VPP interface (ECMP).
+type UplinkInterface struct {
+	InterfaceName string
+	IP            string
+	Gateway       string // next hop of the other nodes via this uplink
+}
+
+// uplink is a physical interface with a path to other nodes.
+type uplink struct {
+	ifName  string
+	gateway string
+	up      bool // false if the path via the uplink is withdrawn
+}
+
+// initUplinks prepares the uplinks of the node: the main VPP interface via the default gateway
+// followed by the configured uplink interfaces. The name of the bond or of the VLAN sub-interface
+// used as the main interface is filled in once it is created.
+func (s *remoteCNIserver) initUplinks(nodeConfig *OneNodeConfig, probeInterval uint32) error {
+	if nodeConfig == nil || len(nodeConfig.UplinkInterfaces) == 0 {
+		return nil
+	}
+	if s.useSRv6 {
+		return errors.New("uplink interfaces are not supported with the srv6 inter-node transport")
+	}
+	if nodeConfig.Gateway == "" ||
+		(nodeConfig.MainVppInterface.InterfaceName == "" && len(nodeConfig.Bond.Members) == 0 && s.vlanID == 0) {
+		return errors.New("uplink interfaces require the name of the main VPP interface (or the bond) and the Gateway")
+	}
+	s.uplinks = []*uplink{{ifName: nodeConfig.MainVppInterface.InterfaceName, gateway: nodeConfig.Gateway, up: true}}
+	for _, uplinkIf := range nodeConfig.UplinkInterfaces {
+		if uplinkIf.InterfaceName == "" || uplinkIf.Gateway == "" {
+			return fmt.Errorf("uplink interface %q requires the name and the Gateway", uplinkIf.InterfaceName)
+		}
+		s.uplinks = append(s.uplinks, &uplink{ifName: uplinkIf.InterfaceName, gateway: uplinkIf.Gateway, up: true})
+	}
+	s.uplinkProbeInterval = time.Duration(probeInterval) * time.Second
+	if s.uplinkProbeInterval == 0 {
+		s.uplinkProbeInterval = defaultUplinkProbeInterval
+	}
+	s.probeUplink = s.probeNIC
+	return nil
+}
+
+// configureUplinkInterfaces configures the uplink interfaces present in VPP.
+func (s *remoteCNIserver) configureUplinkInterfaces(config *vswitchConfig, nodeConfig *OneNodeConfig) error {
+	txn := s.vppTxnFactory().Put()
+	for _, uplinkIf := range nodeConfig.UplinkInterfaces {
+		if _, _, found := s.swIfIndex.LookupIdx(uplinkIf.InterfaceName); !found {
+			s.Logger.Warnf("Uplink interface %s not found in VPP", uplinkIf.InterfaceName)
+			continue
+		}
+		nic := s.physicalInterface(uplinkIf.InterfaceName, uplinkIf.IP)
+		txn.VppInterface(nic)
+		config.nics = append(config.nics, nic)
+		s.physicalIfs = append(s.physicalIfs, nic.Name)
+	}
+	return txn.Send().ReceiveReply()
+}
+
+// uplinkRoute returns the route to the IP of the other node via the given uplink.
+func (s *remoteCNIserver) uplinkRoute(u *uplink, hostIP string) *vpp_l3.StaticRoutes_Route {
+	return &vpp_l3.StaticRoutes_Route{
+		DstIpAddr:         hostIP + "/32",
+		NextHopAddr:       u.gateway,
+		OutgoingInterface: u.ifName,
+	}
+}
+
+// uplinkRoutesToNode returns the ECMP paths to the IP of the other node, one via each uplink that is up.
+func (s *remoteCNIserver) uplinkRoutesToNode(hostIP string) []*vpp_l3.StaticRoutes_Route {
+	var routes []*vpp_l3.StaticRoutes_Route
+	for _, u := range s.uplinks {
+		if u.up {
+			routes = append(routes, s.uplinkRoute(u, hostIP))
+		}
+	}
+	return routes
+}
+
+// runUplinkMonitor periodically checks the uplinks until the context is cancelled.
+func (s *remoteCNIserver) runUplinkMonitor(ctx context.Context) {
+	ticker := time.NewTicker(s.uplinkProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// ticks are dropped while the probe waits in the event loop
+			s.loop.process("uplink probe", timerPriority, s.checkUplinks)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkUplinks probes the link state of the uplinks, withdraws the paths to other nodes via the uplinks
+// that went down and restores them once the uplinks recover. The method is run by the event loop.
+func (s *remoteCNIserver) checkUplinks() error {
+	var wasErr error
+	for _, u := range s.uplinks {
+		err := s.probeUplink(u.ifName)
+		up := err == nil
+		if up == u.up {
+			continue
+		}
+		if err := s.updateUplinkRoutes(u, up); err != nil {
+			wasErr = err
+			continue
+		}
+		u.up = up
+		if up {
+			s.Logger.Infof("Uplink %s recovered, paths to other nodes via %s restored", u.ifName, u.gateway)
+		} else {
+			s.Logger.Warnf("Uplink %s failed (%v), paths to other nodes via %s withdrawn", u.ifName, err, u.gateway)
+		}
+	}
+	return wasErr
+}
+
+// updateUplinkRoutes adds or removes the paths to all routed nodes via the given uplink.
+func (s *remoteCNIserver) updateUplinkRoutes(u *uplink, add bool) error {
+	if len(s.routedNodes) == 0 {
+		return nil
+	}
+	var err error
+	if add {
+		txn := s.vppTxnFactory().Put()
+		for _, nodeInfo := range s.routedNodes {
+			txn.StaticRoute(s.uplinkRoute(u, s.otherHostIP(uint8(nodeInfo.Id), nodeInfo.IpAddress)))
+		}
+		err = txn.Send().ReceiveReply()
+	} else {
+		txn := s.vppTxnFactory().Delete()
+		for _, nodeInfo := range s.routedNodes {
+			route := s.uplinkRoute(u, s.otherHostIP(uint8(nodeInfo.Id), nodeInfo.IpAddress))
+			txn.StaticRoute(route.VrfId, route.DstIpAddr, route.NextHopAddr)
+		}
+		err = txn.Send().ReceiveReply()
+	}
+	if err != nil {
+		return fmt.Errorf("Can't configure VPP to update paths to other nodes via uplink %s: %v", u.ifName, err)
+	}
+	return nil
+}
+
+// anyUplinkUp returns true if the traffic to other nodes can leave via at least one uplink.
+func (s *remoteCNIserver) anyUplinkUp() bool {
+	for _, u := range s.uplinks {
+		if u.up {
+			return true
+		}
+	}
+	return false
+}
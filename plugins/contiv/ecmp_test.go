@@ -0,0 +1,106 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/health/statuscheck"
+	"github.com/onsi/gomega"
+)
+
+func TestECMPUplinks(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	ecmpNodeConfig := nodeConfig
+	ecmpNodeConfig.Gateway = "192.168.1.254"
+	ecmpNodeConfig.UplinkInterfaces = []UplinkInterface{
+		{
+			InterfaceName: "GigabitEthernet0/0/0/2",
+			IP:            "192.168.2.1/24",
+			Gateway:       "192.168.2.254",
+		},
+	}
+	server, txns, _, conn := setupTestCNIServer(&configTapVxlanTCP, &ecmpNodeConfig,
+		"GigabitEthernet0/0/0/1", "GigabitEthernet0/0/0/2")
+	defer conn.Disconnect()
+	gomega.Expect(server.uplinks).To(gomega.HaveLen(2))
+
+	err := server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.GetPhysicalIfNames()).To(gomega.ContainElement("GigabitEthernet0/0/0/2"))
+	uplinkIf := interfaceInSnapshot(txns.AppliedConfig, "GigabitEthernet0/0/0/2")
+	gomega.Expect(uplinkIf).NotTo(gomega.BeNil())
+	gomega.Expect(uplinkIf.IpAddresses).To(gomega.ConsistOf("192.168.2.1/24"))
+
+	// the IP of the other node is reachable via both uplinks
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
+	gomega.Expect(err).To(gomega.BeNil())
+	hostRoute := server.ipPrefixToAddress(otherNodeInfo.IpAddress) + "/32"
+	viaMain := routesViaInSnapshot(txns.AppliedConfig, "192.168.1.254")
+	gomega.Expect(viaMain).To(gomega.HaveLen(2)) // + the default route
+	gomega.Expect(viaMain).To(gomega.ContainElement(server.uplinkRoute(server.uplinks[0], server.ipPrefixToAddress(otherNodeInfo.IpAddress))))
+	viaUplink := routesViaInSnapshot(txns.AppliedConfig, "192.168.2.254")
+	gomega.Expect(viaUplink).To(gomega.HaveLen(1))
+	gomega.Expect(viaUplink[0].DstIpAddr).To(gomega.Equal(hostRoute))
+	gomega.Expect(viaUplink[0].OutgoingInterface).To(gomega.Equal("GigabitEthernet0/0/0/2"))
+
+	// the path via the failed uplink is withdrawn, the dataplane remains functional
+	failed := map[string]bool{}
+	server.probeUplink = func(ifName string) error {
+		if failed[ifName] {
+			return errors.New("interface is down")
+		}
+		return nil
+	}
+	origPingHost := pingHost
+	pingHost = func(ip net.IP) error { return nil }
+	defer func() { pingHost = origPingHost }()
+
+	failed["GigabitEthernet0/0/0/2"] = true
+	gomega.Expect(server.checkUplinks()).To(gomega.Succeed())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "192.168.2.254")).To(gomega.BeEmpty())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "192.168.1.254")).To(gomega.HaveLen(2))
+	state, err := server.dataplaneProbe()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(state).To(gomega.BeEquivalentTo(statuscheck.OK))
+
+	// ... and restored once the uplink recovers
+	failed["GigabitEthernet0/0/0/2"] = false
+	gomega.Expect(server.checkUplinks()).To(gomega.Succeed())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "192.168.2.254")).To(gomega.HaveLen(1))
+
+	// dataplane fails only with all uplinks down
+	failed["GigabitEthernet0/0/0/1"] = true
+	failed["GigabitEthernet0/0/0/2"] = true
+	gomega.Expect(server.checkUplinks()).To(gomega.Succeed())
+	_, err = server.dataplaneProbe()
+	gomega.Expect(err).To(gomega.MatchError("all uplinks are down"))
+
+	// only the paths present are removed with the node
+	failed["GigabitEthernet0/0/0/1"] = false
+	gomega.Expect(server.checkUplinks()).To(gomega.Succeed())
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Delete})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "192.168.1.254")).To(gomega.HaveLen(1))
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "192.168.2.254")).To(gomega.BeEmpty())
+
+	// uplinks require the gateway of the main interface
+	ecmpNodeConfig.Gateway = ""
+	gomega.Expect(server.initUplinks(&ecmpNodeConfig, 0)).NotTo(gomega.Succeed())
+}
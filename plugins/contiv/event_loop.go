@@ -0,0 +1,167 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ligato/cn-infra/logging"
+)
+
+// eventPriority orders the events waiting in the event loop, events of a lower value are handled first.
+type eventPriority int
+
+const (
+	// resyncPriority is the priority of the resync of the vswitch.
+	resyncPriority eventPriority = iota
+
+	// vswitchPriority is the priority of the events completing or changing the base vswitch config
+	// (DHCP leases).
+	vswitchPriority
+
+	// nodePriority is the priority of the changes of other nodes, of the node config and of the reloaded
+	// config file.
+	nodePriority
+
+	// timerPriority is the priority of the periodic tasks (uplink probes, IPsec rekey).
+	timerPriority
+
+	numEventPriorities
+)
+
+// errEventLoopStopped is returned by process if the event loop was stopped before the event was handled.
+var errEventLoopStopped = errors.New("event loop stopped")
+
+// gatedPriority is the first priority of the events that are not handled until the base vswitch config
+// is applied.
+const gatedPriority = nodePriority
+
+// loopEvent is an event waiting in the event loop.
+type loopEvent struct {
+	name    string
+	handler func() error
+	done    func(err error) // called with the result of the handler, may be nil
+}
+
+// eventLoop handles the events changing the config of the vswitch one by one in the order of their priority,
+// events of the same priority are handled in the order of their arrival. The handlers run with the write lock
+// held, which excludes the CNI requests (processed concurrently with the read lock by the pod pipeline).
+// The events of gatedPriority and lower priorities wait in the queue until the base vswitch config is applied,
+// so that no handler needs to wait for it.
+type eventLoop struct {
+	sync.Mutex
+	logger logging.Logger
+
+	// lock held while an event is handled
+	lock sync.Locker
+
+	// configured returns true once the base vswitch config is applied, called with the lock held
+	configured func() bool
+
+	queues  [numEventPriorities][]*loopEvent
+	wakeup  chan struct{}
+	stopped chan struct{} // closed once run returns
+}
+
+// newEventLoop creates new instance of eventLoop, run needs to be started to handle the events.
+func newEventLoop(logger logging.Logger, lock sync.Locker, configured func() bool) *eventLoop {
+	return &eventLoop{
+		logger:     logger,
+		lock:       lock,
+		configured: configured,
+		wakeup:     make(chan struct{}, 1),
+		stopped:    make(chan struct{}),
+	}
+}
+
+// push queues the event without waiting for it to be handled, done (if not nil) is called
+// with the result of the handler.
+func (l *eventLoop) push(name string, priority eventPriority, handler func() error, done func(err error)) {
+	l.Lock()
+	l.queues[priority] = append(l.queues[priority], &loopEvent{name: name, handler: handler, done: done})
+	l.Unlock()
+
+	select {
+	case l.wakeup <- struct{}{}:
+	default:
+		// the loop is already woken up
+	}
+}
+
+// process queues the event and blocks until it is handled, returns the result of the handler.
+func (l *eventLoop) process(name string, priority eventPriority, handler func() error) error {
+	result := make(chan error, 1)
+	l.push(name, priority, handler, func(err error) { result <- err })
+	select {
+	case err := <-result:
+		return err
+	case <-l.stopped:
+		return errEventLoopStopped
+	}
+}
+
+// run handles the events until the context is cancelled.
+func (l *eventLoop) run(ctx context.Context) {
+	defer close(l.stopped)
+	for {
+		for l.handleNext() {
+		}
+		select {
+		case <-l.wakeup:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// handleNext handles the first event of the highest priority that can be handled, returns false
+// if there is none.
+func (l *eventLoop) handleNext() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	event := l.pop()
+	if event == nil {
+		return false
+	}
+	err := event.handler()
+	if err != nil {
+		l.logger.Errorf("Handling of %s failed: %v", event.name, err)
+	}
+	if event.done != nil {
+		event.done(err)
+	}
+	return true
+}
+
+// pop removes the next event from the queues, the gated events are skipped until the base vswitch config
+// is applied. The lock has to be held.
+func (l *eventLoop) pop() *loopEvent {
+	l.Lock()
+	defer l.Unlock()
+	for priority, queue := range l.queues {
+		if len(queue) == 0 {
+			continue
+		}
+		if eventPriority(priority) >= gatedPriority && !l.configured() {
+			return nil
+		}
+		l.queues[priority] = queue[1:]
+		return queue[0]
+	}
+	return nil
+}
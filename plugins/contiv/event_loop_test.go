@@ -0,0 +1,77 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+)
+
+func TestEventLoop(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	var lock sync.Mutex
+	configured := false
+	loop := newEventLoop(logrus.DefaultLogger(), &lock, func() bool { return configured })
+
+	var handled []string
+	handler := func(name string) func() error {
+		return func() error {
+			handled = append(handled, name)
+			return nil
+		}
+	}
+
+	// the events are queued before the loop is started, gated events wait for the vswitch config
+	results := make(chan error, 10)
+	loop.push("timer", timerPriority, handler("timer"), func(err error) { results <- err })
+	loop.push("node1", nodePriority, handler("node1"), func(err error) { results <- err })
+	loop.push("node2", nodePriority, handler("node2"), func(err error) { results <- err })
+	loop.push("dhcp", vswitchPriority, handler("dhcp"), func(err error) { results <- err })
+
+	var resyncErr error
+	resynced := make(chan struct{})
+	loop.push("resync", resyncPriority, func() error {
+		handled = append(handled, "resync")
+		configured = true
+		return errors.New("partially failed")
+	}, func(err error) {
+		resyncErr = err
+		close(resynced)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go loop.run(ctx)
+
+	<-resynced
+	gomega.Expect(resyncErr).To(gomega.HaveOccurred())
+	for i := 0; i < 4; i++ {
+		gomega.Expect(<-results).To(gomega.Succeed())
+	}
+	gomega.Expect(loop.process("node3", nodePriority, handler("node3"))).To(gomega.Succeed())
+
+	lock.Lock()
+	gomega.Expect(handled).To(gomega.Equal([]string{"resync", "dhcp", "node1", "node2", "timer", "node3"}))
+	lock.Unlock()
+
+	cancel()
+	<-loop.stopped
+	gomega.Expect(loop.process("late", nodePriority, handler("late"))).To(gomega.Equal(errEventLoopStopped))
+}
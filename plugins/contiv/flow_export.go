@@ -0,0 +1,194 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:generate binapi-generator --input-file=/usr/share/vpp/api/flowprobe.api.json --output-dir=bin_api
+//go:generate binapi-generator --input-file=/usr/share/vpp/api/ipfix_export.api.json --output-dir=bin_api
+
+package contiv
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+
+	"github.com/contiv/vpp/plugins/contiv/bin_api/flowprobe"
+	"github.com/contiv/vpp/plugins/contiv/bin_api/ipfix_export"
+	"github.com/unrolled/render"
+)
+
+const (
+	// defaultIPFIXCollectorPort is the IANA port of IPFIX collectors.
+	defaultIPFIXCollectorPort = 4739
+
+	// defaultIPFIXTemplateInterval is the default period (in seconds) of re-sending of the IPFIX templates.
+	defaultIPFIXTemplateInterval = 20
+
+	// ipfixPathMTU is the max. size of IPFIX packets, leaves room for the VXLAN encapsulation.
+	ipfixPathMTU = 1450
+
+	// flowprobe record flags: L3 (IP addresses) and L4 (ports) fields are recorded
+	flowprobeRecordL3 = 1 << 1
+	flowprobeRecordL4 = 1 << 2
+
+	// flowprobeWhichIP4 selects the IPv4 flow variant of the flowprobe feature
+	flowprobeWhichIP4 = 0
+
+	// flowMetadataURL is the URL of the REST API mapping flow records to pods.
+	flowMetadataURL = "/contiv/v1/flowmetadata"
+)
+
+// FlowExportConfig configures the export of the flows observed by VPP as IPFIX records.
+type FlowExportConfig struct {
+	CollectorAddress string // IPv4 address of the IPFIX collector, flow export is disabled if empty
+	CollectorPort    uint16 // UDP port of the collector, 4739 by default
+	TemplateInterval uint32 // period of re-sending of the templates in seconds, 20 by default
+	ActiveTimer      uint32 // active flows are exported after the given number of seconds, VPP default if 0
+	PassiveTimer     uint32 // idle flows are exported after the given number of seconds, VPP default if 0
+}
+
+// FlowMetadata maps the interface reported in IPFIX records (egressInterface) and the IP address
+// of the pod to the pod identity, so that collectors can label the flows of the node by pods.
+type FlowMetadata struct {
+	SwIfIndex    uint32 `json:"swIfIndex"`
+	Interface    string `json:"interface"`
+	PodIP        string `json:"podIP"`
+	PodNamespace string `json:"podNamespace"`
+	PodName      string `json:"podName"`
+}
+
+// flowExportEnabled returns true if the flows should be exported to an IPFIX collector.
+func (s *remoteCNIserver) flowExportEnabled() bool {
+	return s.flowExport.CollectorAddress != ""
+}
+
+// configureFlowExport configures the IPFIX exporter of VPP with the node IP as the source address
+// and enables the flowprobe on the main physical interface, so that the flows leaving the node are recorded.
+// The flows of the pods are recorded on their interfaces (enableFlowProbe). The exporter is configured
+// once the node IP is known (with DHCP after the address is assigned). The method must be called
+// with acquired mutex guarding remoteCNI server.
+func (s *remoteCNIserver) configureFlowExport() error {
+	if !s.flowExportEnabled() || s.nodeIP == "" {
+		return nil
+	}
+	collectorIP := net.ParseIP(s.flowExport.CollectorAddress).To4()
+	if collectorIP == nil {
+		return fmt.Errorf("invalid IPFIX collector address %s", s.flowExport.CollectorAddress)
+	}
+	srcIP, _, err := net.ParseCIDR(s.nodeIP)
+	if err != nil {
+		return err
+	}
+	port := s.flowExport.CollectorPort
+	if port == 0 {
+		port = defaultIPFIXCollectorPort
+	}
+	templateInterval := s.flowExport.TemplateInterval
+	if templateInterval == 0 {
+		templateInterval = defaultIPFIXTemplateInterval
+	}
+
+	exporterReq := &ipfix_export.SetIpfixExporter{
+		CollectorAddress: collectorIP,
+		CollectorPort:    port,
+		SrcAddress:       srcIP.To4(),
+		PathMtu:          ipfixPathMTU,
+		TemplateInterval: templateInterval,
+	}
+	exporterReply := &ipfix_export.SetIpfixExporterReply{}
+	if err := s.sendVppRequest(exporterReq, exporterReply, &exporterReply.Retval); err != nil {
+		return fmt.Errorf("can't configure IPFIX exporter: %v", err)
+	}
+
+	paramsReq := &flowprobe.FlowprobeParams{
+		RecordFlags:  flowprobeRecordL3 | flowprobeRecordL4,
+		ActiveTimer:  s.flowExport.ActiveTimer,
+		PassiveTimer: s.flowExport.PassiveTimer,
+	}
+	paramsReply := &flowprobe.FlowprobeParamsReply{}
+	if err := s.sendVppRequest(paramsReq, paramsReply, &paramsReply.Retval); err != nil {
+		return fmt.Errorf("can't configure flowprobe: %v", err)
+	}
+
+	if s.mainPhysicalIf != "" {
+		if err := s.enableFlowProbe(s.mainPhysicalIf); err != nil {
+			// already enabled if the exporter is re-configured
+			s.Logger.Debugf("Flowprobe not enabled on %s: %v", s.mainPhysicalIf, err)
+		}
+	}
+	s.Logger.Infof("Flows are exported to IPFIX collector %s:%d", collectorIP, port)
+	return nil
+}
+
+// enableFlowProbe enables the flowprobe on the given VPP interface.
+func (s *remoteCNIserver) enableFlowProbe(ifName string) error {
+	return s.flowProbeAddDel(ifName, true)
+}
+
+// disableFlowProbe disables the flowprobe on the given VPP interface.
+func (s *remoteCNIserver) disableFlowProbe(ifName string) error {
+	return s.flowProbeAddDel(ifName, false)
+}
+
+func (s *remoteCNIserver) flowProbeAddDel(ifName string, isAdd bool) error {
+	swIfIndex, _, found := s.swIfIndex.LookupIdx(ifName)
+	if !found {
+		return fmt.Errorf("interface %s not found", ifName)
+	}
+	req := &flowprobe.FlowprobeTxInterfaceAddDel{
+		Which:     flowprobeWhichIP4,
+		SwIfIndex: swIfIndex,
+	}
+	if isAdd {
+		req.IsAdd = 1
+	}
+	reply := &flowprobe.FlowprobeTxInterfaceAddDelReply{}
+	return s.sendVppRequest(req, reply, &reply.Retval)
+}
+
+// flowMetadata returns the identity of the pods connected to the node keyed by the interfaces
+// the flowprobe is enabled on.
+func (s *remoteCNIserver) flowMetadata() []*FlowMetadata {
+	metadata := []*FlowMetadata{}
+	if s.configuredContainers == nil {
+		return metadata
+	}
+	for _, containerID := range s.configuredContainers.ListAll() {
+		config, found := s.configuredContainers.LookupContainer(containerID)
+		if !found || config.VppIf == nil || config.VppARPEntry == nil {
+			continue
+		}
+		swIfIndex, _, found := s.swIfIndex.LookupIdx(config.VppIf.Name)
+		if !found {
+			continue
+		}
+		metadata = append(metadata, &FlowMetadata{
+			SwIfIndex:    swIfIndex,
+			Interface:    config.VppIf.Name,
+			PodIP:        config.VppARPEntry.IpAddress,
+			PodNamespace: config.PodNamespace,
+			PodName:      config.PodName,
+		})
+	}
+	sort.Slice(metadata, func(i, j int) bool { return metadata[i].SwIfIndex < metadata[j].SwIfIndex })
+	return metadata
+}
+
+// flowMetadataHandler processes requests to map the flows exported by the node to pods
+// (curl -X GET http://localhost:<port>/contiv/v1/flowmetadata).
+func (plugin *Plugin) flowMetadataHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		formatter.JSON(w, http.StatusOK, plugin.cniServer.flowMetadata())
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+func TestFlowExport(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := configVethL2NoTCP
+	config.FlowExport = FlowExportConfig{CollectorAddress: "10.0.0.100"}
+	server, _, _, conn := setupTestCNIServer(&config, &nodeConfig)
+	defer conn.Disconnect()
+
+	// the exporter is configured with the vswitch connectivity
+	gomega.Expect(server.resync()).To(gomega.Succeed())
+
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+
+	// the flows of the pod can be mapped to the pod identity
+	metadata := server.flowMetadata()
+	gomega.Expect(metadata).To(gomega.HaveLen(1))
+	gomega.Expect(metadata[0].PodName).To(gomega.BeEquivalentTo(podName))
+	gomega.Expect(metadata[0].PodNamespace).To(gomega.BeEquivalentTo("default"))
+	gomega.Expect(reply.Interfaces[0].IpAddresses[0].Address).To(gomega.HavePrefix(metadata[0].PodIP + "/"))
+	gomega.Expect(metadata[0].SwIfIndex).NotTo(gomega.BeZero())
+
+	reply, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+	gomega.Expect(server.flowMetadata()).To(gomega.BeEmpty())
+}
+
+func TestFlowExportInvalidCollector(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := configVethL2NoTCP
+	config.FlowExport = FlowExportConfig{CollectorAddress: "collector"}
+	server, _, _, conn := setupTestCNIServer(&config, &nodeConfig)
+	defer conn.Disconnect()
+
+	gomega.Expect(server.resync()).NotTo(gomega.Succeed())
+}
@@ -16,6 +16,7 @@ package contiv
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
 	"strconv"
 
@@ -61,6 +62,28 @@ func (s *remoteCNIserver) routeFromHost() *linux_l3.LinuxStaticRoutes_Route {
 	return route
 }
 
+func (s *remoteCNIserver) routeFromHostIPv6() *linux_l3.LinuxStaticRoutes_Route {
+	route := s.routeFromHost()
+	route.Name = "host-to-vpp-ip6"
+	route.Description = "IPv6 route from host to VPP for this K8s node."
+	route.DstIpAddr = s.ipam.PodSubnetIPv6().String()
+	route.GwAddr = s.ipam.VEthVPPEndIPv6().String()
+	return route
+}
+
+// routesFromHostToPools returns routes from the host to VPP for the subnets of the namespace IP pools.
+func (s *remoteCNIserver) routesFromHostToPools() []*linux_l3.LinuxStaticRoutes_Route {
+	var routes []*linux_l3.LinuxStaticRoutes_Route
+	for i, subnet := range s.ipam.NamespacePoolSubnets() {
+		route := s.routeFromHost()
+		route.Name = fmt.Sprintf("host-to-vpp-pool%d", i)
+		route.Description = "Route from host to VPP for the namespace IP pool."
+		route.DstIpAddr = subnet.String()
+		routes = append(routes, route)
+	}
+	return routes
+}
+
 func (s *remoteCNIserver) routeServicesFromHost() *linux_l3.LinuxStaticRoutes_Route {
 	route := &linux_l3.LinuxStaticRoutes_Route{
 		Name:        "service-to-vpp",
@@ -110,7 +133,6 @@ func (s *remoteCNIserver) routesToHost() []*vpp_l3.StaticRoutes_Route {
 }
 
 func (s *remoteCNIserver) interconnectTap() *vpp_intf.Interfaces_Interface {
-	size, _ := s.ipam.VPPHostNetwork().Mask.Size()
 	tap := &vpp_intf.Interfaces_Interface{
 		Name:    tapVPPEndLogicalName,
 		Type:    vpp_intf.InterfaceType_TAP_INTERFACE,
@@ -118,9 +140,9 @@ func (s *remoteCNIserver) interconnectTap() *vpp_intf.Interfaces_Interface {
 		Tap: &vpp_intf.Interfaces_Interface_Tap{
 			HostIfName: tapHostEndName,
 		},
-		IpAddresses: []string{s.ipam.VEthVPPEndIP().String() + "/" + strconv.Itoa(size)},
+		IpAddresses: s.vppEndIPAddresses(),
 	}
-	if s.tapVersion == 2 {
+	if s.currentTAPVersion() == 2 {
 		tap.Tap.Version = 2
 		tap.Tap.RxRingSize = uint32(s.tapV2RxRingSize)
 		tap.Tap.TxRingSize = uint32(s.tapV2TxRingSize)
@@ -130,22 +152,50 @@ func (s *remoteCNIserver) interconnectTap() *vpp_intf.Interfaces_Interface {
 }
 
 func (s *remoteCNIserver) configureInterfconnectHostTap() error {
+	// Leave room for the encapsulation between the nodes (see configureMTU).
+	if s.podMTU != 0 {
+		if err := linuxcalls.SetInterfaceMTU(tapHostEndName, int(s.podMTU), nil); err != nil {
+			return err
+		}
+	}
+
 	// Set TAP interface IP to that of the Pod.
-	return linuxcalls.AddInterfaceIP(tapHostEndName, &net.IPNet{IP: s.ipam.VEthHostEndIP(), Mask: s.ipam.VPPHostNetwork().Mask}, nil)
+	err := linuxcalls.AddInterfaceIP(tapHostEndName, &net.IPNet{IP: s.ipam.VEthHostEndIP(), Mask: s.ipam.VPPHostNetwork().Mask}, nil)
+	if err != nil || !s.ipam.IPv6Enabled() {
+		return err
+	}
+	return linuxcalls.AddInterfaceIP(tapHostEndName, &net.IPNet{IP: s.ipam.VEthHostEndIPv6(), Mask: s.ipam.VPPHostNetworkIPv6().Mask}, nil)
+}
+
+// vppEndIPAddresses returns IP addresses of the VPP end of the VPP to host interconnect.
+func (s *remoteCNIserver) vppEndIPAddresses() []string {
+	size, _ := s.ipam.VPPHostNetwork().Mask.Size()
+	addresses := []string{s.ipam.VEthVPPEndIP().String() + "/" + strconv.Itoa(size)}
+	if s.ipam.IPv6Enabled() {
+		size6, _ := s.ipam.VPPHostNetworkIPv6().Mask.Size()
+		addresses = append(addresses, s.ipam.VEthVPPEndIPv6().String()+"/"+strconv.Itoa(size6))
+	}
+	return addresses
 }
 
 func (s *remoteCNIserver) interconnectVethHost() *linux_intf.LinuxInterfaces_Interface {
 	size, _ := s.ipam.VPPHostNetwork().Mask.Size()
-	return &linux_intf.LinuxInterfaces_Interface{
+	veth := &linux_intf.LinuxInterfaces_Interface{
 		Name:       vethHostEndLogicalName,
 		Type:       linux_intf.LinuxInterfaces_VETH,
 		Enabled:    true,
 		HostIfName: vethHostEndName,
+		Mtu:        s.podMTU,
 		Veth: &linux_intf.LinuxInterfaces_Interface_Veth{
 			PeerIfName: vethVPPEndLogicalName,
 		},
 		IpAddresses: []string{s.ipam.VEthHostEndIP().String() + "/" + strconv.Itoa(size)},
 	}
+	if s.ipam.IPv6Enabled() {
+		size6, _ := s.ipam.VPPHostNetworkIPv6().Mask.Size()
+		veth.IpAddresses = append(veth.IpAddresses, s.ipam.VEthHostEndIPv6().String()+"/"+strconv.Itoa(size6))
+	}
+	return veth
 }
 
 func (s *remoteCNIserver) interconnectVethVpp() *linux_intf.LinuxInterfaces_Interface {
@@ -154,6 +204,7 @@ func (s *remoteCNIserver) interconnectVethVpp() *linux_intf.LinuxInterfaces_Inte
 		Type:       linux_intf.LinuxInterfaces_VETH,
 		Enabled:    true,
 		HostIfName: vethVPPEndName,
+		Mtu:        s.podMTU,
 		Veth: &linux_intf.LinuxInterfaces_Interface_Veth{
 			PeerIfName: vethHostEndLogicalName,
 		},
@@ -165,7 +216,6 @@ func (s *remoteCNIserver) interconnectAfpacketName() string {
 }
 
 func (s *remoteCNIserver) interconnectAfpacket() *vpp_intf.Interfaces_Interface {
-	size, _ := s.ipam.VPPHostNetwork().Mask.Size()
 	return &vpp_intf.Interfaces_Interface{
 		Name:    s.interconnectAfpacketName(),
 		Type:    vpp_intf.InterfaceType_AF_PACKET_INTERFACE,
@@ -173,7 +223,7 @@ func (s *remoteCNIserver) interconnectAfpacket() *vpp_intf.Interfaces_Interface
 		Afpacket: &vpp_intf.Interfaces_Interface_Afpacket{
 			HostIfName: vethVPPEndName,
 		},
-		IpAddresses: []string{s.ipam.VEthVPPEndIP().String() + "/" + strconv.Itoa(size)},
+		IpAddresses: s.vppEndIPAddresses(),
 	}
 }
 
@@ -201,17 +251,30 @@ func (s *remoteCNIserver) vxlanBVILoopback() (*vpp_intf.Interfaces_Interface, er
 	if err != nil {
 		return nil, err
 	}
-	return &vpp_intf.Interfaces_Interface{
+	bvi := &vpp_intf.Interfaces_Interface{
 		Name:        "vxlanBVI",
 		Type:        vpp_intf.InterfaceType_SOFTWARE_LOOPBACK,
 		Enabled:     true,
 		IpAddresses: []string{vxlanIP.String()},
 		PhysAddress: s.hwAddrForVXLAN(),
-	}, nil
+	}
+	if s.ipam.IPv6Enabled() {
+		vxlanIPv6, err := s.ipam.VxlanIPv6WithPrefix(s.ipam.NodeID())
+		if err != nil {
+			return nil, err
+		}
+		bvi.IpAddresses = append(bvi.IpAddresses, vxlanIPv6.String())
+	}
+	return bvi, nil
 }
 
 func (s *remoteCNIserver) hwAddrForVXLAN() string {
-	return fmt.Sprintf("1a:2b:3c:4d:5e:%02x", s.ipam.NodeID())
+	return hwAddrForNodeVXLAN(s.ipam.NodeID())
+}
+
+// hwAddrForNodeVXLAN returns the MAC address of the VXLAN BVI of the node with the given ID.
+func hwAddrForNodeVXLAN(nodeID uint8) string {
+	return fmt.Sprintf("1a:2b:3c:4d:5e:%02x", nodeID)
 }
 
 func (s *remoteCNIserver) vxlanBridgeDomain(bviInterface string) *vpp_l2.BridgeDomains_BridgeDomain {
@@ -231,8 +294,10 @@ func (s *remoteCNIserver) vxlanBridgeDomain(bviInterface string) *vpp_l2.BridgeD
 	}
 }
 
-func (s *remoteCNIserver) computeRoutesToHost(hostID uint8, nextHopIP string) (podsRoute *vpp_l3.StaticRoutes_Route, hostRoute *vpp_l3.StaticRoutes_Route, err error) {
-	podsRoute, err = s.routeToOtherHostPods(hostID, nextHopIP)
+// computeRoutesToHost computes routes to pods and to the host stack of another host. The pod network of the host
+// is computed from the host ID unless podNetwork assigned by k8s is given.
+func (s *remoteCNIserver) computeRoutesToHost(hostID uint8, podNetwork string, nextHopIP string) (podsRoute *vpp_l3.StaticRoutes_Route, hostRoute *vpp_l3.StaticRoutes_Route, err error) {
+	podsRoute, err = s.routeToOtherHostPods(hostID, podNetwork, nextHopIP)
 	if err != nil {
 		err = fmt.Errorf("Can't construct route to pods of host %v: %v ", hostID, err)
 		return
@@ -245,7 +310,14 @@ func (s *remoteCNIserver) computeRoutesToHost(hostID uint8, nextHopIP string) (p
 	return
 }
 
-func (s *remoteCNIserver) routeToOtherHostPods(hostID uint8, nextHopIP string) (*vpp_l3.StaticRoutes_Route, error) {
+func (s *remoteCNIserver) routeToOtherHostPods(hostID uint8, podNetworkCIDR string, nextHopIP string) (*vpp_l3.StaticRoutes_Route, error) {
+	if podNetworkCIDR != "" {
+		_, podNetwork, err := net.ParseCIDR(podNetworkCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("Can't parse pod network %v of host ID %v, error: %v ", podNetworkCIDR, hostID, err)
+		}
+		return s.routeToOtherHostNetworks(podNetwork, nextHopIP)
+	}
 	podNetwork, err := s.ipam.OtherNodePodNetwork(hostID)
 	if err != nil {
 		return nil, fmt.Errorf("Can't compute pod network for host ID %v, error: %v ", hostID, err)
@@ -253,6 +325,36 @@ func (s *remoteCNIserver) routeToOtherHostPods(hostID uint8, nextHopIP string) (
 	return s.routeToOtherHostNetworks(podNetwork, nextHopIP)
 }
 
+// routesToOtherHostPools returns the routes to the namespace IP pool networks of another host.
+func (s *remoteCNIserver) routesToOtherHostPools(hostID uint8, nextHopIP string) ([]*vpp_l3.StaticRoutes_Route, error) {
+	poolNetworks, err := s.ipam.OtherNodeNamespacePoolNetworks(hostID)
+	if err != nil {
+		return nil, fmt.Errorf("Can't compute IP pool networks for host ID %v, error: %v ", hostID, err)
+	}
+	var routes []*vpp_l3.StaticRoutes_Route
+	for _, poolNetwork := range poolNetworks {
+		route, err := s.routeToOtherHostNetworks(poolNetwork, nextHopIP)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// routeToOtherHostPodsIPv6 returns the route to IPv6 pods of another host via its VXLAN BVI.
+func (s *remoteCNIserver) routeToOtherHostPodsIPv6(hostID uint8) (*vpp_l3.StaticRoutes_Route, error) {
+	podNetwork, err := s.ipam.OtherNodePodNetworkIPv6(hostID)
+	if err != nil {
+		return nil, fmt.Errorf("Can't compute IPv6 pod network for host ID %v, error: %v ", hostID, err)
+	}
+	nextHop, err := s.ipam.VxlanIPv6Address(hostID)
+	if err != nil {
+		return nil, fmt.Errorf("Can't compute IPv6 VXLAN address for host ID %v, error: %v ", hostID, err)
+	}
+	return s.routeToOtherHostNetworks(podNetwork, nextHop.String())
+}
+
 func (s *remoteCNIserver) routeToOtherHostStack(hostID uint8, nextHopIP string) (*vpp_l3.StaticRoutes_Route, error) {
 	hostNw, err := s.ipam.OtherNodeVPPHostNetwork(hostID)
 	if err != nil {
@@ -270,7 +372,7 @@ func (s *remoteCNIserver) routeToOtherHostNetworks(destNetwork *net.IPNet, nextH
 
 func (s *remoteCNIserver) computeVxlanToHost(hostID uint8, hostIP string) (*vpp_intf.Interfaces_Interface, error) {
 	return &vpp_intf.Interfaces_Interface{
-		Name:    fmt.Sprintf("vxlan%d", hostID),
+		Name:    vxlanIfName(hostID),
 		Type:    vpp_intf.InterfaceType_VXLAN_TUNNEL,
 		Enabled: true,
 		Vxlan: &vpp_intf.Interfaces_Interface_Vxlan{
@@ -281,6 +383,35 @@ func (s *remoteCNIserver) computeVxlanToHost(hostID uint8, hostIP string) (*vpp_
 	}, nil
 }
 
+// arpToOtherHostBVI returns the static ARP entry for the VXLAN BVI of another host. The MAC address
+// of the BVI is derived from the host ID, the next hop of the routes to the host (and its pods)
+// is thus resolved without flooding ARP requests to all VXLAN tunnels.
+func (s *remoteCNIserver) arpToOtherHostBVI(hostID uint8, bviIP net.IP) *vpp_l3.ArpTable_ArpTableEntry {
+	return &vpp_l3.ArpTable_ArpTableEntry{
+		Interface:   s.vxlanBVIIfName,
+		IpAddress:   bviIP.String(),
+		PhysAddress: hwAddrForNodeVXLAN(hostID),
+		Static:      true,
+	}
+}
+
+// fibToOtherHostBVI returns the static L2 FIB entry forwarding the traffic destined to the VXLAN BVI
+// of another host into the VXLAN tunnel to the host, instead of flooding it until the MAC address is learned.
+func (s *remoteCNIserver) fibToOtherHostBVI(hostID uint8, vxlanIfName string) *vpp_l2.FibTableEntries_FibTableEntry {
+	return &vpp_l2.FibTableEntries_FibTableEntry{
+		PhysAddress:       hwAddrForNodeVXLAN(hostID),
+		BridgeDomain:      s.vxlanBD.Name,
+		Action:            vpp_l2.FibTableEntries_FibTableEntry_FORWARD,
+		OutgoingInterface: vxlanIfName,
+		StaticConfig:      true,
+	}
+}
+
+// vxlanIfName returns the name of the VXLAN tunnel to the host with the given ID.
+func vxlanIfName(hostID uint8) string {
+	return fmt.Sprintf("vxlan%d", hostID)
+}
+
 func (s *remoteCNIserver) addInterfaceToVxlanBD(bd *vpp_l2.BridgeDomains_BridgeDomain, ifName string) {
 	bd.Interfaces = append(bd.Interfaces, &vpp_l2.BridgeDomains_BridgeDomain_Interfaces{
 		Name:              ifName,
@@ -288,6 +419,16 @@ func (s *remoteCNIserver) addInterfaceToVxlanBD(bd *vpp_l2.BridgeDomains_BridgeD
 	})
 }
 
+// removeInterfaceFromVxlanBD removes the interface (the VXLAN tunnel to a removed host) from the bridge domain.
+func (s *remoteCNIserver) removeInterfaceFromVxlanBD(bd *vpp_l2.BridgeDomains_BridgeDomain, ifName string) {
+	for i, bdIf := range bd.Interfaces {
+		if bdIf.Name == ifName {
+			bd.Interfaces = append(bd.Interfaces[:i], bd.Interfaces[i+1:]...)
+			return
+		}
+	}
+}
+
 func (s *remoteCNIserver) otherHostIP(hostID uint8, hostIPPrefix string) string {
 	// determine next hop IP - either use provided one, or calculate based on hostIPPrefix
 	if hostIPPrefix != "" {
@@ -336,3 +477,28 @@ func (s *remoteCNIserver) getHostLinkIPs() ([]string, error) {
 	}
 	return res, nil
 }
+
+// configureHostNDProxy enables proxying of neighbor discovery on the configured host interface,
+// so that the host answers neighbor solicitations for the IPv6 address of the VPP end of the interconnect.
+func (s *remoteCNIserver) configureHostNDProxy() error {
+	ifName := s.ipam.NDProxyInterface()
+	if ifName == "" {
+		return nil
+	}
+	for _, param := range []string{"proxy_ndp", "forwarding"} {
+		err := ioutil.WriteFile(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/%s", ifName, param), []byte("1"), 0644)
+		if err != nil {
+			return fmt.Errorf("unable to enable %s on %s: %v", param, ifName, err)
+		}
+	}
+	link, err := netlink.LinkByName(ifName)
+	if err != nil {
+		return err
+	}
+	return netlink.NeighSet(&netlink.Neigh{
+		LinkIndex: link.Attrs().Index,
+		Family:    netlink.FAMILY_V6,
+		Flags:     netlink.NTF_PROXY,
+		IP:        s.ipam.VEthVPPEndIPv6(),
+	})
+}
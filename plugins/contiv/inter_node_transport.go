@@ -0,0 +1,68 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import "fmt"
+
+// values of the InterNodeTransport option
+const (
+	// interNodeTransportVXLAN interconnects the nodes with the full mesh of VXLAN tunnels
+	interNodeTransportVXLAN = "vxlan"
+	// interNodeTransportNoOverlay routes the traffic of other nodes natively via their data-plane IPs
+	interNodeTransportNoOverlay = "nooverlay"
+	// interNodeTransportSRv6 encapsulates the traffic of other nodes into SRv6 towards their SIDs
+	interNodeTransportSRv6 = "srv6"
+)
+
+// selectInterNodeTransport selects how the traffic between the nodes is transported based on the InterNodeTransport
+// option. If the option is not set, UseL2Interconnect applies as it is.
+//
+// Without the overlay, the routes to the pod networks and to the host stack of other nodes point directly
+// to the data-plane IPs of the nodes. The routes carry no outgoing interface, VPP resolves the next hop
+// either as directly connected (L2-adjacent nodes) or recursively via the default gateway of the node
+// (routed fabric, which then needs to know the pod networks of the nodes).
+//
+// With SRv6 (srv6.go), there is no overlay either, but the traffic destined to the networks of other nodes
+// is steered into SRv6 policies instead of being routed, the fabric only needs to route the SIDs of the nodes.
+func (s *remoteCNIserver) selectInterNodeTransport(transport string) error {
+	switch transport {
+	case "":
+		return nil
+	case interNodeTransportVXLAN:
+		s.useL2Interconnect = false
+		s.useSRv6 = false
+	case interNodeTransportNoOverlay:
+		s.useL2Interconnect = true
+		s.useSRv6 = false
+	case interNodeTransportSRv6:
+		s.useL2Interconnect = true
+		s.useSRv6 = true
+	default:
+		return fmt.Errorf("unsupported inter-node transport %s", transport)
+	}
+	s.Logger.Infof("Using inter-node transport %s", s.interNodeTransport())
+	return nil
+}
+
+// interNodeTransport returns the currently used inter-node transport.
+func (s *remoteCNIserver) interNodeTransport() string {
+	if s.useSRv6 {
+		return interNodeTransportSRv6
+	}
+	if s.useL2Interconnect {
+		return interNodeTransportNoOverlay
+	}
+	return interNodeTransportVXLAN
+}
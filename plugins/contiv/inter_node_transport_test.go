@@ -0,0 +1,121 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/onsi/gomega"
+)
+
+func TestNoOverlayTransport(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	// the option overrides UseL2Interconnect
+	config := configTapVxlanTCP
+	config.InterNodeTransport = interNodeTransportNoOverlay
+	server, txns, _, conn := setupTestCNIServer(&config, &nodeConfig)
+	defer conn.Disconnect()
+	gomega.Expect(server.interNodeTransport()).To(gomega.BeEquivalentTo(interNodeTransportNoOverlay))
+
+	err := server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.GetVxlanBVIIfName()).To(gomega.BeEmpty())
+
+	// routes to the pod network and the host stack of the other node point directly to its IP
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, fmt.Sprintf("vxlan%d", otherNodeInfo.Id))).To(gomega.BeNil())
+	routes := routesViaInSnapshot(txns.AppliedConfig, server.ipPrefixToAddress(otherNodeInfo.IpAddress))
+	gomega.Expect(routes).To(gomega.HaveLen(2))
+	for _, route := range routes {
+		gomega.Expect(route.OutgoingInterface).To(gomega.BeEmpty())
+	}
+
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Delete})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, server.ipPrefixToAddress(otherNodeInfo.IpAddress))).To(gomega.BeEmpty())
+
+	// VXLAN can be selected explicitly
+	gomega.Expect(server.selectInterNodeTransport(interNodeTransportVXLAN)).To(gomega.Succeed())
+	gomega.Expect(server.interNodeTransport()).To(gomega.BeEquivalentTo(interNodeTransportVXLAN))
+
+	// unknown option is refused
+	gomega.Expect(server.selectInterNodeTransport("gre")).NotTo(gomega.Succeed())
+}
+
+func TestSRv6Transport(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := configTapVxlanTCP
+	config.InterNodeTransport = interNodeTransportSRv6
+	config.SRv6 = SRv6Config{
+		Waypoints: []string{"fd00:100::1"},
+		Gateway:   "fd00:200::1",
+	}
+	server, txns, _, conn := setupTestCNIServer(&config, &nodeConfig)
+	defer conn.Disconnect()
+	gomega.Expect(server.interNodeTransport()).To(gomega.BeEquivalentTo(interNodeTransportSRv6))
+
+	var commands []string
+	server.execVppCLI = func(command string) (string, error) {
+		commands = append(commands, command)
+		return "", nil
+	}
+
+	err := server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.GetVxlanBVIIfName()).To(gomega.BeEmpty())
+
+	// local SID derived from the node ID
+	err = server.configureSRv6()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(commands).To(gomega.ContainElement("set sr encaps source addr fd00:5::1"))
+	gomega.Expect(commands).To(gomega.ContainElement("sr localsid address fd00:5::1 behavior end.dt4 0"))
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "fd00:200::1")).To(gomega.HaveLen(1))
+
+	// the networks of the other node are steered into the policy instead of being routed
+	commands = nil
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, fmt.Sprintf("vxlan%d", otherNodeInfo.Id))).To(gomega.BeNil())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, server.ipPrefixToAddress(otherNodeInfo.IpAddress))).To(gomega.BeEmpty())
+	bsid := fmt.Sprintf("fd00:6::%d", otherNodeInfo.Id)
+	gomega.Expect(commands).To(gomega.ContainElement(
+		fmt.Sprintf("sr policy add bsid %s next fd00:100::1 next fd00:5::%d encap", bsid, otherNodeInfo.Id)))
+	podNetwork, err := server.ipam.OtherNodePodNetwork(uint8(otherNodeInfo.Id))
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(commands).To(gomega.ContainElement(fmt.Sprintf("sr steer l3 %s via bsid %s", podNetwork, bsid)))
+
+	commands = nil
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Delete})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(commands).To(gomega.ContainElement(fmt.Sprintf("sr steer del l3 %s via bsid %s", podNetwork, bsid)))
+	gomega.Expect(commands).To(gomega.ContainElement("sr policy del bsid " + bsid))
+
+	// errors printed by the CLI are reported
+	server.execVppCLI = func(command string) (string, error) {
+		return "sr policy: BSID already exists\n", nil
+	}
+	gomega.Expect(processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})).NotTo(gomega.Succeed())
+
+	// invalid config
+	config.SRv6 = SRv6Config{SIDSubnetCIDR: "fd00:5::/64", PolicySubnetCIDR: "fd00:5::/96"}
+	gomega.Expect(server.initSRv6(config.SRv6)).NotTo(gomega.Succeed())
+	config.SRv6 = SRv6Config{SIDSubnetCIDR: "10.5.0.0/16"}
+	gomega.Expect(server.initSRv6(config.SRv6)).NotTo(gomega.Succeed())
+}
@@ -24,4 +24,47 @@
 //		Calculated POD IPs: 10.1.5.2 - 10.1.5.254 (/24)
 //		Calculated VPP-host interconnect IPs: 172.30.5.1, 172.30.5.2 (/24)
 //  	Calculated Node Interconnect IP:  192.168.16.5 (/24)
+//
+// The size of the POD network of each node is given by PodNetworkPrefixLen (e.g. /24, /25, /26, at most /30).
+// The POD subnet must be large enough to accommodate the POD network of every node ID, e.g. any node ID fits
+// into POD subnet /16 split into /26 networks, but only node IDs up to 3 fit into /24 split into /26 networks.
+// IPAM fails to initialize if the node ID doesn't fit.
+//
+// The calculation of the POD network can be bypassed by NodePodNetworkCIDR, e.g. with the pod CIDR assigned to the node
+// by k8s. The network must be within PodSubnetCIDR.
+//
+// If PodIPReservationTTL is set, the IP address of a deleted POD stays reserved for the given number of seconds
+// for the POD with the same namespace and name, so that e.g. a restarted StatefulSet POD gets its previous IP address.
+//
+// The assigned POD IP addresses can be persisted in an AllocationStore (SetAllocationStore). The IP addresses
+// assigned before the restart of the agent are then restored from the store and not assigned to new PODs.
+// The reservations of the released IP addresses are persisted and restored as well, until they expire.
+//
+// NamespacePools define additional IP pools bound to namespaces. PODs from the listed namespaces get IP addresses
+// from the network of the pool computed for the node (the same way as the POD network from PodSubnetCIDR),
+// e.g. SubnetCIDR "10.10.0.0/16" with NetworkPrefixLen 24 gives the network 10.10.5.0/24 to the node with ID 5.
+// The pool subnets must not overlap with each other nor with the POD, VPP-host, service and VXLAN subnets.
+// The IP addresses of released PODs are reserved for their keys within the pools as well.
+// A pool with no namespaces is used only for the secondary interfaces of PODs (NextPodIPFromPool).
+//
+// IPv6 addresses are managed only if the optional IPv6 section is present in the config.
+// The IPv6 networks of the node are computed from the IPv6 subnets and the node ID
+// in the same way as the IPv4 ones (at most 8 bits are used for the node ID):
+//
+//	    IPAMConfig:
+//		  IPv6:
+//		    PodSubnetCIDR: "fd00:10:1::/48"
+//		    PodNetworkPrefixLen: 64
+//		    VPPHostSubnetCIDR: "fd00:172:30::/48"
+//		    VPPHostNetworkPrefixLen: 64
+//		    VxlanCIDR: "fd00:192:168:30::/120"
+//
+//		Assigned node ID: 5
+//
+//		Calculated POD IPv6 network: fd00:10:1:5::/64
+//		Calculated VPP-host interconnect IPv6s: fd00:172:30:5::1, fd00:172:30:5::2 (/64)
+//		Calculated VXLAN BVI IPv6: fd00:192:168:30::5 (/120)
+//
+// If NDProxyInterface is set, the host answers neighbor solicitations on that interface
+// for the IPv6 address of the VPP end of the VPP-host interconnect.
 package ipam
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/ligato/cn-infra/logging"
 )
@@ -28,8 +29,12 @@ const (
 	vethVPPEndIPSeqID  = 1              // sequence ID reserved for VPP-end of the VPP to host interconnect
 	vethHostEndIPSeqID = 2              // sequence ID reserved for host-end of the VPP to host interconnect
 	defaultServiceCIDR = "10.96.0.0/12" // default subnet allocated by service
+
+	maxNetworkPrefixLen = 30 // the smallest network of a node must fit at least the zero ending IP, gateway/VPP-end and one more IP
 )
 
+var errIPv6Disabled = fmt.Errorf("IPv6 is not configured in IPAM")
+
 // IPAM represents the basic Contiv IPAM module.
 type IPAM struct {
 	logger logging.Logger
@@ -43,6 +48,21 @@ type IPAM struct {
 	podNetworkGatewayIP net.IP           // gateway IP address for PODs on the node (given by nodeID)
 	assignedPodIPs      map[uintIP]podID // pool of assigned POD IP addresses
 
+	// additional POD IP pools bound to namespaces, IP addresses are assigned from the pool networks of this node
+	// into assignedPodIPs as well
+	namespacePools []*namespacePool
+
+	// store persisting the assigned IP addresses (optional)
+	store         AllocationStore
+	storeMutex    sync.Mutex // serializes the saves into the store, acquired without the mutex
+	revision      uint64     // revision of the assigned IP addresses, incremented with every snapshot
+	savedRevision uint64     // revision of the last saved snapshot, guarded by storeMutex
+
+	// sticky POD IP related variables
+	podIPReservationTTL time.Duration               // how long the IP address of a released POD stays reserved for its key
+	podKeys             map[podID]string            // keys (namespace/name) of the PODs with assigned IP address
+	reservedPodIPs      map[uintIP]podIPReservation // IP addresses of released PODs reserved for their keys
+
 	// VSwitch related variables
 	vppHostSubnetIPPrefix  net.IPNet // IPv4 subnet used across all nodes for VPP to host Linux stack interconnect
 	vppHostNetworkIPPrefix net.IPNet // IPv4 subnet used by the node (given by nodeID) for VPP to host Linux stack interconnect, vppHostSubnetIPPrefix + nodeID ==<computation>==> vppHostNetworkIPPrefix
@@ -56,6 +76,8 @@ type IPAM struct {
 	serviceCIDR          net.IPNet // IPv4 subnet used to allocate ClusterIPs for a service
 
 	lastAssigned int // counter denoting last assigned IP address
+
+	ip6 *ipv6IPAM // IPv6 -related variables, nil if IPv6 is not configured
 }
 
 type uintIP = uint32
@@ -71,6 +93,17 @@ type Config struct {
 	NodeInterconnectDHCP    bool   // if set to true DHCP is used to acquire IP for the main VPP interface (NodeInterconnectCIDR can be omitted in config)
 	VxlanCIDR               string // subnet used for for inter-node VXLAN
 	ServiceCIDR             string // subnet used by services
+	PodIPReservationTTL     uint32 // seconds the IP address of a deleted POD stays reserved for the POD with the same namespace/name (0 = no reservation)
+
+	// additional POD IP pools, PODs of the listed namespaces get IP addresses from the pool instead of PodSubnetCIDR
+	NamespacePools []NamespacePoolConfig
+
+	// POD network of this node assigned outside of IPAM (e.g. Node.Spec.PodCIDR allocated by k8s),
+	// if set it is used instead of the network computed from PodSubnetCIDR and node ID
+	NodePodNetworkCIDR string
+
+	// IPv6 configuration, IPv6 addresses are not managed if not defined
+	IPv6 *IPv6Config
 }
 
 // New returns new IPAM module to be used on the node specified by the nodeID.
@@ -92,6 +125,15 @@ func New(logger logging.Logger, nodeID uint8, config *Config) (*IPAM, error) {
 	if err := initializeNodeInterconnectIPAM(ipam, config); err != nil {
 		return nil, err
 	}
+	ipam.podIPReservationTTL = time.Duration(config.PodIPReservationTTL) * time.Second
+	ipam.podKeys = make(map[podID]string)
+	ipam.reservedPodIPs = make(map[uintIP]podIPReservation)
+	if err := initializeNamespacePools(ipam, config.NamespacePools, nodeID); err != nil {
+		return nil, err
+	}
+	if err := initializeIPv6IPAM(ipam, config.IPv6, nodeID); err != nil {
+		return nil, err
+	}
 	logger.Infof("IPAM values loaded: %+v", ipam)
 
 	return ipam, nil
@@ -227,6 +269,21 @@ func (i *IPAM) ServiceNetwork() *net.IPNet {
 	return &serviceNetwork
 }
 
+// SetServiceNetwork changes range allocated for services (default range if empty).
+func (i *IPAM) SetServiceNetwork(serviceCIDR string) error {
+	if serviceCIDR == "" {
+		serviceCIDR = defaultServiceCIDR
+	}
+	_, serviceSubnet, err := net.ParseCIDR(serviceCIDR)
+	if err != nil {
+		return err
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.serviceCIDR = *serviceSubnet
+	return nil
+}
+
 // PodGatewayIP returns gateway IP address of the POD network of this node.
 func (i *IPAM) PodGatewayIP() net.IP {
 	i.mutex.RLock()
@@ -243,46 +300,91 @@ func (i *IPAM) NodeID() uint8 {
 
 // NextPodIP returns next available POD IP address and remembers that this IP is meant to be used for the POD with the id <podID>.
 func (i *IPAM) NextPodIP(podID string) (net.IP, error) {
+	var snapshot *allocationsSnapshot
+	defer func() { i.saveAllocations(snapshot) }()
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
 
+	ip, err := i.nextPodIP(podID, "")
+	if err != nil {
+		return nil, err
+	}
+	snapshot = i.snapshotAllocations()
+	return ip, nil
+}
+
+// nextPodIP assigns the IP address reserved for the POD key or the next available POD IP address that is not
+// reserved for another POD key. The method must be called with acquired mutex.
+func (i *IPAM) nextPodIP(podID string, podKey string) (net.IP, error) {
 	if len(podID) == 0 { // zero byte length <=> zero character size
 		return nil, fmt.Errorf("Pod ID can't be empty because it is used to release the assigned IP address")
 	}
 
+	return i.nextIPFromNetwork(i.podNetworkIPPrefix, &i.lastAssigned, podID, podKey)
+}
+
+// nextIPFromNetwork assigns the IP address reserved for the POD key if it is from the given network (POD network
+// or network of a namespace pool), otherwise the next available IP address of the network.
+// The method must be called with acquired mutex.
+func (i *IPAM) nextIPFromNetwork(network net.IPNet, lastAssigned *int, podID string, podKey string) (net.IP, error) {
+	// get network prefix as uint32
+	networkPrefix, err := ipv4ToUint32(network.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	// IP address reserved for the POD key
+	if podKey != "" {
+		if ip, found := i.reservedPodIP(podKey); found && network.Contains(uint32ToIpv4(ip)) {
+			if _, assigned := i.assignedPodIPs[ip]; !assigned {
+				delete(i.reservedPodIPs, ip)
+				ipForAssign, success := i.tryToAllocatePodIP(int(ip-networkPrefix), networkPrefix, podID, podKey)
+				if success {
+					return ipForAssign, nil
+				}
+			}
+		}
+	}
+
+	return i.allocateFromNetwork(network, lastAssigned, podID, podKey)
+}
+
+// allocateFromNetwork assigns the next available IP address from the given network (POD network or network
+// of a namespace pool) starting after lastAssigned. The method must be called with acquired mutex.
+func (i *IPAM) allocateFromNetwork(network net.IPNet, lastAssigned *int, podID string, podKey string) (net.IP, error) {
 	// get network prefix as uint32
-	networkPrefix, err := ipv4ToUint32(i.podNetworkIPPrefix.IP)
+	networkPrefix, err := ipv4ToUint32(network.IP)
 	if err != nil {
 		return nil, err
 	}
 
-	last := i.lastAssigned + 1
+	last := *lastAssigned + 1
 	// iterate over all possible IP addresses for pod network prefix
 	// start from the last assigned and take first available IP
-	prefixBits, totalBits := i.podNetworkIPPrefix.Mask.Size()
+	prefixBits, totalBits := network.Mask.Size()
 	maxSeqID := 1 << uint(totalBits-prefixBits) //max IP addresses in network range
 	for j := last; j < maxSeqID; j++ {          // zero ending IP is reserved for network => skip seqID=0
-		ipForAssign, success := i.tryToAllocatePodIP(j, networkPrefix, podID)
+		ipForAssign, success := i.tryToAllocatePodIP(j, networkPrefix, podID, podKey)
 		if success {
-			i.lastAssigned = j
+			*lastAssigned = j
 			return ipForAssign, nil
 		}
 	}
 
 	// iterate from the range start until lastAssigned
 	for j := 1; j < last; j++ { // zero ending IP is reserved for network => skip seqID=0
-		ipForAssign, success := i.tryToAllocatePodIP(j, networkPrefix, podID)
+		ipForAssign, success := i.tryToAllocatePodIP(j, networkPrefix, podID, podKey)
 		if success {
-			i.lastAssigned = j
+			*lastAssigned = j
 			return ipForAssign, nil
 		}
 	}
 
-	return nil, fmt.Errorf("No IP address is free for assignment. All IP addresses for pod network %v are already assigned", i.podNetworkIPPrefix)
+	return nil, fmt.Errorf("No IP address is free for assignment. All IP addresses for pod network %v are already assigned", network.String())
 }
 
 // tryToAllocatePodIP checks whether the IP at the given index is available.
-func (i *IPAM) tryToAllocatePodIP(index int, networkPrefix uint32, podID string) (assignedIP net.IP, success bool) {
+func (i *IPAM) tryToAllocatePodIP(index int, networkPrefix uint32, podID string, podKey string) (assignedIP net.IP, success bool) {
 	if index == podGatewaySeqID {
 		return nil, false // gateway IP address can't be assigned as pod
 	}
@@ -290,8 +392,10 @@ func (i *IPAM) tryToAllocatePodIP(index int, networkPrefix uint32, podID string)
 	if _, found := i.assignedPodIPs[ip]; found {
 		return nil, false // ignore already assigned IP addresses
 	}
+	if i.isReservedForOther(ip, podKey) {
+		return nil, false // ignore IP addresses reserved for other PODs
+	}
 	i.assignedPodIPs[ip] = podID
-	//TODO set etcd for new assigned value
 
 	ipForAssign := uint32ToIpv4(ip)
 	i.logger.Infof("Assigned new pod IP %s", ipForAssign)
@@ -300,8 +404,50 @@ func (i *IPAM) tryToAllocatePodIP(index int, networkPrefix uint32, podID string)
 	return ipForAssign, true
 }
 
+// AllocatePodIP assigns the given IP address (e.g. requested by the POD annotation) to the POD with the id <podID>.
+// The IP address must be from the POD network of this node (or from the network of a namespace pool) and it must not
+// be assigned to other POD or reserved for a POD with another key than <podKey> (namespace/name, may be empty).
+func (i *IPAM) AllocatePodIP(podID string, podKey string, ip net.IP) error {
+	var snapshot *allocationsSnapshot
+	defer func() { i.saveAllocations(snapshot) }()
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if len(podID) == 0 {
+		return fmt.Errorf("Pod ID can't be empty because it is used to release the assigned IP address")
+	}
+	network, _, networkName, found := i.podNetworkOf(ip)
+	if !found {
+		return fmt.Errorf("IP address %v is not from any network of this node (%v)", ip, i.podNetworksString())
+	}
+	networkPrefix, err := ipv4ToUint32(network.IP)
+	if err != nil {
+		return err
+	}
+	requested, err := ipv4ToUint32(ip)
+	if err != nil {
+		return err
+	}
+	index := int(requested - networkPrefix)
+	if index == 0 || index == podGatewaySeqID {
+		return fmt.Errorf("IP address %v is reserved for the %v", ip, networkName)
+	}
+	if assignedTo, found := i.assignedPodIPs[requested]; found {
+		return fmt.Errorf("IP address %v of the %v is already assigned to pod ID %v", ip, networkName, assignedTo)
+	}
+	if _, success := i.tryToAllocatePodIP(index, networkPrefix, podID, podKey); !success {
+		return fmt.Errorf("IP address %v of the %v is reserved for another pod", ip, networkName)
+	}
+	delete(i.reservedPodIPs, requested)
+	snapshot = i.snapshotAllocations()
+	return nil
+}
+
 // ReleasePodIP releases the pod IP address remembered for POD id string, so that it can be reused by the next PODs.
+// IPv6 address of the POD is released as well (if assigned).
 func (i *IPAM) ReleasePodIP(podID string) error {
+	var snapshot *allocationsSnapshot
+	defer func() { i.saveAllocations(snapshot) }()
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
 
@@ -309,13 +455,15 @@ func (i *IPAM) ReleasePodIP(podID string) error {
 		i.logger.Warn("Ignoring pod IP releasing for pod ID that is empty string (possible echoes from restart?)")
 		return nil
 	}
+	i.releasePodIPv6(podID)
 
 	ip, err := i.findIP(podID)
 	if err != nil {
 		return fmt.Errorf("Can't release pod IP: %v", err)
 	}
 	delete(i.assignedPodIPs, ip)
-	//TODO remove from etcd (if inside etcd)
+	i.reservePodIP(podID, ip)
+	snapshot = i.snapshotAllocations()
 
 	i.logger.Infof("Released IP %v for pod ID %v", uint32ToIpv4(ip), podID)
 	i.logAssignedPodIPPool()
@@ -324,7 +472,11 @@ func (i *IPAM) ReleasePodIP(podID string) error {
 
 // initializePodsIPAM initializes POD -related variables of IPAM.
 func initializePodsIPAM(ipam *IPAM, config *Config, nodeID uint8) (err error) {
-	ipam.podSubnetIPPrefix, ipam.podNetworkIPPrefix, err = convertConfigNotation(config.PodSubnetCIDR, config.PodNetworkPrefixLen, nodeID)
+	if config.NodePodNetworkCIDR != "" {
+		ipam.podSubnetIPPrefix, ipam.podNetworkIPPrefix, err = parseAssignedPodNetwork(config.PodSubnetCIDR, config.NodePodNetworkCIDR)
+	} else {
+		ipam.podSubnetIPPrefix, ipam.podNetworkIPPrefix, err = convertConfigNotation(config.PodSubnetCIDR, config.PodNetworkPrefixLen, nodeID)
+	}
 	if err != nil {
 		return
 	}
@@ -334,7 +486,7 @@ func initializePodsIPAM(ipam *IPAM, config *Config, nodeID uint8) (err error) {
 		return
 	}
 	ipam.podNetworkGatewayIP = uint32ToIpv4(podNetworkPrefixUint32 + podGatewaySeqID)
-	ipam.assignedPodIPs = make(map[uintIP]podID) // TODO: load allocated IP addresses from ETCD (failover use case)
+	ipam.assignedPodIPs = make(map[uintIP]podID) // restored from AllocationStore if set
 	return
 }
 
@@ -405,16 +557,55 @@ func convertConfigNotation(subnetCIDR string, networkPrefixLen uint8, nodeID uin
 		err = fmt.Errorf("Network prefix length (%v) must be higher than subnet prefix length (%v) ", networkPrefixLen, subnetPrefixLen)
 		return
 	}
+	if networkPrefixLen > maxNetworkPrefixLen {
+		err = fmt.Errorf("Network prefix length (%v) must not be higher than %v", networkPrefixLen, maxNetworkPrefixLen)
+		return
+	}
 
 	networkIPPrefix, err = applyNodeID(subnetIPPrefix, nodeID, networkPrefixLen)
 	return
 }
 
+// parseAssignedPodNetwork parses POD subnet and POD network assigned to the node outside of IPAM.
+// The network must be within the subnet, since the subnet is routed from the host to VPP.
+func parseAssignedPodNetwork(subnetCIDR string, networkCIDR string) (subnetIPPrefix net.IPNet, networkIPPrefix net.IPNet, err error) {
+	_, pSubnet, err := net.ParseCIDR(subnetCIDR)
+	if err != nil {
+		err = fmt.Errorf("Can't parse SubnetCIDR \"%v\" : %v", subnetCIDR, err)
+		return
+	}
+	_, pNetwork, err := net.ParseCIDR(networkCIDR)
+	if err != nil {
+		err = fmt.Errorf("Can't parse node POD network \"%v\" : %v", networkCIDR, err)
+		return
+	}
+	if pNetwork.IP.To4() == nil {
+		err = fmt.Errorf("Node POD network %v is not an IPv4 network", networkCIDR)
+		return
+	}
+	subnetPrefixLen, _ := pSubnet.Mask.Size()
+	networkPrefixLen, _ := pNetwork.Mask.Size()
+	if !pSubnet.Contains(pNetwork.IP) || networkPrefixLen < subnetPrefixLen {
+		err = fmt.Errorf("Node POD network %v is not within POD subnet %v", networkCIDR, subnetCIDR)
+		return
+	}
+	if networkPrefixLen > maxNetworkPrefixLen {
+		err = fmt.Errorf("Node POD network %v is too small, its prefix length must not be higher than %v", networkCIDR, maxNetworkPrefixLen)
+		return
+	}
+	return newIPNet(*pSubnet), newIPNet(*pNetwork), nil
+}
+
 // applyNodeID creates network (IPNet) from subnet by adding transformed node ID to it.
+// An error is returned if the subnet can't accommodate network of the given node ID.
 func applyNodeID(subnetIPPrefix net.IPNet, nodeID uint8, networkPrefixLen uint8) (networkIPPrefix net.IPNet, err error) {
 	// compute part of IP address representing host
 	subnetPrefixLen, _ := subnetIPPrefix.Mask.Size()
 	nodePartBitSize := networkPrefixLen - uint8(subnetPrefixLen)
+	err = checkNodeIDFits(subnetIPPrefix, nodeID, networkPrefixLen, nodePartBitSize)
+	if err != nil {
+		return
+	}
 	nodeIPPart := convertToNodeIPPart(nodeID, nodePartBitSize)
 
 	// composing network IP prefix from previously computed parts
@@ -482,6 +673,20 @@ func (i *IPAM) findIP(podID string) (uintIP, error) {
 	return 0, fmt.Errorf("Can't find assigned pod IP address for pod ID \"%v\"", podID)
 }
 
+// checkNodeIDFits checks that the network of the node ID is within the subnet, i.e. that the node ID
+// is lower than the number of networks of the given prefix length that the subnet can be split into.
+func checkNodeIDFits(subnetIPPrefix net.IPNet, nodeID uint8, networkPrefixLen uint8, nodePartBitSize uint8) error {
+	if nodePartBitSize >= 8 {
+		return nil // any uint8 node ID fits
+	}
+	maxNodes := 1 << nodePartBitSize
+	if int(nodeID) >= maxNodes {
+		return fmt.Errorf("Node ID %v doesn't fit into subnet %v split into networks with prefix length %v (only %v node networks are available)",
+			nodeID, subnetIPPrefix.String(), networkPrefixLen, maxNodes)
+	}
+	return nil
+}
+
 // convertToNodeIPPart converts nodeID to part of IP address that distinguishes network IP address prefix among
 // different nodes. The result doesn't have to be that whole nodeID, because in IP address there can be allocated
 // less space than the size of the nodeID.
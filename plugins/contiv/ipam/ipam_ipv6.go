@@ -0,0 +1,379 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+const (
+	// maxIPv6SeqIDBits limits the number of POD IPv6 addresses considered for the allocation,
+	// IPv6 POD networks are usually much larger than the number of PODs on the node.
+	maxIPv6SeqIDBits = 16
+)
+
+// IPv6Config represents IPv6 configuration of the IPAM module. The networks of the node
+// are computed from the IPv6 subnets and the node ID the same way as for IPv4.
+type IPv6Config struct {
+	PodSubnetCIDR           string // IPv6 subnet from which individual POD networks are allocated
+	PodNetworkPrefixLen     uint8  // prefix length of IPv6 subnet used for all PODs within 1 node
+	VPPHostSubnetCIDR       string // IPv6 subnet used across all nodes for VPP to host Linux stack interconnect
+	VPPHostNetworkPrefixLen uint8  // prefix length of IPv6 subnet used for VPP to host Linux stack interconnect within 1 node
+	VxlanCIDR               string // IPv6 subnet used for inter-node VXLAN BVIs
+	NDProxyInterface        string // host interface on which the host answers neighbor solicitations for addresses behind VPP (optional)
+}
+
+// ipv6IPAM groups IPv6 -related variables of IPAM.
+type ipv6IPAM struct {
+	podSubnetIPPrefix   net.IPNet        // IPv6 subnet from which individual POD networks are allocated
+	podNetworkIPPrefix  net.IPNet        // IPv6 subnet prefix for all PODs on the node (given by nodeID)
+	podNetworkGatewayIP net.IP           // IPv6 gateway address for PODs on the node (given by nodeID)
+	assignedPodIPs      map[string]podID // pool of assigned POD IPv6 addresses
+	lastAssigned        uint64           // counter denoting last assigned IPv6 address
+
+	vppHostSubnetIPPrefix  net.IPNet // IPv6 subnet used across all nodes for VPP to host Linux stack interconnect
+	vppHostNetworkIPPrefix net.IPNet // IPv6 subnet used by the node (given by nodeID) for VPP to host Linux stack interconnect
+	vethVPPEndIP           net.IP    // IPv6 address for virtual ethernet's VPP-end on given node
+	vethHostEndIP          net.IP    // IPv6 address for virtual ethernet's host-end on given node
+
+	vxlanCIDR        net.IPNet // IPv6 subnet used for inter-node VXLAN
+	ndProxyInterface string    // host interface proxying neighbor discovery
+}
+
+// IPv6Enabled returns true if IPv6 addresses are managed by the IPAM.
+func (i *IPAM) IPv6Enabled() bool {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	return i.ip6 != nil
+}
+
+// NDProxyInterface returns the name of the host interface that should proxy
+// neighbor discovery for IPv6 addresses behind VPP, empty if not configured.
+func (i *IPAM) NDProxyInterface() string {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return ""
+	}
+	return i.ip6.ndProxyInterface
+}
+
+// PodSubnetIPv6 returns IPv6 POD subnet that is a base subnet for all PODs of all nodes.
+func (i *IPAM) PodSubnetIPv6() *net.IPNet {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return nil
+	}
+	podSubnet := newIPv6Net(i.ip6.podSubnetIPPrefix) // defensive copy
+	return &podSubnet
+}
+
+// PodNetworkIPv6 returns IPv6 POD network for the current node.
+func (i *IPAM) PodNetworkIPv6() *net.IPNet {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return nil
+	}
+	podNetwork := newIPv6Net(i.ip6.podNetworkIPPrefix) // defensive copy
+	return &podNetwork
+}
+
+// OtherNodePodNetworkIPv6 returns the IPv6 POD network of another node identified by nodeID.
+func (i *IPAM) OtherNodePodNetworkIPv6(nodeID uint8) (*net.IPNet, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return nil, errIPv6Disabled
+	}
+
+	networkSize, _ := i.ip6.podNetworkIPPrefix.Mask.Size()
+	podNetwork, err := applyNodeIDv6(i.ip6.podSubnetIPPrefix, nodeID, uint8(networkSize))
+	if err != nil {
+		return nil, err
+	}
+	return &podNetwork, nil
+}
+
+// PodGatewayIPv6 returns IPv6 gateway address of the POD network of this node.
+func (i *IPAM) PodGatewayIPv6() net.IP {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return nil
+	}
+	return newIPv6(i.ip6.podNetworkGatewayIP) // defensive copy
+}
+
+// VPPHostNetworkIPv6 returns IPv6 vswitch network used to connect VPP to its host Linux Stack.
+func (i *IPAM) VPPHostNetworkIPv6() *net.IPNet {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return nil
+	}
+	vSwitchNetwork := newIPv6Net(i.ip6.vppHostNetworkIPPrefix) // defensive copy
+	return &vSwitchNetwork
+}
+
+// OtherNodeVPPHostNetworkIPv6 returns IPv6 VPP-host network of another node identified by nodeID.
+func (i *IPAM) OtherNodeVPPHostNetworkIPv6(nodeID uint8) (*net.IPNet, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return nil, errIPv6Disabled
+	}
+
+	networkSize, _ := i.ip6.vppHostNetworkIPPrefix.Mask.Size()
+	vSwitchNetwork, err := applyNodeIDv6(i.ip6.vppHostSubnetIPPrefix, nodeID, uint8(networkSize))
+	if err != nil {
+		return nil, err
+	}
+	return &vSwitchNetwork, nil
+}
+
+// VEthVPPEndIPv6 provides the IPv6 address of the VPP-end of the VPP to host interconnect.
+func (i *IPAM) VEthVPPEndIPv6() net.IP {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return nil
+	}
+	return newIPv6(i.ip6.vethVPPEndIP) // defensive copy
+}
+
+// VEthHostEndIPv6 provides the IPv6 address of the host-end of the VPP to host interconnect.
+func (i *IPAM) VEthHostEndIPv6() net.IP {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return nil
+	}
+	return newIPv6(i.ip6.vethHostEndIP) // defensive copy
+}
+
+// VxlanIPv6Address computes IPv6 address of the VXLAN interface based on the provided node ID.
+func (i *IPAM) VxlanIPv6Address(nodeID uint8) (net.IP, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return nil, errIPv6Disabled
+	}
+	return computeIPv6Address(i.ip6.vxlanCIDR, nodeID), nil
+}
+
+// VxlanIPv6WithPrefix computes IPv6 address of the VXLAN interface with prefix length based on the provided node ID.
+func (i *IPAM) VxlanIPv6WithPrefix(nodeID uint8) (*net.IPNet, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	if i.ip6 == nil {
+		return nil, errIPv6Disabled
+	}
+	return &net.IPNet{
+		IP:   computeIPv6Address(i.ip6.vxlanCIDR, nodeID),
+		Mask: newIPv6Mask(i.ip6.vxlanCIDR.Mask),
+	}, nil
+}
+
+// NextPodIPv6 returns next available POD IPv6 address and remembers that this IP is meant to be used for the POD with the id <podID>.
+func (i *IPAM) NextPodIPv6(podID string) (net.IP, error) {
+	var snapshot *allocationsSnapshot
+	defer func() { i.saveAllocations(snapshot) }()
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.ip6 == nil {
+		return nil, errIPv6Disabled
+	}
+	if len(podID) == 0 {
+		return nil, fmt.Errorf("Pod ID can't be empty because it is used to release the assigned IP address")
+	}
+
+	prefixBits, totalBits := i.ip6.podNetworkIPPrefix.Mask.Size()
+	seqIDBits := totalBits - prefixBits
+	if seqIDBits > maxIPv6SeqIDBits {
+		seqIDBits = maxIPv6SeqIDBits
+	}
+	maxSeqID := uint64(1) << uint(seqIDBits)
+
+	// start from the last assigned and take first available IP, zero ending IP is reserved for network
+	for j := uint64(1); j < maxSeqID; j++ {
+		seqID := (i.ip6.lastAssigned + j) % maxSeqID
+		if seqID == 0 || seqID == podGatewaySeqID {
+			continue
+		}
+		ip := addToIPv6(i.ip6.podNetworkIPPrefix.IP, seqID)
+		if _, found := i.ip6.assignedPodIPs[ip.String()]; found {
+			continue
+		}
+		i.ip6.assignedPodIPs[ip.String()] = podID
+		i.ip6.lastAssigned = seqID
+		i.logger.Infof("Assigned new pod IPv6 %s", ip)
+		snapshot = i.snapshotAllocations()
+		return ip, nil
+	}
+
+	return nil, fmt.Errorf("No IPv6 address is free for assignment. All IP addresses for pod network %v are already assigned",
+		i.ip6.podNetworkIPPrefix.String())
+}
+
+// releasePodIPv6 releases the pod IPv6 address remembered for POD id string (if any).
+// The method must be called with acquired mutex.
+func (i *IPAM) releasePodIPv6(podID string) {
+	if i.ip6 == nil {
+		return
+	}
+	for ip, curPodID := range i.ip6.assignedPodIPs {
+		if curPodID == podID {
+			delete(i.ip6.assignedPodIPs, ip)
+			i.logger.Infof("Released IPv6 %v for pod ID %v", ip, podID)
+		}
+	}
+}
+
+// initializeIPv6IPAM initializes IPv6 -related variables of IPAM. IPv6 stays disabled if not configured.
+func initializeIPv6IPAM(ipam *IPAM, config *IPv6Config, nodeID uint8) (err error) {
+	if config == nil {
+		return nil
+	}
+	ip6 := &ipv6IPAM{
+		assignedPodIPs:   make(map[string]podID),
+		lastAssigned:     podGatewaySeqID,
+		ndProxyInterface: config.NDProxyInterface,
+	}
+
+	ip6.podSubnetIPPrefix, ip6.podNetworkIPPrefix, err = convertIPv6ConfigNotation(config.PodSubnetCIDR, config.PodNetworkPrefixLen, nodeID)
+	if err != nil {
+		return err
+	}
+	ip6.podNetworkGatewayIP = addToIPv6(ip6.podNetworkIPPrefix.IP, podGatewaySeqID)
+
+	ip6.vppHostSubnetIPPrefix, ip6.vppHostNetworkIPPrefix, err = convertIPv6ConfigNotation(config.VPPHostSubnetCIDR, config.VPPHostNetworkPrefixLen, nodeID)
+	if err != nil {
+		return err
+	}
+	ip6.vethVPPEndIP = addToIPv6(ip6.vppHostNetworkIPPrefix.IP, vethVPPEndIPSeqID)
+	ip6.vethHostEndIP = addToIPv6(ip6.vppHostNetworkIPPrefix.IP, vethHostEndIPSeqID)
+
+	_, vxlanSubnet, err := parseIPv6CIDR(config.VxlanCIDR)
+	if err != nil {
+		return err
+	}
+	ip6.vxlanCIDR = *vxlanSubnet
+
+	ipam.ip6 = ip6
+	return nil
+}
+
+// convertIPv6ConfigNotation converts IPv6 config notation and given node ID to IPAM structure notation.
+// I.e: input fd00::/48 (string), /64 (uint8), 5 (uint8) results in fd00::/48 (IPNet), fd00:0:0:5::/64 (IPNet)
+func convertIPv6ConfigNotation(subnetCIDR string, networkPrefixLen uint8, nodeID uint8) (subnetIPPrefix net.IPNet, networkIPPrefix net.IPNet, err error) {
+	_, pSubnet, err := parseIPv6CIDR(subnetCIDR)
+	if err != nil {
+		return
+	}
+	subnetIPPrefix = *pSubnet
+
+	subnetPrefixLen, _ := subnetIPPrefix.Mask.Size()
+	if networkPrefixLen <= uint8(subnetPrefixLen) || networkPrefixLen > 128 {
+		err = fmt.Errorf("Network prefix length (%v) must be higher than subnet prefix length (%v) ", networkPrefixLen, subnetPrefixLen)
+		return
+	}
+
+	networkIPPrefix, err = applyNodeIDv6(subnetIPPrefix, nodeID, networkPrefixLen)
+	return
+}
+
+// parseIPv6CIDR parses the CIDR and checks that it is an IPv6 subnet.
+func parseIPv6CIDR(cidr string) (net.IP, *net.IPNet, error) {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Can't parse IPv6 CIDR \"%v\" : %v", cidr, err)
+	}
+	if ip.To4() != nil {
+		return nil, nil, fmt.Errorf("CIDR \"%v\" is not an IPv6 subnet", cidr)
+	}
+	return ip, ipNet, nil
+}
+
+// applyNodeIDv6 creates IPv6 network (IPNet) from subnet by adding transformed node ID to it.
+func applyNodeIDv6(subnetIPPrefix net.IPNet, nodeID uint8, networkPrefixLen uint8) (net.IPNet, error) {
+	subnetPrefixLen, _ := subnetIPPrefix.Mask.Size()
+	nodePartBitSize := networkPrefixLen - uint8(subnetPrefixLen)
+	if nodePartBitSize > 8 {
+		nodePartBitSize = 8
+	}
+	if err := checkNodeIDFits(subnetIPPrefix, nodeID, networkPrefixLen, nodePartBitSize); err != nil {
+		return net.IPNet{}, err
+	}
+	nodeIPPart := convertToNodeIPPart(nodeID, nodePartBitSize)
+
+	network := new(big.Int).SetBytes(subnetIPPrefix.IP.To16())
+	network.Add(network, new(big.Int).Lsh(big.NewInt(int64(nodeIPPart)), uint(128-networkPrefixLen)))
+	return net.IPNet{
+		IP:   bigIntToIPv6(network),
+		Mask: net.CIDRMask(int(networkPrefixLen), 128),
+	}, nil
+}
+
+// computeIPv6Address computes IPv6 address from the subnet and the node ID.
+func computeIPv6Address(subnet net.IPNet, nodeID uint8) net.IP {
+	subnetPrefixLen, _ := subnet.Mask.Size()
+	nodePartBitSize := 128 - subnetPrefixLen
+	if nodePartBitSize > 8 {
+		nodePartBitSize = 8
+	}
+	return addToIPv6(subnet.IP, uint64(convertToNodeIPPart(nodeID, uint8(nodePartBitSize))))
+}
+
+// addToIPv6 returns the IPv6 address increased by the given number.
+func addToIPv6(ip net.IP, n uint64) net.IP {
+	res := new(big.Int).SetBytes(ip.To16())
+	res.Add(res, new(big.Int).SetUint64(n))
+	return bigIntToIPv6(res)
+}
+
+// bigIntToIPv6 is simple utility function for conversion between big.Int and IPv6.
+func bigIntToIPv6(i *big.Int) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	b := i.Bytes()
+	copy(ip[net.IPv6len-len(b):], b)
+	return ip
+}
+
+// newIPv6Net is simple utility function to create defend copy of IPv6 net.IPNet.
+func newIPv6Net(ipNet net.IPNet) net.IPNet {
+	return net.IPNet{
+		IP:   newIPv6(ipNet.IP),
+		Mask: newIPv6Mask(ipNet.Mask),
+	}
+}
+
+// newIPv6 is simple utility function to create defend copy of IPv6 net.IP.
+func newIPv6(ip net.IP) net.IP {
+	res := make(net.IP, net.IPv6len)
+	copy(res, ip.To16())
+	return res
+}
+
+// newIPv6Mask is simple utility function to create defend copy of IPv6 net.IPMask.
+func newIPv6Mask(mask net.IPMask) net.IPMask {
+	res := make(net.IPMask, len(mask))
+	copy(res, mask)
+	return res
+}
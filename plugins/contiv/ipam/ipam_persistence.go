@@ -0,0 +1,173 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	ipammodel "github.com/contiv/vpp/plugins/contiv/model/ipam"
+)
+
+// AllocationStore persists the IP addresses assigned to the PODs of the node, so that they survive
+// the restart of the agent.
+type AllocationStore interface {
+	// LoadAllocations returns the persisted allocations (nil if nothing was persisted yet).
+	LoadAllocations() (*ipammodel.PodIPAllocations, error)
+
+	// SaveAllocations persists the allocations, the previously persisted ones are overwritten.
+	SaveAllocations(allocations *ipammodel.PodIPAllocations) error
+}
+
+// SetAllocationStore restores the POD IP addresses assigned before the restart of the agent from the store
+// and persists all subsequent changes into it, together with the IP addresses reserved for the released PODs.
+// Restored IP addresses that no longer belong to the POD network of the node are dropped, as well as
+// the expired reservations.
+func (i *IPAM) SetAllocationStore(store AllocationStore) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	allocations, err := store.LoadAllocations()
+	if err != nil {
+		return err
+	}
+	if allocations != nil {
+		i.restoreAllocations(allocations)
+	}
+	i.store = store
+	return nil
+}
+
+// restoreAllocations fills the pools of assigned IP addresses from the persisted allocations.
+func (i *IPAM) restoreAllocations(allocations *ipammodel.PodIPAllocations) {
+	for _, allocation := range allocations.Allocation {
+		ip := net.ParseIP(allocation.IpAddress)
+		network, lastAssigned, networkName, found := i.podNetworkOf(ip)
+		if ip == nil || ip.To4() == nil || !found {
+			i.logger.Warnf("Dropping IP %v of pod ID %v, it is not from any network of this node (%v)",
+				allocation.IpAddress, allocation.PodId, i.podNetworksString())
+			continue
+		}
+		networkPrefix, _ := ipv4ToUint32(network.IP)
+		restored, _ := ipv4ToUint32(ip)
+		i.assignedPodIPs[restored] = allocation.PodId
+		if index := int(restored - networkPrefix); index > *lastAssigned {
+			*lastAssigned = index
+		}
+		if allocation.PodKey != "" {
+			i.podKeys[allocation.PodId] = allocation.PodKey
+		}
+
+		if allocation.Ipv6Address != "" && i.ip6 != nil {
+			ipv6 := net.ParseIP(allocation.Ipv6Address)
+			if ipv6 != nil && i.ip6.podNetworkIPPrefix.Contains(ipv6) {
+				i.ip6.assignedPodIPs[ipv6.String()] = allocation.PodId
+			}
+		}
+		i.logger.Infof("Restored IP %v of the %v for pod ID %v", ip, networkName, allocation.PodId)
+	}
+
+	now := time.Now()
+	for _, reservation := range allocations.Reservation {
+		ip := net.ParseIP(reservation.IpAddress)
+		_, _, _, found := i.podNetworkOf(ip)
+		if ip == nil || ip.To4() == nil || !found {
+			i.logger.Warnf("Dropping IP %v reserved for pod %v, it is not from any network of this node (%v)",
+				reservation.IpAddress, reservation.PodKey, i.podNetworksString())
+			continue
+		}
+		expires := time.Unix(0, reservation.Expires)
+		if i.podIPReservationTTL == 0 || !now.Before(expires) {
+			continue
+		}
+		reserved, _ := ipv4ToUint32(ip)
+		if _, assigned := i.assignedPodIPs[reserved]; assigned {
+			continue
+		}
+		i.reservedPodIPs[reserved] = podIPReservation{podKey: reservation.PodKey, expires: expires}
+		i.logger.Infof("Restored IP %v reserved for pod %v until %v", ip, reservation.PodKey, expires)
+	}
+}
+
+// allocationsSnapshot is a copy of the assigned IP addresses taken to be saved into the store.
+type allocationsSnapshot struct {
+	revision    uint64
+	allocations *ipammodel.PodIPAllocations
+}
+
+// snapshotAllocations returns the copy of all assigned and reserved IP addresses to be saved into the store
+// by saveAllocations (nil if the store is not set). The method must be called with acquired mutex.
+func (i *IPAM) snapshotAllocations() *allocationsSnapshot {
+	if i.store == nil {
+		return nil
+	}
+
+	ipv6Addrs := make(map[podID]string)
+	if i.ip6 != nil {
+		for ip, podID := range i.ip6.assignedPodIPs {
+			ipv6Addrs[podID] = ip
+		}
+	}
+	allocations := &ipammodel.PodIPAllocations{}
+	for ip, podID := range i.assignedPodIPs {
+		allocations.Allocation = append(allocations.Allocation, &ipammodel.PodIPAllocations_Allocation{
+			PodId:       podID,
+			IpAddress:   uint32ToIpv4(ip).String(),
+			Ipv6Address: ipv6Addrs[podID],
+			PodKey:      i.podKeys[podID],
+		})
+	}
+	sort.Slice(allocations.Allocation, func(a, b int) bool {
+		return allocations.Allocation[a].PodId < allocations.Allocation[b].PodId
+	})
+	now := time.Now()
+	for ip, reservation := range i.reservedPodIPs {
+		if !now.Before(reservation.expires) {
+			continue
+		}
+		allocations.Reservation = append(allocations.Reservation, &ipammodel.PodIPAllocations_Reservation{
+			PodKey:    reservation.podKey,
+			IpAddress: uint32ToIpv4(ip).String(),
+			Expires:   reservation.expires.UnixNano(),
+		})
+	}
+	sort.Slice(allocations.Reservation, func(a, b int) bool {
+		return allocations.Reservation[a].IpAddress < allocations.Reservation[b].IpAddress
+	})
+	i.revision++
+	return &allocationsSnapshot{revision: i.revision, allocations: allocations}
+}
+
+// saveAllocations saves the snapshot of the assigned IP addresses into the store. The method must be called
+// without the mutex (deferred before the mutex is acquired), so that the IPAM is not blocked by the store.
+// Snapshots older than the last saved one are skipped. Since all the allocations are saved every time,
+// failure is only logged, the next successful save persists the current state.
+func (i *IPAM) saveAllocations(snapshot *allocationsSnapshot) {
+	if snapshot == nil {
+		return
+	}
+	i.storeMutex.Lock()
+	defer i.storeMutex.Unlock()
+
+	if snapshot.revision <= i.savedRevision {
+		return
+	}
+	if err := i.store.SaveAllocations(snapshot.allocations); err != nil {
+		i.logger.Errorf("Failed to persist allocated pod IPs: %v", err)
+		return
+	}
+	i.savedRevision = snapshot.revision
+}
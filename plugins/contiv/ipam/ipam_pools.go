@@ -0,0 +1,256 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+	"net"
+)
+
+// NamespacePoolConfig defines an additional POD IP pool used by the PODs of the given namespaces.
+// The pool subnet is split into per-node networks in the same way as PodSubnetCIDR.
+// A pool with no namespaces is used only for the secondary interfaces of PODs.
+type NamespacePoolConfig struct {
+	Name             string   // name of the pool
+	Namespaces       []string // namespaces whose PODs get IP addresses from the pool
+	SubnetCIDR       string   // subnet of the pool across all nodes, must not overlap with other subnets
+	NetworkPrefixLen uint8    // prefix length of the pool network of each node
+}
+
+// namespacePool is the runtime state of a namespace pool on this node.
+type namespacePool struct {
+	name            string
+	namespaces      []string
+	subnetIPPrefix  net.IPNet // pool subnet across all nodes
+	networkIPPrefix net.IPNet // pool network of this node (given by nodeID)
+	lastAssigned    int       // counter denoting last assigned IP address of the pool network
+}
+
+// NamespacePool returns the name of the pool the PODs of the given namespace get IP addresses from.
+// Empty string is returned if the namespace is not bound to any pool.
+func (i *IPAM) NamespacePool(namespace string) string {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	for _, pool := range i.namespacePools {
+		for _, poolNamespace := range pool.namespaces {
+			if poolNamespace == namespace {
+				return pool.name
+			}
+		}
+	}
+	return ""
+}
+
+// PoolUtilization describes how many IP addresses of a POD network of this node are assigned.
+type PoolUtilization struct {
+	Pool     string `json:"pool,omitempty"` // name of the namespace pool, empty for the POD network
+	Assigned int    `json:"assigned"`       // number of assigned IP addresses
+	Capacity int    `json:"capacity"`       // number of IP addresses that can be assigned
+}
+
+// PoolsUtilization returns the utilization of the POD network of this node, followed by the utilization
+// of the networks of the namespace pools in the order of the configuration.
+func (i *IPAM) PoolsUtilization() []PoolUtilization {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	utilization := []PoolUtilization{i.networkUtilization("", i.podNetworkIPPrefix)}
+	for _, pool := range i.namespacePools {
+		utilization = append(utilization, i.networkUtilization(pool.name, pool.networkIPPrefix))
+	}
+	return utilization
+}
+
+// networkUtilization computes the utilization of the given network. The method must be called with acquired mutex.
+func (i *IPAM) networkUtilization(pool string, network net.IPNet) PoolUtilization {
+	prefixBits, totalBits := network.Mask.Size()
+	utilization := PoolUtilization{
+		Pool: pool,
+		// zero ending IP address and the gateway IP address can't be assigned
+		Capacity: 1<<uint(totalBits-prefixBits) - 2,
+	}
+	for ip := range i.assignedPodIPs {
+		if network.Contains(uint32ToIpv4(ip)) {
+			utilization.Assigned++
+		}
+	}
+	return utilization
+}
+
+// NextPodIPFromPool returns next available IP address from the pool network of this node and remembers
+// that this IP is meant to be used for the POD with the id <podID>. If the POD key (namespace/name) is not empty,
+// the IP address reserved for the key is assigned again, as with NextPodIPForKey.
+func (i *IPAM) NextPodIPFromPool(podID string, podKey string, poolName string) (net.IP, error) {
+	var snapshot *allocationsSnapshot
+	defer func() { i.saveAllocations(snapshot) }()
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if len(podID) == 0 {
+		return nil, fmt.Errorf("Pod ID can't be empty because it is used to release the assigned IP address")
+	}
+	pool := i.poolByName(poolName)
+	if pool == nil {
+		return nil, fmt.Errorf("IP pool %v is not defined", poolName)
+	}
+	ip, err := i.nextIPFromNetwork(pool.networkIPPrefix, &pool.lastAssigned, podID, podKey)
+	if err != nil {
+		return nil, fmt.Errorf("IP pool %v: %v", poolName, err)
+	}
+	if podKey != "" {
+		i.podKeys[podID] = podKey
+	}
+	snapshot = i.snapshotAllocations()
+	return ip, nil
+}
+
+// NamespacePoolSubnets returns subnets of all namespace pools.
+func (i *IPAM) NamespacePoolSubnets() []*net.IPNet {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	var subnets []*net.IPNet
+	for _, pool := range i.namespacePools {
+		subnet := newIPNet(pool.subnetIPPrefix) // defensive copy
+		subnets = append(subnets, &subnet)
+	}
+	return subnets
+}
+
+// OtherNodeNamespacePoolNetworks returns the pool networks of all namespace pools of the node with the given ID.
+func (i *IPAM) OtherNodeNamespacePoolNetworks(nodeID uint8) ([]*net.IPNet, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	var networks []*net.IPNet
+	for _, pool := range i.namespacePools {
+		networkSize, _ := pool.networkIPPrefix.Mask.Size()
+		networkIPPrefix, err := applyNodeID(pool.subnetIPPrefix, nodeID, uint8(networkSize))
+		if err != nil {
+			return nil, err
+		}
+		network := newIPNet(networkIPPrefix) // defensive copy
+		networks = append(networks, &network)
+	}
+	return networks, nil
+}
+
+// PoolGatewayIP returns the gateway IP address of the pool network of this node.
+func (i *IPAM) PoolGatewayIP(poolName string) (net.IP, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	pool := i.poolByName(poolName)
+	if pool == nil {
+		return nil, fmt.Errorf("IP pool %v is not defined", poolName)
+	}
+	networkPrefix, err := ipv4ToUint32(pool.networkIPPrefix.IP)
+	if err != nil {
+		return nil, err
+	}
+	return uint32ToIpv4(networkPrefix + podGatewaySeqID), nil
+}
+
+// PoolSubnet returns the subnet of the pool across all nodes.
+func (i *IPAM) PoolSubnet(poolName string) (*net.IPNet, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	pool := i.poolByName(poolName)
+	if pool == nil {
+		return nil, fmt.Errorf("IP pool %v is not defined", poolName)
+	}
+	subnet := newIPNet(pool.subnetIPPrefix) // defensive copy
+	return &subnet, nil
+}
+
+// poolByName returns the pool with the given name, or nil if not defined.
+// The method must be called with acquired mutex.
+func (i *IPAM) poolByName(poolName string) *namespacePool {
+	for _, pool := range i.namespacePools {
+		if pool.name == poolName {
+			return pool
+		}
+	}
+	return nil
+}
+
+// podNetworkOf returns the network (POD network or the pool network of this node) the IP address belongs to,
+// together with the counter of the last assigned IP address of the network and the name of the network
+// used in the error messages ("pod network" or "IP pool <name>").
+// The method must be called with acquired mutex.
+func (i *IPAM) podNetworkOf(ip net.IP) (network net.IPNet, lastAssigned *int, name string, found bool) {
+	if i.podNetworkIPPrefix.Contains(ip) {
+		return i.podNetworkIPPrefix, &i.lastAssigned, "pod network", true
+	}
+	for _, pool := range i.namespacePools {
+		if pool.networkIPPrefix.Contains(ip) {
+			return pool.networkIPPrefix, &pool.lastAssigned, "IP pool " + pool.name, true
+		}
+	}
+	return net.IPNet{}, nil, "", false
+}
+
+// podNetworksString returns the POD network and the pool networks of this node for the error messages.
+// The method must be called with acquired mutex.
+func (i *IPAM) podNetworksString() string {
+	networks := "pod network " + i.podNetworkIPPrefix.String()
+	for _, pool := range i.namespacePools {
+		networks += fmt.Sprintf(", IP pool %v %v", pool.name, pool.networkIPPrefix.String())
+	}
+	return networks
+}
+
+// initializeNamespacePools initializes the namespace pools of IPAM.
+func initializeNamespacePools(ipam *IPAM, configs []NamespacePoolConfig, nodeID uint8) error {
+	subnets := []net.IPNet{ipam.podSubnetIPPrefix, ipam.vppHostSubnetIPPrefix, ipam.serviceCIDR, ipam.vxlanCIDR}
+	namespaces := map[string]string{}
+	for _, config := range configs {
+		if config.Name == "" {
+			return fmt.Errorf("Name of the IP pool with subnet %v is not set", config.SubnetCIDR)
+		}
+		for _, pool := range ipam.namespacePools {
+			if pool.name == config.Name {
+				return fmt.Errorf("Duplicate IP pool %v", config.Name)
+			}
+		}
+		for _, namespace := range config.Namespaces {
+			if pool, duplicate := namespaces[namespace]; duplicate {
+				return fmt.Errorf("Namespace %v is bound to both IP pools %v and %v", namespace, pool, config.Name)
+			}
+			namespaces[namespace] = config.Name
+		}
+
+		pool := &namespacePool{
+			name:         config.Name,
+			namespaces:   config.Namespaces,
+			lastAssigned: podGatewaySeqID,
+		}
+		var err error
+		pool.subnetIPPrefix, pool.networkIPPrefix, err = convertConfigNotation(config.SubnetCIDR, config.NetworkPrefixLen, nodeID)
+		if err != nil {
+			return fmt.Errorf("Invalid IP pool %v: %v", config.Name, err)
+		}
+		for _, subnet := range subnets {
+			if subnet.Contains(pool.subnetIPPrefix.IP) || pool.subnetIPPrefix.Contains(subnet.IP) {
+				return fmt.Errorf("Subnet %v of the IP pool %v overlaps with %v", config.SubnetCIDR, config.Name, subnet.String())
+			}
+		}
+		subnets = append(subnets, pool.subnetIPPrefix)
+		ipam.namespacePools = append(ipam.namespacePools, pool)
+	}
+	return nil
+}
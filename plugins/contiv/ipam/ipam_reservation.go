@@ -0,0 +1,89 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"net"
+	"time"
+)
+
+// podIPReservation keeps the IP address of a released POD for the next POD with the same key.
+type podIPReservation struct {
+	podKey  string
+	expires time.Time
+}
+
+// NextPodIPForKey works as NextPodIP, but the POD is identified also by its key (namespace/name), which is stable
+// across POD restarts. If an IP address is reserved for the key, it is assigned again. Once released, the IP address
+// stays reserved for the key for PodIPReservationTTL seconds.
+func (i *IPAM) NextPodIPForKey(podID string, podKey string) (net.IP, error) {
+	var snapshot *allocationsSnapshot
+	defer func() { i.saveAllocations(snapshot) }()
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	ip, err := i.nextPodIP(podID, podKey)
+	if err != nil {
+		return nil, err
+	}
+	if podKey != "" {
+		i.podKeys[podID] = podKey
+	}
+	snapshot = i.snapshotAllocations()
+	return ip, nil
+}
+
+// reservedPodIP returns the IP address reserved for the given POD key (if any).
+// The method must be called with acquired mutex.
+func (i *IPAM) reservedPodIP(podKey string) (ip uintIP, found bool) {
+	for ip, reservation := range i.reservedPodIPs {
+		if reservation.podKey == podKey && time.Now().Before(reservation.expires) {
+			return ip, true
+		}
+	}
+	return 0, false
+}
+
+// isReservedForOther returns true if the IP address is reserved for other POD key than the given one.
+// Expired reservations are removed. The method must be called with acquired mutex.
+func (i *IPAM) isReservedForOther(ip uintIP, podKey string) bool {
+	reservation, found := i.reservedPodIPs[ip]
+	if !found {
+		return false
+	}
+	if !time.Now().Before(reservation.expires) {
+		delete(i.reservedPodIPs, ip)
+		return false
+	}
+	return reservation.podKey != podKey
+}
+
+// reservePodIP reserves the IP address of the released POD for its key (if known and reservations are enabled).
+// The method must be called with acquired mutex.
+func (i *IPAM) reservePodIP(podID string, ip uintIP) {
+	podKey, found := i.podKeys[podID]
+	if !found {
+		return
+	}
+	delete(i.podKeys, podID)
+	if i.podIPReservationTTL == 0 {
+		return
+	}
+	i.reservedPodIPs[ip] = podIPReservation{
+		podKey:  podKey,
+		expires: time.Now().Add(i.podIPReservationTTL),
+	}
+	i.logger.Infof("IP %v reserved for pod %v for %v", uint32ToIpv4(ip), podKey, i.podIPReservationTTL)
+}
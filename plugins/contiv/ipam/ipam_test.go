@@ -10,8 +10,10 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"time"
 
 	"github.com/contiv/vpp/plugins/contiv/ipam"
+	ipammodel "github.com/contiv/vpp/plugins/contiv/model/ipam"
 )
 
 //TODO maybe check multiple hosts IPAMs for no interconnection between them and that hostID is not hardwired into them somehow
@@ -99,6 +101,19 @@ func TestDynamicGetters(t *testing.T) {
 	Expect(*ipNet).To(BeEquivalentTo(network("2.3." + str(b11000000+int(hostID2>>6)) + "." + str(int(hostID2<<2)) + "/30")))
 }
 
+// TestSetServiceNetwork tests the change of the range allocated for services
+func TestSetServiceNetwork(t *testing.T) {
+	i := setup(t, newDefaultConfig())
+	Expect(i.ServiceNetwork().String()).To(Equal("10.96.0.0/12"))
+
+	Expect(i.SetServiceNetwork("10.100.0.0/16")).To(Succeed())
+	Expect(i.ServiceNetwork().String()).To(Equal("10.100.0.0/16"))
+	Expect(i.SetServiceNetwork("10.100.0.0/33")).NotTo(Succeed())
+	Expect(i.ServiceNetwork().String()).To(Equal("10.100.0.0/16"))
+	Expect(i.SetServiceNetwork("")).To(Succeed())
+	Expect(i.ServiceNetwork().String()).To(Equal("10.96.0.0/12"))
+}
+
 // TestBasicAllocateReleasePodAddress test simple happy path scenario for getting 1 pod address and releasing it
 func TestBasicAllocateReleasePodAddress(t *testing.T) {
 	i := setup(t, newDefaultConfig())
@@ -224,15 +239,66 @@ func TestBiggerThan8bitPodIPPoolSize(t *testing.T) {
 	assertCorrectIPExhaustion(i, maxIPCount)
 }
 
-// TestHostIDtrimming test IPAM ability to properly create IP address part corresponding to host ID. More precise the
-// case when 8-bit hostID doesn't fit into less-then-8bit IP Part.
-func TestHostIDtrimming(t *testing.T) {
+// TestNodeIDNotFittingPodSubnet tests that IPAM refuses node ID whose pod network doesn't fit into the pod subnet,
+// i.e the case when 8-bit hostID doesn't fit into less-then-8bit IP Part.
+func TestNodeIDNotFittingPodSubnet(t *testing.T) {
+	RegisterTestingT(t)
+
 	customConfig := newDefaultConfig()
 	customConfig.PodSubnetCIDR = "1.2.3.4/19"
 	customConfig.PodNetworkPrefixLen = 24
-	i := setup(t, customConfig)
+	_, err := ipam.New(logrus.DefaultLogger(), uint8(hostID1), customConfig)
+	Expect(err).NotTo(BeNil(), "Node ID doesn't fit into the pod subnet, but IPAM initialization didn't fail")
+
+	// the highest node ID that still fits
+	i, err := ipam.New(logrus.DefaultLogger(), 1<<5-1, customConfig)
+	Expect(err).To(BeNil())
+	Expect(*i.PodNetwork()).To(BeEquivalentTo(network("1.2.31.0/24")))
+	_, err = i.OtherNodePodNetwork(1 << 5)
+	Expect(err).NotTo(BeNil())
+}
+
+// TestAssignedPodNetwork tests that POD network assigned to the node is used instead of the computed one
+func TestAssignedPodNetwork(t *testing.T) {
+	RegisterTestingT(t)
+
+	customConfig := newDefaultConfig()
+	customConfig.PodSubnetCIDR = "10.1.0.0/16"
+	customConfig.NodePodNetworkCIDR = "10.1.42.0/25"
+	i, err := ipam.New(logrus.DefaultLogger(), uint8(hostID1), customConfig)
+	Expect(err).To(BeNil())
+	Expect(i.PodNetwork().String()).To(BeEquivalentTo("10.1.42.0/25"))
+	Expect(i.PodGatewayIP().String()).To(BeEquivalentTo("10.1.42.1"))
+	ip, err := i.NextPodIP(podID)
+	Expect(err).To(BeNil())
+	Expect(ip.String()).To(BeEquivalentTo("10.1.42.2"))
+
+	// the network must be within the POD subnet
+	customConfig.NodePodNetworkCIDR = "10.2.42.0/24"
+	_, err = ipam.New(logrus.DefaultLogger(), uint8(hostID1), customConfig)
+	Expect(err).NotTo(BeNil())
+
+	customConfig.NodePodNetworkCIDR = "10.1.42./24"
+	_, err = ipam.New(logrus.DefaultLogger(), uint8(hostID1), customConfig)
+	Expect(err).NotTo(BeNil())
+}
+
+// TestPodNetworkPrefixSizes tests pod networks of various sizes within the same pod subnet
+func TestPodNetworkPrefixSizes(t *testing.T) {
+	RegisterTestingT(t)
 
-	Expect(*i.PodNetwork()).To(BeEquivalentTo(network("1.2." + str(int(hostID1&(1<<5-1))) + ".0/24")))
+	for prefixLen, expected := range map[uint8]string{
+		24: "10.1.5.0/24",
+		25: "10.1.2.128/25",
+		26: "10.1.1.64/26",
+	} {
+		customConfig := newDefaultConfig()
+		customConfig.PodSubnetCIDR = "10.1.0.0/16"
+		customConfig.PodNetworkPrefixLen = prefixLen
+		i, err := ipam.New(logrus.DefaultLogger(), 5, customConfig)
+		Expect(err).To(BeNil())
+		Expect(i.PodNetwork().String()).To(BeEquivalentTo(expected))
+	}
 }
 
 // TestConfigWithBadCIDR test if IPAM detects incorrect unparsable CIDR string and handles it correctly (initialization returns error)
@@ -270,6 +336,456 @@ func TestConfigWithBadPrefixSizes(t *testing.T) {
 	customConfig.VPPHostNetworkPrefixLen = 18
 	_, err = ipam.New(logrus.DefaultLogger(), uint8(hostID1), customConfig)
 	Expect(err).NotTo(BeNil())
+
+	customConfig = newDefaultConfig()
+	customConfig.PodNetworkPrefixLen = 31
+	_, err = ipam.New(logrus.DefaultLogger(), uint8(hostID1), customConfig)
+	Expect(err).NotTo(BeNil())
+}
+
+// TestAllocateRequestedPodIP tests assignment of the IP address requested for the POD
+func TestAllocateRequestedPodIP(t *testing.T) {
+	i := setup(t, newDefaultConfig())
+
+	err := i.AllocatePodIP(podID, "", net.ParseIP("1.2.133.12"))
+	Expect(err).To(BeNil())
+
+	// the requested IP address is skipped by the dynamic allocation
+	for _, expected := range []string{"1.2.133.10", "1.2.133.11", "1.2.133.13"} {
+		ip, err := i.NextPodIP(podID + expected)
+		Expect(err).To(BeNil())
+		Expect(ip.String()).To(BeEquivalentTo(expected))
+	}
+
+	// already assigned, gateway or foreign IP address can't be requested
+	Expect(i.AllocatePodIP(podID+"2", "", net.ParseIP("1.2.133.12"))).NotTo(BeNil())
+	Expect(i.AllocatePodIP(podID+"2", "", expectedPodNetworkGatewayIP)).NotTo(BeNil())
+	Expect(i.AllocatePodIP(podID+"2", "", net.ParseIP("1.2.133.20"))).NotTo(BeNil())
+
+	Expect(i.ReleasePodIP(podID)).To(BeNil())
+	Expect(i.AllocatePodIP(podID+"2", "", net.ParseIP("1.2.133.12"))).To(BeNil())
+}
+
+// TestNamespacePools tests assignment of IP addresses from the pools bound to namespaces
+func TestNamespacePools(t *testing.T) {
+	cfg := newDefaultConfig()
+	cfg.NamespacePools = []ipam.NamespacePoolConfig{
+		{Name: "dmz", Namespaces: []string{"dmz", "edge"}, SubnetCIDR: "5.6.0.0/16", NetworkPrefixLen: 24},
+	}
+	i := setup(t, cfg)
+
+	Expect(i.NamespacePool("dmz")).To(BeEquivalentTo("dmz"))
+	Expect(i.NamespacePool("edge")).To(BeEquivalentTo("dmz"))
+	Expect(i.NamespacePool("default")).To(BeEmpty())
+
+	ip, err := i.NextPodIPFromPool(podID, "", "dmz")
+	Expect(err).To(BeNil())
+	Expect(ip.String()).To(BeEquivalentTo("5.6.161.2"))
+	second, err := i.NextPodIPFromPool(podID+"2", "", "dmz")
+	Expect(err).To(BeNil())
+	Expect(second.String()).To(BeEquivalentTo("5.6.161.3"))
+	_, err = i.NextPodIPFromPool(podID+"3", "", "unknown")
+	Expect(err).NotTo(BeNil())
+
+	// IP address from the pool is released as any other POD IP address
+	Expect(i.ReleasePodIP(podID)).To(BeNil())
+
+	Expect(i.NamespacePoolSubnets()).To(HaveLen(1))
+	Expect(i.NamespacePoolSubnets()[0].String()).To(BeEquivalentTo("5.6.0.0/16"))
+	gateway, err := i.PoolGatewayIP("dmz")
+	Expect(err).To(BeNil())
+	Expect(gateway.String()).To(BeEquivalentTo("5.6.161.1"))
+	subnet, err := i.PoolSubnet("dmz")
+	Expect(err).To(BeNil())
+	Expect(subnet.String()).To(BeEquivalentTo("5.6.0.0/16"))
+	_, err = i.PoolGatewayIP("unknown")
+	Expect(err).NotTo(BeNil())
+
+	networks, err := i.OtherNodeNamespacePoolNetworks(5)
+	Expect(err).To(BeNil())
+	Expect(networks).To(HaveLen(1))
+	Expect(networks[0].String()).To(BeEquivalentTo("5.6.5.0/24"))
+}
+
+// TestPoolsUtilization tests the utilization of the POD network and the namespace pools
+func TestPoolsUtilization(t *testing.T) {
+	cfg := newDefaultConfig()
+	cfg.NamespacePools = []ipam.NamespacePoolConfig{
+		{Name: "dmz", Namespaces: []string{"dmz"}, SubnetCIDR: "5.6.0.0/16", NetworkPrefixLen: 24},
+	}
+	i := setup(t, cfg)
+
+	_, err := i.NextPodIP(podID)
+	Expect(err).To(BeNil())
+	_, err = i.NextPodIPFromPool(podID+"2", "", "dmz")
+	Expect(err).To(BeNil())
+	_, err = i.NextPodIPFromPool(podID+"3", "", "dmz")
+	Expect(err).To(BeNil())
+
+	Expect(i.PoolsUtilization()).To(Equal([]ipam.PoolUtilization{
+		{Pool: "", Assigned: 1, Capacity: 6},
+		{Pool: "dmz", Assigned: 2, Capacity: 254},
+	}))
+
+	Expect(i.ReleasePodIP(podID + "2")).To(BeNil())
+	Expect(i.PoolsUtilization()[1].Assigned).To(Equal(1))
+}
+
+// TestPoolWithoutNamespaces tests the IP pool used only for the secondary interfaces of PODs
+func TestPoolWithoutNamespaces(t *testing.T) {
+	cfg := newDefaultConfig()
+	cfg.NamespacePools = []ipam.NamespacePoolConfig{
+		{Name: "net1", SubnetCIDR: "5.7.0.0/16", NetworkPrefixLen: 24},
+	}
+	i := setup(t, cfg)
+
+	Expect(i.NamespacePool("default")).To(BeEmpty())
+	ip, err := i.NextPodIPFromPool(podID+"/net1", "", "net1")
+	Expect(err).To(BeNil())
+	Expect(ip.String()).To(BeEquivalentTo("5.7.161.2"))
+	Expect(i.ReleasePodIP(podID + "/net1")).To(BeNil())
+}
+
+// TestInvalidNamespacePools tests that IPAM refuses inconsistent namespace pools
+func TestInvalidNamespacePools(t *testing.T) {
+	RegisterTestingT(t)
+
+	for _, pools := range [][]ipam.NamespacePoolConfig{
+		{{Name: "dmz", Namespaces: []string{"dmz"}, SubnetCIDR: "1.2.200.0/24", NetworkPrefixLen: 28}},
+		{
+			{Name: "dmz", Namespaces: []string{"dmz"}, SubnetCIDR: "5.6.0.0/16", NetworkPrefixLen: 24},
+			{Name: "dmz", Namespaces: []string{"dmz2"}, SubnetCIDR: "5.7.0.0/16", NetworkPrefixLen: 24},
+		},
+		{{Namespaces: []string{"dmz"}, SubnetCIDR: "5.6.0.0/16", NetworkPrefixLen: 24}},
+		{
+			{Name: "dmz", Namespaces: []string{"dmz"}, SubnetCIDR: "5.6.0.0/16", NetworkPrefixLen: 24},
+			{Name: "dmz2", Namespaces: []string{"dmz"}, SubnetCIDR: "5.7.0.0/16", NetworkPrefixLen: 24},
+		},
+		{
+			{Name: "dmz", Namespaces: []string{"dmz"}, SubnetCIDR: "5.6.0.0/16", NetworkPrefixLen: 24},
+			{Name: "dmz2", Namespaces: []string{"dmz2"}, SubnetCIDR: "5.6.128.0/17", NetworkPrefixLen: 25},
+		},
+		{{Name: "dmz", Namespaces: []string{"dmz"}, SubnetCIDR: "10.96.0.0/16", NetworkPrefixLen: 24}},
+		{{Name: "dmz", Namespaces: []string{"dmz"}, SubnetCIDR: "4.5.0.0/16", NetworkPrefixLen: 24}},
+	} {
+		cfg := newDefaultConfig()
+		cfg.NamespacePools = pools
+		_, err := ipam.New(logger, hostID1, cfg)
+		Expect(err).NotTo(BeNil())
+	}
+}
+
+// memAllocationStore is an in-memory implementation of ipam.AllocationStore
+type memAllocationStore struct {
+	allocations *ipammodel.PodIPAllocations
+}
+
+func (s *memAllocationStore) LoadAllocations() (*ipammodel.PodIPAllocations, error) {
+	return s.allocations, nil
+}
+
+func (s *memAllocationStore) SaveAllocations(allocations *ipammodel.PodIPAllocations) error {
+	s.allocations = allocations
+	return nil
+}
+
+// TestRestoreAllocations tests that the assigned IP addresses are restored from the store after the restart
+func TestRestoreAllocations(t *testing.T) {
+	store := &memAllocationStore{}
+	i := setup(t, newIPv6Config())
+	Expect(i.SetAllocationStore(store)).To(BeNil())
+
+	first, err := i.NextPodIPForKey(podID, "default/web-0")
+	Expect(err).To(BeNil())
+	firstIPv6, err := i.NextPodIPv6(podID)
+	Expect(err).To(BeNil())
+	second, err := i.NextPodIP(podID + "2")
+	Expect(err).To(BeNil())
+	Expect(store.allocations.Allocation).To(HaveLen(2))
+	Expect(store.allocations.Allocation[0].PodKey).To(BeEquivalentTo("default/web-0"))
+	Expect(store.allocations.Allocation[0].Ipv6Address).To(BeEquivalentTo(firstIPv6.String()))
+
+	// IP address from other pod network is dropped
+	store.allocations.Allocation = append(store.allocations.Allocation,
+		&ipammodel.PodIPAllocations_Allocation{PodId: podID + "3", IpAddress: "10.0.0.1"})
+
+	// restart
+	restarted := setup(t, newIPv6Config())
+	Expect(restarted.SetAllocationStore(store)).To(BeNil())
+
+	// restored IP addresses are not assigned again
+	third, err := restarted.NextPodIP(podID + "4")
+	Expect(err).To(BeNil())
+	Expect(third.String()).NotTo(BeEquivalentTo(first.String()))
+	Expect(third.String()).NotTo(BeEquivalentTo(second.String()))
+	thirdIPv6, err := restarted.NextPodIPv6(podID + "4")
+	Expect(err).To(BeNil())
+	Expect(thirdIPv6.String()).NotTo(BeEquivalentTo(firstIPv6.String()))
+	Expect(store.allocations.Allocation).To(HaveLen(3))
+
+	// restored IP address can be released
+	Expect(restarted.ReleasePodIP(podID)).To(BeNil())
+	Expect(restarted.ReleasePodIP(podID + "3")).NotTo(BeNil())
+	Expect(store.allocations.Allocation).To(HaveLen(2))
+}
+
+// callbackAllocationStore is an in-memory ipam.AllocationStore calling the given callback on save
+type callbackAllocationStore struct {
+	memAllocationStore
+	onSave func()
+}
+
+func (s *callbackAllocationStore) SaveAllocations(allocations *ipammodel.PodIPAllocations) error {
+	s.onSave()
+	return s.memAllocationStore.SaveAllocations(allocations)
+}
+
+// TestSaveAllocationsWithoutLock tests that the allocations are saved after the IPAM mutex is released
+func TestSaveAllocationsWithoutLock(t *testing.T) {
+	i := setup(t, newDefaultConfig())
+	saves := 0
+	// the getter would block if the mutex was held during the save
+	store := &callbackAllocationStore{onSave: func() {
+		i.PodGatewayIP()
+		saves++
+	}}
+	Expect(i.SetAllocationStore(store)).To(BeNil())
+
+	_, err := i.NextPodIP(podID)
+	Expect(err).To(BeNil())
+	Expect(i.ReleasePodIP(podID)).To(BeNil())
+	Expect(saves).To(BeEquivalentTo(2))
+	Expect(store.allocations.Allocation).To(BeEmpty())
+}
+
+// TestStickyPodIP tests that the IP address of a released POD is reserved for the POD with the same key
+func TestStickyPodIP(t *testing.T) {
+	cfg := newDefaultConfig()
+	cfg.PodIPReservationTTL = 60
+	i := setup(t, cfg)
+
+	ip, err := i.NextPodIPForKey(podID, "default/web-0")
+	Expect(err).To(BeNil())
+	Expect(ip.String()).To(BeEquivalentTo("1.2.133.10"))
+	Expect(i.ReleasePodIP(podID)).To(BeNil())
+
+	// reserved IP address is not assigned to other PODs even if the range is exhausted
+	for j := 0; ; j++ {
+		other, err := i.NextPodIPForKey(podID+"n"+fmt.Sprint(j), "default/other-"+fmt.Sprint(j))
+		if err != nil {
+			break
+		}
+		Expect(other.String()).NotTo(BeEquivalentTo("1.2.133.10"))
+	}
+
+	// restarted POD gets the same IP address
+	restarted, err := i.NextPodIPForKey(podID+"2", "default/web-0")
+	Expect(err).To(BeNil())
+	Expect(restarted.String()).To(BeEquivalentTo("1.2.133.10"))
+}
+
+// TestStickyPodIPFromPool tests that the IP address of a released POD is reserved for its key in the namespace pool
+func TestStickyPodIPFromPool(t *testing.T) {
+	cfg := newDefaultConfig()
+	cfg.PodIPReservationTTL = 60
+	cfg.NamespacePools = []ipam.NamespacePoolConfig{
+		{Name: "dmz", Namespaces: []string{"dmz"}, SubnetCIDR: "5.6.0.0/16", NetworkPrefixLen: 24},
+	}
+	i := setup(t, cfg)
+
+	ip, err := i.NextPodIPFromPool(podID, "dmz/web-0", "dmz")
+	Expect(err).To(BeNil())
+	Expect(i.ReleasePodIP(podID)).To(BeNil())
+
+	other, err := i.NextPodIPFromPool(podID+"2", "dmz/other", "dmz")
+	Expect(err).To(BeNil())
+	Expect(other.String()).NotTo(BeEquivalentTo(ip.String()))
+
+	// reservation from the pool is not used for the POD network
+	fromPodNetwork, err := i.NextPodIPForKey(podID+"3", "dmz/web-0")
+	Expect(err).To(BeNil())
+	Expect(i.PodNetwork().Contains(fromPodNetwork)).To(BeTrue())
+	Expect(i.ReleasePodIP(podID + "3")).To(BeNil())
+
+	restarted, err := i.NextPodIPFromPool(podID+"4", "dmz/web-0", "dmz")
+	Expect(err).To(BeNil())
+	Expect(restarted.String()).To(BeEquivalentTo(ip.String()))
+}
+
+// TestRestoreReservations tests that the IP addresses reserved for the released PODs survive the restart
+func TestRestoreReservations(t *testing.T) {
+	cfg := newDefaultConfig()
+	cfg.PodIPReservationTTL = 60
+	store := &memAllocationStore{}
+	i := setup(t, cfg)
+	Expect(i.SetAllocationStore(store)).To(BeNil())
+
+	ip, err := i.NextPodIPForKey(podID, "default/web-0")
+	Expect(err).To(BeNil())
+	Expect(i.ReleasePodIP(podID)).To(BeNil())
+	Expect(store.allocations.Allocation).To(BeEmpty())
+	Expect(store.allocations.Reservation).To(HaveLen(1))
+	Expect(store.allocations.Reservation[0].PodKey).To(BeEquivalentTo("default/web-0"))
+	Expect(store.allocations.Reservation[0].IpAddress).To(BeEquivalentTo(ip.String()))
+
+	// expired reservation and reservation from other pod network are dropped
+	store.allocations.Reservation = append(store.allocations.Reservation,
+		&ipammodel.PodIPAllocations_Reservation{PodKey: "default/expired", IpAddress: "1.2.133.11",
+			Expires: time.Now().Add(-time.Second).UnixNano()},
+		&ipammodel.PodIPAllocations_Reservation{PodKey: "default/foreign", IpAddress: "10.0.0.1",
+			Expires: time.Now().Add(time.Minute).UnixNano()})
+
+	// restart
+	restarted := setup(t, cfg)
+	Expect(restarted.SetAllocationStore(store)).To(BeNil())
+
+	// reserved IP address is not assigned to other PODs
+	other, err := restarted.NextPodIPForKey(podID+"2", "default/other")
+	Expect(err).To(BeNil())
+	Expect(other.String()).To(BeEquivalentTo("1.2.133.11"))
+	Expect(store.allocations.Reservation).To(HaveLen(1))
+
+	// restarted POD gets the same IP address
+	again, err := restarted.NextPodIPForKey(podID+"3", "default/web-0")
+	Expect(err).To(BeNil())
+	Expect(again.String()).To(BeEquivalentTo(ip.String()))
+}
+
+// TestRequestedStickyPodIP tests that the IP address reserved for the POD key can be requested only by the POD
+// with the same key
+func TestRequestedStickyPodIP(t *testing.T) {
+	cfg := newDefaultConfig()
+	cfg.PodIPReservationTTL = 60
+	i := setup(t, cfg)
+
+	ip, err := i.NextPodIPForKey(podID, "default/web-0")
+	Expect(err).To(BeNil())
+	Expect(i.ReleasePodIP(podID)).To(BeNil())
+
+	Expect(i.AllocatePodIP(podID+"2", "default/other", ip)).NotTo(BeNil())
+	Expect(i.AllocatePodIP(podID+"2", "", ip)).NotTo(BeNil())
+	Expect(i.AllocatePodIP(podID+"3", "default/web-0", ip)).To(BeNil())
+}
+
+// TestStickyPodIPDisabled tests that no IP address is reserved if the reservation TTL is not configured
+func TestStickyPodIPDisabled(t *testing.T) {
+	i := setup(t, newDefaultConfig())
+
+	_, err := i.NextPodIPForKey(podID, "default/web-0")
+	Expect(err).To(BeNil())
+	Expect(i.ReleasePodIP(podID)).To(BeNil())
+
+	other, err := i.NextPodIPForKey(podID+"2", "default/other")
+	Expect(err).To(BeNil())
+	Expect(other.String()).To(BeEquivalentTo("1.2.133.11"))
+	restarted, err := i.NextPodIPForKey(podID+"3", "default/web-0")
+	Expect(err).To(BeNil())
+	Expect(restarted.String()).To(BeEquivalentTo("1.2.133.12"))
+}
+
+// TestStickyPodIPExpiration tests that the reserved IP address is released once the reservation expires
+func TestStickyPodIPExpiration(t *testing.T) {
+	cfg := newDefaultConfig()
+	cfg.PodIPReservationTTL = 1
+	i := setup(t, cfg)
+
+	_, err := i.NextPodIPForKey(podID, "default/web-0")
+	Expect(err).To(BeNil())
+	Expect(i.ReleasePodIP(podID)).To(BeNil())
+	time.Sleep(1100 * time.Millisecond)
+
+	// previously reserved IP address can be assigned to other PODs
+	reused := false
+	for j := 0; ; j++ {
+		other, err := i.NextPodIPForKey(podID+"n"+fmt.Sprint(j), "default/other-"+fmt.Sprint(j))
+		if err != nil {
+			break
+		}
+		reused = reused || other.String() == "1.2.133.10"
+	}
+	Expect(reused).To(BeTrue())
+}
+
+func newIPv6Config() *ipam.Config {
+	cfg := newDefaultConfig()
+	cfg.IPv6 = &ipam.IPv6Config{
+		PodSubnetCIDR:           "fd00:1::/48",
+		PodNetworkPrefixLen:     64,
+		VPPHostSubnetCIDR:       "fd00:2::/48",
+		VPPHostNetworkPrefixLen: 64,
+		VxlanCIDR:               "fd00:3::/120",
+	}
+	return cfg
+}
+
+// TestIPv6Getters tests IPv6 networks and addresses computed from the IPv6 configuration and node ID
+func TestIPv6Getters(t *testing.T) {
+	i := setup(t, newIPv6Config())
+	Expect(i.IPv6Enabled()).To(BeTrue())
+
+	Expect(*i.PodSubnetIPv6()).To(BeEquivalentTo(network("fd00:1::/48")))
+	Expect(*i.PodNetworkIPv6()).To(BeEquivalentTo(network("fd00:1:0:a1::/64")))
+	Expect(i.PodGatewayIPv6().String()).To(BeEquivalentTo("fd00:1:0:a1::1"))
+
+	Expect(*i.VPPHostNetworkIPv6()).To(BeEquivalentTo(network("fd00:2:0:a1::/64")))
+	Expect(i.VEthVPPEndIPv6().String()).To(BeEquivalentTo("fd00:2:0:a1::1"))
+	Expect(i.VEthHostEndIPv6().String()).To(BeEquivalentTo("fd00:2:0:a1::2"))
+
+	ipNet, err := i.OtherNodePodNetworkIPv6(hostID2)
+	Expect(err).To(BeNil())
+	Expect(*ipNet).To(BeEquivalentTo(network("fd00:1:0:a5::/64")))
+
+	ipNet, err = i.VxlanIPv6WithPrefix(hostID2)
+	Expect(err).To(BeNil())
+	Expect(ipNet.String()).To(BeEquivalentTo("fd00:3::a5/120"))
+}
+
+// TestIPv6AllocateReleasePodAddress tests allocation of POD IPv6 address and its release together with IPv4 address
+func TestIPv6AllocateReleasePodAddress(t *testing.T) {
+	i := setup(t, newIPv6Config())
+	_, err := i.NextPodIP(podID)
+	Expect(err).To(BeNil())
+	ip, err := i.NextPodIPv6(podID)
+	Expect(err).To(BeNil())
+	Expect(ip.String()).To(BeEquivalentTo("fd00:1:0:a1::2"))
+	Expect(i.PodNetworkIPv6().Contains(ip)).To(BeTrue(), "Pod IPv6 address is not from pod network")
+
+	second, err := i.NextPodIPv6(podID + "2")
+	Expect(err).To(BeNil())
+	Expect(second.String()).To(BeEquivalentTo("fd00:1:0:a1::3"))
+
+	Expect(i.ReleasePodIP(podID)).To(BeNil())
+	third, err := i.NextPodIPv6(podID + "3")
+	Expect(err).To(BeNil())
+	Expect(third.String()).To(BeEquivalentTo("fd00:1:0:a1::4"))
+
+	_, err = i.NextPodIPv6(incorrectHostIDForIPAllocation)
+	Expect(err).NotTo(BeNil())
+}
+
+// TestIPv6Disabled tests that IPv6 API fails if IPv6 is not configured
+func TestIPv6Disabled(t *testing.T) {
+	i := setup(t, newDefaultConfig())
+	Expect(i.IPv6Enabled()).To(BeFalse())
+	Expect(i.PodNetworkIPv6()).To(BeNil())
+
+	_, err := i.NextPodIPv6(podID)
+	Expect(err).NotTo(BeNil())
+}
+
+// TestIPv6ConfigWithIPv4CIDR tests that IPv4 subnets are rejected in the IPv6 configuration
+func TestIPv6ConfigWithIPv4CIDR(t *testing.T) {
+	RegisterTestingT(t)
+
+	customConfig := newIPv6Config()
+	customConfig.IPv6.PodSubnetCIDR = "1.2.3.4/19"
+	_, err := ipam.New(logrus.DefaultLogger(), uint8(hostID1), customConfig)
+	Expect(err).NotTo(BeNil())
+
+	customConfig = newIPv6Config()
+	customConfig.IPv6.VPPHostNetworkPrefixLen = 48
+	_, err = ipam.New(logrus.DefaultLogger(), uint8(hostID1), customConfig)
+	Expect(err).NotTo(BeNil())
 }
 
 func exhaustPodIPAddresses(i *ipam.IPAM, maxIPCount int) (allocatedIPs []string, allocatedPodIDS []string) {
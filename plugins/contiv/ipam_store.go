@@ -0,0 +1,59 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"github.com/contiv/vpp/flavors/ksr"
+	ipammodel "github.com/contiv/vpp/plugins/contiv/model/ipam"
+	"github.com/contiv/vpp/plugins/kvstore"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/servicelabel"
+)
+
+const (
+	// allocatedPodIPsKeyPrefix is the prefix of the keys under which the pod IP addresses assigned
+	// by IPAM are stored for each node.
+	allocatedPodIPsKeyPrefix = "allocatedPodIPs/"
+)
+
+// kvIPAMStore persists the pod IP addresses assigned on this node in the key-value data store
+// under allocatedPodIPsKeyPrefix of the KSR microservice.
+type kvIPAMStore struct {
+	broker keyval.ProtoBroker
+	key    string
+}
+
+// newKVIPAMStore creates new instance of kvIPAMStore for the given node.
+func newKVIPAMStore(store kvstore.KvStore, nodeName string) *kvIPAMStore {
+	return &kvIPAMStore{
+		broker: store.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel)),
+		key:    allocatedPodIPsKeyPrefix + nodeName,
+	}
+}
+
+// LoadAllocations returns the persisted allocations of this node (nil if not found).
+func (s *kvIPAMStore) LoadAllocations() (*ipammodel.PodIPAllocations, error) {
+	allocations := &ipammodel.PodIPAllocations{}
+	found, _, err := s.broker.GetValue(s.key, allocations)
+	if err != nil || !found {
+		return nil, err
+	}
+	return allocations, nil
+}
+
+// SaveAllocations overwrites the persisted allocations of this node.
+func (s *kvIPAMStore) SaveAllocations(allocations *ipammodel.PodIPAllocations) error {
+	return s.broker.Put(s.key, allocations)
+}
@@ -0,0 +1,369 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+)
+
+const (
+	// defaultIPSecRekeyInterval is the default interval between rekeys of the IPsec SAs in seconds.
+	defaultIPSecRekeyInterval = 3600
+
+	// ipsecSPD is the ID of the security policy database attached to the main physical interface.
+	ipsecSPD = 1
+
+	// ipsecSABase is the first ID of the IPsec SAs configured by the agent.
+	ipsecSABase = 1000
+
+	// priorities of the IPsec policies, the policies protecting VXLAN take precedence over the bypass
+	ipsecBypassPriority  = 10
+	ipsecProtectPriority = 100
+
+	// minimal SPI, the lower values are reserved
+	ipsecMinSPI = 256
+
+	// vxlanPort is the UDP port of the VXLAN traffic protected by IPsec
+	vxlanPort = 4789
+)
+
+// IPSecConfig configures IPsec protection of the VXLAN traffic between the nodes.
+type IPSecConfig struct {
+	PSKFile       string // file with the pre-shared key of the cluster (mounted from k8s secret), IPsec is disabled if empty
+	RekeyInterval uint32 // interval between rekeys of the IPsec SAs in seconds, 3600 by default
+}
+
+// ipsecTunnel describes the IPsec SAs protecting the VXLAN tunnel to another node.
+type ipsecTunnel struct {
+	localIP       string
+	remoteIP      string
+	outbound      uint64   // epoch of the outbound SA
+	outboundValid bool     // false until the outbound SA is installed
+	inbound       []uint64 // epochs of the inbound SAs
+}
+
+// initIPSec parses the IPsec config.
+func (s *remoteCNIserver) initIPSec(config IPSecConfig) error {
+	if s.useL2Interconnect {
+		return errors.New("IPsec requires the vxlan inter-node transport")
+	}
+	rekeyInterval := config.RekeyInterval
+	if rekeyInterval == 0 {
+		rekeyInterval = defaultIPSecRekeyInterval
+	}
+	s.useIPSec = true
+	s.ipsecPSKFile = config.PSKFile
+	s.ipsecRekeyInterval = time.Duration(rekeyInterval) * time.Second
+	s.ipsecTunnels = map[uint32]*ipsecTunnel{}
+	s.ipsecNow = time.Now
+	return nil
+}
+
+// ipsecCurrentEpoch returns the number of the current rekey interval. All nodes use the SAs
+// of the same epoch (with the synchronized clocks), no key exchange is therefore needed.
+func (s *remoteCNIserver) ipsecCurrentEpoch() uint64 {
+	return uint64(s.ipsecNow().Unix()) / uint64(s.ipsecRekeyInterval/time.Second)
+}
+
+// loadIPSecPSK (re-)reads the pre-shared key from the file mounted from the k8s secret.
+func (s *remoteCNIserver) loadIPSecPSK() error {
+	psk, err := ioutil.ReadFile(s.ipsecPSKFile)
+	if err != nil {
+		return fmt.Errorf("can't read IPsec pre-shared key: %v", err)
+	}
+	psk = bytes.TrimSpace(psk)
+	if len(psk) == 0 {
+		return fmt.Errorf("IPsec pre-shared key in %s is empty", s.ipsecPSKFile)
+	}
+	s.ipsecPSK = psk
+	return nil
+}
+
+// configureIPSec attaches the security policy database to the main physical interface, letting the traffic
+// not destined to the VXLAN tunnels bypass IPsec. The SAs of the already configured tunnels are rekeyed
+// if the epoch has changed meanwhile. The method must be called with acquired mutex guarding remoteCNI server.
+func (s *remoteCNIserver) configureIPSec() error {
+	if s.mainPhysicalIf == "" {
+		return errors.New("IPsec requires the main physical interface")
+	}
+	if err := s.loadIPSecPSK(); err != nil {
+		return err
+	}
+	// the SPD may have been configured by the previous run of the agent
+	s.execConfigCLI("ipsec spd add %d", ipsecSPD)
+	if err := s.execConfigCLI("set interface ipsec spd %s %d", s.mainPhysicalIf, ipsecSPD); err != nil {
+		return err
+	}
+	bypass := fmt.Sprintf("spd %d priority %d outbound action bypass", ipsecSPD, ipsecBypassPriority)
+	s.execConfigCLI("ipsec policy del %s", bypass)
+	if err := s.execConfigCLI("ipsec policy add %s", bypass); err != nil {
+		return err
+	}
+	return s.rekeyIPSec()
+}
+
+// addIPSecToNode protects the VXLAN tunnel to the other node with IPsec. The inbound SAs of the previous
+// and the next epoch are installed as well, tolerating the clock skew between the nodes.
+func (s *remoteCNIserver) addIPSecToNode(nodeInfo *node.NodeInfo) error {
+	if s.ipsecPSK == nil {
+		return errors.New("IPsec pre-shared key is not loaded")
+	}
+	nodeID := uint8(nodeInfo.Id)
+	tunnel := &ipsecTunnel{
+		localIP:  s.ipPrefixToAddress(s.nodeIP),
+		remoteIP: s.otherHostIP(nodeID, nodeInfo.IpAddress),
+	}
+	epoch := s.ipsecEpoch
+	for _, inbound := range []uint64{epoch - 1, epoch, epoch + 1} {
+		if err := s.addIPSecInbound(nodeID, tunnel, inbound); err != nil {
+			return fmt.Errorf("Can't configure VPP to add IPsec to node %v: %v ", nodeInfo.Id, err)
+		}
+	}
+	if err := s.addIPSecOutbound(nodeID, tunnel, epoch); err != nil {
+		return fmt.Errorf("Can't configure VPP to add IPsec to node %v: %v ", nodeInfo.Id, err)
+	}
+	s.ipsecTunnels[nodeInfo.Id] = tunnel
+	s.Logger.Infof("Added IPsec to node %v (epoch %d)", nodeInfo.Id, epoch)
+	return nil
+}
+
+// deleteIPSecToNode removes the IPsec SAs and policies of the VXLAN tunnel to the other node.
+// All of them are removed even if the removal of some fails, the first error is returned.
+func (s *remoteCNIserver) deleteIPSecToNode(nodeInfo *node.NodeInfo) error {
+	tunnel, found := s.ipsecTunnels[nodeInfo.Id]
+	if !found {
+		return nil
+	}
+	nodeID := uint8(nodeInfo.Id)
+	var wasErr error
+	if tunnel.outboundValid {
+		wasErr = s.deleteIPSecOutbound(nodeID, tunnel, tunnel.outbound)
+	}
+	for _, inbound := range append([]uint64{}, tunnel.inbound...) {
+		if err := s.deleteIPSecInbound(nodeID, tunnel, inbound); err != nil && wasErr == nil {
+			wasErr = err
+		}
+	}
+	delete(s.ipsecTunnels, nodeInfo.Id)
+	if wasErr != nil {
+		return fmt.Errorf("Can't configure VPP to remove IPsec to node %v: %v ", nodeInfo.Id, wasErr)
+	}
+	s.Logger.Infof("Deleted IPsec to node %v", nodeInfo.Id)
+	return nil
+}
+
+// rekeyIPSec switches the outbound SAs of all tunnels to the current epoch, installs the inbound SAs
+// of the current and the next epoch and removes the inbound SAs older than the previous epoch. The new outbound SA
+// is installed before the old one is removed, the traffic is thus never sent unprotected.
+// The method is run by the event loop.
+func (s *remoteCNIserver) rekeyIPSec() error {
+	epoch := s.ipsecCurrentEpoch()
+	if epoch == s.ipsecEpoch {
+		return nil
+	}
+	// the secret may have been rotated meanwhile
+	if err := s.loadIPSecPSK(); err != nil {
+		s.Logger.Warnf("Rekeying IPsec with the previous pre-shared key: %v", err)
+	}
+
+	var wasErr error
+	for id, tunnel := range s.ipsecTunnels {
+		nodeID := uint8(id)
+		// the slots of the expired inbound SAs are released before the SA of the next epoch is installed
+		for _, inbound := range append([]uint64{}, tunnel.inbound...) {
+			if inbound+1 < epoch {
+				if err := s.deleteIPSecInbound(nodeID, tunnel, inbound); err != nil {
+					s.Logger.Error(err)
+					wasErr = err
+				}
+			}
+		}
+		for _, inbound := range []uint64{epoch, epoch + 1} {
+			if err := s.addIPSecInbound(nodeID, tunnel, inbound); err != nil {
+				s.Logger.Error(err)
+				wasErr = err
+			}
+		}
+		if !tunnel.outboundValid || tunnel.outbound != epoch {
+			if err := s.addIPSecOutbound(nodeID, tunnel, epoch); err != nil {
+				s.Logger.Error(err)
+				wasErr = err
+			}
+		}
+	}
+	s.ipsecEpoch = epoch
+	s.Logger.Infof("IPsec rekeyed to epoch %d", epoch)
+	return wasErr
+}
+
+// runIPSecRekey rekeys the IPsec SAs at the start of every epoch until the context is cancelled.
+func (s *remoteCNIserver) runIPSecRekey(ctx context.Context) {
+	for {
+		now := s.ipsecNow()
+		interval := int64(s.ipsecRekeyInterval / time.Second)
+		next := time.Unix((now.Unix()/interval+1)*interval, 0)
+		select {
+		case <-time.After(next.Sub(now)):
+			s.loop.process("IPsec rekey", timerPriority, s.rekeyIPSec)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// addIPSecOutbound protects the VXLAN traffic to the other node by the outbound SA of the given epoch,
+// replacing the SA of the previous epoch.
+func (s *remoteCNIserver) addIPSecOutbound(nodeID uint8, tunnel *ipsecTunnel, epoch uint64) error {
+	previous, hasPrevious := tunnel.outbound, tunnel.outboundValid
+	if hasPrevious && ipsecOutboundSlot(previous) == ipsecOutboundSlot(epoch) {
+		// the slot of the SA is reused, the old SA has to be removed first
+		if err := s.deleteIPSecOutbound(nodeID, tunnel, previous); err != nil {
+			return err
+		}
+		hasPrevious = false
+	}
+	if err := s.addIPSecSA(s.ipsecSAID(nodeID, false, ipsecOutboundSlot(epoch)), epoch, tunnel.localIP, tunnel.remoteIP); err != nil {
+		return err
+	}
+	policy := s.ipsecOutboundPolicy(nodeID, tunnel, epoch)
+	s.execConfigCLI("ipsec policy del %s", policy)
+	if err := s.execConfigCLI("ipsec policy add %s", policy); err != nil {
+		return err
+	}
+	tunnel.outbound = epoch
+	tunnel.outboundValid = true
+	if hasPrevious {
+		return s.deleteIPSecOutbound(nodeID, tunnel, previous)
+	}
+	return nil
+}
+
+// deleteIPSecOutbound removes the outbound SA of the given epoch together with its policy.
+func (s *remoteCNIserver) deleteIPSecOutbound(nodeID uint8, tunnel *ipsecTunnel, epoch uint64) error {
+	wasErr := s.execConfigCLI("ipsec policy del %s", s.ipsecOutboundPolicy(nodeID, tunnel, epoch))
+	if err := s.execConfigCLI("ipsec sa del %d", s.ipsecSAID(nodeID, false, ipsecOutboundSlot(epoch))); err != nil && wasErr == nil {
+		wasErr = err
+	}
+	if epoch == tunnel.outbound {
+		tunnel.outboundValid = false
+	}
+	return wasErr
+}
+
+// addIPSecInbound installs the inbound SA of the given epoch from the other node.
+func (s *remoteCNIserver) addIPSecInbound(nodeID uint8, tunnel *ipsecTunnel, epoch uint64) error {
+	for _, inbound := range tunnel.inbound {
+		if inbound == epoch {
+			return nil
+		}
+	}
+	if err := s.addIPSecSA(s.ipsecSAID(nodeID, true, ipsecInboundSlot(epoch)), epoch, tunnel.remoteIP, tunnel.localIP); err != nil {
+		return err
+	}
+	policy := s.ipsecInboundPolicy(nodeID, tunnel, epoch)
+	s.execConfigCLI("ipsec policy del %s", policy)
+	if err := s.execConfigCLI("ipsec policy add %s", policy); err != nil {
+		return err
+	}
+	tunnel.inbound = append(tunnel.inbound, epoch)
+	return nil
+}
+
+// deleteIPSecInbound removes the inbound SA of the given epoch together with its policy.
+func (s *remoteCNIserver) deleteIPSecInbound(nodeID uint8, tunnel *ipsecTunnel, epoch uint64) error {
+	wasErr := s.execConfigCLI("ipsec policy del %s", s.ipsecInboundPolicy(nodeID, tunnel, epoch))
+	if err := s.execConfigCLI("ipsec sa del %d", s.ipsecSAID(nodeID, true, ipsecInboundSlot(epoch))); err != nil && wasErr == nil {
+		wasErr = err
+	}
+	for i, inbound := range tunnel.inbound {
+		if inbound == epoch {
+			tunnel.inbound = append(tunnel.inbound[:i], tunnel.inbound[i+1:]...)
+			break
+		}
+	}
+	return wasErr
+}
+
+// addIPSecSA (re-)configures the ESP SA of the given epoch protecting the traffic from <src> to <dst>.
+func (s *remoteCNIserver) addIPSecSA(id uint32, epoch uint64, src, dst string) error {
+	spi, cryptoKey, integKey := s.ipsecSAKeys(epoch, src, dst)
+	// the SA may have been configured by the previous run of the agent
+	s.execConfigCLI("ipsec sa del %d", id)
+	return s.execConfigCLI("ipsec sa add %d spi %d esp crypto-alg aes-cbc-128 crypto-key %s integ-alg sha-256-128 integ-key %s",
+		id, spi, hex.EncodeToString(cryptoKey), hex.EncodeToString(integKey))
+}
+
+// ipsecSAKeys derives the SPI and the keys of the SA of the given epoch from the pre-shared key.
+// Both nodes of the tunnel derive the same values, the sending node for its outbound SA
+// and the receiving node for its inbound SA.
+func (s *remoteCNIserver) ipsecSAKeys(epoch uint64, src, dst string) (spi uint32, cryptoKey, integKey []byte) {
+	derive := func(purpose string) []byte {
+		mac := hmac.New(sha256.New, s.ipsecPSK)
+		fmt.Fprintf(mac, "%d|%s|%s|%s", epoch, src, dst, purpose)
+		return mac.Sum(nil)
+	}
+	spi = binary.BigEndian.Uint32(derive("spi"))
+	if spi < ipsecMinSPI {
+		spi += ipsecMinSPI
+	}
+	return spi, derive("crypto")[:16], derive("integ")
+}
+
+// ipsecSAID returns the ID of the SA to/from the other node. Two slots are reserved for the outbound SAs
+// (the current and the previous epoch during the rekey) and three for the inbound SAs.
+func (s *remoteCNIserver) ipsecSAID(nodeID uint8, inbound bool, slot uint64) uint32 {
+	id := ipsecSABase + uint32(nodeID)<<3 + uint32(slot)
+	if inbound {
+		id += 4
+	}
+	return id
+}
+
+// ipsecOutboundPolicy returns the outbound policy protecting the VXLAN traffic to the other node.
+func (s *remoteCNIserver) ipsecOutboundPolicy(nodeID uint8, tunnel *ipsecTunnel, epoch uint64) string {
+	slot := ipsecOutboundSlot(epoch)
+	return fmt.Sprintf("spd %d priority %d outbound action protect sa %d local-ip-range %s - %s remote-ip-range %s - %s "+
+		"protocol 17 remote-port-range %d - %d", ipsecSPD, ipsecProtectPriority+slot, s.ipsecSAID(nodeID, false, slot),
+		tunnel.localIP, tunnel.localIP, tunnel.remoteIP, tunnel.remoteIP, vxlanPort, vxlanPort)
+}
+
+// ipsecInboundPolicy returns the inbound policy accepting the ESP traffic from the other node.
+func (s *remoteCNIserver) ipsecInboundPolicy(nodeID uint8, tunnel *ipsecTunnel, epoch uint64) string {
+	slot := ipsecInboundSlot(epoch)
+	return fmt.Sprintf("spd %d priority %d inbound action protect sa %d local-ip-range %s - %s remote-ip-range %s - %s",
+		ipsecSPD, ipsecProtectPriority+slot, s.ipsecSAID(nodeID, true, slot),
+		tunnel.localIP, tunnel.localIP, tunnel.remoteIP, tunnel.remoteIP)
+}
+
+// ipsecOutboundSlot returns the slot of the outbound SA of the given epoch.
+func ipsecOutboundSlot(epoch uint64) uint64 {
+	return epoch % 2
+}
+
+// ipsecInboundSlot returns the slot of the inbound SA of the given epoch.
+func ipsecInboundSlot(epoch uint64) uint64 {
+	return epoch % 3
+}
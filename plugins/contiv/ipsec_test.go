@@ -0,0 +1,132 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/onsi/gomega"
+)
+
+// commandsWithPrefix returns the recorded CLI commands starting with the given prefix.
+func commandsWithPrefix(commands []string, prefix string) []string {
+	var matching []string
+	for _, command := range commands {
+		if strings.HasPrefix(command, prefix) {
+			matching = append(matching, command)
+		}
+	}
+	return matching
+}
+
+func TestIPSec(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	pskFile, err := ioutil.TempFile("", "ipsec-psk")
+	gomega.Expect(err).To(gomega.BeNil())
+	defer os.Remove(pskFile.Name())
+	pskFile.WriteString("secret\n")
+	pskFile.Close()
+
+	config := configTapVxlanTCP
+	config.IPSec = IPSecConfig{PSKFile: pskFile.Name(), RekeyInterval: 60}
+	server, _, _, conn := setupTestCNIServer(&config, &nodeConfig)
+	defer conn.Disconnect()
+	gomega.Expect(server.useIPSec).To(gomega.BeTrue())
+
+	now := time.Unix(10*60+30, 0)
+	server.ipsecNow = func() time.Time { return now }
+	var commands []string
+	failRemovals := false
+	server.execVppCLI = func(command string) (string, error) {
+		commands = append(commands, command)
+		if failRemovals && strings.Contains(command, " del ") {
+			return "", fmt.Errorf("not found")
+		}
+		return "", nil
+	}
+
+	err = server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// SPD attached to the main interface, other traffic bypasses IPsec
+	err = server.configureIPSec()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.ipsecEpoch).To(gomega.BeEquivalentTo(10))
+	gomega.Expect(commands).To(gomega.ContainElement("set interface ipsec spd GigabitEthernet0/0/0/1 1"))
+	gomega.Expect(commands).To(gomega.ContainElement("ipsec policy add spd 1 priority 10 outbound action bypass"))
+
+	// the VXLAN traffic to the other node is protected by the SA of the current epoch
+	commands = nil
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
+	gomega.Expect(err).To(gomega.BeNil())
+	localIP := server.ipPrefixToAddress(server.nodeIP)
+	remoteIP := server.ipPrefixToAddress(otherNodeInfo.IpAddress)
+	outboundSA := ipsecSABase + otherNodeInfo.Id<<3 // epoch 10 -> slot 0
+	gomega.Expect(commands).To(gomega.ContainElement(fmt.Sprintf("ipsec policy add spd 1 priority 100 outbound action protect sa %d "+
+		"local-ip-range %s - %s remote-ip-range %s - %s protocol 17 remote-port-range 4789 - 4789",
+		outboundSA, localIP, localIP, remoteIP, remoteIP)))
+	gomega.Expect(commandsWithPrefix(commands, "ipsec sa add")).To(gomega.HaveLen(4))
+	gomega.Expect(server.ipsecTunnels[otherNodeInfo.Id].inbound).To(gomega.ConsistOf(uint64(9), uint64(10), uint64(11)))
+
+	// both ends derive the same keys, the SAs differ per direction and epoch
+	spi, cryptoKey, integKey := server.ipsecSAKeys(10, localIP, remoteIP)
+	gomega.Expect(spi).To(gomega.BeNumerically(">=", ipsecMinSPI))
+	gomega.Expect(cryptoKey).To(gomega.HaveLen(16))
+	gomega.Expect(integKey).To(gomega.HaveLen(32))
+	spi2, cryptoKey2, _ := server.ipsecSAKeys(10, localIP, remoteIP)
+	gomega.Expect(spi2).To(gomega.Equal(spi))
+	gomega.Expect(cryptoKey2).To(gomega.Equal(cryptoKey))
+	_, reverseKey, _ := server.ipsecSAKeys(10, remoteIP, localIP)
+	gomega.Expect(reverseKey).NotTo(gomega.Equal(cryptoKey))
+	_, nextKey, _ := server.ipsecSAKeys(11, localIP, remoteIP)
+	gomega.Expect(nextKey).NotTo(gomega.Equal(cryptoKey))
+
+	// rekey switches the outbound SA and rotates the inbound SAs
+	commands = nil
+	now = now.Add(time.Minute)
+	err = server.rekeyIPSec()
+	gomega.Expect(err).To(gomega.BeNil())
+	tunnel := server.ipsecTunnels[otherNodeInfo.Id]
+	gomega.Expect(tunnel.outbound).To(gomega.BeEquivalentTo(11))
+	gomega.Expect(tunnel.inbound).To(gomega.ConsistOf(uint64(10), uint64(11), uint64(12)))
+	gomega.Expect(commands).To(gomega.ContainElement(fmt.Sprintf("ipsec sa del %d", outboundSA)))
+	addNew := commandsWithPrefix(commands, fmt.Sprintf("ipsec sa add %d ", outboundSA+1))
+	gomega.Expect(addNew).To(gomega.HaveLen(1))
+	// the old outbound SA is removed only after the new one is installed
+	for i, command := range commands {
+		if command == fmt.Sprintf("ipsec sa del %d", outboundSA) {
+			gomega.Expect(commands[:i]).To(gomega.ContainElement(addNew[0]))
+		}
+	}
+
+	// all SAs are removed even if some removal fails
+	commands = nil
+	failRemovals = true
+	err = server.deleteIPSecToNode(&otherNodeInfo)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	gomega.Expect(server.ipsecTunnels).To(gomega.BeEmpty())
+	gomega.Expect(commandsWithPrefix(commands, "ipsec sa del")).To(gomega.HaveLen(4))
+
+	// IPsec requires VXLAN
+	gomega.Expect(server.selectInterNodeTransport(interNodeTransportNoOverlay)).To(gomega.Succeed())
+	gomega.Expect(server.initIPSec(config.IPSec)).NotTo(gomega.Succeed())
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/cn-infra/servicelabel"
+
+	"github.com/contiv/vpp/flavors/ksr"
+	eventmodel "github.com/contiv/vpp/plugins/ksr/model/event"
+	"github.com/contiv/vpp/plugins/kvstore"
+)
+
+const (
+	// reasonNetworkSetupFailed is the reason of the event of a pod that failed to connect to the network.
+	reasonNetworkSetupFailed = "NetworkSetupFailed"
+
+	// reasonVswitchConfigFailed is the reason of the event of the node whose base vswitch config failed.
+	reasonVswitchConfigFailed = "VswitchConfigFailed"
+)
+
+// k8sEventReporter publishes the warnings about the pods of this node and about the node itself
+// into the data store under the KSR prefix, KSR records them as K8s Events (the agent cannot access
+// K8s API). The events are best-effort, failures to publish them are only logged.
+type k8sEventReporter struct {
+	logger logging.Logger
+	broker keyval.ProtoBroker
+	node   string
+}
+
+// newK8sEventReporter creates new instance of k8sEventReporter for the given node.
+func newK8sEventReporter(logger logging.Logger, store kvstore.KvStore, nodeName string) *k8sEventReporter {
+	return &k8sEventReporter{
+		logger: logger,
+		broker: store.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel)),
+		node:   nodeName,
+	}
+}
+
+// report publishes the event of the given pod, or of this node if podName is empty.
+func (r *k8sEventReporter) report(podNamespace, podName, reason, message string) {
+	ev := &eventmodel.Event{
+		Kind:      eventmodel.KindPod,
+		Name:      podName,
+		Namespace: podNamespace,
+		Node:      r.node,
+		Reason:    reason,
+		Message:   message,
+	}
+	if podName == "" {
+		ev.Kind, ev.Name, ev.Namespace = eventmodel.KindNode, r.node, ""
+	}
+	if err := r.broker.Put(eventmodel.Key(ev), ev); err != nil {
+		r.logger.Warnf("Failed to publish the event %s of %s %s: %v", reason, ev.Kind, ev.Name, err)
+	}
+}
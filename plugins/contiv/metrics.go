@@ -0,0 +1,188 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"time"
+
+	"github.com/contiv/vpp/plugins/contiv/ipam"
+	"github.com/contiv/vpp/plugins/contiv/model/cni"
+	prometheusplugin "github.com/ligato/cn-infra/rpc/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// metricsNamespace is the namespace of all metrics of the contiv plugin.
+	metricsNamespace = "contiv"
+
+	// labels of the metrics
+	requestLabel = "request"
+	resultLabel  = "result"
+	messageLabel = "message"
+	poolLabel    = "pool"
+
+	// values of resultLabel
+	resultOkLabel    = "ok"
+	resultErrorLabel = "error"
+
+	// poolLabel value of the POD network of the node
+	podNetworkPool = "pod-network"
+)
+
+// metrics groups the metrics of the contiv plugin internals exposed by the Prometheus plugin
+// in its default registry (/metrics). The methods can be called on nil metrics (e.g. in tests).
+type metrics struct {
+	cniRequestDuration        *prometheus.HistogramVec
+	vppAPIErrors              *prometheus.CounterVec
+	vppAPIUp                  prometheus.Gauge
+	vppAPIOutages             prometheus.Counter
+	vppRestarts               prometheus.Counter
+	nodeIDAllocationAttempts  prometheus.Counter
+	nodeIDAllocationConflicts prometheus.Counter
+}
+
+// newMetrics creates the metrics of the contiv plugin.
+func newMetrics() *metrics {
+	return &metrics{
+		cniRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "cni",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of CNI requests including the time spent waiting for the previous requests of the POD",
+		}, []string{requestLabel, resultLabel}),
+		vppAPIErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "vpp",
+			Name:      "api_errors_total",
+			Help:      "Number of failed binary API requests sent to VPP directly by the contiv plugin",
+		}, []string{messageLabel}),
+		vppAPIUp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "vpp",
+			Name:      "api_up",
+			Help:      "1 if VPP responds to the probes of its binary API, 0 during an outage",
+		}),
+		vppAPIOutages: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "vpp",
+			Name:      "api_outages_total",
+			Help:      "Number of outages of the VPP binary API detected by the probes",
+		}),
+		vppRestarts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "vpp",
+			Name:      "restarts_total",
+			Help:      "Number of restarts of VPP detected by the probes of its binary API",
+		}),
+		nodeIDAllocationAttempts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "nodeid",
+			Name:      "allocation_attempts_total",
+			Help:      "Number of attempts to allocate a free node ID",
+		}),
+		nodeIDAllocationConflicts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "nodeid",
+			Name:      "allocation_conflicts_total",
+			Help:      "Number of node ID allocation attempts failed since the ID was allocated by another node meanwhile",
+		}),
+	}
+}
+
+// register registers the metrics with the Prometheus plugin, including the gauges of the utilization
+// of the IPAM pools.
+func (m *metrics) register(prom prometheusplugin.API, ipam *ipam.IPAM) error {
+	for _, collector := range []prometheus.Collector{m.cniRequestDuration, m.vppAPIErrors,
+		m.vppAPIUp, m.vppAPIOutages, m.vppRestarts, m.nodeIDAllocationAttempts, m.nodeIDAllocationConflicts} {
+		if err := prom.Register(prometheusplugin.DefaultRegistry, collector); err != nil {
+			return err
+		}
+	}
+
+	for idx, pool := range ipam.PoolsUtilization() {
+		idx := idx
+		poolName := pool.Pool
+		if poolName == "" {
+			poolName = podNetworkPool
+		}
+		labels := prometheus.Labels{poolLabel: poolName}
+		err := prom.RegisterGaugeFunc(prometheusplugin.DefaultRegistry, metricsNamespace, "ipam", "assigned_ips",
+			"Number of IP addresses assigned from the IPAM pool of the node", labels,
+			func() float64 { return float64(ipam.PoolsUtilization()[idx].Assigned) })
+		if err != nil {
+			return err
+		}
+		err = prom.RegisterGaugeFunc(prometheusplugin.DefaultRegistry, metricsNamespace, "ipam", "capacity_ips",
+			"Number of IP addresses that can be assigned from the IPAM pool of the node", labels,
+			func() float64 { return float64(ipam.PoolsUtilization()[idx].Capacity) })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observeCNIRequest records the duration of the CNI request started at the given time.
+func (m *metrics) observeCNIRequest(request string, start time.Time, reply *cni.CNIReply, err error) {
+	if m == nil {
+		return
+	}
+	result := resultOkLabel
+	if err != nil || reply == nil || reply.Result != resultOk {
+		result = resultErrorLabel
+	}
+	m.cniRequestDuration.WithLabelValues(request, result).Observe(time.Since(start).Seconds())
+}
+
+// vppAPIError counts the failed binary API request.
+func (m *metrics) vppAPIError(message string) {
+	if m == nil {
+		return
+	}
+	m.vppAPIErrors.WithLabelValues(message).Inc()
+}
+
+// vppAPIState sets the state of the VPP binary API, an outage is counted if up is false.
+func (m *metrics) vppAPIState(up bool) {
+	if m == nil {
+		return
+	}
+	if up {
+		m.vppAPIUp.Set(1)
+		return
+	}
+	m.vppAPIUp.Set(0)
+	m.vppAPIOutages.Inc()
+}
+
+// vppRestart counts the detected restart of VPP.
+func (m *metrics) vppRestart() {
+	if m == nil {
+		return
+	}
+	m.vppRestarts.Inc()
+}
+
+// nodeIDAllocationAttempt counts the attempt to allocate a node ID, conflict is true if the ID
+// was allocated by another node.
+func (m *metrics) nodeIDAllocationAttempt(conflict bool) {
+	if m == nil {
+		return
+	}
+	m.nodeIDAllocationAttempts.Inc()
+	if conflict {
+		m.nodeIDAllocationConflicts.Inc()
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"testing"
+
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+// racingIDStore is a NodeIDStore where another node allocates the first ID this node attempts to allocate.
+type racingIDStore struct {
+	*memIDStore
+	raced bool
+}
+
+func (s *racingIDStore) PutIfNotExists(entry *node.NodeInfo) (bool, error) {
+	if !s.raced {
+		s.raced = true
+		s.memIDStore.PutIfNotExists(&node.NodeInfo{Id: entry.Id, Name: "other-node"})
+	}
+	return s.memIDStore.PutIfNotExists(entry)
+}
+
+// metricValue reads the current value of the counter or the sample count of the histogram.
+func metricValue(metric prometheus.Metric) float64 {
+	m := &dto.Metric{}
+	gomega.Expect(metric.Write(m)).To(gomega.Succeed())
+	if m.Histogram != nil {
+		return float64(m.Histogram.GetSampleCount())
+	}
+	return m.Counter.GetValue()
+}
+
+func TestCNIRequestMetrics(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, _, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+	server.metrics = newMetrics()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	_, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	_, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+
+	duration := server.metrics.cniRequestDuration
+	gomega.Expect(metricValue(duration.WithLabelValues("add", resultOkLabel))).To(gomega.BeEquivalentTo(1))
+	gomega.Expect(metricValue(duration.WithLabelValues("delete", resultOkLabel))).To(gomega.BeEquivalentTo(1))
+	gomega.Expect(metricValue(duration.WithLabelValues("add", resultErrorLabel))).To(gomega.BeZero())
+}
+
+func TestNodeIDAllocationMetrics(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := &racingIDStore{memIDStore: newMemIDStore()}
+	ia := newIDAllocator(logrus.DefaultLogger(), store, "node1", "", "", "", 0, false, nil, "")
+	ia.metrics = newMetrics()
+
+	id, err := ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(id).To(gomega.BeEquivalentTo(2))
+
+	// the first attempt conflicted with the other node
+	gomega.Expect(metricValue(ia.metrics.nodeIDAllocationAttempts)).To(gomega.BeEquivalentTo(2))
+	gomega.Expect(metricValue(ia.metrics.nodeIDAllocationConflicts)).To(gomega.BeEquivalentTo(1))
+}
@@ -74,6 +74,9 @@ type CNIRequest struct {
 	ExtraNwConfig string `protobuf:"bytes,5,opt,name=extra_nw_config,json=extraNwConfig" json:"extra_nw_config,omitempty"`
 	// Extra arguments passed to CNI plugin. Optional.
 	ExtraArguments string `protobuf:"bytes,6,opt,name=extra_arguments,json=extraArguments" json:"extra_arguments,omitempty"`
+	// IP addresses assigned to the container by an external IPAM plugin, executed by the CNI plugin
+	// if IPAM is configured in the network configuration. Optional.
+	ExternalIpamAddresses []*CNIReply_Interface_IP `protobuf:"bytes,7,rep,name=external_ipam_addresses,json=externalIpamAddresses" json:"external_ipam_addresses,omitempty"`
 }
 
 func (m *CNIRequest) Reset()                    { *m = CNIRequest{} }
@@ -123,6 +126,13 @@ func (m *CNIRequest) GetExtraArguments() string {
 	return ""
 }
 
+func (m *CNIRequest) GetExternalIpamAddresses() []*CNIReply_Interface_IP {
+	if m != nil {
+		return m.ExternalIpamAddresses
+	}
+	return nil
+}
+
 // The response to the CNIRequest. Corresponds to the CNI specification
 // at https://github.com/containernetworking/cni/blob/master/SPEC.md#parameters
 type CNIReply struct {
@@ -188,6 +198,8 @@ type CNIReply_Interface struct {
 	Sandbox string `protobuf:"bytes,3,opt,name=sandbox" json:"sandbox,omitempty"`
 	// List of IP addressess applied on the interface.
 	IpAddresses []*CNIReply_Interface_IP `protobuf:"bytes,4,rep,name=ip_addresses,json=ipAddresses" json:"ip_addresses,omitempty"`
+	// MTU of the interface, 0 if not known.
+	Mtu uint32 `protobuf:"varint,5,opt,name=mtu" json:"mtu,omitempty"`
 }
 
 func (m *CNIReply_Interface) Reset()                    { *m = CNIReply_Interface{} }
@@ -223,6 +235,13 @@ func (m *CNIReply_Interface) GetIpAddresses() []*CNIReply_Interface_IP {
 	return nil
 }
 
+func (m *CNIReply_Interface) GetMtu() uint32 {
+	if m != nil {
+		return m.Mtu
+	}
+	return 0
+}
+
 // IP address details, as described in https://github.com/containernetworking/cni/blob/master/SPEC.md#ips
 type CNIReply_Interface_IP struct {
 	// IP version.
@@ -356,6 +375,8 @@ type RemoteCNIClient interface {
 	Add(ctx context.Context, in *CNIRequest, opts ...grpc.CallOption) (*CNIReply, error)
 	// The request to delete a container from network.
 	Delete(ctx context.Context, in *CNIRequest, opts ...grpc.CallOption) (*CNIReply, error)
+	// The request to check that the networking of a container is configured as expected.
+	Check(ctx context.Context, in *CNIRequest, opts ...grpc.CallOption) (*CNIReply, error)
 }
 
 type remoteCNIClient struct {
@@ -384,6 +405,15 @@ func (c *remoteCNIClient) Delete(ctx context.Context, in *CNIRequest, opts ...gr
 	return out, nil
 }
 
+func (c *remoteCNIClient) Check(ctx context.Context, in *CNIRequest, opts ...grpc.CallOption) (*CNIReply, error) {
+	out := new(CNIReply)
+	err := grpc.Invoke(ctx, "/cni.RemoteCNI/Check", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for RemoteCNI service
 
 type RemoteCNIServer interface {
@@ -391,6 +421,8 @@ type RemoteCNIServer interface {
 	Add(context.Context, *CNIRequest) (*CNIReply, error)
 	// The request to delete a container from network.
 	Delete(context.Context, *CNIRequest) (*CNIReply, error)
+	// The request to check that the networking of a container is configured as expected.
+	Check(context.Context, *CNIRequest) (*CNIReply, error)
 }
 
 func RegisterRemoteCNIServer(s *grpc.Server, srv RemoteCNIServer) {
@@ -433,6 +465,24 @@ func _RemoteCNI_Delete_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RemoteCNI_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CNIRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemoteCNIServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cni.RemoteCNI/Check",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemoteCNIServer).Check(ctx, req.(*CNIRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _RemoteCNI_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "cni.RemoteCNI",
 	HandlerType: (*RemoteCNIServer)(nil),
@@ -445,6 +495,10 @@ var _RemoteCNI_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _RemoteCNI_Delete_Handler,
 		},
+		{
+			MethodName: "Check",
+			Handler:    _RemoteCNI_Check_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "cni.proto",
@@ -453,39 +507,42 @@ var _RemoteCNI_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("cni.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 541 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x53, 0xcd, 0x6e, 0x13, 0x31,
-	0x10, 0x6e, 0x76, 0xf3, 0xd3, 0x4c, 0x9a, 0x34, 0x35, 0x08, 0xac, 0x95, 0x90, 0x42, 0x10, 0xb4,
-	0x50, 0x29, 0x87, 0x80, 0xe0, 0x02, 0x87, 0x28, 0xb9, 0xec, 0x65, 0x15, 0x6d, 0xa5, 0x5e, 0x83,
-	0xbb, 0x3b, 0x0d, 0x2b, 0x12, 0x7b, 0x6b, 0x3b, 0x6c, 0xfb, 0x02, 0xbc, 0x01, 0x2f, 0xc0, 0x03,
-	0xf2, 0x0c, 0xc8, 0x8e, 0x9d, 0x36, 0x87, 0xaa, 0xb7, 0xf9, 0xbe, 0xf9, 0xec, 0xfd, 0xe6, 0xf3,
-	0x2c, 0xb4, 0x33, 0x5e, 0x8c, 0x4a, 0x29, 0xb4, 0x20, 0x61, 0xc6, 0x8b, 0xe1, 0xbf, 0x1a, 0xc0,
-	0x34, 0x89, 0x53, 0xbc, 0xd9, 0xa0, 0xd2, 0x84, 0x42, 0xeb, 0x17, 0x4a, 0x55, 0x08, 0x4e, 0x6b,
-	0x83, 0xda, 0x59, 0x3b, 0xf5, 0x90, 0xbc, 0x86, 0xa3, 0x4c, 0x70, 0xcd, 0x0a, 0x8e, 0x72, 0x51,
-	0xe4, 0x34, 0xb0, 0xed, 0xce, 0x8e, 0x8b, 0x73, 0x72, 0x0e, 0x27, 0x1c, 0x75, 0x25, 0xe4, 0xcf,
-	0x05, 0x67, 0x6b, 0x54, 0x25, 0xcb, 0x90, 0x86, 0x56, 0xd7, 0x77, 0x8d, 0xc4, 0xf3, 0xe4, 0x2d,
-	0xf4, 0x0a, 0xae, 0x51, 0x5e, 0xb3, 0x0c, 0xad, 0x9c, 0xd6, 0xad, 0xb2, 0xbb, 0x63, 0x8d, 0x96,
-	0xbc, 0x83, 0x63, 0xbc, 0xd5, 0x92, 0x2d, 0x78, 0xb5, 0xc8, 0x04, 0xbf, 0x2e, 0x96, 0xb4, 0xb1,
-	0xd5, 0x59, 0x3a, 0xa9, 0xa6, 0x96, 0x24, 0xa7, 0x5e, 0xc7, 0xe4, 0x72, 0xb3, 0x46, 0xae, 0x15,
-	0x6d, 0x5a, 0x5d, 0xcf, 0xd2, 0x13, 0xcf, 0x0e, 0x7f, 0x37, 0xe0, 0xd0, 0x0e, 0x5c, 0xae, 0xee,
-	0xc8, 0x0b, 0x68, 0x4a, 0x54, 0x9b, 0x95, 0xb6, 0xd3, 0x76, 0x53, 0x87, 0xc8, 0x73, 0x68, 0xa0,
-	0x94, 0x42, 0xba, 0x29, 0xb7, 0x80, 0x7c, 0x01, 0xd8, 0x99, 0x53, 0xb4, 0x3e, 0x08, 0xcf, 0x3a,
-	0xe3, 0x97, 0x23, 0x13, 0xa8, 0xbf, 0x70, 0x14, 0xfb, 0x7e, 0xfa, 0x40, 0x4a, 0xce, 0xa1, 0x29,
-	0xc5, 0x46, 0xa3, 0xa2, 0x0d, 0x7b, 0xe8, 0xd9, 0xfe, 0xa1, 0xd4, 0xf4, 0x52, 0x27, 0x21, 0x6f,
-	0x20, 0xcc, 0xb9, 0x71, 0x6f, 0x94, 0x27, 0xfb, 0xca, 0x59, 0x72, 0x91, 0x9a, 0x6e, 0xf4, 0x37,
-	0x80, 0xf6, 0xee, 0x5b, 0x84, 0x40, 0xdd, 0x26, 0xb8, 0x7d, 0x32, 0x5b, 0x93, 0x3e, 0x84, 0x6b,
-	0x96, 0xb9, 0x01, 0x4c, 0x69, 0xde, 0x56, 0x31, 0x9e, 0x5f, 0x89, 0x5b, 0xf7, 0x28, 0x1e, 0x92,
-	0x6f, 0x70, 0x54, 0x94, 0x0b, 0x96, 0xe7, 0x12, 0x95, 0xda, 0x8d, 0x16, 0x3d, 0x32, 0xda, 0x28,
-	0x9e, 0xa7, 0x9d, 0xa2, 0x9c, 0x78, 0x79, 0xf4, 0xa7, 0x06, 0x41, 0x3c, 0x27, 0x5f, 0xf7, 0x77,
-	0xa7, 0x37, 0x1e, 0x3e, 0x7e, 0xc1, 0xe8, 0x72, 0xab, 0xbc, 0xdf, 0x2f, 0x0a, 0x2d, 0x67, 0xc0,
-	0x79, 0xf6, 0xd0, 0x74, 0x96, 0x4c, 0x63, 0xc5, 0xee, 0xbc, 0x6f, 0x07, 0x87, 0xaf, 0xa0, 0xe5,
-	0xee, 0x21, 0x87, 0x50, 0x8f, 0xe7, 0x97, 0x9f, 0xfa, 0x07, 0xae, 0xfa, 0xdc, 0xaf, 0x45, 0xef,
-	0xa1, 0x61, 0xa3, 0x35, 0x59, 0xe4, 0x4a, 0xbb, 0x78, 0x4c, 0x49, 0x7a, 0x10, 0x2c, 0x2b, 0xf7,
-	0xa1, 0x60, 0x59, 0x45, 0x37, 0x10, 0xce, 0x92, 0x0b, 0xb3, 0x0f, 0xb9, 0x58, 0xb3, 0xc2, 0x6f,
-	0xbf, 0x43, 0x64, 0x00, 0x1d, 0xbb, 0xd1, 0x28, 0x8d, 0x5d, 0x1a, 0x0c, 0x42, 0xb3, 0xfb, 0x0f,
-	0x28, 0x73, 0x52, 0x21, 0x93, 0xd9, 0x0f, 0x1a, 0xda, 0xa6, 0x43, 0xc6, 0xbc, 0x28, 0x75, 0x21,
-	0xf8, 0x36, 0xd5, 0x76, 0xea, 0xe1, 0xf8, 0x3b, 0xb4, 0x53, 0x5c, 0x0b, 0x8d, 0xd3, 0x24, 0x26,
-	0xa7, 0x10, 0x4e, 0xf2, 0x9c, 0x1c, 0xdf, 0x27, 0x66, 0xff, 0xc7, 0xa8, 0xbb, 0x17, 0xe1, 0xf0,
-	0x80, 0x7c, 0x80, 0xe6, 0x0c, 0x57, 0xa8, 0xf1, 0x69, 0xed, 0x55, 0xd3, 0xfe, 0xe7, 0x1f, 0xff,
-	0x07, 0x00, 0x00, 0xff, 0xff, 0x3a, 0x51, 0x47, 0xbd, 0xf4, 0x03, 0x00, 0x00,
+	// 587 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0xcd, 0x4e, 0x1b, 0x31,
+	0x10, 0x26, 0xbb, 0xf9, 0x21, 0x13, 0x12, 0x82, 0xfb, 0x83, 0x15, 0xa9, 0x52, 0x9a, 0xaa, 0x05,
+	0x8a, 0x94, 0x03, 0xad, 0xda, 0x4b, 0x7b, 0x40, 0xe1, 0xb2, 0x97, 0x08, 0x2d, 0x12, 0xd7, 0xc8,
+	0xec, 0x0e, 0xc1, 0x22, 0x6b, 0x2f, 0xb6, 0xd3, 0xc0, 0x33, 0xf4, 0x5c, 0xa9, 0x0f, 0xd2, 0x27,
+	0xe8, 0x93, 0x55, 0x76, 0xec, 0x90, 0x1c, 0x10, 0xbd, 0xcd, 0xf7, 0xcd, 0x37, 0xeb, 0xf1, 0xe7,
+	0x99, 0x85, 0x66, 0x26, 0xf8, 0xb0, 0x54, 0xd2, 0x48, 0x12, 0x67, 0x82, 0x0f, 0xfe, 0x46, 0x00,
+	0xa3, 0x71, 0x92, 0xe2, 0xdd, 0x1c, 0xb5, 0x21, 0x14, 0x1a, 0x3f, 0x50, 0x69, 0x2e, 0x05, 0xad,
+	0xf4, 0x2b, 0x87, 0xcd, 0x34, 0x40, 0xf2, 0x16, 0x76, 0x32, 0x29, 0x0c, 0xe3, 0x02, 0xd5, 0x84,
+	0xe7, 0x34, 0x72, 0xe9, 0xd6, 0x8a, 0x4b, 0x72, 0x72, 0x0c, 0x7b, 0x02, 0xcd, 0x42, 0xaa, 0xdb,
+	0x89, 0x60, 0x05, 0xea, 0x92, 0x65, 0x48, 0x63, 0xa7, 0xeb, 0xfa, 0xc4, 0x38, 0xf0, 0xe4, 0x3d,
+	0x74, 0xb8, 0x30, 0xa8, 0xae, 0x59, 0x86, 0x4e, 0x4e, 0xab, 0x4e, 0xd9, 0x5e, 0xb1, 0x56, 0x4b,
+	0x3e, 0xc0, 0x2e, 0xde, 0x1b, 0xc5, 0x26, 0x62, 0x31, 0xc9, 0xa4, 0xb8, 0xe6, 0x53, 0x5a, 0x5b,
+	0xea, 0x1c, 0x3d, 0x5e, 0x8c, 0x1c, 0x49, 0x0e, 0x82, 0x8e, 0xa9, 0xe9, 0xbc, 0x40, 0x61, 0x34,
+	0xad, 0x3b, 0x5d, 0xc7, 0xd1, 0xa7, 0x81, 0x25, 0x29, 0xec, 0xe3, 0xbd, 0x41, 0x25, 0xd8, 0x6c,
+	0xc2, 0x4b, 0x56, 0x4c, 0x58, 0x9e, 0x2b, 0xd4, 0x1a, 0x35, 0x6d, 0xf4, 0xe3, 0xc3, 0xd6, 0x49,
+	0x6f, 0x68, 0x2d, 0x72, 0x9e, 0x94, 0xb3, 0x87, 0x61, 0x12, 0xda, 0x19, 0x26, 0xe7, 0xe9, 0xab,
+	0x50, 0x9a, 0x94, 0xac, 0x38, 0x0d, 0x85, 0x83, 0xdf, 0x35, 0xd8, 0x0e, 0x05, 0xe4, 0x35, 0xd4,
+	0x15, 0xea, 0xf9, 0xcc, 0x38, 0x07, 0xdb, 0xa9, 0x47, 0xe4, 0x25, 0xd4, 0x50, 0x29, 0xa9, 0xbc,
+	0x73, 0x4b, 0x40, 0xbe, 0x02, 0xac, 0x2e, 0xac, 0x69, 0xd5, 0x75, 0xb0, 0xff, 0x44, 0x07, 0xe9,
+	0x9a, 0x94, 0x1c, 0x43, 0x5d, 0xc9, 0xb9, 0x41, 0x4d, 0x6b, 0xae, 0xe8, 0xc5, 0x66, 0x51, 0x6a,
+	0x73, 0xa9, 0x97, 0x90, 0x77, 0x10, 0xe7, 0xc2, 0x3a, 0x62, 0x95, 0x7b, 0x9b, 0xca, 0xb3, 0xf1,
+	0x45, 0x6a, 0xb3, 0xbd, 0x3f, 0x11, 0x34, 0x57, 0x67, 0x11, 0x02, 0x55, 0xf7, 0x2a, 0xcb, 0x31,
+	0x70, 0x31, 0xe9, 0x42, 0x5c, 0xb0, 0xcc, 0x5f, 0xc0, 0x86, 0x76, 0x5e, 0x34, 0x13, 0xf9, 0x95,
+	0xbc, 0xf7, 0x0f, 0x1d, 0x20, 0xf9, 0x0e, 0x3b, 0xbc, 0x5c, 0x33, 0xb7, 0xfa, 0xac, 0xb9, 0x2d,
+	0x5e, 0xae, 0x2c, 0x75, 0x47, 0x99, 0xb9, 0x7b, 0xeb, 0x76, 0x6a, 0xc3, 0xde, 0xaf, 0x0a, 0x44,
+	0xc9, 0x39, 0xf9, 0xb6, 0x39, 0xa1, 0x9d, 0x93, 0xc1, 0xd3, 0x9f, 0x1c, 0x5e, 0x2e, 0x95, 0x8f,
+	0x53, 0x4c, 0xa1, 0xe1, 0x5b, 0xf2, 0xb7, 0x08, 0xd0, 0x66, 0xa6, 0xcc, 0xe0, 0x82, 0x3d, 0x84,
+	0x9b, 0x78, 0x38, 0x78, 0x03, 0x0d, 0xff, 0x1d, 0xb2, 0x0d, 0xd5, 0xe4, 0xfc, 0xf2, 0x73, 0x77,
+	0xcb, 0x47, 0x5f, 0xba, 0x95, 0xde, 0x11, 0xd4, 0x9c, 0xd9, 0xb6, 0xe5, 0x5c, 0x1b, 0x6f, 0x98,
+	0x0d, 0x49, 0x07, 0xa2, 0xe9, 0xc2, 0x1f, 0x14, 0x4d, 0x17, 0xbd, 0x3b, 0x88, 0xcf, 0xc6, 0x17,
+	0x76, 0x42, 0x72, 0x59, 0x30, 0x1e, 0x76, 0xcc, 0x23, 0xd2, 0x87, 0x96, 0xdb, 0x1b, 0x54, 0xb6,
+	0x5d, 0x1a, 0xf5, 0x63, 0xbb, 0x61, 0x6b, 0x94, 0xad, 0xd4, 0xc8, 0x54, 0x76, 0x43, 0x63, 0x97,
+	0xf4, 0xc8, 0x36, 0x2f, 0x4b, 0xc3, 0xa5, 0x58, 0xfa, 0xdc, 0x4c, 0x03, 0x3c, 0xf9, 0x59, 0x81,
+	0x66, 0x8a, 0x85, 0x34, 0x38, 0x1a, 0x27, 0xe4, 0x00, 0xe2, 0xd3, 0x3c, 0x27, 0xbb, 0x8f, 0x96,
+	0xb9, 0xb5, 0xef, 0xb5, 0x37, 0x3c, 0x1c, 0x6c, 0x91, 0x8f, 0x50, 0x3f, 0xc3, 0x19, 0x1a, 0xfc,
+	0x0f, 0xed, 0x11, 0xd4, 0x46, 0x37, 0x98, 0xdd, 0x3e, 0x2f, 0xbd, 0xaa, 0xbb, 0x3f, 0xcf, 0xa7,
+	0x7f, 0x03, 0x00, 0xe9, 0x5e, 0x1c, 0xad, 0x86, 0x04, 0x00, 0x00,
 }
@@ -34,6 +34,15 @@ type NodeInfo struct {
 	Id        uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
 	Name      string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
 	IpAddress string `protobuf:"bytes,3,opt,name=ip_address,json=ipAddress" json:"ip_address,omitempty"`
+	// Unix time (in seconds) when the ID was allocated for the node.
+	AllocationTimestamp int64 `protobuf:"varint,4,opt,name=allocation_timestamp,json=allocationTimestamp" json:"allocation_timestamp,omitempty"`
+	// Management IP address of the node (as known to k8s).
+	ManagementIp string `protobuf:"bytes,5,opt,name=management_ip,json=managementIp" json:"management_ip,omitempty"`
+	// Build version of the agent that allocated the ID.
+	AgentVersion string `protobuf:"bytes,6,opt,name=agent_version,json=agentVersion" json:"agent_version,omitempty"`
+	// Pod network of the node assigned by k8s (Node.Spec.PodCIDR).
+	// Empty if the pod network is derived from the ID by IPAM.
+	PodNetwork string `protobuf:"bytes,7,opt,name=pod_network,json=podNetwork" json:"pod_network,omitempty"`
 }
 
 func (m *NodeInfo) Reset()                    { *m = NodeInfo{} }
@@ -62,6 +71,34 @@ func (m *NodeInfo) GetIpAddress() string {
 	return ""
 }
 
+func (m *NodeInfo) GetAllocationTimestamp() int64 {
+	if m != nil {
+		return m.AllocationTimestamp
+	}
+	return 0
+}
+
+func (m *NodeInfo) GetManagementIp() string {
+	if m != nil {
+		return m.ManagementIp
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetAgentVersion() string {
+	if m != nil {
+		return m.AgentVersion
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetPodNetwork() string {
+	if m != nil {
+		return m.PodNetwork
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*NodeInfo)(nil), "node.NodeInfo")
 }
@@ -69,13 +106,19 @@ func init() {
 func init() { proto.RegisterFile("node.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 113 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xca, 0xcb, 0x4f, 0x49,
-	0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x01, 0xb1, 0x95, 0x7c, 0xb9, 0x38, 0xfc, 0xf2,
-	0x53, 0x52, 0x3d, 0xf3, 0xd2, 0xf2, 0x85, 0xf8, 0xb8, 0x98, 0x32, 0x53, 0x24, 0x18, 0x15, 0x18,
-	0x35, 0x78, 0x83, 0x98, 0x32, 0x53, 0x84, 0x84, 0xb8, 0x58, 0xf2, 0x12, 0x73, 0x53, 0x25, 0x98,
-	0x14, 0x18, 0x35, 0x38, 0x83, 0xc0, 0x6c, 0x21, 0x59, 0x2e, 0xae, 0xcc, 0x82, 0xf8, 0xc4, 0x94,
-	0x94, 0xa2, 0xd4, 0xe2, 0x62, 0x09, 0x66, 0xb0, 0x0c, 0x67, 0x66, 0x81, 0x23, 0x44, 0x20, 0x89,
-	0x0d, 0x6c, 0xb6, 0x31, 0x20, 0x00, 0x00, 0xff, 0xff, 0x4d, 0x15, 0xc8, 0x7f, 0x69, 0x00, 0x00,
+	// 209 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x44, 0x8f, 0xb1, 0x4a, 0x03, 0x41,
+	0x10, 0x86, 0xd9, 0xcb, 0x19, 0xcd, 0x68, 0x2c, 0x56, 0x8b, 0x6d, 0xc4, 0x43, 0x9b, 0xab, 0x04,
+	0xf1, 0x09, 0x2c, 0xd3, 0xa4, 0x38, 0xc4, 0xf6, 0x18, 0x9d, 0x31, 0x0c, 0x66, 0x67, 0x96, 0xbb,
+	0x45, 0x1f, 0xda, 0x97, 0x90, 0xec, 0x12, 0xd2, 0xfd, 0x7c, 0xdf, 0xc7, 0xc0, 0x00, 0xa8, 0x11,
+	0x3f, 0xa5, 0xc9, 0xb2, 0xf9, 0xf6, 0xb0, 0x1f, 0xfe, 0x1c, 0x5c, 0x6c, 0x8d, 0x78, 0xa3, 0x5f,
+	0xe6, 0xaf, 0xa1, 0x11, 0x0a, 0xae, 0x73, 0xfd, 0x7a, 0x68, 0x84, 0xbc, 0x87, 0x56, 0x31, 0x72,
+	0x68, 0x3a, 0xd7, 0xaf, 0x86, 0xb2, 0xfd, 0x1d, 0x80, 0xa4, 0x11, 0x89, 0x26, 0x9e, 0xe7, 0xb0,
+	0x28, 0x66, 0x25, 0xe9, 0xb5, 0x02, 0xff, 0x0c, 0xb7, 0xb8, 0xdf, 0xdb, 0x27, 0x66, 0x31, 0x1d,
+	0xb3, 0x44, 0x9e, 0x33, 0xc6, 0x14, 0xda, 0xce, 0xf5, 0x8b, 0xe1, 0xe6, 0xe4, 0xde, 0x8e, 0xca,
+	0x3f, 0xc2, 0x3a, 0xa2, 0xe2, 0x8e, 0x23, 0x6b, 0x1e, 0x25, 0x85, 0xb3, 0x72, 0xf4, 0xea, 0x04,
+	0x37, 0x25, 0xc2, 0xdd, 0xc1, 0xff, 0xf0, 0x34, 0x8b, 0x69, 0x58, 0xd6, 0xa8, 0xc0, 0xf7, 0xca,
+	0xfc, 0x3d, 0x5c, 0x26, 0xa3, 0x51, 0x39, 0xff, 0xda, 0xf4, 0x1d, 0xce, 0x4b, 0x02, 0xc9, 0x68,
+	0x5b, 0xc9, 0xc7, 0xb2, 0xbc, 0xfe, 0xf2, 0x3f, 0x00, 0x09, 0x14, 0x11, 0x0c, 0x08, 0x01, 0x00,
 	0x00,
 }
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/interfaces"
+)
+
+const (
+	// defaultLinkMTU is the MTU of the node interconnect used if it is neither configured nor detected.
+	defaultLinkMTU = 1500
+
+	// vxlanOverhead is the size of the VXLAN encapsulation: outer IPv4 (20) + UDP (8) + VXLAN (8) + inner Ethernet (14).
+	vxlanOverhead = 50
+
+	// srv6Overhead is the size of the SRv6 encapsulation without segments: outer IPv6 (40) + SRH (8).
+	srv6Overhead = 48
+
+	// srv6SegmentOverhead is the size of one segment in the SRH.
+	srv6SegmentOverhead = 16
+
+	// ipsecOverhead is the max. size of the ESP transport mode with AES-CBC-128 and SHA-256-128:
+	// ESP header (8) + IV (16) + padding (up to 15) + trailer (2) + ICV (16).
+	ipsecOverhead = 57
+)
+
+// configureMTU determines the MTU of the interfaces connecting the pods and the host stack with VPP.
+// It is the MTU of the node interconnect (MTUSize from the config or the link MTU of the main physical
+// interface detected in VPP) lowered by the overhead of the encapsulation between the nodes, so that
+// the encapsulated packets fit into the node interconnect without fragmentation.
+func (s *remoteCNIserver) configureMTU() {
+	linkMTU := s.linkMTU
+	if linkMTU == 0 {
+		linkMTU = s.detectLinkMTU()
+	}
+	overhead := s.encapOverhead()
+	if overhead >= linkMTU {
+		s.Logger.Warnf("MTU %d of the node interconnect is too low for the %s inter-node transport",
+			linkMTU, s.interNodeTransport())
+		return
+	}
+	s.podMTU = linkMTU - overhead
+	s.Logger.Infof("MTU of the node interconnect is %d, pod interfaces use MTU %d", linkMTU, s.podMTU)
+}
+
+// detectLinkMTU returns the link MTU of the main physical interface as reported by VPP, or the default MTU
+// if it can not be determined (e.g. loopback is used instead of the physical interface).
+func (s *remoteCNIserver) detectLinkMTU() uint32 {
+	if s.mainPhysicalIf == "" {
+		return defaultLinkMTU
+	}
+	req := &interfaces.SwInterfaceDump{
+		NameFilterValid: 1,
+		NameFilter:      []byte(s.mainPhysicalIf),
+	}
+	reqCtx := s.govppChan.SendMultiRequest(req)
+	var linkMTU uint32
+	for {
+		details := &interfaces.SwInterfaceDetails{}
+		stop, err := reqCtx.ReceiveReply(details)
+		if stop {
+			break
+		}
+		if err != nil {
+			s.Logger.Warnf("Unable to detect MTU of %s: %v", s.mainPhysicalIf, err)
+			return defaultLinkMTU
+		}
+		linkMTU = uint32(details.LinkMtu)
+	}
+	if linkMTU == 0 {
+		return defaultLinkMTU
+	}
+	return linkMTU
+}
+
+// encapOverhead returns the overhead of the encapsulation of the traffic between the nodes.
+func (s *remoteCNIserver) encapOverhead() uint32 {
+	var overhead uint32
+	switch {
+	case s.useSRv6:
+		overhead = srv6Overhead + srv6SegmentOverhead*uint32(len(s.srv6Waypoints)+1)
+	case !s.useL2Interconnect:
+		overhead = vxlanOverhead
+	}
+	if s.useIPSec {
+		overhead += ipsecOverhead
+	}
+	return overhead
+}
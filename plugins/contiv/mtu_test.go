@@ -0,0 +1,67 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestMTU(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	// link MTU not reported by VPP, the default is used
+	server, _, _, conn := setupTestCNIServer(&configTapVxlanTCP, &nodeConfig)
+	defer conn.Disconnect()
+	err := server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.podMTU).To(gomega.BeEquivalentTo(defaultLinkMTU - vxlanOverhead))
+	gomega.Expect(server.interconnectVethHost().Mtu).To(gomega.BeEquivalentTo(defaultLinkMTU - vxlanOverhead))
+
+	// overhead of the inter-node transports
+	gomega.Expect(server.selectInterNodeTransport(interNodeTransportNoOverlay)).To(gomega.Succeed())
+	gomega.Expect(server.encapOverhead()).To(gomega.BeEquivalentTo(0))
+	gomega.Expect(server.selectInterNodeTransport(interNodeTransportSRv6)).To(gomega.Succeed())
+	gomega.Expect(server.initSRv6(SRv6Config{Waypoints: []string{"fd00:100::1"}})).To(gomega.Succeed())
+	gomega.Expect(server.encapOverhead()).To(gomega.BeEquivalentTo(srv6Overhead + 2*srv6SegmentOverhead))
+	gomega.Expect(server.selectInterNodeTransport(interNodeTransportVXLAN)).To(gomega.Succeed())
+	server.useIPSec = true
+	gomega.Expect(server.encapOverhead()).To(gomega.BeEquivalentTo(vxlanOverhead + ipsecOverhead))
+
+	// configured MTU of the node interconnect is applied to the pods and returned in the CNI reply
+	config := configVethL2NoTCP
+	config.MTUSize = 9000
+	server, _, _, conn = setupTestCNIServer(&config, nil)
+	defer conn.Disconnect()
+	server.configureMTU()
+	gomega.Expect(server.podMTU).To(gomega.BeEquivalentTo(9000))
+	server.setVswitchConfigured()
+
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Interfaces).NotTo(gomega.BeEmpty())
+	for _, iface := range reply.Interfaces {
+		gomega.Expect(iface.Mtu).To(gomega.BeEquivalentTo(9000))
+	}
+
+	// MTU lower than the overhead is not applied
+	server.linkMTU = 40
+	server.podMTU = 0
+	server.useL2Interconnect = false
+	server.configureMTU()
+	gomega.Expect(server.podMTU).To(gomega.BeEquivalentTo(0))
+}
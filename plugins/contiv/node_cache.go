@@ -0,0 +1,346 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+)
+
+// nodeCache is an in-memory cache of the node ID allocations and of the names of the nodes
+// reflected from k8s, fed by the watch of the plugin. Besides the ID, the allocations
+// are indexed by the node name and by the pod network of the node.
+//
+// nodeCache implements NodeIDStore on top of another store. Writes go through to the underlying
+// store and update the cache, reads are served from the cache once it was filled by the first resync
+// of the watch. Until then (i.e. during the node ID allocation at startup) reads go to the underlying
+// store.
+type nodeCache struct {
+	sync.RWMutex
+	store  NodeIDStore
+	synced bool
+
+	byID     map[uint32]*node.NodeInfo
+	byName   map[string]*node.NodeInfo
+	bySubnet map[string]*node.NodeInfo // pod network (CIDR) -> entry
+	// prefix lengths of the indexed pod networks with the number of networks using them
+	prefixLens map[int]int
+	k8sNodes   map[string]struct{}
+
+	// podNetwork returns the pod network of the node, nil if not known
+	podNetwork func(entry *node.NodeInfo) *net.IPNet
+}
+
+// newNodeCache creates new instance of nodeCache reading from the given store until resynced.
+func newNodeCache(store NodeIDStore) *nodeCache {
+	return &nodeCache{
+		store:      store,
+		byID:       map[uint32]*node.NodeInfo{},
+		byName:     map[string]*node.NodeInfo{},
+		bySubnet:   map[string]*node.NodeInfo{},
+		prefixLens: map[int]int{},
+		k8sNodes:   map[string]struct{}{},
+		podNetwork: explicitPodNetwork,
+	}
+}
+
+// explicitPodNetwork returns the pod network stored in the entry, nil if not set.
+func explicitPodNetwork(entry *node.NodeInfo) *net.IPNet {
+	if entry.PodNetwork == "" {
+		return nil
+	}
+	_, podNetwork, err := net.ParseCIDR(entry.PodNetwork)
+	if err != nil {
+		return nil
+	}
+	return podNetwork
+}
+
+// setPodNetwork sets the function returning the pod networks of the nodes and re-indexes
+// the cached entries.
+func (c *nodeCache) setPodNetwork(podNetwork func(entry *node.NodeInfo) *net.IPNet) {
+	c.Lock()
+	defer c.Unlock()
+	c.podNetwork = podNetwork
+	c.bySubnet = map[string]*node.NodeInfo{}
+	c.prefixLens = map[int]int{}
+	for _, entry := range c.byID {
+		c.indexSubnet(entry)
+	}
+}
+
+// resync replaces the content of the cache with the given entries and the names of the k8s nodes.
+func (c *nodeCache) resync(entries []*node.NodeInfo, k8sNodes []string) {
+	c.Lock()
+	defer c.Unlock()
+	c.byID = map[uint32]*node.NodeInfo{}
+	c.byName = map[string]*node.NodeInfo{}
+	c.bySubnet = map[string]*node.NodeInfo{}
+	c.prefixLens = map[int]int{}
+	for _, entry := range entries {
+		c.put(entry)
+	}
+	c.k8sNodes = map[string]struct{}{}
+	for _, name := range k8sNodes {
+		c.k8sNodes[name] = struct{}{}
+	}
+	c.synced = true
+}
+
+// update applies the change of a node ID allocation or of a k8s node to the cache,
+// changes of other keys are ignored.
+func (c *nodeCache) update(changeEv datasync.ChangeEvent) error {
+	key := changeEv.GetKey()
+	switch {
+	case strings.HasPrefix(key, allocatedIDsKeyPrefix):
+		id, err := extractIndexFromKey(key)
+		if err != nil {
+			return err
+		}
+		if changeEv.GetChangeType() == datasync.Delete {
+			c.Lock()
+			c.delete(uint32(id))
+			c.Unlock()
+			return nil
+		}
+		entry := &node.NodeInfo{}
+		if err := changeEv.GetValue(entry); err != nil {
+			return err
+		}
+		c.Lock()
+		c.put(entry)
+		c.Unlock()
+
+	case strings.HasPrefix(key, nodemodel.KeyPrefix()):
+		name, err := nodemodel.ParseNodeFromKey(key)
+		if err != nil {
+			return err
+		}
+		c.Lock()
+		if changeEv.GetChangeType() == datasync.Delete {
+			delete(c.k8sNodes, name)
+		} else {
+			c.k8sNodes[name] = struct{}{}
+		}
+		c.Unlock()
+	}
+	return nil
+}
+
+// put adds or replaces the entry in all indexes. The cache has to be locked.
+func (c *nodeCache) put(entry *node.NodeInfo) {
+	c.delete(entry.Id)
+	entry = proto.Clone(entry).(*node.NodeInfo)
+	c.byID[entry.Id] = entry
+	c.byName[entry.Name] = entry
+	c.indexSubnet(entry)
+}
+
+// subnetOf returns the pod network of the node, nil if not known.
+func (c *nodeCache) subnetOf(entry *node.NodeInfo) *net.IPNet {
+	podNetwork := c.podNetwork(entry)
+	if podNetwork == nil || podNetwork.IP == nil || podNetwork.Mask == nil {
+		return nil
+	}
+	return podNetwork
+}
+
+// indexSubnet adds the entry into the index of the pod networks. The cache has to be locked.
+func (c *nodeCache) indexSubnet(entry *node.NodeInfo) {
+	podNetwork := c.subnetOf(entry)
+	if podNetwork == nil {
+		return
+	}
+	if _, indexed := c.bySubnet[podNetwork.String()]; !indexed {
+		prefixLen, _ := podNetwork.Mask.Size()
+		c.prefixLens[prefixLen]++
+	}
+	c.bySubnet[podNetwork.String()] = entry
+}
+
+// delete removes the entry of the given ID from all indexes. The cache has to be locked.
+func (c *nodeCache) delete(id uint32) {
+	entry, found := c.byID[id]
+	if !found {
+		return
+	}
+	delete(c.byID, id)
+	if c.byName[entry.Name] == entry {
+		delete(c.byName, entry.Name)
+	}
+	if podNetwork := c.subnetOf(entry); podNetwork != nil && c.bySubnet[podNetwork.String()] == entry {
+		delete(c.bySubnet, podNetwork.String())
+		prefixLen, _ := podNetwork.Mask.Size()
+		if c.prefixLens[prefixLen]--; c.prefixLens[prefixLen] == 0 {
+			delete(c.prefixLens, prefixLen)
+		}
+	}
+}
+
+// ListEntries returns all entries of the allocated IDs.
+func (c *nodeCache) ListEntries() ([]*node.NodeInfo, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if !c.synced {
+		return c.store.ListEntries()
+	}
+	entries := make([]*node.NodeInfo, 0, len(c.byID))
+	for _, entry := range c.byID {
+		entries = append(entries, proto.Clone(entry).(*node.NodeInfo))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Id < entries[j].Id })
+	return entries, nil
+}
+
+// GetEntry returns the entry of the given allocated ID.
+func (c *nodeCache) GetEntry(id uint32) (entry *node.NodeInfo, found bool, err error) {
+	c.RLock()
+	defer c.RUnlock()
+	if !c.synced {
+		return c.store.GetEntry(id)
+	}
+	if entry, found = c.byID[id]; !found {
+		return nil, false, nil
+	}
+	return proto.Clone(entry).(*node.NodeInfo), true, nil
+}
+
+// PutIfNotExists atomically stores the entry into the underlying store if the ID is not allocated yet.
+func (c *nodeCache) PutIfNotExists(entry *node.NodeInfo) (succeeded bool, err error) {
+	succeeded, err = c.store.PutIfNotExists(entry)
+	if succeeded {
+		c.Lock()
+		c.put(entry)
+		c.Unlock()
+	}
+	return succeeded, err
+}
+
+// Put creates or overwrites the entry in the underlying store and in the cache.
+func (c *nodeCache) Put(entry *node.NodeInfo, ttl time.Duration) error {
+	if err := c.store.Put(entry, ttl); err != nil {
+		return err
+	}
+	c.Lock()
+	c.put(entry)
+	c.Unlock()
+	return nil
+}
+
+// KeepAlive extends TTL of the entry in the underlying store.
+func (c *nodeCache) KeepAlive(id uint32) (found bool, err error) {
+	return c.store.KeepAlive(id)
+}
+
+// Delete removes the entry of the given ID from the underlying store and from the cache.
+func (c *nodeCache) Delete(id uint32) error {
+	if err := c.store.Delete(id); err != nil {
+		return err
+	}
+	c.Lock()
+	c.delete(id)
+	c.Unlock()
+	return nil
+}
+
+// DeleteIfAllocatedTo removes the entry of the given ID from the underlying store and
+// from the cache if the ID is still allocated to the given node.
+func (c *nodeCache) DeleteIfAllocatedTo(id uint32, nodeName string) (deleted bool, err error) {
+	deleted, err = c.store.DeleteIfAllocatedTo(id, nodeName)
+	if deleted {
+		c.Lock()
+		c.delete(id)
+		c.Unlock()
+	}
+	return deleted, err
+}
+
+// lookupName returns the entry of the ID allocated for the node with the given name.
+func (c *nodeCache) lookupName(name string) (entry *node.NodeInfo, found bool, err error) {
+	c.RLock()
+	defer c.RUnlock()
+	if !c.synced {
+		entries, err := c.store.ListEntries()
+		if err != nil {
+			return nil, false, err
+		}
+		for _, entry := range entries {
+			if entry.Name == name {
+				return entry, true, nil
+			}
+		}
+		return nil, false, nil
+	}
+	if entry, found = c.byName[name]; !found {
+		return nil, false, nil
+	}
+	return proto.Clone(entry).(*node.NodeInfo), true, nil
+}
+
+// lookupPodIP returns the entry of the node whose pod network contains the given IP address.
+func (c *nodeCache) lookupPodIP(ip net.IP) (entry *node.NodeInfo, found bool, err error) {
+	c.RLock()
+	defer c.RUnlock()
+	if !c.synced {
+		entries, err := c.store.ListEntries()
+		if err != nil {
+			return nil, false, err
+		}
+		for _, entry := range entries {
+			if podNetwork := c.subnetOf(entry); podNetwork != nil && podNetwork.Contains(ip) {
+				return entry, true, nil
+			}
+		}
+		return nil, false, nil
+	}
+	bits := net.IPv6len * 8
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, net.IPv4len*8
+	}
+	for prefixLen := range c.prefixLens {
+		mask := net.CIDRMask(prefixLen, bits)
+		if mask == nil {
+			continue
+		}
+		network := &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+		if entry, found = c.bySubnet[network.String()]; found {
+			return proto.Clone(entry).(*node.NodeInfo), true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// listK8sNodeNames returns the names of the nodes reflected from k8s, synced is false
+// if the cache was not filled yet.
+func (c *nodeCache) listK8sNodeNames() (names []string, synced bool) {
+	c.RLock()
+	defer c.RUnlock()
+	if !c.synced {
+		return nil, false
+	}
+	for name := range c.k8sNodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, true
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"net"
+	"testing"
+
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/onsi/gomega"
+)
+
+func TestNodeCache(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := newMemIDStore()
+	store.Put(&node.NodeInfo{Id: 1, Name: "node1", PodNetwork: "10.1.1.0/24"}, 0)
+	cache := newNodeCache(store)
+
+	// reads go to the store until the cache is resynced
+	entry, found, err := cache.lookupName("node1")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(entry.Id).To(gomega.BeEquivalentTo(1))
+	_, synced := cache.listK8sNodeNames()
+	gomega.Expect(synced).To(gomega.BeFalse())
+
+	cache.resync([]*node.NodeInfo{
+		{Id: 1, Name: "node1", PodNetwork: "10.1.1.0/24"},
+		{Id: 2, Name: "node2"},
+	}, []string{"node2", "node1"})
+	names, synced := cache.listK8sNodeNames()
+	gomega.Expect(synced).To(gomega.BeTrue())
+	gomega.Expect(names).To(gomega.Equal([]string{"node1", "node2"}))
+
+	// the cache is no longer read through, node2 is not in the store
+	entry, found, err = cache.GetEntry(2)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(entry.Name).To(gomega.Equal("node2"))
+
+	// index by subnet, only explicit pod networks are known by default
+	entry, found, _ = cache.lookupPodIP(net.ParseIP("10.1.1.5"))
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(entry.Id).To(gomega.BeEquivalentTo(1))
+	_, found, _ = cache.lookupPodIP(net.ParseIP("10.1.2.5"))
+	gomega.Expect(found).To(gomega.BeFalse())
+
+	cache.setPodNetwork(func(entry *node.NodeInfo) *net.IPNet {
+		if podNetwork := explicitPodNetwork(entry); podNetwork != nil {
+			return podNetwork
+		}
+		return &net.IPNet{IP: net.IPv4(10, 1, byte(entry.Id), 0).To4(), Mask: net.CIDRMask(24, 32)}
+	})
+	entry, found, _ = cache.lookupPodIP(net.ParseIP("10.1.2.5"))
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(entry.Name).To(gomega.Equal("node2"))
+
+	// writes go through to the store
+	gomega.Expect(cache.Put(&node.NodeInfo{Id: 3, Name: "node3"}, 0)).To(gomega.Succeed())
+	_, found, _ = store.GetEntry(3)
+	gomega.Expect(found).To(gomega.BeTrue())
+	entry, found, _ = cache.lookupName("node3")
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(entry.Id).To(gomega.BeEquivalentTo(3))
+
+	gomega.Expect(cache.Delete(3)).To(gomega.Succeed())
+	_, found, _ = cache.lookupName("node3")
+	gomega.Expect(found).To(gomega.BeFalse())
+	_, found, _ = cache.lookupPodIP(net.ParseIP("10.1.3.5"))
+	gomega.Expect(found).To(gomega.BeFalse())
+
+	// changes from the watch
+	gomega.Expect(cache.update(&nodeAddDelEvent{evType: datasync.Put})).To(gomega.Succeed())
+	entry, found, _ = cache.lookupName(otherNodeInfo.Name)
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(entry.Id).To(gomega.Equal(otherNodeInfo.Id))
+	gomega.Expect(cache.update(&nodeAddDelEvent{evType: datasync.Delete})).To(gomega.Succeed())
+	_, found, _ = cache.GetEntry(otherNodeInfo.Id)
+	gomega.Expect(found).To(gomega.BeFalse())
+
+	gomega.Expect(cache.update(&k8sNodeEvent{evType: datasync.Delete, name: "node1"})).To(gomega.Succeed())
+	gomega.Expect(cache.update(&k8sNodeEvent{evType: datasync.Put, name: "node3"})).To(gomega.Succeed())
+	names, _ = cache.listK8sNodeNames()
+	gomega.Expect(names).To(gomega.Equal([]string{"node2", "node3"}))
+
+	entries, err := cache.ListEntries()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(entries).To(gomega.HaveLen(2))
+	gomega.Expect(entries[0].Id).To(gomega.BeEquivalentTo(1))
+	gomega.Expect(entries[1].Id).To(gomega.BeEquivalentTo(2))
+}
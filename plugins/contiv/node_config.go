@@ -0,0 +1,204 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/contiv/vpp/flavors/ksr"
+	"github.com/contiv/vpp/plugins/contiv/model/nodeconfig"
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/servicelabel"
+	vpp_intf "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	vpp_l3 "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
+)
+
+const (
+	// nodeConfigKeyPrefix is the prefix of the keys under which the operators store the configuration
+	// of individual nodes, overriding the NodeConfig entries of the config file.
+	nodeConfigKeyPrefix = "nodeconfig/"
+)
+
+// nodeConfigFromModel returns the node config given by the data store entry. The settings the entry does not
+// cover (bond, VLAN, uplinks) are taken over from the base config (from the config file, may be nil).
+func nodeConfigFromModel(model *nodeconfig.NodeConfig, base *OneNodeConfig) *OneNodeConfig {
+	nodeConfig := &OneNodeConfig{}
+	if base != nil {
+		*nodeConfig = *base
+	}
+	nodeConfig.NodeName = model.NodeName
+	nodeConfig.MainVppInterface = interfaceWithIPFromModel(model.MainVppInterface)
+	nodeConfig.OtherVPPInterfaces = nil
+	for _, intf := range model.OtherVppInterfaces {
+		nodeConfig.OtherVPPInterfaces = append(nodeConfig.OtherVPPInterfaces, interfaceWithIPFromModel(intf))
+	}
+	nodeConfig.Gateway = model.Gateway
+	return nodeConfig
+}
+
+func interfaceWithIPFromModel(model *nodeconfig.NodeConfig_InterfaceConfig) InterfaceWithIP {
+	if model == nil {
+		return InterfaceWithIP{}
+	}
+	return InterfaceWithIP{
+		InterfaceName: model.InterfaceName,
+		IP:            model.Ip,
+		UseDHCP:       model.UseDhcp,
+	}
+}
+
+// loadNodeConfigFromStore overrides the config of this node with the entry stored under nodeConfigKeyPrefix
+// of the KSR microservice, if there is any. Returns true if the entry was found.
+func (plugin *Plugin) loadNodeConfigFromStore() (bool, error) {
+	broker := plugin.KVStore.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel))
+	model := &nodeconfig.NodeConfig{}
+	found, _, err := broker.GetValue(nodeConfigKeyPrefix+plugin.ServiceLabel.GetAgentLabel(), model)
+	if err != nil {
+		return false, fmt.Errorf("Can't load configuration of the node from the data store: %v", err)
+	}
+	if found {
+		plugin.Log.Infof("Using configuration of the node from the data store: %v", model)
+		plugin.myNodeConfig = nodeConfigFromModel(model, plugin.myNodeConfig)
+	}
+	return found, nil
+}
+
+// handleNodeConfigChange applies the change of the data store entry with the config of this node. Once the entry
+// is removed, the config from the config file applies again. Entries of other nodes are ignored.
+// The method must be called with acquired mutex guarding remoteCNI server.
+func (s *remoteCNIserver) handleNodeConfigChange(key string, value datasync.LazyValue) error {
+	if strings.TrimPrefix(key, nodeConfigKeyPrefix) != s.agentLabel {
+		return nil
+	}
+	nodeConfig := s.fileNodeConfig
+	s.nodeConfigStored = value != nil
+	if value != nil {
+		model := &nodeconfig.NodeConfig{}
+		if err := value.GetValue(model); err != nil {
+			return err
+		}
+		nodeConfig = nodeConfigFromModel(model, s.fileNodeConfig)
+	}
+	return s.applyNodeConfig(nodeConfig)
+}
+
+// applyNodeConfig re-configures the interfaces of the node and its default gateway to match the given node
+// config. The main VPP interface can not be replaced at runtime, the change of its name (or of the way
+// its IP is assigned) takes effect after the restart of the agent. The method must be called with acquired
+// mutex guarding remoteCNI server.
+func (s *remoteCNIserver) applyNodeConfig(nodeConfig *OneNodeConfig) error {
+	oldConfig := s.nodeConfig
+	if oldConfig == nil {
+		oldConfig = &OneNodeConfig{}
+	}
+	if nodeConfig == nil {
+		nodeConfig = &OneNodeConfig{}
+	}
+
+	mainIf := nodeConfig.MainVppInterface
+	if mainIf.InterfaceName != oldConfig.MainVppInterface.InterfaceName ||
+		mainIf.UseDHCP != oldConfig.MainVppInterface.UseDHCP {
+		s.Logger.Warnf("Change of the main VPP interface to %+v requires restart of the agent", mainIf)
+	}
+
+	txn := s.vppTxnFactory().Put()
+	delTxn := s.vppTxnFactory().Delete()
+	var removedKeys []string
+	putChanges := map[string]proto.Message{}
+
+	// static IP of the main interface
+	changeIP := s.mainPhysicalIf != "" && !oldConfig.MainVppInterface.UseDHCP && !mainIf.UseDHCP &&
+		mainIf.IP != "" && mainIf.IP != s.nodeIP
+	if changeIP {
+		nic := s.physicalInterface(s.mainPhysicalIf, mainIf.IP)
+		txn.VppInterface(nic)
+		putChanges[vpp_intf.InterfaceKey(nic.Name)] = nic
+	}
+
+	// default gateway
+	if s.mainPhysicalIf != "" && nodeConfig.Gateway != oldConfig.Gateway {
+		if oldConfig.Gateway != "" {
+			route := s.defaultRoute(oldConfig.Gateway, s.mainPhysicalIf)
+			delTxn.StaticRoute(route.VrfId, route.DstIpAddr, route.NextHopAddr)
+			removedKeys = append(removedKeys, vpp_l3.RouteKey(route.VrfId, route.DstIpAddr, route.NextHopAddr))
+		}
+		if nodeConfig.Gateway != "" {
+			route := s.defaultRoute(nodeConfig.Gateway, s.mainPhysicalIf)
+			txn.StaticRoute(route)
+			putChanges[vpp_l3.RouteKey(route.VrfId, route.DstIpAddr, route.NextHopAddr)] = route
+		}
+	}
+
+	// other interfaces
+	oldIfs := map[string]string{}
+	for _, intf := range oldConfig.OtherVPPInterfaces {
+		oldIfs[intf.InterfaceName] = intf.IP
+	}
+	newIfs := map[string]string{}
+	for _, intf := range nodeConfig.OtherVPPInterfaces {
+		newIfs[intf.InterfaceName] = intf.IP
+		if ip, configured := oldIfs[intf.InterfaceName]; configured && ip == intf.IP {
+			continue
+		}
+		if _, _, found := s.swIfIndex.LookupIdx(intf.InterfaceName); !found {
+			s.Logger.Warnf("Interface %s not found in VPP", intf.InterfaceName)
+			continue
+		}
+		nic := s.physicalInterface(intf.InterfaceName, intf.IP)
+		txn.VppInterface(nic)
+		putChanges[vpp_intf.InterfaceKey(nic.Name)] = nic
+		if _, configured := oldIfs[intf.InterfaceName]; !configured {
+			s.physicalIfs = append(s.physicalIfs, nic.Name)
+		}
+	}
+	for name := range oldIfs {
+		if _, configured := newIfs[name]; configured {
+			continue
+		}
+		delTxn.VppInterface(name)
+		removedKeys = append(removedKeys, vpp_intf.InterfaceKey(name))
+		for i, ifName := range s.physicalIfs {
+			if ifName == name {
+				s.physicalIfs = append(s.physicalIfs[:i], s.physicalIfs[i+1:]...)
+				break
+			}
+		}
+	}
+
+	if len(removedKeys) > 0 {
+		if err := delTxn.Send().ReceiveReply(); err != nil {
+			return fmt.Errorf("Can't remove configuration of the node: %v", err)
+		}
+	}
+	if len(putChanges) > 0 {
+		if err := txn.Send().ReceiveReply(); err != nil {
+			return fmt.Errorf("Can't apply configuration of the node: %v", err)
+		}
+	}
+	if len(removedKeys) > 0 || len(putChanges) > 0 {
+		if err := s.persistChanges(removedKeys, putChanges); err != nil {
+			s.Logger.Warnf("Unable to persist configuration of the node: %v", err)
+		}
+	}
+	s.nodeConfig = nodeConfig
+
+	if changeIP {
+		s.Logger.Infof("Node IP changed by the configuration of the node from %s to %s", s.nodeIP, mainIf.IP)
+		return s.changeNodeIP(mainIf.IP)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/contiv/vpp/plugins/contiv/model/nodeconfig"
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/onsi/gomega"
+)
+
+// nodeConfigEvent simulates change of the node config stored in the data store
+type nodeConfigEvent struct {
+	evType   datasync.PutDel
+	nodeName string
+	config   *nodeconfig.NodeConfig
+}
+
+func (e *nodeConfigEvent) Done(error) {}
+
+func (e nodeConfigEvent) GetChangeType() datasync.PutDel {
+	return e.evType
+}
+
+func (e nodeConfigEvent) GetKey() string {
+	return nodeConfigKeyPrefix + e.nodeName
+}
+
+func (e nodeConfigEvent) GetValue(value proto.Message) error {
+	proto.Merge(value, e.config)
+	return nil
+}
+
+func (e nodeConfigEvent) GetPrevValue(prevValue proto.Message) (prevValueExist bool, err error) {
+	return false, nil
+}
+
+func (e nodeConfigEvent) GetRevision() int64 {
+	return 0
+}
+
+func TestNodeConfigChange(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, txns, _, conn := setupTestCNIServer(&configTapVxlanTCP, &nodeConfig,
+		"GigabitEthernet0/0/0/1", "GigabitEthernet0/0/0/10", "GigabitEthernet0/0/0/11")
+	defer conn.Disconnect()
+	nodeIPs := make(chan string, 1)
+	server.WatchNodeIP(nodeIPs)
+
+	err := server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(<-nodeIPs).To(gomega.Equal("192.168.1.1/24"))
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// the config of other nodes is ignored
+	err = processNodeChange(server, &nodeConfigEvent{evType: datasync.Put, nodeName: "other-node",
+		config: &nodeconfig.NodeConfig{Gateway: "192.168.1.254"}})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "192.168.1.254")).To(gomega.BeEmpty())
+
+	// the config of this node changes the node IP, the default gateway and the other interfaces
+	err = processNodeChange(server, &nodeConfigEvent{evType: datasync.Put, nodeName: "testLabel",
+		config: &nodeconfig.NodeConfig{
+			NodeName: "testLabel",
+			MainVppInterface: &nodeconfig.NodeConfig_InterfaceConfig{
+				InterfaceName: "GigabitEthernet0/0/0/1",
+				Ip:            "192.168.1.2/24",
+			},
+			OtherVppInterfaces: []*nodeconfig.NodeConfig_InterfaceConfig{
+				{InterfaceName: "GigabitEthernet0/0/0/11", Ip: "192.168.2.11/24"},
+			},
+			Gateway: "192.168.1.254",
+		}})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(<-nodeIPs).To(gomega.Equal("192.168.1.2/24"))
+	mainIf := interfaceInSnapshot(txns.AppliedConfig, "GigabitEthernet0/0/0/1")
+	gomega.Expect(mainIf.IpAddresses).To(gomega.ConsistOf("192.168.1.2/24"))
+	vxlanName := fmt.Sprintf("vxlan%d", otherNodeInfo.Id)
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, vxlanName).Vxlan.SrcAddress).To(gomega.Equal("192.168.1.2"))
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "192.168.1.254")).To(gomega.HaveLen(1))
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, "GigabitEthernet0/0/0/10")).To(gomega.BeNil())
+	otherIf := interfaceInSnapshot(txns.AppliedConfig, "GigabitEthernet0/0/0/11")
+	gomega.Expect(otherIf).NotTo(gomega.BeNil())
+	gomega.Expect(otherIf.IpAddresses).To(gomega.ConsistOf("192.168.2.11/24"))
+
+	// removal of the entry restores the config from the config file
+	err = processNodeChange(server, &nodeConfigEvent{evType: datasync.Delete, nodeName: "testLabel"})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.GetNodeIP().String()).To(gomega.Equal("192.168.1.1"))
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, "192.168.1.254")).To(gomega.BeEmpty())
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, "GigabitEthernet0/0/0/10")).NotTo(gomega.BeNil())
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, "GigabitEthernet0/0/0/11")).To(gomega.BeNil())
+}
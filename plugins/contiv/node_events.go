@@ -22,6 +22,7 @@ import (
 	"net"
 
 	"github.com/contiv/vpp/plugins/contiv/model/node"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	"github.com/golang/protobuf/proto"
 	"github.com/ligato/cn-infra/datasync"
 	vpp_l2 "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
@@ -35,13 +36,19 @@ func (s *remoteCNIserver) handleNodeEvents(ctx context.Context, resyncChan chan
 		select {
 
 		case resyncEv := <-resyncChan:
-			// resync needs to return done immediately, to not block resync of the remote cni server
-			go s.nodeResync(resyncEv)
+			// the values are read (and the node cache is resynced) before any subsequent change
+			// is queued, resync needs to return done immediately though, to not block resync
+			// of the remote cni server - the nodes are configured once the vswitch is configured
+			nodes, err := s.readNodeResync(resyncEv)
+			if err != nil {
+				s.Logger.Error(err)
+			} else {
+				s.loop.push("resync of nodes", nodePriority, func() error { return s.nodeResync(nodes) }, nil)
+			}
 			resyncEv.Done(nil)
 
 		case changeEv := <-changeChan:
-			err := s.nodeChangePropageteEvent(changeEv)
-			changeEv.Done(err)
+			s.queueNodeChange(changeEv)
 
 		case <-ctx.Done():
 			return
@@ -49,103 +56,243 @@ func (s *remoteCNIserver) handleNodeEvents(ctx context.Context, resyncChan chan
 	}
 }
 
-// nodeResync processes all nodes data and configures vswitch (routes to the other nodes) accordingly.
-func (s *remoteCNIserver) nodeResync(dataResyncEv datasync.ResyncEvent) error {
-
-	// do not handle other nodes until the base vswitch config is successfully applied
-	s.Lock()
-	for !s.vswitchConnectivityConfigured {
-		s.vswitchCond.Wait()
+// queueNodeChange updates the node cache and queues the change of a node into the event loop, the change
+// is applied once the base vswitch config is applied. The result is reported by Done of the event.
+func (s *remoteCNIserver) queueNodeChange(changeEv datasync.ChangeEvent) {
+	if s.nodeCache != nil {
+		if err := s.nodeCache.update(changeEv); err != nil {
+			s.Logger.Error(err)
+		}
 	}
-	defer s.Unlock()
-
-	// TODO: implement proper resync (handle deleted routes as well)
+	s.loop.push("change of "+changeEv.GetKey(), nodePriority,
+		func() error { return s.applyNodeChange(changeEv) }, changeEv.Done)
+}
 
-	var err error
-	data := dataResyncEv.GetValues()
+// nodeResyncData is the state of the nodes read from the resync event.
+type nodeResyncData struct {
+	otherNodes map[uint32]*node.NodeInfo
+	k8sNodes   map[string]*nodemodel.Node
+	nodeConfig datasync.KeyVal // nil if there is no config of this node
+}
 
-	for prefix, it := range data {
-		if prefix == allocatedIDsKeyPrefix {
-			for {
-				kv, stop := it.GetNext()
-				if stop {
-					break
-				}
+// readNodeResync reads all nodes data from the resync event and resyncs the node cache.
+func (s *remoteCNIserver) readNodeResync(dataResyncEv datasync.ResyncEvent) (*nodeResyncData, error) {
+	var allNodes []*node.NodeInfo
+	otherNodes := map[uint32]*node.NodeInfo{}
+	k8sNodes := map[string]*nodemodel.Node{}
+	var nodeConfig datasync.KeyVal
+	for prefix, it := range dataResyncEv.GetValues() {
+		for {
+			kv, stop := it.GetNext()
+			if stop {
+				break
+			}
+			switch prefix {
+			case allocatedIDsKeyPrefix:
 				nodeInfo := &node.NodeInfo{}
-				err = kv.GetValue(nodeInfo)
+				err := kv.GetValue(nodeInfo)
 				if err != nil {
-					return err
+					return nil, err
 				}
-
-				nodeID := uint8(nodeInfo.Id)
-
-				if nodeID != s.ipam.NodeID() {
-					s.Logger.Info("Other node discovered: ", nodeID)
-					if nodeInfo.IpAddress != "" {
-						// add routes to the node
-						err = s.addRoutesToNode(nodeInfo)
-					} else {
-						s.Logger.Infof("Ip address of node %v is not known yet.")
-					}
+				allNodes = append(allNodes, nodeInfo)
+				if nodeInfo.Id != uint32(s.nodeID) {
+					otherNodes[nodeInfo.Id] = nodeInfo
+				}
+			case nodemodel.KeyPrefix():
+				k8sNode := &nodemodel.Node{}
+				err := kv.GetValue(k8sNode)
+				if err != nil {
+					return nil, err
+				}
+				k8sNodes[k8sNode.Name] = k8sNode
+			case nodeConfigKeyPrefix:
+				if kv.GetKey() == nodeConfigKeyPrefix+s.agentLabel {
+					nodeConfig = kv
 				}
 			}
 		}
 	}
 
-	return err
+	if s.nodeCache != nil {
+		var k8sNodeNames []string
+		for name := range k8sNodes {
+			k8sNodeNames = append(k8sNodeNames, name)
+		}
+		s.nodeCache.resync(allNodes, k8sNodeNames)
+	}
+	return &nodeResyncData{otherNodes: otherNodes, k8sNodes: k8sNodes, nodeConfig: nodeConfig}, nil
 }
 
-// nodeChangePropageteEvent handles change in nodes within the k8s cluster (node add / delete)
-// and configures vswitch (routes to the other nodes) accordingly.
-func (s *remoteCNIserver) nodeChangePropageteEvent(dataChngEv datasync.ChangeEvent) error {
+// nodeResync processes all nodes data and configures vswitch (routes to the other nodes) accordingly.
+// The method is run by the event loop.
+func (s *remoteCNIserver) nodeResync(nodes *nodeResyncData) error {
+	s.otherNodes = nodes.otherNodes
+	s.k8sNodes = nodes.k8sNodes
+
+	// the config of the node changed while the agent was disconnected from the data store
+	if err := s.handleNodeConfigChange(nodeConfigKeyPrefix+s.agentLabel, nodes.nodeConfig); err != nil {
+		s.Logger.Error(err)
+	}
 
-	// do not handle other nodes until the base vswitch config is successfully applied
-	s.Lock()
-	for !s.vswitchConnectivityConfigured {
-		s.vswitchCond.Wait()
+	if s.useSRv6 {
+		if err := s.configureSRv6(); err != nil {
+			return err
+		}
 	}
-	defer s.Unlock()
+	if s.useIPSec {
+		if err := s.configureIPSec(); err != nil {
+			return err
+		}
+	}
+
+	// routes to the nodes removed meanwhile are deleted as well
+	return s.syncRoutesToNodes()
+}
 
+// applyNodeChange applies the change of a node, the method is run by the event loop.
+func (s *remoteCNIserver) applyNodeChange(dataChngEv datasync.ChangeEvent) error {
 	key := dataChngEv.GetKey()
-	var err error
 
 	if strings.HasPrefix(key, allocatedIDsKeyPrefix) {
-		nodeInfo := &node.NodeInfo{}
-		err = dataChngEv.GetValue(nodeInfo)
+		id, err := extractIndexFromKey(key)
 		if err != nil {
 			return err
 		}
+		nodeID := uint32(id)
 
 		// skip nodeInfo of this node
-		if nodeInfo.Id == uint32(s.nodeID) {
+		if nodeID == uint32(s.nodeID) {
 			return nil
 		}
 
 		if dataChngEv.GetChangeType() == datasync.Put {
+			nodeInfo := &node.NodeInfo{}
+			err = dataChngEv.GetValue(nodeInfo)
+			if err != nil {
+				return err
+			}
+			s.otherNodes[nodeID] = nodeInfo
+		} else {
+			delete(s.otherNodes, nodeID)
+		}
+		return s.syncRoutesToNode(nodeID)
+	}
 
-			// Note: the case where IP address is changed during runtime is not handled
-			if nodeInfo.IpAddress != "" {
-				s.Logger.Info("New node discovered: ", nodeInfo.Id)
-				// add routes to the node
-				err = s.addRoutesToNode(nodeInfo)
-			} else {
-				s.Logger.Infof("Ip address of node %v is not known yet.")
+	if strings.HasPrefix(key, nodemodel.KeyPrefix()) {
+		name, err := nodemodel.ParseNodeFromKey(key)
+		if err != nil {
+			return err
+		}
+
+		if dataChngEv.GetChangeType() == datasync.Put {
+			k8sNode := &nodemodel.Node{}
+			err = dataChngEv.GetValue(k8sNode)
+			if err != nil {
+				return err
 			}
+			s.k8sNodes[name] = k8sNode
 		} else {
-			s.Logger.Info("Node removed: ", nodeInfo.Id)
+			delete(s.k8sNodes, name)
+			// the ID of the node is released by the leader, the routes to the node are removed below
+			if s.k8sNodeDeleted != nil && name != s.agentLabel {
+				s.k8sNodeDeleted(name)
+			}
+		}
+		// the first reflected k8s node may affect the routes to all nodes (see isK8sNode)
+		return s.syncRoutesToNodes()
+	}
 
-			// delete routes to the node
-			err = s.deleteRoutesToNode(nodeInfo)
+	if strings.HasPrefix(key, nodeConfigKeyPrefix) {
+		if dataChngEv.GetChangeType() == datasync.Put {
+			return s.handleNodeConfigChange(key, dataChngEv)
 		}
-	} else {
-		return fmt.Errorf("Unknown key %v", key)
+		return s.handleNodeConfigChange(key, nil)
+	}
+
+	return fmt.Errorf("Unknown key %v", key)
+}
+
+// syncRoutesToNodes updates routes to all other nodes.
+func (s *remoteCNIserver) syncRoutesToNodes() error {
+	ids := map[uint32]struct{}{}
+	for id := range s.otherNodes {
+		ids[id] = struct{}{}
+	}
+	for id := range s.routedNodes {
+		ids[id] = struct{}{}
+	}
+
+	var wasErr error
+	for id := range ids {
+		if err := s.syncRoutesToNode(id); err != nil {
+			s.Logger.Error(err)
+			wasErr = err
+		}
+	}
+	return wasErr
+}
+
+// syncRoutesToNode adds, updates or removes routes to the other node with the given ID,
+// so that the node is routed if it has an allocated ID with a known IP address and it is
+// present in the k8s cluster.
+func (s *remoteCNIserver) syncRoutesToNode(id uint32) error {
+	nodeInfo, allocated := s.otherNodes[id]
+	routed, isRouted := s.routedNodes[id]
+
+	wanted := allocated && nodeInfo.IpAddress != "" && s.isK8sNode(nodeInfo.Name)
+	if isRouted && wanted && sameNodeRoutes(routed, nodeInfo) {
+		// e.g. refresh of the node ID lease, the node is already known
+		return nil
+	}
+
+	if isRouted {
+		s.Logger.Info("Node removed: ", id)
+		err := s.deleteRoutesToNode(routed)
+		if err != nil {
+			return err
+		}
+		delete(s.routedNodes, id)
+	}
+
+	if !wanted {
+		if allocated && nodeInfo.IpAddress == "" {
+			s.Logger.Infof("Ip address of node %v is not known yet.", id)
+		} else if allocated {
+			s.Logger.Debugf("Node %v (%s) is not present in the k8s cluster.", id, nodeInfo.Name)
+		}
+		return nil
+	}
+
+	s.Logger.Info("New node discovered: ", id)
+	err := s.addRoutesToNode(nodeInfo)
+	if err != nil {
+		return err
 	}
+	s.routedNodes[id] = nodeInfo
+	return nil
+}
 
-	return err
+// isK8sNode returns true if the node of the given name is reflected from k8s by KSR.
+// Until KSR reflects any node (e.g. older KSR version is deployed), all nodes with
+// an allocated ID are considered to be present.
+func (s *remoteCNIserver) isK8sNode(name string) bool {
+	if len(s.k8sNodes) == 0 {
+		return true
+	}
+	_, found := s.k8sNodes[name]
+	return found
+}
+
+// sameNodeRoutes returns true if the routes to the node described by the given entries are the same.
+func sameNodeRoutes(a, b *node.NodeInfo) bool {
+	return a.Id == b.Id && a.IpAddress == b.IpAddress && a.PodNetwork == b.PodNetwork
 }
 
 // addRoutesToNode add routes to the node specified by nodeID.
 func (s *remoteCNIserver) addRoutesToNode(nodeInfo *node.NodeInfo) error {
+	if s.useSRv6 {
+		return s.addSRv6PolicyToNode(nodeInfo)
+	}
 
 	txn := s.vppTxnFactory().Put()
 	hostIP := s.otherHostIP(uint8(nodeInfo.Id), nodeInfo.IpAddress)
@@ -175,14 +322,19 @@ func (s *remoteCNIserver) addRoutesToNode(nodeInfo *node.NodeInfo) error {
 	)
 	if s.useL2Interconnect {
 		// static route directly to other node IP
-		podsRoute, hostRoute, err = s.computeRoutesToHost(uint8(nodeInfo.Id), hostIP)
+		podsRoute, hostRoute, err = s.computeRoutesToHost(uint8(nodeInfo.Id), nodeInfo.PodNetwork, hostIP)
 	} else {
 		// static route to other node VXLAN BVI
 		vxlanNextHop, err = s.ipam.VxlanIPAddress(uint8(nodeInfo.Id))
 		if err != nil {
 			return err
 		}
-		podsRoute, hostRoute, err = s.computeRoutesToHost(uint8(nodeInfo.Id), vxlanNextHop.String())
+		podsRoute, hostRoute, err = s.computeRoutesToHost(uint8(nodeInfo.Id), nodeInfo.PodNetwork, vxlanNextHop.String())
+		if err == nil {
+			// static ARP and L2 FIB entries of the next hop avoid flooding across all VXLAN tunnels
+			txn.Arp(s.arpToOtherHostBVI(uint8(nodeInfo.Id), vxlanNextHop))
+			txn.BDFIB(s.fibToOtherHostBVI(uint8(nodeInfo.Id), vxlanIfName(uint8(nodeInfo.Id))))
+		}
 	}
 	if err != nil {
 		return err
@@ -192,27 +344,120 @@ func (s *remoteCNIserver) addRoutesToNode(nodeInfo *node.NodeInfo) error {
 	s.Logger.Info("Adding PODs route: ", podsRoute)
 	s.Logger.Info("Adding host route: ", hostRoute)
 
+	// routes to the namespace IP pools of the node use the same next hop as the PODs route
+	poolRoutes, err := s.routesToOtherHostPools(uint8(nodeInfo.Id), podsRoute.NextHopAddr)
+	if err != nil {
+		return err
+	}
+	for _, poolRoute := range poolRoutes {
+		txn.StaticRoute(poolRoute)
+		s.Logger.Info("Adding IP pool route: ", poolRoute)
+	}
+
+	// IPv6 pods are reachable only via VXLAN
+	if !s.useL2Interconnect && s.ipam.IPv6Enabled() {
+		podsRouteIPv6, err := s.routeToOtherHostPodsIPv6(uint8(nodeInfo.Id))
+		if err != nil {
+			return err
+		}
+		txn.StaticRoute(podsRouteIPv6)
+		s.Logger.Info("Adding IPv6 PODs route: ", podsRouteIPv6)
+	}
+
+	// ECMP paths to the IP of the node via the uplinks
+	for _, uplinkRoute := range s.uplinkRoutesToNode(hostIP) {
+		txn.StaticRoute(uplinkRoute)
+		s.Logger.Info("Adding uplink route: ", uplinkRoute)
+	}
+
 	// send the config transaction
 	err = txn.Send().ReceiveReply()
 	if err != nil {
 		return fmt.Errorf("Can't configure VPP to add routes to node %v: %v ", nodeInfo.Id, err)
 	}
+	if s.useIPSec {
+		return s.addIPSecToNode(nodeInfo)
+	}
 	return nil
 }
 
 // deleteRoutesToNode delete routes to the node specified by nodeID.
 func (s *remoteCNIserver) deleteRoutesToNode(nodeInfo *node.NodeInfo) error {
-	podsRoute, hostRoute, err := s.computeRoutesToHost(uint8(nodeInfo.Id), nodeInfo.IpAddress)
+	if s.useSRv6 {
+		return s.deleteSRv6PolicyToNode(nodeInfo)
+	}
+	if s.useIPSec {
+		if err := s.deleteIPSecToNode(nodeInfo); err != nil {
+			return err
+		}
+	}
+	// the next hop is determined the same way as in addRoutesToNode
+	hostIP := s.otherHostIP(uint8(nodeInfo.Id), nodeInfo.IpAddress)
+	nextHop := hostIP
+	if !s.useL2Interconnect {
+		vxlanNextHop, err := s.ipam.VxlanIPAddress(uint8(nodeInfo.Id))
+		if err != nil {
+			return err
+		}
+		nextHop = vxlanNextHop.String()
+	}
+	podsRoute, hostRoute, err := s.computeRoutesToHost(uint8(nodeInfo.Id), nodeInfo.PodNetwork, nextHop)
 	if err != nil {
 		return err
 	}
 	s.Logger.Info("Deleting PODs route: ", podsRoute)
 	s.Logger.Info("Deleting host route: ", hostRoute)
 
-	err = s.vppTxnFactory().Delete().
+	txn := s.vppTxnFactory().Delete().
 		StaticRoute(podsRoute.VrfId, podsRoute.DstIpAddr, podsRoute.NextHopAddr).
-		StaticRoute(hostRoute.VrfId, hostRoute.DstIpAddr, hostRoute.NextHopAddr).
-		Send().ReceiveReply()
+		StaticRoute(hostRoute.VrfId, hostRoute.DstIpAddr, hostRoute.NextHopAddr)
+
+	poolRoutes, err := s.routesToOtherHostPools(uint8(nodeInfo.Id), podsRoute.NextHopAddr)
+	if err != nil {
+		return err
+	}
+	for _, poolRoute := range poolRoutes {
+		s.Logger.Info("Deleting IP pool route: ", poolRoute)
+		txn.StaticRoute(poolRoute.VrfId, poolRoute.DstIpAddr, poolRoute.NextHopAddr)
+	}
+
+	if !s.useL2Interconnect {
+		vxlanNextHop, err := s.ipam.VxlanIPAddress(uint8(nodeInfo.Id))
+		if err != nil {
+			return err
+		}
+		txn.Arp(s.vxlanBVIIfName, vxlanNextHop.String())
+		txn.BDFIB(s.vxlanBD.Name, hwAddrForNodeVXLAN(uint8(nodeInfo.Id)))
+
+		// VXLAN tunnel, removed from the bridge domain below
+		txn.VppInterface(vxlanIfName(uint8(nodeInfo.Id)))
+	}
+
+	if !s.useL2Interconnect && s.ipam.IPv6Enabled() {
+		podsRouteIPv6, err := s.routeToOtherHostPodsIPv6(uint8(nodeInfo.Id))
+		if err != nil {
+			return err
+		}
+		s.Logger.Info("Deleting IPv6 PODs route: ", podsRouteIPv6)
+		txn.StaticRoute(podsRouteIPv6.VrfId, podsRouteIPv6.DstIpAddr, podsRouteIPv6.NextHopAddr)
+	}
+
+	for _, uplinkRoute := range s.uplinkRoutesToNode(hostIP) {
+		s.Logger.Info("Deleting uplink route: ", uplinkRoute)
+		txn.StaticRoute(uplinkRoute.VrfId, uplinkRoute.DstIpAddr, uplinkRoute.NextHopAddr)
+	}
+
+	if !s.useL2Interconnect {
+		// the remembered bridge domain is replaced only once the transaction succeeds
+		bd := proto.Clone(s.vxlanBD).(*vpp_l2.BridgeDomains_BridgeDomain)
+		s.removeInterfaceFromVxlanBD(bd, vxlanIfName(uint8(nodeInfo.Id)))
+		err = txn.Put().BD(proto.Clone(bd).(*vpp_l2.BridgeDomains_BridgeDomain)).Send().ReceiveReply()
+		if err == nil {
+			s.vxlanBD = bd
+		}
+	} else {
+		err = txn.Send().ReceiveReply()
+	}
 
 	if err != nil {
 		return fmt.Errorf("Can't configure vpp to remove route to host %v (and its pods): %v ", nodeInfo.Id, err)
@@ -15,55 +15,113 @@
 package contiv
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"hash/fnv"
+	"math"
 	"sort"
-	"strconv"
-	"strings"
 	"sync"
+	"time"
 
-	"github.com/contiv/vpp/flavors/ksr"
 	"github.com/contiv/vpp/plugins/contiv/model/node"
-	"github.com/ligato/cn-infra/db/keyval"
-	"github.com/ligato/cn-infra/db/keyval/etcdv3"
-	"github.com/ligato/cn-infra/servicelabel"
+	"github.com/contiv/vpp/plugins/kvstore"
+	"github.com/ligato/cn-infra/core"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/logging"
 )
 
 const (
 	allocatedIDsKeyPrefix = "allocatedIDs/"
 	maxAttempts           = 10
+
+	// defaultLeaseTTL is used when no TTL of the node ID lease is configured
+	defaultLeaseTTL = 60 * time.Second
+
+	// leaseRefreshRatio determines how many times the lease is refreshed within one TTL period
+	leaseRefreshRatio = 3
+
+	// maxNodeID is the highest node ID, IPAM computations use 8-bit IDs
+	maxNodeID = math.MaxUint8
 )
 
 var (
 	errInvalidKey         = fmt.Errorf("invalid key for nodeID")
 	errUnableToAllocateID = fmt.Errorf("unable to allocate unique id for node (max attempt limit reached)")
 	errNoIDallocated      = fmt.Errorf("there is no ID allocated for the node")
+	errIDRangeExhausted   = fmt.Errorf("all IDs from the range of the node are already allocated")
+	errNoManagementIP     = fmt.Errorf("management IP of the node is not known, unable to derive the node ID from it")
+)
+
+// Strategies of the node ID allocation (NodeIDDerivation in the config file).
+const (
+	// FirstFreeNodeID allocates the lowest ID not allocated by any other node (default).
+	FirstFreeNodeID = "first-free"
+	// NodeNameHashNodeID derives the ID from the hash of the node name.
+	NodeNameHashNodeID = "node-name"
+	// NodeIPHashNodeID derives the ID from the hash of the management IP address of the node.
+	NodeIPHashNodeID = "node-ip"
 )
 
 // idAllocator manages allocation/deallocation of unique number identifying a node in the k8s cluster.
 // Retrieved identifier is used as input of IPAM module for the node.
-// (AllocatedID is represented by an entry in ETCD. The process of allocation leverages etcd transaction
-// to atomically check if the key exists and if not, a new key-value pair representing
-// the allocation is inserted)
+// (AllocatedID is represented by an entry in the NodeIDStore (ETCD by default). The process of allocation
+// leverages atomic put-if-not-exists operation of the store to check if the ID is free and if so, a new entry
+// representing the allocation is inserted)
+//
+// The allocated entry is bound to a lease with the given TTL. The lease is periodically
+// kept alive by the allocator without rewriting the entry, therefore if the node dies without
+// releasing its ID, the entry expires and the ID becomes available for other nodes.
+//
+// The allocator also checks the changes of allocations made by other nodes/operators. If the entry of this node
+// is claimed by another node, an error is reported. If the entry is deleted externally, it is either reported
+// or restored (if restoreEntry is enabled). Note that a different ID can not be allocated at runtime, since
+// the whole IPAM of the node is derived from the ID.
 type idAllocator struct {
 	sync.Mutex
-	etcd   *etcdv3.Plugin
-	broker keyval.ProtoBroker
+	logger logging.Logger
+	store  NodeIDStore
 
 	allocated bool
 	ID        uint32
+	// Unix time of the allocation
+	allocationTimestamp int64
 
 	nodeName string
 	nodeIP   string
+	mgmtIP   string
+	// pod network assigned to the node by k8s, empty if computed by IPAM
+	podNetwork string
+
+	leaseTTL     time.Duration
+	restoreEntry bool
+
+	// range the ID is allocated from, nil if any ID can be allocated
+	idRange *NodeIDRange
+
+	// strategy used to choose a new ID
+	derivation string
+
+	// metrics exposed via Prometheus, nil if not exposed
+	metrics *metrics
 }
 
 // newIDAllocator creates new instance of idAllocator
-func newIDAllocator(etcd *etcdv3.Plugin, nodeName string, nodeIP string) *idAllocator {
+func newIDAllocator(logger logging.Logger, store NodeIDStore, nodeName string, nodeIP string, mgmtIP string,
+	podNetwork string, leaseTTL time.Duration, restoreEntry bool, idRange *NodeIDRange, derivation string) *idAllocator {
+	if leaseTTL == 0 {
+		leaseTTL = defaultLeaseTTL
+	}
 	return &idAllocator{
-		etcd:     etcd,
-		broker:   etcd.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel)),
-		nodeName: nodeName,
-		nodeIP:   nodeIP,
+		logger:       logger,
+		store:        store,
+		nodeName:     nodeName,
+		nodeIP:       nodeIP,
+		mgmtIP:       mgmtIP,
+		podNetwork:   podNetwork,
+		leaseTTL:     leaseTTL,
+		restoreEntry: restoreEntry,
+		idRange:      idRange,
+		derivation:   derivation,
 	}
 }
 
@@ -77,34 +135,58 @@ func (ia *idAllocator) getID() (id uint8, err error) {
 	}
 
 	// check if there is already assign ID for the serviceLabel
-	existingEntry, err := ia.findExistingEntry(ia.broker)
+	existingEntry, err := ia.findExistingEntry()
 	if err != nil {
 		return 0, err
 	}
 
 	if existingEntry != nil {
+		if !ia.idRange.contains(existingEntry.Id) {
+			ia.logger.Warnf("Existing ID %v of the node is out of the configured range %v-%v",
+				existingEntry.Id, ia.idRange.MinID, ia.idRange.MaxID)
+		}
 		ia.allocated = true
 		ia.ID = existingEntry.Id
+		ia.allocationTimestamp = existingEntry.AllocationTimestamp
+		// the entry might have been written by a previous run, bind it to a fresh lease
+		err = ia.putEntry()
+		if err == kvstore.ErrReadOnly {
+			// the entry is served from the local cache, the lease is refreshed once reconnected
+			ia.logger.Warnf("Unable to refresh the entry of the node ID %v, data store is not reachable", ia.ID)
+		} else if err != nil {
+			return 0, err
+		}
 		return uint8(ia.ID), nil
 	}
 
 	attempts := 0
 	for {
-		ids, err := listAllIDs(ia.broker)
+		ids, err := ia.listAllIDs()
 		if err != nil {
 			return 0, err
 		}
 		sort.Ints(ids)
 
 		attempts++
-		ia.ID = uint32(findFirstAvailableIndex(ids))
+		freeID, err := ia.selectFreeID(ids)
+		if err != nil {
+			return 0, err
+		}
+		ia.ID = uint32(freeID)
+		ia.allocationTimestamp = time.Now().Unix()
 
 		succ, err := ia.writeIfNotExists(ia.ID)
 		if err != nil {
 			return 0, err
 		}
+		ia.metrics.nodeIDAllocationAttempt(!succ)
 		if succ {
 			ia.allocated = true
+			// put-if-not-exists does not allow to attach the lease, the entry is rewritten with it
+			err = ia.putEntry()
+			if err != nil {
+				return 0, err
+			}
 			break
 		}
 
@@ -131,15 +213,108 @@ func (ia *idAllocator) updateIP(newIP string) error {
 
 	ia.nodeIP = newIP
 
-	value := &node.NodeInfo{
-		Id:        ia.ID,
-		Name:      ia.nodeName,
-		IpAddress: ia.nodeIP,
+	return ia.putEntry()
+}
+
+// refreshLease periodically refreshes the lease of the allocated ID until the context is cancelled.
+func (ia *idAllocator) refreshLease(ctx context.Context) {
+	ticker := time.NewTicker(ia.leaseTTL / leaseRefreshRatio)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := ia.refreshEntry()
+			if err != nil {
+				ia.logger.Errorf("Unable to refresh lease of the node ID: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
 	}
-	err = ia.broker.Put(createKey(ia.ID), value)
+}
 
-	return err
+// refreshEntry keeps the lease of the allocated ID alive. The entry is not rewritten,
+// therefore the entry of another node that allocated the ID in the meantime cannot be
+// overwritten.
+func (ia *idAllocator) refreshEntry() error {
+	ia.Lock()
+	defer ia.Unlock()
+
+	if !ia.allocated {
+		return nil
+	}
+
+	current, found, err := ia.store.GetEntry(ia.ID)
+	if err != nil {
+		return err
+	}
+	if found && current.Name != ia.nodeName {
+		return fmt.Errorf("ID %v is already allocated by node %v", ia.ID, current.Name)
+	}
+	if found {
+		found, err = ia.store.KeepAlive(ia.ID)
+		if err != nil || found {
+			return err
+		}
+	}
+
+	// The entry has expired (e.g. etcd was not reachable for longer than TTL),
+	// the ID is allocated again unless another node has allocated it in the meantime.
+	ia.logger.Warnf("Entry of the node ID %v has expired, allocating it again", ia.ID)
+	succ, err := ia.writeIfNotExists(ia.ID)
+	if err != nil {
+		return err
+	}
+	if !succ {
+		return fmt.Errorf("ID %v was allocated by another node after the entry expired", ia.ID)
+	}
+	return ia.putEntry()
+}
+
+// putEntry writes the entry of the allocated ID bound to a new lease.
+// The method must be called with acquired mutex.
+func (ia *idAllocator) putEntry() error {
+	return ia.store.Put(ia.newEntry(ia.ID), ia.leaseTTL)
+}
+
+// checkChange checks whether the change of allocated node IDs conflicts with the ID allocated for this node.
+func (ia *idAllocator) checkChange(changeEv datasync.ChangeEvent) {
+	id, err := extractIndexFromKey(changeEv.GetKey())
+	if err != nil {
+		ia.logger.Warnf("Unable to check change of the node ID %v: %v", changeEv.GetKey(), err)
+		return
+	}
+
+	ia.Lock()
+	defer ia.Unlock()
 
+	if !ia.allocated || uint32(id) != ia.ID {
+		return
+	}
+
+	if changeEv.GetChangeType() == datasync.Delete {
+		if !ia.restoreEntry {
+			ia.logger.Errorf("Entry of the node ID %v allocated for this node was deleted externally", ia.ID)
+			return
+		}
+		ia.logger.Warnf("Entry of the node ID %v allocated for this node was deleted externally, restoring it", ia.ID)
+		err = ia.putEntry()
+		if err != nil {
+			ia.logger.Errorf("Unable to restore entry of the node ID %v: %v", ia.ID, err)
+		}
+		return
+	}
+
+	entry := &node.NodeInfo{}
+	err = changeEv.GetValue(entry)
+	if err != nil {
+		ia.logger.Warnf("Unable to check change of the node ID %v: %v", ia.ID, err)
+		return
+	}
+	if entry.Name != ia.nodeName {
+		ia.logger.Errorf("Node ID %v allocated for this node was claimed by the node %v", ia.ID, entry.Name)
+	}
 }
 
 // releaseID returns allocated ID back to the pool
@@ -151,9 +326,11 @@ func (ia *idAllocator) releaseID() error {
 		return errNoIDallocated
 	}
 
-	_, err := ia.broker.Delete(createKey(ia.ID))
-	if err == nil {
-		ia.allocated = false
+	// unset the flag before the delete, so that the change is not considered as an external one
+	ia.allocated = false
+	err := ia.store.Delete(ia.ID)
+	if err != nil {
+		ia.allocated = true
 	}
 
 	return err
@@ -161,61 +338,110 @@ func (ia *idAllocator) releaseID() error {
 
 func (ia *idAllocator) writeIfNotExists(id uint32) (succeeded bool, err error) {
 
-	value := &node.NodeInfo{
-		Id:        id,
-		Name:      ia.nodeName,
-		IpAddress: ia.nodeIP,
+	return ia.store.PutIfNotExists(ia.newEntry(id))
+}
+
+// newEntry builds the entry of the given ID allocated for this node including the allocation metadata.
+func (ia *idAllocator) newEntry(id uint32) *node.NodeInfo {
+	return &node.NodeInfo{
+		Id:                  id,
+		Name:                ia.nodeName,
+		IpAddress:           ia.nodeIP,
+		AllocationTimestamp: ia.allocationTimestamp,
+		ManagementIp:        ia.mgmtIP,
+		AgentVersion:        core.BuildVersion,
+		PodNetwork:          ia.podNetwork,
 	}
+}
 
-	encoded, err := json.Marshal(value)
-	if err != nil {
-		return false, err
+// findExistingEntry lists all allocated entries and checks if the store contains ID assigned
+// to the serviceLabel. The node cache is looked up by the node name instead.
+func (ia *idAllocator) findExistingEntry() (id *node.NodeInfo, err error) {
+	if cache, isCache := ia.store.(*nodeCache); isCache {
+		entry, _, err := cache.lookupName(ia.nodeName)
+		return entry, err
 	}
 
-	succeeded, err = ia.etcd.PutIfNotExists(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel)+createKey(id), encoded)
+	entries, err := ia.store.ListEntries()
+	if err != nil {
+		return nil, err
+	}
 
-	return succeeded, err
+	for _, item := range entries {
+		if item.Name == ia.nodeName {
+			return item, nil
+		}
+	}
 
+	return nil, nil
 }
 
-// findExistingEntry lists all allocated entries and checks if the etcd contains ID assigned
-// to the serviceLabel
-func (ia *idAllocator) findExistingEntry(broker keyval.ProtoBroker) (id *node.NodeInfo, err error) {
-	var existingEntry *node.NodeInfo
-	it, err := broker.ListValues(allocatedIDsKeyPrefix)
-	if err != nil {
-		return nil, err
+// selectFreeID chooses an ID not present among the sorted allocated ids according to the configured strategy.
+func (ia *idAllocator) selectFreeID(ids []int) (int, error) {
+	minID, maxID := 1, 0
+	if ia.idRange != nil {
+		minID, maxID = int(ia.idRange.MinID), int(ia.idRange.MaxID)
 	}
 
-	for {
-		item := &node.NodeInfo{}
-		kv, stop := it.GetNext()
-
-		if stop {
-			break
+	switch ia.derivation {
+	case NodeNameHashNodeID, NodeIPHashNodeID:
+		if maxID == 0 {
+			maxID = maxNodeID
 		}
-
-		err := kv.GetValue(item)
-		if err != nil {
-			return nil, err
+		key := ia.nodeName
+		if ia.derivation == NodeIPHashNodeID {
+			if ia.mgmtIP == "" {
+				return 0, errNoManagementIP
+			}
+			key = ia.mgmtIP
 		}
-
-		if item.Name == ia.nodeName {
-			existingEntry = item
-			break
+		freeID, found := findAvailableIndexFrom(ids, deriveIndex(key, minID, maxID), minID, maxID)
+		if !found {
+			return 0, errIDRangeExhausted
+		}
+		return freeID, nil
+	default:
+		freeID := findFirstAvailableIndex(ids, minID)
+		if maxID > 0 && freeID > maxID {
+			return 0, errIDRangeExhausted
 		}
+		return freeID, nil
 	}
+}
 
-	return existingEntry, nil
+// deriveIndex maps the hash of the key into the range <minID, maxID>.
+func deriveIndex(key string, minID, maxID int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return minID + int(h.Sum32()%uint32(maxID-minID+1))
+}
 
+// findAvailableIndexFrom returns the first int of the range <minID, maxID> not contained in ids,
+// starting the search at the preferred index and wrapping around at the end of the range.
+func findAvailableIndexFrom(ids []int, preferred, minID, maxID int) (int, bool) {
+	allocated := map[int]struct{}{}
+	for _, v := range ids {
+		allocated[v] = struct{}{}
+	}
+	size := maxID - minID + 1
+	for i := 0; i < size; i++ {
+		candidate := minID + (preferred-minID+i)%size
+		if _, taken := allocated[candidate]; !taken {
+			return candidate, true
+		}
+	}
+	return 0, false
 }
 
-// findFirstAvailableIndex returns the smallest int that is not assigned to a node
-func findFirstAvailableIndex(ids []int) int {
-	res := 1
+// findFirstAvailableIndex returns the smallest int not lower than minID that is not assigned to a node.
+// The ids are expected to be sorted.
+func findFirstAvailableIndex(ids []int, minID int) int {
+	res := minID
 	for _, v := range ids {
 
-		if res == v {
+		if v < res {
+			continue
+		} else if res == v {
 			res++
 		} else {
 			break
@@ -225,39 +451,48 @@ func findFirstAvailableIndex(ids []int) int {
 }
 
 // listAllIDs returns slice that contains allocated ids i.e.: ids assigned to a node
-func listAllIDs(broker keyval.ProtoBroker) (ids []int, err error) {
-	it, err := broker.ListKeys(allocatedIDsKeyPrefix)
+func (ia *idAllocator) listAllIDs() (ids []int, err error) {
+	entries, err := ia.store.ListEntries()
 	if err != nil {
 		return nil, err
 	}
 
-	for {
-
-		key, _, stop := it.GetNext()
-
-		if stop {
-			break
-		}
-
-		id, err := extractIndexFromKey(key)
-		if err != nil {
-			return nil, err
-		}
-		ids = append(ids, id)
+	for _, item := range entries {
+		ids = append(ids, int(item.Id))
 	}
 	return ids, nil
-
 }
 
-func extractIndexFromKey(key string) (int, error) {
-	if strings.HasPrefix(key, allocatedIDsKeyPrefix) {
-		return strconv.Atoi(strings.Replace(key, allocatedIDsKeyPrefix, "", 1))
+// selectNodeIDRange returns the first of the configured ranges whose labels are all present among the node labels.
+func selectNodeIDRange(ranges []NodeIDRange, nodeLabels map[string]string) (*NodeIDRange, error) {
+	for i := range ranges {
+		idRange := ranges[i]
+		if idRange.MinID == 0 {
+			// zero is not a valid node ID
+			idRange.MinID = 1
+		}
+		if idRange.MaxID < idRange.MinID || idRange.MaxID > maxNodeID {
+			return nil, fmt.Errorf("invalid node ID range %v-%v", idRange.MinID, idRange.MaxID)
+		}
 
+		matches := true
+		for key, value := range idRange.NodeLabels {
+			if nodeValue, found := nodeLabels[key]; !found || nodeValue != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return &idRange, nil
+		}
 	}
-	return 0, errInvalidKey
+	return nil, fmt.Errorf("none of the configured node ID ranges matches labels of the node: %v", nodeLabels)
 }
 
-func createKey(index uint32) string {
-	str := strconv.FormatUint(uint64(index), 10)
-	return allocatedIDsKeyPrefix + str
+// contains returns true if the given ID belongs to the range. Nil range contains all IDs.
+func (r *NodeIDRange) contains(id uint32) bool {
+	if r == nil {
+		return true
+	}
+	return id >= r.MinID && id <= r.MaxID
 }
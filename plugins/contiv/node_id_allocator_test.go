@@ -0,0 +1,314 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/datasync/syncbase"
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+)
+
+// memIDStore is an in-memory NodeIDStore used by the tests.
+type memIDStore struct {
+	sync.Mutex
+	entries map[uint32]*node.NodeInfo
+	ttls    map[uint32]time.Duration
+
+	keepAlives int
+}
+
+func newMemIDStore() *memIDStore {
+	return &memIDStore{
+		entries: map[uint32]*node.NodeInfo{},
+		ttls:    map[uint32]time.Duration{},
+	}
+}
+
+func (s *memIDStore) ListEntries() ([]*node.NodeInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+	var res []*node.NodeInfo
+	for _, e := range s.entries {
+		res = append(res, proto.Clone(e).(*node.NodeInfo))
+	}
+	return res, nil
+}
+
+func (s *memIDStore) GetEntry(id uint32) (*node.NodeInfo, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	e, found := s.entries[id]
+	if !found {
+		return nil, false, nil
+	}
+	return proto.Clone(e).(*node.NodeInfo), true, nil
+}
+
+func (s *memIDStore) PutIfNotExists(entry *node.NodeInfo) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	if _, found := s.entries[entry.Id]; found {
+		return false, nil
+	}
+	s.entries[entry.Id] = proto.Clone(entry).(*node.NodeInfo)
+	return true, nil
+}
+
+func (s *memIDStore) Put(entry *node.NodeInfo, ttl time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+	s.entries[entry.Id] = proto.Clone(entry).(*node.NodeInfo)
+	s.ttls[entry.Id] = ttl
+	return nil
+}
+
+func (s *memIDStore) KeepAlive(id uint32) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	_, found := s.entries[id]
+	if found {
+		s.keepAlives++
+	}
+	return found, nil
+}
+
+func (s *memIDStore) Delete(id uint32) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.entries, id)
+	delete(s.ttls, id)
+	return nil
+}
+
+func (s *memIDStore) DeleteIfAllocatedTo(id uint32, nodeName string) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	if e, found := s.entries[id]; !found || e.Name != nodeName {
+		return false, nil
+	}
+	delete(s.entries, id)
+	delete(s.ttls, id)
+	return true, nil
+}
+
+func TestAllocateFirstFreeID(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := newMemIDStore()
+	store.Put(&node.NodeInfo{Id: 1, Name: "node1"}, 0)
+	store.Put(&node.NodeInfo{Id: 3, Name: "node3"}, 0)
+
+	ia := newIDAllocator(logrus.DefaultLogger(), store, "node2", "", "", "", 0, false, nil, "")
+	id, err := ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(id).To(gomega.BeEquivalentTo(2))
+
+	// the allocation is bound to a lease
+	gomega.Expect(store.ttls[2]).To(gomega.BeEquivalentTo(defaultLeaseTTL))
+
+	err = ia.releaseID()
+	gomega.Expect(err).To(gomega.BeNil())
+	_, found, _ := store.GetEntry(2)
+	gomega.Expect(found).To(gomega.BeFalse())
+}
+
+func TestReuseExistingID(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := newMemIDStore()
+	store.Put(&node.NodeInfo{Id: 1, Name: "node1"}, 0)
+	store.Put(&node.NodeInfo{Id: 5, Name: "node5"}, 0)
+
+	ia := newIDAllocator(logrus.DefaultLogger(), store, "node5", "", "", "", 10*time.Second, false, nil, "")
+	id, err := ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(id).To(gomega.BeEquivalentTo(5))
+	gomega.Expect(store.ttls[5]).To(gomega.BeEquivalentTo(10 * time.Second))
+}
+
+func TestRefreshExpiredEntry(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := newMemIDStore()
+	ia := newIDAllocator(logrus.DefaultLogger(), store, "node1", "", "", "", 0, false, nil, "")
+	id, err := ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// the lease is kept alive
+	err = ia.refreshEntry()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(store.keepAlives).To(gomega.Equal(1))
+
+	// simulate expiration of the lease
+	store.Delete(uint32(id))
+	err = ia.refreshEntry()
+	gomega.Expect(err).To(gomega.BeNil())
+	entry, found, _ := store.GetEntry(uint32(id))
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(entry.Name).To(gomega.BeEquivalentTo("node1"))
+	gomega.Expect(store.ttls[uint32(id)]).To(gomega.BeEquivalentTo(defaultLeaseTTL))
+
+	// the ID was taken by another node in the meantime
+	store.Put(&node.NodeInfo{Id: uint32(id), Name: "node2"}, 0)
+	err = ia.refreshEntry()
+	gomega.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestRestoreDeletedEntry(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := newMemIDStore()
+	ia := newIDAllocator(logrus.DefaultLogger(), store, "node1", "", "", "", 0, true, nil, "")
+	id, err := ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// deletion of an entry of another node is ignored
+	store.Put(&node.NodeInfo{Id: 2, Name: "node2"}, 0)
+	store.Delete(2)
+	ia.checkChange(&syncbase.ChangeEvent{Key: createKey(2), ChangeType: datasync.Delete})
+	_, found, _ := store.GetEntry(2)
+	gomega.Expect(found).To(gomega.BeFalse())
+
+	// the entry of this node is restored
+	store.Delete(uint32(id))
+	ia.checkChange(&syncbase.ChangeEvent{Key: createKey(uint32(id)), ChangeType: datasync.Delete})
+	entry, found, _ := store.GetEntry(uint32(id))
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(entry.Name).To(gomega.BeEquivalentTo("node1"))
+
+	// released ID is not restored
+	err = ia.releaseID()
+	gomega.Expect(err).To(gomega.BeNil())
+	ia.checkChange(&syncbase.ChangeEvent{Key: createKey(uint32(id)), ChangeType: datasync.Delete})
+	_, found, _ = store.GetEntry(uint32(id))
+	gomega.Expect(found).To(gomega.BeFalse())
+}
+
+func TestAllocateIDFromRange(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	ranges := []NodeIDRange{
+		{NodeLabels: map[string]string{"node-role.kubernetes.io/master": ""}, MinID: 1, MaxID: 3},
+		{MinID: 4, MaxID: 5},
+	}
+	masterRange, err := selectNodeIDRange(ranges, map[string]string{"node-role.kubernetes.io/master": ""})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(masterRange.MinID).To(gomega.BeEquivalentTo(1))
+	workerRange, err := selectNodeIDRange(ranges, map[string]string{"kubernetes.io/hostname": "worker"})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(workerRange.MinID).To(gomega.BeEquivalentTo(4))
+
+	store := newMemIDStore()
+	store.Put(&node.NodeInfo{Id: 1, Name: "master1"}, 0)
+	store.Put(&node.NodeInfo{Id: 4, Name: "worker1"}, 0)
+
+	ia := newIDAllocator(logrus.DefaultLogger(), store, "worker2", "", "", "", 0, false, workerRange, "")
+	id, err := ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(id).To(gomega.BeEquivalentTo(5))
+
+	// the range of workers is exhausted
+	ia = newIDAllocator(logrus.DefaultLogger(), store, "worker3", "", "", "", 0, false, workerRange, "")
+	_, err = ia.getID()
+	gomega.Expect(err).To(gomega.Equal(errIDRangeExhausted))
+
+	ia = newIDAllocator(logrus.DefaultLogger(), store, "master2", "", "", "", 0, false, masterRange, "")
+	id, err = ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(id).To(gomega.BeEquivalentTo(2))
+
+	// invalid range
+	_, err = selectNodeIDRange([]NodeIDRange{{MinID: 10, MaxID: 5}}, nil)
+	gomega.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestDeriveIDFromNodeName(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := newMemIDStore()
+	ia := newIDAllocator(logrus.DefaultLogger(), store, "node1", "", "", "", 0, false, nil, NodeNameHashNodeID)
+	id, err := ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(int(id)).To(gomega.BeEquivalentTo(deriveIndex("node1", 1, maxNodeID)))
+
+	// the same ID is derived after the cluster is rebuilt
+	store = newMemIDStore()
+	ia = newIDAllocator(logrus.DefaultLogger(), store, "node1", "", "", "", 0, false, nil, NodeNameHashNodeID)
+	rebuiltID, err := ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(rebuiltID).To(gomega.BeEquivalentTo(id))
+
+	// collision with another node falls back to the next free ID, wrapping around the range
+	idRange := &NodeIDRange{MinID: 1, MaxID: 2}
+	preferred := deriveIndex("node1", 1, 2)
+	store = newMemIDStore()
+	store.Put(&node.NodeInfo{Id: uint32(preferred), Name: "node2"}, 0)
+	ia = newIDAllocator(logrus.DefaultLogger(), store, "node1", "", "", "", 0, false, idRange, NodeNameHashNodeID)
+	id, err = ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(int(id)).To(gomega.BeEquivalentTo(3 - preferred))
+
+	// the range is exhausted
+	ia = newIDAllocator(logrus.DefaultLogger(), store, "node3", "", "", "", 0, false, idRange, NodeNameHashNodeID)
+	_, err = ia.getID()
+	gomega.Expect(err).To(gomega.Equal(errIDRangeExhausted))
+}
+
+func TestDeriveIDFromNodeIP(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	// the ID is derived from the management IP, not from the IP of the VPP interface
+	store := newMemIDStore()
+	ia := newIDAllocator(logrus.DefaultLogger(), store, "node1", "192.168.16.1/24", "10.0.0.1", "", 0, false, nil, NodeIPHashNodeID)
+	id, err := ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(int(id)).To(gomega.BeEquivalentTo(deriveIndex("10.0.0.1", 1, maxNodeID)))
+
+	// the management IP is required
+	ia = newIDAllocator(logrus.DefaultLogger(), newMemIDStore(), "node1", "192.168.16.1/24", "", "", 0, false, nil, NodeIPHashNodeID)
+	_, err = ia.getID()
+	gomega.Expect(err).To(gomega.Equal(errNoManagementIP))
+}
+
+func TestAllocationMetadata(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := newMemIDStore()
+	ia := newIDAllocator(logrus.DefaultLogger(), store, "node1", "", "10.0.0.1", "10.1.7.0/24", 0, false, nil, "")
+	id, err := ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+
+	entry, found, _ := store.GetEntry(uint32(id))
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(entry.ManagementIp).To(gomega.BeEquivalentTo("10.0.0.1"))
+	gomega.Expect(entry.PodNetwork).To(gomega.BeEquivalentTo("10.1.7.0/24"))
+	gomega.Expect(entry.AllocationTimestamp).ToNot(gomega.BeZero())
+
+	// allocation time is preserved when the entry is refreshed
+	allocatedAt := entry.AllocationTimestamp
+	ia = newIDAllocator(logrus.DefaultLogger(), store, "node1", "", "10.0.0.1", "10.1.7.0/24", 0, false, nil, "")
+	_, err = ia.getID()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(ia.refreshEntry()).To(gomega.BeNil())
+	entry, _, _ = store.GetEntry(uint32(id))
+	gomega.Expect(entry.AllocationTimestamp).To(gomega.BeEquivalentTo(allocatedAt))
+}
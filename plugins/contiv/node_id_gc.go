@@ -0,0 +1,151 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"context"
+	"time"
+
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/ligato/cn-infra/logging"
+)
+
+const (
+	// defaultNodeIDGCInterval is used if the interval of the garbage collection is not configured.
+	defaultNodeIDGCInterval = 5 * time.Minute
+)
+
+// nodeIDCollector removes entries of the allocated node IDs that belong to nodes
+// no longer present in the k8s cluster. The collection runs periodically and
+// immediately once the deletion of a k8s node is observed.
+//
+// The collection runs only on the leader, which is the node with the lowest allocated ID.
+// Since the allocations are bound to leases, the leadership passes to another node
+// automatically once the entry of the leader expires. Even if two nodes consider themselves
+// leaders for a short time, removal of the same stale entry is harmless. An entry is removed
+// only if its ID is still allocated to the collected node, not to remove the ID re-allocated
+// meanwhile to a new node.
+type nodeIDCollector struct {
+	logger   logging.Logger
+	store    NodeIDStore
+	nodeName string
+
+	// listK8sNodes returns names of the nodes in the k8s cluster
+	listK8sNodes func() ([]string, error)
+
+	// entries allocated less than gracePeriod ago are never removed, the node
+	// might not be reflected from k8s yet
+	gracePeriod time.Duration
+
+	// trigger requests the collection outside of the period
+	trigger chan struct{}
+}
+
+// newNodeIDCollector creates new instance of nodeIDCollector.
+func newNodeIDCollector(logger logging.Logger, store NodeIDStore, nodeName string,
+	listK8sNodes func() ([]string, error), gracePeriod time.Duration) *nodeIDCollector {
+	return &nodeIDCollector{
+		logger:       logger,
+		store:        store,
+		nodeName:     nodeName,
+		listK8sNodes: listK8sNodes,
+		gracePeriod:  gracePeriod,
+		trigger:      make(chan struct{}, 1),
+	}
+}
+
+// nodeDeleted requests the collection after the k8s node of the given name was deleted.
+// The method does not block, the collection runs in the goroutine of run.
+func (c *nodeIDCollector) nodeDeleted(name string) {
+	c.logger.Debugf("Node %v deleted from the k8s cluster, collecting its node ID", name)
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+		// the collection is already requested
+	}
+}
+
+// run collects stale entries periodically and on request until the context is cancelled.
+func (c *nodeIDCollector) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.trigger:
+		case <-ctx.Done():
+			return
+		}
+		err := c.collect()
+		if err != nil {
+			c.logger.Errorf("Garbage collection of node IDs failed: %v", err)
+		}
+	}
+}
+
+// collect removes the entries of the nodes deleted from the k8s cluster if this node is the leader.
+func (c *nodeIDCollector) collect() error {
+	entries, err := c.store.ListEntries()
+	if err != nil {
+		return err
+	}
+	if !c.isLeader(entries) {
+		return nil
+	}
+
+	k8sNodes, err := c.listK8sNodes()
+	if err != nil {
+		return err
+	}
+	if len(k8sNodes) == 0 {
+		// nodes are not reflected yet, do not remove everything
+		c.logger.Debug("No k8s nodes known, skipping garbage collection of node IDs")
+		return nil
+	}
+	existing := map[string]struct{}{}
+	for _, name := range k8sNodes {
+		existing[name] = struct{}{}
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		if _, found := existing[entry.Name]; found || entry.Name == c.nodeName {
+			continue
+		}
+		if entry.AllocationTimestamp != 0 && now.Sub(time.Unix(entry.AllocationTimestamp, 0)) < c.gracePeriod {
+			continue
+		}
+		deleted, err := c.store.DeleteIfAllocatedTo(entry.Id, entry.Name)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			c.logger.Infof("Removed stale node ID %v of the node %v that is no longer in the cluster", entry.Id, entry.Name)
+		}
+	}
+	return nil
+}
+
+// isLeader returns true if this node owns the lowest of the allocated IDs.
+func (c *nodeIDCollector) isLeader(entries []*node.NodeInfo) bool {
+	var leader *node.NodeInfo
+	for _, entry := range entries {
+		if leader == nil || entry.Id < leader.Id {
+			leader = entry
+		}
+	}
+	return leader != nil && leader.Name == c.nodeName
+}
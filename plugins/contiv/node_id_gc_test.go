@@ -0,0 +1,113 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+)
+
+func TestCollectStaleNodeIDs(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := newMemIDStore()
+	store.Put(&node.NodeInfo{Id: 1, Name: "node1"}, 0)
+	store.Put(&node.NodeInfo{Id: 2, Name: "node2"}, 0)
+	store.Put(&node.NodeInfo{Id: 3, Name: "deleted-node"}, 0)
+	store.Put(&node.NodeInfo{Id: 4, Name: "new-node", AllocationTimestamp: time.Now().Unix()}, 0)
+
+	k8sNodes := []string{}
+	listK8sNodes := func() ([]string, error) { return k8sNodes, nil }
+
+	// nothing is removed until nodes are reflected
+	leader := newNodeIDCollector(logrus.DefaultLogger(), store, "node1", listK8sNodes, time.Minute)
+	gomega.Expect(leader.collect()).To(gomega.BeNil())
+	entries, _ := store.ListEntries()
+	gomega.Expect(entries).To(gomega.HaveLen(4))
+
+	// only the leader collects the stale entries
+	k8sNodes = []string{"node1", "node2"}
+	follower := newNodeIDCollector(logrus.DefaultLogger(), store, "node2", listK8sNodes, time.Minute)
+	gomega.Expect(follower.collect()).To(gomega.BeNil())
+	entries, _ = store.ListEntries()
+	gomega.Expect(entries).To(gomega.HaveLen(4))
+
+	// recently allocated entry is kept for the grace period
+	gomega.Expect(leader.collect()).To(gomega.BeNil())
+	_, found, _ := store.GetEntry(3)
+	gomega.Expect(found).To(gomega.BeFalse())
+	_, found, _ = store.GetEntry(4)
+	gomega.Expect(found).To(gomega.BeTrue())
+	entries, _ = store.ListEntries()
+	gomega.Expect(entries).To(gomega.HaveLen(3))
+}
+
+func TestCollectOnNodeDeletion(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := newMemIDStore()
+	store.Put(&node.NodeInfo{Id: 1, Name: "node1"}, 0)
+	store.Put(&node.NodeInfo{Id: 2, Name: "deleted-node"}, 0)
+	listK8sNodes := func() ([]string, error) { return []string{"node1"}, nil }
+
+	// the deletion of the k8s node triggers the collection before the period elapses
+	collector := newNodeIDCollector(logrus.DefaultLogger(), store, "node1", listK8sNodes, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go collector.run(ctx, time.Hour)
+
+	collector.nodeDeleted("deleted-node")
+	gomega.Eventually(func() bool {
+		_, found, _ := store.GetEntry(2)
+		return found
+	}).Should(gomega.BeFalse())
+}
+
+// reallocatingIDStore is a NodeIDStore where the listed ID of a stale node gets
+// re-allocated to a new node before the collector removes it.
+type reallocatingIDStore struct {
+	*memIDStore
+	reallocated *node.NodeInfo
+}
+
+func (s *reallocatingIDStore) ListEntries() ([]*node.NodeInfo, error) {
+	entries, err := s.memIDStore.ListEntries()
+	s.memIDStore.Put(s.reallocated, 0)
+	return entries, err
+}
+
+func TestCollectReallocatedNodeID(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := &reallocatingIDStore{
+		memIDStore:  newMemIDStore(),
+		reallocated: &node.NodeInfo{Id: 2, Name: "new-node"},
+	}
+	store.Put(&node.NodeInfo{Id: 1, Name: "node1"}, 0)
+	store.Put(&node.NodeInfo{Id: 2, Name: "deleted-node"}, 0)
+	listK8sNodes := func() ([]string, error) { return []string{"node1", "new-node"}, nil }
+
+	// the ID allocated to the new node meanwhile is kept
+	collector := newNodeIDCollector(logrus.DefaultLogger(), store, "node1", listK8sNodes, time.Minute)
+	gomega.Expect(collector.collect()).To(gomega.BeNil())
+	entry, found, _ := store.GetEntry(2)
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(entry.Name).To(gomega.Equal("new-node"))
+}
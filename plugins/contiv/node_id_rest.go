@@ -0,0 +1,123 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/gorilla/mux"
+	"github.com/unrolled/render"
+)
+
+const (
+	// nodeIDsURL is the URL of the REST API listing allocated node IDs.
+	nodeIDsURL = "/contiv/v1/nodeids"
+
+	// nodeIDVarName is the name of the URL variable with the inspected node ID.
+	nodeIDVarName = "id"
+)
+
+// NodeIDAllocation describes one allocated node ID as exposed by the REST API.
+type NodeIDAllocation struct {
+	ID           uint32 `json:"id"`
+	NodeName     string `json:"nodeName"`
+	NodeIP       string `json:"nodeIP,omitempty"`
+	ManagementIP string `json:"managementIP,omitempty"`
+	PodSubnet    string `json:"podSubnet,omitempty"`
+	AllocatedAt  string `json:"allocatedAt,omitempty"`
+	AgentVersion string `json:"agentVersion,omitempty"`
+}
+
+// registerHandlers registers read-only REST handlers inspecting the node ID allocations:
+//   - List all allocated node IDs:
+//     > curl -X GET http://localhost:<port>/contiv/v1/nodeids
+//   - Inspect the allocation of the given node ID:
+//     > curl -X GET http://localhost:<port>/contiv/v1/nodeids/<id>
+func (plugin *Plugin) registerHandlers() {
+	plugin.HTTPHandlers.RegisterHTTPHandler(nodeIDsURL, plugin.listNodeIDsHandler, "GET")
+	plugin.HTTPHandlers.RegisterHTTPHandler(fmt.Sprintf("%s/{%s:[0-9]+}", nodeIDsURL, nodeIDVarName),
+		plugin.nodeIDHandler, "GET")
+}
+
+// listNodeIDsHandler processes requests to list all allocated node IDs.
+func (plugin *Plugin) listNodeIDsHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		entries, err := plugin.NodeIDStore.ListEntries()
+		if err != nil {
+			plugin.Log.Errorf("Unable to list node IDs: %v", err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+
+		allocations := []*NodeIDAllocation{}
+		for _, entry := range entries {
+			allocations = append(allocations, plugin.nodeIDAllocation(entry))
+		}
+		sort.Slice(allocations, func(i, j int) bool { return allocations[i].ID < allocations[j].ID })
+		formatter.JSON(w, http.StatusOK, allocations)
+	}
+}
+
+// nodeIDHandler processes requests to inspect the allocation of a single node ID.
+func (plugin *Plugin) nodeIDHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.ParseUint(mux.Vars(req)[nodeIDVarName], 10, 32)
+		if err != nil {
+			formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+			return
+		}
+
+		entry, found, err := plugin.NodeIDStore.GetEntry(uint32(id))
+		if err != nil {
+			plugin.Log.Errorf("Unable to read node ID %v: %v", id, err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		if !found {
+			formatter.JSON(w, http.StatusNotFound, struct{ Error string }{fmt.Sprintf("node ID %v is not allocated", id)})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, plugin.nodeIDAllocation(entry))
+	}
+}
+
+// nodeIDAllocation converts the stored entry into its REST representation
+// including the pod subnet of the node (assigned by k8s or derived from the ID).
+func (plugin *Plugin) nodeIDAllocation(entry *node.NodeInfo) *NodeIDAllocation {
+	allocation := &NodeIDAllocation{
+		ID:           entry.Id,
+		NodeName:     entry.Name,
+		NodeIP:       entry.IpAddress,
+		ManagementIP: entry.ManagementIp,
+		AgentVersion: entry.AgentVersion,
+	}
+	if entry.AllocationTimestamp != 0 {
+		allocation.AllocatedAt = time.Unix(entry.AllocationTimestamp, 0).UTC().Format(time.RFC3339)
+	}
+	if entry.PodNetwork != "" {
+		allocation.PodSubnet = entry.PodNetwork
+	} else if entry.Id <= maxNodeID {
+		podSubnet, err := plugin.cniServer.ipam.OtherNodePodNetwork(uint8(entry.Id))
+		if err == nil {
+			allocation.PodSubnet = podSubnet.String()
+		}
+	}
+	return allocation
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/contiv/vpp/plugins/contiv/ipam"
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/gorilla/mux"
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+	"github.com/unrolled/render"
+)
+
+func TestNodeIDsREST(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	store := newMemIDStore()
+	store.Put(&node.NodeInfo{Id: 2, Name: "node2", IpAddress: "192.168.16.2/24", AllocationTimestamp: 1500000000}, 0)
+	store.Put(&node.NodeInfo{Id: 1, Name: "node1"}, 0)
+
+	ipamInst, err := ipam.New(logrus.DefaultLogger(), 1, &configTapVxlanTCP.IPAMConfig)
+	gomega.Expect(err).To(gomega.BeNil())
+	plugin := &Plugin{cniServer: &remoteCNIserver{ipam: ipamInst}}
+	plugin.Log = logging.ForPlugin("contiv", logrus.NewLogRegistry())
+	plugin.NodeIDStore = store
+
+	router := mux.NewRouter()
+	formatter := render.New()
+	router.HandleFunc(nodeIDsURL, plugin.listNodeIDsHandler(formatter))
+	router.HandleFunc(nodeIDsURL+"/{"+nodeIDVarName+":[0-9]+}", plugin.nodeIDHandler(formatter))
+
+	// list all allocations
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", nodeIDsURL, nil))
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	allocations := []NodeIDAllocation{}
+	gomega.Expect(json.Unmarshal(rec.Body.Bytes(), &allocations)).To(gomega.BeNil())
+	gomega.Expect(allocations).To(gomega.HaveLen(2))
+	gomega.Expect(allocations[0].NodeName).To(gomega.BeEquivalentTo("node1"))
+	gomega.Expect(allocations[0].AllocatedAt).To(gomega.BeEmpty())
+	gomega.Expect(allocations[1].NodeName).To(gomega.BeEquivalentTo("node2"))
+	gomega.Expect(allocations[1].PodSubnet).To(gomega.BeEquivalentTo("10.1.2.0/24"))
+	gomega.Expect(allocations[1].AllocatedAt).To(gomega.BeEquivalentTo("2017-07-14T02:40:00Z"))
+
+	// inspect single allocation
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", nodeIDsURL+"/2", nil))
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	allocation := NodeIDAllocation{}
+	gomega.Expect(json.Unmarshal(rec.Body.Bytes(), &allocation)).To(gomega.BeNil())
+	gomega.Expect(allocation.NodeIP).To(gomega.BeEquivalentTo("192.168.16.2/24"))
+
+	// not allocated ID
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", nodeIDsURL+"/3", nil))
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusNotFound))
+}
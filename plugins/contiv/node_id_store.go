@@ -0,0 +1,163 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/contiv/vpp/flavors/ksr"
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/contiv/vpp/plugins/kvstore"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/servicelabel"
+)
+
+// NodeIDStore is a storage of node ID allocations used by the node ID allocator.
+// The store has to be shared by all nodes of the cluster. Every allocation is represented
+// by a NodeInfo entry identified by the allocated ID.
+type NodeIDStore interface {
+	// ListEntries returns all entries of the allocated IDs.
+	ListEntries() ([]*node.NodeInfo, error)
+
+	// GetEntry returns the entry of the given allocated ID.
+	GetEntry(id uint32) (entry *node.NodeInfo, found bool, err error)
+
+	// PutIfNotExists atomically stores the entry if the ID is not allocated yet.
+	// If the ID is already allocated, succeeded is false and the stored entry is untouched.
+	PutIfNotExists(entry *node.NodeInfo) (succeeded bool, err error)
+
+	// Put creates or overwrites the entry. If ttl is non-zero, the entry
+	// is removed from the store unless it is put again before ttl elapses.
+	Put(entry *node.NodeInfo, ttl time.Duration) error
+
+	// KeepAlive extends TTL of the entry put with a non-zero ttl, without rewriting it.
+	// found is false if the entry does not exist (e.g. it has expired).
+	KeepAlive(id uint32) (found bool, err error)
+
+	// Delete removes the entry of the given ID.
+	Delete(id uint32) error
+
+	// DeleteIfAllocatedTo atomically removes the entry of the given ID only if the ID
+	// is still allocated to the node of the given name.
+	DeleteIfAllocatedTo(id uint32, nodeName string) (deleted bool, err error)
+}
+
+// kvIDStore is the default NodeIDStore implementation, storing allocations
+// in the key-value data store under allocatedIDsKeyPrefix of the KSR microservice.
+type kvIDStore struct {
+	store  kvstore.KvStore
+	prefix string
+	broker keyval.ProtoBroker
+}
+
+// newKVIDStore creates new instance of kvIDStore.
+func newKVIDStore(store kvstore.KvStore) *kvIDStore {
+	prefix := servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel)
+	return &kvIDStore{
+		store:  store,
+		prefix: prefix,
+		broker: store.NewBroker(prefix),
+	}
+}
+
+// ListEntries returns all entries of the allocated IDs.
+func (s *kvIDStore) ListEntries() ([]*node.NodeInfo, error) {
+	var entries []*node.NodeInfo
+	it, err := s.broker.ListValues(allocatedIDsKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+
+		item := &node.NodeInfo{}
+		err := kv.GetValue(item)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, item)
+	}
+	return entries, nil
+}
+
+// GetEntry returns the entry of the given allocated ID.
+func (s *kvIDStore) GetEntry(id uint32) (entry *node.NodeInfo, found bool, err error) {
+	entry = &node.NodeInfo{}
+	found, _, err = s.broker.GetValue(createKey(id), entry)
+	if err != nil || !found {
+		return nil, found, err
+	}
+	return entry, true, nil
+}
+
+// PutIfNotExists atomically stores the entry if the ID is not allocated yet.
+func (s *kvIDStore) PutIfNotExists(entry *node.NodeInfo) (succeeded bool, err error) {
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return false, err
+	}
+	return s.store.PutIfNotExists(s.prefix+createKey(entry.Id), encoded)
+}
+
+// Put creates or overwrites the entry, binding it to a new lease if ttl is non-zero.
+func (s *kvIDStore) Put(entry *node.NodeInfo, ttl time.Duration) error {
+	if ttl == 0 {
+		return s.broker.Put(createKey(entry.Id), entry)
+	}
+	return s.broker.Put(createKey(entry.Id), entry, datasync.WithTTL(ttl))
+}
+
+// KeepAlive extends TTL of the entry without rewriting it.
+func (s *kvIDStore) KeepAlive(id uint32) (found bool, err error) {
+	return s.store.KeepAlive(s.prefix + createKey(id))
+}
+
+// Delete removes the entry of the given ID.
+func (s *kvIDStore) Delete(id uint32) error {
+	_, err := s.broker.Delete(createKey(id))
+	return err
+}
+
+// DeleteIfAllocatedTo removes the entry of the given ID if it belongs to the given node
+// and has not been modified since it was read.
+func (s *kvIDStore) DeleteIfAllocatedTo(id uint32, nodeName string) (deleted bool, err error) {
+	entry := &node.NodeInfo{}
+	found, rev, err := s.broker.GetValue(createKey(id), entry)
+	if err != nil || !found || entry.Name != nodeName {
+		return false, err
+	}
+	return s.store.DeleteIfNotChanged(s.prefix+createKey(id), rev)
+}
+
+func extractIndexFromKey(key string) (int, error) {
+	if strings.HasPrefix(key, allocatedIDsKeyPrefix) {
+		return strconv.Atoi(strings.Replace(key, allocatedIDsKeyPrefix, "", 1))
+
+	}
+	return 0, errInvalidKey
+}
+
+func createKey(index uint32) string {
+	str := strconv.FormatUint(uint64(index), 10)
+	return allocatedIDsKeyPrefix + str
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync/atomic"
+
+	"github.com/contiv/vpp/plugins/contiv/containeridx"
+	"github.com/contiv/vpp/plugins/contiv/model/cni"
+	containermodel "github.com/contiv/vpp/plugins/contiv/model/container"
+)
+
+// cleanupOrphanedContainers is called once the vswitch connectivity is configured after the start of the agent.
+// It loads the records of the containers connected before the restart and removes the configuration left behind
+// by the orphaned ones - whose network namespace does not exist anymore or whose POD is not running in k8s.
+// The configuration of the other containers is restored (see restoreContainer). If it cannot be restored,
+// the records are kept, so that the configuration can be removed by CNI Delete later.
+func (s *remoteCNIserver) cleanupOrphanedContainers() {
+	if s.containerStore == nil {
+		return
+	}
+	containers, err := s.containerStore.ListContainers()
+	if err != nil {
+		s.Logger.Warnf("Failed to load the records of the connected containers: %v", err)
+		return
+	}
+
+	runningPods := s.runningPods()
+	orphans := 0
+
+	s.restoredContainersLock.Lock()
+	defer s.restoredContainersLock.Unlock()
+
+	for _, container := range containers {
+		if s.isOrphanedContainer(container, runningPods) {
+			s.Logger.Infof("Removing configuration of the orphaned container %s (pod %s/%s)",
+				container.Id, container.PodNamespace, container.PodName)
+			s.removeContainerConfig(container)
+			orphans++
+			continue
+		}
+		if !s.restoreContainer(container) {
+			s.restoredContainers[container.Id] = container
+		}
+	}
+	s.Logger.Infof("Found %d containers connected before the restart, %d of them orphaned", len(containers), orphans)
+}
+
+// runningPods returns the keys (namespace/name) of the PODs reflected from k8s by KSR.
+// Nil is returned if the PODs are not known (yet).
+func (s *remoteCNIserver) runningPods() map[string]struct{} {
+	if s.listPods == nil {
+		return nil
+	}
+	podKeys, err := s.listPods()
+	if err != nil || len(podKeys) == 0 {
+		// rely only on the network namespaces
+		return nil
+	}
+	runningPods := map[string]struct{}{}
+	for _, podKey := range podKeys {
+		runningPods[podKey] = struct{}{}
+	}
+	return runningPods
+}
+
+// isOrphanedContainer returns true if the container does not exist anymore.
+func (s *remoteCNIserver) isOrphanedContainer(container *containermodel.Container, runningPods map[string]struct{}) bool {
+	if _, err := os.Stat(container.NetworkNamespace); os.IsNotExist(err) {
+		return true
+	}
+	if runningPods != nil && container.PodName != "" {
+		if _, running := runningPods[container.PodNamespace+"/"+container.PodName]; !running {
+			return true
+		}
+	}
+	return false
+}
+
+// removeRestoredContainer removes the configuration of the container connected before the restart
+// of the agent. Returns false if the container is not known.
+func (s *remoteCNIserver) removeRestoredContainer(containerID string) bool {
+	s.restoredContainersLock.Lock()
+	defer s.restoredContainersLock.Unlock()
+
+	container, found := s.restoredContainers[containerID]
+	if !found {
+		return false
+	}
+	delete(s.restoredContainers, containerID)
+	s.removeContainerConfig(container)
+	return true
+}
+
+// removeContainerConfig deletes the configuration persisted for the container, which makes the VPP agent
+// remove it from VPP and from the host, releases the IP addresses of the container and deletes its record.
+func (s *remoteCNIserver) removeContainerConfig(container *containermodel.Container) {
+	for _, key := range container.PersistedKeys {
+		// the delete is not ignored by the proxy, so the VPP agent applies it
+		if _, err := s.proxy.Delete(key); err != nil {
+			s.Logger.Warnf("Failed to delete %s of the container %s: %v", key, container.Id, err)
+		}
+	}
+	for _, podID := range container.IpamPodIds {
+		s.ipam.ReleasePodIP(podID)
+	}
+	if err := s.containerStore.DeleteContainer(container.Id); err != nil {
+		s.Logger.Warnf("Failed to delete the record of the container %s: %v", container.Id, err)
+	}
+}
+
+// storeContainer persists the record of the connected container with the keys of its persisted configuration
+// and with the applied configuration, which is restored after the restart of the agent (see restoreContainer).
+func (s *remoteCNIserver) storeContainer(request *cni.CNIRequest, config *containeridx.Config, persistedKeys []string) error {
+	if s.containerStore == nil {
+		return nil
+	}
+	encodedConfig, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	container := &containermodel.Container{
+		Id:               request.ContainerId,
+		PodName:          config.PodName,
+		PodNamespace:     config.PodNamespace,
+		NetworkNamespace: request.NetworkNamespace,
+		InterfaceName:    request.InterfaceName,
+		PersistedKeys:    persistedKeys,
+		IpamPodIds:       []string{request.NetworkNamespace},
+		Config:           encodedConfig,
+		LastPodIfIdx:     atomic.LoadInt32(&s.counter),
+	}
+	sort.Strings(container.PersistedKeys)
+	for _, secondaryIf := range config.SecondaryIfs {
+		container.IpamPodIds = append(container.IpamPodIds, s.secondaryIfPodID(request, secondaryIf.PodIfName))
+	}
+	return s.containerStore.PutContainer(container)
+}
+
+// deleteContainer removes the record of the disconnected container.
+func (s *remoteCNIserver) deleteContainer(containerID string) error {
+	if s.containerStore == nil {
+		return nil
+	}
+	return s.containerStore.DeleteContainer(containerID)
+}
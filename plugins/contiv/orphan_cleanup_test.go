@@ -0,0 +1,196 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/contiv/vpp/plugins/contiv/model/cni"
+	containermodel "github.com/contiv/vpp/plugins/contiv/model/container"
+	ipammodel "github.com/contiv/vpp/plugins/contiv/model/ipam"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+// containerStoreMock keeps the records of the containers in memory.
+type containerStoreMock struct {
+	sync.Mutex
+	containers map[string]*containermodel.Container
+}
+
+func newContainerStoreMock() *containerStoreMock {
+	return &containerStoreMock{containers: map[string]*containermodel.Container{}}
+}
+
+func (s *containerStoreMock) ListContainers() ([]*containermodel.Container, error) {
+	s.Lock()
+	defer s.Unlock()
+	var containers []*containermodel.Container
+	for _, container := range s.containers {
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+func (s *containerStoreMock) PutContainer(container *containermodel.Container) error {
+	s.Lock()
+	defer s.Unlock()
+	s.containers[container.Id] = container
+	return nil
+}
+
+func (s *containerStoreMock) DeleteContainer(id string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.containers, id)
+	return nil
+}
+
+// allocationStoreMock keeps the IP addresses allocated by IPAM in memory.
+type allocationStoreMock struct {
+	allocations *ipammodel.PodIPAllocations
+}
+
+func (s *allocationStoreMock) LoadAllocations() (*ipammodel.PodIPAllocations, error) {
+	return s.allocations, nil
+}
+
+func (s *allocationStoreMock) SaveAllocations(allocations *ipammodel.PodIPAllocations) error {
+	s.allocations = allocations
+	return nil
+}
+
+// testStores groups the stores surviving the restart of the CNI server.
+type testStores struct {
+	containers  *containerStoreMock
+	allocations *allocationStoreMock
+}
+
+func newTestStores() *testStores {
+	return &testStores{
+		containers:  newContainerStoreMock(),
+		allocations: &allocationStoreMock{},
+	}
+}
+
+// startServer creates new CNI server using the given stores.
+func startServer(stores *testStores) (*remoteCNIserver, func()) {
+	server, _, _, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	server.containerStore = stores.containers
+	gomega.Expect(server.ipam.SetAllocationStore(stores.allocations)).To(gomega.BeNil())
+	return server, conn.Disconnect
+}
+
+// connectContainer connects the container by the CNI Add request and returns its persisted record.
+func connectContainer(stores *testStores, request *cni.CNIRequest) *containermodel.Container {
+	server, disconnect := startServer(stores)
+	defer disconnect()
+	server.setVswitchConfigured()
+
+	reply, err := server.Add(context.Background(), request)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+
+	container, found := stores.containers.containers[request.ContainerId]
+	gomega.Expect(found).To(gomega.BeTrue())
+	return container
+}
+
+// restartServer creates new CNI server using the given stores, the returned channel receives
+// the changes of the persisted configuration applied by the VPP agent.
+func restartServer(stores *testStores, runningPods ...string) (*remoteCNIserver, chan datasync.ChangeEvent, func()) {
+	server, disconnect := startServer(stores)
+	server.listPods = func() ([]string, error) {
+		return runningPods, nil
+	}
+	changes := make(chan datasync.ChangeEvent, 100)
+	server.proxy.Watch("test", changes, nil, "/")
+	return server, changes, disconnect
+}
+
+// deletedKeys returns the keys deleted so far.
+func deletedKeys(changes chan datasync.ChangeEvent) []string {
+	var keys []string
+	for {
+		select {
+		case change := <-changes:
+			if change.GetChangeType() == datasync.Delete {
+				keys = append(keys, change.GetKey())
+			}
+		default:
+			return keys
+		}
+	}
+}
+
+func TestCleanupOrphanedContainers(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	netns, err := ioutil.TempFile("", "netns")
+	gomega.Expect(err).To(gomega.BeNil())
+	netns.Close()
+	defer os.Remove(netns.Name())
+
+	stores := newTestStores()
+
+	// the network namespace of the container is gone
+	removedNetns := req
+	removedNetns.ContainerId = "removedNetns"
+	orphan1 := connectContainer(stores, &removedNetns)
+	gomega.Expect(orphan1.PersistedKeys).NotTo(gomega.BeEmpty())
+	gomega.Expect(orphan1.PodName).To(gomega.BeEquivalentTo(podName))
+	gomega.Expect(orphan1.IpamPodIds).To(gomega.Equal([]string{removedNetns.NetworkNamespace}))
+
+	// the POD of the container is not running anymore
+	removedPod := req
+	removedPod.ContainerId = "removedPod"
+	removedPod.NetworkNamespace = netns.Name()
+	removedPod.ExtraArguments = "K8S_POD_NAMESPACE=default;K8S_POD_NAME=removed"
+	orphan2 := connectContainer(stores, &removedPod)
+
+	// the container is still running
+	running := req
+	running.ContainerId = "running"
+	running.NetworkNamespace = netns.Name()
+	container := connectContainer(stores, &running)
+
+	server, changes, disconnect := restartServer(stores, "default/"+podName)
+	defer disconnect()
+
+	// configuration of the orphans is removed
+	server.cleanupOrphanedContainers()
+	gomega.Expect(deletedKeys(changes)).To(gomega.ConsistOf(append(orphan1.PersistedKeys, orphan2.PersistedKeys...)))
+	gomega.Expect(stores.containers.containers).To(gomega.HaveLen(1))
+	gomega.Expect(stores.containers.containers).To(gomega.HaveKey(running.ContainerId))
+
+	// configuration of the running container is removed by CNI Delete
+	server.setVswitchConfigured()
+	reply, err := server.Delete(context.Background(), &running)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+	gomega.Expect(deletedKeys(changes)).To(gomega.ConsistOf(container.PersistedKeys))
+	gomega.Expect(stores.containers.containers).To(gomega.BeEmpty())
+
+	// nothing is removed if the PODs are not known
+	connectContainer(stores, &removedPod)
+	server, _, disconnect = restartServer(stores)
+	defer disconnect()
+	server.cleanupOrphanedContainers()
+	gomega.Expect(stores.containers.containers).To(gomega.HaveLen(1))
+}
@@ -0,0 +1,397 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"git.fd.io/govpp.git/api"
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/interfaces"
+	"github.com/unrolled/render"
+)
+
+const (
+	// captureURL is the URL of the REST API controlling the packet capture on pod interfaces.
+	captureURL = "/contiv/v1/capture"
+
+	// capturePcapURL is the URL of the REST API downloading the captured packets.
+	capturePcapURL = captureURL + "/pcap"
+
+	// limits of the capture, the capture is stopped after whichever is reached first
+	defaultCaptureMaxPackets = 100
+	maxCaptureMaxPackets     = 10000
+	defaultCaptureDuration   = 10 * time.Second
+	maxCaptureDuration       = 5 * time.Minute
+
+	// capture states
+	captureRunning  = "running"
+	captureFinished = "finished"
+	captureFailed   = "failed"
+)
+
+// captureDir is the directory VPP writes the pcap files to, the agent runs in the same container as VPP.
+var captureDir = "/tmp"
+
+// CaptureRequest is the body of the request starting the packet capture on the interface of a pod.
+type CaptureRequest struct {
+	PodNamespace string `json:"podNamespace"`
+	PodName      string `json:"podName"`
+	MaxPackets   uint32 `json:"maxPackets,omitempty"` // 100 by default, at most 10000
+	Duration     uint32 `json:"duration,omitempty"`   // in seconds, 10 by default, at most 300
+}
+
+// PacketCapture describes the last packet capture started on the node.
+type PacketCapture struct {
+	PodNamespace    string `json:"podNamespace"`
+	PodName         string `json:"podName"`
+	Interface       string `json:"interface"`
+	MaxPackets      uint32 `json:"maxPackets"`
+	Duration        uint32 `json:"duration"`
+	StartedAt       string `json:"startedAt"`
+	State           string `json:"state"`
+	CapturedPackets uint32 `json:"capturedPackets"`
+	File            string `json:"file"`
+	Error           string `json:"error,omitempty"`
+}
+
+// pcapStatusRegexp matches the number of captured packets printed by "pcap tx trace status",
+// e.g. "pcap tx capture is on: 10 of 100 pkts...".
+var pcapStatusRegexp = regexp.MustCompile(`(\d+) of (\d+) pkts`)
+
+// podInterface identifies the VPP interface of a pod.
+type podInterface struct {
+	name      string // name of the interface as printed by VPP CLI
+	swIfIndex uint32
+	podIP     string
+}
+
+// packetCapture captures the packets VPP transmits to the interface of a pod into a pcap file using
+// the pcap tx trace of VPP. VPP supports a single capture at a time, the capture is stopped (and the file
+// written) after the given number of packets by VPP and after the given duration by the timer.
+type packetCapture struct {
+	sync.Mutex
+	logger logging.Logger
+
+	// cli executes VPP CLI command
+	cli func(command string) (string, error)
+
+	// podInterface returns the name of the VPP interface of the pod as known to VPP CLI
+	podInterface func(podNamespace, podName string) (string, error)
+
+	capture *PacketCapture
+	timer   *time.Timer
+}
+
+// newPacketCapture creates new instance of packetCapture.
+func newPacketCapture(logger logging.Logger, cli func(command string) (string, error),
+	podInterface func(podNamespace, podName string) (string, error)) *packetCapture {
+	return &packetCapture{
+		logger:       logger,
+		cli:          cli,
+		podInterface: podInterface,
+	}
+}
+
+// start starts the capture on the interface of the pod. Fails if another capture is running.
+func (c *packetCapture) start(request *CaptureRequest, duration time.Duration) (*PacketCapture, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.capture != nil && c.capture.State == captureRunning {
+		return nil, fmt.Errorf("capture on %s is already running", c.capture.Interface)
+	}
+	ifName, err := c.podInterface(request.PodNamespace, request.PodName)
+	if err != nil {
+		return nil, err
+	}
+	capture := &PacketCapture{
+		PodNamespace: request.PodNamespace,
+		PodName:      request.PodName,
+		Interface:    ifName,
+		MaxPackets:   request.MaxPackets,
+		Duration:     uint32(duration / time.Second),
+		StartedAt:    time.Now().UTC().Format(time.RFC3339),
+		State:        captureRunning,
+		File:         fmt.Sprintf("contiv-capture-%s-%s.pcap", request.PodNamespace, request.PodName),
+	}
+	// remove the result of the previous capture, the file is re-created by VPP
+	os.Remove(filepath.Join(captureDir, capture.File))
+
+	_, err = c.cli(fmt.Sprintf("pcap tx trace on max %d intfc %s file %s", capture.MaxPackets, ifName, capture.File))
+	if err != nil {
+		return nil, err
+	}
+	c.logger.Infof("Started capture of %d packets on %s of the pod %s/%s", capture.MaxPackets, ifName,
+		request.PodNamespace, request.PodName)
+	c.capture = capture
+	c.timer = time.AfterFunc(duration, func() {
+		if err := c.stop(); err != nil {
+			c.logger.Warnf("Failed to stop capture on %s: %v", ifName, err)
+		}
+	})
+	return c.status(), nil
+}
+
+// stop stops the running capture, VPP writes the captured packets into the file.
+func (c *packetCapture) stop() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.capture == nil || c.capture.State != captureRunning {
+		return nil
+	}
+	c.timer.Stop()
+	c.updateCapturedPackets()
+	if c.capture.State != captureRunning {
+		// stopped by VPP after reaching the max. number of packets
+		return nil
+	}
+	if _, err := c.cli("pcap tx trace off"); err != nil {
+		c.capture.State = captureFailed
+		c.capture.Error = err.Error()
+		return err
+	}
+	c.capture.State = captureFinished
+	c.logger.Infof("Captured %d packets on %s into %s", c.capture.CapturedPackets, c.capture.Interface, c.capture.File)
+	return nil
+}
+
+// current returns the state of the last capture, nil if no capture was started.
+func (c *packetCapture) current() *PacketCapture {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.capture == nil {
+		return nil
+	}
+	if c.capture.State == captureRunning {
+		c.updateCapturedPackets()
+	}
+	return c.status()
+}
+
+// pcapFile returns the path to the file of the last finished capture.
+func (c *packetCapture) pcapFile() (string, error) {
+	capture := c.current()
+	if capture == nil || capture.State != captureFinished {
+		return "", fmt.Errorf("no finished capture")
+	}
+	return filepath.Join(captureDir, capture.File), nil
+}
+
+// updateCapturedPackets reads the number of the captured packets from VPP. The capture is finished
+// once VPP stops it after reaching the max. number of packets. The method must be called with the lock acquired.
+func (c *packetCapture) updateCapturedPackets() {
+	output, err := c.cli("pcap tx trace status")
+	if err != nil {
+		c.logger.Warnf("Failed to read status of the capture: %v", err)
+		return
+	}
+	if stats := pcapStatusRegexp.FindStringSubmatch(output); stats != nil {
+		captured, _ := strconv.ParseUint(stats[1], 10, 32)
+		c.capture.CapturedPackets = uint32(captured)
+		return
+	}
+	// VPP is not capturing, the file was written after the last packet
+	c.capture.CapturedPackets = c.capture.MaxPackets
+	c.capture.State = captureFinished
+	c.timer.Stop()
+}
+
+// status returns a copy of the last capture. The method must be called with the lock acquired.
+func (c *packetCapture) status() *PacketCapture {
+	capture := *c.capture
+	return &capture
+}
+
+// podCaptureInterface returns the name of the VPP interface of the pod as printed by VPP CLI.
+func (plugin *Plugin) podCaptureInterface(podNamespace, podName string) (string, error) {
+	podIf, err := plugin.podVPPInterface(podNamespace, podName)
+	if err != nil {
+		return "", err
+	}
+	return podIf.name, nil
+}
+
+// podVPPInterface looks up the VPP interface of the pod connected on this node.
+func (plugin *Plugin) podVPPInterface(podNamespace, podName string) (*podInterface, error) {
+	config := plugin.getContainerConfig(podNamespace, podName)
+	if config == nil || config.VppIf == nil {
+		return nil, fmt.Errorf("pod %s/%s is not connected on this node", podNamespace, podName)
+	}
+	podIf := &podInterface{}
+	if config.VppARPEntry != nil {
+		podIf.podIP = config.VppARPEntry.IpAddress
+	}
+	var found bool
+	podIf.swIfIndex, _, found = plugin.cniServer.swIfIndex.LookupIdx(config.VppIf.Name)
+	if !found {
+		return nil, fmt.Errorf("interface %s of the pod %s/%s is not configured", config.VppIf.Name, podNamespace, podName)
+	}
+	plugin.diagLock.Lock()
+	defer plugin.diagLock.Unlock()
+	var err error
+	podIf.name, err = vppInterfaceName(plugin.vppDiagChan(), podIf.swIfIndex)
+	if err != nil {
+		return nil, err
+	}
+	return podIf, nil
+}
+
+// vppDiagCLI executes VPP CLI command on the diagnostics channel.
+func (plugin *Plugin) vppDiagCLI(command string) (string, error) {
+	plugin.diagLock.Lock()
+	defer plugin.diagLock.Unlock()
+	return vppCLI(plugin.vppDiagChan(), command)
+}
+
+// vppInterfaceName returns the name VPP gives to the interface with the given index.
+func vppInterfaceName(ch *api.Channel, swIfIndex uint32) (string, error) {
+	name := ""
+	reqCtx := ch.SendMultiRequest(&interfaces.SwInterfaceDump{})
+	for {
+		details := &interfaces.SwInterfaceDetails{}
+		stop, err := reqCtx.ReceiveReply(details)
+		if stop {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if details.SwIfIndex == swIfIndex {
+			name = string(bytesUntilNull(details.InterfaceName))
+		}
+	}
+	if name == "" {
+		return "", fmt.Errorf("interface with index %d not found in VPP", swIfIndex)
+	}
+	return name, nil
+}
+
+// bytesUntilNull returns the bytes of the null-terminated string.
+func bytesUntilNull(data []byte) []byte {
+	for i, b := range data {
+		if b == 0 {
+			return data[:i]
+		}
+	}
+	return data
+}
+
+// registerCaptureHandlers registers REST handlers controlling the packet capture on pod interfaces:
+//   - Start capture of the packets sent to the pod:
+//     > curl -X POST -d '{"podNamespace":"default","podName":"nginx","maxPackets":100,"duration":10}' http://localhost:<port>/contiv/v1/capture
+//   - Inspect the last capture:
+//     > curl -X GET http://localhost:<port>/contiv/v1/capture
+//   - Stop the running capture:
+//     > curl -X DELETE http://localhost:<port>/contiv/v1/capture
+//   - Download the captured packets:
+//     > curl -X GET http://localhost:<port>/contiv/v1/capture/pcap -o capture.pcap
+func (plugin *Plugin) registerCaptureHandlers() {
+	plugin.HTTPHandlers.RegisterHTTPHandler(captureURL, plugin.startCaptureHandler, "POST")
+	plugin.HTTPHandlers.RegisterHTTPHandler(captureURL, plugin.captureHandler, "GET")
+	plugin.HTTPHandlers.RegisterHTTPHandler(captureURL, plugin.stopCaptureHandler, "DELETE")
+	plugin.HTTPHandlers.RegisterHTTPHandler(capturePcapURL, plugin.capturePcapHandler, "GET")
+}
+
+// startCaptureHandler processes requests to start the packet capture.
+func (plugin *Plugin) startCaptureHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		request := &CaptureRequest{}
+		if err := json.NewDecoder(req.Body).Decode(request); err != nil || request.PodName == "" {
+			formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{"pod expected"})
+			return
+		}
+		if request.PodNamespace == "" {
+			request.PodNamespace = "default"
+		}
+		if request.MaxPackets == 0 {
+			request.MaxPackets = defaultCaptureMaxPackets
+		}
+		duration := time.Duration(request.Duration) * time.Second
+		if duration == 0 {
+			duration = defaultCaptureDuration
+		}
+		if request.MaxPackets > maxCaptureMaxPackets || duration > maxCaptureDuration {
+			formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{
+				fmt.Sprintf("capture is limited to %d packets and %v", maxCaptureMaxPackets, maxCaptureDuration)})
+			return
+		}
+
+		capture, err := plugin.packetCapture.start(request, duration)
+		if err != nil {
+			plugin.Log.Errorf("Unable to start capture: %v", err)
+			formatter.JSON(w, http.StatusConflict, struct{ Error string }{err.Error()})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, capture)
+	}
+}
+
+// captureHandler processes requests to inspect the last packet capture.
+func (plugin *Plugin) captureHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		capture := plugin.packetCapture.current()
+		if capture == nil {
+			formatter.JSON(w, http.StatusNotFound, struct{ Error string }{"no capture was started"})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, capture)
+	}
+}
+
+// stopCaptureHandler processes requests to stop the running packet capture.
+func (plugin *Plugin) stopCaptureHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if err := plugin.packetCapture.stop(); err != nil {
+			plugin.Log.Errorf("Unable to stop capture: %v", err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		capture := plugin.packetCapture.current()
+		if capture == nil {
+			formatter.JSON(w, http.StatusNotFound, struct{ Error string }{"no capture was started"})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, capture)
+	}
+}
+
+// capturePcapHandler processes requests to download the packets of the last finished capture.
+func (plugin *Plugin) capturePcapHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		file, err := plugin.packetCapture.pcapFile()
+		if err != nil {
+			formatter.JSON(w, http.StatusNotFound, struct{ Error string }{err.Error()})
+			return
+		}
+		if _, err := os.Stat(file); err != nil {
+			formatter.JSON(w, http.StatusNotFound, struct{ Error string }{err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filepath.Base(file))
+		http.ServeFile(w, req, file)
+	}
+}
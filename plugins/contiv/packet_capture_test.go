@@ -0,0 +1,156 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+	"github.com/unrolled/render"
+)
+
+// pcapMock emulates the pcap tx trace of VPP.
+type pcapMock struct {
+	sync.Mutex
+	commands []string
+	captured int
+	max      int
+	on       bool
+}
+
+func (m *pcapMock) cli(command string) (string, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.commands = append(m.commands, command)
+	switch {
+	case strings.HasPrefix(command, "pcap tx trace on"):
+		fmt.Sscanf(command, "pcap tx trace on max %d", &m.max)
+		m.on, m.captured = true, 0
+	case command == "pcap tx trace off":
+		if !m.on {
+			return "", fmt.Errorf("pcap tx capture already off")
+		}
+		m.on = false
+	case command == "pcap tx trace status":
+		if m.on {
+			return fmt.Sprintf("pcap tx capture is on: %d of %d pkts...", m.captured, m.max), nil
+		}
+		return "pcap tx capture is off...", nil
+	}
+	return "", nil
+}
+
+func TestPacketCapture(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "capture")
+	gomega.Expect(err).To(gomega.BeNil())
+	defer os.RemoveAll(dir)
+	captureDir = dir
+	defer func() { captureDir = "/tmp" }()
+
+	vpp := &pcapMock{}
+	podInterface := func(podNamespace, podName string) (string, error) {
+		if podName != "nginx" {
+			return "", fmt.Errorf("pod %s/%s is not connected on this node", podNamespace, podName)
+		}
+		return "tap-1", nil
+	}
+	plugin := &Plugin{packetCapture: newPacketCapture(logrus.DefaultLogger(), vpp.cli, podInterface)}
+	plugin.Log = logging.ForPlugin("contiv", logrus.NewLogRegistry())
+
+	router := mux.NewRouter()
+	formatter := render.New()
+	router.HandleFunc(captureURL, plugin.startCaptureHandler(formatter)).Methods("POST")
+	router.HandleFunc(captureURL, plugin.captureHandler(formatter)).Methods("GET")
+	router.HandleFunc(captureURL, plugin.stopCaptureHandler(formatter)).Methods("DELETE")
+	router.HandleFunc(capturePcapURL, plugin.capturePcapHandler(formatter)).Methods("GET")
+	request := func(method, url, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return rec
+	}
+
+	// no capture yet
+	gomega.Expect(request("GET", captureURL, "").Code).To(gomega.BeEquivalentTo(http.StatusNotFound))
+	gomega.Expect(request("GET", capturePcapURL, "").Code).To(gomega.BeEquivalentTo(http.StatusNotFound))
+
+	// invalid requests
+	gomega.Expect(request("POST", captureURL, `{}`).Code).To(gomega.BeEquivalentTo(http.StatusBadRequest))
+	gomega.Expect(request("POST", captureURL, `{"podName":"nginx","maxPackets":100000}`).Code).
+		To(gomega.BeEquivalentTo(http.StatusBadRequest))
+	gomega.Expect(request("POST", captureURL, `{"podName":"unknown"}`).Code).
+		To(gomega.BeEquivalentTo(http.StatusConflict))
+
+	// start capture with the defaults
+	rec := request("POST", captureURL, `{"podName":"nginx"}`)
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	gomega.Expect(vpp.commands).To(gomega.ContainElement(
+		"pcap tx trace on max 100 intfc tap-1 file contiv-capture-default-nginx.pcap"))
+	// only one capture at a time
+	gomega.Expect(request("POST", captureURL, `{"podName":"nginx"}`).Code).To(gomega.BeEquivalentTo(http.StatusConflict))
+
+	// captured packets are reported
+	vpp.Lock()
+	vpp.captured = 5
+	vpp.Unlock()
+	capture := plugin.packetCapture.current()
+	gomega.Expect(capture.State).To(gomega.BeEquivalentTo(captureRunning))
+	gomega.Expect(capture.CapturedPackets).To(gomega.BeEquivalentTo(5))
+
+	// stop, VPP writes the file
+	rec = request("DELETE", captureURL, "")
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	gomega.Expect(rec.Body.String()).To(gomega.ContainSubstring(captureFinished))
+	gomega.Expect(vpp.commands).To(gomega.ContainElement("pcap tx trace off"))
+	ioutil.WriteFile(filepath.Join(dir, "contiv-capture-default-nginx.pcap"), []byte("pcap"), 0644)
+	rec = request("GET", capturePcapURL, "")
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	gomega.Expect(rec.Body.String()).To(gomega.BeEquivalentTo("pcap"))
+
+	// capture stopped by VPP after max. packets is not stopped again
+	_, err = plugin.packetCapture.start(&CaptureRequest{PodNamespace: "default", PodName: "nginx", MaxPackets: 10}, time.Minute)
+	gomega.Expect(err).To(gomega.BeNil())
+	vpp.Lock()
+	vpp.on = false
+	vpp.Unlock()
+	gomega.Expect(plugin.packetCapture.stop()).To(gomega.BeNil())
+	capture = plugin.packetCapture.current()
+	gomega.Expect(capture.State).To(gomega.BeEquivalentTo(captureFinished))
+	gomega.Expect(capture.CapturedPackets).To(gomega.BeEquivalentTo(10))
+
+	// capture is stopped after the duration
+	_, err = plugin.packetCapture.start(&CaptureRequest{PodNamespace: "default", PodName: "nginx", MaxPackets: 10},
+		10*time.Millisecond)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Eventually(func() bool {
+		vpp.Lock()
+		defer vpp.Unlock()
+		return vpp.on
+	}).Should(gomega.BeFalse())
+	gomega.Expect(plugin.packetCapture.current().State).To(gomega.BeEquivalentTo(captureFinished))
+}
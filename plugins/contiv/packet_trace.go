@@ -0,0 +1,312 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ligato/cn-infra/logging"
+	"github.com/unrolled/render"
+)
+
+const (
+	// traceURL is the URL of the REST API controlling the packet trace of VPP.
+	traceURL = "/contiv/v1/trace"
+
+	// limits of the number of packets traced by each input node
+	defaultTracePackets = 50
+	maxTracePackets     = 1000
+
+	// traceDropNode is the graph node of VPP dropping the packets
+	traceDropNode = "error-drop"
+)
+
+// traceInputNodes are the input nodes of VPP the packets of pods may enter VPP through: from pods connected
+// via AF_PACKET, TAPv1 and TAPv2 interfaces and from the physical interfaces (traffic from other nodes).
+var traceInputNodes = []string{"af-packet-input", "tapcli-rx", "virtio-input", "dpdk-input"}
+
+// TraceRequest is the body of the request starting the packet trace of a pod.
+type TraceRequest struct {
+	PodNamespace string `json:"podNamespace"`
+	PodName      string `json:"podName"`
+	Packets      uint32 `json:"packets,omitempty"` // traced by each input node, 50 by default, at most 1000
+}
+
+// PacketTrace is a packet traced by VPP decoded from the output of "show trace".
+type PacketTrace struct {
+	Thread int          `json:"thread"`
+	ID     int          `json:"id"`
+	Nodes  []*TraceNode `json:"nodes"`
+
+	// set if the packet was dropped: the node that dropped the packet and the reason
+	Dropped    bool   `json:"dropped"`
+	DropNode   string `json:"dropNode,omitempty"`
+	DropReason string `json:"dropReason,omitempty"`
+}
+
+// TraceNode is a graph node of VPP the traced packet passed through.
+type TraceNode struct {
+	Time string   `json:"time"`
+	Name string   `json:"name"`
+	Info []string `json:"info,omitempty"`
+}
+
+// regular expressions matching the output of "show trace"
+var (
+	traceThreadRegexp = regexp.MustCompile(`^-+ Start of thread (\d+)`)
+	tracePacketRegexp = regexp.MustCompile(`^Packet (\d+)$`)
+	traceNodeRegexp   = regexp.MustCompile(`^(\d{2}:\d{2}:\d{2}:\d+): (\S+)$`)
+)
+
+// packetTracer arms the packet trace of VPP on the input nodes and returns the traced packets of a pod.
+// The trace of VPP is global, the packets are filtered by the interface and the IP address of the pod.
+type packetTracer struct {
+	sync.Mutex
+	logger logging.Logger
+
+	// cli executes VPP CLI command
+	cli func(command string) (string, error)
+
+	// podInterface looks up the VPP interface of the pod
+	podInterface func(podNamespace, podName string) (*podInterface, error)
+}
+
+// newPacketTracer creates new instance of packetTracer.
+func newPacketTracer(logger logging.Logger, cli func(command string) (string, error),
+	podInterface func(podNamespace, podName string) (*podInterface, error)) *packetTracer {
+	return &packetTracer{
+		logger:       logger,
+		cli:          cli,
+		podInterface: podInterface,
+	}
+}
+
+// start clears the trace and starts tracing of the given number of packets on each input node.
+// Input nodes not present in VPP (e.g. dpdk-input without DPDK) are skipped.
+func (t *packetTracer) start(request *TraceRequest) error {
+	t.Lock()
+	defer t.Unlock()
+
+	if _, err := t.podInterface(request.PodNamespace, request.PodName); err != nil {
+		return err
+	}
+	if _, err := t.cli("clear trace"); err != nil {
+		return err
+	}
+	traced := 0
+	for _, node := range traceInputNodes {
+		output, err := t.cli(fmt.Sprintf("trace add %s %d", node, request.Packets))
+		if err != nil || strings.Contains(output, "not found") {
+			t.logger.Debugf("Packets not traced on %s: %v %s", node, err, strings.TrimSpace(output))
+			continue
+		}
+		traced++
+	}
+	if traced == 0 {
+		return fmt.Errorf("unable to trace packets on any of the input nodes %v", traceInputNodes)
+	}
+	t.logger.Infof("Tracing %d packets on the input nodes for the pod %s/%s", request.Packets,
+		request.PodNamespace, request.PodName)
+	return nil
+}
+
+// traces returns the traced packets of the pod.
+func (t *packetTracer) traces(podNamespace, podName string) ([]*PacketTrace, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	podIf, err := t.podInterface(podNamespace, podName)
+	if err != nil {
+		return nil, err
+	}
+	output, err := t.cli("show trace")
+	if err != nil {
+		return nil, err
+	}
+	traces := []*PacketTrace{}
+	for _, trace := range parsePacketTrace(output) {
+		if trace.matches(podIf) {
+			traces = append(traces, trace)
+		}
+	}
+	return traces, nil
+}
+
+// clear clears the trace of VPP.
+func (t *packetTracer) clear() error {
+	t.Lock()
+	defer t.Unlock()
+
+	_, err := t.cli("clear trace")
+	return err
+}
+
+// matches returns true if the packet passed through the interface of the pod or carries the IP address
+// of the pod. The interface is printed either by its name or by its index.
+func (p *PacketTrace) matches(podIf *podInterface) bool {
+	swIfIndex := strconv.FormatUint(uint64(podIf.swIfIndex), 10)
+	for _, node := range p.Nodes {
+		for _, info := range node.Info {
+			fields := strings.FieldsFunc(info, func(r rune) bool { return r == ' ' || r == ',' })
+			for i, field := range fields {
+				field = strings.TrimSuffix(field, ":")
+				switch {
+				case field == podIf.name || podIf.podIP != "" && field == podIf.podIP:
+					return true
+				case (field == "sw_if_index" || field == "tx_sw_if_index" || field == "rx_sw_if_index") &&
+					i+1 < len(fields) && fields[i+1] == swIfIndex:
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// parsePacketTrace decodes the output of "show trace".
+func parsePacketTrace(output string) []*PacketTrace {
+	var (
+		traces []*PacketTrace
+		trace  *PacketTrace
+		node   *TraceNode
+		thread int
+	)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		if match := traceThreadRegexp.FindStringSubmatch(line); match != nil {
+			thread, _ = strconv.Atoi(match[1])
+			trace, node = nil, nil
+			continue
+		}
+		if match := tracePacketRegexp.FindStringSubmatch(line); match != nil {
+			id, _ := strconv.Atoi(match[1])
+			trace = &PacketTrace{Thread: thread, ID: id}
+			traces = append(traces, trace)
+			node = nil
+			continue
+		}
+		if trace == nil {
+			continue
+		}
+		if match := traceNodeRegexp.FindStringSubmatch(line); match != nil {
+			node = &TraceNode{Time: match[1], Name: match[2]}
+			trace.Nodes = append(trace.Nodes, node)
+			continue
+		}
+		if node != nil && strings.TrimSpace(line) != "" {
+			node.Info = append(node.Info, strings.TrimSpace(line))
+		}
+	}
+
+	for _, trace := range traces {
+		for _, node := range trace.Nodes {
+			if node.Name != traceDropNode {
+				continue
+			}
+			// error-drop prints "<node>: <reason>"
+			trace.Dropped = true
+			if len(node.Info) > 0 {
+				reason := strings.SplitN(node.Info[0], ": ", 2)
+				trace.DropNode = reason[0]
+				if len(reason) > 1 {
+					trace.DropReason = reason[1]
+				}
+			}
+		}
+	}
+	return traces
+}
+
+// registerPacketTraceHandlers registers REST handlers controlling the packet trace of VPP:
+//   - Start tracing of the packets of the pod:
+//     > curl -X POST -d '{"podNamespace":"default","podName":"nginx","packets":50}' http://localhost:<port>/contiv/v1/trace
+//   - Read the traced packets of the pod:
+//     > curl -X GET "http://localhost:<port>/contiv/v1/trace?podNamespace=default&podName=nginx"
+//   - Clear the trace:
+//     > curl -X DELETE http://localhost:<port>/contiv/v1/trace
+func (plugin *Plugin) registerPacketTraceHandlers() {
+	plugin.HTTPHandlers.RegisterHTTPHandler(traceURL, plugin.startTraceHandler, "POST")
+	plugin.HTTPHandlers.RegisterHTTPHandler(traceURL, plugin.packetTraceHandler, "GET")
+	plugin.HTTPHandlers.RegisterHTTPHandler(traceURL, plugin.clearTraceHandler, "DELETE")
+}
+
+// startTraceHandler processes requests to start tracing of the packets of a pod.
+func (plugin *Plugin) startTraceHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		request := &TraceRequest{}
+		if err := json.NewDecoder(req.Body).Decode(request); err != nil || request.PodName == "" {
+			formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{"pod expected"})
+			return
+		}
+		if request.PodNamespace == "" {
+			request.PodNamespace = "default"
+		}
+		if request.Packets == 0 {
+			request.Packets = defaultTracePackets
+		}
+		if request.Packets > maxTracePackets {
+			formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{
+				fmt.Sprintf("trace is limited to %d packets", maxTracePackets)})
+			return
+		}
+		if err := plugin.packetTracer.start(request); err != nil {
+			plugin.Log.Errorf("Unable to start packet trace: %v", err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, request)
+	}
+}
+
+// packetTraceHandler processes requests to read the traced packets of a pod.
+func (plugin *Plugin) packetTraceHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		podNamespace, podName := req.URL.Query().Get("podNamespace"), req.URL.Query().Get("podName")
+		if podName == "" {
+			formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{"pod expected"})
+			return
+		}
+		if podNamespace == "" {
+			podNamespace = "default"
+		}
+		traces, err := plugin.packetTracer.traces(podNamespace, podName)
+		if err != nil {
+			plugin.Log.Errorf("Unable to read packet trace: %v", err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, traces)
+	}
+}
+
+// clearTraceHandler processes requests to clear the packet trace.
+func (plugin *Plugin) clearTraceHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if err := plugin.packetTracer.clear(); err != nil {
+			plugin.Log.Errorf("Unable to clear packet trace: %v", err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, struct{}{})
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+	"github.com/unrolled/render"
+)
+
+const showTraceOutput = `------------------- Start of thread 0 vpp_main -------------------
+Packet 1
+
+00:01:02:123456: af-packet-input
+  af_packet: hw_if_index 3 next-index 4
+    tpacket2_hdr:
+      status 0x20000001 len 98 snaplen 98 mac 66 net 80
+00:01:02:123470: ethernet-input
+  IP4: 00:00:00:aa:00:02 -> 01:23:45:67:89:42
+00:01:02:123480: ip4-input
+  ICMP: 10.1.1.2 -> 10.1.2.5
+    tos 0x00, ttl 64, length 84, checksum 0x1c22
+00:01:02:123490: ip4-lookup
+  fib 0 dpo-idx 0 flow hash: 0x00000000
+00:01:02:123500: ip4-drop
+    ICMP: 10.1.1.2 -> 10.1.2.5
+00:01:02:123510: error-drop
+  ip4-input: ip4 adjacency drop
+
+Packet 2
+
+00:01:03:000001: dpdk-input
+  GigabitEthernet0/8/0 rx queue 0
+00:01:03:000010: ip4-input
+  UDP: 192.168.16.2 -> 192.168.16.1
+00:01:03:000020: ip4-rewrite
+  tx_sw_if_index 1 dpo-idx 2 : ipv4 via 192.168.16.1 GigabitEthernet0/8/0
+------------------- Start of thread 1 vpp_wk_0 -------------------
+Packet 1
+
+00:01:04:000001: virtio-input
+  virtio: hw_if_index 4 next-index 4 vring 0 len 98
+00:01:04:000010: ip4-rewrite
+  tx_sw_if_index 3 dpo-idx 5 : ipv4 via 10.1.1.2 tap-1
+`
+
+func TestParsePacketTrace(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	traces := parsePacketTrace(showTraceOutput)
+	gomega.Expect(traces).To(gomega.HaveLen(3))
+
+	dropped := traces[0]
+	gomega.Expect(dropped.Thread).To(gomega.BeEquivalentTo(0))
+	gomega.Expect(dropped.ID).To(gomega.BeEquivalentTo(1))
+	gomega.Expect(dropped.Nodes).To(gomega.HaveLen(6))
+	gomega.Expect(dropped.Nodes[0].Name).To(gomega.BeEquivalentTo("af-packet-input"))
+	gomega.Expect(dropped.Nodes[0].Time).To(gomega.BeEquivalentTo("00:01:02:123456"))
+	gomega.Expect(dropped.Nodes[0].Info).To(gomega.HaveLen(3))
+	gomega.Expect(dropped.Dropped).To(gomega.BeTrue())
+	gomega.Expect(dropped.DropNode).To(gomega.BeEquivalentTo("ip4-input"))
+	gomega.Expect(dropped.DropReason).To(gomega.BeEquivalentTo("ip4 adjacency drop"))
+
+	gomega.Expect(traces[1].Dropped).To(gomega.BeFalse())
+	gomega.Expect(traces[2].Thread).To(gomega.BeEquivalentTo(1))
+	gomega.Expect(traces[2].ID).To(gomega.BeEquivalentTo(1))
+
+	// packets of the pod are matched by its IP address or interface
+	podIf := &podInterface{name: "tap-1", swIfIndex: 3, podIP: "10.1.1.2"}
+	gomega.Expect(traces[0].matches(podIf)).To(gomega.BeTrue())
+	gomega.Expect(traces[1].matches(podIf)).To(gomega.BeFalse())
+	gomega.Expect(traces[2].matches(&podInterface{name: "tap-1", swIfIndex: 3})).To(gomega.BeTrue())
+	gomega.Expect(traces[2].matches(&podInterface{name: "tap-2", swIfIndex: 7})).To(gomega.BeFalse())
+}
+
+func TestPacketTraceREST(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	commands := []string{}
+	cli := func(command string) (string, error) {
+		commands = append(commands, command)
+		switch {
+		case command == "show trace":
+			return showTraceOutput, nil
+		case command == "trace add dpdk-input 20":
+			return "node 'dpdk-input' not found", nil
+		}
+		return "", nil
+	}
+	podIf := func(podNamespace, podName string) (*podInterface, error) {
+		if podName != "nginx" {
+			return nil, fmt.Errorf("pod %s/%s is not connected on this node", podNamespace, podName)
+		}
+		return &podInterface{name: "tap-1", swIfIndex: 3, podIP: "10.1.1.2"}, nil
+	}
+	plugin := &Plugin{packetTracer: newPacketTracer(logrus.DefaultLogger(), cli, podIf)}
+	plugin.Log = logging.ForPlugin("contiv", logrus.NewLogRegistry())
+
+	router := mux.NewRouter()
+	formatter := render.New()
+	router.HandleFunc(traceURL, plugin.startTraceHandler(formatter)).Methods("POST")
+	router.HandleFunc(traceURL, plugin.packetTraceHandler(formatter)).Methods("GET")
+	router.HandleFunc(traceURL, plugin.clearTraceHandler(formatter)).Methods("DELETE")
+	request := func(method, url, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return rec
+	}
+
+	// start trace
+	gomega.Expect(request("POST", traceURL, `{"podName":"nginx","packets":5000}`).Code).
+		To(gomega.BeEquivalentTo(http.StatusBadRequest))
+	gomega.Expect(request("POST", traceURL, `{"podName":"unknown"}`).Code).
+		To(gomega.BeEquivalentTo(http.StatusInternalServerError))
+	gomega.Expect(request("POST", traceURL, `{"podName":"nginx","packets":20}`).Code).
+		To(gomega.BeEquivalentTo(http.StatusOK))
+	gomega.Expect(commands).To(gomega.ContainElement("clear trace"))
+	gomega.Expect(commands).To(gomega.ContainElement("trace add af-packet-input 20"))
+	gomega.Expect(commands).To(gomega.ContainElement("trace add virtio-input 20"))
+
+	// traced packets of the pod
+	rec := request("GET", traceURL+"?podNamespace=default&podName=nginx", "")
+	gomega.Expect(rec.Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	traces := []*PacketTrace{}
+	gomega.Expect(json.Unmarshal(rec.Body.Bytes(), &traces)).To(gomega.BeNil())
+	gomega.Expect(traces).To(gomega.HaveLen(2))
+	gomega.Expect(traces[0].DropNode).To(gomega.BeEquivalentTo("ip4-input"))
+
+	// clear
+	commands = nil
+	gomega.Expect(request("DELETE", traceURL, "").Code).To(gomega.BeEquivalentTo(http.StatusOK))
+	gomega.Expect(commands).To(gomega.Equal([]string{"clear trace"}))
+}
@@ -41,4 +41,12 @@ type API interface {
 	// GetVxlanBVIIfName returns the name of an BVI interface facing towards VXLAN tunnels to other hosts.
 	// Returns an empty string if VXLAN is not used (in L2 interconnect mode).
 	GetVxlanBVIIfName() string
+
+	// AdvertiseRoute starts (advertise=true) or stops advertising the route to the given prefix
+	// via this node to the BGP peers. Does nothing if BGP is not configured.
+	AdvertiseRoute(prefix *net.IPNet, advertise bool)
+
+	// ReportK8sEvent reports a Warning K8s Event with the given reason and message
+	// of the pod, or of this node if podName is empty. The event is recorded by KSR.
+	ReportK8sEvent(podNamespace, podName, reason, message string)
 }
@@ -14,6 +14,11 @@
 
 //go:generate protoc -I ./model/cni --go_out=plugins=grpc:./model/cni ./model/cni/cni.proto
 //go:generate protoc -I ./model/node --go_out=plugins=grpc:./model/node ./model/node/node.proto
+//go:generate protoc -I ./model/ipam --go_out=plugins=grpc:./model/ipam ./model/ipam/ipam.proto
+//go:generate protoc -I ./model/container --go_out=plugins=grpc:./model/container ./model/container/container.proto
+//go:generate protoc -I ./model/connectivity --go_out=plugins=grpc:./model/connectivity ./model/connectivity/connectivity.proto
+//go:generate protoc -I ./model/podconfig --go_out=plugins=grpc:./model/podconfig ./model/podconfig/podconfig.proto
+//go:generate protoc -I ./model/nodeconfig --go_out=plugins=grpc:./model/nodeconfig ./model/nodeconfig/nodeconfig.proto
 
 package contiv
 
@@ -21,18 +26,30 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
+	"sync"
+	"time"
 
 	"git.fd.io/govpp.git/api"
+	"github.com/contiv/vpp/flavors/ksr"
+	"github.com/contiv/vpp/plugins/contiv/bgp"
 	"github.com/contiv/vpp/plugins/contiv/containeridx"
 	"github.com/contiv/vpp/plugins/contiv/ipam"
 	"github.com/contiv/vpp/plugins/contiv/model/cni"
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/contiv/vpp/plugins/contiv/model/podconfig"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
+	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	"github.com/contiv/vpp/plugins/kvdbproxy"
+	"github.com/contiv/vpp/plugins/kvstore"
 	"github.com/ligato/cn-infra/datasync"
 	"github.com/ligato/cn-infra/datasync/resync"
-	"github.com/ligato/cn-infra/db/keyval/etcdv3"
 	"github.com/ligato/cn-infra/flavors/local"
 	"github.com/ligato/cn-infra/logging"
 	"github.com/ligato/cn-infra/rpc/grpc"
+	prometheusplugin "github.com/ligato/cn-infra/rpc/prometheus"
+	"github.com/ligato/cn-infra/rpc/rest"
+	"github.com/ligato/cn-infra/servicelabel"
 	"github.com/ligato/cn-infra/utils/safeclose"
 	"github.com/ligato/vpp-agent/clientv1/linux"
 	linuxlocalclient "github.com/ligato/vpp-agent/clientv1/linux/localclient"
@@ -46,18 +63,39 @@ type Plugin struct {
 	Deps
 	govppCh *api.Channel
 
+	// channel and lock used by REST handlers inspecting VPP
+	diagChan *api.Channel
+	diagLock sync.Mutex
+
+	packetCapture *packetCapture
+	packetTracer  *packetTracer
+
+	// connectivity check, nil if disabled
+	connectivityChan  *api.Channel
+	connectivityStore connectivityStore
+
+	// BGP speaker advertising the routes via this node, nil if disabled
+	bgpSpeaker *bgp.Speaker
+
+	// reporter of the K8s Events, the events are recorded by KSR
+	eventReporter *k8sEventReporter
+
 	configuredContainers *containeridx.ConfigIndex
 	cniServer            *remoteCNIserver
 
 	nodeIDAllocator   *idAllocator
+	nodeCache         *nodeCache
+	metrics           *metrics
 	nodeIDsresyncChan chan datasync.ResyncEvent
 	nodeIDSchangeChan chan datasync.ChangeEvent
 	nodeIDwatchReg    datasync.WatchRegistration
+	nodeEventsChan    chan datasync.ChangeEvent
 
 	ctx           context.Context
 	ctxCancelFunc context.CancelFunc
 
 	Config        *Config
+	fileConfig    *Config // config as loaded from the config file (nil if injected), updated by the reload
 	myNodeConfig  *OneNodeConfig
 	nodeIPWatcher chan string
 }
@@ -70,8 +108,17 @@ type Deps struct {
 	VPP     *defaultplugins.Plugin
 	GoVPP   govppmux.API
 	Resync  resync.Subscriber
-	ETCD    *etcdv3.Plugin
+	KVStore kvstore.KvStore
 	Watcher datasync.KeyValProtoWatcher
+
+	// NodeIDStore is optional, allocations of node IDs are stored in KVStore if not injected.
+	NodeIDStore NodeIDStore
+
+	// HTTPHandlers is optional, used to expose REST API inspecting the node ID allocations.
+	HTTPHandlers rest.HTTPHandlers
+
+	// Prometheus is optional, used to expose metrics of the plugin internals.
+	Prometheus prometheusplugin.API
 }
 
 // Config represents configuration for the Contiv plugin.
@@ -81,19 +128,63 @@ type Config struct {
 	TCPChecksumOffloadDisabled bool
 	TCPstackDisabled           bool
 	UseL2Interconnect          bool
+	InterNodeTransport         string // vxlan, nooverlay or srv6, overrides UseL2Interconnect if set
+	SRv6                       SRv6Config
+	IPSec                      IPSecConfig
 	UseTAPInterfaces           bool
 	TAPInterfaceVersion        uint8
+	PodInterconnect            string // auto, tapv1, tapv2 or veth, overrides UseTAPInterfaces and TAPInterfaceVersion if set
 	TAPv2RxRingSize            uint16
 	TAPv2TxRingSize            uint16
+	MTUSize                    uint32 // MTU of the node interconnect, detected from the main VPP interface if not set
+	VlanID                     uint16 // 802.1Q VLAN of the main VPP interface, untagged if 0
+	UplinkProbeInterval        uint32 // interval of the link state checks of the uplinks in seconds, 2 by default
+	NodeIDLeaseTTL             uint32
+	RestoreNodeIDEntry         bool
+	NodeIDRanges               []NodeIDRange
+	NodeIDDerivation           string
+	NodeIDGCDisabled           bool
+	NodeIDGCInterval           uint32
+	UseK8sPodCIDR              bool
 	IPAMConfig                 ipam.Config
 	NodeConfig                 []OneNodeConfig
+	SecondaryNetworks          []SecondaryNetwork
+	MaxParallelPodRequests     int // max. number of CNI requests of different pods processed in parallel, 8 by default
+	FlowExport                 FlowExportConfig
+	ConnectivityCheck          ConnectivityCheckConfig
+	VPPSupervision             VPPSupervisionConfig
+	BGP                        bgp.Config
+}
+
+// NodeIDRange represents a range of node IDs reserved for the nodes with matching labels.
+type NodeIDRange struct {
+	NodeLabels map[string]string // labels the node needs to have to allocate its ID from the range, empty matches any node
+	MinID      uint32            // the lowest ID of the range (inclusive)
+	MaxID      uint32            // the highest ID of the range (inclusive)
+}
+
+// SecondaryNetwork defines a network pods can attach additional interfaces to
+// by listing it in the k8s.v1.cni.cncf.io/networks annotation.
+type SecondaryNetwork struct {
+	Name            string // name of the network referenced by the pod annotation
+	InterfaceType   string // type of the pod interfaces: "veth" (default), "tap", "memif" or "vhostuser"
+	IPPool          string // IPAM pool (IPAMConfig.NamespacePools) the interfaces get IP addresses from
+	MemifSocketDir  string // host directory with memif sockets to be mounted into pods, "/run/vpp/memif" by default
+	MemifRingSize   uint32 // number of entries of memif rings (power of 2), 1024 by default
+	MemifBufferSize uint32 // size of memif buffers in bytes, 2048 by default
+	MemifQueues     uint32 // number of memif RX and TX queues, 1 by default
+
+	VhostUserSocketDir string // host directory with vhost-user sockets to be mounted into pods, "/run/vpp/vhost-user" by default
 }
 
 // OneNodeConfig represents configuration for one node. It contains only settings specific to given node.
 type OneNodeConfig struct {
 	NodeName           string            // name of the node, should match withs the hostname
 	MainVppInterface   InterfaceWithIP   // main VPP interface used for the inter-node connectivity
+	Bond               BondConfig        // bond of NICs used as the main VPP interface if members are listed
+	VlanID             uint16            // 802.1Q VLAN of the main VPP interface, overrides the global VlanID
 	OtherVPPInterfaces []InterfaceWithIP // other interfaces on VPP, not necessarily used for inter-node connectivity
+	UplinkInterfaces   []UplinkInterface // interfaces sharing the inter-node traffic with the main interface (ECMP)
 	Gateway            string            // IP address of the default gateway
 }
 
@@ -115,10 +206,18 @@ func (plugin *Plugin) Init() error {
 		if err := plugin.loadExternalConfig(); err != nil {
 			return err
 		}
-		plugin.myNodeConfig = plugin.loadNodeSpecificConfig()
+		fileConfig := *plugin.Config
+		plugin.fileConfig = &fileConfig
+		plugin.myNodeConfig = loadNodeSpecificConfig(plugin.Config, plugin.ServiceLabel.GetAgentLabel())
+	}
+	fileNodeConfig := plugin.myNodeConfig
+	// the entry of the node in the data store overrides the config file
+	nodeConfigStored, err := plugin.loadNodeConfigFromStore()
+	if err != nil {
+		return err
 	}
 
-	var err error
+	plugin.metrics = newMetrics()
 	plugin.govppCh, err = plugin.GoVPP.NewAPIChannel()
 	if err != nil {
 		return err
@@ -129,17 +228,80 @@ func (plugin *Plugin) Init() error {
 	if plugin.myNodeConfig != nil {
 		nodeIP = plugin.myNodeConfig.MainVppInterface.IP
 	}
-	plugin.nodeIDAllocator = newIDAllocator(plugin.ETCD, plugin.ServiceLabel.GetAgentLabel(), nodeIP)
+	if plugin.NodeIDStore == nil {
+		plugin.NodeIDStore = newKVIDStore(plugin.KVStore)
+	}
+	// once resynced, the allocations are read from the cache fed by the watch below
+	plugin.nodeCache = newNodeCache(plugin.NodeIDStore)
+	plugin.NodeIDStore = plugin.nodeCache
+	switch plugin.Config.NodeIDDerivation {
+	case "", FirstFreeNodeID, NodeNameHashNodeID, NodeIPHashNodeID:
+	default:
+		return fmt.Errorf("unsupported node ID derivation: %v", plugin.Config.NodeIDDerivation)
+	}
+	// node as reflected by KSR is needed to select the ID range or to read the pod CIDR,
+	// otherwise it only provides metadata
+	k8sNodeAttempts := 1
+	if len(plugin.Config.NodeIDRanges) > 0 || plugin.Config.UseK8sPodCIDR {
+		k8sNodeAttempts = maxAttempts
+	}
+	k8sNode, err := plugin.loadK8sNode(k8sNodeAttempts)
+	if err != nil {
+		return err
+	}
+	podNetwork := ""
+	if plugin.Config.UseK8sPodCIDR {
+		if k8sNode == nil || k8sNode.Pod_CIDR == "" {
+			return fmt.Errorf("pod CIDR of the node %v was not assigned by k8s", plugin.ServiceLabel.GetAgentLabel())
+		}
+		podNetwork = k8sNode.Pod_CIDR
+		plugin.Config.IPAMConfig.NodePodNetworkCIDR = podNetwork
+		plugin.Log.Infof("Using pod network %v assigned by k8s", podNetwork)
+	}
+	var nodeIDRange *NodeIDRange
+	if len(plugin.Config.NodeIDRanges) > 0 {
+		if k8sNode == nil {
+			return fmt.Errorf("node %v was not reflected by KSR, unable to determine its labels",
+				plugin.ServiceLabel.GetAgentLabel())
+		}
+		nodeIDRange, err = selectNodeIDRange(plugin.Config.NodeIDRanges, k8sNodeLabels(k8sNode))
+		if err != nil {
+			return err
+		}
+		plugin.Log.Infof("Node ID will be allocated from the range %v-%v", nodeIDRange.MinID, nodeIDRange.MaxID)
+	}
+	plugin.nodeIDAllocator = newIDAllocator(plugin.Log, plugin.NodeIDStore, plugin.ServiceLabel.GetAgentLabel(), nodeIP,
+		k8sNodeMgmtIP(k8sNode), podNetwork,
+		time.Duration(plugin.Config.NodeIDLeaseTTL)*time.Second, plugin.Config.RestoreNodeIDEntry, nodeIDRange,
+		plugin.Config.NodeIDDerivation)
+	plugin.nodeIDAllocator.metrics = plugin.metrics
 	nodeID, err := plugin.nodeIDAllocator.getID()
 	if err != nil {
 		return err
 	}
 	plugin.Log.Infof("ID of the node is %v", nodeID)
 
+	// keep the lease of the allocated ID alive
+	go plugin.nodeIDAllocator.refreshLease(plugin.ctx)
+
+	// remove IDs of the nodes deleted from the cluster
+	var collector *nodeIDCollector
+	if !plugin.Config.NodeIDGCDisabled {
+		gcInterval := time.Duration(plugin.Config.NodeIDGCInterval) * time.Second
+		if gcInterval == 0 {
+			gcInterval = defaultNodeIDGCInterval
+		}
+		collector = newNodeIDCollector(plugin.Log, plugin.NodeIDStore, plugin.ServiceLabel.GetAgentLabel(),
+			plugin.listK8sNodeNames, gcInterval)
+		go collector.run(plugin.ctx, gcInterval)
+	}
+
 	plugin.nodeIDsresyncChan = make(chan datasync.ResyncEvent)
 	plugin.nodeIDSchangeChan = make(chan datasync.ChangeEvent)
+	plugin.nodeEventsChan = make(chan datasync.ChangeEvent)
 
-	plugin.nodeIDwatchReg, err = plugin.Watcher.Watch("contiv-plugin", plugin.nodeIDSchangeChan, plugin.nodeIDsresyncChan, allocatedIDsKeyPrefix)
+	plugin.nodeIDwatchReg, err = plugin.Watcher.Watch("contiv-plugin", plugin.nodeIDSchangeChan, plugin.nodeIDsresyncChan,
+		allocatedIDsKeyPrefix, nodemodel.KeyPrefix(), nodeConfigKeyPrefix)
 	if err != nil {
 		return err
 	}
@@ -160,14 +322,91 @@ func (plugin *Plugin) Init() error {
 	if err != nil {
 		return fmt.Errorf("Can't create new remote CNI server due to error: %v ", err)
 	}
+	plugin.cniServer.nodeCache = plugin.nodeCache
+	if collector != nil {
+		plugin.cniServer.k8sNodeDeleted = collector.nodeDeleted
+	}
+	plugin.nodeCache.setPodNetwork(func(entry *node.NodeInfo) *net.IPNet {
+		return nodePodNetwork(plugin.cniServer.ipam, entry)
+	})
+	plugin.cniServer.fileNodeConfig = fileNodeConfig
+	plugin.cniServer.nodeConfigStored = nodeConfigStored
+	// the bond and the VLAN sub-interface have to exist before the resync of the VPP agent
+	if err := plugin.cniServer.configureBond(); err != nil {
+		return err
+	}
+	if err := plugin.cniServer.configureVlanSubIf(); err != nil {
+		return err
+	}
+	err = plugin.cniServer.ipam.SetAllocationStore(newKVIPAMStore(plugin.KVStore, plugin.ServiceLabel.GetAgentLabel()))
+	if err != nil {
+		return fmt.Errorf("Can't restore allocated pod IPs: %v", err)
+	}
+	plugin.cniServer.probeChan, err = plugin.GoVPP.NewAPIChannel()
+	if err != nil {
+		return err
+	}
+	plugin.diagChan, err = plugin.GoVPP.NewAPIChannel()
+	if err != nil {
+		return err
+	}
+	if plugin.StatusCheck != nil {
+		plugin.StatusCheck.Register(plugin.PluginName, plugin.cniServer.dataplaneProbe)
+	}
+	plugin.cniServer.metrics = plugin.metrics
+	if plugin.Prometheus != nil {
+		if err := plugin.metrics.register(plugin.Prometheus, plugin.cniServer.ipam); err != nil {
+			return fmt.Errorf("Can't register metrics: %v", err)
+		}
+	}
+	plugin.cniServer.containerStore = newKVContainerStore(plugin.KVStore, plugin.ServiceLabel.GetAgentLabel())
+	plugin.cniServer.listPods = plugin.listK8sPods
+	plugin.cniServer.getPodAnnotations = plugin.getK8sPodAnnotations
+	plugin.cniServer.publishPodAnnotations = plugin.publishK8sPodAnnotations
+	plugin.eventReporter = newK8sEventReporter(plugin.Log, plugin.KVStore, plugin.ServiceLabel.GetAgentLabel())
+	plugin.cniServer.reportK8sEvent = plugin.eventReporter.report
 	cni.RegisterRemoteCNIServer(plugin.GRPC.Server(), plugin.cniServer)
+	podconfig.RegisterPodNetworkConfigServer(plugin.GRPC.Server(), plugin.cniServer)
+
+	plugin.packetCapture = newPacketCapture(plugin.Log, plugin.vppDiagCLI, plugin.podCaptureInterface)
+	plugin.packetTracer = newPacketTracer(plugin.Log, plugin.vppDiagCLI, plugin.podVPPInterface)
+	if err := plugin.startConnectivityCheck(); err != nil {
+		return err
+	}
+
+	// detect outages and restarts of VPP
+	if !plugin.Config.VPPSupervision.Disabled {
+		supervisor := newVPPSupervisor(plugin.Log, plugin.Config.VPPSupervision, plugin.metrics, plugin.GoVPP.NewAPIChannel)
+		go supervisor.run(plugin.ctx)
+	}
+
+	if plugin.Config.BGP.Enabled() {
+		plugin.bgpSpeaker, err = bgp.NewSpeaker(plugin.Log.NewLogger("-bgp"), plugin.Config.BGP)
+		if err != nil {
+			return err
+		}
+		plugin.bgpSpeaker.Advertise(plugin.cniServer.ipam.PodNetwork())
+		plugin.bgpSpeaker.Start(plugin.ctx)
+	}
+
+	if plugin.cniServer.useIPSec {
+		go plugin.cniServer.runIPSecRekey(plugin.ctx)
+	}
+	if len(plugin.cniServer.uplinks) > 0 {
+		go plugin.cniServer.runUplinkMonitor(plugin.ctx)
+	}
+
+	if plugin.fileConfig != nil {
+		go plugin.watchConfigReload()
+	}
 
 	plugin.nodeIPWatcher = make(chan string)
 	go plugin.watchNodeIP()
 	plugin.cniServer.WatchNodeIP(plugin.nodeIPWatcher)
 
-	// start goroutine handling changes in nodes within the k8s cluster
-	go plugin.cniServer.handleNodeEvents(plugin.ctx, plugin.nodeIDsresyncChan, plugin.nodeIDSchangeChan)
+	// start goroutines handling changes in nodes within the k8s cluster
+	go plugin.watchNodeIDs()
+	go plugin.cniServer.handleNodeEvents(plugin.ctx, plugin.nodeIDsresyncChan, plugin.nodeEventsChan)
 
 	return nil
 }
@@ -175,11 +414,25 @@ func (plugin *Plugin) Init() error {
 // AfterInit is called by the plugin infra after Init of all plugins is finished.
 // It registers to the ResyncOrchestrator. The registration is done in this phase
 // in order to trigger the resync for this plugin once the resync of VPP plugins is finished.
+// REST handlers are registered here as well, if the HTTP server is available.
 func (plugin *Plugin) AfterInit() error {
 	if plugin.Resync != nil {
 		reg := plugin.Resync.Register(string(plugin.PluginName))
 		go plugin.handleResync(reg.StatusChan())
 	}
+	if plugin.HTTPHandlers != nil {
+		plugin.registerHandlers()
+		plugin.registerTraceHandlers()
+		plugin.registerDiagnosticsHandlers()
+		plugin.registerCaptureHandlers()
+		plugin.registerPacketTraceHandlers()
+		if plugin.connectivityStore != nil {
+			plugin.HTTPHandlers.RegisterHTTPHandler(connectivityURL, plugin.connectivityHandler, "GET")
+		}
+		if plugin.cniServer.flowExportEnabled() {
+			plugin.HTTPHandlers.RegisterHTTPHandler(flowMetadataURL, plugin.flowMetadataHandler, "GET")
+		}
+	}
 	return nil
 }
 
@@ -188,7 +441,8 @@ func (plugin *Plugin) Close() error {
 	plugin.ctxCancelFunc()
 	plugin.cniServer.close()
 	plugin.nodeIDAllocator.releaseID()
-	_, err := safeclose.CloseAll(plugin.govppCh, plugin.nodeIDwatchReg)
+	_, err := safeclose.CloseAll(plugin.govppCh, plugin.cniServer.probeChan, plugin.diagChan, plugin.connectivityChan,
+		plugin.nodeIDwatchReg)
 	return err
 }
 
@@ -264,6 +518,27 @@ func (plugin *Plugin) GetVxlanBVIIfName() string {
 	return plugin.cniServer.GetVxlanBVIIfName()
 }
 
+// AdvertiseRoute starts or stops advertising the route to the given prefix via this node
+// to the BGP peers. Does nothing if BGP is not configured.
+func (plugin *Plugin) AdvertiseRoute(prefix *net.IPNet, advertise bool) {
+	if plugin.bgpSpeaker == nil {
+		return
+	}
+	if advertise {
+		plugin.bgpSpeaker.Advertise(prefix)
+	} else {
+		plugin.bgpSpeaker.Withdraw(prefix)
+	}
+}
+
+// ReportK8sEvent reports a Warning K8s Event of the pod, or of this node if podName is empty.
+func (plugin *Plugin) ReportK8sEvent(podNamespace, podName, reason, message string) {
+	if plugin.eventReporter == nil {
+		return
+	}
+	plugin.eventReporter.report(podNamespace, podName, reason, message)
+}
+
 // handleResync handles resync events of the plugin. Called automatically by the plugin infra.
 func (plugin *Plugin) handleResync(resyncChan chan resync.StatusEvent) {
 	for {
@@ -271,10 +546,8 @@ func (plugin *Plugin) handleResync(resyncChan chan resync.StatusEvent) {
 		case ev := <-resyncChan:
 			status := ev.ResyncStatus()
 			if status == resync.Started {
-				err := plugin.cniServer.resync()
-				if err != nil {
-					plugin.Log.Error(err)
-				}
+				// the failure is logged by the event loop
+				plugin.cniServer.resync()
 			}
 			ev.Ack()
 		case <-plugin.ctx.Done():
@@ -285,34 +558,185 @@ func (plugin *Plugin) handleResync(resyncChan chan resync.StatusEvent) {
 
 // loadExternalConfig attempts to load external configuration from a YAML file.
 func (plugin *Plugin) loadExternalConfig() error {
+	externalCfg, err := plugin.readExternalConfig()
+	if err != nil {
+		return err
+	}
+	plugin.Config = externalCfg
+	return nil
+}
+
+// readExternalConfig reads the external configuration from the YAML file.
+func (plugin *Plugin) readExternalConfig() (*Config, error) {
 	externalCfg := &Config{}
 	found, err := plugin.PluginConfig.GetValue(externalCfg) // It tries to lookup `PluginName + "-config"` in the executable arguments.
 	if err != nil {
-		return fmt.Errorf("External Contiv plugin configuration could not load or other problem happened: %v", err)
+		return nil, fmt.Errorf("External Contiv plugin configuration could not load or other problem happened: %v", err)
 	}
 	if !found {
-		return fmt.Errorf("External Contiv plugin configuration was not found")
+		return nil, fmt.Errorf("External Contiv plugin configuration was not found")
 	}
-	plugin.Config = externalCfg
 
 	// use tap version 2 as default in case that TAPs are enabled
-	if plugin.Config.TAPInterfaceVersion == 0 {
-		plugin.Config.TAPInterfaceVersion = 2
+	if externalCfg.TAPInterfaceVersion == 0 {
+		externalCfg.TAPInterfaceVersion = 2
 	}
 
-	return nil
+	return externalCfg, nil
 }
 
-// loadNodeSpecificConfig loads config specific for this node (given by its agent label).
-func (plugin *Plugin) loadNodeSpecificConfig() *OneNodeConfig {
-	for _, oneNodeConfig := range plugin.Config.NodeConfig {
-		if oneNodeConfig.NodeName == plugin.ServiceLabel.GetAgentLabel() {
+// loadNodeSpecificConfig loads config specific for the node given by its agent label.
+func loadNodeSpecificConfig(config *Config, agentLabel string) *OneNodeConfig {
+	for _, oneNodeConfig := range config.NodeConfig {
+		if oneNodeConfig.NodeName == agentLabel {
 			return &oneNodeConfig
 		}
 	}
 	return nil
 }
 
+// loadK8sNode loads this node as reflected into KVStore by KSR. Since KSR may not have reflected the node yet,
+// the lookup is repeated up to the given number of attempts. Nil is returned if the node was not found.
+// If the pod CIDR assigned by k8s is used, the lookup is repeated also until the pod CIDR is assigned.
+func (plugin *Plugin) loadK8sNode(attempts int) (*nodemodel.Node, error) {
+	broker := plugin.KVStore.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel))
+
+	k8sNode := &nodemodel.Node{}
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+		found, _, err := broker.GetValue(nodemodel.Key(plugin.ServiceLabel.GetAgentLabel()), k8sNode)
+		if err != nil {
+			return nil, err
+		}
+		if found && (!plugin.Config.UseK8sPodCIDR || k8sNode.Pod_CIDR != "") {
+			return k8sNode, nil
+		}
+	}
+	if k8sNode.Name != "" {
+		// reflected, but the pod CIDR is not assigned yet
+		return k8sNode, nil
+	}
+	return nil, nil
+}
+
+// getK8sPodAnnotations returns annotations of the pod as reflected into KVStore by KSR.
+// Nil is returned if the pod was not reflected (yet).
+func (plugin *Plugin) getK8sPodAnnotations(podNamespace, podName string) (map[string]string, error) {
+	broker := plugin.KVStore.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel))
+
+	k8sPod := &podmodel.Pod{}
+	found, _, err := broker.GetValue(podmodel.Key(podName, podNamespace), k8sPod)
+	if err != nil || !found {
+		return nil, err
+	}
+	annotations := map[string]string{}
+	for _, annotation := range k8sPod.Annotation {
+		annotations[annotation.Key] = annotation.Value
+	}
+	return annotations, nil
+}
+
+// publishK8sPodAnnotations publishes request for KSR to annotate the pod into KVStore.
+// Empty annotations withdraw the request.
+func (plugin *Plugin) publishK8sPodAnnotations(podNamespace, podName string, annotations map[string]string) error {
+	broker := plugin.KVStore.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel))
+	key := podmodel.AnnotationsKey(podName, podNamespace)
+
+	if len(annotations) == 0 {
+		_, err := broker.Delete(key)
+		return err
+	}
+	request := &podmodel.Pod{
+		Name:      podName,
+		Namespace: podNamespace,
+	}
+	for annotationKey, annotationValue := range annotations {
+		request.Annotation = append(request.Annotation, &podmodel.Pod_Annotation{Key: annotationKey, Value: annotationValue})
+	}
+	return broker.Put(key, request)
+}
+
+// k8sNodeLabels returns labels of the reflected k8s node.
+func k8sNodeLabels(k8sNode *nodemodel.Node) map[string]string {
+	labels := map[string]string{}
+	for _, label := range k8sNode.Label {
+		labels[label.Key] = label.Value
+	}
+	return labels
+}
+
+// k8sNodeMgmtIP returns the management IP address of the reflected k8s node (internal IP preferred),
+// empty string if the node is not known.
+func k8sNodeMgmtIP(k8sNode *nodemodel.Node) string {
+	if k8sNode == nil {
+		return ""
+	}
+	mgmtIP := ""
+	for _, address := range k8sNode.Addresses {
+		if address.Type == nodemodel.NodeAddress_NodeInternalIP {
+			return address.Address
+		}
+		if address.Type == nodemodel.NodeAddress_NodeExternalIP && mgmtIP == "" {
+			mgmtIP = address.Address
+		}
+	}
+	return mgmtIP
+}
+
+// listK8sNodeNames returns names of all k8s nodes reflected into KVStore by KSR,
+// served from the node cache once it is resynced.
+func (plugin *Plugin) listK8sNodeNames() ([]string, error) {
+	if names, synced := plugin.nodeCache.listK8sNodeNames(); synced {
+		return names, nil
+	}
+	broker := plugin.KVStore.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel))
+	it, err := broker.ListValues(nodemodel.KeyPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+		k8sNode := &nodemodel.Node{}
+		err = kv.GetValue(k8sNode)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, k8sNode.Name)
+	}
+	return names, nil
+}
+
+// listK8sPods returns keys (namespace/name) of all k8s PODs reflected into KVStore by KSR.
+func (plugin *Plugin) listK8sPods() ([]string, error) {
+	broker := plugin.KVStore.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel))
+	it, err := broker.ListValues(podmodel.KeyPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	var pods []string
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+		k8sPod := &podmodel.Pod{}
+		err = kv.GetValue(k8sPod)
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, k8sPod.Namespace+"/"+k8sPod.Name)
+	}
+	return pods, nil
+}
+
 // getContainerConfig returns the configuration of the container associated with the given POD name.
 func (plugin *Plugin) getContainerConfig(podNamespace string, podName string) *containeridx.Config {
 	podNamesMatch := plugin.configuredContainers.LookupPodName(podName)
@@ -332,6 +756,26 @@ func (plugin *Plugin) getContainerConfig(podNamespace string, podName string) *c
 	return nil
 }
 
+// watchNodeIDs checks changes of the allocated node IDs for conflicts with ID of this node
+// and passes them, together with changes of the nodes reflected from k8s, to the CNI server.
+func (plugin *Plugin) watchNodeIDs() {
+	for {
+		select {
+		case changeEv := <-plugin.nodeIDSchangeChan:
+			if strings.HasPrefix(changeEv.GetKey(), allocatedIDsKeyPrefix) {
+				plugin.nodeIDAllocator.checkChange(changeEv)
+			}
+			select {
+			case plugin.nodeEventsChan <- changeEv:
+			case <-plugin.ctx.Done():
+				return
+			}
+		case <-plugin.ctx.Done():
+			return
+		}
+	}
+}
+
 func (plugin *Plugin) watchNodeIP() {
 	for {
 		select {
@@ -341,6 +785,9 @@ func (plugin *Plugin) watchNodeIP() {
 				if err != nil {
 					plugin.Log.Error(err)
 				}
+				if nodeIP, _, err := net.ParseCIDR(newIP); err == nil && plugin.bgpSpeaker != nil {
+					plugin.bgpSpeaker.SetNextHop(nodeIP)
+				}
 			}
 		case <-plugin.ctx.Done():
 		}
@@ -81,9 +81,58 @@ func (s *remoteCNIserver) getPIDFromNwNsPath(ns string) (int, error) {
 	return pid, nil
 }
 
+// checkPodInterface verifies that the POD interface exists in the network namespace of the container,
+// and that it has the IP address of the POD and the default route assigned.
+func (s *remoteCNIserver) checkPodInterface(request *cni.CNIRequest, podIP string) error {
+	containerNs := &linux_intf.LinuxInterfaces_Interface_Namespace{
+		Type:     linux_intf.LinuxInterfaces_Interface_Namespace_FILE_REF_NS,
+		Filepath: request.NetworkNamespace,
+	}
+	nsMgmtCtx := linuxcalls.NewNamespaceMgmtCtx()
+
+	// Switch to the namespace of the container.
+	revertNs, err := linuxcalls.ToGenericNs(containerNs).SwitchNamespace(nsMgmtCtx, s.Logger)
+	if err != nil {
+		return fmt.Errorf("can't enter network namespace %s: %v", request.NetworkNamespace, err)
+	}
+	defer revertNs()
+
+	dev, err := netlink.LinkByName(request.InterfaceName)
+	if err != nil {
+		return fmt.Errorf("interface %s not found in the pod: %v", request.InterfaceName, err)
+	}
+
+	addrs, err := netlink.AddrList(dev, netlink.FAMILY_V4)
+	if err != nil {
+		return err
+	}
+	hasIP := false
+	for _, addr := range addrs {
+		if addr.IP.String() == podIP {
+			hasIP = true
+			break
+		}
+	}
+	if !hasIP {
+		return fmt.Errorf("interface %s of the pod does not have IP address %s", request.InterfaceName, podIP)
+	}
+
+	routes, err := netlink.RouteList(dev, netlink.FAMILY_V4)
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		if route.Dst == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("default route via %s is missing in the pod", request.InterfaceName)
+}
+
 // configureHostTAP configures TAP interface created in the host by VPP.
 // TODO: move to the linuxplugin
-func (s *remoteCNIserver) configureHostTAP(request *cni.CNIRequest, podIPNet *net.IPNet, vppHw string) error {
+// The IPv6 configuration of the pod is skipped if podIPv6Net is nil.
+func (s *remoteCNIserver) configureHostTAP(request *cni.CNIRequest, podIPNet *net.IPNet, podIPv6Net *net.IPNet, vppHw string) error {
 	tapTmpHostIfName := s.tapTmpHostNameFromRequest(request)
 	tapHostIfName := s.tapHostNameFromRequest(request)
 	containerNs := &linux_intf.LinuxInterfaces_Interface_Namespace{
@@ -127,6 +176,14 @@ func (s *remoteCNIserver) configureHostTAP(request *cni.CNIRequest, podIPNet *ne
 		return err
 	}
 
+	// Leave room for the encapsulation between the nodes (see configureMTU).
+	if s.podMTU != 0 {
+		err = linuxcalls.SetInterfaceMTU(tapHostIfName, int(s.podMTU), nil)
+		if err != nil {
+			return err
+		}
+	}
+
 	// FIXME: following items ARP + link scope route + default route should be configured by linux plugin
 	dev, err := netlink.LinkByName(request.InterfaceName)
 	if err != nil {
@@ -165,11 +222,125 @@ func (s *remoteCNIserver) configureHostTAP(request *cni.CNIRequest, podIPNet *ne
 		return err
 	}
 
-	return l3_linux.AddStaticRoute("pod default route", &netlink.Route{
+	err = l3_linux.AddStaticRoute("pod default route", &netlink.Route{
 		LinkIndex: dev.Attrs().Index,
 		Dst:       defaultDst,
 		Gw:        s.ipam.PodGatewayIP(),
 	}, s.Logger, nil)
+	if err != nil || podIPv6Net == nil {
+		return err
+	}
+
+	// IPv6 counterparts of the address, neighbor entry and routes
+	err = linuxcalls.AddInterfaceIP(tapHostIfName, podIPv6Net, nil)
+	if err != nil {
+		return err
+	}
+
+	err = l3_linux.AddArpEntry("pod-vpp ipv6 neighbor", &netlink.Neigh{
+		LinkIndex:    dev.Attrs().Index,
+		Family:       netlink.FAMILY_V6,
+		State:        netlink.NUD_PERMANENT,
+		Type:         1,
+		IP:           s.ipam.PodGatewayIPv6(),
+		HardwareAddr: macAddr,
+	}, s.Logger, nil)
+	if err != nil {
+		return err
+	}
+
+	err = l3_linux.AddStaticRoute("pod-link-scope ipv6", &netlink.Route{
+		LinkIndex: dev.Attrs().Index,
+		Dst:       &net.IPNet{IP: s.ipam.PodGatewayIPv6(), Mask: net.CIDRMask(net.IPv6len*8, net.IPv6len*8)},
+		Scope:     netlink.SCOPE_LINK,
+	}, s.Logger, nil)
+	if err != nil {
+		return err
+	}
+
+	_, defaultDstIPv6, err := net.ParseCIDR("::/0")
+	if err != nil {
+		return err
+	}
+
+	return l3_linux.AddStaticRoute("pod default route ipv6", &netlink.Route{
+		LinkIndex: dev.Attrs().Index,
+		Dst:       defaultDstIPv6,
+		Gw:        s.ipam.PodGatewayIPv6(),
+	}, s.Logger, nil)
+}
+
+// configureSecondaryHostTAP configures TAP interface of a secondary network created in the host by VPP:
+// the interface is moved into the namespace of the container and the subnet of the network is routed
+// via the gateway of the network.
+// TODO: move to the linuxplugin
+func (s *remoteCNIserver) configureSecondaryHostTAP(request *cni.CNIRequest, ipNet *net.IPNet, gateway net.IP, subnet *net.IPNet, vppHw string) error {
+	tapTmpHostIfName := s.tapTmpHostNameFromRequest(request)
+	tapHostIfName := s.tapHostNameFromRequest(request)
+	containerNs := &linux_intf.LinuxInterfaces_Interface_Namespace{
+		Type:     linux_intf.LinuxInterfaces_Interface_Namespace_FILE_REF_NS,
+		Filepath: request.NetworkNamespace,
+	}
+	nsMgmtCtx := linuxcalls.NewNamespaceMgmtCtx()
+
+	// Move TAP into the namespace of the container.
+	linuxcalls.SetInterfaceNamespace(nsMgmtCtx, tapTmpHostIfName, containerNs, s.Logger, nil)
+
+	// Switch to the namespace of the container.
+	revertNs, err := linuxcalls.ToGenericNs(containerNs).SwitchNamespace(nsMgmtCtx, s.Logger)
+	if err != nil {
+		return err
+	}
+	defer revertNs()
+
+	err = linuxcalls.RenameInterface(tapTmpHostIfName, tapHostIfName, nil)
+	if err != nil {
+		return err
+	}
+	err = linuxcalls.SetInterfaceMac(tapHostIfName, s.hwAddrForContainer(), nil)
+	if err != nil {
+		return err
+	}
+	err = linuxcalls.AddInterfaceIP(tapHostIfName, ipNet, nil)
+	if err != nil {
+		return err
+	}
+
+	dev, err := netlink.LinkByName(tapHostIfName)
+	if err != nil {
+		return err
+	}
+	macAddr, err := net.ParseMAC(vppHw)
+	if err != nil {
+		return err
+	}
+
+	err = l3_linux.AddArpEntry("secondary gateway arp", &netlink.Neigh{
+		LinkIndex:    dev.Attrs().Index,
+		Family:       netlink.FAMILY_V4,
+		State:        netlink.NUD_PERMANENT,
+		Type:         1,
+		IP:           gateway,
+		HardwareAddr: macAddr,
+	}, s.Logger, nil)
+	if err != nil {
+		return err
+	}
+
+	err = l3_linux.AddStaticRoute("secondary link-scope", &netlink.Route{
+		LinkIndex: dev.Attrs().Index,
+		Dst:       &net.IPNet{IP: gateway, Mask: net.CIDRMask(net.IPv4len*8, net.IPv4len*8)},
+		Scope:     netlink.SCOPE_LINK,
+	}, s.Logger, nil)
+	if err != nil {
+		return err
+	}
+
+	return l3_linux.AddStaticRoute("secondary subnet route", &netlink.Route{
+		LinkIndex: dev.Attrs().Index,
+		Dst:       subnet,
+		Gw:        gateway,
+	}, s.Logger, nil)
 }
 
 // unconfigureHostTAP removes TAP interface from the host stack if it wasn't
@@ -236,12 +407,30 @@ func (s *remoteCNIserver) tapHostNameFromRequest(request *cni.CNIRequest) string
 	return request.InterfaceName
 }
 
+func (s *remoteCNIserver) memifNameFromRequest(request *cni.CNIRequest) string {
+	return memifNamePrefix + request.ContainerId
+}
+
 func (s *remoteCNIserver) loopbackNameFromRequest(request *cni.CNIRequest) string {
 	return "loop" + s.veth2NameFromRequest(request)
 }
 
-func (s *remoteCNIserver) ipAddrForPodVPPIf() string {
-	return podIfIPPrefix + "." + strconv.Itoa(s.counter+1) + "/32"
+func (s *remoteCNIserver) ipAddrForPodVPPIf(podIfIdx int) string {
+	return podIfIPPrefix + "." + strconv.Itoa(podIfIdx+1) + "/32"
+}
+
+func (s *remoteCNIserver) ipv6AddrForPodVPPIf(podIfIdx int) string {
+	return fmt.Sprintf("%s%x/128", podIfIPv6Prefix, podIfIdx+1)
+}
+
+// podVPPIfIPAddresses returns IP addresses of the VPP end of the pod interconnect
+// with the given index (see nextPodIfIdx).
+func (s *remoteCNIserver) podVPPIfIPAddresses(podIfIdx int) []string {
+	addresses := []string{s.ipAddrForPodVPPIf(podIfIdx)}
+	if s.ipam.IPv6Enabled() {
+		addresses = append(addresses, s.ipv6AddrForPodVPPIf(podIfIdx))
+	}
+	return addresses
 }
 
 func (s *remoteCNIserver) hwAddrForContainer() string {
@@ -267,6 +456,7 @@ func (s *remoteCNIserver) veth1FromRequest(request *cni.CNIRequest, podIP string
 			PeerIfName: s.veth2NameFromRequest(request),
 		},
 		IpAddresses: []string{podIP},
+		Mtu:         s.podMTU,
 		Namespace: &linux_intf.LinuxInterfaces_Interface_Namespace{
 			Type:     linux_intf.LinuxInterfaces_Interface_Namespace_FILE_REF_NS,
 			Filepath: request.NetworkNamespace,
@@ -280,13 +470,14 @@ func (s *remoteCNIserver) veth2FromRequest(request *cni.CNIRequest) *linux_intf.
 		Type:       linux_intf.LinuxInterfaces_VETH,
 		Enabled:    true,
 		HostIfName: s.veth2HostIfNameFromRequest(request),
+		Mtu:        s.podMTU,
 		Veth: &linux_intf.LinuxInterfaces_Interface_Veth{
 			PeerIfName: s.veth1NameFromRequest(request),
 		},
 	}
 }
 
-func (s *remoteCNIserver) afpacketFromRequest(request *cni.CNIRequest, configureContainerProxy bool, containerProxyIP string) *vpp_intf.Interfaces_Interface {
+func (s *remoteCNIserver) afpacketFromRequest(request *cni.CNIRequest, podIfIdx int, configureContainerProxy bool, containerProxyIP string) *vpp_intf.Interfaces_Interface {
 	af := &vpp_intf.Interfaces_Interface{
 		Name:    s.afpacketNameFromRequest(request),
 		Type:    vpp_intf.InterfaceType_AF_PACKET_INTERFACE,
@@ -294,7 +485,7 @@ func (s *remoteCNIserver) afpacketFromRequest(request *cni.CNIRequest, configure
 		Afpacket: &vpp_intf.Interfaces_Interface_Afpacket{
 			HostIfName: s.veth2HostIfNameFromRequest(request),
 		},
-		IpAddresses: []string{s.ipAddrForPodVPPIf()},
+		IpAddresses: s.podVPPIfIPAddresses(podIfIdx),
 		PhysAddress: s.generateHwAddrForPodVPPIf(),
 	}
 	if configureContainerProxy {
@@ -303,7 +494,7 @@ func (s *remoteCNIserver) afpacketFromRequest(request *cni.CNIRequest, configure
 	return af
 }
 
-func (s *remoteCNIserver) tapFromRequest(request *cni.CNIRequest, configureContainerProxy bool, containerProxyIP string) *vpp_intf.Interfaces_Interface {
+func (s *remoteCNIserver) tapFromRequest(request *cni.CNIRequest, podIfIdx int, configureContainerProxy bool, containerProxyIP string) *vpp_intf.Interfaces_Interface {
 	tap := &vpp_intf.Interfaces_Interface{
 		Name:    s.tapNameFromRequest(request),
 		Type:    vpp_intf.InterfaceType_TAP_INTERFACE,
@@ -311,10 +502,10 @@ func (s *remoteCNIserver) tapFromRequest(request *cni.CNIRequest, configureConta
 		Tap: &vpp_intf.Interfaces_Interface_Tap{
 			HostIfName: s.tapTmpHostNameFromRequest(request),
 		},
-		IpAddresses: []string{s.ipAddrForPodVPPIf()},
+		IpAddresses: s.podVPPIfIPAddresses(podIfIdx),
 		PhysAddress: s.generateHwAddrForPodVPPIf(),
 	}
-	if s.tapVersion == 2 {
+	if s.currentTAPVersion() == 2 {
 		tap.Tap.Version = 2
 		tap.Tap.RxRingSize = uint32(s.tapV2RxRingSize)
 		tap.Tap.TxRingSize = uint32(s.tapV2TxRingSize)
@@ -325,6 +516,24 @@ func (s *remoteCNIserver) tapFromRequest(request *cni.CNIRequest, configureConta
 	return tap
 }
 
+func (s *remoteCNIserver) memifFromRequest(request *cni.CNIRequest, podIfIdx int, socketFilename string, network SecondaryNetwork) *vpp_intf.Interfaces_Interface {
+	return &vpp_intf.Interfaces_Interface{
+		Name:    s.memifNameFromRequest(request),
+		Type:    vpp_intf.InterfaceType_MEMORY_INTERFACE,
+		Enabled: true,
+		Memif: &vpp_intf.Interfaces_Interface_Memif{
+			Master:         true,
+			SocketFilename: socketFilename,
+			RingSize:       network.MemifRingSize,
+			BufferSize:     network.MemifBufferSize,
+			RxQueues:       network.MemifQueues,
+			TxQueues:       network.MemifQueues,
+		},
+		IpAddresses: s.podVPPIfIPAddresses(podIfIdx),
+		PhysAddress: s.generateHwAddrForPodVPPIf(),
+	}
+}
+
 func (s *remoteCNIserver) loopbackFromRequest(request *cni.CNIRequest, loopIP string) *vpp_intf.Interfaces_Interface {
 	return &vpp_intf.Interfaces_Interface{
 		Name:        s.loopbackNameFromRequest(request),
@@ -422,3 +631,26 @@ func (s *remoteCNIserver) podDefaultRouteFromRequest(request *cni.CNIRequest, if
 		GwAddr: s.ipam.PodGatewayIP().String(),
 	}
 }
+
+func (s *remoteCNIserver) podArpEntryIPv6(request *cni.CNIRequest, ifName string, macAddr string) *linux_l3.LinuxStaticArpEntries_ArpEntry {
+	entry := s.podArpEntry(request, ifName, macAddr)
+	entry.Name = request.ContainerId + "-ip6"
+	entry.Family = netlink.FAMILY_V6
+	entry.IpAddr = s.ipam.PodGatewayIPv6().String()
+	return entry
+}
+
+func (s *remoteCNIserver) podLinkRouteIPv6FromRequest(request *cni.CNIRequest, ifName string) *linux_l3.LinuxStaticRoutes_Route {
+	route := s.podLinkRouteFromRequest(request, ifName)
+	route.Name = "LINK6-" + request.ContainerId
+	route.DstIpAddr = s.ipam.PodGatewayIPv6().String() + "/128"
+	return route
+}
+
+func (s *remoteCNIserver) podDefaultRouteIPv6FromRequest(request *cni.CNIRequest, ifName string) *linux_l3.LinuxStaticRoutes_Route {
+	route := s.podDefaultRouteFromRequest(request, ifName)
+	route.Name = "DEFAULT6-" + request.ContainerId
+	route.DstIpAddr = "::/0"
+	route.GwAddr = s.ipam.PodGatewayIPv6().String()
+	return route
+}
@@ -0,0 +1,527 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/gogo/protobuf/proto"
+	"golang.org/x/net/context"
+
+	"github.com/contiv/vpp/plugins/contiv/containeridx"
+	"github.com/contiv/vpp/plugins/contiv/model/cni"
+	"github.com/contiv/vpp/plugins/contiv/model/podconfig"
+	vpp_acl "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
+	linux_l3 "github.com/ligato/vpp-agent/plugins/linuxplugin/l3plugin/model/l3"
+)
+
+const (
+	// PodConfigACLNamePrefix is the prefix of the names of the ACLs applied via the pod network
+	// config API. The ACLs are not managed by the policy renderer.
+	PodConfigACLNamePrefix = "EXT-"
+
+	// podConfigRoutePrefix is the prefix of the names of the routes configured in the pod via the pod network config API.
+	podConfigRoutePrefix = "EXT-"
+
+	maxPortNum = uint32(^uint16(0))
+)
+
+// Apply handles the request of an external controller to apply additional network configuration
+// for a running pod. The configuration of the same name applied before is replaced.
+func (s *remoteCNIserver) Apply(ctx context.Context, request *podconfig.PodConfigRequest) (*podconfig.PodConfigReply, error) {
+	s.Info("Pod config apply request received ", *request)
+	return s.processPodConfigRequest(request, s.applyPodConfig)
+}
+
+// Remove handles the request of an external controller to remove the named configuration from a running pod.
+func (s *remoteCNIserver) Remove(ctx context.Context, request *podconfig.PodConfigRequest) (*podconfig.PodConfigReply, error) {
+	s.Info("Pod config remove request received ", *request)
+	return s.processPodConfigRequest(request, s.removePodConfigByName)
+}
+
+// processPodConfigRequest finds the container of the pod and processes the request in the pipeline
+// of the container, so that it is serialized with the CNI requests of the same container.
+func (s *remoteCNIserver) processPodConfigRequest(request *podconfig.PodConfigRequest,
+	process func(request *podconfig.PodConfigRequest, containerID string) (*podconfig.PodConfigReply, error)) (*podconfig.PodConfigReply, error) {
+
+	if request.Name == "" {
+		return s.generatePodConfigErrorReply(fmt.Errorf("name of the pod configuration is not set"))
+	}
+	containerID, _, found := s.lookupPodContainer(request.PodNamespace, request.PodName)
+	if !found {
+		return s.generatePodConfigErrorReply(fmt.Errorf("pod %s/%s is not connected", request.PodNamespace, request.PodName))
+	}
+
+	var reply *podconfig.PodConfigReply
+	var err error
+	s.podPipeline.process(containerID, func() {
+		s.waitForVswitchConnectivity()
+		s.RLock()
+		defer s.RUnlock()
+		reply, err = process(request, containerID)
+	})
+	return reply, err
+}
+
+// lookupPodContainer returns the ID and the configuration of the container of the given pod.
+func (s *remoteCNIserver) lookupPodContainer(podNamespace, podName string) (containerID string, config *containeridx.Config, found bool) {
+	if s.configuredContainers == nil {
+		return "", nil, false
+	}
+	for _, containerID := range s.configuredContainers.LookupPodName(podName) {
+		config, found := s.configuredContainers.LookupContainer(containerID)
+		if found && config.PodNamespace == podNamespace {
+			return containerID, config, true
+		}
+	}
+	return "", nil, false
+}
+
+// applyPodConfig applies the requested configuration for the pod. Nothing of the requested
+// configuration is left applied if the request fails.
+func (s *remoteCNIserver) applyPodConfig(request *podconfig.PodConfigRequest, containerID string) (*podconfig.PodConfigReply, error) {
+	config, found := s.configuredContainers.LookupContainer(containerID)
+	if !found {
+		return s.generatePodConfigErrorReply(fmt.Errorf("pod %s/%s is not connected", request.PodNamespace, request.PodName))
+	}
+	podRequest := s.containerRequest(containerID, config)
+
+	// the configuration of the same name is replaced
+	newConfig := *config
+	replaced := false
+	if ext := s.findExtConfig(&newConfig, request.Name); ext != nil {
+		err := s.unconfigureExtConfig(podRequest, &newConfig, ext)
+		if err != nil {
+			s.Logger.Error(err)
+			return s.generatePodConfigErrorReply(err)
+		}
+		newConfig.SecondaryIfs = s.podIfsOutsideExtConfig(&newConfig, request.Name)
+		s.dropExtConfig(&newConfig, request.Name)
+		replaced = true
+	}
+
+	ext := &containeridx.ExtConfig{Name: request.Name}
+	extIfs := &containeridx.Config{PodName: config.PodName, PodNamespace: config.PodNamespace}
+	reply, err := s.configureExtConfig(podRequest, &newConfig, request, ext, extIfs)
+
+	// the interfaces added so far are recorded also after a failure, so that they get removed below
+	newConfig.SecondaryIfs = append(append([]*containeridx.SecondaryIf{}, newConfig.SecondaryIfs...), extIfs.SecondaryIfs...)
+	newConfig.ExtConfigs = append(append([]*containeridx.ExtConfig{}, newConfig.ExtConfigs...), ext)
+	if err == nil {
+		err = s.persistExtConfig(podRequest, &newConfig, ext)
+	}
+	if err != nil {
+		s.Logger.Error(err)
+		// do not leave the configuration applied so far behind
+		s.unconfigureExtConfig(podRequest, &newConfig, ext)
+		newConfig.SecondaryIfs = s.podIfsOutsideExtConfig(&newConfig, request.Name)
+		s.dropExtConfig(&newConfig, request.Name)
+		if replaced {
+			// the replaced configuration is gone as well
+			if err := s.storeContainer(podRequest, &newConfig, s.podConfigKeys(&newConfig)); err != nil {
+				s.Logger.Error(err)
+			}
+			s.configuredContainers.RegisterContainer(containerID, &newConfig)
+		}
+		return s.generatePodConfigErrorReply(err)
+	}
+
+	s.configuredContainers.RegisterContainer(containerID, &newConfig)
+	return reply, nil
+}
+
+// removePodConfigByName removes the configuration of the given name from the pod.
+func (s *remoteCNIserver) removePodConfigByName(request *podconfig.PodConfigRequest, containerID string) (*podconfig.PodConfigReply, error) {
+	config, found := s.configuredContainers.LookupContainer(containerID)
+	if !found {
+		return s.generatePodConfigErrorReply(fmt.Errorf("pod %s/%s is not connected", request.PodNamespace, request.PodName))
+	}
+	ext := s.findExtConfig(config, request.Name)
+	if ext == nil {
+		return s.generatePodConfigErrorReply(fmt.Errorf("pod %s/%s has no configuration %s", request.PodNamespace, request.PodName, request.Name))
+	}
+	podRequest := s.containerRequest(containerID, config)
+
+	newConfig := *config
+	err := s.unconfigureExtConfig(podRequest, &newConfig, ext)
+	if err == nil {
+		newConfig.SecondaryIfs = s.podIfsOutsideExtConfig(&newConfig, request.Name)
+		s.dropExtConfig(&newConfig, request.Name)
+		err = s.storeContainer(podRequest, &newConfig, s.podConfigKeys(&newConfig))
+	}
+	if err != nil {
+		s.Logger.Error(err)
+		return s.generatePodConfigErrorReply(err)
+	}
+
+	s.configuredContainers.RegisterContainer(containerID, &newConfig)
+	return &podconfig.PodConfigReply{Result: resultOk}, nil
+}
+
+// configureExtConfig applies the requested interfaces, routes and ACLs for the pod.
+// The added interfaces are collected in extIfs.
+func (s *remoteCNIserver) configureExtConfig(podRequest *cni.CNIRequest, config *containeridx.Config,
+	request *podconfig.PodConfigRequest, ext *containeridx.ExtConfig, extIfs *containeridx.Config) (*podconfig.PodConfigReply, error) {
+
+	reply := &podconfig.PodConfigReply{Result: resultOk}
+
+	// interfaces
+	for _, iface := range request.Interfaces {
+		network, defined := s.secondaryNetworks[iface.Network]
+		if !defined {
+			return nil, fmt.Errorf("secondary network %s is not defined", iface.Network)
+		}
+		if iface.Name == "" || len(iface.Name) > linuxIfMaxLen {
+			return nil, fmt.Errorf("invalid interface name %q", iface.Name)
+		}
+		if s.findPodIf(config, iface.Name) != nil || s.findPodIf(extIfs, iface.Name) != nil || iface.Name == config.InterfaceName {
+			return nil, fmt.Errorf("interface %s already exists in pod %s/%s", iface.Name, config.PodNamespace, config.PodName)
+		}
+		secondaryIf, err := s.configureSecondaryIf(podRequest, network, iface.Name)
+		if secondaryIf != nil {
+			secondaryIf.ExtConfig = ext.Name
+			extIfs.SecondaryIfs = append(extIfs.SecondaryIfs, secondaryIf)
+		}
+		if err != nil {
+			return nil, err
+		}
+		reply.Interfaces = append(reply.Interfaces, &podconfig.PodConfigReply_Interface{
+			Name:      iface.Name,
+			IpAddress: secondaryIf.IPAddress + "/32",
+		})
+	}
+	allIfs := &containeridx.Config{
+		PodName:      config.PodName,
+		PodNamespace: config.PodNamespace,
+		SecondaryIfs: append(append([]*containeridx.SecondaryIf{}, config.SecondaryIfs...), extIfs.SecondaryIfs...),
+	}
+	if err := s.publishSecondaryIfAnnotations(allIfs); err != nil {
+		return nil, err
+	}
+
+	// routes
+	for idx, route := range request.Routes {
+		podRoute, err := s.extPodRoute(podRequest, config, extIfs, route)
+		if err != nil {
+			return nil, err
+		}
+		podRoute.Name = fmt.Sprintf("%s%s-%d-%s", podConfigRoutePrefix, ext.Name, idx, podRequest.ContainerId)
+		ext.PodRoutes = append(ext.PodRoutes, podRoute)
+	}
+
+	// ACLs
+	for _, acl := range request.Acls {
+		secondaryIf := s.findPodIf(extIfs, acl.Interface)
+		if secondaryIf == nil || secondaryIf.VppIf == nil {
+			return nil, fmt.Errorf("ACLs can be applied only on the veth, tap and memif interfaces added by the same configuration, not on %s", acl.Interface)
+		}
+		// the traffic sent by the pod is received by VPP
+		if len(acl.Egress) > 0 {
+			ext.ACLs = append(ext.ACLs, s.extACL(ext.Name, podRequest, secondaryIf, acl.Egress, true))
+		}
+		if len(acl.Ingress) > 0 {
+			ext.ACLs = append(ext.ACLs, s.extACL(ext.Name, podRequest, secondaryIf, acl.Ingress, false))
+		}
+	}
+
+	if len(ext.PodRoutes) == 0 && len(ext.ACLs) == 0 {
+		return reply, nil
+	}
+	txn := s.vppTxnFactory().Put()
+	for _, route := range ext.PodRoutes {
+		txn.LinuxRoute(route)
+	}
+	for _, acl := range ext.ACLs {
+		txn.ACL(acl)
+	}
+	return reply, txn.Send().ReceiveReply()
+}
+
+// unconfigureExtConfig removes the routes, ACLs and interfaces applied for the pod by the configuration
+// together with their persisted configuration. Everything is processed, the first error is returned.
+func (s *remoteCNIserver) unconfigureExtConfig(podRequest *cni.CNIRequest, config *containeridx.Config, ext *containeridx.ExtConfig) error {
+	wasErr := s.unconfigureExtConfigItems([]*containeridx.ExtConfig{ext})
+
+	extIfs := &containeridx.Config{
+		PodName:      config.PodName,
+		PodNamespace: config.PodNamespace,
+		SecondaryIfs: s.extConfigIfs(config, ext.Name),
+		ExtConfigs:   []*containeridx.ExtConfig{ext},
+	}
+	err := s.unconfigureSecondaryIfs(podRequest, extIfs)
+	if err != nil && wasErr == nil {
+		wasErr = err
+	}
+
+	// the annotations are withdrawn with the removed memif and vhost-user interfaces, the remaining ones are announced again
+	if len(s.secondaryIfAnnotations(extIfs)) > 0 {
+		err = s.publishSecondaryIfAnnotations(&containeridx.Config{
+			PodName:      config.PodName,
+			PodNamespace: config.PodNamespace,
+			SecondaryIfs: s.podIfsOutsideExtConfig(config, ext.Name),
+		})
+		if err != nil && wasErr == nil {
+			wasErr = err
+		}
+	}
+
+	err = s.persistChanges(s.changeKeys(s.extConfigChanges(extIfs)), nil)
+	if err != nil && wasErr == nil {
+		wasErr = err
+	}
+	return wasErr
+}
+
+// unconfigureExtConfigItems removes the routes and ACLs of the given configurations. The interfaces
+// added by the configurations are removed together with the other secondary interfaces of the pod.
+func (s *remoteCNIserver) unconfigureExtConfigItems(exts []*containeridx.ExtConfig) error {
+	txn := s.vppTxnFactory().Delete()
+	empty := true
+	for _, ext := range exts {
+		for _, acl := range ext.ACLs {
+			txn.ACL(acl.AclName)
+			empty = false
+		}
+		if !s.test {
+			for _, route := range ext.PodRoutes {
+				txn.LinuxRoute(route.Name)
+				empty = false
+			}
+		}
+	}
+	if empty {
+		return nil
+	}
+	err := txn.Send().ReceiveReply()
+	if err != nil {
+		s.Logger.Error(err)
+	}
+	return err
+}
+
+// persistExtConfig persists the configuration applied by the external controller and updates
+// the record of the container, so that the configuration is restored after restart and removed
+// together with the pod.
+func (s *remoteCNIserver) persistExtConfig(podRequest *cni.CNIRequest, config *containeridx.Config, ext *containeridx.ExtConfig) error {
+	changes := s.extConfigChanges(&containeridx.Config{
+		SecondaryIfs: config.SecondaryIfs,
+		ExtConfigs:   []*containeridx.ExtConfig{ext},
+	})
+	err := s.persistChanges(nil, changes)
+	if err != nil {
+		return err
+	}
+	return s.storeContainer(podRequest, config, s.podConfigKeys(config))
+}
+
+// extConfigChanges returns the configuration applied by the external controllers to be persisted,
+// keyed by the keys of the items. The interfaces added by the configurations are included.
+func (s *remoteCNIserver) extConfigChanges(config *containeridx.Config) map[string]proto.Message {
+	changes := map[string]proto.Message{}
+	for _, ext := range config.ExtConfigs {
+		for key, value := range s.secondaryIfChanges(&containeridx.Config{SecondaryIfs: s.extConfigIfs(config, ext.Name)}) {
+			changes[key] = value
+		}
+		for _, route := range ext.PodRoutes {
+			changes[linux_l3.StaticRouteKey(route.Name)] = route
+		}
+		for _, acl := range ext.ACLs {
+			changes[vpp_acl.Key(acl.AclName)] = acl
+		}
+	}
+	return changes
+}
+
+// extPodRoute builds the route inside of the pod requested by the external controller.
+// Routes can be configured only via veth interfaces.
+func (s *remoteCNIserver) extPodRoute(podRequest *cni.CNIRequest, config *containeridx.Config, extIfs *containeridx.Config,
+	route *podconfig.PodConfigRequest_Route) (*linux_l3.LinuxStaticRoutes_Route, error) {
+
+	_, dst, err := net.ParseCIDR(route.Dst)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination of the route: %v", err)
+	}
+
+	var ifName string
+	var gateway net.IP
+	if route.Interface == "" || route.Interface == config.InterfaceName {
+		if config.Veth1 == nil {
+			return nil, fmt.Errorf("routes via the pod interface are supported only with veth interfaces")
+		}
+		ifName = config.Veth1.Name
+		gateway = s.ipam.PodGatewayIP()
+	} else {
+		secondaryIf := s.findPodIf(config, route.Interface)
+		if secondaryIf == nil {
+			secondaryIf = s.findPodIf(extIfs, route.Interface)
+		}
+		if secondaryIf == nil || secondaryIf.Veth1 == nil {
+			return nil, fmt.Errorf("routes are supported only via veth interfaces, not via %s", route.Interface)
+		}
+		ifName = secondaryIf.Veth1.Name
+		gateway, err = s.ipam.PoolGatewayIP(s.secondaryNetworks[secondaryIf.Network].IPPool)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if route.Gw != "" {
+		gateway = net.ParseIP(route.Gw)
+		if gateway == nil {
+			return nil, fmt.Errorf("invalid gateway of the route: %s", route.Gw)
+		}
+	}
+
+	podRoute := s.podDefaultRouteFromRequest(podRequest, ifName)
+	podRoute.Default = false
+	podRoute.DstIpAddr = dst.String()
+	podRoute.GwAddr = gateway.String()
+	return podRoute, nil
+}
+
+// extACL builds the ACL applied on the VPP end of the interface added by the external controller.
+// The egress rules of the pod are applied in the ingress direction of VPP and vice versa.
+// The traffic not matching any rule is denied by VPP.
+func (s *remoteCNIserver) extACL(extName string, podRequest *cni.CNIRequest, secondaryIf *containeridx.SecondaryIf,
+	rules []*podconfig.PodConfigRequest_Acl_Rule, egress bool) *vpp_acl.AccessLists_Acl {
+
+	acl := &vpp_acl.AccessLists_Acl{
+		Interfaces: &vpp_acl.AccessLists_Acl_Interfaces{},
+	}
+	if egress {
+		acl.AclName = fmt.Sprintf("%s%s-%s-egress-%s", PodConfigACLNamePrefix, extName, secondaryIf.PodIfName, podRequest.ContainerId)
+		acl.Interfaces.Ingress = []string{secondaryIf.VppIf.Name}
+	} else {
+		acl.AclName = fmt.Sprintf("%s%s-%s-ingress-%s", PodConfigACLNamePrefix, extName, secondaryIf.PodIfName, podRequest.ContainerId)
+		acl.Interfaces.Egress = []string{secondaryIf.VppIf.Name}
+	}
+
+	for idx, rule := range rules {
+		aclRule := &vpp_acl.AccessLists_Acl_Rule{
+			RuleName: fmt.Sprintf("rule%d", idx),
+			Actions:  &vpp_acl.AccessLists_Acl_Rule_Actions{AclAction: vpp_acl.AclAction_DENY},
+			Matches: &vpp_acl.AccessLists_Acl_Rule_Matches{
+				IpRule: &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule{
+					Ip: &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Ip{
+						SourceNetwork:      rule.SrcNetwork,
+						DestinationNetwork: rule.DstNetwork,
+					},
+				},
+			},
+		}
+		if rule.Action == podconfig.PodConfigRequest_Acl_Rule_PERMIT {
+			aclRule.Actions.AclAction = vpp_acl.AclAction_REFLECT
+		}
+		lowerPort, upperPort := uint32(0), maxPortNum
+		if rule.DstPort != 0 {
+			lowerPort, upperPort = rule.DstPort, rule.DstPort
+		}
+		switch rule.Protocol {
+		case podconfig.PodConfigRequest_Acl_Rule_TCP:
+			aclRule.Matches.IpRule.Tcp = &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Tcp{
+				SourcePortRange:      &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Tcp_SourcePortRange{LowerPort: 0, UpperPort: maxPortNum},
+				DestinationPortRange: &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Tcp_DestinationPortRange{LowerPort: lowerPort, UpperPort: upperPort},
+			}
+		case podconfig.PodConfigRequest_Acl_Rule_UDP:
+			aclRule.Matches.IpRule.Udp = &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Udp{
+				SourcePortRange:      &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Udp_SourcePortRange{LowerPort: 0, UpperPort: maxPortNum},
+				DestinationPortRange: &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Udp_DestinationPortRange{LowerPort: lowerPort, UpperPort: upperPort},
+			}
+		}
+		acl.Rules = append(acl.Rules, aclRule)
+	}
+	return acl
+}
+
+// containerRequest rebuilds the CNI request of the connected container used to derive the names
+// of the configured items.
+func (s *remoteCNIserver) containerRequest(containerID string, config *containeridx.Config) *cni.CNIRequest {
+	return &cni.CNIRequest{
+		ContainerId:      containerID,
+		NetworkNamespace: config.NetworkNamespace,
+		InterfaceName:    config.InterfaceName,
+	}
+}
+
+// findExtConfig returns the configuration of the given name applied for the pod, nil if not found.
+func (s *remoteCNIserver) findExtConfig(config *containeridx.Config, name string) *containeridx.ExtConfig {
+	for _, ext := range config.ExtConfigs {
+		if ext.Name == name {
+			return ext
+		}
+	}
+	return nil
+}
+
+// dropExtConfig removes the configuration of the given name from the list of the applied configurations.
+func (s *remoteCNIserver) dropExtConfig(config *containeridx.Config, name string) {
+	var exts []*containeridx.ExtConfig
+	for _, ext := range config.ExtConfigs {
+		if ext.Name != name {
+			exts = append(exts, ext)
+		}
+	}
+	config.ExtConfigs = exts
+}
+
+// findPodIf returns the secondary interface of the pod with the given name, nil if not found.
+func (s *remoteCNIserver) findPodIf(config *containeridx.Config, podIfName string) *containeridx.SecondaryIf {
+	for _, secondaryIf := range config.SecondaryIfs {
+		if secondaryIf.PodIfName == podIfName {
+			return secondaryIf
+		}
+	}
+	return nil
+}
+
+// extConfigIfs returns the interfaces added by the configuration of the given name.
+func (s *remoteCNIserver) extConfigIfs(config *containeridx.Config, name string) []*containeridx.SecondaryIf {
+	var ifs []*containeridx.SecondaryIf
+	for _, secondaryIf := range config.SecondaryIfs {
+		if secondaryIf.ExtConfig == name {
+			ifs = append(ifs, secondaryIf)
+		}
+	}
+	return ifs
+}
+
+// podIfsOutsideExtConfig returns the secondary interfaces of the pod not added by the configuration of the given name.
+func (s *remoteCNIserver) podIfsOutsideExtConfig(config *containeridx.Config, name string) []*containeridx.SecondaryIf {
+	var ifs []*containeridx.SecondaryIf
+	for _, secondaryIf := range config.SecondaryIfs {
+		if secondaryIf.ExtConfig != name {
+			ifs = append(ifs, secondaryIf)
+		}
+	}
+	return ifs
+}
+
+// changeKeys returns the keys of the changes.
+func (s *remoteCNIserver) changeKeys(changes map[string]proto.Message) []string {
+	var keys []string
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// generatePodConfigErrorReply generates the pod config error reply with the proper result code and error message.
+func (s *remoteCNIserver) generatePodConfigErrorReply(err error) (*podconfig.PodConfigReply, error) {
+	reply := &podconfig.PodConfigReply{
+		Result: resultErr,
+		Error:  err.Error(),
+	}
+	return reply, err
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/contiv/vpp/mock/localclient"
+	"github.com/contiv/vpp/plugins/contiv/ipam"
+	"github.com/contiv/vpp/plugins/contiv/model/podconfig"
+	vpp_acl "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
+)
+
+// aclsInSnapshot returns the ACLs applied via the pod network config API from the config snapshot.
+func aclsInSnapshot(snapshot localclient.ConfigSnapshot) []*vpp_acl.AccessLists_Acl {
+	var acls []*vpp_acl.AccessLists_Acl
+	for key, value := range snapshot {
+		if strings.HasPrefix(key, vpp_acl.Key(PodConfigACLNamePrefix)) {
+			acls = append(acls, value.(*vpp_acl.AccessLists_Acl))
+		}
+	}
+	return acls
+}
+
+func TestPodConfigAPI(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := configVethL2NoTCP
+	config.IPAMConfig.NamespacePools = []ipam.NamespacePoolConfig{
+		{Name: "dataplane", SubnetCIDR: "10.20.0.0/16", NetworkPrefixLen: 24},
+	}
+	config.SecondaryNetworks = []SecondaryNetwork{
+		{Name: "dataplane", IPPool: "dataplane"},
+	}
+	server, txns, configuredContainers, conn := setupTestCNIServer(&config, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	// the pod has to be connected
+	request := &podconfig.PodConfigRequest{
+		PodName:      podName,
+		PodNamespace: "default",
+		Name:         "nfv",
+		Interfaces: []*podconfig.PodConfigRequest_Interface{
+			{Network: "dataplane", Name: "nfv0"},
+		},
+		Routes: []*podconfig.PodConfigRequest_Route{
+			{Dst: "10.100.0.0/16", Interface: "nfv0"},
+			{Dst: "10.200.0.0/16", Gw: "10.1.1.1"},
+		},
+		Acls: []*podconfig.PodConfigRequest_Acl{
+			{
+				Interface: "nfv0",
+				Egress: []*podconfig.PodConfigRequest_Acl_Rule{
+					{
+						Action:     podconfig.PodConfigRequest_Acl_Rule_PERMIT,
+						Protocol:   podconfig.PodConfigRequest_Acl_Rule_TCP,
+						DstNetwork: "10.100.0.0/16",
+						DstPort:    80,
+					},
+				},
+			},
+		},
+	}
+	reply, err := server.Apply(context.Background(), request)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultErr))
+
+	_, err = server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// the interface, routes and ACL are applied
+	reply, err = server.Apply(context.Background(), request)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Interfaces).To(gomega.HaveLen(1))
+	gomega.Expect(reply.Interfaces[0].Name).To(gomega.BeEquivalentTo("nfv0"))
+	gomega.Expect(reply.Interfaces[0].IpAddress).To(gomega.BeEquivalentTo("10.20.1.2/32"))
+
+	podConfig, found := configuredContainers.LookupContainer(containerID)
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(podConfig.SecondaryIfs).To(gomega.HaveLen(1))
+	gomega.Expect(podConfig.SecondaryIfs[0].ExtConfig).To(gomega.BeEquivalentTo("nfv"))
+	gomega.Expect(podConfig.ExtConfigs).To(gomega.HaveLen(1))
+	gomega.Expect(podConfig.ExtConfigs[0].PodRoutes).To(gomega.HaveLen(2))
+	gomega.Expect(podConfig.ExtConfigs[0].PodRoutes[0].GwAddr).To(gomega.BeEquivalentTo("10.20.1.1"))
+	gomega.Expect(podConfig.ExtConfigs[0].PodRoutes[1].Interface).To(gomega.BeEquivalentTo(podConfig.Veth1.Name))
+	acls := aclsInSnapshot(txns.AppliedConfig)
+	gomega.Expect(acls).To(gomega.HaveLen(1))
+	gomega.Expect(acls[0].Interfaces.Ingress).To(gomega.ConsistOf(podConfig.SecondaryIfs[0].VppIf.Name))
+	gomega.Expect(acls[0].Rules[0].Matches.IpRule.Tcp.DestinationPortRange.LowerPort).To(gomega.BeEquivalentTo(80))
+
+	// invalid configuration is not applied, the pod keeps the previous one
+	_, err = server.Apply(context.Background(), &podconfig.PodConfigRequest{
+		PodName:      podName,
+		PodNamespace: "default",
+		Name:         "invalid",
+		Interfaces: []*podconfig.PodConfigRequest_Interface{
+			{Network: "dataplane", Name: "nfv1"},
+		},
+		Acls: []*podconfig.PodConfigRequest_Acl{
+			{Interface: "nfv0"},
+		},
+	})
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	podConfig, _ = configuredContainers.LookupContainer(containerID)
+	gomega.Expect(podConfig.SecondaryIfs).To(gomega.HaveLen(1))
+	gomega.Expect(podConfig.ExtConfigs).To(gomega.HaveLen(1))
+
+	// the configuration of the same name is replaced
+	_, err = server.Apply(context.Background(), &podconfig.PodConfigRequest{
+		PodName:      podName,
+		PodNamespace: "default",
+		Name:         "nfv",
+		Routes: []*podconfig.PodConfigRequest_Route{
+			{Dst: "10.200.0.0/16"},
+		},
+	})
+	gomega.Expect(err).To(gomega.BeNil())
+	podConfig, _ = configuredContainers.LookupContainer(containerID)
+	gomega.Expect(podConfig.SecondaryIfs).To(gomega.BeEmpty())
+	gomega.Expect(podConfig.ExtConfigs).To(gomega.HaveLen(1))
+	gomega.Expect(podConfig.ExtConfigs[0].PodRoutes[0].GwAddr).To(gomega.BeEquivalentTo("10.1.1.1"))
+	gomega.Expect(aclsInSnapshot(txns.AppliedConfig)).To(gomega.BeEmpty())
+
+	// the configuration is removed by name
+	reply, err = server.Remove(context.Background(), &podconfig.PodConfigRequest{PodName: podName, PodNamespace: "default", Name: "nfv"})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+	podConfig, _ = configuredContainers.LookupContainer(containerID)
+	gomega.Expect(podConfig.ExtConfigs).To(gomega.BeEmpty())
+	_, err = server.Remove(context.Background(), &podconfig.PodConfigRequest{PodName: podName, PodNamespace: "default", Name: "nfv"})
+	gomega.Expect(err).NotTo(gomega.BeNil())
+
+	// the configuration is removed together with the pod
+	_, err = server.Apply(context.Background(), request)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(aclsInSnapshot(txns.AppliedConfig)).To(gomega.HaveLen(1))
+	podConfig, _ = configuredContainers.LookupContainer(containerID)
+	vppIfName := podConfig.SecondaryIfs[0].VppIf.Name
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, vppIfName)).NotTo(gomega.BeNil())
+	_, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(aclsInSnapshot(txns.AppliedConfig)).To(gomega.BeEmpty())
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, vppIfName)).To(gomega.BeNil())
+}
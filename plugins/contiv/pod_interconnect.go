@@ -0,0 +1,131 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"os"
+
+	vpp_intf "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+)
+
+// values of the PodInterconnect option
+const (
+	// podInterconnectAuto selects TAPv2 if supported by the kernel, veth + AF_PACKET otherwise
+	podInterconnectAuto = "auto"
+	// podInterconnectTAPv1 selects the legacy TAP interfaces
+	podInterconnectTAPv1 = "tapv1"
+	// podInterconnectTAPv2 selects the virtio-based TAP interfaces
+	podInterconnectTAPv2 = "tapv2"
+	// podInterconnectVeth selects veth pairs + AF_PACKET interfaces
+	podInterconnectVeth = "veth"
+)
+
+// kernel devices required by the TAP drivers
+const (
+	tunDevicePath      = "/dev/net/tun"
+	vhostNetDevicePath = "/dev/vhost-net"
+)
+
+// detectTAPSupport returns whether the kernel of the node supports the legacy TAP (tapv1)
+// and the virtio-based TAP (tapv2) interfaces. Can be replaced by tests.
+var detectTAPSupport = func() (tapv1 bool, tapv2 bool) {
+	tapv1 = deviceExists(tunDevicePath)
+	// TAPv2 is backed by vhost-net in the kernel
+	tapv2 = tapv1 && deviceExists(vhostNetDevicePath)
+	return tapv1, tapv2
+}
+
+// deviceExists returns true if the device file exists.
+func deviceExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// selectPodInterconnect selects the interfaces connecting PODs (and the host) with VPP based on the
+// PodInterconnect option and the kernel support. If the option is not set, UseTAPInterfaces
+// and TAPInterfaceVersion apply as they are. Options unsupported by the kernel fall back
+// to the fastest supported option instead of failing.
+func (s *remoteCNIserver) selectPodInterconnect(podInterconnect string) error {
+	if podInterconnect == "" {
+		return nil
+	}
+	tapv1, tapv2 := detectTAPSupport()
+
+	switch podInterconnect {
+	case podInterconnectAuto, podInterconnectTAPv2:
+		switch {
+		case tapv2:
+			s.useTAPInterfaces, s.tapVersion = true, 2
+			// the kernel may still refuse to create TAPv2 interfaces
+			s.tapV2Fallback = true
+		case podInterconnectAuto == podInterconnect || !tapv1:
+			s.useTAPInterfaces = false
+		default:
+			s.useTAPInterfaces, s.tapVersion = true, 1
+		}
+	case podInterconnectTAPv1:
+		s.useTAPInterfaces, s.tapVersion = tapv1, 1
+	case podInterconnectVeth:
+		s.useTAPInterfaces = false
+	default:
+		return fmt.Errorf("unsupported pod interconnect %s", podInterconnect)
+	}
+
+	selected := s.podInterconnect()
+	if selected != podInterconnect && podInterconnect != podInterconnectAuto {
+		s.Logger.Warnf("Pod interconnect %s is not supported by the kernel, falling back to %s", podInterconnect, selected)
+	} else {
+		s.Logger.Infof("Using pod interconnect %s", selected)
+	}
+	return nil
+}
+
+// podInterconnect returns the currently used pod interconnect.
+func (s *remoteCNIserver) podInterconnect() string {
+	switch {
+	case !s.useTAPInterfaces:
+		return podInterconnectVeth
+	case s.currentTAPVersion() == 2:
+		return podInterconnectTAPv2
+	default:
+		return podInterconnectTAPv1
+	}
+}
+
+// currentTAPVersion returns the version of the TAP interfaces to create.
+func (s *remoteCNIserver) currentTAPVersion() uint8 {
+	s.tapLock.Lock()
+	defer s.tapLock.Unlock()
+	return s.tapVersion
+}
+
+// fallbackFromTAPv2 switches from TAPv2 to the legacy TAP interfaces after the kernel refused
+// to create the given TAPv2 interface. Returns true if the interface should be re-created
+// as the legacy TAP: the fallback is enabled and the interface was not legacy already.
+// PODs connected concurrently may all fail with TAPv2, the switch happens only once.
+func (s *remoteCNIserver) fallbackFromTAPv2(tap *vpp_intf.Interfaces_Interface, err error) bool {
+	s.tapLock.Lock()
+	defer s.tapLock.Unlock()
+
+	if !s.tapV2Fallback || tap.Tap == nil || tap.Tap.Version != 2 {
+		return false
+	}
+	if s.tapVersion == 2 {
+		s.Logger.Warnf("Failed to create TAPv2 interface (%v), falling back to %s", err, podInterconnectTAPv1)
+		s.tapVersion = 1
+	}
+	return true
+}
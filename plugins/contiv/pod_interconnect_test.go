@@ -0,0 +1,111 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/contiv/vpp/mock/localclient"
+	vpp_intf "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/onsi/gomega"
+)
+
+// withTAPSupport replaces the detection of the kernel support for TAP interfaces,
+// the returned function restores the original detection.
+func withTAPSupport(tapv1, tapv2 bool) func() {
+	origDetect := detectTAPSupport
+	detectTAPSupport = func() (bool, bool) {
+		return tapv1, tapv2
+	}
+	return func() {
+		detectTAPSupport = origDetect
+	}
+}
+
+func TestSelectPodInterconnect(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	testCases := []struct {
+		podInterconnect string
+		tapv1, tapv2    bool
+		selected        string
+	}{
+		{podInterconnectAuto, true, true, podInterconnectTAPv2},
+		{podInterconnectAuto, true, false, podInterconnectVeth},
+		{podInterconnectTAPv2, true, true, podInterconnectTAPv2},
+		{podInterconnectTAPv2, true, false, podInterconnectTAPv1},
+		{podInterconnectTAPv2, false, false, podInterconnectVeth},
+		{podInterconnectTAPv1, true, true, podInterconnectTAPv1},
+		{podInterconnectTAPv1, false, false, podInterconnectVeth},
+		{podInterconnectVeth, true, true, podInterconnectVeth},
+	}
+	for _, testCase := range testCases {
+		restore := withTAPSupport(testCase.tapv1, testCase.tapv2)
+		config := configVethL2NoTCP
+		config.PodInterconnect = testCase.podInterconnect
+		server, _, _, conn := setupTestCNIServer(&config, nil)
+		gomega.Expect(server.podInterconnect()).To(gomega.BeEquivalentTo(testCase.selected), testCase.podInterconnect)
+		conn.Disconnect()
+		restore()
+	}
+
+	// without PodInterconnect, UseTAPInterfaces and TAPInterfaceVersion apply
+	server, _, _, conn := setupTestCNIServer(&configTapVxlanTCP, nil)
+	defer conn.Disconnect()
+	gomega.Expect(server.podInterconnect()).To(gomega.BeEquivalentTo(podInterconnectTAPv2))
+
+	// unknown option is refused
+	gomega.Expect(server.selectPodInterconnect("macvlan")).NotTo(gomega.Succeed())
+}
+
+func TestTAPv2Fallback(t *testing.T) {
+	gomega.RegisterTestingT(t)
+	defer withTAPSupport(true, true)()
+
+	config := configVethL2NoTCP
+	config.PodInterconnect = podInterconnectAuto
+	server, _, configuredContainers, conn := setupTestCNIServer(&config, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	// the kernel refuses to create TAPv2 interfaces
+	swIfIdx := swIfIndexMock()
+	txns := localclient.NewTxnTracker(func(txn *localclient.Txn) error {
+		if txn.LinuxDataChangeTxn != nil {
+			for _, op := range txn.LinuxDataChangeTxn.Ops {
+				if tap, isIf := op.Value.(*vpp_intf.Interfaces_Interface); isIf && tap.Tap != nil && tap.Tap.Version == 2 {
+					return errors.New("TAPv2 is not supported")
+				}
+			}
+		}
+		return addIfsIntoTheIndex(swIfIdx)(txn)
+	})
+	server.vppTxnFactory = txns.NewLinuxDataChangeTxn
+	server.swIfIndex = swIfIdx
+
+	// CNI Add falls back to the legacy TAP interface
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+	gomega.Expect(server.podInterconnect()).To(gomega.BeEquivalentTo(podInterconnectTAPv1))
+
+	podConfig, found := configuredContainers.LookupContainer(containerID)
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(podConfig.VppIf.Tap.Version).To(gomega.BeZero())
+}
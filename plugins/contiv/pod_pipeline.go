@@ -0,0 +1,96 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import "sync"
+
+// defaultMaxParallelPodRequests is the default number of CNI requests of different PODs
+// processed in parallel.
+const defaultMaxParallelPodRequests = 8
+
+// podPipeline processes CNI requests of different PODs concurrently, while the requests
+// of the same POD are processed one by one in the order of their arrival (e.g. Delete
+// received while Add of the same POD is still in progress waits until Add is finished).
+// Each POD with pending requests is served by its own worker goroutine, the number
+// of requests processed at the same time is bounded.
+type podPipeline struct {
+	sync.Mutex
+
+	// semaphore bounding the number of requests in progress
+	slots chan struct{}
+
+	// queues of pending requests keyed by POD (container ID), the first request
+	// of each queue is being processed by the worker of the POD
+	queues map[string][]*podRequest
+}
+
+// podRequest is a CNI request waiting in the pipeline.
+type podRequest struct {
+	process func()
+	done    chan struct{}
+}
+
+// newPodPipeline creates a new pipeline processing at most maxParallel requests at the same time.
+func newPodPipeline(maxParallel int) *podPipeline {
+	if maxParallel <= 0 {
+		maxParallel = defaultMaxParallelPodRequests
+	}
+	return &podPipeline{
+		slots:  make(chan struct{}, maxParallel),
+		queues: map[string][]*podRequest{},
+	}
+}
+
+// process queues the request of the given POD and blocks until the request is processed.
+func (p *podPipeline) process(podKey string, process func()) {
+	request := &podRequest{
+		process: process,
+		done:    make(chan struct{}),
+	}
+
+	p.Lock()
+	queue, busy := p.queues[podKey]
+	p.queues[podKey] = append(queue, request)
+	p.Unlock()
+
+	if !busy {
+		go p.serve(podKey)
+	}
+	<-request.done
+}
+
+// serve processes the requests of the given POD until its queue is empty.
+func (p *podPipeline) serve(podKey string) {
+	for {
+		p.Lock()
+		request := p.queues[podKey][0]
+		p.Unlock()
+
+		p.slots <- struct{}{}
+		request.process()
+		<-p.slots
+		close(request.done)
+
+		p.Lock()
+		queue := p.queues[podKey][1:]
+		if len(queue) == 0 {
+			delete(p.queues, podKey)
+			p.Unlock()
+			return
+		}
+		p.queues[podKey] = queue
+		p.Unlock()
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/contiv/vpp/plugins/contiv/model/cni"
+	"github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+func TestPodPipeline(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	pipeline := newPodPipeline(2)
+
+	var lock sync.Mutex
+	var inProgress, maxInProgress int
+	processed := map[string][]int{}
+
+	// nothing is finished until all the requests are queued
+	release := make(chan struct{})
+	queued := func(pod string) int {
+		pipeline.Lock()
+		defer pipeline.Unlock()
+		return len(pipeline.queues[pod])
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		pod := fmt.Sprintf("pod%d", i%5)
+		seq := i / 5
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pipeline.process(pod, func() {
+				lock.Lock()
+				inProgress++
+				if inProgress > maxInProgress {
+					maxInProgress = inProgress
+				}
+				processed[pod] = append(processed[pod], seq)
+				lock.Unlock()
+
+				<-release
+				time.Sleep(time.Millisecond)
+
+				lock.Lock()
+				inProgress--
+				lock.Unlock()
+			})
+		}()
+		// requests of the same POD arrive one after another
+		gomega.Eventually(func() int { return queued(pod) }).Should(gomega.Equal(seq + 1))
+	}
+	close(release)
+	wg.Wait()
+
+	// the number of requests processed in parallel is bounded
+	gomega.Expect(maxInProgress).To(gomega.BeNumerically("<=", 2))
+	// the requests of each POD are processed in the order of arrival
+	gomega.Expect(processed).To(gomega.HaveLen(5))
+	for pod, seqs := range processed {
+		gomega.Expect(seqs).To(gomega.Equal([]int{0, 1, 2, 3, 4, 5}), pod)
+	}
+	// workers of idle PODs are gone
+	gomega.Eventually(func() int {
+		pipeline.Lock()
+		defer pipeline.Unlock()
+		return len(pipeline.queues)
+	}).Should(gomega.BeZero())
+}
+
+func TestAddDelParallel(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, configuredContainers, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	const pods = 50
+	requests := make([]*cni.CNIRequest, pods)
+	for i := range requests {
+		requests[i] = &cni.CNIRequest{
+			Version:          req.Version,
+			InterfaceName:    req.InterfaceName,
+			ContainerId:      fmt.Sprintf("%s%d", containerID, i),
+			NetworkNamespace: fmt.Sprintf("/var/run/netns%d", i),
+			ExtraArguments:   fmt.Sprintf("K8S_POD_NAMESPACE=default;K8S_POD_NAME=pod%d", i),
+		}
+	}
+
+	// CNI Add of all PODs at once
+	replies := make([]*cni.CNIReply, pods)
+	errs := make([]error, pods)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			replies[i], errs[i] = server.Add(context.Background(), requests[i])
+		}(i)
+	}
+	wg.Wait()
+
+	podIPs := map[string]struct{}{}
+	vppIPs := map[string]struct{}{}
+	for i, request := range requests {
+		gomega.Expect(errs[i]).To(gomega.BeNil())
+		gomega.Expect(replies[i].Result).To(gomega.BeEquivalentTo(resultOk))
+		config, found := configuredContainers.LookupContainer(request.ContainerId)
+		gomega.Expect(found).To(gomega.BeTrue())
+		podIPs[config.Veth1.IpAddresses[0]] = struct{}{}
+		vppIPs[config.VppIf.IpAddresses[0]] = struct{}{}
+	}
+	// each POD got unique IP address, as well as the VPP end of its interface
+	gomega.Expect(podIPs).To(gomega.HaveLen(pods))
+	gomega.Expect(vppIPs).To(gomega.HaveLen(pods))
+
+	// CNI Delete of all PODs at once
+	for i := range requests {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			replies[i], errs[i] = server.Delete(context.Background(), requests[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, request := range requests {
+		gomega.Expect(errs[i]).To(gomega.BeNil())
+		gomega.Expect(replies[i].Result).To(gomega.BeEquivalentTo(resultOk))
+		_, found := configuredContainers.LookupContainer(request.ContainerId)
+		gomega.Expect(found).To(gomega.BeFalse())
+	}
+}
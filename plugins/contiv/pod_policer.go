@@ -0,0 +1,289 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:generate binapi-generator --input-file=/usr/share/vpp/api/policer.api.json --output-dir=bin_api
+//go:generate binapi-generator --input-file=/usr/share/vpp/api/classify.api.json --output-dir=bin_api
+//go:generate binapi-generator --input-file=/usr/share/vpp/api/policer_classify.api.json --output-dir=bin_api
+
+package contiv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/contiv/vpp/plugins/contiv/bin_api/classify"
+	"github.com/contiv/vpp/plugins/contiv/bin_api/policer"
+	"github.com/contiv/vpp/plugins/contiv/bin_api/policer_classify"
+	"github.com/contiv/vpp/plugins/contiv/containeridx"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// egressBandwidthAnnotation limits the rate of the traffic sent by the pod (standard k8s annotation).
+	egressBandwidthAnnotation = "kubernetes.io/egress-bandwidth"
+
+	// ingressBandwidthAnnotation limits the rate of the traffic received by the pod (standard k8s annotation).
+	ingressBandwidthAnnotation = "kubernetes.io/ingress-bandwidth"
+
+	// dscpAnnotation sets the DSCP mark of the traffic sent by the pod, either a number (0-63) or a name
+	// of the class (e.g. "EF", "AF41", "CS5").
+	dscpAnnotation = "contivpp.io/dscp"
+
+	// podPolicerPrefix is the prefix of the names of the policers applied on the traffic of the pods,
+	// followed by the name of the VPP interface of the pod.
+	podPolicerPrefix = "bw-"
+
+	// unlimitedPolicerRate is the rate (in kbps) of the policers that only mark the traffic.
+	unlimitedPolicerRate = 100 * 1000 * 1000
+
+	// minPolicerBurst is the minimal committed burst (in bytes) of the policers.
+	minPolicerBurst = 32 * 1024
+
+	// policer parameters: single rate with two colors, rate in kbps, conforming traffic is transmitted
+	// (marked if DSCP is set), exceeding traffic is dropped (only marked if the rate is not limited)
+	policerType1R2C              = 0
+	policerRateKbps              = 0
+	policerRoundClosest          = 0
+	policerActionDrop            = 0
+	policerActionTransmit        = 1
+	policerActionMarkAndTransmit = 2
+	policerClassifyTableIP4      = 0
+
+	// classifyMatchAllLen is the length of the mask and of the match of the classify tables
+	// matching all packets (a single vector of zeros)
+	classifyMatchAllLen = 16
+)
+
+// dscpClasses maps the names of the DSCP classes to their values.
+var dscpClasses = map[string]uint8{
+	"CS0": 0, "CS1": 8, "CS2": 16, "CS3": 24, "CS4": 32, "CS5": 40, "CS6": 48, "CS7": 56,
+	"AF11": 10, "AF12": 12, "AF13": 14, "AF21": 18, "AF22": 20, "AF23": 22,
+	"AF31": 26, "AF32": 28, "AF33": 30, "AF41": 34, "AF42": 36, "AF43": 38,
+	"EF": 46,
+}
+
+// podPolicer groups the settings of the policer applied on the traffic sent by a pod.
+type podPolicer struct {
+	// egressKbps limits the rate of the traffic, 0 if not limited
+	egressKbps uint32
+	// dscp is the DSCP mark of the traffic, valid only if mark is true
+	dscp uint8
+	mark bool
+}
+
+// podPolicerFromAnnotations returns the policer requested by the annotations of the pod (nil if none).
+// The ingress bandwidth can not be limited, the policers of VPP apply only to the traffic received by VPP.
+func (s *remoteCNIserver) podPolicerFromAnnotations(config *containeridx.Config) (*podPolicer, error) {
+	if s.getPodAnnotations == nil || config.PodName == "" {
+		return nil, nil
+	}
+	annotations, err := s.getPodAnnotations(config.PodNamespace, config.PodName)
+	if err != nil {
+		return nil, fmt.Errorf("can't read annotations of pod %s/%s: %v", config.PodNamespace, config.PodName, err)
+	}
+	if _, requested := annotations[ingressBandwidthAnnotation]; requested {
+		s.Logger.Warnf("Annotation %s of pod %s/%s is not supported, only the egress bandwidth can be limited",
+			ingressBandwidthAnnotation, config.PodNamespace, config.PodName)
+	}
+	settings := &podPolicer{}
+	if value, requested := annotations[egressBandwidthAnnotation]; requested {
+		if settings.egressKbps, err = parseBandwidth(value); err != nil {
+			return nil, err
+		}
+	}
+	if value, requested := annotations[dscpAnnotation]; requested {
+		if settings.dscp, err = parseDSCP(value); err != nil {
+			return nil, err
+		}
+		settings.mark = true
+	}
+	if settings.egressKbps == 0 && !settings.mark {
+		return nil, nil
+	}
+	return settings, nil
+}
+
+// parseDSCP parses the DSCP value given either as a number or as a name of the class.
+func parseDSCP(value string) (uint8, error) {
+	if dscp, known := dscpClasses[strings.ToUpper(value)]; known {
+		return dscp, nil
+	}
+	dscp, err := strconv.ParseUint(value, 10, 8)
+	if err != nil || dscp > 63 {
+		return 0, fmt.Errorf("invalid DSCP %q", value)
+	}
+	return uint8(dscp), nil
+}
+
+// parseBandwidth parses the bandwidth given in bits per second as k8s quantity (e.g. "10M") into kbps.
+func parseBandwidth(value string) (uint32, error) {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth %q: %v", value, err)
+	}
+	kbps := quantity.Value() / 1000
+	if kbps < 1 || kbps > int64(^uint32(0)) {
+		return 0, fmt.Errorf("bandwidth %q out of range", value)
+	}
+	return uint32(kbps), nil
+}
+
+// configurePodPolicer applies the policer requested by the annotations of the pod on all traffic received
+// from the pod by its VPP interface - the policer limits the egress bandwidth of the pod and/or sets the DSCP
+// mark of its traffic. Interface with a policer already applied (e.g. after restart of the agent) is left as is.
+func (s *remoteCNIserver) configurePodPolicer(config *containeridx.Config) error {
+	settings, err := s.podPolicerFromAnnotations(config)
+	if err != nil || settings == nil || config.VppIf == nil {
+		return err
+	}
+	swIfIndex, _, found := s.swIfIndex.LookupIdx(config.VppIf.Name)
+	if !found {
+		return fmt.Errorf("interface %s not found", config.VppIf.Name)
+	}
+	tableIndex, err := s.policerClassifyTable(swIfIndex)
+	if err != nil || tableIndex != ^uint32(0) {
+		return err
+	}
+
+	policerReq := &policer.PolicerAddDel{
+		IsAdd:             1,
+		Name:              []byte(podPolicerPrefix + config.VppIf.Name),
+		Cir:               settings.egressKbps,
+		RateType:          policerRateKbps,
+		RoundType:         policerRoundClosest,
+		Type:              policerType1R2C,
+		ConformActionType: policerActionTransmit,
+		ExceedActionType:  policerActionDrop,
+		ViolateActionType: policerActionDrop,
+	}
+	if settings.egressKbps == 0 {
+		policerReq.Cir = unlimitedPolicerRate
+	}
+	policerReq.Cb = uint64(policerReq.Cir) * 1000 / 8 / 10 // 100ms of traffic
+	if policerReq.Cb < minPolicerBurst {
+		policerReq.Cb = minPolicerBurst
+	}
+	if settings.mark {
+		policerReq.ConformActionType = policerActionMarkAndTransmit
+		policerReq.ConformDscp = settings.dscp
+		if settings.egressKbps == 0 {
+			policerReq.ExceedActionType = policerActionMarkAndTransmit
+			policerReq.ExceedDscp = settings.dscp
+			policerReq.ViolateActionType = policerActionMarkAndTransmit
+			policerReq.ViolateDscp = settings.dscp
+		}
+	}
+	policerReply := &policer.PolicerAddDelReply{}
+	if err := s.sendVppRequest(policerReq, policerReply, &policerReply.Retval); err != nil {
+		return fmt.Errorf("can't add policer for %s: %v", config.VppIf.Name, err)
+	}
+
+	// the policer is applied to all packets via a classify table with a single session matching anything
+	tableReq := &classify.ClassifyAddDelTable{
+		IsAdd:          1,
+		TableIndex:     ^uint32(0),
+		Nbuckets:       2,
+		MemorySize:     64 * 1024,
+		MatchNVectors:  1,
+		NextTableIndex: ^uint32(0),
+		MissNextIndex:  ^uint32(0),
+		Mask:           make([]byte, classifyMatchAllLen),
+	}
+	tableReply := &classify.ClassifyAddDelTableReply{}
+	if err := s.sendVppRequest(tableReq, tableReply, &tableReply.Retval); err != nil {
+		return fmt.Errorf("can't add classify table for %s: %v", config.VppIf.Name, err)
+	}
+	sessionReq := &classify.ClassifyAddDelSession{
+		IsAdd:        1,
+		TableIndex:   tableReply.NewTableIndex,
+		HitNextIndex: policerReply.PolicerIndex,
+		OpaqueIndex:  ^uint32(0),
+		Match:        make([]byte, classifyMatchAllLen),
+	}
+	sessionReply := &classify.ClassifyAddDelSessionReply{}
+	if err := s.sendVppRequest(sessionReq, sessionReply, &sessionReply.Retval); err != nil {
+		return fmt.Errorf("can't add classify session for %s: %v", config.VppIf.Name, err)
+	}
+	return s.policerClassifySetInterface(swIfIndex, tableReply.NewTableIndex, true)
+}
+
+// unconfigurePodPolicer removes the policer applied on the traffic of the pod, if there is any.
+// Must be called before the VPP interface of the pod is deleted.
+func (s *remoteCNIserver) unconfigurePodPolicer(config *containeridx.Config) error {
+	if config.VppIf == nil {
+		return nil
+	}
+	swIfIndex, _, found := s.swIfIndex.LookupIdx(config.VppIf.Name)
+	if !found {
+		return nil
+	}
+	tableIndex, err := s.policerClassifyTable(swIfIndex)
+	if err != nil || tableIndex == ^uint32(0) {
+		return err
+	}
+	if err := s.policerClassifySetInterface(swIfIndex, tableIndex, false); err != nil {
+		return err
+	}
+	tableReq := &classify.ClassifyAddDelTable{
+		TableIndex: tableIndex,
+	}
+	tableReply := &classify.ClassifyAddDelTableReply{}
+	if err := s.sendVppRequest(tableReq, tableReply, &tableReply.Retval); err != nil {
+		return fmt.Errorf("can't delete classify table of %s: %v", config.VppIf.Name, err)
+	}
+	policerReq := &policer.PolicerAddDel{
+		Name: []byte(podPolicerPrefix + config.VppIf.Name),
+	}
+	policerReply := &policer.PolicerAddDelReply{}
+	if err := s.sendVppRequest(policerReq, policerReply, &policerReply.Retval); err != nil {
+		return fmt.Errorf("can't delete policer of %s: %v", config.VppIf.Name, err)
+	}
+	return nil
+}
+
+// policerClassifyTable returns the index of the IPv4 policer classify table applied on the given interface
+// (^uint32(0) if none).
+func (s *remoteCNIserver) policerClassifyTable(swIfIndex uint32) (uint32, error) {
+	tableIndex := ^uint32(0)
+	reqCtx := s.govppChan.SendMultiRequest(&policer_classify.PolicerClassifyDump{Type: policerClassifyTableIP4})
+	for {
+		details := &policer_classify.PolicerClassifyDetails{}
+		stop, err := reqCtx.ReceiveReply(details)
+		if err != nil {
+			s.metrics.vppAPIError(details.GetMessageName())
+			return tableIndex, err
+		}
+		if stop {
+			return tableIndex, nil
+		}
+		if details.SwIfIndex == swIfIndex {
+			tableIndex = details.TableIndex
+		}
+	}
+}
+
+func (s *remoteCNIserver) policerClassifySetInterface(swIfIndex, tableIndex uint32, isAdd bool) error {
+	req := &policer_classify.PolicerClassifySetInterface{
+		SwIfIndex:     swIfIndex,
+		IP4TableIndex: tableIndex,
+		IP6TableIndex: tableIndex,
+		L2TableIndex:  ^uint32(0),
+	}
+	if isAdd {
+		req.IsAdd = 1
+	}
+	reply := &policer_classify.PolicerClassifySetInterfaceReply{}
+	return s.sendVppRequest(req, reply, &reply.Retval)
+}
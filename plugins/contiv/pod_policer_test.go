@@ -0,0 +1,88 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	"golang.org/x/net/context"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	kbps, err := parseBandwidth("10M")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(kbps).To(gomega.BeEquivalentTo(10000))
+
+	kbps, err = parseBandwidth("1G")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(kbps).To(gomega.BeEquivalentTo(1000000))
+
+	_, err = parseBandwidth("fast")
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	_, err = parseBandwidth("100")
+	gomega.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestParseDSCP(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	dscp, err := parseDSCP("ef")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(dscp).To(gomega.BeEquivalentTo(46))
+
+	dscp, err = parseDSCP("26")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(dscp).To(gomega.BeEquivalentTo(26))
+
+	_, err = parseDSCP("64")
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	_, err = parseDSCP("gold")
+	gomega.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestPodPolicer(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, configuredContainers, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+	server.getPodAnnotations = func(podNamespace, podName string) (map[string]string, error) {
+		return map[string]string{egressBandwidthAnnotation: "10M", ingressBandwidthAnnotation: "10M",
+			dscpAnnotation: "AF41"}, nil
+	}
+
+	// the pod with the bandwidth limit and DSCP mark is connected
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+
+	reply, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+
+	// the pod with invalid DSCP is refused
+	server.getPodAnnotations = func(podNamespace, podName string) (map[string]string, error) {
+		return map[string]string{dscpAnnotation: "gold"}, nil
+	}
+	_, err = server.Add(context.Background(), &req)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	_, found := configuredContainers.LookupContainer(containerID)
+	gomega.Expect(found).To(gomega.BeFalse())
+}
@@ -13,14 +13,18 @@
 // limitations under the License.
 
 //go:generate binapi-generator --input-file=/usr/share/vpp/api/dhcp.api.json --output-dir=bin_api
+//go:generate binapi-generator --input-file=/usr/share/vpp/api/vhost_user.api.json --output-dir=bin_api
 
 package contiv
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"git.fd.io/govpp.git/api"
 	govppapi "git.fd.io/govpp.git/api"
@@ -28,7 +32,11 @@ import (
 	"github.com/contiv/vpp/plugins/contiv/containeridx"
 	"github.com/contiv/vpp/plugins/contiv/ipam"
 	"github.com/contiv/vpp/plugins/contiv/model/cni"
+	containermodel "github.com/contiv/vpp/plugins/contiv/model/container"
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	"github.com/contiv/vpp/plugins/kvdbproxy"
+	"github.com/contiv/vpp/plugins/kvstore"
 	"github.com/gogo/protobuf/proto"
 	"github.com/ligato/cn-infra/datasync"
 	"github.com/ligato/cn-infra/logging"
@@ -50,6 +58,7 @@ const (
 	linuxIfMaxLen                 = 15
 	afPacketNamePrefix            = "afpacket"
 	tapNamePrefix                 = "tap"
+	memifNamePrefix               = "memif"
 	podNameExtraArg               = "K8S_POD_NAME"
 	podNamespaceExtraArg          = "K8S_POD_NAMESPACE"
 	vethHostEndLogicalName        = "veth-vpp1"
@@ -60,13 +69,33 @@ const (
 	tapVPPEndLogicalName          = "tap-vpp2"
 	tapVPPEndName                 = "vpp2"
 	podIfIPPrefix                 = "10.2.1"
+	podIfIPv6Prefix               = "fd02:1::"
+	podIPAddressAnnotation        = "contivpp.io/ip-address"
+
+	// defaultPodReflectionTimeout is the maximum time a CNI Add waits for the pod to be reflected by KSR
+	defaultPodReflectionTimeout = 5 * time.Second
+	// podReflectionCheckPeriod is the period of the checks whether the pod is reflected by KSR
+	podReflectionCheckPeriod = 100 * time.Millisecond
 )
 
 // remoteCNIserver represents the remote CNI server instance. It accepts the requests from the contiv-CNI
 // (acting as a GRPC-client) and configures the networking between VPP and the PODs.
 type remoteCNIserver struct {
 	logging.Logger
-	sync.Mutex
+
+	// CNI requests hold the read lock, so that the requests of different PODs
+	// are processed in parallel, changes of the vswitch config hold the write lock.
+	// The lock stays until the CNI requests are routed through the event loop as well.
+	sync.RWMutex
+
+	// event loop serializing the changes of the vswitch config (resync, DHCP, node changes, timers)
+	loop *eventLoop
+
+	// pipeline processing CNI requests of different PODs concurrently
+	podPipeline *podPipeline
+
+	// metrics exposed via Prometheus, nil if not exposed
+	metrics *metrics
 
 	// VPP local client transaction factory
 	vppTxnFactory func() linux.DataChangeDSL
@@ -77,18 +106,37 @@ type remoteCNIserver struct {
 	// GoVPP channel for direct binary API calls (if needed)
 	govppChan *api.Channel
 
+	// GoVPP channel used by the dataplane probe, so that the probe does not interfere
+	// with CNI requests using govppChan (govppChan is used if nil)
+	probeChan *api.Channel
+
 	// VPP interface index map
 	swIfIndex ifaceidx.SwIfIndex
 
 	// map of configured containers
 	configuredContainers *containeridx.ConfigIndex
 
+	// persistent records of the connected containers, used to remove the configuration
+	// of containers that disappeared while the agent was not running
+	containerStore containerStore
+
+	// returns keys (namespace/name) of the PODs running in k8s
+	listPods func() ([]string, error)
+
+	// containers connected before the restart of the agent, which are still running
+	restoredContainers     map[string]*containermodel.Container
+	restoredContainersLock sync.Mutex
+
+	// true once the orphaned containers were cleaned up after the start of the agent
+	orphansCleaned bool
+
 	// IPAM module used by the CNI server
 	ipam *ipam.IPAM
 
 	// counter of connected containers. It is used for generating afpacket names and assigned IP addresses.
+	// Updated atomically, since PODs are connected concurrently (see nextPodIfIdx).
 	// TODO: do not rely on counter, since it can overflow uint8 after many container add/remove transactions
-	counter int
+	counter int32
 
 	// set to true when running unit tests
 	test bool
@@ -108,13 +156,25 @@ type remoteCNIserver struct {
 	// node specific configuration
 	nodeConfig *OneNodeConfig
 
+	// entries of the IDs allocated by the other nodes, keyed by the ID
+	otherNodes map[uint32]*node.NodeInfo
+
+	// nodes of the k8s cluster reflected by KSR, keyed by the node name
+	k8sNodes map[string]*nodemodel.Node
+
+	// cache of the node ID allocations and k8s nodes filled by the resync of the nodes, may be nil
+	nodeCache *nodeCache
+
+	// other nodes with the configured routes, keyed by the ID
+	routedNodes map[uint32]*node.NodeInfo
+
 	// other configuration
 	tcpChecksumOffloadDisabled bool
 
-	// the variables ensures that add/del requests are processed
-	// only when vswitch connectivity is configured
+	// the variables ensures that add/del requests and the gated events of the event loop
+	// are processed only when vswitch connectivity is configured, vswitchReady is closed once configured
 	vswitchConnectivityConfigured bool
-	vswitchCond                   *sync.Cond
+	vswitchReady                  chan struct{}
 
 	// if the flag is true only veth without stn and tcp stack is configured
 	disableTCPstack bool
@@ -126,6 +186,12 @@ type remoteCNIserver struct {
 	// version of the TAP interface to use (if useTAPInterfaces==true)
 	tapVersion uint8
 
+	// if true, legacy TAP interfaces are used once the kernel refuses to create TAPv2 interface
+	tapV2Fallback bool
+
+	// guards tapVersion, which can change by the fallback from TAPv2 while PODs are being connected
+	tapLock sync.Mutex
+
 	// Rx/Tx ring size for TAPv2
 	tapV2RxRingSize uint16
 	tapV2TxRingSize uint16
@@ -133,12 +199,56 @@ type remoteCNIserver struct {
 	// use pure L2 node interconnect instead of VXLANs
 	useL2Interconnect bool
 
+	// steer the traffic of other nodes into SRv6 policies (on top of the L2 interconnect)
+	useSRv6          bool
+	srv6SIDSubnet    *net.IPNet
+	srv6PolicySubnet *net.IPNet
+	srv6Waypoints    []net.IP
+	srv6Gateway      net.IP
+
+	// MTU of the node interconnect (0 = detect) and of the interfaces connecting pods and the host stack with VPP
+	linkMTU uint32
+	podMTU  uint32
+
+	// protect the VXLAN traffic to other nodes with IPsec
+	useIPSec           bool
+	ipsecPSKFile       string
+	ipsecPSK           []byte
+	ipsecRekeyInterval time.Duration
+	ipsecEpoch         uint64
+	ipsecTunnels       map[uint32]*ipsecTunnel
+	ipsecNow           func() time.Time
+
+	// balance the traffic to other nodes across multiple uplinks (ECMP)
+	uplinks             []*uplink
+	uplinkProbeInterval time.Duration
+	probeUplink         func(ifName string) error
+
+	// executes VPP CLI command (used for the configuration not supported by vpp-agent)
+	execVppCLI func(command string) (string, error)
+
+	// config of this node from the config file, applies once the data store entry of the node is removed
+	fileNodeConfig *OneNodeConfig
+
+	// true if the config of this node is overridden by its entry in the data store
+	nodeConfigStored bool
+
 	// bridge domain used for VXLAN tunnels
 	vxlanBD *vpp_l2.BridgeDomains_BridgeDomain
 
 	// name of physical interfaces configured by the agent
 	physicalIfs []string
 
+	// name of the main physical interface (empty if loopback is used instead)
+	mainPhysicalIf string
+
+	// name of the bond interface used as the main physical interface (empty if not bonded)
+	bondIfName string
+
+	// 802.1Q VLAN of the main physical interface (0 = untagged) and the name of its sub-interface
+	vlanID     uint16
+	vlanIfName string
+
 	// name of the interface interconnecting VPP with the host stack
 	hostInterconnectIfName string
 
@@ -147,6 +257,27 @@ type remoteCNIserver struct {
 
 	dhcpNotif chan govppapi.Message
 
+	// getPodAnnotations returns annotations of the given pod as reflected by KSR (nil if not known)
+	getPodAnnotations func(podNamespace, podName string) (map[string]string, error)
+
+	// maximum time a CNI Add waits for the pod to be reflected by KSR before it fails
+	podReflectionTimeout time.Duration
+
+	// publishPodAnnotations requests KSR to annotate the given pod (nil annotations withdraw the request)
+	publishPodAnnotations func(podNamespace, podName string, annotations map[string]string) error
+
+	// k8sNodeDeleted is called when the deletion of another k8s node is observed (may be nil)
+	k8sNodeDeleted func(name string)
+
+	// reportK8sEvent reports a Warning K8s Event of the given pod, or of this node if podName is empty (may be nil)
+	reportK8sEvent func(podNamespace, podName, reason, message string)
+
+	// secondary networks pods can attach additional interfaces to, keyed by network name
+	secondaryNetworks map[string]SecondaryNetwork
+
+	// export of the flows observed by VPP to an IPFIX collector
+	flowExport FlowExportConfig
+
 	ctx           context.Context
 	ctxCancelFunc context.CancelFunc
 }
@@ -161,10 +292,12 @@ type vswitchConfig struct {
 	vethVpp        *linux_intf.LinuxInterfaces_Interface
 	interconnectAF *vpp_intf.Interfaces_Interface
 
-	routesToHost     []*vpp_l3.StaticRoutes_Route
-	routeFromHost    *linux_l3.LinuxStaticRoutes_Route
-	routeForServices *linux_l3.LinuxStaticRoutes_Route
-	l4Features       *vpp_l4.L4Features
+	routesToHost      []*vpp_l3.StaticRoutes_Route
+	routeFromHost     *linux_l3.LinuxStaticRoutes_Route
+	routeFromHostIPv6 *linux_l3.LinuxStaticRoutes_Route
+	routesForPools    []*linux_l3.LinuxStaticRoutes_Route
+	routeForServices  *linux_l3.LinuxStaticRoutes_Route
+	l4Features        *vpp_l4.L4Features
 
 	vxlanBVI *vpp_intf.Interfaces_Interface
 	vxlanBD  *vpp_l2.BridgeDomains_BridgeDomain
@@ -190,6 +323,7 @@ func newRemoteCNIServer(logger logging.Logger, vppTxnFactory func() linux.DataCh
 		nodeID:                     nodeID,
 		ipam:                       ipam,
 		nodeConfig:                 nodeConfig,
+		fileNodeConfig:             nodeConfig,
 		tcpChecksumOffloadDisabled: config.TCPChecksumOffloadDisabled,
 		useTAPInterfaces:           config.UseTAPInterfaces,
 		tapVersion:                 config.TAPInterfaceVersion,
@@ -197,25 +331,86 @@ func newRemoteCNIServer(logger logging.Logger, vppTxnFactory func() linux.DataCh
 		tapV2TxRingSize:            config.TAPv2TxRingSize,
 		disableTCPstack:            config.TCPstackDisabled,
 		useL2Interconnect:          config.UseL2Interconnect,
-	}
-	server.vswitchCond = sync.NewCond(&server.Mutex)
+		podPipeline:                newPodPipeline(config.MaxParallelPodRequests),
+		restoredContainers:         map[string]*containermodel.Container{},
+		otherNodes:                 map[uint32]*node.NodeInfo{},
+		k8sNodes:                   map[string]*nodemodel.Node{},
+		routedNodes:                map[uint32]*node.NodeInfo{},
+		flowExport:                 config.FlowExport,
+		linkMTU:                    config.MTUSize,
+		podReflectionTimeout:       defaultPodReflectionTimeout,
+	}
+	server.vswitchReady = make(chan struct{})
 	server.ctx, server.ctxCancelFunc = context.WithCancel(context.Background())
+	server.loop = newEventLoop(logger, &server.RWMutex, func() bool { return server.vswitchConnectivityConfigured })
+	go server.loop.run(server.ctx)
 	server.dhcpNotif = make(chan govppapi.Message, 1)
+	server.execVppCLI = func(command string) (string, error) {
+		return vppCLI(govppChan, command)
+	}
+	if err := server.selectPodInterconnect(config.PodInterconnect); err != nil {
+		return nil, err
+	}
+	if err := server.selectInterNodeTransport(config.InterNodeTransport); err != nil {
+		return nil, err
+	}
+	if server.useSRv6 {
+		if err := server.initSRv6(config.SRv6); err != nil {
+			return nil, err
+		}
+	}
+	if config.IPSec.PSKFile != "" {
+		if err := server.initIPSec(config.IPSec); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateBond(nodeConfig); err != nil {
+		return nil, err
+	}
+	if err := server.initVlan(config.VlanID, nodeConfig); err != nil {
+		return nil, err
+	}
+	if err := server.initUplinks(nodeConfig, config.UplinkProbeInterval); err != nil {
+		return nil, err
+	}
+	if server.useL2Interconnect && server.ipam.IPv6Enabled() {
+		logger.Warn("IPv6 pods of other nodes are reachable only via VXLAN")
+	}
+	if err := server.validateSecondaryNetworks(config.SecondaryNetworks); err != nil {
+		return nil, err
+	}
 	return server, nil
 }
 
 // resync is called by the plugin infra when the state of the GRPC server needs to be resynchronized,
-// including the initialization phase
+// including the initialization phase. The resync is handled by the event loop before any other event.
 func (s *remoteCNIserver) resync() error {
-	s.Lock()
-	defer s.Unlock()
+	err := s.loop.process("resync", resyncPriority, s.resyncVswitch)
+	if err != nil && err != errEventLoopStopped && s.reportK8sEvent != nil {
+		s.reportK8sEvent("", "", reasonVswitchConfigFailed,
+			fmt.Sprintf("Configuration of the vswitch failed, pods cannot be connected: %v", err))
+	}
+	return err
+}
 
+// resyncVswitch applies the base vswitch config, the method is run by the event loop.
+func (s *remoteCNIserver) resyncVswitch() error {
 	err := s.configureVswitchConnectivity()
 	if err != nil {
-		s.Logger.Error(err)
+		return err
 	}
 
-	return err
+	// re-applied on every resync, VPP may have been restarted
+	err = s.configureFlowExport()
+	if err != nil {
+		return err
+	}
+
+	if !s.orphansCleaned {
+		s.cleanupOrphanedContainers()
+		s.orphansCleaned = true
+	}
+	return nil
 }
 
 // close is called by the plugin infra when the CNI server needs to be stopped.
@@ -228,13 +423,77 @@ func (s *remoteCNIserver) close() {
 // Add handles CNI Add request, connects the container to the network.
 func (s *remoteCNIserver) Add(ctx context.Context, request *cni.CNIRequest) (*cni.CNIReply, error) {
 	s.Info("Add request received ", *request)
-	return s.configureContainerConnectivity(request)
+	start := time.Now()
+	tr := requestTraceFromContext(ctx)
+	queued := tr.startSpan("pipeline")
+	var reply *cni.CNIReply
+	var err error
+	s.podPipeline.process(request.ContainerId, func() {
+		queued.finish(nil)
+		reply, err = s.configureContainerConnectivity(tr, request)
+	})
+	s.metrics.observeCNIRequest("add", start, reply, err)
+	if err != nil && s.reportK8sEvent != nil {
+		extraArgs := s.parseCniExtraArgs(request.ExtraArguments)
+		if podName := extraArgs[podNameExtraArg]; podName != "" {
+			s.reportK8sEvent(extraArgs[podNamespaceExtraArg], podName, reasonNetworkSetupFailed,
+				fmt.Sprintf("Connecting the pod to the network failed: %v", err))
+		}
+	}
+	return reply, err
 }
 
 // Delete handles CNI Delete request, disconnects the container from the network.
 func (s *remoteCNIserver) Delete(ctx context.Context, request *cni.CNIRequest) (*cni.CNIReply, error) {
 	s.Info("Delete request received ", *request)
-	return s.unconfigureContainerConnectivity(request)
+	start := time.Now()
+	tr := requestTraceFromContext(ctx)
+	queued := tr.startSpan("pipeline")
+	var reply *cni.CNIReply
+	var err error
+	s.podPipeline.process(request.ContainerId, func() {
+		queued.finish(nil)
+		reply, err = s.unconfigureContainerConnectivity(tr, request)
+	})
+	s.metrics.observeCNIRequest("delete", start, reply, err)
+	return reply, err
+}
+
+// Check handles CNI Check request, verifies that the container is still connected to the network.
+func (s *remoteCNIserver) Check(ctx context.Context, request *cni.CNIRequest) (*cni.CNIReply, error) {
+	s.Info("Check request received ", *request)
+	start := time.Now()
+	tr := requestTraceFromContext(ctx)
+	queued := tr.startSpan("pipeline")
+	var reply *cni.CNIReply
+	var err error
+	s.podPipeline.process(request.ContainerId, func() {
+		queued.finish(nil)
+		reply, err = s.checkContainerConnectivity(tr, request)
+	})
+	s.metrics.observeCNIRequest("check", start, reply, err)
+	return reply, err
+}
+
+// waitForVswitchConnectivity blocks until the base vswitch config is successfully applied.
+func (s *remoteCNIserver) waitForVswitchConnectivity() {
+	<-s.vswitchReady
+}
+
+// setVswitchConfigured marks the base vswitch config as applied, which unblocks the CNI requests
+// and the gated events of the event loop. The method must be called with acquired mutex guarding
+// remoteCNI server.
+func (s *remoteCNIserver) setVswitchConfigured() {
+	if !s.vswitchConnectivityConfigured {
+		s.vswitchConnectivityConfigured = true
+		close(s.vswitchReady)
+	}
+}
+
+// nextPodIfIdx allocates a unique index of the VPP end of a POD interface,
+// the index is used to generate IP addresses of the interface.
+func (s *remoteCNIserver) nextPodIfIdx() int {
+	return int(atomic.AddInt32(&s.counter, 1))
 }
 
 // configureVswitchConnectivity configures base vSwitch VPP connectivity to the host IP stack and to the other hosts.
@@ -250,8 +509,8 @@ func (s *remoteCNIserver) configureVswitchConnectivity() error {
 	// only apply the config if resync hasn't done it already
 	if _, _, found := s.swIfIndex.LookupIdx(s.interconnectAfpacketName()); found {
 		s.Logger.Info("VSwitch connectivity is considered configured, skipping...")
-		s.vswitchConnectivityConfigured = true
-		s.vswitchCond.Broadcast()
+		s.configureMTU()
+		s.setVswitchConfigured()
 		return nil
 	}
 
@@ -266,6 +525,9 @@ func (s *remoteCNIserver) configureVswitchConnectivity() error {
 		return err
 	}
 
+	// MTU of the host interconnect and the pods depends on the main physical NIC
+	s.configureMTU()
+
 	// configure vswitch to host connectivity
 	err = s.configureVswitchHostConnectivity(config)
 	if err != nil {
@@ -284,15 +546,17 @@ func (s *remoteCNIserver) configureVswitchConnectivity() error {
 
 	// persist vswitch configuration in ETCD
 	err = s.persistVswitchConfig(config)
-	if err != nil {
+	if err == kvstore.ErrReadOnly {
+		// the configuration persisted by the previous run is served from the local cache
+		s.Logger.Warn("Unable to persist vswitch configuration, data store is not reachable")
+	} else if err != nil {
 		s.Logger.Error(err)
 		return err
 	}
 
 	if s.nodeIP != "" {
 		// set the state to configured and broadcast
-		s.vswitchConnectivityConfigured = true
-		s.vswitchCond.Broadcast()
+		s.setVswitchConfigured()
 	}
 	return err
 }
@@ -309,7 +573,15 @@ func (s *remoteCNIserver) configureVswitchNICs(config *vswitchConfig) error {
 	// find name of the main VPP NIC interface
 	nicName := ""
 	useDHCP := false
-	if s.nodeConfig != nil {
+	if s.vlanIfName != "" {
+		// the VLAN sub-interface of the NIC
+		nicName = s.vlanIfName
+		s.Logger.Debugf("Physical NIC is the VLAN sub-interface: %v ", nicName)
+	} else if s.bondIfName != "" {
+		// the bond aggregating the NICs listed in node config YAML
+		nicName = s.bondIfName
+		s.Logger.Debugf("Physical NIC is the bond: %v ", nicName)
+	} else if s.nodeConfig != nil {
 		// use name as as specified in node config YAML
 		nicName = s.nodeConfig.MainVppInterface.InterfaceName
 		s.Logger.Debugf("Physical NIC name taken from nodeConfig: %v ", nicName)
@@ -318,8 +590,7 @@ func (s *remoteCNIserver) configureVswitchNICs(config *vswitchConfig) error {
 	if nicName == "" {
 		// name not specified in config, use heuristic - first non-virtual interface
 		for _, name := range s.swIfIndex.GetMapping().ListNames() {
-			if strings.HasPrefix(name, "local") || strings.HasPrefix(name, "loop") ||
-				strings.HasPrefix(name, "host") || strings.HasPrefix(name, "tap") {
+			if isVirtualIfName(name) {
 				continue
 			} else {
 				nicName = name
@@ -357,9 +628,26 @@ func (s *remoteCNIserver) configureVswitchNICs(config *vswitchConfig) error {
 		}
 	}
 
+	// configure uplinks sharing the traffic to other nodes with the main interface
+	if s.nodeConfig != nil && len(s.nodeConfig.UplinkInterfaces) > 0 {
+		s.Logger.Debug("Configuring VPP for uplink interfaces")
+
+		err := s.configureUplinkInterfaces(config, s.nodeConfig)
+		if err != nil {
+			s.Logger.Error(err)
+			return err
+		}
+	}
+
 	return nil
 }
 
+// isVirtualIfName returns true if the VPP interface of the given name is not a physical NIC.
+func isVirtualIfName(name string) bool {
+	return strings.HasPrefix(name, "local") || strings.HasPrefix(name, "loop") ||
+		strings.HasPrefix(name, "host") || strings.HasPrefix(name, "tap")
+}
+
 // configureMainVPPInterface configures the main NIC used for node interconnect on vswitch VPP.
 func (s *remoteCNIserver) configureMainVPPInterface(config *vswitchConfig, nicName string, nicIP string, useDHCP bool) error {
 	var err error
@@ -394,6 +682,7 @@ func (s *remoteCNIserver) configureMainVPPInterface(config *vswitchConfig, nicNa
 		txn1.VppInterface(nic)
 		config.nics = append(config.nics, nic)
 		s.physicalIfs = append(s.physicalIfs, nicName)
+		s.mainPhysicalIf = nicName
 	} else {
 		// configure loopback instead of the physical NIC
 		s.Logger.Debug("Physical NIC not found, configuring loopback instead.")
@@ -468,14 +757,9 @@ func (s *remoteCNIserver) handleDHCPNotifications(notifCh chan govppapi.Message)
 				} else {
 					ipAddr = fmt.Sprintf("%s/%d", net.IP(notif.HostAddress[:4]).To4().String(), notif.MaskWidth)
 				}
-				s.Lock()
-				if s.nodeIP != "" && s.nodeIP != ipAddr {
-					s.Logger.Error("Update of Node IP address is not supported")
-				}
-				s.vswitchConnectivityConfigured = true
-				s.vswitchCond.Broadcast()
-				s.setNodeIP(ipAddr)
-				s.Unlock()
+				s.loop.process("DHCP lease", vswitchPriority, func() error {
+					return s.applyDHCPLease(ipAddr)
+				})
 
 				s.Logger.Info("DHCP event", *notif)
 			}
@@ -486,6 +770,50 @@ func (s *remoteCNIserver) handleDHCPNotifications(notifCh chan govppapi.Message)
 
 }
 
+// applyDHCPLease applies the IP address leased by DHCP to the main interface as the node IP. The first lease
+// completes the vswitch connectivity, a lease of a different address (e.g. after the lease expired) changes
+// the node IP. The method is run by the event loop.
+func (s *remoteCNIserver) applyDHCPLease(ipAddr string) error {
+	var err error
+	switch s.nodeIP {
+	case ipAddr:
+		// renewal of the lease
+		return nil
+	case "":
+		s.setNodeIP(ipAddr)
+	default:
+		s.Logger.Infof("Node IP changed by DHCP from %s to %s", s.nodeIP, ipAddr)
+		err = s.changeNodeIP(ipAddr)
+	}
+	s.setVswitchConfigured()
+	if flowErr := s.configureFlowExport(); flowErr != nil {
+		s.Logger.Error(flowErr)
+	}
+	return err
+}
+
+// changeNodeIP re-configures the connectivity to other nodes after the IP address of the node has changed.
+// The tunnels and IPsec SAs sourced from the old IP are removed and re-created with the new
+// one. The new IP is published to other nodes (subscribers of the node IP), which re-create their routes
+// towards this node. The method must be called with acquired mutex guarding remoteCNI server.
+func (s *remoteCNIserver) changeNodeIP(nodeIP string) error {
+	var wasErr error
+	for id, nodeInfo := range s.routedNodes {
+		if err := s.deleteRoutesToNode(nodeInfo); err != nil {
+			s.Logger.Error(err)
+			wasErr = err
+		}
+		delete(s.routedNodes, id)
+	}
+
+	s.setNodeIP(nodeIP)
+
+	if err := s.syncRoutesToNodes(); err != nil {
+		wasErr = err
+	}
+	return wasErr
+}
+
 // configureOtherVPPInterfaces other interfaces that were configured in contiv plugin YAML configuration.
 func (s *remoteCNIserver) configureOtherVPPInterfaces(config *vswitchConfig, nodeConfig *OneNodeConfig) error {
 
@@ -547,6 +875,11 @@ func (s *remoteCNIserver) configureVswitchHostConnectivity(config *vswitchConfig
 
 	// execute the config transaction
 	err := txn1.Send().ReceiveReply()
+	if err != nil && s.useTAPInterfaces && s.fallbackFromTAPv2(config.tapHost, err) {
+		// retry with the legacy TAP interface
+		config.tapHost = s.interconnectTap()
+		err = s.vppTxnFactory().Put().VppInterface(config.tapHost).Send().ReceiveReply()
+	}
 	if err != nil {
 		s.Logger.Error(err)
 		return err
@@ -589,6 +922,14 @@ func (s *remoteCNIserver) configureVswitchHostConnectivity(config *vswitchConfig
 	// configure the route from the host to PODs
 	config.routeFromHost = s.routeFromHost()
 	txn2.LinuxRoute(config.routeFromHost)
+	if s.ipam.IPv6Enabled() {
+		config.routeFromHostIPv6 = s.routeFromHostIPv6()
+		txn2.LinuxRoute(config.routeFromHostIPv6)
+	}
+	config.routesForPools = s.routesFromHostToPools()
+	for _, r := range config.routesForPools {
+		txn2.LinuxRoute(r)
+	}
 
 	// route from the host to k8s service range from the host
 	config.routeForServices = s.routeServicesFromHost()
@@ -605,6 +946,18 @@ func (s *remoteCNIserver) configureVswitchHostConnectivity(config *vswitchConfig
 		return err
 	}
 
+	// proxy neighbor discovery for the IPv6 addresses behind VPP
+	if s.ipam.IPv6Enabled() {
+		err = s.configureHostNDProxy()
+		if err != nil {
+			s.Logger.Error(err)
+			if !s.test {
+				// skip error by unit tests
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -675,6 +1028,12 @@ func (s *remoteCNIserver) persistVswitchConfig(config *vswitchConfig) error {
 		}
 	}
 	changes[linux_l3.StaticRouteKey(config.routeFromHost.Name)] = config.routeFromHost
+	if config.routeFromHostIPv6 != nil {
+		changes[linux_l3.StaticRouteKey(config.routeFromHostIPv6.Name)] = config.routeFromHostIPv6
+	}
+	for _, r := range config.routesForPools {
+		changes[linux_l3.StaticRouteKey(r.Name)] = r
+	}
 	changes[linux_l3.StaticRouteKey(config.routeForServices.Name)] = config.routeForServices
 	changes[vpp_l4.FeatureKey()] = config.l4Features
 
@@ -714,55 +1073,146 @@ func (s *remoteCNIserver) cleanupVswitchConnectivity() {
 	}
 }
 
+// assignPodIP assigns an IP address for the POD. The IPv4 address assigned by the external IPAM plugin
+// or requested by the POD annotation is used if present. PODs of namespaces bound to an IP pool get the next
+// available IP address from the pool. Otherwise the IP address of a restarted POD is kept if reserved
+// or the next available one is assigned.
+func (s *remoteCNIserver) assignPodIP(request *cni.CNIRequest, config *containeridx.Config) (net.IP, error) {
+	podID := request.NetworkNamespace
+	podKey := ""
+	if config.PodName != "" {
+		podKey = config.PodNamespace + "/" + config.PodName
+	}
+
+	for _, addr := range request.ExternalIpamAddresses {
+		if addr.Version != cni.CNIReply_Interface_IP_IPV4 {
+			continue
+		}
+		ip, _, err := net.ParseCIDR(addr.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q assigned by the external IPAM: %v", addr.Address, err)
+		}
+		err = s.ipam.AllocatePodIP(podID, podKey, ip)
+		if err != nil {
+			return nil, fmt.Errorf("IP address %v assigned by the external IPAM can't be used: %v", ip, err)
+		}
+		return ip.To4(), nil
+	}
+
+	if s.getPodAnnotations != nil && config.PodName != "" {
+		annotations, err := s.waitForPodAnnotations(config.PodNamespace, config.PodName)
+		if err != nil {
+			return nil, err
+		}
+		if requested, found := annotations[podIPAddressAnnotation]; found {
+			ip := net.ParseIP(requested)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q in the annotation %s", requested, podIPAddressAnnotation)
+			}
+			err = s.ipam.AllocatePodIP(podID, podKey, ip)
+			if err != nil {
+				return nil, fmt.Errorf("IP address %v requested by the annotation %s is not available: %v",
+					ip, podIPAddressAnnotation, err)
+			}
+			return ip.To4(), nil
+		}
+	}
+
+	if pool := s.ipam.NamespacePool(config.PodNamespace); pool != "" {
+		return s.ipam.NextPodIPFromPool(podID, podKey, pool)
+	}
+	return s.ipam.NextPodIPForKey(podID, podKey)
+}
+
+// waitForPodAnnotations returns annotations of the given pod. The pod may not be reflected by KSR yet
+// when the CNI request arrives, the annotations are therefore re-read until podReflectionTimeout elapses,
+// so that the IP address requested by the annotation is not ignored.
+func (s *remoteCNIserver) waitForPodAnnotations(podNamespace, podName string) (map[string]string, error) {
+	deadline := time.Now().Add(s.podReflectionTimeout)
+	for {
+		annotations, err := s.getPodAnnotations(podNamespace, podName)
+		if err != nil || annotations != nil {
+			return annotations, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("pod %s/%s is not reflected by KSR, unable to read its annotations",
+				podNamespace, podName)
+		}
+		time.Sleep(podReflectionCheckPeriod)
+	}
+}
+
 // configureContainerConnectivity connects the POD to vSwitch VPP based on the CNI server configuration:
 // either via virtual ethernet interface pair and AF_PACKET, or via TAP interface.
 // It also configures the VPP TCP stack for this container, in case it would be LD_PRELOAD-ed.
-func (s *remoteCNIserver) configureContainerConnectivity(request *cni.CNIRequest) (*cni.CNIReply, error) {
+func (s *remoteCNIserver) configureContainerConnectivity(tr *requestTrace, request *cni.CNIRequest) (*cni.CNIReply, error) {
 
 	// do not connect any containers until the base vswitch config is successfully applied
-	s.Lock()
-	for !s.vswitchConnectivityConfigured {
-		s.vswitchCond.Wait()
-	}
-	defer s.Unlock()
+	span := tr.startSpan("vswitch connectivity")
+	s.waitForVswitchConnectivity()
+	s.RLock()
+	defer s.RUnlock()
+	span.finish(nil)
 
-	// increment request counter
-	s.counter++
+	// allocate index of the VPP end of the POD interface
+	podIfIdx := s.nextPodIfIdx()
 
 	// prepare config details struct
 	extraArgs := s.parseCniExtraArgs(request.ExtraArguments)
 	config := &containeridx.Config{
-		PodName:      extraArgs[podNameExtraArg],
-		PodNamespace: extraArgs[podNamespaceExtraArg],
+		PodName:          extraArgs[podNameExtraArg],
+		PodNamespace:     extraArgs[podNamespaceExtraArg],
+		NetworkNamespace: request.NetworkNamespace,
+		InterfaceName:    request.InterfaceName,
 	}
 
 	// assign an IP address for this POD
-	podIP, err := s.ipam.NextPodIP(request.NetworkNamespace)
+	span = tr.startSpan("ipam")
+	podIP, err := s.assignPodIP(request, config)
 	if err != nil {
+		span.finish(err)
 		return nil, fmt.Errorf("Can't get new IP address for pod: %v", err)
 	}
 	podIPCIDR := podIP.String() + "/32"
 
-	// TODO: merge transactions into one once linuxplugin supports TAPs and all race-conditions are fixed.
+	// assign also an IPv6 address if dual-stack is enabled
+	var podIPv6 net.IP
+	if s.ipam.IPv6Enabled() {
+		podIPv6, err = s.ipam.NextPodIPv6(request.NetworkNamespace)
+		if err != nil {
+			span.finish(err)
+			s.ipam.ReleasePodIP(request.NetworkNamespace)
+			return nil, fmt.Errorf("Can't get new IPv6 address for pod: %v", err)
+		}
+		config.PodIPv6 = podIPv6.String()
+	}
+	span.finish(nil)
 
-	// configure POD interface
-	err = s.configurePodInterface(request, podIP, config)
+	// configure POD interface together with the POD-related config on VPP
+	err = s.configurePodInterface(tr, request, podIfIdx, podIP, podIPv6, config)
 	if err != nil {
 		s.Logger.Error(err)
+		s.ipam.ReleasePodIP(request.NetworkNamespace)
 		return s.generateCniErrorReply(err)
 	}
 
-	// configure POD-related config on on VPP
-	err = s.configurePodVPPSide(request, podIP, config)
+	// attach the POD to the secondary networks requested by its annotation
+	span = tr.startSpan("secondary interfaces")
+	err = s.configureSecondaryIfs(request, config)
+	span.finish(err)
 	if err != nil {
 		s.Logger.Error(err)
+		s.rollbackContainerConnectivity(request, config)
 		return s.generateCniErrorReply(err)
 	}
 
 	// persist POD configuration in ETCD
-	err = s.persistPodConfig(config)
+	span = tr.startSpan("persist config")
+	err = s.persistPodConfig(request, config)
+	span.finish(err)
 	if err != nil {
 		s.Logger.Error(err)
+		s.rollbackContainerConnectivity(request, config)
 		return s.generateCniErrorReply(err)
 	}
 
@@ -773,19 +1223,33 @@ func (s *remoteCNIserver) configureContainerConnectivity(request *cni.CNIRequest
 
 	// prepare and send reply for the CNI request
 	reply := s.generateCniReply(config, request.NetworkNamespace, podIPCIDR)
+	reply.Interfaces = append(reply.Interfaces, s.secondaryIfReplies(request, config)...)
 	return reply, err
 }
 
+// rollbackContainerConnectivity removes the configuration of the POD applied before a failure of the CNI Add request
+// and releases the IP address of the POD, so that no half-configured POD is left behind.
+func (s *remoteCNIserver) rollbackContainerConnectivity(request *cni.CNIRequest, config *containeridx.Config) {
+	if len(config.SecondaryIfs) > 0 {
+		s.unconfigureSecondaryIfs(request, config)
+		config.SecondaryIfs = nil
+	}
+	if err := s.unconfigurePodInterface(request, config); err != nil {
+		s.Logger.Warnf("Failed to roll back the configuration of the POD %s: %v", request.ContainerId, err)
+	}
+	s.ipam.ReleasePodIP(request.NetworkNamespace)
+}
+
 // unconfigureContainerConnectivity disconnects the POD from vSwitch VPP.
-func (s *remoteCNIserver) unconfigureContainerConnectivity(request *cni.CNIRequest) (*cni.CNIReply, error) {
+func (s *remoteCNIserver) unconfigureContainerConnectivity(tr *requestTrace, request *cni.CNIRequest) (*cni.CNIReply, error) {
 	var err error
 
 	// do not try to disconnect any containers until the base vswitch config is successfully applied
-	s.Lock()
-	for !s.vswitchConnectivityConfigured {
-		s.vswitchCond.Wait()
-	}
-	defer s.Unlock()
+	span := tr.startSpan("vswitch connectivity")
+	s.waitForVswitchConnectivity()
+	s.RLock()
+	defer s.RUnlock()
+	span.finish(nil)
 
 	// configuredContainers should not be nil unless this is a unit test
 	if s.configuredContainers == nil {
@@ -797,27 +1261,42 @@ func (s *remoteCNIserver) unconfigureContainerConnectivity(request *cni.CNIReque
 	// load container config
 	config, found := s.configuredContainers.LookupContainer(request.ContainerId)
 	if !found {
+		// the container may have been connected before the restart of the agent
+		if s.removeRestoredContainer(request.ContainerId) {
+			s.Logger.Infof("removed configuration of the container %s connected before restart", request.ContainerId)
+			return s.generateCniEmptyOKReply(), nil
+		}
 		s.Logger.Warnf("cannot find configuration for container: %s\n", request.ContainerId)
 		reply := s.generateCniEmptyOKReply()
 		return reply, nil
 	}
 
-	// delete POD-related config on VPP
-	err = s.unconfigurePodVPPSide(config)
+	// delete routes and ACLs applied via the pod network config API, the interfaces added by the API
+	// are removed together with the other secondary interfaces
+	span = tr.startSpan("secondary interfaces")
+	err = s.unconfigureExtConfigItems(config.ExtConfigs)
+	if err == nil {
+		err = s.unconfigureSecondaryIfs(request, config)
+	}
+	span.finish(err)
 	if err != nil {
 		s.Logger.Error(err)
 		return s.generateCniErrorReply(err)
 	}
 
-	// configure POD interface
+	// delete POD interface together with the POD-related config on VPP
+	span = tr.startSpan("vpp transaction")
 	err = s.unconfigurePodInterface(request, config)
+	span.finish(err)
 	if err != nil {
 		s.Logger.Error(err)
 		return s.generateCniErrorReply(err)
 	}
 
 	// delete persisted POD configuration from ETCD
-	err = s.deletePersistedPodConfig(config)
+	span = tr.startSpan("persist config")
+	err = s.deletePersistedPodConfig(request, config)
+	span.finish(err)
 	if err != nil {
 		s.Logger.Error(err)
 		return s.generateCniErrorReply(err)
@@ -829,7 +1308,9 @@ func (s *remoteCNIserver) unconfigureContainerConnectivity(request *cni.CNIReque
 	}
 
 	// release IP address of the POD
+	span = tr.startSpan("ipam")
 	err = s.ipam.ReleasePodIP(request.NetworkNamespace)
+	span.finish(err)
 	if err != nil {
 		s.Logger.Error(err)
 		return s.generateCniErrorReply(err)
@@ -840,65 +1321,112 @@ func (s *remoteCNIserver) unconfigureContainerConnectivity(request *cni.CNIReque
 	return reply, nil
 }
 
-// configurePodInterface configures POD's network interface and its routes + ARPs.
-func (s *remoteCNIserver) configurePodInterface(request *cni.CNIRequest, podIP net.IP, config *containeridx.Config) error {
-
-	podIPCIDR := podIP.String() + "/32"
-	podIPNet := &net.IPNet{
-		IP:   podIP,
-		Mask: net.CIDRMask(net.IPv4len*8, net.IPv4len*8),
-	}
+// checkContainerConnectivity verifies that the configuration applied for the POD by the Add request
+// is still in place: the POD interface with its IP address and default route in the network namespace
+// of the container and the interfaces on the VPP side. Missing configuration is reported as an error,
+// so that the container runtime re-creates the POD networking.
+func (s *remoteCNIserver) checkContainerConnectivity(tr *requestTrace, request *cni.CNIRequest) (*cni.CNIReply, error) {
+	var err error
 
-	// prepare the config transaction 1
-	txn1 := s.vppTxnFactory().Put()
+	s.waitForVswitchConnectivity()
+	s.RLock()
+	defer s.RUnlock()
 
-	podIfName := ""
+	// configuredContainers should not be nil unless this is a unit test
+	if s.configuredContainers == nil {
+		err = fmt.Errorf("configuration was not stored for container: %s", request.ContainerId)
+		s.Logger.Error(err)
+		return s.generateCniErrorReply(err)
+	}
 
-	// create VPP to POD interconnect interface
-	if s.useTAPInterfaces {
-		// TAP interface
-		config.VppIf = s.tapFromRequest(request, !s.disableTCPstack, podIPCIDR)
+	// load container config
+	config, found := s.configuredContainers.LookupContainer(request.ContainerId)
+	if !found {
+		err = fmt.Errorf("container %s is not connected to the network", request.ContainerId)
+		s.Logger.Error(err)
+		return s.generateCniErrorReply(err)
+	}
 
-		txn1.VppInterface(config.VppIf)
-	} else {
-		// veth pair + AF_PACKET
-		config.Veth1 = s.veth1FromRequest(request, podIPCIDR)
-		config.Veth2 = s.veth2FromRequest(request)
-		config.VppIf = s.afpacketFromRequest(request, !s.disableTCPstack, podIPCIDR)
+	// check POD-related config on VPP
+	vppIfs := []*vpp_intf.Interfaces_Interface{config.VppIf, config.Loopback}
+	for _, secondaryIf := range config.SecondaryIfs {
+		if secondaryIf.VppIf != nil {
+			vppIfs = append(vppIfs, secondaryIf.VppIf)
+		} else if !s.test {
+			if err := s.checkVhostUserIf(secondaryIf); err != nil {
+				s.Logger.Error(err)
+				return s.generateCniErrorReply(err)
+			}
+		}
+	}
+	for _, vppIf := range vppIfs {
+		if vppIf == nil {
+			continue
+		}
+		if _, _, exists := s.swIfIndex.LookupIdx(vppIf.Name); !exists {
+			err = fmt.Errorf("interface %s of container %s not found in VPP", vppIf.Name, request.ContainerId)
+			s.Logger.Error(err)
+			return s.generateCniErrorReply(err)
+		}
+	}
 
-		txn1.LinuxInterface(config.Veth1).
-			LinuxInterface(config.Veth2).
-			VppInterface(config.VppIf)
-		podIfName = config.Veth1.Name
+	// check POD interface and routes inside of the container
+	if !s.test {
+		err = s.checkPodInterface(request, config.VppARPEntry.IpAddress)
+		if err != nil {
+			s.Logger.Error(err)
+			return s.generateCniErrorReply(err)
+		}
 	}
 
-	if !s.useTAPInterfaces {
-		// TODO: temporary bypass this section for TAP interfaces, configured in configureHostTAP
+	reply := s.generateCniEmptyOKReply()
+	return reply, nil
+}
 
-		// link scope route - must be added before the default route
-		config.PodLinkRoute = s.podLinkRouteFromRequest(request, podIfName)
-		txn1.LinuxRoute(config.PodLinkRoute)
+// configurePodInterface configures POD's network interface and its routes + ARPs together with the vswitch VPP
+// part of the POD networking. VPP-side operations are applied in one transaction with the interface, so that
+// the POD is configured either completely or not at all - whatever was applied before a failure is rolled back.
+// IPv6 configuration is applied only if podIPv6 is not nil.
+func (s *remoteCNIserver) configurePodInterface(tr *requestTrace, request *cni.CNIRequest, podIfIdx int, podIP net.IP, podIPv6 net.IP, config *containeridx.Config) error {
 
-		// ARP to VPP
-		config.PodARPEntry = s.podArpEntry(request, podIfName, config.VppIf.PhysAddress)
-		txn1.LinuxArpEntry(config.PodARPEntry)
+	podIPNet := &net.IPNet{
+		IP:   podIP,
+		Mask: net.CIDRMask(net.IPv4len*8, net.IPv4len*8),
+	}
+	var podIPv6Net *net.IPNet
+	if podIPv6 != nil {
+		podIPv6Net = &net.IPNet{
+			IP:   podIPv6,
+			Mask: net.CIDRMask(net.IPv6len*8, net.IPv6len*8),
+		}
 	}
 
-	// execute the config transaction
-	err := txn1.Send().ReceiveReply()
+	// execute the config transaction 1
+	span := tr.startSpan("vpp transaction")
+	err := s.podConfigTxn(request, podIfIdx, podIP, podIPv6, config).Send().ReceiveReply()
+	if err != nil && s.useTAPInterfaces && s.fallbackFromTAPv2(config.VppIf, err) {
+		// retry with the legacy TAP interface
+		s.removePodConfig(config)
+		err = s.podConfigTxn(request, podIfIdx, podIP, podIPv6, config).Send().ReceiveReply()
+	}
+	span.finish(err)
 	if err != nil {
 		s.Logger.Error(err)
+		s.removePodConfig(config)
 		return err
 	}
 
 	// finish the TAP interface configuration (rename, move to proper namespace, etc.)
 	if s.useTAPInterfaces {
-		err = s.configureHostTAP(request, podIPNet, config.VppIf.PhysAddress)
-		// TODO: not stored in config, this will not be resynced in case of resync!!!
+		// re-applied by restoreContainer after restart
+		span = tr.startSpan("host TAP")
+		err = s.configureHostTAP(request, podIPNet, podIPv6Net, config.VppIf.PhysAddress)
+		span.finish(err)
 		if err != nil {
 			s.Logger.Error(err)
 			if !s.test {
 				// skip error by tests
+				s.removePodConfig(config)
 				return err
 			}
 		}
@@ -913,71 +1441,104 @@ func (s *remoteCNIserver) configurePodInterface(request *cni.CNIRequest, podIP n
 		txn2 := s.vppTxnFactory().Put()
 
 		// Add default route for the container
-		config.PodDefaultRoute = s.podDefaultRouteFromRequest(request, podIfName)
+		config.PodDefaultRoute = s.podDefaultRouteFromRequest(request, config.Veth1.Name)
 		txn2.LinuxRoute(config.PodDefaultRoute)
+		if podIPv6 != nil {
+			config.PodDefaultRouteIPv6 = s.podDefaultRouteIPv6FromRequest(request, config.Veth1.Name)
+			txn2.LinuxRoute(config.PodDefaultRouteIPv6)
+		}
 
 		// execute the config transaction
+		span = tr.startSpan("linux transaction")
 		err = txn2.Send().ReceiveReply()
+		span.finish(err)
 		if err != nil {
 			s.Logger.Error(err)
+			s.removePodConfig(config)
 			return err
 		}
 	}
 
-	return nil
-}
-
-// unconfigurePodInterface unconfigures POD's network interface and its routes + ARPs.
-func (s *remoteCNIserver) unconfigurePodInterface(request *cni.CNIRequest, config *containeridx.Config) error {
-
-	// prepare the config transaction
-	txn2 := s.vppTxnFactory().Delete()
-
-	// delete VPP to POD interconnect interface
-	txn2.VppInterface(config.VppIf.Name)
-	if !s.useTAPInterfaces {
-		txn2.LinuxInterface(config.Veth1.Name).
-			LinuxInterface(config.Veth2.Name)
+	// if requested, disable TCP checksum offload on the eth0 veth/TAP interface in the container.
+	if s.tcpChecksumOffloadDisabled {
+		err = s.disableTCPChecksumOffload(request)
+		if err != nil {
+			s.Logger.Error(err)
+			s.removePodConfig(config)
+			return err
+		}
 	}
 
-	if !s.useTAPInterfaces && !s.test {
-		// TODO: temporary bypass this section for TAP interfaces
-
-		// delete static routes
-		txn2.LinuxRoute(config.PodLinkRoute.Name).
-			LinuxRoute(config.PodDefaultRoute.Name)
-
-		// delete the ARP entry
-		txn2.LinuxArpEntry(config.PodARPEntry.Name)
+	// record the flows of the POD on its VPP interface
+	if s.flowExportEnabled() {
+		err = s.enableFlowProbe(config.VppIf.Name)
+		if err != nil {
+			s.Logger.Error(err)
+			s.unconfigurePodInterface(request, config)
+			return err
+		}
 	}
 
-	// execute the config transaction
-	err := txn2.Send().ReceiveReply()
+	// limit the egress bandwidth and/or mark the traffic of the POD as requested by its annotations
+	err = s.configurePodPolicer(config)
 	if err != nil {
 		s.Logger.Error(err)
+		s.unconfigurePodInterface(request, config)
 		return err
 	}
 
-	// delete the TAP interface from the host stack
-	if s.useTAPInterfaces {
-		err = s.unconfigureHostTAP(request)
-		// TODO: not stored in config, this will not be resynced in case of resync!!!
-		if err != nil {
-			s.Logger.Error(err)
-			return err
-		}
-	}
-
 	return nil
 }
 
-// configurePodVPPSide configures vswitch VPP part of the POD networking.
-func (s *remoteCNIserver) configurePodVPPSide(request *cni.CNIRequest, podIP net.IP, config *containeridx.Config) error {
+// podConfigTxn prepares the transaction creating VPP to POD interconnect interface with the routes + ARPs in the POD
+// and the vswitch VPP part of the POD networking (routes, ARP entries and the VPP TCP stack config).
+func (s *remoteCNIserver) podConfigTxn(request *cni.CNIRequest, podIfIdx int, podIP net.IP, podIPv6 net.IP, config *containeridx.Config) linux.PutDSL {
 	podIPCIDR := podIP.String() + "/32"
 
-	// prepare the config transaction
 	txn := s.vppTxnFactory().Put()
 
+	podIfName := ""
+
+	// create VPP to POD interconnect interface
+	if s.useTAPInterfaces {
+		// TAP interface
+		config.VppIf = s.tapFromRequest(request, podIfIdx, !s.disableTCPstack, podIPCIDR)
+
+		txn.VppInterface(config.VppIf)
+	} else {
+		// veth pair + AF_PACKET
+		config.Veth1 = s.veth1FromRequest(request, podIPCIDR)
+		if podIPv6 != nil {
+			config.Veth1.IpAddresses = append(config.Veth1.IpAddresses, podIPv6.String()+"/128")
+		}
+		config.Veth2 = s.veth2FromRequest(request)
+		config.VppIf = s.afpacketFromRequest(request, podIfIdx, !s.disableTCPstack, podIPCIDR)
+
+		txn.LinuxInterface(config.Veth1).
+			LinuxInterface(config.Veth2).
+			VppInterface(config.VppIf)
+		podIfName = config.Veth1.Name
+	}
+
+	if !s.useTAPInterfaces {
+		// TODO: temporary bypass this section for TAP interfaces, configured in configureHostTAP
+
+		// link scope route - must be added before the default route
+		config.PodLinkRoute = s.podLinkRouteFromRequest(request, podIfName)
+		txn.LinuxRoute(config.PodLinkRoute)
+
+		// ARP to VPP
+		config.PodARPEntry = s.podArpEntry(request, podIfName, config.VppIf.PhysAddress)
+		txn.LinuxArpEntry(config.PodARPEntry)
+
+		if podIPv6 != nil {
+			config.PodLinkRouteIPv6 = s.podLinkRouteIPv6FromRequest(request, podIfName)
+			config.PodARPEntryIPv6 = s.podArpEntryIPv6(request, podIfName, config.VppIf.PhysAddress)
+			txn.LinuxRoute(config.PodLinkRouteIPv6).
+				LinuxArpEntry(config.PodARPEntryIPv6)
+		}
+	}
+
 	if !s.disableTCPstack {
 		// VPP TCP stack config
 		config.Loopback = s.loopbackFromRequest(request, podIP.String())
@@ -998,16 +1559,44 @@ func (s *remoteCNIserver) configurePodVPPSide(request *cni.CNIRequest, podIP net
 	config.VppARPEntry = s.vppArpEntry(config.VppIf.Name, podIP, s.hwAddrForContainer())
 	txn.Arp(config.VppARPEntry)
 
+	// route + neighbor entry for POD IPv6 (TCP stack is not used for IPv6)
+	if podIPv6 != nil {
+		config.VppRouteIPv6 = s.vppRouteFromRequest(request, podIPv6.String()+"/128")
+		config.VppARPEntryIPv6 = s.vppArpEntry(config.VppIf.Name, podIPv6, s.hwAddrForContainer())
+		txn.StaticRoute(config.VppRouteIPv6).
+			Arp(config.VppARPEntryIPv6)
+	}
+
+	return txn
+}
+
+// unconfigurePodInterface unconfigures POD's network interface and its routes + ARPs together with
+// the vswitch VPP part of the POD networking.
+func (s *remoteCNIserver) unconfigurePodInterface(request *cni.CNIRequest, config *containeridx.Config) error {
+
+	// the flowprobe has to be disabled before the interface is deleted
+	if s.flowExportEnabled() && config.VppIf != nil {
+		if err := s.disableFlowProbe(config.VppIf.Name); err != nil {
+			s.Logger.Warnf("Failed to disable flowprobe on %s: %v", config.VppIf.Name, err)
+		}
+	}
+
+	// the policer as well
+	if err := s.unconfigurePodPolicer(config); err != nil {
+		s.Logger.Warnf("Failed to remove policer of %s: %v", config.VppIf.Name, err)
+	}
+
 	// execute the config transaction
-	err := txn.Send().ReceiveReply()
+	err := s.podConfigDeleteTxn(config).Send().ReceiveReply()
 	if err != nil {
 		s.Logger.Error(err)
 		return err
 	}
 
-	// if requested, disable TCP checksum offload on the eth0 veth/TAP interface in the container.
-	if s.tcpChecksumOffloadDisabled {
-		err = s.disableTCPChecksumOffload(request)
+	// delete the TAP interface from the host stack
+	if s.useTAPInterfaces {
+		err = s.unconfigureHostTAP(request)
+		// TODO: not stored in config, this will not be resynced in case of resync!!!
 		if err != nil {
 			s.Logger.Error(err)
 			return err
@@ -1017,38 +1606,76 @@ func (s *remoteCNIserver) configurePodVPPSide(request *cni.CNIRequest, podIP net
 	return nil
 }
 
-// unconfigurePodVPPSide deletes vswitch VPP part of the POD networking.
-func (s *remoteCNIserver) unconfigurePodVPPSide(config *containeridx.Config) error {
-
-	// prepare the config transaction
+// podConfigDeleteTxn prepares the transaction deleting the configuration created by the transaction
+// from podConfigTxn together with the default routes of the POD. Only the parts present in the config are deleted.
+func (s *remoteCNIserver) podConfigDeleteTxn(config *containeridx.Config) linux.DeleteDSL {
 	txn := s.vppTxnFactory().Delete()
 
-	if !s.disableTCPstack {
-		// VPP TCP stack config
-		txn.VppInterface(config.Loopback.Name).
-			AppNamespace(config.AppNamespace.NamespaceId).
-			StnRule(config.StnRule.RuleName)
-	} else {
-		// route to PodIP via AF_PACKET / TAP
+	// VPP TCP stack config or the route to PodIP via AF_PACKET / TAP
+	if config.Loopback != nil {
+		txn.VppInterface(config.Loopback.Name)
+	}
+	if config.AppNamespace != nil {
+		txn.AppNamespace(config.AppNamespace.NamespaceId)
+	}
+	if config.StnRule != nil {
+		txn.StnRule(config.StnRule.RuleName)
+	}
+	if config.VppRoute != nil {
 		txn.StaticRoute(config.VppRoute.VrfId, config.VppRoute.DstIpAddr, config.VppRoute.NextHopAddr)
 	}
 
 	// ARP entry for POD IP
-	txn.Arp(config.VppARPEntry.Interface, config.VppARPEntry.IpAddress)
+	if config.VppARPEntry != nil {
+		txn.Arp(config.VppARPEntry.Interface, config.VppARPEntry.IpAddress)
+	}
 
-	// execute the config transaction
-	err := txn.Send().ReceiveReply()
-	if err != nil {
-		s.Logger.Error(err)
-		return err
+	// route + neighbor entry for POD IPv6
+	if config.VppRouteIPv6 != nil {
+		txn.StaticRoute(config.VppRouteIPv6.VrfId, config.VppRouteIPv6.DstIpAddr, config.VppRouteIPv6.NextHopAddr)
+	}
+	if config.VppARPEntryIPv6 != nil {
+		txn.Arp(config.VppARPEntryIPv6.Interface, config.VppARPEntryIPv6.IpAddress)
 	}
 
-	return nil
+	// static routes and ARP entries in the POD
+	for _, route := range []*linux_l3.LinuxStaticRoutes_Route{config.PodLinkRoute, config.PodDefaultRoute,
+		config.PodLinkRouteIPv6, config.PodDefaultRouteIPv6} {
+		if route != nil {
+			txn.LinuxRoute(route.Name)
+		}
+	}
+	for _, arp := range []*linux_l3.LinuxStaticArpEntries_ArpEntry{config.PodARPEntry, config.PodARPEntryIPv6} {
+		if arp != nil {
+			txn.LinuxArpEntry(arp.Name)
+		}
+	}
+
+	// VPP to POD interconnect interface
+	if config.VppIf != nil {
+		txn.VppInterface(config.VppIf.Name)
+	}
+	if config.Veth1 != nil {
+		txn.LinuxInterface(config.Veth1.Name)
+	}
+	if config.Veth2 != nil {
+		txn.LinuxInterface(config.Veth2.Name)
+	}
+
+	return txn
 }
 
-// deletePersistedPodConfig persists POD configuration into ETCD.
-func (s *remoteCNIserver) persistPodConfig(config *containeridx.Config) error {
-	var err error
+// removePodConfig rolls back the configuration of the POD interface after a failure.
+// The transaction may have been applied only partially, the whole configuration is removed.
+func (s *remoteCNIserver) removePodConfig(config *containeridx.Config) {
+	err := s.podConfigDeleteTxn(config).Send().ReceiveReply()
+	if err != nil {
+		s.Logger.Warnf("Failed to roll back the configuration of the POD interface: %v", err)
+	}
+}
+
+// podConfigChanges returns the configuration of the POD to be persisted, keyed by the keys of the items.
+func (s *remoteCNIserver) podConfigChanges(config *containeridx.Config) map[string]proto.Message {
 	changes := map[string]proto.Message{}
 
 	// POD interface configuration
@@ -1061,6 +1688,11 @@ func (s *remoteCNIserver) persistPodConfig(config *containeridx.Config) error {
 		changes[linux_l3.StaticRouteKey(config.PodLinkRoute.Name)] = config.PodLinkRoute
 		changes[linux_l3.StaticRouteKey(config.PodDefaultRoute.Name)] = config.PodDefaultRoute
 		changes[linux_l3.StaticArpKey(config.PodARPEntry.Name)] = config.PodARPEntry
+		if config.PodIPv6 != "" {
+			changes[linux_l3.StaticRouteKey(config.PodLinkRouteIPv6.Name)] = config.PodLinkRouteIPv6
+			changes[linux_l3.StaticRouteKey(config.PodDefaultRouteIPv6.Name)] = config.PodDefaultRouteIPv6
+			changes[linux_l3.StaticArpKey(config.PodARPEntryIPv6.Name)] = config.PodARPEntryIPv6
+		}
 	}
 
 	// VPP-side configuration
@@ -1072,9 +1704,41 @@ func (s *remoteCNIserver) persistPodConfig(config *containeridx.Config) error {
 		changes[vpp_l3.RouteKey(config.VppRoute.VrfId, config.VppRoute.DstIpAddr, config.VppRoute.NextHopAddr)] = config.VppRoute
 	}
 	changes[vpp_l3.ArpEntryKey(config.VppARPEntry.Interface, config.VppARPEntry.IpAddress)] = config.VppARPEntry
+	if config.PodIPv6 != "" {
+		changes[vpp_l3.RouteKey(config.VppRouteIPv6.VrfId, config.VppRouteIPv6.DstIpAddr, config.VppRouteIPv6.NextHopAddr)] = config.VppRouteIPv6
+		changes[vpp_l3.ArpEntryKey(config.VppARPEntryIPv6.Interface, config.VppARPEntryIPv6.IpAddress)] = config.VppARPEntryIPv6
+	}
+
+	// secondary interfaces
+	for key, value := range s.secondaryIfChanges(config) {
+		changes[key] = value
+	}
+
+	// configuration applied via the pod network config API
+	for key, value := range s.extConfigChanges(config) {
+		changes[key] = value
+	}
+	return changes
+}
+
+// podConfigKeys returns the keys of the persisted configuration of the POD.
+func (s *remoteCNIserver) podConfigKeys(config *containeridx.Config) []string {
+	return s.changeKeys(s.podConfigChanges(config))
+}
+
+// persistPodConfig persists POD configuration into ETCD together with the record of the container.
+func (s *remoteCNIserver) persistPodConfig(request *cni.CNIRequest, config *containeridx.Config) error {
+	changes := s.podConfigChanges(config)
 
 	// persist the configuration
-	err = s.persistChanges(nil, changes)
+	err := s.persistChanges(nil, changes)
+	if err != nil {
+		s.Logger.Error(err)
+		return err
+	}
+
+	// persist the record of the container with the keys, so that the configuration can be removed after restart
+	err = s.storeContainer(request, config, s.changeKeys(changes))
 	if err != nil {
 		s.Logger.Error(err)
 		return err
@@ -1084,37 +1748,16 @@ func (s *remoteCNIserver) persistPodConfig(config *containeridx.Config) error {
 }
 
 // deletePersistedPodConfig deletes persisted POD configuration from ETCD.
-func (s *remoteCNIserver) deletePersistedPodConfig(config *containeridx.Config) error {
-	// collect keys to be removed from ETCD
-	var removedKeys []string
-
-	// POD interface configuration
-	removedKeys = append(removedKeys, vpp_intf.InterfaceKey(config.VppIf.Name))
-	if !s.useTAPInterfaces {
-		removedKeys = append(removedKeys,
-			linux_intf.InterfaceKey(config.Veth1.Name),
-			linux_intf.InterfaceKey(config.Veth2.Name),
-			//FIXME: the following items should be deleted once arp and routes can be configured with TAPs
-			linux_l3.StaticRouteKey(config.PodLinkRoute.Name),
-			linux_l3.StaticRouteKey(config.PodDefaultRoute.Name),
-			linux_l3.StaticArpKey(config.PodARPEntry.Name),
-		)
-	}
-
-	// VPP-side configuration
-	if !s.disableTCPstack {
-		removedKeys = append(removedKeys,
-			vpp_intf.InterfaceKey(config.Loopback.Name),
-			stn.Key(config.StnRule.RuleName),
-			vpp_l4.AppNamespacesKey(config.AppNamespace.NamespaceId))
-	} else {
-		removedKeys = append(removedKeys,
-			vpp_l3.RouteKey(config.VppRoute.VrfId, config.VppRoute.DstIpAddr, config.VppRoute.NextHopAddr))
+func (s *remoteCNIserver) deletePersistedPodConfig(request *cni.CNIRequest, config *containeridx.Config) error {
+	// remove persisted configuration from ETCD
+	err := s.persistChanges(s.podConfigKeys(config), nil)
+	if err != nil {
+		s.Logger.Error(err)
+		return err
 	}
-	removedKeys = append(removedKeys, vpp_l3.ArpEntryKey(config.VppARPEntry.Interface, config.VppARPEntry.IpAddress))
 
-	// remove persisted configuration from ETCD
-	err := s.persistChanges(removedKeys, nil)
+	// remove the record of the container
+	err = s.deleteContainer(request.ContainerId)
 	if err != nil {
 		s.Logger.Error(err)
 		return err
@@ -1138,13 +1781,16 @@ func (s *remoteCNIserver) parseCniExtraArgs(input string) map[string]string {
 }
 
 // generateCniReply fills the CNI reply with the data of an interface.
+// The IPv6 address and default route are included for dual-stack pods.
+// If veths are used, the host end of the veth pair is included as the second interface (without sandbox).
 func (s *remoteCNIserver) generateCniReply(config *containeridx.Config, nsName string, podIP string) *cni.CNIReply {
-	return &cni.CNIReply{
+	reply := &cni.CNIReply{
 		Result: resultOk,
 		Interfaces: []*cni.CNIReply_Interface{
 			{
 				Name:    config.VppIf.Name,
 				Sandbox: nsName,
+				Mtu:     s.podMTU,
 				IpAddresses: []*cni.CNIReply_Interface_IP{
 					{
 						Version: cni.CNIReply_Interface_IP_IPV4,
@@ -1161,6 +1807,26 @@ func (s *remoteCNIserver) generateCniReply(config *containeridx.Config, nsName s
 			},
 		},
 	}
+	if config.Veth2 != nil {
+		// the host end of the veth pair allows chained plugins (e.g. bandwidth) to configure the pod traffic
+		reply.Interfaces = append(reply.Interfaces, &cni.CNIReply_Interface{
+			Name: config.Veth2.HostIfName,
+			Mtu:  s.podMTU,
+		})
+	}
+	if config.PodIPv6 != "" {
+		iface := reply.Interfaces[0]
+		iface.IpAddresses = append(iface.IpAddresses, &cni.CNIReply_Interface_IP{
+			Version: cni.CNIReply_Interface_IP_IPV6,
+			Address: config.PodIPv6 + "/128",
+			Gateway: s.ipam.PodGatewayIPv6().String(),
+		})
+		reply.Routes = append(reply.Routes, &cni.CNIReply_Route{
+			Dst: "::/0",
+			Gw:  s.ipam.PodGatewayIPv6().String(),
+		})
+	}
+	return reply
 }
 
 // generateCniEmptyOKReply generates CNI reply with OK result code and ampty body.
@@ -1210,8 +1876,8 @@ func (s *remoteCNIserver) persistChanges(removedKeys []string, putChanges map[st
 
 // GetPhysicalIfNames returns a slice of names of all configured physical interfaces.
 func (s *remoteCNIserver) GetPhysicalIfNames() []string {
-	s.Lock()
-	defer s.Unlock()
+	s.RLock()
+	defer s.RUnlock()
 
 	return s.physicalIfs
 }
@@ -1219,8 +1885,8 @@ func (s *remoteCNIserver) GetPhysicalIfNames() []string {
 // GetVxlanBVIIfName returns the name of an BVI interface facing towards VXLAN tunnels to other hosts.
 // Returns an empty string if VXLAN is not used (in L2 interconnect mode).
 func (s *remoteCNIserver) GetVxlanBVIIfName() string {
-	s.Lock()
-	defer s.Unlock()
+	s.RLock()
+	defer s.RUnlock()
 
 	if s.useL2Interconnect {
 		return ""
@@ -1232,16 +1898,31 @@ func (s *remoteCNIserver) GetVxlanBVIIfName() string {
 // GetHostInterconnectIfName returns the name of the TAP/AF_PACKET interface
 // interconnecting VPP with the host stack.
 func (s *remoteCNIserver) GetHostInterconnectIfName() string {
-	s.Lock()
-	defer s.Unlock()
+	s.RLock()
+	defer s.RUnlock()
 
 	return s.hostInterconnectIfName
 }
 
+// execConfigCLI executes VPP CLI command configuring the features not supported by the vendored vpp-agent
+// (SRv6, IPsec). The configuration commands print nothing on success.
+func (s *remoteCNIserver) execConfigCLI(format string, args ...interface{}) error {
+	command := fmt.Sprintf(format, args...)
+	output, err := s.execVppCLI(command)
+	if err == nil && strings.TrimSpace(output) != "" {
+		err = errors.New(strings.TrimSpace(output))
+	}
+	if err != nil {
+		return fmt.Errorf("VPP CLI '%s' failed: %v", command, err)
+	}
+	s.Logger.Debugf("VPP CLI '%s' executed", command)
+	return nil
+}
+
 // GetNodeIP returns the IP address of this node.
 func (s *remoteCNIserver) GetNodeIP() net.IP {
-	s.Lock()
-	defer s.Unlock()
+	s.RLock()
+	defer s.RUnlock()
 
 	if s.nodeIP == "" {
 		return nil
@@ -16,10 +16,13 @@ package contiv
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"git.fd.io/govpp.git/adapter/mock"
 	govppmock "git.fd.io/govpp.git/adapter/mock"
@@ -28,9 +31,15 @@ import (
 	govpp "git.fd.io/govpp.git/core"
 
 	"github.com/contiv/vpp/mock/localclient"
+	"github.com/contiv/vpp/plugins/contiv/bin_api/flowprobe"
+	"github.com/contiv/vpp/plugins/contiv/bin_api/ipfix_export"
+	"github.com/contiv/vpp/plugins/contiv/bin_api/policer"
+	"github.com/contiv/vpp/plugins/contiv/bin_api/policer_classify"
+	"github.com/contiv/vpp/plugins/contiv/bin_api/vhost_user"
 	"github.com/contiv/vpp/plugins/contiv/containeridx"
 	"github.com/contiv/vpp/plugins/contiv/model/cni"
 	"github.com/contiv/vpp/plugins/contiv/model/node"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	"github.com/contiv/vpp/plugins/kvdbproxy"
 	"github.com/golang/protobuf/proto"
 
@@ -45,9 +54,12 @@ import (
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/vpe"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/vxlan"
 	vpp_intf "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	vpp_l2 "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
 	vpp_l3 "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/ifplugin/ifaceidx"
+	linux_l3 "github.com/ligato/vpp-agent/plugins/linuxplugin/l3plugin/model/l3"
 
+	"github.com/contiv/vpp/plugins/contiv/bin_api/classify"
 	"github.com/contiv/vpp/plugins/contiv/bin_api/dhcp"
 	"github.com/contiv/vpp/plugins/contiv/ipam"
 	"github.com/ligato/cn-infra/datasync"
@@ -94,6 +106,25 @@ var (
 			VxlanCIDR:               "192.168.30.0/24",
 		},
 	}
+	configTapVxlanIPv6 = Config{
+		UseTAPInterfaces:    true,
+		TAPInterfaceVersion: 2,
+		IPAMConfig: ipam.Config{
+			PodSubnetCIDR:           "10.1.0.0/16",
+			PodNetworkPrefixLen:     24,
+			VPPHostSubnetCIDR:       "172.30.0.0/16",
+			VPPHostNetworkPrefixLen: 24,
+			NodeInterconnectCIDR:    "192.168.16.0/24",
+			VxlanCIDR:               "192.168.30.0/24",
+			IPv6: &ipam.IPv6Config{
+				PodSubnetCIDR:           "fd00:1::/48",
+				PodNetworkPrefixLen:     64,
+				VPPHostSubnetCIDR:       "fd00:2::/48",
+				VPPHostNetworkPrefixLen: 64,
+				VxlanCIDR:               "fd00:3::/120",
+			},
+		},
+	}
 	nodeConfig = OneNodeConfig{
 		NodeName: "test-node",
 		MainVppInterface: InterfaceWithIP{
@@ -162,7 +193,7 @@ func TestAddDelVeth(t *testing.T) {
 	defer conn.Disconnect()
 
 	// pretend that connectivity is configured to unblock CNI requests
-	server.vswitchConnectivityConfigured = true
+	server.setVswitchConfigured()
 
 	// CNI Add
 	reply, err := server.Add(context.Background(), &req)
@@ -171,7 +202,7 @@ func TestAddDelVeth(t *testing.T) {
 	gomega.Expect(reply).NotTo(gomega.BeNil())
 
 	gomega.Expect(len(txns.PendingTxns)).To(gomega.BeEquivalentTo(0))
-	gomega.Expect(len(txns.CommittedTxns)).To(gomega.BeEquivalentTo(3))
+	gomega.Expect(len(txns.CommittedTxns)).To(gomega.BeEquivalentTo(2))
 	// TODO add asserts for txns(one linux plugin txn and one default plugins txn) / currently applied config
 
 	res := configuredContainers.LookupPodName(podName)
@@ -186,6 +217,40 @@ func TestAddDelVeth(t *testing.T) {
 	gomega.Expect(reply).NotTo(gomega.BeNil())
 }
 
+func TestAddPodRollback(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, configuredContainers, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	// the default route of the pod cannot be configured
+	swIfIdx := swIfIndexMock()
+	txns := localclient.NewTxnTracker(func(txn *localclient.Txn) error {
+		if txn.LinuxDataChangeTxn != nil {
+			for _, op := range txn.LinuxDataChangeTxn.Ops {
+				if route, isRoute := op.Value.(*linux_l3.LinuxStaticRoutes_Route); isRoute && route.Default {
+					return errors.New("default route cannot be added")
+				}
+			}
+		}
+		return addIfsIntoTheIndex(swIfIdx)(txn)
+	})
+	server.vppTxnFactory = txns.NewLinuxDataChangeTxn
+	server.swIfIndex = swIfIdx
+
+	// CNI Add fails
+	reply, _ := server.Add(context.Background(), &req)
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultErr))
+
+	// the interface, routes and ARP entries applied before the failure were removed
+	gomega.Expect(txns.AppliedConfig).To(gomega.BeEmpty())
+	_, found := configuredContainers.LookupContainer(containerID)
+	gomega.Expect(found).To(gomega.BeFalse())
+}
+
 func TestConfigureVswitchDHCP(t *testing.T) {
 	gomega.RegisterTestingT(t)
 
@@ -208,6 +273,460 @@ func TestConfigureVswitchDHCP(t *testing.T) {
 	gomega.Expect(len(txns.CommittedTxns)).To(gomega.BeEquivalentTo(5))
 }
 
+func TestDHCPLeaseChange(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, txns, _, conn := setupTestCNIServer(&configTapVxlanTCP, &nodeDHCPConfig, nodeDHCPConfig.MainVppInterface.InterfaceName)
+	defer conn.Disconnect()
+	nodeIPs := make(chan string, 1)
+	server.WatchNodeIP(nodeIPs)
+	applyDHCPLease := func(ipAddr string) error {
+		return server.loop.process("DHCP lease", vswitchPriority, func() error { return server.applyDHCPLease(ipAddr) })
+	}
+
+	err := server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.vswitchConnectivityConfigured).To(gomega.BeFalse())
+
+	// the first lease completes the vswitch connectivity
+	err = applyDHCPLease("192.168.1.5/24")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.vswitchConnectivityConfigured).To(gomega.BeTrue())
+	gomega.Expect(<-nodeIPs).To(gomega.Equal("192.168.1.5/24"))
+
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
+	gomega.Expect(err).To(gomega.BeNil())
+	vxlanName := fmt.Sprintf("vxlan%d", otherNodeInfo.Id)
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, vxlanName).Vxlan.SrcAddress).To(gomega.Equal("192.168.1.5"))
+
+	// renewal of the lease changes nothing
+	err = applyDHCPLease("192.168.1.5/24")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(nodeIPs).NotTo(gomega.Receive())
+
+	// the tunnels are re-created with the new address, which is published
+	err = applyDHCPLease("192.168.1.6/24")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.GetNodeIP().String()).To(gomega.Equal("192.168.1.6"))
+	gomega.Expect(<-nodeIPs).To(gomega.Equal("192.168.1.6/24"))
+	gomega.Expect(server.routedNodes).To(gomega.HaveKey(otherNodeInfo.Id))
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, vxlanName).Vxlan.SrcAddress).To(gomega.Equal("192.168.1.6"))
+}
+
+func TestAddDelVethDualStack(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := configVethL2NoTCP
+	config.IPAMConfig.IPv6 = configTapVxlanIPv6.IPAMConfig.IPv6
+	server, _, configuredContainers, conn := setupTestCNIServer(&config, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	// CNI Add
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply).NotTo(gomega.BeNil())
+
+	// both address families are returned in the reply
+	gomega.Expect(reply.Interfaces).To(gomega.HaveLen(2))
+	gomega.Expect(reply.Interfaces[1].Sandbox).To(gomega.BeEmpty())
+	ips := reply.Interfaces[0].IpAddresses
+	gomega.Expect(ips).To(gomega.HaveLen(2))
+	gomega.Expect(ips[0].Version).To(gomega.BeEquivalentTo(cni.CNIReply_Interface_IP_IPV4))
+	gomega.Expect(ips[1].Version).To(gomega.BeEquivalentTo(cni.CNIReply_Interface_IP_IPV6))
+	gomega.Expect(ips[1].Address).To(gomega.BeEquivalentTo("fd00:1:0:1::2/128"))
+	gomega.Expect(ips[1].Gateway).To(gomega.BeEquivalentTo("fd00:1:0:1::1"))
+	gomega.Expect(reply.Routes).To(gomega.HaveLen(2))
+	gomega.Expect(reply.Routes[1].Dst).To(gomega.BeEquivalentTo("::/0"))
+
+	// IPv6 routes are configured in VPP and in the pod
+	podConfig, found := configuredContainers.LookupContainer(containerID)
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(podConfig.Veth1.IpAddresses).To(gomega.ContainElement("fd00:1:0:1::2/128"))
+	gomega.Expect(podConfig.VppRouteIPv6.DstIpAddr).To(gomega.BeEquivalentTo("fd00:1:0:1::2/128"))
+	gomega.Expect(podConfig.VppARPEntryIPv6.IpAddress).To(gomega.BeEquivalentTo("fd00:1:0:1::2"))
+	gomega.Expect(podConfig.PodDefaultRouteIPv6.GwAddr).To(gomega.BeEquivalentTo("fd00:1:0:1::1"))
+
+	// CNI Delete
+	reply, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply).NotTo(gomega.BeNil())
+}
+
+func TestCheckPod(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, _, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	// CNI Check of a pod that was not added
+	_, err := server.Check(context.Background(), &req)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+
+	// CNI Add
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply).NotTo(gomega.BeNil())
+
+	// CNI Check succeeds while the configuration is in place
+	reply, err = server.Check(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+
+	// CNI Check fails once the VPP interface of the pod disappears
+	config, found := server.configuredContainers.LookupContainer(containerID)
+	gomega.Expect(found).To(gomega.BeTrue())
+	server.swIfIndex.(ifaceidx.SwIfIndexRW).UnregisterName(config.VppIf.Name)
+	reply, err = server.Check(context.Background(), &req)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultErr))
+
+	// CNI Delete
+	reply, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply).NotTo(gomega.BeNil())
+}
+
+func TestAddPodWithRequestedIP(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, _, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+	server.getPodAnnotations = func(podNamespace, podName string) (map[string]string, error) {
+		return map[string]string{podIPAddressAnnotation: "10.1.1.50"}, nil
+	}
+	var events []string
+	server.reportK8sEvent = func(podNamespace, podName, reason, message string) {
+		events = append(events, podNamespace+"/"+podName+" "+reason)
+	}
+
+	// CNI Add assigns the requested IP address
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Interfaces[0].IpAddresses[0].Address).To(gomega.BeEquivalentTo("10.1.1.50/32"))
+
+	// another pod requesting the same IP address fails
+	otherReq := req
+	otherReq.ContainerId = "otherContainer"
+	otherReq.NetworkNamespace = "/var/run/other"
+	_, err = server.Add(context.Background(), &otherReq)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	gomega.Expect(err.Error()).To(gomega.ContainSubstring("10.1.1.50"))
+
+	// the failure is reported as an event of the pod
+	gomega.Expect(events).To(gomega.Equal([]string{"default/" + podName + " " + reasonNetworkSetupFailed}))
+
+	// CNI Delete
+	reply, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply).NotTo(gomega.BeNil())
+}
+
+func TestAddPodWithRequestedIPNotReflected(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, _, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+	server.podReflectionTimeout = time.Second
+	reflected := make(chan struct{})
+	server.getPodAnnotations = func(podNamespace, podName string) (map[string]string, error) {
+		select {
+		case <-reflected:
+			return map[string]string{podIPAddressAnnotation: "10.1.1.50"}, nil
+		default:
+			return nil, nil
+		}
+	}
+
+	// CNI Add fails if the pod is not reflected
+	_, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+
+	// CNI Add waits for the pod to be reflected and assigns the requested IP address
+	time.AfterFunc(200*time.Millisecond, func() { close(reflected) })
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Interfaces[0].IpAddresses[0].Address).To(gomega.BeEquivalentTo("10.1.1.50/32"))
+}
+
+func TestAddPodInNamespacePool(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := configVethL2NoTCP
+	config.IPAMConfig.NamespacePools = []ipam.NamespacePoolConfig{
+		{Name: "dmz", Namespaces: []string{"default"}, SubnetCIDR: "10.10.0.0/16", NetworkPrefixLen: 24},
+	}
+	server, _, _, conn := setupTestCNIServer(&config, nil)
+	defer conn.Disconnect()
+
+	// the host is able to reach the pool networks via VPP
+	gomega.Expect(server.routesFromHostToPools()).To(gomega.HaveLen(1))
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	// CNI Add assigns the IP address from the pool bound to the namespace of the pod
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Interfaces[0].IpAddresses[0].Address).To(gomega.BeEquivalentTo("10.10.1.2/32"))
+
+	// CNI Delete
+	reply, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply).NotTo(gomega.BeNil())
+}
+
+func TestAddPodWithSecondaryIfs(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := configVethL2NoTCP
+	config.IPAMConfig.NamespacePools = []ipam.NamespacePoolConfig{
+		{Name: "dataplane", SubnetCIDR: "10.20.0.0/16", NetworkPrefixLen: 24},
+		{Name: "control", SubnetCIDR: "10.30.0.0/16", NetworkPrefixLen: 24},
+	}
+	config.SecondaryNetworks = []SecondaryNetwork{
+		{Name: "dataplane", IPPool: "dataplane"},
+		{Name: "control", InterfaceType: secondaryIfTypeTap, IPPool: "control"},
+	}
+	server, _, configuredContainers, conn := setupTestCNIServer(&config, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+	server.getPodAnnotations = func(podNamespace, podName string) (map[string]string, error) {
+		return map[string]string{podNetworksAnnotation: "dataplane, default/control@ctl0"}, nil
+	}
+
+	// CNI Add attaches the pod to the secondary networks
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Interfaces).To(gomega.HaveLen(4))
+	gomega.Expect(reply.Interfaces[2].Name).To(gomega.BeEquivalentTo("net1"))
+	gomega.Expect(reply.Interfaces[2].IpAddresses[0].Address).To(gomega.BeEquivalentTo("10.20.1.2/32"))
+	gomega.Expect(reply.Interfaces[2].IpAddresses[0].Gateway).To(gomega.BeEquivalentTo("10.20.1.1"))
+	gomega.Expect(reply.Interfaces[3].Name).To(gomega.BeEquivalentTo("ctl0"))
+	gomega.Expect(reply.Interfaces[3].IpAddresses[0].Address).To(gomega.BeEquivalentTo("10.30.1.2/32"))
+
+	podConfig, found := configuredContainers.LookupContainer(containerID)
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(podConfig.SecondaryIfs).To(gomega.HaveLen(2))
+	gomega.Expect(podConfig.SecondaryIfs[0].Veth1.IpAddresses).To(gomega.ConsistOf("10.20.1.2/32"))
+	gomega.Expect(podConfig.SecondaryIfs[0].PodRoute.DstIpAddr).To(gomega.BeEquivalentTo("10.20.0.0/16"))
+	gomega.Expect(podConfig.SecondaryIfs[1].Veth1).To(gomega.BeNil())
+	gomega.Expect(podConfig.SecondaryIfs[1].VppIf.Type).To(gomega.BeEquivalentTo(vpp_intf.InterfaceType_TAP_INTERFACE))
+	gomega.Expect(configuredContainers.LookupPodIf(podConfig.SecondaryIfs[1].VppIf.Name)).To(gomega.ConsistOf(containerID))
+
+	// CNI Check verifies the secondary interfaces as well
+	_, err = server.Check(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// CNI Delete
+	reply, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply).NotTo(gomega.BeNil())
+
+	// the pod requesting an undefined network is refused
+	server.getPodAnnotations = func(podNamespace, podName string) (map[string]string, error) {
+		return map[string]string{podNetworksAnnotation: "unknown"}, nil
+	}
+	_, err = server.Add(context.Background(), &req)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestAddPodWithMemif(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := configVethL2NoTCP
+	config.IPAMConfig.NamespacePools = []ipam.NamespacePoolConfig{
+		{Name: "dpdk", SubnetCIDR: "10.40.0.0/16", NetworkPrefixLen: 24},
+	}
+	config.SecondaryNetworks = []SecondaryNetwork{
+		{Name: "dpdk", InterfaceType: secondaryIfTypeMemif, IPPool: "dpdk", MemifRingSize: 512},
+	}
+	server, _, configuredContainers, conn := setupTestCNIServer(&config, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+	server.getPodAnnotations = func(podNamespace, podName string) (map[string]string, error) {
+		return map[string]string{podNetworksAnnotation: "dpdk@dpdk0"}, nil
+	}
+	published := map[string]string{}
+	server.publishPodAnnotations = func(podNamespace, podName string, annotations map[string]string) error {
+		published = annotations
+		return nil
+	}
+
+	// CNI Add creates the memif on VPP and publishes its parameters in the pod annotation
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Interfaces).To(gomega.HaveLen(2))
+
+	podConfig, found := configuredContainers.LookupContainer(containerID)
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(podConfig.SecondaryIfs).To(gomega.HaveLen(1))
+	memif := podConfig.SecondaryIfs[0].VppIf
+	gomega.Expect(memif.Type).To(gomega.BeEquivalentTo(vpp_intf.InterfaceType_MEMORY_INTERFACE))
+	gomega.Expect(memif.Memif.Master).To(gomega.BeTrue())
+	gomega.Expect(memif.Memif.SocketFilename).To(gomega.BeEquivalentTo(defaultMemifSocketDir + "/dpdk0-" + containerID + ".sock"))
+	gomega.Expect(memif.Memif.RingSize).To(gomega.BeEquivalentTo(512))
+	gomega.Expect(memif.Memif.BufferSize).To(gomega.BeEquivalentTo(defaultMemifBufferSize))
+
+	infos := map[string]*memifInfo{}
+	gomega.Expect(json.Unmarshal([]byte(published[podMemifAnnotation]), &infos)).To(gomega.Succeed())
+	gomega.Expect(infos).To(gomega.HaveKey("dpdk0"))
+	gomega.Expect(infos["dpdk0"].Socket).To(gomega.BeEquivalentTo(memif.Memif.SocketFilename))
+	gomega.Expect(infos["dpdk0"].IPAddress).To(gomega.BeEquivalentTo("10.40.1.2/32"))
+	gomega.Expect(infos["dpdk0"].Gateway).To(gomega.BeEquivalentTo("10.40.1.1"))
+	gomega.Expect(infos["dpdk0"].GatewayMacAddress).To(gomega.BeEquivalentTo(memif.PhysAddress))
+
+	// CNI Delete withdraws the annotation
+	_, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(published).To(gomega.BeEmpty())
+}
+
+func TestAddPodWithVhostUser(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := configVethL2NoTCP
+	config.IPAMConfig.NamespacePools = []ipam.NamespacePoolConfig{
+		{Name: "vm", SubnetCIDR: "10.50.0.0/16", NetworkPrefixLen: 24},
+	}
+	config.SecondaryNetworks = []SecondaryNetwork{
+		{Name: "vm", InterfaceType: secondaryIfTypeVhostUser, IPPool: "vm"},
+	}
+	server, _, configuredContainers, conn := setupTestCNIServer(&config, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+	server.getPodAnnotations = func(podNamespace, podName string) (map[string]string, error) {
+		return map[string]string{podNetworksAnnotation: "vm"}, nil
+	}
+	published := map[string]string{}
+	server.publishPodAnnotations = func(podNamespace, podName string, annotations map[string]string) error {
+		published = annotations
+		return nil
+	}
+
+	// CNI Add creates the vhost-user interface on VPP and publishes its socket in the pod annotation
+	reply, err := server.Add(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Interfaces).To(gomega.HaveLen(2))
+
+	podConfig, found := configuredContainers.LookupContainer(containerID)
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(podConfig.SecondaryIfs).To(gomega.HaveLen(1))
+	vhostUser := podConfig.SecondaryIfs[0]
+	gomega.Expect(vhostUser.VppIf).To(gomega.BeNil())
+	gomega.Expect(vhostUser.VhostUserSocket).To(gomega.BeEquivalentTo(defaultVhostUserSocketDir + "/net1-" + containerID + ".sock"))
+	gomega.Expect(vhostUser.VhostUserSwIfIndex).NotTo(gomega.BeZero())
+
+	infos := map[string]*vhostUserInfo{}
+	gomega.Expect(json.Unmarshal([]byte(published[podVhostUserAnnotation]), &infos)).To(gomega.Succeed())
+	gomega.Expect(infos).To(gomega.HaveKey("net1"))
+	gomega.Expect(infos["net1"].Socket).To(gomega.BeEquivalentTo(vhostUser.VhostUserSocket))
+	gomega.Expect(infos["net1"].IPAddress).To(gomega.BeEquivalentTo("10.50.1.2/32"))
+	gomega.Expect(infos["net1"].GatewayMacAddress).To(gomega.BeEquivalentTo(vhostUser.VhostUserPhysAddress))
+
+	// CNI Check
+	_, err = server.Check(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// CNI Delete removes the interface and withdraws the annotation
+	_, err = server.Delete(context.Background(), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(published).To(gomega.BeEmpty())
+	_, found = configuredContainers.LookupContainer(containerID)
+	gomega.Expect(found).To(gomega.BeFalse())
+}
+
+func TestInvalidMemifNetwork(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := configVethL2NoTCP
+	config.IPAMConfig.NamespacePools = []ipam.NamespacePoolConfig{
+		{Name: "dpdk", SubnetCIDR: "10.40.0.0/16", NetworkPrefixLen: 24},
+	}
+	server, _, _, conn := setupTestCNIServer(&config, nil)
+	defer conn.Disconnect()
+
+	// memif ring size must be a power of 2
+	err := server.validateSecondaryNetworks([]SecondaryNetwork{
+		{Name: "dpdk", InterfaceType: secondaryIfTypeMemif, IPPool: "dpdk", MemifRingSize: 1000},
+	})
+	gomega.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestParseNetworksAnnotation(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	selections, err := parseNetworksAnnotation("net-a, ns1/net-b@data0")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(selections).To(gomega.Equal([]networkSelection{
+		{Name: "net-a", Interface: "net1"},
+		{Name: "net-b", Interface: "data0"},
+	}))
+
+	selections, err = parseNetworksAnnotation(`[{"name": "net-a"}, {"name": "net-b", "interface": "data0"}]`)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(selections).To(gomega.Equal([]networkSelection{
+		{Name: "net-a", Interface: "net1"},
+		{Name: "net-b", Interface: "data0"},
+	}))
+
+	_, err = parseNetworksAnnotation(`[{"interface": "data0"}]`)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+	_, err = parseNetworksAnnotation("net-a@veryLongInterfaceName")
+	gomega.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestAddPodWithExternalIPAM(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, _, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	// CNI Add uses the IP address assigned by the external IPAM
+	ipamReq := req
+	ipamReq.ExternalIpamAddresses = []*cni.CNIReply_Interface_IP{
+		{Version: cni.CNIReply_Interface_IP_IPV4, Address: "10.1.1.60/24", Gateway: "10.1.1.1"},
+	}
+	reply, err := server.Add(context.Background(), &ipamReq)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Interfaces[0].IpAddresses[0].Address).To(gomega.BeEquivalentTo("10.1.1.60/32"))
+
+	// address outside of the pod network of the node is refused
+	otherReq := ipamReq
+	otherReq.ContainerId = "otherContainer"
+	otherReq.NetworkNamespace = "/var/run/other"
+	otherReq.ExternalIpamAddresses = []*cni.CNIReply_Interface_IP{
+		{Version: cni.CNIReply_Interface_IP_IPV4, Address: "10.5.5.5/24"},
+	}
+	_, err = server.Add(context.Background(), &otherReq)
+	gomega.Expect(err).NotTo(gomega.BeNil())
+
+	// CNI Delete
+	reply, err = server.Delete(context.Background(), &ipamReq)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply).NotTo(gomega.BeNil())
+}
+
 func TestAddDelTap(t *testing.T) {
 	gomega.RegisterTestingT(t)
 
@@ -215,7 +734,7 @@ func TestAddDelTap(t *testing.T) {
 	defer conn.Disconnect()
 
 	// pretend that connectivity is configured to unblock CNI requests
-	server.vswitchConnectivityConfigured = true
+	server.setVswitchConfigured()
 
 	// CNI Add
 	reply, err := server.Add(context.Background(), &req)
@@ -224,7 +743,7 @@ func TestAddDelTap(t *testing.T) {
 	gomega.Expect(reply).NotTo(gomega.BeNil())
 
 	gomega.Expect(len(txns.PendingTxns)).To(gomega.BeEquivalentTo(0))
-	gomega.Expect(len(txns.CommittedTxns)).To(gomega.BeEquivalentTo(2))
+	gomega.Expect(len(txns.CommittedTxns)).To(gomega.BeEquivalentTo(1))
 	// TODO add asserts for txns(one linux plugin txn and one default plugins txn) / currently applied config
 
 	res := configuredContainers.LookupPodName(podName)
@@ -292,6 +811,37 @@ func TestConfigureVswitchTap(t *testing.T) {
 	gomega.Expect(len(txns.CommittedTxns)).To(gomega.BeEquivalentTo(5))
 }
 
+func TestConfigureVswitchIPv6(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, txns, _, conn := setupTestCNIServer(&configTapVxlanIPv6, nil)
+	defer conn.Disconnect()
+
+	// exec resync to configure vswitch
+	err := server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// both address families are configured on the VPP end of the host interconnect and on the VXLAN BVI
+	tap := interfaceInSnapshot(txns.AppliedConfig, server.GetHostInterconnectIfName())
+	gomega.Expect(tap).ToNot(gomega.BeNil())
+	gomega.Expect(tap.IpAddresses).To(gomega.ConsistOf("172.30.1.1/24", "fd00:2:0:1::1/64"))
+
+	bvi := interfaceInSnapshot(txns.AppliedConfig, server.GetVxlanBVIIfName())
+	gomega.Expect(bvi).ToNot(gomega.BeNil())
+	gomega.Expect(bvi.IpAddresses).To(gomega.ConsistOf("192.168.30.1/24", "fd00:3::1/120"))
+
+	route := server.routeFromHostIPv6()
+	gomega.Expect(route.DstIpAddr).To(gomega.BeEquivalentTo("fd00:1::/48"))
+	gomega.Expect(route.GwAddr).To(gomega.BeEquivalentTo("fd00:2:0:1::1"))
+
+	// IPv6 pods of other nodes are routed via their VXLAN BVI
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
+	gomega.Expect(err).To(gomega.BeNil())
+	routes := routesViaInSnapshot(txns.AppliedConfig, "fd00:3::5")
+	gomega.Expect(routes).To(gomega.HaveLen(1))
+	gomega.Expect(routes[0].DstIpAddr).To(gomega.BeEquivalentTo("fd00:1:0:5::/64"))
+}
+
 func TestNodeAddDelL2(t *testing.T) {
 	gomega.RegisterTestingT(t)
 
@@ -302,7 +852,7 @@ func TestNodeAddDelL2(t *testing.T) {
 	err := server.resync()
 	gomega.Expect(err).To(gomega.BeNil())
 
-	err = server.nodeChangePropageteEvent(&nodeAddDelEvent{evType: datasync.Put})
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
 	gomega.Expect(err).To(gomega.BeNil())
 
 	// check that the VXLAN interface does not exist
@@ -314,7 +864,7 @@ func TestNodeAddDelL2(t *testing.T) {
 	routes := routesViaInSnapshot(txns.AppliedConfig, nexthopIP)
 	gomega.Expect(len(routes)).To(gomega.BeEquivalentTo(2))
 
-	err = server.nodeChangePropageteEvent(&nodeAddDelEvent{evType: datasync.Delete})
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Delete})
 	gomega.Expect(err).To(gomega.BeNil())
 }
 
@@ -328,7 +878,7 @@ func TestNodeAddDelVXLAN(t *testing.T) {
 	err := server.resync()
 	gomega.Expect(err).To(gomega.BeNil())
 
-	err = server.nodeChangePropageteEvent(&nodeAddDelEvent{evType: datasync.Put})
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
 	gomega.Expect(err).To(gomega.BeNil())
 
 	// check that the VXLAN tunnel config has been properly added
@@ -341,8 +891,87 @@ func TestNodeAddDelVXLAN(t *testing.T) {
 	routes := routesViaInSnapshot(txns.AppliedConfig, nexthopIP.String())
 	gomega.Expect(len(routes)).To(gomega.BeEquivalentTo(2))
 
-	err = server.nodeChangePropageteEvent(&nodeAddDelEvent{evType: datasync.Delete})
+	// check that the next hop is resolved statically
+	arpKey := vpp_l3.ArpEntryKey(server.GetVxlanBVIIfName(), nexthopIP.String())
+	gomega.Expect(txns.AppliedConfig).To(gomega.HaveKey(arpKey))
+	arp := txns.AppliedConfig[arpKey].(*vpp_l3.ArpTable_ArpTableEntry)
+	gomega.Expect(arp.PhysAddress).To(gomega.Equal(hwAddrForNodeVXLAN(uint8(otherNodeInfo.Id))))
+	fibKey := vpp_l2.FibKey(server.vxlanBD.Name, arp.PhysAddress)
+	gomega.Expect(txns.AppliedConfig).To(gomega.HaveKey(fibKey))
+	fib := txns.AppliedConfig[fibKey].(*vpp_l2.FibTableEntries_FibTableEntry)
+	gomega.Expect(fib.OutgoingInterface).To(gomega.Equal(vxlanIf.Name))
+
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Delete})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(txns.AppliedConfig).ToNot(gomega.HaveKey(arpKey))
+	gomega.Expect(txns.AppliedConfig).ToNot(gomega.HaveKey(fibKey))
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, nexthopIP.String())).To(gomega.BeEmpty())
+
+	// the VXLAN tunnel is removed from the bridge domain and deleted
+	gomega.Expect(interfaceInSnapshot(txns.AppliedConfig, vxlanIf.Name)).To(gomega.BeNil())
+	bd := txns.AppliedConfig[vpp_l2.BridgeDomainKey(server.vxlanBD.Name)].(*vpp_l2.BridgeDomains_BridgeDomain)
+	for _, bdIf := range bd.Interfaces {
+		gomega.Expect(bdIf.Name).ToNot(gomega.Equal(vxlanIf.Name))
+	}
+}
+
+func TestNodeDiscoveryByK8sNodes(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, txns, _, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	// exec resync to configure vswitch
+	err := server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+	nexthopIP := server.ipPrefixToAddress(otherNodeInfo.IpAddress)
+
+	// the node with the allocated ID is not routed while it is not in the k8s cluster
+	err = processNodeChange(server, &k8sNodeEvent{evType: datasync.Put, name: "node1"})
+	gomega.Expect(err).To(gomega.BeNil())
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, nexthopIP)).To(gomega.BeEmpty())
+
+	// the node joins the k8s cluster
+	err = processNodeChange(server, &k8sNodeEvent{evType: datasync.Put, name: otherNodeInfo.Name})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, nexthopIP)).To(gomega.HaveLen(2))
+
+	// refresh of the node ID lease does not reconfigure the routes
+	committed := len(txns.CommittedTxns)
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Put})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(txns.CommittedTxns).To(gomega.HaveLen(committed))
+
+	// the node is removed from the k8s cluster before its ID is released
+	var deleted []string
+	server.k8sNodeDeleted = func(name string) { deleted = append(deleted, name) }
+	err = processNodeChange(server, &k8sNodeEvent{evType: datasync.Delete, name: otherNodeInfo.Name})
 	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(routesViaInSnapshot(txns.AppliedConfig, nexthopIP)).To(gomega.BeEmpty())
+	gomega.Expect(deleted).To(gomega.Equal([]string{otherNodeInfo.Name}))
+
+	err = processNodeChange(server, &nodeAddDelEvent{evType: datasync.Delete})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.otherNodes).To(gomega.BeEmpty())
+}
+
+func TestRoutesToNodeWithAssignedPodNetwork(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, _, conn := setupTestCNIServer(&configTapVxlanTCP, nil)
+	defer conn.Disconnect()
+
+	podsRoute, _, err := server.computeRoutesToHost(5, "10.1.100.0/24", "192.168.30.5")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(podsRoute.DstIpAddr).To(gomega.BeEquivalentTo("10.1.100.0/24"))
+	gomega.Expect(podsRoute.NextHopAddr).To(gomega.BeEquivalentTo("192.168.30.5"))
+
+	// computed from the ID if not assigned
+	podsRoute, _, err = server.computeRoutesToHost(5, "", "192.168.30.5")
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(podsRoute.DstIpAddr).To(gomega.BeEquivalentTo("10.1.5.0/24"))
 }
 
 func TestVeth1NameFromRequest(t *testing.T) {
@@ -376,6 +1005,12 @@ func vppChanMock() (*api.Channel, *govpp.Connection) {
 	vppMock.RegisterBinAPITypes(vxlan.Types)
 	vppMock.RegisterBinAPITypes(ip.Types)
 	vppMock.RegisterBinAPITypes(dhcp.Types)
+	vppMock.RegisterBinAPITypes(vhost_user.Types)
+	vppMock.RegisterBinAPITypes(flowprobe.Types)
+	vppMock.RegisterBinAPITypes(ipfix_export.Types)
+	vppMock.RegisterBinAPITypes(policer.Types)
+	vppMock.RegisterBinAPITypes(policer_classify.Types)
+	vppMock.RegisterBinAPITypes(classify.Types)
 
 	vppMock.MockReplyHandler(func(request govppmock.MessageDTO) (reply []byte, msgID uint16, prepared bool) {
 		reqName, found := vppMock.GetMsgNameByID(request.MsgID)
@@ -500,6 +1135,29 @@ func routesViaInSnapshot(snapshot localclient.ConfigSnapshot, nexthopIP string)
 	return routes
 }
 
+// processNodeChange queues the change of a node into the event loop as handleNodeEvents does
+// and waits until the change is applied.
+func processNodeChange(server *remoteCNIserver, changeEv datasync.ChangeEvent) error {
+	result := make(chan error, 1)
+	server.queueNodeChange(&resultEvent{ChangeEvent: changeEv, result: result})
+	select {
+	case err := <-result:
+		return err
+	case <-server.loop.stopped:
+		return errEventLoopStopped
+	}
+}
+
+// resultEvent reports the result of the wrapped change event into the channel
+type resultEvent struct {
+	datasync.ChangeEvent
+	result chan error
+}
+
+func (e *resultEvent) Done(err error) {
+	e.result <- err
+}
+
 // nodeAddDelEvent simulates addition of a k8s node into a cluster
 type nodeAddDelEvent struct {
 	evType datasync.PutDel
@@ -512,7 +1170,7 @@ func (e nodeAddDelEvent) GetChangeType() datasync.PutDel {
 }
 
 func (e nodeAddDelEvent) GetKey() string {
-	return allocatedIDsKeyPrefix
+	return createKey(otherNodeInfo.Id)
 }
 
 func (e nodeAddDelEvent) GetValue(value proto.Message) error {
@@ -530,3 +1188,32 @@ func (e nodeAddDelEvent) GetPrevValue(prevValue proto.Message) (prevValueExist b
 func (e nodeAddDelEvent) GetRevision() int64 {
 	return 0
 }
+
+// k8sNodeEvent simulates change of a k8s node reflected by KSR
+type k8sNodeEvent struct {
+	evType datasync.PutDel
+	name   string
+}
+
+func (e *k8sNodeEvent) Done(error) {}
+
+func (e k8sNodeEvent) GetChangeType() datasync.PutDel {
+	return e.evType
+}
+
+func (e k8sNodeEvent) GetKey() string {
+	return nodemodel.Key(e.name)
+}
+
+func (e k8sNodeEvent) GetValue(value proto.Message) error {
+	value.(*nodemodel.Node).Name = e.name
+	return nil
+}
+
+func (e k8sNodeEvent) GetPrevValue(prevValue proto.Message) (prevValueExist bool, err error) {
+	return false, nil
+}
+
+func (e k8sNodeEvent) GetRevision() int64 {
+	return 0
+}
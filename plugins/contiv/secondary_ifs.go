@@ -0,0 +1,576 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/contiv/vpp/plugins/contiv/containeridx"
+	"github.com/contiv/vpp/plugins/contiv/model/cni"
+	"github.com/golang/protobuf/proto"
+	vpp_intf "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	vpp_l3 "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
+	linux_intf "github.com/ligato/vpp-agent/plugins/linuxplugin/ifplugin/model/interfaces"
+	linux_l3 "github.com/ligato/vpp-agent/plugins/linuxplugin/l3plugin/model/l3"
+)
+
+const (
+	// podNetworksAnnotation is the pod annotation listing the secondary networks of the pod
+	// (in the format of k8s network attachment selection used by Multus).
+	podNetworksAnnotation = "k8s.v1.cni.cncf.io/networks"
+
+	// secondaryIfNamePrefix is the prefix of the default names of the secondary pod interfaces (net1, net2, ...).
+	secondaryIfNamePrefix = "net"
+
+	// types of the secondary pod interfaces
+	secondaryIfTypeVeth      = "veth"
+	secondaryIfTypeTap       = "tap"
+	secondaryIfTypeMemif     = "memif"
+	secondaryIfTypeVhostUser = "vhostuser"
+
+	// podMemifAnnotation is the pod annotation describing the memif interfaces of the pod
+	// (JSON object with memifInfo keyed by the interface names).
+	podMemifAnnotation = "contivpp.io/memif"
+
+	// podVhostUserAnnotation is the pod annotation describing the vhost-user interfaces of the pod
+	// (JSON object with vhostUserInfo keyed by the interface names).
+	podVhostUserAnnotation = "contivpp.io/vhost-user"
+
+	// defaults of the memif parameters of the secondary networks
+	defaultMemifSocketDir  = "/run/vpp/memif"
+	defaultMemifRingSize   = 1024
+	defaultMemifBufferSize = 2048
+	defaultMemifQueues     = 1
+
+	// default directory of the vhost-user sockets of the secondary networks
+	defaultVhostUserSocketDir = "/run/vpp/vhost-user"
+)
+
+// networkSelection is one secondary network requested by the pod annotation.
+type networkSelection struct {
+	Name      string `json:"name"`
+	Interface string `json:"interface,omitempty"`
+}
+
+// memifInfo describes one memif interface of the pod in the memif annotation. VPP is the master
+// of the memif connection, the pod application connects to the socket as slave.
+type memifInfo struct {
+	Socket            string `json:"socket"`            // path of the memif socket on the host
+	ID                uint32 `json:"id"`                // memif ID of the connection
+	RingSize          uint32 `json:"ringSize"`          // number of entries of the rings
+	BufferSize        uint32 `json:"bufferSize"`        // size of the buffers in bytes
+	Queues            uint32 `json:"queues"`            // number of RX and TX queues
+	IPAddress         string `json:"ipAddress"`         // IP address assigned to the pod end
+	MacAddress        string `json:"macAddress"`        // MAC address the pod end is expected to use
+	Gateway           string `json:"gateway"`           // IP address of the network gateway (VPP)
+	GatewayMacAddress string `json:"gatewayMacAddress"` // MAC address of the VPP end
+}
+
+// vhostUserInfo describes one vhost-user interface of the pod in the vhost-user annotation.
+// VPP is the vhost-user server, the VM running in the pod (e.g. QEMU) connects to the socket as client.
+type vhostUserInfo struct {
+	Socket            string `json:"socket"`            // path of the vhost-user socket on the host
+	IPAddress         string `json:"ipAddress"`         // IP address assigned to the VM interface
+	MacAddress        string `json:"macAddress"`        // MAC address the VM interface is expected to use
+	Gateway           string `json:"gateway"`           // IP address of the network gateway (VPP)
+	GatewayMacAddress string `json:"gatewayMacAddress"` // MAC address of the VPP end
+}
+
+// parseNetworksAnnotation parses the value of the networks annotation. Both the comma-separated list
+// of network names (optionally followed by @<interface name>) and the JSON list of network selection
+// objects are accepted. Interfaces without explicit name are named net1, net2, ... in the order of appearance.
+func parseNetworksAnnotation(value string) ([]networkSelection, error) {
+	var selections []networkSelection
+
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &selections); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %v", podNetworksAnnotation, err)
+		}
+	} else {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			selection := networkSelection{Name: item}
+			if at := strings.Index(item, "@"); at >= 0 {
+				selection.Name, selection.Interface = item[:at], item[at+1:]
+			}
+			selections = append(selections, selection)
+		}
+	}
+
+	for idx := range selections {
+		selection := &selections[idx]
+		// networks are not namespaced in contiv, <namespace>/<name> refers to the network <name>
+		if slash := strings.LastIndex(selection.Name, "/"); slash >= 0 {
+			selection.Name = selection.Name[slash+1:]
+		}
+		if selection.Name == "" {
+			return nil, fmt.Errorf("invalid %s annotation: network name is missing", podNetworksAnnotation)
+		}
+		if selection.Interface == "" {
+			selection.Interface = fmt.Sprintf("%s%d", secondaryIfNamePrefix, idx+1)
+		}
+		if len(selection.Interface) > linuxIfMaxLen {
+			return nil, fmt.Errorf("invalid %s annotation: interface name %s is too long", podNetworksAnnotation, selection.Interface)
+		}
+	}
+	return selections, nil
+}
+
+// validateSecondaryNetworks checks the secondary networks defined in the config.
+func (s *remoteCNIserver) validateSecondaryNetworks(networks []SecondaryNetwork) error {
+	s.secondaryNetworks = map[string]SecondaryNetwork{}
+	for _, network := range networks {
+		if network.Name == "" {
+			return fmt.Errorf("name of the secondary network is not set")
+		}
+		if _, duplicate := s.secondaryNetworks[network.Name]; duplicate {
+			return fmt.Errorf("duplicate secondary network %s", network.Name)
+		}
+		if network.InterfaceType == "" {
+			network.InterfaceType = secondaryIfTypeVeth
+		}
+		switch network.InterfaceType {
+		case secondaryIfTypeVeth, secondaryIfTypeTap:
+		case secondaryIfTypeMemif:
+			if network.MemifSocketDir == "" {
+				network.MemifSocketDir = defaultMemifSocketDir
+			}
+			if network.MemifRingSize == 0 {
+				network.MemifRingSize = defaultMemifRingSize
+			}
+			if network.MemifBufferSize == 0 {
+				network.MemifBufferSize = defaultMemifBufferSize
+			}
+			if network.MemifQueues == 0 {
+				network.MemifQueues = defaultMemifQueues
+			}
+			if network.MemifRingSize&(network.MemifRingSize-1) != 0 {
+				return fmt.Errorf("memif ring size %d of the secondary network %s is not a power of 2", network.MemifRingSize, network.Name)
+			}
+		case secondaryIfTypeVhostUser:
+			if network.VhostUserSocketDir == "" {
+				network.VhostUserSocketDir = defaultVhostUserSocketDir
+			}
+		default:
+			return fmt.Errorf("unsupported interface type %s of the secondary network %s", network.InterfaceType, network.Name)
+		}
+		if _, err := s.ipam.PoolSubnet(network.IPPool); err != nil {
+			return fmt.Errorf("invalid secondary network %s: %v", network.Name, err)
+		}
+		s.secondaryNetworks[network.Name] = network
+	}
+	return nil
+}
+
+// configureSecondaryIfs connects the POD to the secondary networks requested by its annotation.
+// Each secondary interface gets an IP address from the IP pool of its network.
+func (s *remoteCNIserver) configureSecondaryIfs(request *cni.CNIRequest, config *containeridx.Config) error {
+	if s.getPodAnnotations == nil || len(s.secondaryNetworks) == 0 {
+		return nil
+	}
+	annotations, err := s.getPodAnnotations(config.PodNamespace, config.PodName)
+	if err != nil {
+		return fmt.Errorf("can't read annotations of pod %s/%s: %v", config.PodNamespace, config.PodName, err)
+	}
+	value, requested := annotations[podNetworksAnnotation]
+	if !requested {
+		return nil
+	}
+	selections, err := parseNetworksAnnotation(value)
+	if err != nil {
+		return err
+	}
+
+	for _, selection := range selections {
+		network, defined := s.secondaryNetworks[selection.Name]
+		if !defined {
+			err = fmt.Errorf("secondary network %s is not defined", selection.Name)
+			break
+		}
+		var secondaryIf *containeridx.SecondaryIf
+		secondaryIf, err = s.configureSecondaryIf(request, network, selection.Interface)
+		if secondaryIf != nil {
+			config.SecondaryIfs = append(config.SecondaryIfs, secondaryIf)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		// let the pod know how to connect to its memif and vhost-user interfaces
+		err = s.publishSecondaryIfAnnotations(config)
+	}
+	if err != nil {
+		// do not leave the interfaces configured so far behind
+		s.unconfigureSecondaryIfs(request, config)
+		config.SecondaryIfs = nil
+	}
+	return err
+}
+
+// configureSecondaryIf configures one secondary interface of the POD.
+func (s *remoteCNIserver) configureSecondaryIf(request *cni.CNIRequest, network SecondaryNetwork, podIfName string) (*containeridx.SecondaryIf, error) {
+	ifRequest := s.secondaryIfRequest(request, podIfName)
+
+	// the VPP end of each pod interface gets a unique IP address generated from the counter
+	podIfIdx := s.nextPodIfIdx()
+
+	gateway, err := s.ipam.PoolGatewayIP(network.IPPool)
+	if err != nil {
+		return nil, err
+	}
+	subnet, err := s.ipam.PoolSubnet(network.IPPool)
+	if err != nil {
+		return nil, err
+	}
+	// IP addresses of the secondary interfaces are not reserved for the POD key
+	ip, err := s.ipam.NextPodIPFromPool(s.secondaryIfPodID(request, podIfName), "", network.IPPool)
+	if err != nil {
+		return nil, fmt.Errorf("can't get IP address for interface %s from network %s: %v", podIfName, network.Name, err)
+	}
+	ipCIDR := ip.String() + "/32"
+
+	secondaryIf := &containeridx.SecondaryIf{
+		Network:   network.Name,
+		PodIfName: podIfName,
+		IPAddress: ip.String(),
+	}
+
+	if network.InterfaceType == secondaryIfTypeVhostUser {
+		// vhost-user interface is configured directly on VPP, VPP creates the socket, but not the directory
+		if !s.test {
+			err = os.MkdirAll(network.VhostUserSocketDir, 0755)
+		}
+		if err == nil {
+			err = s.configureVhostUserIf(secondaryIf, podIfIdx, s.vhostUserSocketFromRequest(ifRequest, network.VhostUserSocketDir), ip)
+		}
+		if err != nil {
+			s.ipam.ReleasePodIP(s.secondaryIfPodID(request, podIfName))
+			return nil, err
+		}
+		return secondaryIf, nil
+	}
+
+	// prepare the config transaction 1
+	txn1 := s.vppTxnFactory().Put()
+
+	switch network.InterfaceType {
+	case secondaryIfTypeTap:
+		secondaryIf.VppIf = s.tapFromRequest(ifRequest, podIfIdx, false, "")
+		txn1.VppInterface(secondaryIf.VppIf)
+	case secondaryIfTypeMemif:
+		// VPP creates the socket, but not the directory
+		if !s.test {
+			err = os.MkdirAll(network.MemifSocketDir, 0755)
+			if err != nil {
+				s.ipam.ReleasePodIP(s.secondaryIfPodID(request, podIfName))
+				return nil, fmt.Errorf("can't create memif socket directory %s: %v", network.MemifSocketDir, err)
+			}
+		}
+		secondaryIf.VppIf = s.memifFromRequest(ifRequest, podIfIdx, s.memifSocketFromRequest(ifRequest, network.MemifSocketDir), network)
+		txn1.VppInterface(secondaryIf.VppIf)
+	default:
+		secondaryIf.Veth1 = s.veth1FromRequest(ifRequest, ipCIDR)
+		secondaryIf.Veth2 = s.veth2FromRequest(ifRequest)
+		secondaryIf.VppIf = s.afpacketFromRequest(ifRequest, podIfIdx, false, "")
+
+		// link scope route and ARP entry of the network gateway
+		secondaryIf.PodLinkRoute = s.podLinkRouteFromRequest(ifRequest, secondaryIf.Veth1.Name)
+		secondaryIf.PodLinkRoute.DstIpAddr = gateway.String() + "/32"
+		secondaryIf.PodARPEntry = s.podArpEntry(ifRequest, secondaryIf.Veth1.Name, secondaryIf.VppIf.PhysAddress)
+		secondaryIf.PodARPEntry.IpAddr = gateway.String()
+
+		txn1.LinuxInterface(secondaryIf.Veth1).
+			LinuxInterface(secondaryIf.Veth2).
+			VppInterface(secondaryIf.VppIf).
+			LinuxRoute(secondaryIf.PodLinkRoute).
+			LinuxArpEntry(secondaryIf.PodARPEntry)
+	}
+
+	// route + ARP entry from VPP to the interface
+	secondaryIf.VppRoute = &vpp_l3.StaticRoutes_Route{
+		DstIpAddr:         ipCIDR,
+		OutgoingInterface: secondaryIf.VppIf.Name,
+	}
+	secondaryIf.VppARPEntry = s.vppArpEntry(secondaryIf.VppIf.Name, ip, s.hwAddrForContainer())
+	txn1.StaticRoute(secondaryIf.VppRoute).
+		Arp(secondaryIf.VppARPEntry)
+
+	err = txn1.Send().ReceiveReply()
+	if err != nil {
+		s.ipam.ReleasePodIP(s.secondaryIfPodID(request, podIfName))
+		return nil, err
+	}
+
+	if network.InterfaceType == secondaryIfTypeTap {
+		// finish the TAP interface configuration in the pod
+		ipNet := &net.IPNet{IP: ip, Mask: net.CIDRMask(net.IPv4len*8, net.IPv4len*8)}
+		err = s.configureSecondaryHostTAP(ifRequest, ipNet, gateway, subnet, secondaryIf.VppIf.PhysAddress)
+		if err != nil {
+			s.Logger.Error(err)
+			if !s.test {
+				// skip error by tests
+				return secondaryIf, err
+			}
+		}
+		return secondaryIf, nil
+	}
+	if network.InterfaceType == secondaryIfTypeMemif {
+		// the pod end of memif is configured by the pod application itself
+		return secondaryIf, nil
+	}
+
+	// the route to the subnet of the network depends on the link route from the transaction 1
+	secondaryIf.PodRoute = s.podDefaultRouteFromRequest(ifRequest, secondaryIf.Veth1.Name)
+	secondaryIf.PodRoute.Name = "SUBNET-" + ifRequest.ContainerId
+	secondaryIf.PodRoute.Default = false
+	secondaryIf.PodRoute.DstIpAddr = subnet.String()
+	secondaryIf.PodRoute.GwAddr = gateway.String()
+
+	err = s.vppTxnFactory().Put().LinuxRoute(secondaryIf.PodRoute).Send().ReceiveReply()
+	return secondaryIf, err
+}
+
+// unconfigureSecondaryIfs removes the secondary interfaces of the POD and releases their IP addresses.
+// All interfaces are processed, the first error is returned.
+func (s *remoteCNIserver) unconfigureSecondaryIfs(request *cni.CNIRequest, config *containeridx.Config) error {
+	var wasErr error
+	for _, secondaryIf := range config.SecondaryIfs {
+		ifRequest := s.secondaryIfRequest(request, secondaryIf.PodIfName)
+
+		if secondaryIf.VhostUserSocket != "" {
+			err := s.unconfigureVhostUserIf(secondaryIf)
+			if err != nil {
+				s.Logger.Error(err)
+				if wasErr == nil {
+					wasErr = err
+				}
+			}
+			err = s.ipam.ReleasePodIP(s.secondaryIfPodID(request, secondaryIf.PodIfName))
+			if err != nil {
+				s.Logger.Error(err)
+				if wasErr == nil {
+					wasErr = err
+				}
+			}
+			continue
+		}
+
+		txn := s.vppTxnFactory().Delete().
+			StaticRoute(secondaryIf.VppRoute.VrfId, secondaryIf.VppRoute.DstIpAddr, secondaryIf.VppRoute.NextHopAddr).
+			Arp(secondaryIf.VppARPEntry.Interface, secondaryIf.VppARPEntry.IpAddress).
+			VppInterface(secondaryIf.VppIf.Name)
+		if secondaryIf.Veth1 != nil {
+			txn.LinuxInterface(secondaryIf.Veth1.Name).
+				LinuxInterface(secondaryIf.Veth2.Name)
+			if !s.test {
+				txn.LinuxRoute(secondaryIf.PodLinkRoute.Name).
+					LinuxArpEntry(secondaryIf.PodARPEntry.Name)
+				if secondaryIf.PodRoute != nil {
+					txn.LinuxRoute(secondaryIf.PodRoute.Name)
+				}
+			}
+		}
+		err := txn.Send().ReceiveReply()
+		if err == nil && secondaryIf.VppIf.Type == vpp_intf.InterfaceType_TAP_INTERFACE && !s.test {
+			err = s.unconfigureHostTAP(ifRequest)
+		}
+		if err != nil {
+			s.Logger.Error(err)
+			if wasErr == nil {
+				wasErr = err
+			}
+		}
+
+		err = s.ipam.ReleasePodIP(s.secondaryIfPodID(request, secondaryIf.PodIfName))
+		if err != nil {
+			s.Logger.Error(err)
+			if wasErr == nil {
+				wasErr = err
+			}
+		}
+	}
+
+	// withdraw the memif and vhost-user annotations
+	if len(s.secondaryIfAnnotations(config)) > 0 && s.publishPodAnnotations != nil {
+		err := s.publishPodAnnotations(config.PodNamespace, config.PodName, nil)
+		if err != nil {
+			s.Logger.Error(err)
+			if wasErr == nil {
+				wasErr = err
+			}
+		}
+	}
+	return wasErr
+}
+
+// memifInfos returns the description of the memif interfaces of the POD keyed by the interface names.
+func (s *remoteCNIserver) memifInfos(config *containeridx.Config) map[string]*memifInfo {
+	infos := map[string]*memifInfo{}
+	for _, secondaryIf := range config.SecondaryIfs {
+		if secondaryIf.VppIf == nil || secondaryIf.VppIf.Type != vpp_intf.InterfaceType_MEMORY_INTERFACE {
+			continue
+		}
+		info := &memifInfo{
+			Socket:            secondaryIf.VppIf.Memif.SocketFilename,
+			ID:                secondaryIf.VppIf.Memif.Id,
+			RingSize:          secondaryIf.VppIf.Memif.RingSize,
+			BufferSize:        secondaryIf.VppIf.Memif.BufferSize,
+			Queues:            secondaryIf.VppIf.Memif.RxQueues,
+			IPAddress:         secondaryIf.IPAddress + "/32",
+			MacAddress:        secondaryIf.VppARPEntry.PhysAddress,
+			GatewayMacAddress: secondaryIf.VppIf.PhysAddress,
+		}
+		if gateway, err := s.ipam.PoolGatewayIP(s.secondaryNetworks[secondaryIf.Network].IPPool); err == nil {
+			info.Gateway = gateway.String()
+		}
+		infos[secondaryIf.PodIfName] = info
+	}
+	return infos
+}
+
+// vhostUserInfos returns the description of the vhost-user interfaces of the POD keyed by the interface names.
+func (s *remoteCNIserver) vhostUserInfos(config *containeridx.Config) map[string]*vhostUserInfo {
+	infos := map[string]*vhostUserInfo{}
+	for _, secondaryIf := range config.SecondaryIfs {
+		if secondaryIf.VhostUserSocket == "" {
+			continue
+		}
+		info := &vhostUserInfo{
+			Socket:            secondaryIf.VhostUserSocket,
+			IPAddress:         secondaryIf.IPAddress + "/32",
+			MacAddress:        s.hwAddrForContainer(),
+			GatewayMacAddress: secondaryIf.VhostUserPhysAddress,
+		}
+		if gateway, err := s.ipam.PoolGatewayIP(s.secondaryNetworks[secondaryIf.Network].IPPool); err == nil {
+			info.Gateway = gateway.String()
+		}
+		infos[secondaryIf.PodIfName] = info
+	}
+	return infos
+}
+
+// secondaryIfAnnotations returns the annotations describing the memif and vhost-user interfaces of the POD.
+func (s *remoteCNIserver) secondaryIfAnnotations(config *containeridx.Config) map[string]string {
+	annotations := map[string]string{}
+	if infos := s.memifInfos(config); len(infos) > 0 {
+		value, _ := json.Marshal(infos)
+		annotations[podMemifAnnotation] = string(value)
+	}
+	if infos := s.vhostUserInfos(config); len(infos) > 0 {
+		value, _ := json.Marshal(infos)
+		annotations[podVhostUserAnnotation] = string(value)
+	}
+	return annotations
+}
+
+// publishSecondaryIfAnnotations requests KSR to annotate the POD with the description
+// of its memif and vhost-user interfaces.
+func (s *remoteCNIserver) publishSecondaryIfAnnotations(config *containeridx.Config) error {
+	annotations := s.secondaryIfAnnotations(config)
+	if len(annotations) == 0 || s.publishPodAnnotations == nil {
+		return nil
+	}
+	err := s.publishPodAnnotations(config.PodNamespace, config.PodName, annotations)
+	if err != nil {
+		return fmt.Errorf("can't publish annotations of pod %s/%s: %v", config.PodNamespace, config.PodName, err)
+	}
+	return nil
+}
+
+// secondaryIfChanges returns the configuration of the secondary interfaces of the POD
+// to be persisted, keyed by the keys of the items.
+func (s *remoteCNIserver) secondaryIfChanges(config *containeridx.Config) map[string]proto.Message {
+	changes := map[string]proto.Message{}
+	for _, secondaryIf := range config.SecondaryIfs {
+		if secondaryIf.VppIf == nil {
+			// vhost-user interfaces are not managed by the VPP agent
+			continue
+		}
+		changes[vpp_intf.InterfaceKey(secondaryIf.VppIf.Name)] = secondaryIf.VppIf
+		changes[vpp_l3.RouteKey(secondaryIf.VppRoute.VrfId, secondaryIf.VppRoute.DstIpAddr, secondaryIf.VppRoute.NextHopAddr)] = secondaryIf.VppRoute
+		changes[vpp_l3.ArpEntryKey(secondaryIf.VppARPEntry.Interface, secondaryIf.VppARPEntry.IpAddress)] = secondaryIf.VppARPEntry
+		if secondaryIf.Veth1 != nil {
+			changes[linux_intf.InterfaceKey(secondaryIf.Veth1.Name)] = secondaryIf.Veth1
+			changes[linux_intf.InterfaceKey(secondaryIf.Veth2.Name)] = secondaryIf.Veth2
+			changes[linux_l3.StaticRouteKey(secondaryIf.PodLinkRoute.Name)] = secondaryIf.PodLinkRoute
+			changes[linux_l3.StaticArpKey(secondaryIf.PodARPEntry.Name)] = secondaryIf.PodARPEntry
+			if secondaryIf.PodRoute != nil {
+				changes[linux_l3.StaticRouteKey(secondaryIf.PodRoute.Name)] = secondaryIf.PodRoute
+			}
+		}
+	}
+	return changes
+}
+
+// secondaryIfReplies returns the secondary interfaces of the POD in the format of the CNI reply.
+// Memif and vhost-user interfaces are not included, they are described by the annotations of the POD instead.
+func (s *remoteCNIserver) secondaryIfReplies(request *cni.CNIRequest, config *containeridx.Config) []*cni.CNIReply_Interface {
+	var ifs []*cni.CNIReply_Interface
+	for _, secondaryIf := range config.SecondaryIfs {
+		if secondaryIf.VppIf == nil || secondaryIf.VppIf.Type == vpp_intf.InterfaceType_MEMORY_INTERFACE {
+			continue
+		}
+		gateway, _ := s.ipam.PoolGatewayIP(s.secondaryNetworks[secondaryIf.Network].IPPool)
+		iface := &cni.CNIReply_Interface{
+			Name:    secondaryIf.PodIfName,
+			Sandbox: request.NetworkNamespace,
+			IpAddresses: []*cni.CNIReply_Interface_IP{
+				{
+					Version: cni.CNIReply_Interface_IP_IPV4,
+					Address: secondaryIf.IPAddress + "/32",
+				},
+			},
+		}
+		if gateway != nil {
+			iface.IpAddresses[0].Gateway = gateway.String()
+		}
+		ifs = append(ifs, iface)
+	}
+	return ifs
+}
+
+// secondaryIfRequest derives the request used to build the configuration of the secondary interface
+// from the CNI request of the POD, so that the names of the configured items are unique.
+func (s *remoteCNIserver) secondaryIfRequest(request *cni.CNIRequest, podIfName string) *cni.CNIRequest {
+	ifRequest := *request
+	ifRequest.InterfaceName = podIfName
+	ifRequest.ContainerId = podIfName + "-" + request.ContainerId
+	return &ifRequest
+}
+
+// memifSocketFromRequest returns the path of the memif socket of the secondary interface.
+func (s *remoteCNIserver) memifSocketFromRequest(ifRequest *cni.CNIRequest, socketDir string) string {
+	return filepath.Join(socketDir, ifRequest.ContainerId+".sock")
+}
+
+// vhostUserSocketFromRequest returns the path of the vhost-user socket of the secondary interface.
+func (s *remoteCNIserver) vhostUserSocketFromRequest(ifRequest *cni.CNIRequest, socketDir string) string {
+	return filepath.Join(socketDir, ifRequest.ContainerId+".sock")
+}
+
+// secondaryIfPodID returns the ID under which the IP address of the secondary interface is assigned in IPAM.
+func (s *remoteCNIserver) secondaryIfPodID(request *cni.CNIRequest, podIfName string) string {
+	return request.NetworkNamespace + "/" + podIfName
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	vpp_l3 "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
+)
+
+const (
+	// defaultSRv6SIDSubnetCIDR is the default subnet of the local SIDs of the nodes.
+	defaultSRv6SIDSubnetCIDR = "fd00:5::/64"
+
+	// defaultSRv6PolicySubnetCIDR is the default subnet of the binding SIDs of the policies to other nodes.
+	defaultSRv6PolicySubnetCIDR = "fd00:6::/64"
+
+	// srv6PodFIBTable is the FIB table the decapsulated traffic of the pods is looked up in.
+	srv6PodFIBTable = 0
+)
+
+// SRv6Config configures the SRv6 inter-node transport (InterNodeTransport: srv6).
+type SRv6Config struct {
+	SIDSubnetCIDR    string   // IPv6 subnet of the local SIDs of the nodes (subnet + node ID), "fd00:5::/64" by default
+	PolicySubnetCIDR string   // IPv6 subnet of the binding SIDs of the policies to other nodes, "fd00:6::/64" by default
+	Waypoints        []string // SIDs the traffic to other nodes is steered through before reaching the SID of the node
+	Gateway          string   // IPv6 next hop of the SIDs of other nodes, the fabric has to route them to VPP otherwise
+}
+
+// initSRv6 parses the SRv6 config.
+func (s *remoteCNIserver) initSRv6(config SRv6Config) (err error) {
+	sidSubnet := config.SIDSubnetCIDR
+	if sidSubnet == "" {
+		sidSubnet = defaultSRv6SIDSubnetCIDR
+	}
+	policySubnet := config.PolicySubnetCIDR
+	if policySubnet == "" {
+		policySubnet = defaultSRv6PolicySubnetCIDR
+	}
+	if s.srv6SIDSubnet, err = parseSRv6Subnet(sidSubnet); err != nil {
+		return err
+	}
+	if s.srv6PolicySubnet, err = parseSRv6Subnet(policySubnet); err != nil {
+		return err
+	}
+	if s.srv6SIDSubnet.Contains(s.srv6PolicySubnet.IP) || s.srv6PolicySubnet.Contains(s.srv6SIDSubnet.IP) {
+		return fmt.Errorf("SRv6 SID subnet %v overlaps with the policy subnet %v", sidSubnet, policySubnet)
+	}
+	s.srv6Waypoints = nil
+	for _, waypoint := range config.Waypoints {
+		sid := net.ParseIP(waypoint)
+		if sid == nil || sid.To4() != nil {
+			return fmt.Errorf("invalid SRv6 waypoint SID %s", waypoint)
+		}
+		s.srv6Waypoints = append(s.srv6Waypoints, sid)
+	}
+	s.srv6Gateway = nil
+	if config.Gateway != "" {
+		s.srv6Gateway = net.ParseIP(config.Gateway)
+		if s.srv6Gateway == nil || s.srv6Gateway.To4() != nil {
+			return fmt.Errorf("invalid SRv6 gateway %s", config.Gateway)
+		}
+	}
+	return nil
+}
+
+// parseSRv6Subnet parses IPv6 subnet of the SIDs.
+func parseSRv6Subnet(cidr string) (*net.IPNet, error) {
+	ip, subnet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() != nil {
+		return nil, fmt.Errorf("invalid SRv6 subnet %s", cidr)
+	}
+	return subnet, nil
+}
+
+// srv6SID returns the SID of the given node ID from the given subnet.
+func srv6SID(subnet *net.IPNet, nodeID uint8) (net.IP, error) {
+	sid := make(net.IP, net.IPv6len)
+	copy(sid, subnet.IP.To16())
+	carry := uint16(nodeID)
+	for i := net.IPv6len - 1; i >= 0 && carry > 0; i-- {
+		sum := uint16(sid[i]) + carry
+		sid[i] = byte(sum)
+		carry = sum >> 8
+	}
+	if !subnet.Contains(sid) {
+		return nil, fmt.Errorf("node ID %d does not fit into SRv6 subnet %v", nodeID, subnet)
+	}
+	return sid, nil
+}
+
+// configureSRv6 configures the local SID of this node, decapsulating the traffic of other nodes
+// and looking it up in the FIB table of the pods (End.DT4), and the source address of the encapsulated
+// traffic. The method must be called with acquired mutex guarding remoteCNI server.
+func (s *remoteCNIserver) configureSRv6() error {
+	localSID, err := srv6SID(s.srv6SIDSubnet, s.ipam.NodeID())
+	if err != nil {
+		return err
+	}
+	if err := s.execConfigCLI("set sr encaps source addr %s", localSID); err != nil {
+		return err
+	}
+	// the local SID may have been configured by the previous run of the agent
+	s.execConfigCLI("sr localsid del address %s", localSID)
+	if err := s.execConfigCLI("sr localsid address %s behavior end.dt4 %d", localSID, srv6PodFIBTable); err != nil {
+		return err
+	}
+	s.Logger.Infof("SRv6 local SID of the node is %v", localSID)
+
+	if s.srv6Gateway != nil {
+		route := s.srv6GatewayRoute()
+		s.Logger.Info("Adding SRv6 gateway route: ", route)
+		err = s.vppTxnFactory().Put().StaticRoute(route).Send().ReceiveReply()
+		if err != nil {
+			return fmt.Errorf("Can't configure VPP to add SRv6 gateway route: %v ", err)
+		}
+	}
+	return nil
+}
+
+// srv6GatewayRoute returns the route to the SIDs of other nodes via the SRv6 gateway.
+func (s *remoteCNIserver) srv6GatewayRoute() *vpp_l3.StaticRoutes_Route {
+	return &vpp_l3.StaticRoutes_Route{
+		DstIpAddr:         s.srv6SIDSubnet.String(),
+		NextHopAddr:       s.srv6Gateway.String(),
+		OutgoingInterface: s.mainPhysicalIf,
+	}
+}
+
+// addSRv6PolicyToNode steers the traffic destined to the networks of the other node into the SRv6 policy
+// encapsulating it towards the SID of the node (through the waypoints, if configured).
+func (s *remoteCNIserver) addSRv6PolicyToNode(nodeInfo *node.NodeInfo) error {
+	bsid, segments, prefixes, err := s.srv6PolicyToNode(nodeInfo)
+	if err != nil {
+		return err
+	}
+	// the policy may have been configured by the previous run of the agent
+	s.deleteSRv6Policy(bsid, prefixes)
+
+	var next []string
+	for _, segment := range segments {
+		next = append(next, "next "+segment.String())
+	}
+	if err := s.execConfigCLI("sr policy add bsid %s %s encap", bsid, strings.Join(next, " ")); err != nil {
+		return fmt.Errorf("Can't configure VPP to add SRv6 policy to node %v: %v ", nodeInfo.Id, err)
+	}
+	for _, prefix := range prefixes {
+		if err := s.execConfigCLI("sr steer l3 %s via bsid %s", prefix, bsid); err != nil {
+			return fmt.Errorf("Can't configure VPP to steer %s to node %v: %v ", prefix, nodeInfo.Id, err)
+		}
+	}
+	s.Logger.Infof("Added SRv6 policy %v to node %v via %v steering %v", bsid, nodeInfo.Id, segments, prefixes)
+	return nil
+}
+
+// deleteSRv6PolicyToNode removes the SRv6 policy to the other node together with its steering.
+func (s *remoteCNIserver) deleteSRv6PolicyToNode(nodeInfo *node.NodeInfo) error {
+	bsid, _, prefixes, err := s.srv6PolicyToNode(nodeInfo)
+	if err != nil {
+		return err
+	}
+	if err := s.deleteSRv6Policy(bsid, prefixes); err != nil {
+		return fmt.Errorf("Can't configure VPP to remove SRv6 policy to node %v: %v ", nodeInfo.Id, err)
+	}
+	s.Logger.Infof("Deleted SRv6 policy %v to node %v", bsid, nodeInfo.Id)
+	return nil
+}
+
+// deleteSRv6Policy removes the steering of the prefixes and the SRv6 policy.
+// The steering is removed even if the removal of some prefixes fails, the first error is returned.
+func (s *remoteCNIserver) deleteSRv6Policy(bsid net.IP, prefixes []string) error {
+	var wasErr error
+	for _, prefix := range prefixes {
+		if err := s.execConfigCLI("sr steer del l3 %s via bsid %s", prefix, bsid); err != nil && wasErr == nil {
+			wasErr = err
+		}
+	}
+	if err := s.execConfigCLI("sr policy del bsid %s", bsid); err != nil && wasErr == nil {
+		wasErr = err
+	}
+	return wasErr
+}
+
+// srv6PolicyToNode returns the binding SID and the segments of the SRv6 policy to the other node,
+// together with the networks of the node steered into the policy.
+func (s *remoteCNIserver) srv6PolicyToNode(nodeInfo *node.NodeInfo) (bsid net.IP, segments []net.IP, prefixes []string, err error) {
+	nodeID := uint8(nodeInfo.Id)
+	if bsid, err = srv6SID(s.srv6PolicySubnet, nodeID); err != nil {
+		return nil, nil, nil, err
+	}
+	nodeSID, err := srv6SID(s.srv6SIDSubnet, nodeID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	segments = append(append(segments, s.srv6Waypoints...), nodeSID)
+
+	hostIP := s.otherHostIP(nodeID, nodeInfo.IpAddress)
+	podsRoute, hostRoute, err := s.computeRoutesToHost(nodeID, nodeInfo.PodNetwork, hostIP)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	prefixes = append(prefixes, podsRoute.DstIpAddr, hostRoute.DstIpAddr)
+	poolRoutes, err := s.routesToOtherHostPools(nodeID, hostIP)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, poolRoute := range poolRoutes {
+		prefixes = append(prefixes, poolRoute.DstIpAddr)
+	}
+	return bsid, segments, prefixes, nil
+}
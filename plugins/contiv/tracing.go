@@ -0,0 +1,90 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/unrolled/render"
+	"golang.org/x/net/context"
+	"golang.org/x/net/trace"
+)
+
+const (
+	// tracesURL is the URL of the traces of the recent and active requests (served by golang.org/x/net/trace).
+	tracesURL = "/debug/requests"
+
+	// traceEventsURL is the URL of the long-lived event logs (served by golang.org/x/net/trace).
+	traceEventsURL = "/debug/events"
+)
+
+// requestTrace records the stages of a CNI request (waiting in the pipeline, IPAM, VPP transactions, ...)
+// as timed spans of the trace created for the request by the GRPC server, so that a slow setup of a pod
+// can be examined on /debug/requests. The methods can be called on nil trace (request not traced).
+type requestTrace struct {
+	tr trace.Trace
+}
+
+// traceSpan is a stage of the traced request.
+type traceSpan struct {
+	tr    trace.Trace
+	name  string
+	start time.Time
+}
+
+// requestTraceFromContext returns the trace of the request, nil if the request is not traced.
+func requestTraceFromContext(ctx context.Context) *requestTrace {
+	if ctx == nil {
+		return nil
+	}
+	if tr, ok := trace.FromContext(ctx); ok {
+		return &requestTrace{tr: tr}
+	}
+	return nil
+}
+
+// startSpan records the start of the named stage of the request.
+func (t *requestTrace) startSpan(name string) *traceSpan {
+	if t == nil {
+		return nil
+	}
+	t.tr.LazyPrintf("%s started", name)
+	return &traceSpan{tr: t.tr, name: name, start: time.Now()}
+}
+
+// finish records the end of the stage, the request is marked as failed if the stage failed.
+func (s *traceSpan) finish(err error) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		s.tr.LazyPrintf("%s failed after %v: %v", s.name, time.Since(s.start), err)
+		s.tr.SetError()
+		return
+	}
+	s.tr.LazyPrintf("%s finished in %v", s.name, time.Since(s.start))
+}
+
+// registerTraceHandlers exposes the traces on the HTTP server of the agent (accessible only from localhost,
+// see trace.AuthRequest).
+func (plugin *Plugin) registerTraceHandlers() {
+	plugin.HTTPHandlers.RegisterHTTPHandler(tracesURL, func(formatter *render.Render) http.HandlerFunc {
+		return trace.Traces
+	}, "GET")
+	plugin.HTTPHandlers.RegisterHTTPHandler(traceEventsURL, func(formatter *render.Render) http.HandlerFunc {
+		return trace.Events
+	}, "GET")
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"golang.org/x/net/context"
+	"golang.org/x/net/trace"
+)
+
+// traceMock records the events of the trace.
+type traceMock struct {
+	sync.Mutex
+	events []string
+	failed bool
+}
+
+func (t *traceMock) LazyLog(x fmt.Stringer, sensitive bool) {
+	t.LazyPrintf("%s", x)
+}
+
+func (t *traceMock) LazyPrintf(format string, a ...interface{}) {
+	t.Lock()
+	defer t.Unlock()
+	t.events = append(t.events, fmt.Sprintf(format, a...))
+}
+
+func (t *traceMock) SetError() {
+	t.Lock()
+	defer t.Unlock()
+	t.failed = true
+}
+
+func (t *traceMock) SetRecycler(f func(interface{})) {}
+
+func (t *traceMock) SetTraceInfo(traceID, spanID uint64) {}
+
+func (t *traceMock) SetMaxEvents(m int) {}
+
+func (t *traceMock) Finish() {}
+
+// finished returns the names of the finished spans.
+func (t *traceMock) finished() []string {
+	t.Lock()
+	defer t.Unlock()
+	var spans []string
+	for _, event := range t.events {
+		if idx := strings.Index(event, " finished in "); idx > 0 {
+			spans = append(spans, event[:idx])
+		}
+	}
+	return spans
+}
+
+func TestTraceCNIRequests(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	server, _, _, conn := setupTestCNIServer(&configVethL2NoTCP, nil)
+	defer conn.Disconnect()
+
+	// pretend that connectivity is configured to unblock CNI requests
+	server.setVswitchConfigured()
+
+	addTrace := &traceMock{}
+	reply, err := server.Add(trace.NewContext(context.Background(), addTrace), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+	gomega.Expect(addTrace.finished()).To(gomega.Equal([]string{"pipeline", "vswitch connectivity", "ipam",
+		"vpp transaction", "linux transaction", "secondary interfaces", "persist config"}))
+	gomega.Expect(addTrace.failed).To(gomega.BeFalse())
+
+	delTrace := &traceMock{}
+	reply, err = server.Delete(trace.NewContext(context.Background(), delTrace), &req)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(reply.Result).To(gomega.BeEquivalentTo(resultOk))
+	gomega.Expect(delTrace.finished()).To(gomega.Equal([]string{"pipeline", "vswitch connectivity",
+		"secondary interfaces", "vpp transaction", "persist config", "ipam"}))
+
+	// requests received without trace are not traced
+	gomega.Expect(requestTraceFromContext(context.Background())).To(gomega.BeNil())
+}
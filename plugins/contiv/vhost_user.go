@@ -0,0 +1,209 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+
+	"git.fd.io/govpp.git/api"
+	"github.com/contiv/vpp/plugins/contiv/bin_api/vhost_user"
+	"github.com/contiv/vpp/plugins/contiv/containeridx"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/ip"
+)
+
+// vhostUserIfNamePrefix is the prefix of the names VPP gives to vhost-user interfaces.
+const vhostUserIfNamePrefix = "VirtualEthernet"
+
+// configureVhostUserIf creates vhost-user interface in server mode listening on the given socket
+// and routes the pod IP address to it. The interfaces are not supported by the VPP agent,
+// they are configured directly via VPP binary API.
+func (s *remoteCNIserver) configureVhostUserIf(secondaryIf *containeridx.SecondaryIf, podIfIdx int, socket string, podIP net.IP) error {
+	secondaryIf.VhostUserSocket = socket
+	secondaryIf.VhostUserPhysAddress = s.generateHwAddrForPodVPPIf()
+	secondaryIf.VhostUserIPAddress = s.ipAddrForPodVPPIf(podIfIdx)
+	return s.createVhostUserIf(secondaryIf, podIP)
+}
+
+// createVhostUserIf creates the vhost-user interface with the socket, MAC and IP address
+// of the VPP end stored in secondaryIf.
+func (s *remoteCNIserver) createVhostUserIf(secondaryIf *containeridx.SecondaryIf, podIP net.IP) error {
+	mac, _ := net.ParseMAC(secondaryIf.VhostUserPhysAddress)
+
+	createReq := &vhost_user.CreateVhostUserIf{
+		IsServer:     1,
+		SockFilename: []byte(secondaryIf.VhostUserSocket),
+		UseCustomMac: 1,
+		MacAddress:   mac,
+	}
+	createReply := &vhost_user.CreateVhostUserIfReply{}
+	err := s.sendVppRequest(createReq, createReply, &createReply.Retval)
+	if err != nil {
+		return fmt.Errorf("can't create vhost-user interface with socket %s: %v", secondaryIf.VhostUserSocket, err)
+	}
+	secondaryIf.VhostUserSwIfIndex = createReply.SwIfIndex
+
+	err = s.configureVhostUserL3(secondaryIf.VhostUserSwIfIndex, secondaryIf.VhostUserIPAddress, podIP)
+	if err != nil {
+		s.unconfigureVhostUserIf(secondaryIf)
+		return fmt.Errorf("can't configure vhost-user interface with socket %s: %v", secondaryIf.VhostUserSocket, err)
+	}
+	return nil
+}
+
+// restoreVhostUserIf re-creates the vhost-user interface if it does not exist in VPP (e.g. after restart of VPP).
+// The interface is looked up by its MAC address, since VPP may assign the index to another interface after restart.
+func (s *remoteCNIserver) restoreVhostUserIf(secondaryIf *containeridx.SecondaryIf) error {
+	swIfIndex, found, err := s.findVhostUserIf(secondaryIf.VhostUserPhysAddress)
+	if err != nil {
+		return err
+	}
+	if found {
+		secondaryIf.VhostUserSwIfIndex = swIfIndex
+		return nil
+	}
+	s.Logger.Infof("Re-creating vhost-user interface with socket %s", secondaryIf.VhostUserSocket)
+	return s.createVhostUserIf(secondaryIf, net.ParseIP(secondaryIf.IPAddress))
+}
+
+// configureVhostUserL3 enables the vhost-user interface, assigns it the IP address of the VPP end
+// of pod interfaces and adds the route and the static ARP entry of the pod IP address.
+func (s *remoteCNIserver) configureVhostUserL3(swIfIndex uint32, vppIPAddress string, podIP net.IP) error {
+	vppIP, _, err := net.ParseCIDR(vppIPAddress)
+	if err != nil {
+		return err
+	}
+	containerMac, _ := net.ParseMAC(s.hwAddrForContainer())
+
+	flagsReq := &interfaces.SwInterfaceSetFlags{
+		SwIfIndex:   swIfIndex,
+		AdminUpDown: 1,
+	}
+	flagsReply := &interfaces.SwInterfaceSetFlagsReply{}
+	if err := s.sendVppRequest(flagsReq, flagsReply, &flagsReply.Retval); err != nil {
+		return err
+	}
+
+	addrReq := &interfaces.SwInterfaceAddDelAddress{
+		SwIfIndex:     swIfIndex,
+		IsAdd:         1,
+		AddressLength: net.IPv4len * 8,
+		Address:       vppIP.To4(),
+	}
+	addrReply := &interfaces.SwInterfaceAddDelAddressReply{}
+	if err := s.sendVppRequest(addrReq, addrReply, &addrReply.Retval); err != nil {
+		return err
+	}
+
+	routeReq := &ip.IPAddDelRoute{
+		NextHopSwIfIndex: swIfIndex,
+		IsAdd:            1,
+		DstAddressLength: net.IPv4len * 8,
+		DstAddress:       podIP.To4(),
+		NextHopAddress:   podIP.To4(),
+	}
+	routeReply := &ip.IPAddDelRouteReply{}
+	if err := s.sendVppRequest(routeReq, routeReply, &routeReply.Retval); err != nil {
+		return err
+	}
+
+	arpReq := &ip.IPNeighborAddDel{
+		SwIfIndex:  swIfIndex,
+		IsAdd:      1,
+		IsStatic:   1,
+		MacAddress: containerMac,
+		DstAddress: podIP.To4(),
+	}
+	arpReply := &ip.IPNeighborAddDelReply{}
+	return s.sendVppRequest(arpReq, arpReply, &arpReply.Retval)
+}
+
+// unconfigureVhostUserIf deletes the vhost-user interface. VPP removes the address, the route
+// and the ARP entry of the interface together with it.
+func (s *remoteCNIserver) unconfigureVhostUserIf(secondaryIf *containeridx.SecondaryIf) error {
+	req := &vhost_user.DeleteVhostUserIf{
+		SwIfIndex: secondaryIf.VhostUserSwIfIndex,
+	}
+	reply := &vhost_user.DeleteVhostUserIfReply{}
+	err := s.sendVppRequest(req, reply, &reply.Retval)
+	if err != nil {
+		return fmt.Errorf("can't delete vhost-user interface with socket %s: %v", secondaryIf.VhostUserSocket, err)
+	}
+	return nil
+}
+
+// checkVhostUserIf verifies that the vhost-user interface still exists in VPP.
+func (s *remoteCNIserver) checkVhostUserIf(secondaryIf *containeridx.SecondaryIf) error {
+	reqCtx := s.govppChan.SendMultiRequest(&interfaces.SwInterfaceDump{})
+	found := false
+	for {
+		details := &interfaces.SwInterfaceDetails{}
+		stop, err := reqCtx.ReceiveReply(details)
+		if stop {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := string(details.InterfaceName)
+		if details.SwIfIndex == secondaryIf.VhostUserSwIfIndex && strings.HasPrefix(name, vhostUserIfNamePrefix) {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("vhost-user interface with socket %s not found", secondaryIf.VhostUserSocket)
+	}
+	return nil
+}
+
+// findVhostUserIf looks up the vhost-user interface with the given MAC address in VPP.
+func (s *remoteCNIserver) findVhostUserIf(hwAddr string) (swIfIndex uint32, found bool, err error) {
+	mac, err := net.ParseMAC(hwAddr)
+	if err != nil {
+		return 0, false, err
+	}
+	reqCtx := s.govppChan.SendMultiRequest(&interfaces.SwInterfaceDump{})
+	for {
+		details := &interfaces.SwInterfaceDetails{}
+		stop, err := reqCtx.ReceiveReply(details)
+		if stop {
+			break
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		name := string(details.InterfaceName)
+		if strings.HasPrefix(name, vhostUserIfNamePrefix) && len(details.L2Address) >= len(mac) &&
+			bytes.Equal(details.L2Address[:len(mac)], mac) {
+			swIfIndex, found = details.SwIfIndex, true
+		}
+	}
+	return swIfIndex, found, nil
+}
+
+// sendVppRequest sends the request to VPP and checks the return value of the reply.
+func (s *remoteCNIserver) sendVppRequest(req api.Message, reply api.Message, retval *int32) error {
+	err := s.govppChan.SendRequest(req).ReceiveReply(reply)
+	if err == nil && *retval != 0 {
+		err = fmt.Errorf("%s returned %d", reply.GetMessageName(), *retval)
+	}
+	if err != nil {
+		s.metrics.vppAPIError(req.GetMessageName())
+	}
+	return err
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/interfaces"
+)
+
+// maxVlanID is the highest usable 802.1Q VLAN ID.
+const maxVlanID = 4094
+
+// initVlan selects the VLAN of the main VPP interface, the VLAN of the node overrides the global one.
+func (s *remoteCNIserver) initVlan(vlanID uint16, nodeConfig *OneNodeConfig) error {
+	if nodeConfig != nil && nodeConfig.VlanID != 0 {
+		vlanID = nodeConfig.VlanID
+	}
+	if vlanID > maxVlanID {
+		return fmt.Errorf("invalid VLAN ID %d", vlanID)
+	}
+	s.vlanID = vlanID
+	return nil
+}
+
+// vlanSubIfName returns the name VPP gives to the 802.1Q sub-interface of the given interface.
+func vlanSubIfName(parent string, vlanID uint16) string {
+	return fmt.Sprintf("%s.%d", parent, vlanID)
+}
+
+// configureVlanSubIf creates the 802.1Q sub-interface of the main NIC (or of the bond) the node interconnect
+// runs over. Like the bond, it is created before the resync of the VPP agent, which then registers it
+// the same way as the physical NICs, so that it can be configured as the main VPP interface.
+func (s *remoteCNIserver) configureVlanSubIf() error {
+	if s.vlanID == 0 {
+		return nil
+	}
+	parent := s.bondIfName
+	if parent == "" && s.nodeConfig != nil {
+		parent = s.nodeConfig.MainVppInterface.InterfaceName
+	}
+	if parent == "" {
+		var err error
+		if parent, err = s.findPhysicalNIC(); err != nil {
+			return err
+		}
+	}
+	if parent == "" {
+		return errors.New("no NIC found for the VLAN sub-interface")
+	}
+
+	ifName := vlanSubIfName(parent, s.vlanID)
+	output, err := s.execVppCLI(fmt.Sprintf("create sub-interfaces %s %d", parent, s.vlanID))
+	if err != nil {
+		return fmt.Errorf("VPP CLI 'create sub-interfaces' failed: %v", err)
+	}
+	// the name of the created interface is printed, the sub-interface created by the previous run is reused
+	output = strings.TrimSpace(output)
+	if output != ifName && !strings.Contains(output, "already") {
+		return fmt.Errorf("VPP CLI 'create sub-interfaces' failed: %s", output)
+	}
+	// the tagged traffic is received only with the parent interface up
+	if err := s.execConfigCLI("set interface state %s up", parent); err != nil {
+		return err
+	}
+	s.vlanIfName = ifName
+	if len(s.uplinks) > 0 {
+		s.uplinks[0].ifName = ifName
+	}
+	s.Logger.Infof("Main VPP interface is the sub-interface %s of VLAN %d", s.vlanIfName, s.vlanID)
+	return nil
+}
+
+// findPhysicalNIC returns the name of the first physical NIC in VPP, the same heuristic is used to select
+// the main VPP interface if its name is not configured.
+func (s *remoteCNIserver) findPhysicalNIC() (string, error) {
+	reqCtx := s.govppChan.SendMultiRequest(&interfaces.SwInterfaceDump{})
+	nicName := ""
+	for {
+		details := &interfaces.SwInterfaceDetails{}
+		stop, err := reqCtx.ReceiveReply(details)
+		if stop {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		name := strings.TrimRight(string(details.InterfaceName), "\x00")
+		if nicName == "" && details.SupSwIfIndex == details.SwIfIndex && !isVirtualIfName(name) {
+			nicName = name
+		}
+	}
+	return nicName, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestVlanSubIf(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	// the VLAN of the node overrides the global one
+	config := configTapVxlanTCP
+	config.VlanID = 100
+	vlanNodeConfig := nodeConfig
+	vlanNodeConfig.VlanID = 200
+	server, txns, _, conn := setupTestCNIServer(&config, &vlanNodeConfig,
+		"GigabitEthernet0/0/0/1", "GigabitEthernet0/0/0/1.200")
+	defer conn.Disconnect()
+	gomega.Expect(server.vlanID).To(gomega.BeEquivalentTo(200))
+
+	// the sub-interface of the main NIC is created
+	var commands []string
+	server.execVppCLI = func(command string) (string, error) {
+		commands = append(commands, command)
+		if strings.HasPrefix(command, "create sub-interfaces") {
+			return "GigabitEthernet0/0/0/1.200\n", nil
+		}
+		return "", nil
+	}
+	err := server.configureVlanSubIf()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(commands).To(gomega.Equal([]string{
+		"create sub-interfaces GigabitEthernet0/0/0/1 200",
+		"set interface state GigabitEthernet0/0/0/1 up",
+	}))
+
+	// the sub-interface is the main VPP interface with the node IP
+	err = server.resync()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(server.mainPhysicalIf).To(gomega.Equal("GigabitEthernet0/0/0/1.200"))
+	subIf := interfaceInSnapshot(txns.AppliedConfig, "GigabitEthernet0/0/0/1.200")
+	gomega.Expect(subIf).NotTo(gomega.BeNil())
+	gomega.Expect(subIf.IpAddresses).To(gomega.ConsistOf(vlanNodeConfig.MainVppInterface.IP))
+
+	// the sub-interface created by the previous run is reused, other errors are reported
+	server.execVppCLI = func(command string) (string, error) {
+		if strings.HasPrefix(command, "create sub-interfaces") {
+			return "create sub-interfaces: vlan is already in use\n", nil
+		}
+		return "", nil
+	}
+	gomega.Expect(server.configureVlanSubIf()).To(gomega.Succeed())
+	server.execVppCLI = func(command string) (string, error) {
+		return "create sub-interfaces: unknown interface\n", nil
+	}
+	gomega.Expect(server.configureVlanSubIf()).NotTo(gomega.Succeed())
+
+	// the global VLAN applies to the nodes without own VLAN, invalid IDs are refused
+	gomega.Expect(server.initVlan(100, &nodeConfig)).To(gomega.Succeed())
+	gomega.Expect(server.vlanID).To(gomega.BeEquivalentTo(100))
+	gomega.Expect(server.initVlan(4095, nil)).NotTo(gomega.Succeed())
+}
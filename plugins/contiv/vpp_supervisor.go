@@ -0,0 +1,194 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"git.fd.io/govpp.git/api"
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/vpe"
+)
+
+const (
+	// defaultVPPProbeInterval is the default period of the probes of the VPP binary API.
+	defaultVPPProbeInterval = 2 * time.Second
+
+	// defaultVPPFailureThreshold is the default number of consecutive failed probes
+	// after which VPP is considered down.
+	defaultVPPFailureThreshold = 3
+
+	// defaultVPPMaxBackoff is the default max. period of the probes during an outage.
+	defaultVPPMaxBackoff = 30 * time.Second
+)
+
+// VPPSupervisionConfig configures the supervision of the binary API connection to VPP.
+type VPPSupervisionConfig struct {
+	Disabled         bool   // disables the supervision
+	ProbeInterval    uint32 // period of the probes in seconds, 2 by default
+	FailureThreshold uint32 // number of consecutive failed probes after which VPP is considered down, 3 by default
+	MaxBackoff       uint32 // max. period of the probes during an outage in seconds, 30 by default
+}
+
+// restartAgent makes the agent exit gracefully, supervisord of the vswitch then starts it again
+// and the configuration is resynced to VPP. Can be replaced by tests.
+var restartAgent = func() error {
+	return syscall.Kill(os.Getpid(), syscall.SIGTERM)
+}
+
+// vppSupervisor periodically probes the binary API of VPP with control pings on its own channel.
+//
+// The connection itself is re-established by GoVPP, which disconnects once its health check fails
+// and reconnects when VPP is ready again. The supervisor reports the outage (log, metrics) and backs
+// off its probes while VPP is down. A probe that fails may leave a stale reply in the channel,
+// a new channel is therefore used after each failure.
+//
+// The VPP agent in use can not replay the resync of the configuration after VPP was restarted,
+// a restart of VPP (detected by the changed PID of VPP in the replies) therefore restarts the agent,
+// which resyncs the persisted configuration into VPP and restores the pods on start.
+type vppSupervisor struct {
+	logger  logging.Logger
+	metrics *metrics
+
+	interval         time.Duration
+	failureThreshold int
+	maxBackoff       time.Duration
+
+	// newChannel creates a new GoVPP channel
+	newChannel func() (*api.Channel, error)
+	// ping returns the PID of VPP, replaced by tests
+	ping func() (pid uint32, err error)
+
+	ch       *api.Channel
+	up       bool
+	failures int
+	down     time.Time // start of the current outage
+	pid      uint32    // PID of VPP from the last successful probe, 0 if not known yet
+}
+
+// newVPPSupervisor creates new instance of vppSupervisor, VPP is expected to be up (connected).
+func newVPPSupervisor(logger logging.Logger, config VPPSupervisionConfig, metrics *metrics,
+	newChannel func() (*api.Channel, error)) *vppSupervisor {
+	s := &vppSupervisor{
+		logger:           logger,
+		metrics:          metrics,
+		interval:         time.Duration(config.ProbeInterval) * time.Second,
+		failureThreshold: int(config.FailureThreshold),
+		maxBackoff:       time.Duration(config.MaxBackoff) * time.Second,
+		newChannel:       newChannel,
+		up:               true,
+	}
+	if s.interval == 0 {
+		s.interval = defaultVPPProbeInterval
+	}
+	if s.failureThreshold == 0 {
+		s.failureThreshold = defaultVPPFailureThreshold
+	}
+	if s.maxBackoff == 0 {
+		s.maxBackoff = defaultVPPMaxBackoff
+	}
+	if s.maxBackoff < s.interval {
+		s.maxBackoff = s.interval
+	}
+	s.ping = s.controlPing
+	return s
+}
+
+// run probes VPP until the context is cancelled.
+func (s *vppSupervisor) run(ctx context.Context) {
+	s.metrics.vppAPIState(true)
+	for {
+		select {
+		case <-time.After(s.nextProbe()):
+			s.probe()
+		case <-ctx.Done():
+			if s.ch != nil {
+				s.ch.Close()
+			}
+			return
+		}
+	}
+}
+
+// nextProbe returns the time until the next probe, doubled with each failure while VPP is down.
+func (s *vppSupervisor) nextProbe() time.Duration {
+	if s.up {
+		return s.interval
+	}
+	backoff := s.interval
+	for i := s.failureThreshold; i < s.failures && backoff < s.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.maxBackoff {
+		backoff = s.maxBackoff
+	}
+	return backoff
+}
+
+// probe sends a single probe and updates the state of VPP.
+func (s *vppSupervisor) probe() {
+	pid, err := s.ping()
+	if err != nil {
+		s.failures++
+		if s.up && s.failures >= s.failureThreshold {
+			s.up = false
+			s.down = time.Now()
+			s.metrics.vppAPIState(false)
+			s.logger.Errorf("VPP binary API is not responding (%d failed probes): %v", s.failures, err)
+		}
+		return
+	}
+
+	if !s.up {
+		s.logger.Infof("VPP binary API is responding again after %v", time.Since(s.down).Round(time.Second))
+		s.metrics.vppAPIState(true)
+	}
+	s.up = true
+	s.failures = 0
+
+	if s.pid != 0 && pid != s.pid {
+		s.metrics.vppRestart()
+		s.logger.Warnf("VPP was restarted (PID %d -> %d), restarting the agent to resync the configuration", s.pid, pid)
+		if err := restartAgent(); err != nil {
+			s.logger.Errorf("Failed to restart the agent: %v", err)
+		}
+	}
+	s.pid = pid
+}
+
+// controlPing sends control ping to VPP and returns the PID of VPP from the reply.
+func (s *vppSupervisor) controlPing() (pid uint32, err error) {
+	if s.ch == nil {
+		if s.ch, err = s.newChannel(); err != nil {
+			return 0, err
+		}
+	}
+	reply := &vpe.ControlPingReply{}
+	err = s.ch.SendRequest(&vpe.ControlPing{}).ReceiveReply(reply)
+	if err == nil && reply.Retval != 0 {
+		err = fmt.Errorf("%s returned %d", reply.GetMessageName(), reply.Retval)
+	}
+	if err != nil {
+		s.metrics.vppAPIError((&vpe.ControlPing{}).GetMessageName())
+		s.ch.Close()
+		s.ch = nil
+		return 0, err
+	}
+	return reply.VpePid, nil
+}
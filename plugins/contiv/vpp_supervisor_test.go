@@ -0,0 +1,72 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contiv
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+)
+
+func TestVPPSupervisor(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	restarts := 0
+	origRestartAgent := restartAgent
+	restartAgent = func() error {
+		restarts++
+		return nil
+	}
+	defer func() { restartAgent = origRestartAgent }()
+
+	s := newVPPSupervisor(logrus.DefaultLogger(), VPPSupervisionConfig{MaxBackoff: 5}, nil, nil)
+	var pingErr error
+	var pid uint32 = 100
+	s.ping = func() (uint32, error) { return pid, pingErr }
+
+	s.probe()
+	gomega.Expect(s.up).To(gomega.BeTrue())
+	gomega.Expect(s.nextProbe()).To(gomega.Equal(defaultVPPProbeInterval))
+
+	// VPP is down after the threshold of failed probes, the probes back off up to the max.
+	pingErr = errors.New("timeout")
+	for i := 0; i < defaultVPPFailureThreshold-1; i++ {
+		s.probe()
+	}
+	gomega.Expect(s.up).To(gomega.BeTrue())
+	s.probe()
+	gomega.Expect(s.up).To(gomega.BeFalse())
+	gomega.Expect(s.nextProbe()).To(gomega.Equal(defaultVPPProbeInterval))
+	s.probe()
+	gomega.Expect(s.nextProbe()).To(gomega.Equal(2 * defaultVPPProbeInterval))
+	s.probe()
+	s.probe()
+	gomega.Expect(s.nextProbe()).To(gomega.Equal(5 * time.Second))
+
+	// recovery of the same VPP does not restart the agent
+	pingErr = nil
+	s.probe()
+	gomega.Expect(s.up).To(gomega.BeTrue())
+	gomega.Expect(s.failures).To(gomega.BeZero())
+	gomega.Expect(restarts).To(gomega.BeZero())
+
+	// restarted VPP restarts the agent
+	pid = 200
+	s.probe()
+	gomega.Expect(restarts).To(gomega.Equal(1))
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	networkingV1 "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sRuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/rest"
+)
+
+// The types below define the contivpp.io/v1 CustomNetworkPolicy resource,
+// registered into the cluster as a CustomResourceDefinition (see k8s/contiv-vpp.yaml).
+// The policy is cluster-scoped and applies to the host endpoints of the nodes
+// selected by the node selector. Peers are selected by IP blocks or by DNS names,
+// ports and IP blocks are defined the same way as in K8s network policies.
+
+// customPolicyGroupVersion is the API group and version of the reflected custom policies.
+var customPolicyGroupVersion = schema.GroupVersion{Group: "contivpp.io", Version: "v1"}
+
+// customPolicyResource is the plural name of the custom policy resource.
+const customPolicyResource = "customnetworkpolicies"
+
+// CustomNetworkPolicy describes what network traffic is allowed for the host
+// endpoints of a set of nodes.
+type CustomNetworkPolicy struct {
+	metaV1.TypeMeta   `json:",inline"`
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec is the specification of the desired behavior of the policy.
+	Spec CustomNetworkPolicySpec `json:"spec"`
+}
+
+// CustomNetworkPolicySpec provides the specification of a CustomNetworkPolicy.
+type CustomNetworkPolicySpec struct {
+	// NodeSelector selects the nodes to whose host endpoints the policy applies.
+	// An empty selector matches all nodes.
+	NodeSelector metaV1.LabelSelector `json:"nodeSelector"`
+	// Ingress is a list of ingress rules applied to the selected host endpoints.
+	Ingress []CustomNetworkPolicyIngressRule `json:"ingress,omitempty"`
+	// Egress is a list of egress rules applied to the selected host endpoints.
+	Egress []CustomNetworkPolicyEgressRule `json:"egress,omitempty"`
+	// PolicyTypes are the rule types that the policy relates to, with the same
+	// defaults as for K8s network policies.
+	PolicyTypes []networkingV1.PolicyType `json:"policyTypes,omitempty"`
+}
+
+// CustomNetworkPolicyIngressRule matches the traffic that matches both the ports and the sources.
+type CustomNetworkPolicyIngressRule struct {
+	Ports []networkingV1.NetworkPolicyPort `json:"ports,omitempty"`
+	From  []CustomNetworkPolicyPeer        `json:"from,omitempty"`
+}
+
+// CustomNetworkPolicyEgressRule matches the traffic that matches both the ports and the destinations.
+type CustomNetworkPolicyEgressRule struct {
+	Ports []networkingV1.NetworkPolicyPort `json:"ports,omitempty"`
+	To    []CustomNetworkPolicyPeer        `json:"to,omitempty"`
+}
+
+// CustomNetworkPolicyPeer selects a set of peers by IP addresses or by a DNS name
+// (e.g. "api.example.com"), resolved periodically by the agents into IP addresses.
+type CustomNetworkPolicyPeer struct {
+	IPBlock *networkingV1.IPBlock `json:"ipBlock,omitempty"`
+	FQDN    string                `json:"fqdn,omitempty"`
+}
+
+// CustomNetworkPolicyList represents a list of CustomNetworkPolicies.
+type CustomNetworkPolicyList struct {
+	metaV1.TypeMeta `json:",inline"`
+	metaV1.ListMeta `json:"metadata,omitempty"`
+
+	Items []CustomNetworkPolicy `json:"items"`
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *CustomNetworkPolicy) DeepCopyInto(out *CustomNetworkPolicy) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.NodeSelector.DeepCopyInto(&out.Spec.NodeSelector)
+	if in.Spec.Ingress != nil {
+		out.Spec.Ingress = make([]CustomNetworkPolicyIngressRule, len(in.Spec.Ingress))
+		for i := range in.Spec.Ingress {
+			out.Spec.Ingress[i].Ports = deepCopyPolicyPorts(in.Spec.Ingress[i].Ports)
+			out.Spec.Ingress[i].From = deepCopyPolicyPeers(in.Spec.Ingress[i].From)
+		}
+	}
+	if in.Spec.Egress != nil {
+		out.Spec.Egress = make([]CustomNetworkPolicyEgressRule, len(in.Spec.Egress))
+		for i := range in.Spec.Egress {
+			out.Spec.Egress[i].Ports = deepCopyPolicyPorts(in.Spec.Egress[i].Ports)
+			out.Spec.Egress[i].To = deepCopyPolicyPeers(in.Spec.Egress[i].To)
+		}
+	}
+	if in.Spec.PolicyTypes != nil {
+		out.Spec.PolicyTypes = append([]networkingV1.PolicyType(nil), in.Spec.PolicyTypes...)
+	}
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *CustomNetworkPolicy) DeepCopyObject() k8sRuntime.Object {
+	if in == nil {
+		return nil
+	}
+	out := &CustomNetworkPolicy{}
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *CustomNetworkPolicyList) DeepCopyObject() k8sRuntime.Object {
+	if in == nil {
+		return nil
+	}
+	out := &CustomNetworkPolicyList{}
+	*out = *in
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]CustomNetworkPolicy, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+	return out
+}
+
+func deepCopyPolicyPorts(in []networkingV1.NetworkPolicyPort) []networkingV1.NetworkPolicyPort {
+	if in == nil {
+		return nil
+	}
+	out := make([]networkingV1.NetworkPolicyPort, len(in))
+	for i := range in {
+		in[i].DeepCopyInto(&out[i])
+	}
+	return out
+}
+
+func deepCopyPolicyPeers(in []CustomNetworkPolicyPeer) []CustomNetworkPolicyPeer {
+	if in == nil {
+		return nil
+	}
+	out := make([]CustomNetworkPolicyPeer, len(in))
+	for i := range in {
+		out[i].FQDN = in[i].FQDN
+		if in[i].IPBlock != nil {
+			out[i].IPBlock = &networkingV1.IPBlock{}
+			in[i].IPBlock.DeepCopyInto(out[i].IPBlock)
+		}
+	}
+	return out
+}
+
+// newCustomPolicyClient builds REST client for the contivpp.io API group.
+func newCustomPolicyClient(config *rest.Config) (rest.Interface, error) {
+	scheme := k8sRuntime.NewScheme()
+	scheme.AddKnownTypes(customPolicyGroupVersion, &CustomNetworkPolicy{}, &CustomNetworkPolicyList{})
+	metaV1.AddToGroupVersion(scheme, customPolicyGroupVersion)
+
+	policyConfig := *config
+	policyConfig.GroupVersion = &customPolicyGroupVersion
+	policyConfig.APIPath = "/apis"
+	policyConfig.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)}
+	if policyConfig.UserAgent == "" {
+		policyConfig.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+	return rest.RESTClientFor(&policyConfig)
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	coreV1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/contiv/vpp/plugins/ksr/model/custompolicy"
+)
+
+// CustomPolicyReflector subscribes to K8s cluster to watch for changes
+// in the configuration of Contiv custom network policies (CRD).
+// Protobuf-modelled changes are published into the selected key-value store.
+type CustomPolicyReflector struct {
+	Reflector
+
+	// K8sPolicyClient is REST client for the contivpp.io API group.
+	K8sPolicyClient rest.Interface
+}
+
+// Init subscribes to K8s cluster to watch for changes in the configuration
+// of custom network policies. The subscription does not become active until
+// Start() is called.
+func (cpr *CustomPolicyReflector) Init(stopCh2 <-chan struct{}, wg *sync.WaitGroup) error {
+	customPolicyReflectorFuncs := ReflectorFunctions{
+		EventHdlrFunc: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				cpr.addCustomPolicy(obj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				cpr.deleteCustomPolicy(obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				cpr.updateCustomPolicy(oldObj, newObj)
+			},
+		},
+		ProtoAllocFunc: func() proto.Message {
+			return &custompolicy.CustomNetworkPolicy{}
+		},
+		K8s2NodeFunc: func(k8sObj interface{}) (interface{}, string, bool) {
+			k8sPolicy, ok := k8sObj.(*CustomNetworkPolicy)
+			if !ok {
+				cpr.Log.Errorf("custom policy syncDataStore: wrong object type %s, obj %+v",
+					reflect.TypeOf(k8sObj), k8sObj)
+				return nil, "", false
+			}
+			return cpr.customPolicyToProto(k8sPolicy), custompolicy.Key(k8sPolicy.Name), true
+		},
+		K8sClntGetFunc: func(*kubernetes.Clientset) rest.Interface {
+			return cpr.K8sPolicyClient
+		},
+	}
+
+	return cpr.ksrInit(stopCh2, wg, custompolicy.KeyPrefix(), customPolicyResource,
+		&CustomNetworkPolicy{}, customPolicyReflectorFuncs)
+}
+
+// addCustomPolicy adds state data of a newly created custom policy into the data
+// store.
+func (cpr *CustomPolicyReflector) addCustomPolicy(obj interface{}) {
+	cpr.Log.WithField("customPolicy", obj).Info("Custom policy added")
+
+	k8sPolicy, ok := obj.(*CustomNetworkPolicy)
+	if !ok {
+		cpr.Log.Warn("Failed to cast newly created custom policy object")
+		cpr.stats.ArgErrors++
+		return
+	}
+
+	key := custompolicy.Key(k8sPolicy.GetName())
+	cpr.ksrAdd(key, cpr.customPolicyToProto(k8sPolicy))
+}
+
+// deleteCustomPolicy deletes state data of a removed custom policy from the data
+// store.
+func (cpr *CustomPolicyReflector) deleteCustomPolicy(obj interface{}) {
+	cpr.Log.WithField("customPolicy", obj).Info("Custom policy removed")
+
+	k8sPolicy, ok := obj.(*CustomNetworkPolicy)
+	if !ok {
+		cpr.Log.Warn("Failed to cast removed custom policy object")
+		cpr.stats.ArgErrors++
+		return
+	}
+
+	key := custompolicy.Key(k8sPolicy.GetName())
+	cpr.ksrDelete(key)
+}
+
+// updateCustomPolicy updates state data of a changed custom policy in the data
+// store.
+func (cpr *CustomPolicyReflector) updateCustomPolicy(oldObj, newObj interface{}) {
+	oldK8sPolicy, ok1 := oldObj.(*CustomNetworkPolicy)
+	newK8sPolicy, ok2 := newObj.(*CustomNetworkPolicy)
+	if !ok1 || !ok2 {
+		cpr.Log.Warn("Failed to cast changed custom policy object")
+		cpr.stats.ArgErrors++
+		return
+	}
+	cpr.Log.WithFields(map[string]interface{}{"customPolicy-old": oldK8sPolicy, "customPolicy-new": newK8sPolicy}).
+		Info("Custom policy updated")
+
+	oldPolicyProto := cpr.customPolicyToProto(oldK8sPolicy)
+	newPolicyProto := cpr.customPolicyToProto(newK8sPolicy)
+	key := custompolicy.Key(newK8sPolicy.GetName())
+	cpr.ksrUpdate(key, oldPolicyProto, newPolicyProto)
+}
+
+// customPolicyToProto converts custom policy data from the k8s representation
+// into our protobuf-modelled data structure.
+func (cpr *CustomPolicyReflector) customPolicyToProto(k8sPolicy *CustomNetworkPolicy) *custompolicy.CustomNetworkPolicy {
+	policyProto := &custompolicy.CustomNetworkPolicy{}
+
+	// Name
+	policyProto.Name = k8sPolicy.GetName()
+
+	// Labels
+	for key, val := range k8sPolicy.GetLabels() {
+		policyProto.Label = append(policyProto.Label, &custompolicy.CustomNetworkPolicy_Label{Key: key, Value: val})
+	}
+	sort.Slice(policyProto.Label, func(i, j int) bool {
+		return policyProto.Label[i].Key < policyProto.Label[j].Key
+	})
+
+	// Node selector
+	policyProto.Nodes = cpr.labelSelectorToProto(&k8sPolicy.Spec.NodeSelector)
+
+	// Policy type
+	hasIngress, hasEgress := false, false
+	for _, policyType := range k8sPolicy.Spec.PolicyTypes {
+		switch policyType {
+		case networkingV1.PolicyTypeIngress:
+			hasIngress = true
+		case networkingV1.PolicyTypeEgress:
+			hasEgress = true
+		}
+	}
+	switch {
+	case hasIngress && hasEgress:
+		policyProto.PolicyType = custompolicy.CustomNetworkPolicy_INGRESS_AND_EGRESS
+	case hasIngress:
+		policyProto.PolicyType = custompolicy.CustomNetworkPolicy_INGRESS
+	case hasEgress:
+		policyProto.PolicyType = custompolicy.CustomNetworkPolicy_EGRESS
+	case len(k8sPolicy.Spec.Egress) > 0:
+		// Resolved here, egress rules that match nothing are not reflected.
+		policyProto.PolicyType = custompolicy.CustomNetworkPolicy_INGRESS_AND_EGRESS
+	}
+
+	// Rules
+	for _, ingress := range k8sPolicy.Spec.Ingress {
+		if rule := cpr.ruleToProto(k8sPolicy.Name, ingress.Ports, ingress.From); rule != nil {
+			policyProto.IngressRule = append(policyProto.IngressRule, rule)
+		}
+	}
+	for _, egress := range k8sPolicy.Spec.Egress {
+		if rule := cpr.ruleToProto(k8sPolicy.Name, egress.Ports, egress.To); rule != nil {
+			policyProto.EgressRule = append(policyProto.EgressRule, rule)
+		}
+	}
+
+	return policyProto
+}
+
+// ruleToProto converts ingress or egress rule from the k8s representation into
+// our protobuf-modelled data structure. Returns nil if none of the listed ports
+// or peers can be converted, i.e. the rule matches nothing.
+func (cpr *CustomPolicyReflector) ruleToProto(policy string, ports []networkingV1.NetworkPolicyPort,
+	peers []CustomNetworkPolicyPeer) *custompolicy.CustomNetworkPolicy_Rule {
+
+	rule := &custompolicy.CustomNetworkPolicy_Rule{
+		Port: cpr.portsToProto(policy, ports),
+		Peer: cpr.peersToProto(peers),
+		Fqdn: cpr.fqdnsToProto(peers),
+	}
+	if (len(ports) > 0 && len(rule.Port) == 0) || (len(peers) > 0 && len(rule.Peer) == 0 && len(rule.Fqdn) == 0) {
+		cpr.Log.WithField("customPolicy", policy).Warn("Skipping rule that matches nothing")
+		return nil
+	}
+	return rule
+}
+
+// labelSelectorToProto converts label selector from the k8s representation into
+// our protobuf-modelled data structure.
+func (cpr *CustomPolicyReflector) labelSelectorToProto(selector *metaV1.LabelSelector) *custompolicy.CustomNetworkPolicy_LabelSelector {
+	selectorProto := &custompolicy.CustomNetworkPolicy_LabelSelector{}
+	// MatchLabels
+	for key, val := range selector.MatchLabels {
+		selectorProto.MatchLabel = append(selectorProto.MatchLabel,
+			&custompolicy.CustomNetworkPolicy_Label{Key: key, Value: val})
+	}
+	// MatchExpressions
+	for _, expression := range selector.MatchExpressions {
+		expressionProto := &custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression{
+			Key:   expression.Key,
+			Value: append([]string(nil), expression.Values...),
+		}
+		switch expression.Operator {
+		case metaV1.LabelSelectorOpIn:
+			expressionProto.Operator = custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_IN
+		case metaV1.LabelSelectorOpNotIn:
+			expressionProto.Operator = custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_NOT_IN
+		case metaV1.LabelSelectorOpExists:
+			expressionProto.Operator = custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_EXISTS
+		case metaV1.LabelSelectorOpDoesNotExist:
+			expressionProto.Operator = custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_DOES_NOT_EXIST
+		}
+		selectorProto.MatchExpression = append(selectorProto.MatchExpression, expressionProto)
+	}
+
+	// Make sure that match labels are always stored in the same order to avoid
+	// unnecessary updates during resync.
+	sort.Slice(selectorProto.MatchLabel, func(i, j int) bool {
+		return selectorProto.MatchLabel[i].Key < selectorProto.MatchLabel[j].Key
+	})
+
+	return selectorProto
+}
+
+// portsToProto converts a list of ports from the k8s representation into
+// our protobuf-modelled data structure. Host endpoints do not define any named
+// ports, ports referenced by name are therefore skipped.
+func (cpr *CustomPolicyReflector) portsToProto(policy string, ports []networkingV1.NetworkPolicyPort) (portsProto []*custompolicy.CustomNetworkPolicy_Port) {
+	for _, port := range ports {
+		portProto := &custompolicy.CustomNetworkPolicy_Port{}
+		// Protocol
+		if port.Protocol != nil {
+			switch *port.Protocol {
+			case coreV1.ProtocolTCP:
+				portProto.Protocol = custompolicy.CustomNetworkPolicy_Port_TCP
+			case coreV1.ProtocolUDP:
+				portProto.Protocol = custompolicy.CustomNetworkPolicy_Port_UDP
+			case protocolSCTP:
+				portProto.Protocol = custompolicy.CustomNetworkPolicy_Port_SCTP
+			}
+		}
+		// Port number
+		if port.Port != nil {
+			if port.Port.Type == intstr.String {
+				cpr.Log.WithField("customPolicy", policy).
+					Warnf("Skipping port %s referenced by name", port.Port.StrVal)
+				continue
+			}
+			portProto.Port = port.Port.IntVal
+		}
+		portsProto = append(portsProto, portProto)
+	}
+	return portsProto
+}
+
+// peersToProto converts a list of peers from the k8s representation into
+// our protobuf-modelled data structure.
+func (cpr *CustomPolicyReflector) peersToProto(peers []CustomNetworkPolicyPeer) (peersProto []*custompolicy.CustomNetworkPolicy_IPBlock) {
+	for _, peer := range peers {
+		if peer.IPBlock == nil {
+			continue
+		}
+		peersProto = append(peersProto, &custompolicy.CustomNetworkPolicy_IPBlock{
+			Cidr:   peer.IPBlock.CIDR,
+			Except: append([]string(nil), peer.IPBlock.Except...),
+		})
+	}
+	return peersProto
+}
+
+// fqdnsToProto returns the DNS names of the peers selected by name, normalized
+// to lower case without the trailing dot.
+func (cpr *CustomPolicyReflector) fqdnsToProto(peers []CustomNetworkPolicyPeer) (fqdns []string) {
+	for _, peer := range peers {
+		fqdn := strings.ToLower(strings.TrimSuffix(peer.FQDN, "."))
+		if fqdn == "" {
+			continue
+		}
+		fqdns = append(fqdns, fqdn)
+	}
+	return fqdns
+}
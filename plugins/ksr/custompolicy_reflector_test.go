@@ -0,0 +1,274 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	coreV1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/contiv/vpp/plugins/ksr/model/custompolicy"
+	"github.com/ligato/cn-infra/flavors/local"
+)
+
+type CustomPolicyTestVars struct {
+	k8sListWatch         *mockK8sListWatch
+	mockKvBroker         *mockKeyProtoValBroker
+	customPolicyRefl     *CustomPolicyReflector
+	customPolicyTestData []CustomNetworkPolicy
+}
+
+var cpTestVars CustomPolicyTestVars
+
+func newTestCustomPolicy(name string, sshPort int32, sshFrom string) CustomNetworkPolicy {
+	protocolTCP := coreV1.ProtocolTCP
+	protocolUDP := coreV1.ProtocolUDP
+	ssh := intstr.FromInt(int(sshPort))
+	dns := intstr.FromInt(53)
+	named := intstr.FromString("metrics")
+
+	return CustomNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"role": "hosts", "app": "contiv"},
+		},
+		Spec: CustomNetworkPolicySpec{
+			NodeSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"zone": "dmz", "env": "prod"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "node-role.kubernetes.io/master",
+						Operator: metav1.LabelSelectorOpDoesNotExist,
+					},
+				},
+			},
+			Ingress: []CustomNetworkPolicyIngressRule{
+				{
+					Ports: []networkingV1.NetworkPolicyPort{{Protocol: &protocolTCP, Port: &ssh}},
+					From: []CustomNetworkPolicyPeer{
+						{IPBlock: &networkingV1.IPBlock{CIDR: sshFrom, Except: []string{"10.0.0.0/24"}}},
+					},
+				},
+				{
+					// matches nothing - not reflected
+					Ports: []networkingV1.NetworkPolicyPort{{Port: &named}},
+				},
+			},
+			Egress: []CustomNetworkPolicyEgressRule{
+				{
+					Ports: []networkingV1.NetworkPolicyPort{{Protocol: &protocolUDP, Port: &dns}},
+				},
+			},
+		},
+	}
+}
+
+func TestCustomPolicyReflector(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	flavorLocal := &local.FlavorLocal{}
+	flavorLocal.Inject()
+
+	cpTestVars.k8sListWatch = &mockK8sListWatch{}
+	cpTestVars.mockKvBroker = newMockKeyProtoValBroker()
+
+	cpTestVars.customPolicyRefl = &CustomPolicyReflector{
+		Reflector: Reflector{
+			Log:          flavorLocal.LoggerFor("custompolicy-reflector"),
+			K8sClientset: &kubernetes.Clientset{},
+			K8sListWatch: cpTestVars.k8sListWatch,
+			Broker:       cpTestVars.mockKvBroker,
+			dsSynced:     false,
+			objType:      customPolicyObjType,
+		},
+	}
+
+	cpTestVars.customPolicyTestData = []CustomNetworkPolicy{
+		newTestCustomPolicy("allow-ssh", 22, "10.0.0.0/8"),
+		newTestCustomPolicy("allow-ssh-alt", 2222, "172.16.0.0/12"),
+		newTestCustomPolicy("stale", 22, "0.0.0.0/0"),
+	}
+
+	// The mock function returns two K8s mock custom policies already
+	// in the K8s cache.
+	MockK8sCache.ListFunc = func() []interface{} {
+		return []interface{}{
+			&cpTestVars.customPolicyTestData[0],
+			&cpTestVars.customPolicyTestData[1],
+		}
+	}
+
+	// Pre-populate the mock data store with pre-existing data that is supposed
+	// to be updated during the test.
+	policyProto := cpTestVars.customPolicyRefl.customPolicyToProto(&cpTestVars.customPolicyTestData[1])
+	policyProto.IngressRule[0].Peer[0].Cidr = "1.2.3.0/24"
+	cpTestVars.mockKvBroker.Put(custompolicy.Key("allow-ssh-alt"), policyProto)
+
+	// Pre-populate the mock data store with "stale" data that is supposed to
+	// be deleted during the test.
+	cpTestVars.mockKvBroker.Put(custompolicy.Key("stale"),
+		cpTestVars.customPolicyRefl.customPolicyToProto(&cpTestVars.customPolicyTestData[2]))
+
+	statsBefore := *cpTestVars.customPolicyRefl.GetStats()
+
+	stopCh := make(chan struct{})
+	var wg sync.WaitGroup
+	err := cpTestVars.customPolicyRefl.Init(stopCh, &wg)
+	gomega.Expect(err).To(gomega.BeNil())
+
+	cpTestVars.customPolicyRefl.startDataStoreResync()
+
+	// Wait for the initial sync to finish
+	for {
+		if cpTestVars.customPolicyRefl.HasSynced() {
+			break
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+
+	statsAfter := *cpTestVars.customPolicyRefl.GetStats()
+
+	gomega.Expect(cpTestVars.mockKvBroker.ds).Should(gomega.HaveLen(2))
+	gomega.Expect(statsBefore.Adds + 1).Should(gomega.BeNumerically("==", statsAfter.Adds))
+	gomega.Expect(statsBefore.Updates + 1).Should(gomega.BeNumerically("==", statsAfter.Updates))
+	gomega.Expect(statsBefore.Deletes + 1).Should(gomega.BeNumerically("==", statsAfter.Deletes))
+
+	cpTestVars.mockKvBroker.ClearDs()
+
+	t.Run("customPolicyToProto", testCustomPolicyToProto)
+	t.Run("addDeleteCustomPolicy", testAddDeleteCustomPolicy)
+	t.Run("updateCustomPolicy", testUpdateCustomPolicy)
+}
+
+func testCustomPolicyToProto(t *testing.T) {
+	policyProto := cpTestVars.customPolicyRefl.customPolicyToProto(&cpTestVars.customPolicyTestData[0])
+
+	gomega.Expect(policyProto.Name).To(gomega.Equal("allow-ssh"))
+	gomega.Expect(policyProto.Label).To(gomega.HaveLen(2))
+	gomega.Expect(policyProto.Label[0].Key).To(gomega.Equal("app"))
+
+	// node selector (match labels sorted)
+	gomega.Expect(policyProto.Nodes.MatchLabel).To(gomega.HaveLen(2))
+	gomega.Expect(policyProto.Nodes.MatchLabel[0].Key).To(gomega.Equal("env"))
+	gomega.Expect(policyProto.Nodes.MatchLabel[1].Key).To(gomega.Equal("zone"))
+	gomega.Expect(policyProto.Nodes.MatchExpression).To(gomega.HaveLen(1))
+	gomega.Expect(policyProto.Nodes.MatchExpression[0].Operator).
+		To(gomega.Equal(custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_DOES_NOT_EXIST))
+
+	// policy type resolved from the presence of egress rules
+	gomega.Expect(policyProto.PolicyType).To(gomega.Equal(custompolicy.CustomNetworkPolicy_INGRESS_AND_EGRESS))
+
+	// the rule with the named port only is left out
+	gomega.Expect(policyProto.IngressRule).To(gomega.HaveLen(1))
+	gomega.Expect(policyProto.IngressRule[0].Port).To(gomega.HaveLen(1))
+	gomega.Expect(policyProto.IngressRule[0].Port[0].Protocol).To(gomega.Equal(custompolicy.CustomNetworkPolicy_Port_TCP))
+	gomega.Expect(policyProto.IngressRule[0].Port[0].Port).To(gomega.BeEquivalentTo(22))
+	gomega.Expect(policyProto.IngressRule[0].Peer).To(gomega.HaveLen(1))
+	gomega.Expect(policyProto.IngressRule[0].Peer[0].Cidr).To(gomega.Equal("10.0.0.0/8"))
+	gomega.Expect(policyProto.IngressRule[0].Peer[0].Except).To(gomega.Equal([]string{"10.0.0.0/24"}))
+
+	// egress rule without peers matches all destinations
+	gomega.Expect(policyProto.EgressRule).To(gomega.HaveLen(1))
+	gomega.Expect(policyProto.EgressRule[0].Port[0].Protocol).To(gomega.Equal(custompolicy.CustomNetworkPolicy_Port_UDP))
+	gomega.Expect(policyProto.EgressRule[0].Port[0].Port).To(gomega.BeEquivalentTo(53))
+	gomega.Expect(policyProto.EgressRule[0].Peer).To(gomega.BeEmpty())
+
+	// explicit policy types
+	k8sPolicy := cpTestVars.customPolicyTestData[0]
+	k8sPolicy.Spec.PolicyTypes = []networkingV1.PolicyType{networkingV1.PolicyTypeIngress}
+	policyProto = cpTestVars.customPolicyRefl.customPolicyToProto(&k8sPolicy)
+	gomega.Expect(policyProto.PolicyType).To(gomega.Equal(custompolicy.CustomNetworkPolicy_INGRESS))
+
+	// egress rule with peers selected by DNS names only
+	k8sPolicy.Spec.Egress = []CustomNetworkPolicyEgressRule{
+		{To: []CustomNetworkPolicyPeer{{FQDN: "API.example.com."}, {FQDN: "registry.example.com"}}},
+	}
+	policyProto = cpTestVars.customPolicyRefl.customPolicyToProto(&k8sPolicy)
+	gomega.Expect(policyProto.EgressRule).To(gomega.HaveLen(1))
+	gomega.Expect(policyProto.EgressRule[0].Peer).To(gomega.BeEmpty())
+	gomega.Expect(policyProto.EgressRule[0].Fqdn).To(gomega.Equal([]string{"api.example.com", "registry.example.com"}))
+}
+
+func testAddDeleteCustomPolicy(t *testing.T) {
+	k8sPolicy := &cpTestVars.customPolicyTestData[0]
+
+	// Take a snapshot of counters
+	adds := cpTestVars.customPolicyRefl.GetStats().Adds
+	argErrs := cpTestVars.customPolicyRefl.GetStats().ArgErrors
+
+	// Test add with wrong argument type
+	cpTestVars.k8sListWatch.Add(&k8sPolicy)
+
+	gomega.Expect(argErrs + 1).To(gomega.Equal(cpTestVars.customPolicyRefl.GetStats().ArgErrors))
+	gomega.Expect(adds).To(gomega.Equal(cpTestVars.customPolicyRefl.GetStats().Adds))
+
+	// Test add where everything should be good
+	cpTestVars.k8sListWatch.Add(k8sPolicy)
+	gomega.Expect(adds + 1).To(gomega.Equal(cpTestVars.customPolicyRefl.GetStats().Adds))
+
+	policyProto := &custompolicy.CustomNetworkPolicy{}
+	found, _, err := cpTestVars.mockKvBroker.GetValue(custompolicy.Key(k8sPolicy.Name), policyProto)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(policyProto.Name).To(gomega.Equal(k8sPolicy.Name))
+
+	// Test delete with wrong argument type
+	dels := cpTestVars.customPolicyRefl.GetStats().Deletes
+	cpTestVars.k8sListWatch.Delete(&k8sPolicy)
+	gomega.Expect(argErrs + 2).To(gomega.Equal(cpTestVars.customPolicyRefl.GetStats().ArgErrors))
+	gomega.Expect(dels).To(gomega.Equal(cpTestVars.customPolicyRefl.GetStats().Deletes))
+
+	// Test delete where everything should be good
+	cpTestVars.k8sListWatch.Delete(k8sPolicy)
+	gomega.Expect(dels + 1).To(gomega.Equal(cpTestVars.customPolicyRefl.GetStats().Deletes))
+
+	found, _, _ = cpTestVars.mockKvBroker.GetValue(custompolicy.Key(k8sPolicy.Name), policyProto)
+	gomega.Ω(found).ShouldNot(gomega.BeTrue())
+}
+
+func testUpdateCustomPolicy(t *testing.T) {
+	oldPolicy := &cpTestVars.customPolicyTestData[0]
+	newPolicy := newTestCustomPolicy(oldPolicy.Name, 2022, "192.168.0.0/16")
+
+	cpTestVars.k8sListWatch.Add(oldPolicy)
+	upd := cpTestVars.customPolicyRefl.GetStats().Updates
+
+	// Test update with wrong argument type
+	cpTestVars.k8sListWatch.Update(*oldPolicy, newPolicy)
+	gomega.Expect(upd).To(gomega.Equal(cpTestVars.customPolicyRefl.GetStats().Updates))
+
+	// Ensure that there is no update if old and new values are the same
+	cpTestVars.k8sListWatch.Update(oldPolicy, oldPolicy)
+	gomega.Expect(upd).To(gomega.Equal(cpTestVars.customPolicyRefl.GetStats().Updates))
+
+	// Test update where everything should be good
+	cpTestVars.k8sListWatch.Update(oldPolicy, &newPolicy)
+	gomega.Expect(upd + 1).To(gomega.Equal(cpTestVars.customPolicyRefl.GetStats().Updates))
+
+	policyProto := &custompolicy.CustomNetworkPolicy{}
+	found, _, err := cpTestVars.mockKvBroker.GetValue(custompolicy.Key(oldPolicy.Name), policyProto)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(policyProto.IngressRule[0].Port[0].Port).To(gomega.BeEquivalentTo(2022))
+	gomega.Expect(policyProto.IngressRule[0].Peer[0].Cidr).To(gomega.Equal("192.168.0.0/16"))
+}
@@ -1,3 +1,17 @@
 // Package ksr implements plugin that watches K8s resources and causes all
-// changes to be reflected in the ETCD data store.
+// changes to be reflected in the ETCD data store. In the opposite direction,
+// it annotates K8s pods as requested by Contiv agents through the data store
+// and K8s NetworkPolicies with the status of their enforcement published
+// by the agents. Failures reported by the agents are recorded as K8s Events
+// of the pods and nodes concerned (see EventRecorder).
+//
+// Endpoints of services are reflected from EndpointSlices (discovery.k8s.io)
+// if served by the K8s API server, since Endpoints objects of services with
+// many backends run into the object size limits. Otherwise, core Endpoints
+// are reflected. Either way, they are published as the same endpoints model.
+//
+// In small clusters, the reflectors may run directly inside the Contiv agents
+// instead of contiv-ksr (the direct K8s API mode, see StateStore). The K8s
+// state is then kept in memory of every agent and only the state shared
+// by the agents remains in the data store.
 package ksr
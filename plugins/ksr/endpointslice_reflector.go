@@ -0,0 +1,273 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/contiv/vpp/plugins/ksr/model/endpoints"
+	"github.com/golang/protobuf/proto"
+)
+
+// EndpointSliceReflector subscribes to K8s cluster to watch for changes
+// in the EndpointSlices of k8s services. All slices of a service are merged
+// into a single instance of the endpoints data model, one endpoint subset per
+// slice, therefore the consumers of the endpoints do not need to distinguish
+// between Endpoints and EndpointSlices.
+// Protobuf-modelled changes are published into the selected key-value store.
+type EndpointSliceReflector struct {
+	Reflector
+
+	// K8sSliceClient is REST client for the discovery.k8s.io API group.
+	K8sSliceClient rest.Interface
+}
+
+// Address types of EndpointSlices convertible into endpoint addresses.
+const (
+	sliceAddressTypeIP   = "IP"
+	sliceAddressTypeIPv4 = "IPv4"
+)
+
+// Init subscribes to K8s cluster to watch for changes in the EndpointSlices
+// of k8s services. The subscription does not become active until Start()
+// is called.
+func (esr *EndpointSliceReflector) Init(stopCh2 <-chan struct{}, wg *sync.WaitGroup) error {
+	sliceReflectorFuncs := ReflectorFunctions{
+		EventHdlrFunc: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				esr.addEndpointSlice(obj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				esr.deleteEndpointSlice(obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				esr.updateEndpointSlice(oldObj, newObj)
+			},
+		},
+		ProtoAllocFunc: func() proto.Message {
+			return &endpoints.Endpoints{}
+		},
+		K8s2NodeFunc: func(k8sObj interface{}) (interface{}, string, bool) {
+			slice, ok := k8sObj.(*EndpointSlice)
+			if !ok {
+				esr.Log.Errorf("endpoint slice syncDataStore: wrong object type %s, obj %+v",
+					reflect.TypeOf(k8sObj), k8sObj)
+				return nil, "", false
+			}
+			service, found := slice.Labels[endpointSliceServiceLabel]
+			if !found {
+				return nil, "", false
+			}
+			slices := esr.serviceSlices(service, slice.Namespace)
+			return esr.slicesToProto(service, slice.Namespace, slices), endpoints.Key(service, slice.Namespace), true
+		},
+		K8sClntGetFunc: func(*kubernetes.Clientset) rest.Interface {
+			return esr.K8sSliceClient
+		},
+	}
+
+	return esr.ksrInit(stopCh2, wg, endpoints.KeyPrefix(), "endpointslices", &EndpointSlice{}, sliceReflectorFuncs)
+}
+
+// addEndpointSlice updates the endpoints of the service of a newly created
+// K8s EndpointSlice in the data store.
+func (esr *EndpointSliceReflector) addEndpointSlice(obj interface{}) {
+	slice, ok := obj.(*EndpointSlice)
+	if !ok {
+		esr.Log.Warn("Failed to cast newly created endpoint slice object")
+		esr.stats.ArgErrors++
+		return
+	}
+	service, found := slice.Labels[endpointSliceServiceLabel]
+	if !found {
+		// Not managed on behalf of a service.
+		return
+	}
+
+	esr.Log.WithField("endpointSlice", obj).Info("addEndpointSlice")
+	slices := withSlice(esr.serviceSlices(service, slice.Namespace), slice)
+	key := endpoints.Key(service, slice.Namespace)
+	esr.ksrAdd(key, esr.slicesToProto(service, slice.Namespace, slices))
+}
+
+// deleteEndpointSlice updates the endpoints of the service of a removed K8s
+// EndpointSlice in the data store. The endpoints are removed together with
+// the last slice of the service.
+func (esr *EndpointSliceReflector) deleteEndpointSlice(obj interface{}) {
+	slice, ok := obj.(*EndpointSlice)
+	if !ok {
+		esr.Log.Warn("Failed to cast removed endpoint slice object")
+		esr.stats.ArgErrors++
+		return
+	}
+	service, found := slice.Labels[endpointSliceServiceLabel]
+	if !found {
+		return
+	}
+
+	esr.Log.WithField("endpointSlice", obj).Info("deleteEndpointSlice")
+	slices := esr.serviceSlices(service, slice.Namespace)
+	key := endpoints.Key(service, slice.Namespace)
+	remaining := withoutSlice(slices, slice)
+	if len(remaining) == 0 {
+		esr.ksrDelete(key)
+		return
+	}
+	esr.ksrUpdate(key, esr.slicesToProto(service, slice.Namespace, withSlice(slices, slice)),
+		esr.slicesToProto(service, slice.Namespace, remaining))
+}
+
+// updateEndpointSlice updates the endpoints of the service of a changed K8s
+// EndpointSlice in the data store.
+func (esr *EndpointSliceReflector) updateEndpointSlice(oldObj, newObj interface{}) {
+	sliceOld, ok1 := oldObj.(*EndpointSlice)
+	sliceNew, ok2 := newObj.(*EndpointSlice)
+	if !ok1 || !ok2 {
+		esr.Log.Warn("Failed to cast changed endpoint slice object")
+		esr.stats.ArgErrors++
+		return
+	}
+	serviceOld, foundOld := sliceOld.Labels[endpointSliceServiceLabel]
+	serviceNew, foundNew := sliceNew.Labels[endpointSliceServiceLabel]
+	if foundOld && (!foundNew || serviceOld != serviceNew) {
+		// The slice no longer belongs to the original service.
+		esr.deleteEndpointSlice(sliceOld)
+	}
+	if !foundNew {
+		return
+	}
+
+	esr.Log.WithFields(map[string]interface{}{"endpointSlice-old": sliceOld, "endpointSlice-new": sliceNew}).
+		Info("EndpointSlice updated")
+
+	slices := esr.serviceSlices(serviceNew, sliceNew.Namespace)
+	key := endpoints.Key(serviceNew, sliceNew.Namespace)
+	if !foundOld || serviceOld != serviceNew {
+		esr.ksrAdd(key, esr.slicesToProto(serviceNew, sliceNew.Namespace, withSlice(slices, sliceNew)))
+		return
+	}
+	esr.ksrUpdate(key, esr.slicesToProto(serviceNew, sliceNew.Namespace, withSlice(slices, sliceOld)),
+		esr.slicesToProto(serviceNew, sliceNew.Namespace, withSlice(slices, sliceNew)))
+}
+
+// serviceSlices returns all EndpointSlices of the given service found in the K8s cache.
+func (esr *EndpointSliceReflector) serviceSlices(service, namespace string) []*EndpointSlice {
+	var slices []*EndpointSlice
+	for _, obj := range esr.k8sStore.List() {
+		slice, ok := obj.(*EndpointSlice)
+		if !ok || slice.Namespace != namespace || slice.Labels[endpointSliceServiceLabel] != service {
+			continue
+		}
+		slices = append(slices, slice)
+	}
+	return slices
+}
+
+// withSlice returns the slices with the slice of the same name replaced by the given one
+// (or the given slice appended if not present).
+func withSlice(slices []*EndpointSlice, slice *EndpointSlice) []*EndpointSlice {
+	return append(withoutSlice(slices, slice), slice)
+}
+
+// withoutSlice returns the slices without the slice of the same name as the given one.
+func withoutSlice(slices []*EndpointSlice, slice *EndpointSlice) []*EndpointSlice {
+	var others []*EndpointSlice
+	for _, s := range slices {
+		if s.Name != slice.Name {
+			others = append(others, s)
+		}
+	}
+	return others
+}
+
+// slicesToProto merges the EndpointSlices of a service into our protobuf-modelled
+// endpoints, each slice is converted into a separate endpoint subset.
+func (esr *EndpointSliceReflector) slicesToProto(service, namespace string, slices []*EndpointSlice) *endpoints.Endpoints {
+	epsProto := &endpoints.Endpoints{}
+	epsProto.Name = service
+	epsProto.Namespace = namespace
+
+	sorted := append([]*EndpointSlice(nil), slices...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var subsets []*endpoints.EndpointSubset
+	for _, slice := range sorted {
+		if slice.AddressType != sliceAddressTypeIPv4 && slice.AddressType != sliceAddressTypeIP {
+			esr.Log.WithField("endpointSlice", slice.Name).
+				Warnf("Skipping endpoint slice with unsupported address type %s", slice.AddressType)
+			continue
+		}
+		pss := &endpoints.EndpointSubset{}
+
+		for _, ep := range slice.Endpoints {
+			for _, ip := range ep.Addresses {
+				addr := sliceAddressToProto(ip, &ep)
+				if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+					pss.Addresses = append(pss.Addresses, addr)
+				} else {
+					pss.NotReadyAddresses = append(pss.NotReadyAddresses, addr)
+				}
+			}
+		}
+
+		for _, port := range slice.Ports {
+			portProto := &endpoints.EndpointSubset_EndpointPort{
+				Protocol: string(coreV1.ProtocolTCP),
+			}
+			if port.Name != nil {
+				portProto.Name = *port.Name
+			}
+			if port.Port != nil {
+				portProto.Port = *port.Port
+			}
+			if port.Protocol != nil {
+				portProto.Protocol = string(*port.Protocol)
+			}
+			pss.Ports = append(pss.Ports, portProto)
+		}
+
+		subsets = append(subsets, pss)
+	}
+
+	epsProto.EndpointSubsets = subsets
+
+	return epsProto
+}
+
+// sliceAddressToProto converts an address of an EndpointSlice endpoint into
+// our protobuf-modelled data structure.
+func sliceAddressToProto(ip string, ep *SliceEndpoint) *endpoints.EndpointSubset_EndpointAddress {
+	addr := &coreV1.EndpointAddress{
+		IP:        ip,
+		NodeName:  ep.NodeName,
+		TargetRef: ep.TargetRef,
+	}
+	if ep.Hostname != nil {
+		addr.Hostname = *ep.Hostname
+	}
+	if addr.NodeName == nil {
+		if nodeName, found := ep.Topology[endpointSliceHostnameTopology]; found {
+			addr.NodeName = &nodeName
+		}
+	}
+	return addressToProto(addr)
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/contiv/vpp/plugins/ksr/model/endpoints"
+	"github.com/ligato/cn-infra/flavors/local"
+)
+
+type EndpointSliceTestVars struct {
+	k8sListWatch   *mockK8sListWatch
+	mockKvBroker   *mockKeyProtoValBroker
+	sliceReflector *EndpointSliceReflector
+	sliceTestData  []EndpointSlice
+	k8sCache       []interface{}
+}
+
+var sliceTestVars EndpointSliceTestVars
+
+func newTestEndpointSlice(name, service string, ready bool, ips ...string) EndpointSlice {
+	nodeName := "cvpp"
+	portName := "http"
+	port := int32(80)
+	protocol := coreV1.ProtocolTCP
+
+	slice := EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{endpointSliceServiceLabel: service},
+		},
+		AddressType: sliceAddressTypeIPv4,
+		Ports: []SliceEndpointPort{
+			{
+				Name:     &portName,
+				Port:     &port,
+				Protocol: &protocol,
+			},
+		},
+	}
+	for _, ip := range ips {
+		slice.Endpoints = append(slice.Endpoints, SliceEndpoint{
+			Addresses:  []string{ip},
+			Conditions: SliceEndpointConditions{Ready: &ready},
+			NodeName:   &nodeName,
+			TargetRef: &coreV1.ObjectReference{
+				Kind:      "Pod",
+				Namespace: "default",
+				Name:      name + "-" + ip,
+			},
+		})
+	}
+	return slice
+}
+
+func TestEndpointSliceReflector(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	flavorLocal := &local.FlavorLocal{}
+	flavorLocal.Inject()
+
+	sliceTestVars.k8sListWatch = &mockK8sListWatch{}
+	sliceTestVars.mockKvBroker = newMockKeyProtoValBroker()
+
+	sliceTestVars.sliceReflector = &EndpointSliceReflector{
+		Reflector: Reflector{
+			Log:          flavorLocal.LoggerFor("endpointslice-reflector"),
+			K8sClientset: &kubernetes.Clientset{},
+			K8sListWatch: sliceTestVars.k8sListWatch,
+			Broker:       sliceTestVars.mockKvBroker,
+			dsSynced:     false,
+			objType:      sliceObjType,
+		},
+	}
+
+	sliceTestVars.sliceTestData = []EndpointSlice{
+		// Test data 0, 1: two slices of the same service
+		newTestEndpointSlice("my-nginx-abcde", "my-nginx", true, "192.168.49.80", "192.168.49.81"),
+		newTestEndpointSlice("my-nginx-fghij", "my-nginx", false, "192.168.49.82"),
+		// Test data 2: slice of a service pre-existing in the data store
+		newTestEndpointSlice("calico-etcd-klmno", "calico-etcd", true, "10.0.2.15"),
+		// Test data 3: "stale" data in the data store
+		newTestEndpointSlice("kube-dns-pqrst", "kube-dns", true, "10.1.1.2"),
+	}
+
+	sliceTestVars.k8sCache = []interface{}{
+		&sliceTestVars.sliceTestData[0],
+		&sliceTestVars.sliceTestData[1],
+		&sliceTestVars.sliceTestData[2],
+	}
+	MockK8sCache.ListFunc = func() []interface{} {
+		return sliceTestVars.k8sCache
+	}
+
+	// Pre-populate the mock data store with pre-existing data that is supposed
+	// to be updated during the test.
+	slice2 := &sliceTestVars.sliceTestData[2]
+	proto2 := sliceTestVars.sliceReflector.slicesToProto("calico-etcd", "default", []*EndpointSlice{slice2})
+	proto2.EndpointSubsets[0].Addresses[0].Ip = "1.2.3.4"
+	sliceTestVars.mockKvBroker.Put(endpoints.Key("calico-etcd", "default"), proto2)
+
+	// Pre-populate the mock data store with "stale" data that is supposed to
+	// be deleted during the test.
+	slice3 := &sliceTestVars.sliceTestData[3]
+	sliceTestVars.mockKvBroker.Put(endpoints.Key("kube-dns", "default"),
+		sliceTestVars.sliceReflector.slicesToProto("kube-dns", "default", []*EndpointSlice{slice3}))
+
+	statsBefore := *sliceTestVars.sliceReflector.GetStats()
+
+	stopCh := make(chan struct{})
+	var wg sync.WaitGroup
+	err := sliceTestVars.sliceReflector.Init(stopCh, &wg)
+	gomega.Expect(err).To(gomega.BeNil())
+
+	sliceTestVars.sliceReflector.startDataStoreResync()
+
+	// Wait for the initial sync to finish
+	for {
+		if sliceTestVars.sliceReflector.HasSynced() {
+			break
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+
+	statsAfter := *sliceTestVars.sliceReflector.GetStats()
+
+	gomega.Expect(sliceTestVars.mockKvBroker.ds).Should(gomega.HaveLen(2))
+	gomega.Expect(statsAfter.Adds).Should(gomega.BeNumerically(">", statsBefore.Adds))
+	gomega.Expect(statsBefore.Updates + 1).Should(gomega.BeNumerically("==", statsAfter.Updates))
+	gomega.Expect(statsBefore.Deletes + 1).Should(gomega.BeNumerically("==", statsAfter.Deletes))
+
+	// both slices of the service are merged
+	epsProto := &endpoints.Endpoints{}
+	found, _, err := sliceTestVars.mockKvBroker.GetValue(endpoints.Key("my-nginx", "default"), epsProto)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(epsProto.Name).To(gomega.Equal("my-nginx"))
+	gomega.Expect(epsProto.EndpointSubsets).To(gomega.HaveLen(2))
+	gomega.Expect(epsProto.EndpointSubsets[0].Addresses).To(gomega.HaveLen(2))
+	gomega.Expect(epsProto.EndpointSubsets[0].Addresses[0].Ip).To(gomega.Equal("192.168.49.80"))
+	gomega.Expect(epsProto.EndpointSubsets[0].Addresses[0].NodeName).To(gomega.Equal("cvpp"))
+	gomega.Expect(epsProto.EndpointSubsets[0].Addresses[0].TargetRef.Name).
+		To(gomega.Equal("my-nginx-abcde-192.168.49.80"))
+	gomega.Expect(epsProto.EndpointSubsets[0].Ports[0].Name).To(gomega.Equal("http"))
+	gomega.Expect(epsProto.EndpointSubsets[0].Ports[0].Port).To(gomega.BeEquivalentTo(80))
+	gomega.Expect(epsProto.EndpointSubsets[0].Ports[0].Protocol).To(gomega.Equal("TCP"))
+	gomega.Expect(epsProto.EndpointSubsets[1].Addresses).To(gomega.BeEmpty())
+	gomega.Expect(epsProto.EndpointSubsets[1].NotReadyAddresses).To(gomega.HaveLen(1))
+	gomega.Expect(epsProto.EndpointSubsets[1].NotReadyAddresses[0].Ip).To(gomega.Equal("192.168.49.82"))
+
+	sliceTestVars.mockKvBroker.ClearDs()
+
+	t.Run("addDeleteEndpointSlice", testAddDeleteEndpointSlice)
+
+	sliceTestVars.mockKvBroker.ClearDs()
+	t.Run("updateEndpointSlice", testUpdateEndpointSlice)
+}
+
+func testAddDeleteEndpointSlice(t *testing.T) {
+	slice0 := &sliceTestVars.sliceTestData[0]
+	slice1 := &sliceTestVars.sliceTestData[1]
+	key := endpoints.Key("my-nginx", "default")
+
+	// the cache contains the other slice of the service only
+	sliceTestVars.k8sCache = []interface{}{slice1}
+
+	// Take a snapshot of counters
+	adds := sliceTestVars.sliceReflector.GetStats().Adds
+	argErrs := sliceTestVars.sliceReflector.GetStats().ArgErrors
+
+	// Test add with wrong argument type
+	sliceTestVars.k8sListWatch.Add(&slice0)
+
+	gomega.Expect(argErrs + 1).To(gomega.Equal(sliceTestVars.sliceReflector.GetStats().ArgErrors))
+	gomega.Expect(adds).To(gomega.Equal(sliceTestVars.sliceReflector.GetStats().Adds))
+
+	// Test add where everything should be good
+	sliceTestVars.k8sListWatch.Add(slice0)
+	gomega.Expect(adds + 1).To(gomega.Equal(sliceTestVars.sliceReflector.GetStats().Adds))
+
+	epsProto := &endpoints.Endpoints{}
+	found, _, err := sliceTestVars.mockKvBroker.GetValue(key, epsProto)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(epsProto.EndpointSubsets).To(gomega.HaveLen(2))
+
+	// Delete of one slice leaves the endpoints of the other one
+	sliceTestVars.k8sCache = []interface{}{slice1}
+	upd := sliceTestVars.sliceReflector.GetStats().Updates
+	sliceTestVars.k8sListWatch.Delete(slice0)
+	gomega.Expect(upd + 1).To(gomega.Equal(sliceTestVars.sliceReflector.GetStats().Updates))
+
+	epsProto = &endpoints.Endpoints{}
+	found, _, _ = sliceTestVars.mockKvBroker.GetValue(key, epsProto)
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(epsProto.EndpointSubsets).To(gomega.HaveLen(1))
+	gomega.Expect(epsProto.EndpointSubsets[0].NotReadyAddresses[0].Ip).To(gomega.Equal("192.168.49.82"))
+
+	// Delete of the last slice removes the endpoints
+	sliceTestVars.k8sCache = []interface{}{}
+	dels := sliceTestVars.sliceReflector.GetStats().Deletes
+	sliceTestVars.k8sListWatch.Delete(slice1)
+	gomega.Expect(dels + 1).To(gomega.Equal(sliceTestVars.sliceReflector.GetStats().Deletes))
+
+	found, _, _ = sliceTestVars.mockKvBroker.GetValue(key, &endpoints.Endpoints{})
+	gomega.Ω(found).ShouldNot(gomega.BeTrue())
+}
+
+func testUpdateEndpointSlice(t *testing.T) {
+	sliceOld := &sliceTestVars.sliceTestData[0]
+	sliceNew := newTestEndpointSlice(sliceOld.Name, "my-nginx", true, "192.168.49.80")
+	key := endpoints.Key("my-nginx", "default")
+
+	sliceTestVars.k8sCache = []interface{}{&sliceNew}
+	upd := sliceTestVars.sliceReflector.GetStats().Updates
+
+	// Test update with wrong argument type
+	sliceTestVars.k8sListWatch.Update(*sliceOld, sliceNew)
+	gomega.Expect(upd).To(gomega.Equal(sliceTestVars.sliceReflector.GetStats().Updates))
+
+	// Ensure that there is no update if old and new values are the same
+	sliceTestVars.k8sListWatch.Update(sliceOld, sliceOld)
+	gomega.Expect(upd).To(gomega.Equal(sliceTestVars.sliceReflector.GetStats().Updates))
+
+	// Test update where everything should be good
+	sliceTestVars.k8sListWatch.Update(sliceOld, &sliceNew)
+	gomega.Expect(upd + 1).To(gomega.Equal(sliceTestVars.sliceReflector.GetStats().Updates))
+
+	epsProto := &endpoints.Endpoints{}
+	_, _, err := sliceTestVars.mockKvBroker.GetValue(key, epsProto)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(epsProto.EndpointSubsets).To(gomega.HaveLen(1))
+	gomega.Expect(epsProto.EndpointSubsets[0].Addresses).To(gomega.HaveLen(1))
+	gomega.Expect(epsProto.EndpointSubsets[0].Addresses[0].Ip).To(gomega.Equal("192.168.49.80"))
+}
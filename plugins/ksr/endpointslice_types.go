@@ -0,0 +1,187 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sRuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/rest"
+)
+
+// The vendored k8s client does not include the discovery.k8s.io API group yet.
+// The types below are the subset of the discovery.k8s.io/v1beta1 EndpointSlice
+// API needed by KSR; the JSON representation matches the upstream types.
+
+// endpointSliceGroupVersion is the API group and version of the reflected EndpointSlices.
+var endpointSliceGroupVersion = schema.GroupVersion{Group: "discovery.k8s.io", Version: "v1beta1"}
+
+// endpointSliceServiceLabel is the label referencing the service an EndpointSlice belongs to.
+const endpointSliceServiceLabel = "kubernetes.io/service-name"
+
+// endpointSliceHostnameTopology is the topology key with the name of the node of an endpoint.
+const endpointSliceHostnameTopology = "kubernetes.io/hostname"
+
+// EndpointSlice represents a subset of the endpoints that implement a service.
+type EndpointSlice struct {
+	metaV1.TypeMeta   `json:",inline"`
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+
+	// AddressType specifies the type of address carried by this EndpointSlice.
+	AddressType string `json:"addressType"`
+	// Endpoints is a list of unique endpoints in this slice.
+	Endpoints []SliceEndpoint `json:"endpoints"`
+	// Ports specifies the list of network ports exposed by each endpoint.
+	Ports []SliceEndpointPort `json:"ports"`
+}
+
+// SliceEndpoint represents a single logical backend implementing a service.
+type SliceEndpoint struct {
+	Addresses  []string                `json:"addresses"`
+	Conditions SliceEndpointConditions `json:"conditions,omitempty"`
+	Hostname   *string                 `json:"hostname,omitempty"`
+	TargetRef  *coreV1.ObjectReference `json:"targetRef,omitempty"`
+	Topology   map[string]string       `json:"topology,omitempty"`
+	NodeName   *string                 `json:"nodeName,omitempty"`
+}
+
+// SliceEndpointConditions represents the current condition of an endpoint.
+type SliceEndpointConditions struct {
+	// Ready unset is interpreted as ready.
+	Ready *bool `json:"ready,omitempty"`
+}
+
+// SliceEndpointPort represents a port used by an EndpointSlice.
+type SliceEndpointPort struct {
+	Name     *string          `json:"name,omitempty"`
+	Protocol *coreV1.Protocol `json:"protocol,omitempty"`
+	Port     *int32           `json:"port,omitempty"`
+}
+
+// EndpointSliceList represents a list of EndpointSlices.
+type EndpointSliceList struct {
+	metaV1.TypeMeta `json:",inline"`
+	metaV1.ListMeta `json:"metadata,omitempty"`
+
+	Items []EndpointSlice `json:"items"`
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *EndpointSlice) DeepCopyInto(out *EndpointSlice) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Endpoints != nil {
+		out.Endpoints = make([]SliceEndpoint, len(in.Endpoints))
+		for i := range in.Endpoints {
+			in.Endpoints[i].deepCopyInto(&out.Endpoints[i])
+		}
+	}
+	if in.Ports != nil {
+		out.Ports = make([]SliceEndpointPort, len(in.Ports))
+		for i := range in.Ports {
+			in.Ports[i].deepCopyInto(&out.Ports[i])
+		}
+	}
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *EndpointSlice) DeepCopyObject() k8sRuntime.Object {
+	if in == nil {
+		return nil
+	}
+	out := &EndpointSlice{}
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *EndpointSliceList) DeepCopyObject() k8sRuntime.Object {
+	if in == nil {
+		return nil
+	}
+	out := &EndpointSliceList{}
+	*out = *in
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]EndpointSlice, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+	return out
+}
+
+func (in *SliceEndpoint) deepCopyInto(out *SliceEndpoint) {
+	*out = *in
+	if in.Addresses != nil {
+		out.Addresses = append([]string(nil), in.Addresses...)
+	}
+	if in.Conditions.Ready != nil {
+		ready := *in.Conditions.Ready
+		out.Conditions.Ready = &ready
+	}
+	if in.Hostname != nil {
+		hostname := *in.Hostname
+		out.Hostname = &hostname
+	}
+	if in.TargetRef != nil {
+		targetRef := *in.TargetRef
+		out.TargetRef = &targetRef
+	}
+	if in.Topology != nil {
+		out.Topology = make(map[string]string, len(in.Topology))
+		for key, value := range in.Topology {
+			out.Topology[key] = value
+		}
+	}
+	if in.NodeName != nil {
+		nodeName := *in.NodeName
+		out.NodeName = &nodeName
+	}
+}
+
+func (in *SliceEndpointPort) deepCopyInto(out *SliceEndpointPort) {
+	*out = *in
+	if in.Name != nil {
+		name := *in.Name
+		out.Name = &name
+	}
+	if in.Protocol != nil {
+		protocol := *in.Protocol
+		out.Protocol = &protocol
+	}
+	if in.Port != nil {
+		port := *in.Port
+		out.Port = &port
+	}
+}
+
+// newEndpointSliceClient builds REST client for the discovery.k8s.io API group.
+func newEndpointSliceClient(config *rest.Config) (rest.Interface, error) {
+	scheme := k8sRuntime.NewScheme()
+	scheme.AddKnownTypes(endpointSliceGroupVersion, &EndpointSlice{}, &EndpointSliceList{})
+	metaV1.AddToGroupVersion(scheme, endpointSliceGroupVersion)
+
+	sliceConfig := *config
+	sliceConfig.GroupVersion = &endpointSliceGroupVersion
+	sliceConfig.APIPath = "/apis"
+	sliceConfig.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)}
+	if sliceConfig.UserAgent == "" {
+		sliceConfig.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+	return rest.RESTClientFor(&sliceConfig)
+}
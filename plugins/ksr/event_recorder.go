@@ -0,0 +1,96 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/logging"
+
+	"github.com/contiv/vpp/plugins/ksr/model/event"
+)
+
+// EventRecorder records the K8s Events reported by Contiv agents. The agents
+// cannot access K8s API, so they publish the events into the data store
+// (see event.Key) and KSR records them as Warning events of the pods or nodes
+// concerned, so that users see from kubectl describe why e.g. a pod is stuck
+// in ContainerCreating. The recorded requests are removed from the data store.
+type EventRecorder struct {
+	// Log is the logger of the recorder.
+	Log logging.Logger
+	// Broker is used to read the requests at startup and to remove the recorded ones.
+	Broker KeyProtoValBroker
+	// Watcher is used to watch the requests published later.
+	Watcher keyval.ProtoWatcher
+	// RecordEvent records the K8s Event.
+	RecordEvent func(ev *event.Event) error
+	// Active returns false if the events are recorded by another KSR replica
+	// (the leader), nil if the leader election is disabled.
+	Active func() bool
+}
+
+// Init records the events already present in the data store and starts
+// watching for new ones.
+func (er *EventRecorder) Init() error {
+	it, err := er.Broker.ListValues(event.KeyPrefix())
+	if err != nil {
+		return err
+	}
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+		ev := &event.Event{}
+		if err := kv.GetValue(ev); err != nil {
+			er.Log.WithField("key", kv.GetKey()).Error("Failed to read the event")
+			continue
+		}
+		er.recordEvent(kv.GetKey(), ev)
+	}
+
+	return er.Watcher.Watch(er.handleRequest, nil, event.KeyPrefix())
+}
+
+// handleRequest records the event published into the data store.
+// Deletes of the requests (including those of the recorded events) are ignored.
+func (er *EventRecorder) handleRequest(resp keyval.ProtoWatchResp) {
+	if resp.GetChangeType() != datasync.Put {
+		return
+	}
+	ev := &event.Event{}
+	if err := resp.GetValue(ev); err != nil {
+		er.Log.WithField("key", resp.GetKey()).Error("Failed to read the event")
+		return
+	}
+	er.recordEvent(resp.GetKey(), ev)
+}
+
+// recordEvent records the K8s Event and removes the request from the data store.
+func (er *EventRecorder) recordEvent(key string, ev *event.Event) {
+	if er.Active != nil && !er.Active() {
+		return
+	}
+	fields := map[string]interface{}{"kind": ev.Kind, "name": ev.Name, "namespace": ev.Namespace, "reason": ev.Reason}
+	if err := er.RecordEvent(ev); err != nil {
+		// the request is kept, it is recorded after the restart of KSR
+		er.Log.WithFields(fields).Warnf("Failed to record the event: %v", err)
+		return
+	}
+	if _, err := er.Broker.Delete(key); err != nil {
+		er.Log.WithFields(fields).Warnf("Failed to remove the recorded event: %v", err)
+	}
+	er.Log.WithFields(fields).Debug("Event recorded")
+}
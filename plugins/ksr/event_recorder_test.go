@@ -0,0 +1,90 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/flavors/local"
+
+	"github.com/contiv/vpp/plugins/ksr/model/event"
+)
+
+func TestEventRecorder(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	flavorLocal := &local.FlavorLocal{}
+	flavorLocal.Inject()
+
+	broker := newMockKeyProtoValBroker()
+	watcher := &mockProtoWatcher{}
+	var recorded []*event.Event
+	var recordErr error
+	active := true
+
+	recorder := &EventRecorder{
+		Log:     flavorLocal.LoggerFor("event-recorder"),
+		Broker:  broker,
+		Watcher: watcher,
+		RecordEvent: func(ev *event.Event) error {
+			if recordErr != nil {
+				return recordErr
+			}
+			recorded = append(recorded, ev)
+			return nil
+		},
+		Active: func() bool { return active },
+	}
+
+	// the event published before the start is recorded by Init and removed
+	podEvent := &event.Event{Kind: event.KindPod, Name: "pod1", Namespace: "default", Node: "node1",
+		Reason: "NetworkSetupFailed", Message: "no free IP address"}
+	broker.Put(event.Key(podEvent), podEvent)
+
+	err := recorder.Init()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(watcher.keys).To(gomega.ConsistOf(event.KeyPrefix()))
+	gomega.Expect(recorded).To(gomega.HaveLen(1))
+	gomega.Expect(recorded[0].Message).To(gomega.Equal("no free IP address"))
+	gomega.Expect(broker.ds).To(gomega.BeEmpty())
+
+	// the event published later is recorded when watched, kept if recording failed
+	nodeEvent := &event.Event{Kind: event.KindNode, Name: "node1", Node: "node1",
+		Reason: "VswitchConfigFailed", Message: "main interface not found"}
+	broker.Put(event.Key(nodeEvent), nodeEvent)
+	recordErr = errors.New("API server not reachable")
+	watcher.callback(&mockProtoWatchResp{changeType: datasync.Put, key: event.Key(nodeEvent), value: nodeEvent})
+	gomega.Expect(recorded).To(gomega.HaveLen(1))
+	gomega.Expect(broker.ds).To(gomega.HaveKey(event.Key(nodeEvent)))
+
+	recordErr = nil
+	watcher.callback(&mockProtoWatchResp{changeType: datasync.Put, key: event.Key(nodeEvent), value: nodeEvent})
+	gomega.Expect(recorded).To(gomega.HaveLen(2))
+	gomega.Expect(recorded[1].Kind).To(gomega.Equal(event.KindNode))
+	gomega.Expect(broker.ds).To(gomega.BeEmpty())
+
+	// the removal of the request is ignored
+	watcher.callback(&mockProtoWatchResp{changeType: datasync.Delete, key: event.Key(nodeEvent)})
+	gomega.Expect(recorded).To(gomega.HaveLen(2))
+
+	// the events are recorded by the leader only
+	active = false
+	watcher.callback(&mockProtoWatchResp{changeType: datasync.Put, key: event.Key(podEvent), value: podEvent})
+	gomega.Expect(recorded).To(gomega.HaveLen(2))
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// defaultCoalescingKey is the key of the coalescing configuration applied
+// to the reflectors without their own configuration.
+const defaultCoalescingKey = "default"
+
+// CoalescingConfig configures the coalescing of the data store updates
+// of a reflector. Bursts of K8s events (e.g. churn of endpoints during
+// a rolling update) are collected for the given delay, all events of the same
+// item are coalesced into a single write and the writes are rate limited,
+// which protects etcd and the watchers on every node.
+type CoalescingConfig struct {
+	// Delay is the period of writing the collected updates into the data
+	// store. Zero disables the coalescing, every event is written immediately.
+	Delay time.Duration `json:"delay"`
+
+	// MaxRate limits the number of writes per second, zero means no limit.
+	// Updates exceeding the limit are postponed (and coalesced further)
+	// till the next period.
+	MaxRate float64 `json:"max-rate"`
+}
+
+// CoalescingConfigs maps reflector object types (e.g. "Endpoints", "Pod")
+// to their coalescing configuration, "default" applies to the other reflectors.
+type CoalescingConfigs map[string]CoalescingConfig
+
+// forObjType returns the coalescing configuration of the reflector
+// of the given object type.
+func (configs CoalescingConfigs) forObjType(objType string) CoalescingConfig {
+	if config, found := configs[objType]; found {
+		return config
+	}
+	return configs[defaultCoalescingKey]
+}
+
+// enabled returns true if the updates should be coalesced.
+func (config CoalescingConfig) enabled() bool {
+	return config.Delay > 0
+}
+
+// writesPerPeriod returns the maximum number of writes in one period,
+// 0 if unlimited.
+func (config CoalescingConfig) writesPerPeriod() int {
+	if config.MaxRate <= 0 {
+		return 0
+	}
+	writes := int(config.MaxRate * config.Delay.Seconds())
+	if writes < 1 {
+		writes = 1
+	}
+	return writes
+}
+
+// pendingOp is the data store operation of a pending update.
+type pendingOp int
+
+const (
+	pendingAdd pendingOp = iota
+	pendingUpdate
+	pendingDelete
+)
+
+// pendingWrite is an update waiting to be written into the data store.
+type pendingWrite struct {
+	op   pendingOp
+	item proto.Message // nil for delete
+}
+
+// coalesce merges the update with the update of the same item waiting
+// to be written (if any). This function must be called with dsMutex locked.
+func (r *Reflector) coalesce(key string, op pendingOp, item proto.Message) {
+	if r.pending == nil {
+		r.pending = make(map[string]*pendingWrite)
+	}
+	if pending, found := r.pending[key]; found {
+		if !(pending.op == pendingAdd && op == pendingUpdate) {
+			// item added and updated within the period is still an add
+			pending.op = op
+		}
+		pending.item = item
+		return
+	}
+	r.pending[key] = &pendingWrite{op: op, item: item}
+	r.pendingOrder = append(r.pendingOrder, key)
+}
+
+// flushPending writes the pending updates into the data store, at most
+// <limit> of them (all if zero) in the order of their first occurrence.
+// This function must be called with dsMutex locked.
+func (r *Reflector) flushPending(limit int) {
+	if !r.dsSynced {
+		// the data store is resynced from the K8s cache
+		r.dropPending()
+		return
+	}
+	written := 0
+	for len(r.pendingOrder) > 0 && (limit == 0 || written < limit) {
+		key := r.pendingOrder[0]
+		pending := r.pending[key]
+		r.pendingOrder = r.pendingOrder[1:]
+		delete(r.pending, key)
+		written++
+
+		var ok bool
+		switch pending.op {
+		case pendingAdd:
+			ok = r.dsAdd(key, pending.item)
+		case pendingUpdate:
+			ok = r.dsUpdate(key, pending.item)
+		case pendingDelete:
+			ok = r.dsDelete(key)
+		}
+		if !ok {
+			r.dropPending()
+			return
+		}
+	}
+	if len(r.pendingOrder) > 0 {
+		r.Log.Debugf("%s: %d updates postponed by the rate limit", r.objType, len(r.pendingOrder))
+	}
+}
+
+// dropPending discards the pending updates. This function must be called with
+// dsMutex locked.
+func (r *Reflector) dropPending() {
+	r.pending = nil
+	r.pendingOrder = nil
+}
+
+// flushPendingLoop periodically writes the pending updates into the data store
+// until the reflector is stopped.
+func (r *Reflector) flushPendingLoop() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.Coalescing.Delay)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ksrStopCh:
+			r.dsMutex.Lock()
+			r.flushPending(0)
+			r.dsMutex.Unlock()
+			return
+		case <-ticker.C:
+			r.dsMutex.Lock()
+			r.flushPending(r.Coalescing.writesPerPeriod())
+			r.dsMutex.Unlock()
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ligato/cn-infra/flavors/local"
+
+	"github.com/contiv/vpp/plugins/ksr/model/namespace"
+)
+
+func TestCoalescingConfig(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	configs := CoalescingConfigs{
+		defaultCoalescingKey: {Delay: time.Second},
+		endpointsObjType:     {Delay: 100 * time.Millisecond, MaxRate: 50},
+		podObjType:           {Delay: 100 * time.Millisecond, MaxRate: 1},
+	}
+	gomega.Expect(configs.forObjType(namespaceObjType).Delay).To(gomega.Equal(time.Second))
+	gomega.Expect(configs.forObjType(namespaceObjType).writesPerPeriod()).To(gomega.Equal(0))
+	gomega.Expect(configs.forObjType(endpointsObjType).writesPerPeriod()).To(gomega.Equal(5))
+	gomega.Expect(configs.forObjType(podObjType).writesPerPeriod()).To(gomega.Equal(1))
+	gomega.Expect(CoalescingConfigs{}.forObjType(podObjType).enabled()).To(gomega.BeFalse())
+}
+
+func TestReflectorCoalescing(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	flavorLocal := &local.FlavorLocal{}
+	flavorLocal.Inject()
+
+	broker := newMockKeyProtoValBroker()
+	r := &Reflector{
+		Log:        flavorLocal.LoggerFor("coalescing-test"),
+		Broker:     broker,
+		objType:    namespaceObjType,
+		dsSynced:   true,
+		Coalescing: CoalescingConfig{Delay: time.Second, MaxRate: 2},
+	}
+	ns1 := &namespace.Namespace{Name: "ns1"}
+	ns1Updated := &namespace.Namespace{Name: "ns1", Label: []*namespace.Namespace_Label{{Key: "a", Value: "b"}}}
+	ns2 := &namespace.Namespace{Name: "ns2"}
+	ns3 := &namespace.Namespace{Name: "ns3"}
+	broker.Put(namespace.Key("ns3"), ns3)
+
+	// the burst is not written immediately
+	r.ksrAdd(namespace.Key("ns1"), ns1)
+	r.ksrUpdate(namespace.Key("ns1"), ns1, ns1Updated)
+	r.ksrAdd(namespace.Key("ns2"), ns2)
+	r.ksrDelete(namespace.Key("ns2"))
+	r.ksrDelete(namespace.Key("ns3"))
+	gomega.Expect(broker.ds).To(gomega.HaveLen(1))
+	gomega.Expect(r.pendingOrder).To(gomega.HaveLen(3))
+
+	// updates of the same item are coalesced, writes are rate limited
+	r.flushPending(r.Coalescing.writesPerPeriod())
+	gomega.Expect(broker.ds).To(gomega.HaveLen(2))
+	gomega.Expect(broker.ds[namespace.Key("ns1")].val).To(gomega.Equal(ns1Updated))
+	gomega.Expect(broker.ds[namespace.Key("ns1")].rev).To(gomega.BeEquivalentTo(1))
+	gomega.Expect(r.GetStats().Adds).To(gomega.BeEquivalentTo(1))
+	gomega.Expect(r.GetStats().Deletes).To(gomega.BeEquivalentTo(1))
+
+	r.flushPending(r.Coalescing.writesPerPeriod())
+	gomega.Expect(broker.ds).To(gomega.HaveLen(1))
+	gomega.Expect(broker.ds).To(gomega.HaveKey(namespace.Key("ns1")))
+	gomega.Expect(r.pendingOrder).To(gomega.BeEmpty())
+	gomega.Expect(r.GetStats().Deletes).To(gomega.BeEquivalentTo(2))
+
+	// pending updates are dropped while the data store is out of sync
+	r.ksrDelete(namespace.Key("ns1"))
+	r.dsSynced = false
+	r.flushPending(0)
+	gomega.Expect(r.pending).To(gomega.BeEmpty())
+	gomega.Expect(broker.ds).To(gomega.HaveKey(namespace.Key("ns1")))
+}
@@ -58,6 +58,8 @@ type Reflector struct {
 	k8sController cache.Controller
 	// Reflector statistics
 	stats ksrapi.KsrStats
+	// Coalescing of the data store updates, disabled if zero.
+	Coalescing CoalescingConfig
 
 	prefix string
 	pa     ProtoAllocator
@@ -68,6 +70,11 @@ type Reflector struct {
 	dsMutex  sync.Mutex
 
 	syncStopCh chan bool
+
+	// updates waiting to be written into the data store if coalescing is enabled,
+	// protected by dsMutex
+	pending      map[string]*pendingWrite
+	pendingOrder []string
 }
 
 // reflectors is the reflector registry
@@ -144,6 +151,10 @@ func (r *Reflector) GetStats() *ksrapi.KsrStats {
 func (r *Reflector) Start() {
 	r.wg.Add(1)
 	go r.ksrRun()
+	if r.Coalescing.enabled() {
+		r.wg.Add(1)
+		go r.flushPendingLoop()
+	}
 }
 
 // Close does nothing for this particular reflector.
@@ -223,6 +234,9 @@ func (r *Reflector) listDataStoreItems(pfx string, iaf func() proto.Message) (Ds
 // If data can not be written into the data store, mark-and-sweep is aborted
 // and the function returns an error.
 func (r *Reflector) markAndSweep(dsItems DsItems, oc K8sToProtoConverter) error {
+	// the K8s cache is at least as recent as the pending updates
+	r.dropPending()
+
 	for _, obj := range r.k8sStore.List() {
 		k8sProtoObj, key, ok := oc(obj)
 		if ok {
@@ -267,6 +281,53 @@ func (r *Reflector) markAndSweep(dsItems DsItems, oc K8sToProtoConverter) error
 	return nil
 }
 
+// driftStats counts the data store items repaired by a drift check.
+type driftStats struct {
+	missing uint64 // items present in the K8s cache but missing in the data store
+	stale   uint64 // items differing between the K8s cache and the data store
+	extra   uint64 // items present in the data store but no longer in the K8s cache
+}
+
+// total returns the number of all repaired items.
+func (ds driftStats) total() uint64 {
+	return ds.missing + ds.stale + ds.extra
+}
+
+// repairDrift compares the data store with the K8s cache (kept up-to-date
+// by the K8s watch) and repairs the items that drifted apart, e.g. due to
+// a transient data store write failure or a modification by other client.
+// The check is skipped while the data store is out of sync, i.e. until
+// a pending resync finishes.
+//
+// If data can not be written into the data store, the reflector is marked
+// out of sync and a full data store resync is started.
+func (r *Reflector) repairDrift() (driftStats, error) {
+	r.dsMutex.Lock()
+	defer r.dsMutex.Unlock()
+
+	drift := driftStats{}
+	if !r.dsSynced {
+		return drift, nil
+	}
+
+	dsItems, err := r.listDataStoreItems(r.prefix, r.pa)
+	if err != nil {
+		return drift, err
+	}
+
+	before := r.stats
+	err = r.markAndSweep(dsItems, r.kpc)
+	drift.missing = r.stats.Adds - before.Adds
+	drift.stale = r.stats.Updates - before.Updates
+	drift.extra = r.stats.Deletes - before.Deletes
+	if err != nil {
+		r.dsSynced = false
+		r.startDataStoreResync()
+		return drift, fmt.Errorf("%s drift repair: mark-and-sweep failed, '%s'", r.objType, err)
+	}
+	return drift, nil
+}
+
 // syncDataStoreWithK8sCache syncs data in etcd with data in KSR's
 // k8s cache. Returns ok if reconciliation is successful, error otherwise.
 func (r *Reflector) syncDataStoreWithK8sCache(dsItems DsItems) error {
@@ -367,40 +428,68 @@ func (r *Reflector) startDataStoreResync() {
 // ksrAdd adds an item to the Etcd data store. This function must be called
 // with dsMutex locked, since it manipulates the dsSynced flag.
 func (r *Reflector) ksrAdd(key string, item proto.Message) {
-	err := r.Broker.Put(key, item)
-	if err != nil {
-		r.Log.WithField("rwErr", err).Warnf("%s: failed to add item to data store", r.objType)
-		r.stats.AddErrors++
-		r.dsSynced = false
-		r.startDataStoreResync()
+	if r.Coalescing.enabled() {
+		r.coalesce(key, pendingAdd, item)
 		return
 	}
-	r.stats.Adds++
+	r.dsAdd(key, item)
 }
 
 // ksrUpdate updates an item to the Etcd data store. This function must be
 // called with dsMutex locked, since it manipulates the dsSynced flag.
 func (r *Reflector) ksrUpdate(key string, itemOld, itemNew proto.Message) {
 	if !reflect.DeepEqual(itemOld, itemNew) {
-
-		r.Log.WithField("key", key).Debugf("%s: updating item in data store", r.objType)
-
-		err := r.Broker.Put(key, itemNew)
-		if err != nil {
-			r.Log.WithField("rwErr", err).
-				Warnf("%s: failed to update item in data store", r.objType)
-			r.stats.UpdErrors++
-			r.dsSynced = false
-			r.startDataStoreResync()
+		if r.Coalescing.enabled() {
+			r.coalesce(key, pendingUpdate, itemNew)
 			return
 		}
-		r.stats.Updates++
+		r.dsUpdate(key, itemNew)
 	}
 }
 
 // ksrDelete deletes an item from the Etcd data store. This function must be
 // called with dsMutex locked, since it manipulates the dsSynced flag.
 func (r *Reflector) ksrDelete(key string) {
+	if r.Coalescing.enabled() {
+		r.coalesce(key, pendingDelete, nil)
+		return
+	}
+	r.dsDelete(key)
+}
+
+// dsAdd writes an added item into the data store, returns false on failure.
+func (r *Reflector) dsAdd(key string, item proto.Message) bool {
+	err := r.Broker.Put(key, item)
+	if err != nil {
+		r.Log.WithField("rwErr", err).Warnf("%s: failed to add item to data store", r.objType)
+		r.stats.AddErrors++
+		r.dsSynced = false
+		r.startDataStoreResync()
+		return false
+	}
+	r.stats.Adds++
+	return true
+}
+
+// dsUpdate writes an updated item into the data store, returns false on failure.
+func (r *Reflector) dsUpdate(key string, item proto.Message) bool {
+	r.Log.WithField("key", key).Debugf("%s: updating item in data store", r.objType)
+
+	err := r.Broker.Put(key, item)
+	if err != nil {
+		r.Log.WithField("rwErr", err).
+			Warnf("%s: failed to update item in data store", r.objType)
+		r.stats.UpdErrors++
+		r.dsSynced = false
+		r.startDataStoreResync()
+		return false
+	}
+	r.stats.Updates++
+	return true
+}
+
+// dsDelete removes an item from the data store, returns false on failure.
+func (r *Reflector) dsDelete(key string) bool {
 	_, err := r.Broker.Delete(key)
 	if err != nil {
 		r.Log.WithField("rwErr", err).
@@ -408,9 +497,10 @@ func (r *Reflector) ksrDelete(key string) {
 		r.stats.DelErrors++
 		r.dsSynced = false
 		r.startDataStoreResync()
-		return
+		return false
 	}
 	r.stats.Deletes++
+	return true
 }
 
 // Init subscribes to K8s cluster to watch for changes in the configuration
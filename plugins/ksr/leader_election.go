@@ -0,0 +1,301 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ligato/cn-infra/logging"
+)
+
+const (
+	// leaderAnnotation is the annotation of the lock ConfigMap with the leader record.
+	leaderAnnotation = "contivpp.io/ksr-leader"
+
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+	defaultLockNamespace = "kube-system"
+	defaultLockName      = "contiv-ksr-leader"
+)
+
+// LeaderElectionConfig configures the election of the KSR replica reflecting
+// the K8s state into the data store.
+type LeaderElectionConfig struct {
+	// Enabled turns the election on, otherwise every replica is the leader.
+	Enabled bool `json:"enabled"`
+
+	// LeaseDuration is the time the other replicas wait since the last renewal
+	// of the leadership before they try to take it over.
+	LeaseDuration time.Duration `json:"lease-duration"`
+
+	// RenewDeadline is the time the leader keeps retrying to renew the leadership
+	// before it gives it up. Must be shorter than LeaseDuration.
+	RenewDeadline time.Duration `json:"renew-deadline"`
+
+	// RetryPeriod is the period of the attempts to acquire or renew the leadership.
+	RetryPeriod time.Duration `json:"retry-period"`
+
+	// LockNamespace and LockName identify the ConfigMap used as the lock.
+	LockNamespace string `json:"lock-namespace"`
+	LockName      string `json:"lock-name"`
+}
+
+// leaderRecord is the record of the current leader stored in the lock.
+type leaderRecord struct {
+	HolderIdentity string    `json:"holderIdentity"`
+	AcquireTime    time.Time `json:"acquireTime"`
+	RenewTime      time.Time `json:"renewTime"`
+}
+
+// leaderLock stores the leader record. Update must fail if the record has
+// changed since the last Get.
+type leaderLock interface {
+	// Get returns the current record, nil if the lock does not exist yet.
+	Get() (*leaderRecord, error)
+	// Create creates the lock with the given record.
+	Create(record *leaderRecord) error
+	// Update overwrites the record read by the last Get.
+	Update(record *leaderRecord) error
+}
+
+// LeaderElector elects one of the KSR replicas as the leader.
+// The election is based on the optimistic concurrency of the updates
+// of the lock object.
+type LeaderElector struct {
+	Log      logging.Logger
+	Identity string
+	Config   LeaderElectionConfig
+
+	lock leaderLock
+
+	sync.Mutex
+	leader       bool
+	lastRenew    time.Time     // last successful acquire or renewal by this replica
+	observed     *leaderRecord // last observed record
+	observedTime time.Time     // local time when the record was observed to change
+}
+
+// NewLeaderElector creates leader elector using ConfigMap as the lock.
+func NewLeaderElector(log logging.Logger, identity string, config LeaderElectionConfig,
+	clientset kubernetes.Interface) *LeaderElector {
+
+	if config.LockNamespace == "" {
+		config.LockNamespace = defaultLockNamespace
+	}
+	if config.LockName == "" {
+		config.LockName = defaultLockName
+	}
+	return newLeaderElector(log, identity, config, &configMapLock{
+		clientset: clientset,
+		namespace: config.LockNamespace,
+		name:      config.LockName,
+	})
+}
+
+// newLeaderElector creates leader elector using the given lock.
+func newLeaderElector(log logging.Logger, identity string, config LeaderElectionConfig, lock leaderLock) *LeaderElector {
+	if config.LeaseDuration == 0 {
+		config.LeaseDuration = defaultLeaseDuration
+	}
+	if config.RenewDeadline == 0 {
+		config.RenewDeadline = defaultRenewDeadline
+	}
+	if config.RetryPeriod == 0 {
+		config.RetryPeriod = defaultRetryPeriod
+	}
+	return &LeaderElector{Log: log, Identity: identity, Config: config, lock: lock}
+}
+
+// IsLeader returns true if this replica is currently the leader.
+func (le *LeaderElector) IsLeader() bool {
+	le.Lock()
+	defer le.Unlock()
+	return le.leader
+}
+
+// Run keeps trying to acquire or renew the leadership until the stop channel
+// is closed. The leadership is released on stop.
+func (le *LeaderElector) Run(stopCh <-chan struct{}) {
+	for {
+		le.tryAcquireOrRenew(time.Now())
+		select {
+		case <-stopCh:
+			le.release()
+			return
+		case <-time.After(le.Config.RetryPeriod):
+		}
+	}
+}
+
+// tryAcquireOrRenew makes one attempt to acquire or renew the leadership
+// and updates the leader state.
+func (le *LeaderElector) tryAcquireOrRenew(now time.Time) {
+	acquired, err := le.acquireOrRenew(now)
+	if err != nil {
+		le.Log.WithField("err", err).Debug("Failed to acquire or renew the leadership")
+	}
+
+	le.Lock()
+	defer le.Unlock()
+	if acquired {
+		if !le.leader {
+			le.Log.WithField("identity", le.Identity).Info("Became the leader")
+		}
+		le.leader = true
+		le.lastRenew = now
+		return
+	}
+	// without an error, the leadership is held by another replica
+	if le.leader && (err == nil || now.Sub(le.lastRenew) >= le.Config.RenewDeadline) {
+		le.Log.WithField("identity", le.Identity).Warn("Lost the leadership")
+		le.leader = false
+	}
+}
+
+// acquireOrRenew returns true if this replica holds the leadership.
+func (le *LeaderElector) acquireOrRenew(now time.Time) (bool, error) {
+	record := &leaderRecord{HolderIdentity: le.Identity, AcquireTime: now, RenewTime: now}
+
+	current, err := le.lock.Get()
+	if err != nil {
+		return false, err
+	}
+	if current == nil {
+		if err := le.lock.Create(record); err != nil {
+			return false, err
+		}
+		le.observe(record, now)
+		return true, nil
+	}
+
+	le.observe(current, now)
+	if current.HolderIdentity != le.Identity && current.HolderIdentity != "" &&
+		now.Before(le.observedTime.Add(le.Config.LeaseDuration)) {
+		// the lease of another replica has not expired yet
+		return false, nil
+	}
+	if current.HolderIdentity == le.Identity {
+		record.AcquireTime = current.AcquireTime
+	}
+	if err := le.lock.Update(record); err != nil {
+		return false, err
+	}
+	le.observe(record, now)
+	return true, nil
+}
+
+// observe remembers the local time when the record was seen to change.
+// Clocks of the replicas are not compared, the lease expires LeaseDuration
+// after the last observed change of the record.
+func (le *LeaderElector) observe(record *leaderRecord, now time.Time) {
+	le.Lock()
+	defer le.Unlock()
+	if le.observed == nil || *le.observed != *record {
+		le.observed = record
+		le.observedTime = now
+	}
+}
+
+// release gives up the leadership, so that another replica can take over
+// without waiting for the lease to expire.
+func (le *LeaderElector) release() {
+	if !le.IsLeader() {
+		return
+	}
+	le.Lock()
+	le.leader = false
+	le.Unlock()
+
+	current, err := le.lock.Get()
+	if err != nil || current == nil || current.HolderIdentity != le.Identity {
+		return
+	}
+	if err := le.lock.Update(&leaderRecord{}); err != nil {
+		le.Log.WithField("err", err).Warn("Failed to release the leadership")
+	}
+}
+
+// configMapLock stores the leader record in an annotation of a ConfigMap.
+type configMapLock struct {
+	clientset kubernetes.Interface
+	namespace string
+	name      string
+
+	configMap *coreV1.ConfigMap // from the last Get
+}
+
+// Get reads the ConfigMap and returns the leader record.
+func (l *configMapLock) Get() (*leaderRecord, error) {
+	configMap, err := l.clientset.CoreV1().ConfigMaps(l.namespace).Get(l.name, metaV1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		l.configMap = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.configMap = configMap
+	record := &leaderRecord{}
+	if value, found := configMap.Annotations[leaderAnnotation]; found {
+		if err := json.Unmarshal([]byte(value), record); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// Create creates the ConfigMap with the given record.
+func (l *configMapLock) Create(record *leaderRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	l.configMap, err = l.clientset.CoreV1().ConfigMaps(l.namespace).Create(&coreV1.ConfigMap{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace:   l.namespace,
+			Name:        l.name,
+			Annotations: map[string]string{leaderAnnotation: string(value)},
+		},
+	})
+	return err
+}
+
+// Update overwrites the record in the ConfigMap read by the last Get.
+// K8s rejects the update if the ConfigMap has been modified meanwhile.
+func (l *configMapLock) Update(record *leaderRecord) error {
+	if l.configMap == nil {
+		return errors.New("lock ConfigMap has not been read")
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	configMap := l.configMap.DeepCopy()
+	if configMap.Annotations == nil {
+		configMap.Annotations = make(map[string]string)
+	}
+	configMap.Annotations[leaderAnnotation] = string(value)
+	l.configMap, err = l.clientset.CoreV1().ConfigMaps(l.namespace).Update(configMap)
+	return err
+}
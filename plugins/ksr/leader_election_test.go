@@ -0,0 +1,137 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ligato/cn-infra/flavors/local"
+)
+
+// mockLockStore is an in-memory lock object with a version.
+type mockLockStore struct {
+	record  *leaderRecord
+	version int
+}
+
+// mockLeaderLock is a mock implementation of leaderLock accessing mockLockStore
+// with the optimistic concurrency.
+type mockLeaderLock struct {
+	store   *mockLockStore
+	version int
+}
+
+func (mock *mockLeaderLock) Get() (*leaderRecord, error) {
+	mock.version = mock.store.version
+	if mock.store.record == nil {
+		return nil, nil
+	}
+	record := *mock.store.record
+	return &record, nil
+}
+
+func (mock *mockLeaderLock) Create(record *leaderRecord) error {
+	if mock.store.record != nil {
+		return errors.New("already exists")
+	}
+	mock.store.record = record
+	mock.store.version++
+	return nil
+}
+
+func (mock *mockLeaderLock) Update(record *leaderRecord) error {
+	if mock.version != mock.store.version {
+		return errors.New("conflict")
+	}
+	mock.store.record = record
+	mock.store.version++
+	return nil
+}
+
+func TestLeaderElection(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	flavorLocal := &local.FlavorLocal{}
+	flavorLocal.Inject()
+	log := flavorLocal.LoggerFor("leader-election-test")
+
+	store := &mockLockStore{}
+	config := LeaderElectionConfig{Enabled: true}
+	le1 := newLeaderElector(log, "master1", config, &mockLeaderLock{store: store})
+	le2 := newLeaderElector(log, "master2", config, &mockLeaderLock{store: store})
+
+	// The first replica acquires the leadership.
+	now := time.Now()
+	le1.tryAcquireOrRenew(now)
+	le2.tryAcquireOrRenew(now)
+	gomega.Expect(le1.IsLeader()).To(gomega.BeTrue())
+	gomega.Expect(le2.IsLeader()).To(gomega.BeFalse())
+
+	// The leader renews the leadership.
+	for i := 0; i < 10; i++ {
+		now = now.Add(le1.Config.RetryPeriod)
+		le1.tryAcquireOrRenew(now)
+		le2.tryAcquireOrRenew(now)
+		gomega.Expect(le1.IsLeader()).To(gomega.BeTrue())
+		gomega.Expect(le2.IsLeader()).To(gomega.BeFalse())
+	}
+
+	// The leader stops renewing, the other replica takes over once the lease expires.
+	now = now.Add(le1.Config.LeaseDuration / 2)
+	le2.tryAcquireOrRenew(now)
+	gomega.Expect(le2.IsLeader()).To(gomega.BeFalse())
+	now = now.Add(le1.Config.LeaseDuration)
+	le2.tryAcquireOrRenew(now)
+	gomega.Expect(le2.IsLeader()).To(gomega.BeTrue())
+	gomega.Expect(store.record.HolderIdentity).To(gomega.Equal("master2"))
+
+	// The former leader finds out it has lost the leadership.
+	le1.tryAcquireOrRenew(now)
+	gomega.Expect(le1.IsLeader()).To(gomega.BeFalse())
+
+	// The leader releases the leadership on stop, the other replica takes over immediately.
+	le2.release()
+	gomega.Expect(le2.IsLeader()).To(gomega.BeFalse())
+	now = now.Add(le1.Config.RetryPeriod)
+	le1.tryAcquireOrRenew(now)
+	gomega.Expect(le1.IsLeader()).To(gomega.BeTrue())
+}
+
+func TestLeaderElectionConflict(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	flavorLocal := &local.FlavorLocal{}
+	flavorLocal.Inject()
+	log := flavorLocal.LoggerFor("leader-election-test")
+
+	// Both replicas read the released lock, only the first update succeeds.
+	store := &mockLockStore{record: &leaderRecord{}}
+	lock1 := &mockLeaderLock{store: store}
+	lock2 := &mockLeaderLock{store: store}
+	le1 := newLeaderElector(log, "master1", LeaderElectionConfig{Enabled: true}, lock1)
+	le2 := newLeaderElector(log, "master2", LeaderElectionConfig{Enabled: true}, lock2)
+
+	now := time.Now()
+	lock2.Get()
+	le1.tryAcquireOrRenew(now)
+	gomega.Expect(le1.IsLeader()).To(gomega.BeTrue())
+	gomega.Expect(lock2.Update(&leaderRecord{HolderIdentity: "master2"})).ToNot(gomega.BeNil())
+	le2.tryAcquireOrRenew(now)
+	gomega.Expect(le2.IsLeader()).To(gomega.BeFalse())
+}
@@ -0,0 +1,508 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+)
+
+// memoryStore is an in-memory key-value store with the semantics of the data
+// store plugins (keyval.KvProtoPlugin): values are serialized, every change
+// increments the revision and the watchers are notified of the changes
+// asynchronously, in the order of the changes.
+type memoryStore struct {
+	sync.Mutex
+	serializer keyval.Serializer
+	items      map[string]*memoryItem
+	rev        int64
+	watches    []*memoryWatch
+	closed     bool
+}
+
+// memoryItem is a single value of the store.
+type memoryItem struct {
+	value []byte
+	rev   int64
+}
+
+// newMemoryStore creates new empty instance of memoryStore.
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		serializer: &keyval.SerializerJSON{},
+		items:      make(map[string]*memoryItem),
+	}
+}
+
+// NewBroker returns a ProtoBroker prepending the given prefix to all keys.
+func (s *memoryStore) NewBroker(keyPrefix string) keyval.ProtoBroker {
+	return &memoryBroker{store: s, prefix: keyPrefix}
+}
+
+// NewWatcher returns a ProtoWatcher prepending the given prefix to the watched keys.
+func (s *memoryStore) NewWatcher(keyPrefix string) keyval.ProtoWatcher {
+	return &memoryWatcher{store: s, prefix: keyPrefix}
+}
+
+// Disabled returns false, the store needs no configuration.
+func (s *memoryStore) Disabled() bool {
+	return false
+}
+
+// close stops the delivery of the changes to all watchers.
+func (s *memoryStore) close() {
+	s.Lock()
+	defer s.Unlock()
+	s.closed = true
+	for _, watch := range s.watches {
+		watch.stop()
+	}
+	s.watches = nil
+}
+
+// put writes the serialized value under the given key.
+func (s *memoryStore) put(key string, value []byte) {
+	s.Lock()
+	defer s.Unlock()
+	s.rev++
+	prev := s.items[key]
+	s.items[key] = &memoryItem{value: value, rev: s.rev}
+	s.notify(key, datasync.Put, value, prev)
+}
+
+// putIfNotExists writes the serialized value under the given key, unless the key exists.
+func (s *memoryStore) putIfNotExists(key string, value []byte) bool {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.items[key]; exists {
+		return false
+	}
+	s.rev++
+	s.items[key] = &memoryItem{value: value, rev: s.rev}
+	s.notify(key, datasync.Put, value, nil)
+	return true
+}
+
+// delete removes the value of the given key, or of all keys with the given prefix.
+func (s *memoryStore) delete(key string, withPrefix bool) (existed bool) {
+	s.Lock()
+	defer s.Unlock()
+	for itemKey, item := range s.items {
+		if itemKey != key && !(withPrefix && strings.HasPrefix(itemKey, key)) {
+			continue
+		}
+		existed = true
+		s.rev++
+		delete(s.items, itemKey)
+		s.notify(itemKey, datasync.Delete, nil, item)
+	}
+	return existed
+}
+
+// deleteIfNotChanged removes the value of the given key if its revision equals the given one.
+func (s *memoryStore) deleteIfNotChanged(key string, revision int64) bool {
+	s.Lock()
+	defer s.Unlock()
+	item, exists := s.items[key]
+	if !exists || item.rev != revision {
+		return false
+	}
+	s.rev++
+	delete(s.items, key)
+	s.notify(key, datasync.Delete, nil, item)
+	return true
+}
+
+// get returns the value of the given key, nil if not found.
+func (s *memoryStore) get(key string) *memoryItem {
+	s.Lock()
+	defer s.Unlock()
+	return s.items[key]
+}
+
+// memoryEntry is a key-value pair listed from the store.
+type memoryEntry struct {
+	key string
+	*memoryItem
+}
+
+// list returns the values of all keys with the given prefix, ordered by the key.
+func (s *memoryStore) list(prefix string) []memoryEntry {
+	s.Lock()
+	defer s.Unlock()
+	var entries []memoryEntry
+	for key, item := range s.items {
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, memoryEntry{key: key, memoryItem: item})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	return entries
+}
+
+// notify queues the change for the watchers of the key. The lock has to be held.
+func (s *memoryStore) notify(key string, changeType datasync.PutDel, value []byte, prev *memoryItem) {
+	for _, watch := range s.watches {
+		watchedKey, watched := watch.match(key)
+		if !watched {
+			continue
+		}
+		resp := &memoryWatchResp{
+			serializer: s.serializer,
+			changeType: changeType,
+			key:        watchedKey,
+			value:      value,
+			rev:        s.rev,
+		}
+		if prev != nil {
+			resp.prevValue = prev.value
+		}
+		watch.queue(resp)
+	}
+}
+
+// memoryBroker is a ProtoBroker of memoryStore.
+type memoryBroker struct {
+	store  *memoryStore
+	prefix string
+}
+
+// Put puts single key-value pair into the store, the options are ignored.
+func (b *memoryBroker) Put(key string, data proto.Message, opts ...datasync.PutOption) error {
+	value, err := b.store.serializer.Marshal(data)
+	if err != nil {
+		return err
+	}
+	b.store.put(b.prefix+key, value)
+	return nil
+}
+
+// NewTxn creates a transaction.
+func (b *memoryBroker) NewTxn() keyval.ProtoTxn {
+	return &memoryTxn{broker: b}
+}
+
+// GetValue retrieves one item under the provided <key>.
+func (b *memoryBroker) GetValue(key string, reqObj proto.Message) (found bool, revision int64, err error) {
+	item := b.store.get(b.prefix + key)
+	if item == nil {
+		return false, 0, nil
+	}
+	return true, item.rev, b.store.serializer.Unmarshal(item.value, reqObj)
+}
+
+// ListValues returns an iterator over the items stored under the provided <key>.
+func (b *memoryBroker) ListValues(key string) (keyval.ProtoKeyValIterator, error) {
+	return &memoryKeyValIterator{broker: b, entries: b.store.list(b.prefix + key)}, nil
+}
+
+// ListKeys returns an iterator over the keys with the given <prefix>.
+func (b *memoryBroker) ListKeys(prefix string) (keyval.ProtoKeyIterator, error) {
+	return &memoryKeyIterator{prefix: b.prefix, entries: b.store.list(b.prefix + prefix)}, nil
+}
+
+// Delete removes data stored under the <key>.
+func (b *memoryBroker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	withPrefix := false
+	for _, opt := range opts {
+		if _, ok := opt.(*datasync.WithPrefixOpt); ok {
+			withPrefix = true
+		}
+	}
+	return b.store.delete(b.prefix+key, withPrefix), nil
+}
+
+// memoryTxn is a transaction of memoryStore, the operations are applied
+// once all values are serialized.
+type memoryTxn struct {
+	broker *memoryBroker
+	ops    []memoryTxnOp
+}
+
+// memoryTxnOp is a single operation of the transaction, data is nil for delete.
+type memoryTxnOp struct {
+	key  string
+	data proto.Message
+}
+
+// Put adds put operation into the transaction.
+func (t *memoryTxn) Put(key string, data proto.Message) keyval.ProtoTxn {
+	t.ops = append(t.ops, memoryTxnOp{key: key, data: data})
+	return t
+}
+
+// Delete adds delete operation into the transaction.
+func (t *memoryTxn) Delete(key string) keyval.ProtoTxn {
+	t.ops = append(t.ops, memoryTxnOp{key: key})
+	return t
+}
+
+// Commit applies all operations of the transaction.
+func (t *memoryTxn) Commit() error {
+	values := make([][]byte, len(t.ops))
+	for i, op := range t.ops {
+		if op.data == nil {
+			continue
+		}
+		value, err := t.broker.store.serializer.Marshal(op.data)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	for i, op := range t.ops {
+		if op.data == nil {
+			t.broker.store.delete(t.broker.prefix+op.key, false)
+			continue
+		}
+		t.broker.store.put(t.broker.prefix+op.key, values[i])
+	}
+	return nil
+}
+
+// memoryKeyValIterator iterates over the listed values.
+type memoryKeyValIterator struct {
+	broker  *memoryBroker
+	entries []memoryEntry
+	index   int
+}
+
+// GetNext returns the next listed item.
+func (it *memoryKeyValIterator) GetNext() (kv keyval.ProtoKeyVal, stop bool) {
+	if it.index >= len(it.entries) {
+		return nil, true
+	}
+	entry := it.entries[it.index]
+	it.index++
+	return &memoryWatchResp{
+		serializer: it.broker.store.serializer,
+		changeType: datasync.Put,
+		key:        strings.TrimPrefix(entry.key, it.broker.prefix),
+		value:      entry.value,
+		rev:        entry.rev,
+	}, false
+}
+
+// Close does nothing.
+func (it *memoryKeyValIterator) Close() error {
+	return nil
+}
+
+// memoryKeyIterator iterates over the listed keys.
+type memoryKeyIterator struct {
+	prefix  string
+	entries []memoryEntry
+	index   int
+}
+
+// GetNext returns the next listed key.
+func (it *memoryKeyIterator) GetNext() (key string, rev int64, stop bool) {
+	if it.index >= len(it.entries) {
+		return "", 0, true
+	}
+	entry := it.entries[it.index]
+	it.index++
+	return strings.TrimPrefix(entry.key, it.prefix), entry.rev, false
+}
+
+// Close does nothing.
+func (it *memoryKeyIterator) Close() error {
+	return nil
+}
+
+// memoryWatcher is a ProtoWatcher of memoryStore.
+type memoryWatcher struct {
+	store  *memoryStore
+	prefix string
+}
+
+// Watch starts watching of the given keys (prefixes). Sending a watched key
+// into <closeChan> stops watching of the key.
+func (w *memoryWatcher) Watch(resp func(keyval.ProtoWatchResp), closeChan chan string, keys ...string) error {
+	watch := &memoryWatch{
+		prefix: w.prefix,
+		keys:   keys,
+		resp:   resp,
+		wakeup: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	w.store.Lock()
+	if w.store.closed {
+		w.store.Unlock()
+		return nil
+	}
+	w.store.watches = append(w.store.watches, watch)
+	w.store.Unlock()
+
+	go watch.run()
+	if closeChan != nil {
+		go w.watchClose(watch, closeChan)
+	}
+	return nil
+}
+
+// watchClose stops watching of the keys received from <closeChan>,
+// the watch is removed once no key is watched.
+func (w *memoryWatcher) watchClose(watch *memoryWatch, closeChan chan string) {
+	for {
+		select {
+		case key := <-closeChan:
+			w.store.Lock()
+			if watch.unwatch(key) {
+				for i, other := range w.store.watches {
+					if other == watch {
+						w.store.watches = append(w.store.watches[:i], w.store.watches[i+1:]...)
+						break
+					}
+				}
+				watch.stop()
+			}
+			w.store.Unlock()
+		case <-watch.done:
+			return
+		}
+	}
+}
+
+// memoryWatch is a single watch registration, the changes are delivered
+// by its own go routine.
+type memoryWatch struct {
+	sync.Mutex
+	prefix  string
+	keys    []string
+	resp    func(keyval.ProtoWatchResp)
+	pending []*memoryWatchResp
+	wakeup  chan struct{}
+	done    chan struct{}
+	stopped bool
+}
+
+// match returns the key relative to the watcher prefix and true if the key is watched.
+// The lock of the store has to be held.
+func (w *memoryWatch) match(key string) (string, bool) {
+	if !strings.HasPrefix(key, w.prefix) {
+		return "", false
+	}
+	relKey := strings.TrimPrefix(key, w.prefix)
+	for _, watched := range w.keys {
+		if strings.HasPrefix(relKey, watched) {
+			return relKey, true
+		}
+	}
+	return "", false
+}
+
+// unwatch stops watching of the given key, returns true if no key is watched anymore.
+// The lock of the store has to be held.
+func (w *memoryWatch) unwatch(key string) bool {
+	for i, watched := range w.keys {
+		if watched == key {
+			w.keys = append(w.keys[:i], w.keys[i+1:]...)
+			break
+		}
+	}
+	return len(w.keys) == 0
+}
+
+// queue queues the change to be delivered.
+func (w *memoryWatch) queue(resp *memoryWatchResp) {
+	w.Lock()
+	w.pending = append(w.pending, resp)
+	w.Unlock()
+	select {
+	case w.wakeup <- struct{}{}:
+	default:
+		// the delivery is already woken up
+	}
+}
+
+// stop stops the delivery of the changes.
+func (w *memoryWatch) stop() {
+	w.Lock()
+	defer w.Unlock()
+	if !w.stopped {
+		w.stopped = true
+		close(w.done)
+	}
+}
+
+// run delivers the queued changes until the watch is stopped.
+func (w *memoryWatch) run() {
+	for {
+		select {
+		case <-w.wakeup:
+		case <-w.done:
+			return
+		}
+		for {
+			w.Lock()
+			if len(w.pending) == 0 || w.stopped {
+				w.Unlock()
+				break
+			}
+			resp := w.pending[0]
+			w.pending = w.pending[1:]
+			w.Unlock()
+			w.resp(resp)
+		}
+	}
+}
+
+// memoryWatchResp is a change of memoryStore, also used as a listed key-value pair.
+type memoryWatchResp struct {
+	serializer keyval.Serializer
+	changeType datasync.PutDel
+	key        string
+	value      []byte
+	prevValue  []byte
+	rev        int64
+}
+
+// GetChangeType returns the type of the change.
+func (r *memoryWatchResp) GetChangeType() datasync.PutDel {
+	return r.changeType
+}
+
+// GetKey returns the changed key.
+func (r *memoryWatchResp) GetKey() string {
+	return r.key
+}
+
+// GetValue unmarshals the new value, does nothing for delete.
+func (r *memoryWatchResp) GetValue(value proto.Message) error {
+	if r.value == nil {
+		return nil
+	}
+	return r.serializer.Unmarshal(r.value, value)
+}
+
+// GetPrevValue unmarshals the previous value if there was one.
+func (r *memoryWatchResp) GetPrevValue(prevValue proto.Message) (prevValueExist bool, err error) {
+	if r.prevValue == nil {
+		return false, nil
+	}
+	return true, r.serializer.Unmarshal(r.prevValue, prevValue)
+}
+
+// GetRevision returns the revision of the change.
+func (r *memoryWatchResp) GetRevision() int64 {
+	return r.rev
+}
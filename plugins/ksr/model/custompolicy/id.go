@@ -0,0 +1,31 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custompolicy
+
+// ID used to uniquely represent a CustomNetworkPolicy (cluster-scoped).
+type ID string
+
+// GetID returns ID of a custom network policy.
+func GetID(policy *CustomNetworkPolicy) ID {
+	if policy != nil {
+		return ID(policy.Name)
+	}
+	return ID("")
+}
+
+// String returns a string representation of a custom network policy ID.
+func (id ID) String() string {
+	return string(id)
+}
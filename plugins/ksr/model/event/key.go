@@ -0,0 +1,46 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"strings"
+
+	"github.com/contiv/vpp/plugins/ksr/model/ksrkey"
+)
+
+const (
+	// EventKeyword defines the keyword identifying the requests to record
+	// K8s Events.
+	EventKeyword = "event"
+
+	// KindPod is the kind of the events about pods.
+	KindPod = "Pod"
+
+	// KindNode is the kind of the events about nodes.
+	KindNode = "Node"
+)
+
+// KeyPrefix returns the key prefix identifying all requests to record K8s Events
+// in the data store.
+func KeyPrefix() string {
+	return ksrkey.KeyPrefix(EventKeyword)
+}
+
+// Key returns the key under which the request to record the given event is stored
+// in the data store. Repeated events of the same object, reason and node share the key,
+// a request not yet recorded by KSR is thus overwritten by the later one.
+func Key(event *Event) string {
+	return strings.Join([]string{KeyPrefix(), event.Node, event.Kind, event.Namespace, event.Name, event.Reason}, "/")
+}
@@ -134,6 +134,8 @@ type Stats struct {
 	ServiceStats *KsrStats `protobuf:"bytes,5,opt,name=serviceStats" json:"serviceStats,omitempty"`
 	// Statistics for the Node Reflector
 	NodeStats *KsrStats `protobuf:"bytes,6,opt,name=nodeStats" json:"nodeStats,omitempty"`
+	// Statistics for the Custom Network Policy Reflector
+	CustomPolicyStats *KsrStats `protobuf:"bytes,7,opt,name=customPolicyStats" json:"customPolicyStats,omitempty"`
 }
 
 func (m *Stats) Reset()                    { *m = Stats{} }
@@ -183,6 +185,13 @@ func (m *Stats) GetNodeStats() *KsrStats {
 	return nil
 }
 
+func (m *Stats) GetCustomPolicyStats() *KsrStats {
+	if m != nil {
+		return m.CustomPolicyStats
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*KsrStats)(nil), "ksrapi.KsrStats")
 	proto.RegisterType((*Stats)(nil), "ksrapi.Stats")
@@ -191,24 +200,25 @@ func init() {
 func init() { proto.RegisterFile("ksr_nb_api.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 291 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x92, 0xb1, 0x6a, 0xc3, 0x30,
-	0x10, 0x86, 0x89, 0x63, 0x3b, 0xf6, 0xa5, 0x94, 0xa0, 0xc9, 0x43, 0x87, 0x92, 0xa9, 0x43, 0xf1,
-	0x90, 0x76, 0xe8, 0x1a, 0x48, 0xa7, 0x6e, 0x2e, 0x99, 0x83, 0x62, 0x89, 0x62, 0xe2, 0x4a, 0x42,
-	0x67, 0x0a, 0x59, 0xfb, 0x6a, 0x7d, 0xb1, 0x22, 0x9d, 0xec, 0x34, 0x05, 0x6d, 0xbe, 0xff, 0xfb,
-	0x3f, 0x21, 0x1f, 0x82, 0xd5, 0x09, 0xed, 0x41, 0x1d, 0x0f, 0xdc, 0x74, 0xb5, 0xb1, 0x7a, 0xd0,
-	0x2c, 0x3f, 0xa1, 0xe5, 0xa6, 0x5b, 0x7f, 0x27, 0x50, 0xbc, 0xa1, 0x7d, 0x1f, 0xf8, 0x80, 0x8c,
-	0x41, 0xba, 0x15, 0x02, 0xab, 0xd9, 0xfd, 0xec, 0x21, 0x6d, 0xfc, 0x37, 0xab, 0x60, 0xb1, 0x37,
-	0x82, 0x0f, 0x12, 0xab, 0xc4, 0xc7, 0xe3, 0xe8, 0xc8, 0x4e, 0xf6, 0xd2, 0x91, 0x39, 0x91, 0x30,
-	0x3a, 0xd2, 0x48, 0x3c, 0xab, 0x16, 0xab, 0x94, 0x48, 0x18, 0xd9, 0x1d, 0x94, 0x5b, 0x21, 0x5e,
-	0xad, 0xd5, 0x16, 0xab, 0xcc, 0xb3, 0x4b, 0xe0, 0xe8, 0xde, 0x8c, 0x34, 0x27, 0x3a, 0x05, 0x8e,
-	0xee, 0x64, 0x1f, 0xe8, 0x82, 0xe8, 0x14, 0xf8, 0x93, 0xed, 0x47, 0xa0, 0x45, 0x38, 0x79, 0x0c,
-	0x1c, 0x6d, 0x24, 0x06, 0x5a, 0x12, 0x9d, 0x82, 0xf5, 0x4f, 0x02, 0x19, 0x6d, 0xe0, 0x05, 0x6e,
-	0x15, 0xff, 0x94, 0x68, 0x78, 0x2b, 0x7d, 0xe2, 0x77, 0xb1, 0xdc, 0xac, 0x6a, 0xda, 0x57, 0x3d,
-	0xee, 0xaa, 0xf9, 0xd7, 0x63, 0x8f, 0x50, 0x18, 0x2d, 0xc8, 0x49, 0x22, 0xce, 0xd4, 0x60, 0x1b,
-	0x58, 0x1a, 0xdd, 0x77, 0xed, 0x99, 0x84, 0x79, 0x44, 0xf8, 0x5b, 0x72, 0x77, 0x93, 0x4a, 0x18,
-	0xdd, 0xa9, 0x01, 0x49, 0x4b, 0x63, 0x77, 0xbb, 0xee, 0xb1, 0x67, 0xb8, 0x41, 0x69, 0xbf, 0xba,
-	0xf1, 0x9f, 0xb2, 0x88, 0x77, 0xd5, 0x62, 0x35, 0x94, 0x4a, 0x8b, 0xa0, 0xe4, 0x11, 0xe5, 0x52,
-	0x39, 0xe6, 0xfe, 0x65, 0x3d, 0xfd, 0x06, 0x00, 0x00, 0xff, 0xff, 0x7e, 0xf8, 0x2a, 0x91, 0x6d,
-	0x02, 0x00, 0x00,
+	// 305 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x92, 0xbf, 0x4f, 0xc3, 0x30,
+	0x10, 0x85, 0xd5, 0x36, 0x4d, 0x9b, 0x2b, 0x42, 0xc5, 0x53, 0x06, 0x06, 0xd4, 0x89, 0x01, 0x65,
+	0x28, 0x0c, 0x4c, 0x48, 0x95, 0xca, 0xc4, 0x82, 0x82, 0x32, 0x57, 0x6e, 0x6c, 0x55, 0x51, 0x53,
+	0xdb, 0xf2, 0x19, 0xa4, 0xae, 0xf0, 0x8f, 0xa3, 0xd8, 0xce, 0x8f, 0x82, 0xbc, 0xe5, 0xde, 0xf7,
+	0x3e, 0xcb, 0x3e, 0x05, 0x96, 0x47, 0xd4, 0x3b, 0xb1, 0xdf, 0x51, 0x55, 0x65, 0x4a, 0x4b, 0x23,
+	0x49, 0x7c, 0x44, 0x4d, 0x55, 0xb5, 0xfa, 0x1e, 0xc3, 0xfc, 0x0d, 0xf5, 0x87, 0xa1, 0x06, 0x09,
+	0x81, 0x68, 0xc3, 0x18, 0xa6, 0xa3, 0xbb, 0xd1, 0x7d, 0x94, 0xdb, 0x6f, 0x92, 0xc2, 0xac, 0x50,
+	0x8c, 0x1a, 0x8e, 0xe9, 0xd8, 0xc6, 0xed, 0xd8, 0x90, 0x2d, 0xaf, 0x79, 0x43, 0x26, 0x8e, 0xf8,
+	0xb1, 0x21, 0x39, 0xc7, 0xb3, 0x28, 0x31, 0x8d, 0x1c, 0xf1, 0x23, 0xb9, 0x85, 0x64, 0xc3, 0xd8,
+	0xab, 0xd6, 0x52, 0x63, 0x3a, 0xb5, 0xac, 0x0f, 0x1a, 0x5a, 0xa8, 0x96, 0xc6, 0x8e, 0x16, 0x6a,
+	0x40, 0xb7, 0xbc, 0xf6, 0x74, 0xe6, 0x68, 0x17, 0xd8, 0x93, 0xf5, 0xc1, 0xd3, 0xb9, 0x3f, 0x59,
+	0x1f, 0x7a, 0x9a, 0x73, 0xf4, 0x34, 0x71, 0xb4, 0x0b, 0x56, 0x3f, 0x13, 0x98, 0xba, 0x0d, 0x3c,
+	0xc3, 0xb5, 0xa0, 0x27, 0x8e, 0x8a, 0x96, 0xdc, 0x26, 0x76, 0x17, 0x8b, 0xf5, 0x32, 0x73, 0xfb,
+	0xca, 0xda, 0x5d, 0xe5, 0x7f, 0x7a, 0xe4, 0x01, 0xe6, 0x4a, 0x32, 0xe7, 0x8c, 0x03, 0x4e, 0xd7,
+	0x20, 0x6b, 0x58, 0x28, 0x59, 0x57, 0xe5, 0xd9, 0x09, 0x93, 0x80, 0x30, 0x2c, 0x35, 0x77, 0xe3,
+	0x82, 0x29, 0x59, 0x09, 0x83, 0x4e, 0x8b, 0x42, 0x77, 0xbb, 0xec, 0x91, 0x27, 0xb8, 0x42, 0xae,
+	0xbf, 0xaa, 0xf6, 0x4d, 0xd3, 0x80, 0x77, 0xd1, 0x22, 0x19, 0x24, 0x42, 0x32, 0xaf, 0xc4, 0x01,
+	0xa5, 0xaf, 0x90, 0x17, 0xb8, 0x29, 0x3f, 0xd1, 0xc8, 0xd3, 0xfb, 0xe0, 0x65, 0xb3, 0x80, 0xf7,
+	0xbf, 0xba, 0x8f, 0xed, 0x9f, 0xf9, 0xf8, 0x3b, 0x00, 0xb3, 0x71, 0x4b, 0x0a, 0xad, 0x02, 0x00,
+	0x00,
 }
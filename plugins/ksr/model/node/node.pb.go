@@ -87,6 +87,12 @@ type Node struct {
 	// More info: https://kubernetes.io/docs/concepts/nodes/node/#info
 	// +optional
 	NodeInfo *NodeSystemInfo `protobuf:"bytes,5,opt,name=node_info,json=nodeInfo" json:"node_info,omitempty"`
+	// A list of labels attached to this node.
+	// +optional
+	Label []*Node_Label `protobuf:"bytes,6,rep,name=label" json:"label,omitempty"`
+	// A list of taints attached to this node.
+	// +optional
+	Taint []*Node_Taint `protobuf:"bytes,7,rep,name=taint" json:"taint,omitempty"`
 }
 
 func (m *Node) Reset()                    { *m = Node{} }
@@ -129,6 +135,80 @@ func (m *Node) GetNodeInfo() *NodeSystemInfo {
 	return nil
 }
 
+func (m *Node) GetLabel() []*Node_Label {
+	if m != nil {
+		return m.Label
+	}
+	return nil
+}
+
+func (m *Node) GetTaint() []*Node_Taint {
+	if m != nil {
+		return m.Taint
+	}
+	return nil
+}
+
+// Label is a key/value pair attached to an object (node in this case).
+// Labels are used to organize and to select subsets of objects.
+type Node_Label struct {
+	Key   string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (m *Node_Label) Reset()                    { *m = Node_Label{} }
+func (m *Node_Label) String() string            { return proto.CompactTextString(m) }
+func (*Node_Label) ProtoMessage()               {}
+func (*Node_Label) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0} }
+
+func (m *Node_Label) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Node_Label) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+// Taint attached to the node repels pods that do not tolerate it.
+type Node_Taint struct {
+	Key   string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+	// Effect of the taint (NoSchedule, PreferNoSchedule or NoExecute).
+	Effect string `protobuf:"bytes,3,opt,name=effect" json:"effect,omitempty"`
+}
+
+func (m *Node_Taint) Reset()                    { *m = Node_Taint{} }
+func (m *Node_Taint) String() string            { return proto.CompactTextString(m) }
+func (*Node_Taint) ProtoMessage()               {}
+func (*Node_Taint) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 1} }
+
+func (m *Node_Taint) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Node_Taint) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Node_Taint) GetEffect() string {
+	if m != nil {
+		return m.Effect
+	}
+	return ""
+}
+
 // NodeAddress contains information for the node's address.
 type NodeAddress struct {
 	// Node address type, one of Hostname, ExternalIP or InternalIP.
@@ -263,6 +343,8 @@ func (m *NodeSystemInfo) GetArchitecture() string {
 
 func init() {
 	proto.RegisterType((*Node)(nil), "node.Node")
+	proto.RegisterType((*Node_Label)(nil), "node.Node.Label")
+	proto.RegisterType((*Node_Taint)(nil), "node.Node.Taint")
 	proto.RegisterType((*NodeAddress)(nil), "node.NodeAddress")
 	proto.RegisterType((*NodeSystemInfo)(nil), "node.NodeSystemInfo")
 	proto.RegisterEnum("node.NodeAddress_AddressType", NodeAddress_AddressType_name, NodeAddress_AddressType_value)
@@ -271,36 +353,40 @@ func init() {
 func init() { proto.RegisterFile("node.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 483 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x93, 0xc1, 0x6e, 0xd3, 0x4c,
-	0x10, 0x80, 0x7f, 0x37, 0x6e, 0x1c, 0x8f, 0xfb, 0x27, 0x66, 0x40, 0xea, 0xf6, 0x50, 0x11, 0x59,
-	0x42, 0x44, 0x1c, 0x82, 0x1a, 0x6e, 0xdc, 0x2a, 0x8c, 0xc4, 0x0a, 0x29, 0x54, 0x2e, 0xe1, 0x6a,
-	0x39, 0xf1, 0xb4, 0xb5, 0x12, 0xef, 0x5a, 0xeb, 0x4d, 0x69, 0x5e, 0x80, 0x03, 0x8f, 0xc4, 0xeb,
-	0xf0, 0x22, 0x68, 0xd7, 0x76, 0x52, 0xe8, 0xc9, 0x3b, 0xdf, 0x7c, 0x33, 0xeb, 0xdd, 0xb1, 0x01,
-	0x84, 0xcc, 0x69, 0x5a, 0x29, 0xa9, 0x25, 0xba, 0x66, 0x1d, 0xfd, 0x72, 0xc0, 0x9d, 0xcb, 0x9c,
-	0x10, 0xc1, 0x15, 0x59, 0x49, 0xcc, 0x19, 0x3b, 0x13, 0x3f, 0xb1, 0x6b, 0x3c, 0x83, 0x41, 0x25,
-	0xf3, 0xf4, 0x03, 0x8f, 0x13, 0x76, 0x64, 0xb9, 0x57, 0xc9, 0xdc, 0x84, 0xf8, 0x12, 0x82, 0x4a,
-	0xc9, 0xfb, 0x22, 0x27, 0x95, 0xf2, 0x98, 0xf5, 0x6c, 0x16, 0x3a, 0xc4, 0x63, 0x7c, 0x0b, 0x7e,
-	0x96, 0xe7, 0x8a, 0xea, 0x9a, 0x6a, 0xe6, 0x8e, 0x7b, 0x93, 0x60, 0xf6, 0x6c, 0x6a, 0xb7, 0x37,
-	0xdb, 0x5d, 0x36, 0xa9, 0xe4, 0xe0, 0xe0, 0x05, 0xf8, 0x26, 0x9d, 0x16, 0xe2, 0x46, 0xb2, 0xe3,
-	0xb1, 0x33, 0x09, 0x66, 0x2f, 0x0e, 0x05, 0xd7, 0xbb, 0x5a, 0x53, 0xc9, 0xc5, 0x8d, 0x4c, 0x06,
-	0x06, 0x9a, 0x55, 0xf4, 0xdb, 0x81, 0xe0, 0x51, 0x37, 0xbc, 0x00, 0x57, 0xef, 0xaa, 0xe6, 0x0c,
-	0xc3, 0xd9, 0xf9, 0x93, 0xed, 0xa6, 0xed, 0xf3, 0xeb, 0xae, 0xa2, 0xc4, 0xaa, 0xc8, 0xc0, 0x6b,
-	0x5f, 0xa1, 0x3b, 0x61, 0x1b, 0x46, 0x3f, 0x1c, 0x08, 0x1e, 0xf9, 0xf8, 0x1c, 0x46, 0xa6, 0xd5,
-	0x42, 0xac, 0x85, 0xfc, 0x2e, 0x4c, 0x26, 0xfc, 0x0f, 0x43, 0x38, 0x31, 0xf0, 0x93, 0xac, 0xf5,
-	0x3c, 0x2b, 0x29, 0x74, 0x10, 0x61, 0x68, 0xc8, 0xc7, 0x07, 0x4d, 0x4a, 0x64, 0x1b, 0x7e, 0x15,
-	0x1e, 0x75, 0x8c, 0x8b, 0x3d, 0xeb, 0x75, 0xed, 0x3a, 0x2f, 0x9e, 0x5f, 0x87, 0x6e, 0x07, 0x3b,
-	0xd1, 0xc0, 0xe3, 0xe8, 0x67, 0xaf, 0x29, 0x3f, 0x5c, 0x01, 0x9e, 0x03, 0x94, 0xd9, 0xea, 0xae,
-	0x10, 0x64, 0x2e, 0xbf, 0x19, 0x99, 0xdf, 0x12, 0x1e, 0x9b, 0xe1, 0xd4, 0x56, 0x4e, 0x17, 0x0b,
-	0x1e, 0xb7, 0x07, 0x83, 0x06, 0x19, 0x82, 0xa7, 0xe0, 0x2d, 0xa5, 0xd4, 0x87, 0xc9, 0xf5, 0x4d,
-	0xc8, 0x63, 0x7c, 0x05, 0xc3, 0x35, 0x29, 0x41, 0x9b, 0xf4, 0x9e, 0x54, 0x5d, 0x48, 0xc1, 0x5c,
-	0x9b, 0xff, 0xbf, 0xa1, 0xdf, 0x1a, 0x68, 0x3e, 0x0c, 0x59, 0xa7, 0x45, 0x99, 0xdd, 0x92, 0x1d,
-	0x95, 0x9f, 0x78, 0xb2, 0xe6, 0x26, 0xc4, 0xf7, 0x70, 0xb6, 0x92, 0x42, 0x67, 0x85, 0x20, 0x95,
-	0xaa, 0xad, 0xd0, 0x45, 0x49, 0xfb, 0x66, 0x7d, 0xeb, 0x9e, 0xee, 0x85, 0xa4, 0xc9, 0x77, 0x6d,
-	0x5f, 0xc3, 0x68, 0xbd, 0x5d, 0xd2, 0x86, 0xf4, 0xbe, 0xc2, 0xb3, 0x15, 0xc3, 0x16, 0x77, 0xe2,
-	0x1b, 0x08, 0x3f, 0x6f, 0x97, 0x74, 0xa5, 0xe4, 0xc3, 0xae, 0x65, 0x6c, 0x60, 0xcd, 0x27, 0x1c,
-	0x27, 0x30, 0xfa, 0x52, 0x91, 0xca, 0x74, 0x21, 0x6e, 0x9b, 0x2b, 0x64, 0xbe, 0x55, 0xff, 0xc5,
-	0x18, 0xc1, 0xc9, 0xa5, 0x5a, 0xdd, 0x15, 0x9a, 0x56, 0x7a, 0xab, 0x88, 0x81, 0xd5, 0xfe, 0x62,
-	0xcb, 0xbe, 0xfd, 0x79, 0xde, 0xfd, 0x09, 0x00, 0x00, 0xff, 0xff, 0x51, 0x7c, 0x50, 0x68, 0x4a,
-	0x03, 0x00, 0x00,
+	// 554 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x94, 0x41, 0x6f, 0xd3, 0x40,
+	0x10, 0x85, 0x71, 0x6d, 0xc7, 0xf1, 0xa4, 0x24, 0x66, 0xa9, 0xe8, 0xb6, 0x52, 0x45, 0x14, 0x09,
+	0x88, 0x38, 0xa4, 0x6a, 0xb8, 0x71, 0xab, 0x30, 0x02, 0x0b, 0x14, 0x2a, 0xb7, 0xe1, 0x6a, 0x39,
+	0xf1, 0xa4, 0xb5, 0x62, 0xef, 0x5a, 0xeb, 0x4d, 0x68, 0xee, 0x88, 0x03, 0x7f, 0x93, 0x3f, 0x82,
+	0x76, 0x6d, 0x27, 0x2d, 0xb9, 0x70, 0xca, 0xce, 0xf7, 0xde, 0xcc, 0x2a, 0x6f, 0x47, 0x06, 0x60,
+	0x3c, 0xc1, 0x51, 0x21, 0xb8, 0xe4, 0xc4, 0x52, 0xe7, 0xc1, 0x4f, 0x13, 0xac, 0x09, 0x4f, 0x90,
+	0x10, 0xb0, 0x58, 0x9c, 0x23, 0x35, 0xfa, 0xc6, 0xd0, 0x0d, 0xf5, 0x99, 0x9c, 0x40, 0xbb, 0xe0,
+	0x49, 0xf4, 0x21, 0xf0, 0x43, 0x7a, 0xa0, 0xb9, 0x53, 0xf0, 0x44, 0x95, 0xe4, 0x25, 0x74, 0x0a,
+	0xc1, 0xd7, 0x69, 0x82, 0x22, 0x0a, 0x7c, 0x6a, 0x6a, 0x15, 0x1a, 0x14, 0xf8, 0xe4, 0x1c, 0xdc,
+	0x38, 0x49, 0x04, 0x96, 0x25, 0x96, 0xd4, 0xea, 0x9b, 0xc3, 0xce, 0xf8, 0xd9, 0x48, 0x5f, 0xaf,
+	0xae, 0xbb, 0xac, 0xa4, 0x70, 0xe7, 0x21, 0x17, 0xe0, 0x2a, 0x39, 0x4a, 0xd9, 0x82, 0x53, 0xbb,
+	0x6f, 0x0c, 0x3b, 0xe3, 0xa3, 0x5d, 0xc3, 0xf5, 0xa6, 0x94, 0x98, 0x07, 0x6c, 0xc1, 0xc3, 0xb6,
+	0x82, 0xea, 0x44, 0x5e, 0x83, 0x9d, 0xc5, 0x33, 0xcc, 0x68, 0x4b, 0xcf, 0xf7, 0x76, 0xf6, 0xd1,
+	0x57, 0xc5, 0xc3, 0x4a, 0x56, 0x3e, 0x19, 0xa7, 0x4c, 0x52, 0x67, 0xcf, 0x77, 0xa3, 0x78, 0x58,
+	0xc9, 0xa7, 0xe7, 0x60, 0xeb, 0x3e, 0xe2, 0x81, 0xb9, 0xc4, 0x4d, 0x9d, 0x85, 0x3a, 0x92, 0x23,
+	0xb0, 0xd7, 0x71, 0xb6, 0xc2, 0x3a, 0x87, 0xaa, 0x38, 0xfd, 0x04, 0xb6, 0x1e, 0xf0, 0xbf, 0x0d,
+	0xe4, 0x05, 0xb4, 0x70, 0xb1, 0xc0, 0xb9, 0xac, 0x13, 0xab, 0xab, 0xc1, 0x1f, 0x03, 0x3a, 0x0f,
+	0x72, 0x21, 0x17, 0x60, 0xc9, 0x4d, 0x51, 0xbd, 0x46, 0x77, 0x7c, 0xb6, 0x17, 0xdc, 0xa8, 0xfe,
+	0xbd, 0xd9, 0x14, 0x18, 0x6a, 0x2b, 0xa1, 0xe0, 0xd4, 0x61, 0x36, 0x6f, 0x55, 0x97, 0x83, 0x5f,
+	0x06, 0x74, 0x1e, 0xf8, 0xc9, 0x73, 0xe8, 0xa9, 0x51, 0x53, 0xb6, 0x64, 0xfc, 0x07, 0x53, 0x8a,
+	0xf7, 0x84, 0x78, 0x70, 0xa8, 0xe0, 0x67, 0x5e, 0xca, 0x49, 0x9c, 0xa3, 0x67, 0x10, 0x02, 0x5d,
+	0x45, 0x3e, 0xde, 0x4b, 0x14, 0x2c, 0xce, 0x82, 0x2b, 0xef, 0xa0, 0x61, 0x01, 0xdb, 0x32, 0xb3,
+	0x19, 0xd7, 0xf8, 0xfc, 0xc9, 0xb5, 0x67, 0x35, 0x30, 0x60, 0x3b, 0x68, 0x0f, 0x7e, 0x9b, 0xd0,
+	0x7d, 0xfc, 0x98, 0xe4, 0x0c, 0x20, 0x8f, 0xe7, 0x77, 0x29, 0x43, 0xb5, 0x46, 0x55, 0x7e, 0x6e,
+	0x4d, 0x02, 0x5f, 0xad, 0x59, 0xa9, 0xcd, 0xd1, 0x74, 0x1a, 0xf8, 0xf5, 0x1f, 0x83, 0x0a, 0x29,
+	0x42, 0x8e, 0xc1, 0x99, 0x71, 0x2e, 0x77, 0x3b, 0xd8, 0x52, 0x65, 0xe0, 0x93, 0x57, 0xd0, 0x5d,
+	0xa2, 0x60, 0x98, 0x45, 0x6b, 0x14, 0x65, 0xca, 0x19, 0xb5, 0xb4, 0xfe, 0xb4, 0xa2, 0xdf, 0x2b,
+	0xa8, 0x56, 0x9c, 0x97, 0x51, 0x9a, 0xc7, 0xb7, 0xa8, 0x97, 0xce, 0x0d, 0x1d, 0x5e, 0x06, 0xaa,
+	0x24, 0xef, 0xe1, 0x64, 0xce, 0x99, 0xda, 0x0c, 0x14, 0x91, 0x58, 0x31, 0x99, 0xe6, 0xb8, 0x1d,
+	0xd6, 0xd2, 0xde, 0xe3, 0xad, 0x21, 0xac, 0xf4, 0x66, 0xec, 0x1b, 0xe8, 0x2d, 0x57, 0x33, 0xcc,
+	0x50, 0x6e, 0x3b, 0x1c, 0xdd, 0xd1, 0xad, 0x71, 0x63, 0x7c, 0x0b, 0xde, 0x97, 0xd5, 0x0c, 0xaf,
+	0x04, 0xbf, 0xdf, 0xd4, 0x8c, 0xb6, 0xb5, 0x73, 0x8f, 0x93, 0x21, 0xf4, 0xbe, 0x15, 0x28, 0x62,
+	0x99, 0xb2, 0xdb, 0x2a, 0x42, 0xea, 0x6a, 0xeb, 0xbf, 0x98, 0x0c, 0xe0, 0xf0, 0x52, 0xcc, 0xef,
+	0x52, 0x89, 0x73, 0xb9, 0x12, 0x48, 0x41, 0xdb, 0x1e, 0xb1, 0x59, 0x4b, 0x7f, 0x06, 0xde, 0xfd,
+	0x1d, 0x00, 0x2f, 0x2e, 0x66, 0xe6, 0x14, 0x04, 0x00, 0x00,
 }
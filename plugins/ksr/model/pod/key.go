@@ -40,3 +40,28 @@ func ParsePodFromKey(key string) (pod string, namespace string, err error) {
 func Key(name string, namespace string) string {
 	return ksrkey.Key(PodKeyword, name, namespace)
 }
+
+const (
+	// AnnotationsKeyword defines the keyword identifying requests to annotate
+	// K8s Pods.
+	AnnotationsKeyword = "annotations"
+)
+
+// AnnotationsKeyPrefix returns the key prefix identifying all requests to
+// annotate K8s Pods in the data store.
+func AnnotationsKeyPrefix() string {
+	return ksrkey.KeyPrefix(AnnotationsKeyword)
+}
+
+// ParsePodFromAnnotationsKey parses pod and namespace ids from the key of
+// a request to annotate the pod.
+func ParsePodFromAnnotationsKey(key string) (pod string, namespace string, err error) {
+	return ksrkey.ParseNameFromKey(AnnotationsKeyword, key)
+}
+
+// AnnotationsKey returns the key under which a request to annotate a given
+// K8s pod is stored in the data store. The request is stored as Pod with
+// the name, the namespace and the requested annotations.
+func AnnotationsKey(name string, namespace string) string {
+	return ksrkey.Key(AnnotationsKeyword, name, namespace)
+}
@@ -33,17 +33,20 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 type Pod_Container_Port_Protocol int32
 
 const (
-	Pod_Container_Port_TCP Pod_Container_Port_Protocol = 0
-	Pod_Container_Port_UDP Pod_Container_Port_Protocol = 1
+	Pod_Container_Port_TCP  Pod_Container_Port_Protocol = 0
+	Pod_Container_Port_UDP  Pod_Container_Port_Protocol = 1
+	Pod_Container_Port_SCTP Pod_Container_Port_Protocol = 2
 )
 
 var Pod_Container_Port_Protocol_name = map[int32]string{
 	0: "TCP",
 	1: "UDP",
+	2: "SCTP",
 }
 var Pod_Container_Port_Protocol_value = map[string]int32{
-	"TCP": 0,
-	"UDP": 1,
+	"TCP":  0,
+	"UDP":  1,
+	"SCTP": 2,
 }
 
 func (x Pod_Container_Port_Protocol) String() string {
@@ -80,6 +83,9 @@ type Pod struct {
 	// There must be at least one container in a Pod.
 	// Cannot be updated.
 	Container []*Pod_Container `protobuf:"bytes,6,rep,name=container" json:"container,omitempty"`
+	// A list of annotations attached to this pod.
+	// +optional
+	Annotation []*Pod_Annotation `protobuf:"bytes,7,rep,name=annotation" json:"annotation,omitempty"`
 }
 
 func (m *Pod) Reset()                    { *m = Pod{} }
@@ -129,6 +135,13 @@ func (m *Pod) GetContainer() []*Pod_Container {
 	return nil
 }
 
+func (m *Pod) GetAnnotation() []*Pod_Annotation {
+	if m != nil {
+		return m.Annotation
+	}
+	return nil
+}
+
 // Label is a key/value pair attached to an object (pod in this case).
 // Labels are used to organize and to select subsets of objects.
 type Pod_Label struct {
@@ -206,7 +219,7 @@ type Pod_Container_Port struct {
 	// Port number to expose on the pod's IP address.
 	// The port number is in the range: 0 < x < 65536.
 	ContainerPort int32 `protobuf:"varint,3,opt,name=container_port,json=containerPort" json:"container_port,omitempty"`
-	// Protocol for port. Must be UDP or TCP.
+	// Protocol for port. Must be UDP, TCP or SCTP.
 	// Defaults to "TCP".
 	// +optional
 	Protocol Pod_Container_Port_Protocol `protobuf:"varint,4,opt,name=protocol,enum=pod.Pod_Container_Port_Protocol" json:"protocol,omitempty"`
@@ -255,37 +268,66 @@ func (m *Pod_Container_Port) GetHostIpAddress() string {
 	return ""
 }
 
+// Annotation is a key/value pair attached to an object (pod in this case).
+// Annotations carry non-identifying metadata of the object.
+type Pod_Annotation struct {
+	Key   string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (m *Pod_Annotation) Reset()                    { *m = Pod_Annotation{} }
+func (m *Pod_Annotation) String() string            { return proto.CompactTextString(m) }
+func (*Pod_Annotation) ProtoMessage()               {}
+func (*Pod_Annotation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 2} }
+
+func (m *Pod_Annotation) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Pod_Annotation) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Pod)(nil), "pod.Pod")
 	proto.RegisterType((*Pod_Label)(nil), "pod.Pod.Label")
 	proto.RegisterType((*Pod_Container)(nil), "pod.Pod.Container")
 	proto.RegisterType((*Pod_Container_Port)(nil), "pod.Pod.Container.Port")
+	proto.RegisterType((*Pod_Annotation)(nil), "pod.Pod.Annotation")
 	proto.RegisterEnum("pod.Pod_Container_Port_Protocol", Pod_Container_Port_Protocol_name, Pod_Container_Port_Protocol_value)
 }
 
 func init() { proto.RegisterFile("pod.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 322 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x91, 0xdf, 0x4a, 0xc3, 0x30,
-	0x14, 0xc6, 0xed, 0xd2, 0xce, 0xe5, 0xc8, 0xe6, 0x08, 0x82, 0x61, 0x4e, 0x18, 0x43, 0x65, 0x20,
-	0x54, 0x99, 0xb7, 0xde, 0xc8, 0xbc, 0x11, 0xbc, 0x08, 0x41, 0xaf, 0x47, 0xb6, 0x04, 0x1c, 0xd6,
-	0x25, 0xb4, 0x55, 0xf0, 0xb1, 0xbc, 0xf6, 0x6d, 0x7c, 0x12, 0xc9, 0x69, 0x9b, 0x09, 0x4e, 0xf0,
-	0xaa, 0x27, 0xdf, 0xf9, 0x9d, 0xef, 0xfc, 0x29, 0x50, 0x67, 0x75, 0xea, 0x72, 0x5b, 0x5a, 0x46,
-	0x9c, 0xd5, 0xe3, 0xcf, 0x18, 0x88, 0xb0, 0x9a, 0x31, 0x88, 0xd7, 0xea, 0xc5, 0xf0, 0x68, 0x14,
-	0x4d, 0xa8, 0xc4, 0x98, 0x0d, 0x81, 0xfa, 0x6f, 0xe1, 0xd4, 0xd2, 0xf0, 0x16, 0x26, 0x36, 0x02,
-	0x3b, 0x81, 0x24, 0x53, 0x0b, 0x93, 0x71, 0x32, 0x22, 0x93, 0xbd, 0x69, 0x2f, 0xf5, 0xce, 0xc2,
-	0xea, 0xf4, 0xde, 0xab, 0xb2, 0x4a, 0xb2, 0x63, 0x80, 0x95, 0x9b, 0x2b, 0xad, 0x73, 0x53, 0x14,
-	0x3c, 0xae, 0x4c, 0x56, 0xee, 0xa6, 0x12, 0xd8, 0x19, 0xec, 0x3f, 0xd9, 0xa2, 0x9c, 0xff, 0x60,
-	0x12, 0x64, 0xba, 0x5e, 0xbe, 0x0b, 0xdc, 0x25, 0xd0, 0xa5, 0x5d, 0x97, 0x6a, 0xb5, 0x36, 0x39,
-	0x6f, 0x63, 0x43, 0x16, 0x1a, 0xce, 0x9a, 0x8c, 0xdc, 0x40, 0x83, 0x0b, 0x48, 0x70, 0x10, 0xd6,
-	0x07, 0xf2, 0x6c, 0xde, 0xeb, 0xc5, 0x7c, 0xc8, 0x0e, 0x20, 0x79, 0x53, 0xd9, 0x6b, 0xb3, 0x53,
-	0xf5, 0x18, 0x7c, 0xb4, 0x80, 0x06, 0xa7, 0xad, 0xf7, 0x38, 0x87, 0xd8, 0xd9, 0xbc, 0xe4, 0x2d,
-	0xec, 0x7f, 0xf8, 0xbb, 0x7f, 0x2a, 0x6c, 0x5e, 0x4a, 0x84, 0x06, 0x5f, 0x11, 0xc4, 0xfe, 0xb9,
-	0xd5, 0xe9, 0x08, 0x28, 0xae, 0x5d, 0xdb, 0x45, 0x93, 0x44, 0x76, 0xbc, 0x80, 0x05, 0xa7, 0xd0,
-	0x0b, 0x6b, 0x54, 0x04, 0x41, 0xa2, 0x1b, 0x54, 0xc4, 0xae, 0xa1, 0x83, 0xff, 0x71, 0x69, 0x33,
-	0xbc, 0x6b, 0x6f, 0x3a, 0xfa, 0x63, 0xa2, 0x54, 0xd4, 0x9c, 0x0c, 0x15, 0xff, 0x3d, 0xfc, 0x78,
-	0x08, 0x9d, 0xa6, 0x9a, 0xed, 0x02, 0x79, 0x98, 0x89, 0xfe, 0x8e, 0x0f, 0x1e, 0x6f, 0x45, 0x3f,
-	0x5a, 0xb4, 0xd1, 0xef, 0xea, 0x3b, 0x00, 0x00, 0xff, 0xff, 0x1e, 0x6d, 0x78, 0xcf, 0x56, 0x02,
-	0x00, 0x00,
+	// 358 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x91, 0x4d, 0x4b, 0xf3, 0x40,
+	0x10, 0xc7, 0x9f, 0xbc, 0xb5, 0xc9, 0x3c, 0xb4, 0x86, 0x55, 0x30, 0x44, 0x85, 0x52, 0xb4, 0x14,
+	0x84, 0x28, 0xad, 0x47, 0x2f, 0xa5, 0x5e, 0x04, 0x0f, 0xcb, 0x5a, 0xcf, 0x65, 0xdb, 0x04, 0x0c,
+	0xc6, 0xec, 0x92, 0xac, 0x82, 0xdf, 0xca, 0xbb, 0x5f, 0xc9, 0x0f, 0x21, 0x3b, 0x69, 0xb7, 0x05,
+	0x2b, 0xf4, 0x94, 0xd9, 0xff, 0xfc, 0xe6, 0x25, 0xff, 0x81, 0x40, 0x8a, 0x34, 0x91, 0x95, 0x50,
+	0x82, 0x38, 0x52, 0xa4, 0xfd, 0x4f, 0x0f, 0x1c, 0x2a, 0x52, 0x42, 0xc0, 0x2d, 0xf9, 0x6b, 0x16,
+	0x59, 0x3d, 0x6b, 0x18, 0x30, 0x8c, 0xc9, 0x29, 0x04, 0xfa, 0x5b, 0x4b, 0xbe, 0xcc, 0x22, 0x1b,
+	0x13, 0x1b, 0x81, 0x9c, 0x83, 0x57, 0xf0, 0x45, 0x56, 0x44, 0x4e, 0xcf, 0x19, 0xfe, 0x1f, 0x75,
+	0x13, 0xdd, 0x99, 0x8a, 0x34, 0x79, 0xd0, 0x2a, 0x6b, 0x92, 0xe4, 0x0c, 0x20, 0x97, 0x73, 0x9e,
+	0xa6, 0x55, 0x56, 0xd7, 0x91, 0xdb, 0x34, 0xc9, 0xe5, 0xa4, 0x11, 0xc8, 0x00, 0x0e, 0x9e, 0x45,
+	0xad, 0xe6, 0x5b, 0x8c, 0x87, 0x4c, 0x47, 0xcb, 0xf7, 0x86, 0xbb, 0x86, 0x60, 0x29, 0x4a, 0xc5,
+	0xf3, 0x32, 0xab, 0xa2, 0x16, 0x0e, 0x24, 0x66, 0xe0, 0x74, 0x9d, 0x61, 0x1b, 0x88, 0x8c, 0x01,
+	0x78, 0x59, 0x0a, 0xc5, 0x55, 0x2e, 0xca, 0xa8, 0x8d, 0x25, 0x87, 0xa6, 0x64, 0x62, 0x52, 0x6c,
+	0x0b, 0x8b, 0xaf, 0xc0, 0xc3, 0xed, 0x49, 0x08, 0xce, 0x4b, 0xf6, 0xb1, 0x72, 0x43, 0x87, 0xe4,
+	0x08, 0xbc, 0x77, 0x5e, 0xbc, 0xad, 0x8d, 0x68, 0x1e, 0xf1, 0x97, 0x0d, 0x81, 0x19, 0xbf, 0xd3,
+	0xc4, 0x4b, 0x70, 0xa5, 0xa8, 0x54, 0x64, 0xe3, 0x06, 0xc7, 0xbf, 0x97, 0x4e, 0xa8, 0xa8, 0x14,
+	0x43, 0x28, 0xfe, 0xb6, 0xc0, 0xd5, 0xcf, 0x9d, 0x9d, 0x4e, 0x20, 0x40, 0xaf, 0x56, 0xed, 0xac,
+	0xa1, 0xc7, 0x7c, 0x2d, 0x60, 0xc1, 0x05, 0x74, 0xcd, 0xbf, 0x37, 0x84, 0x83, 0x44, 0xc7, 0xa8,
+	0x88, 0xdd, 0x82, 0x8f, 0xc7, 0x5f, 0x8a, 0x02, 0x8f, 0xd1, 0x1d, 0xf5, 0xfe, 0xd8, 0x28, 0xa1,
+	0x2b, 0x8e, 0x99, 0x8a, 0x7d, 0xaf, 0xd5, 0x1f, 0x80, 0xbf, 0xae, 0x26, 0x6d, 0x70, 0x66, 0x53,
+	0x1a, 0xfe, 0xd3, 0xc1, 0xd3, 0x1d, 0x0d, 0x2d, 0xe2, 0x83, 0xfb, 0x38, 0x9d, 0xd1, 0xd0, 0x8e,
+	0x6f, 0x00, 0x36, 0x87, 0xd8, 0xd7, 0xf3, 0x45, 0x0b, 0xf7, 0x19, 0xff, 0x0c, 0x00, 0x1c, 0xcf,
+	0x96, 0x25, 0xcb, 0x02, 0x00, 0x00,
 }
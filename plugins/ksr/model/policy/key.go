@@ -15,6 +15,9 @@
 package policy
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/contiv/vpp/plugins/ksr/model/ksrkey"
 )
 
@@ -40,3 +43,39 @@ func ParsePolicyFromKey(key string) (policy string, namespace string, err error)
 func Key(name string, namespace string) string {
 	return ksrkey.Key(PolicyKeyword, name, namespace)
 }
+
+const (
+	// StatusKeyword defines the keyword identifying the statuses of the policy
+	// enforcement published by the agents.
+	// The keyword must not start with PolicyKeyword, otherwise the statuses
+	// would be watched as policies.
+	StatusKeyword = "networkpolicystatus"
+
+	// nodeID defines keyword identifying the node of a policy status.
+	nodeID = "node"
+)
+
+// StatusKeyPrefix returns the key prefix identifying all statuses of the policy
+// enforcement in the data store.
+func StatusKeyPrefix() string {
+	return ksrkey.KeyPrefix(StatusKeyword)
+}
+
+// ParseStatusKey parses policy, namespace and node ids from the key of a policy
+// status.
+func ParseStatusKey(key string) (policy string, namespace string, node string, err error) {
+	keywords := strings.Split(key, "/")
+	if len(keywords) == 7 && keywords[5] == nodeID {
+		policy, namespace, err = ksrkey.ParseNameFromKey(StatusKeyword, strings.Join(keywords[:5], "/"))
+		if err == nil {
+			return policy, namespace, keywords[6], nil
+		}
+	}
+	return "", "", "", fmt.Errorf("invalid format of the key %s", key)
+}
+
+// StatusKey returns the key under which the status of the enforcement of a given
+// K8s policy on a given node is stored in the data store.
+func StatusKey(name string, namespace string, node string) string {
+	return ksrkey.Key(StatusKeyword, name, namespace) + "/" + nodeID + "/" + node
+}
@@ -11,6 +11,7 @@ It is generated from these files:
 
 It has these top-level messages:
 	Policy
+	PolicyStatus
 */
 package policy
 
@@ -98,23 +99,26 @@ func (Policy_LabelSelector_LabelExpression_Operator) EnumDescriptor() ([]byte, [
 	return fileDescriptor0, []int{0, 1, 0, 0}
 }
 
-// The protocol (TCP or UDP) which traffic must match.
+// The protocol (TCP, UDP or SCTP) which traffic must match.
 // If not specified, this field defaults to TCP.
 // +optional
 type Policy_Port_Protocol int32
 
 const (
-	Policy_Port_TCP Policy_Port_Protocol = 0
-	Policy_Port_UDP Policy_Port_Protocol = 1
+	Policy_Port_TCP  Policy_Port_Protocol = 0
+	Policy_Port_UDP  Policy_Port_Protocol = 1
+	Policy_Port_SCTP Policy_Port_Protocol = 2
 )
 
 var Policy_Port_Protocol_name = map[int32]string{
 	0: "TCP",
 	1: "UDP",
+	2: "SCTP",
 }
 var Policy_Port_Protocol_value = map[string]int32{
-	"TCP": 0,
-	"UDP": 1,
+	"TCP":  0,
+	"UDP":  1,
+	"SCTP": 2,
 }
 
 func (x Policy_Port_Protocol) String() string {
@@ -569,6 +573,61 @@ func (m *Policy_EgressRule) GetTo() []*Policy_Peer {
 	return nil
 }
 
+// PolicyStatus is the status of the enforcement of a policy on one node,
+// published by the agent of the node for KSR to reflect it back into K8s.
+type PolicyStatus struct {
+	// Name and namespace of the policy.
+	Name      string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Namespace string `protobuf:"bytes,2,opt,name=namespace" json:"namespace,omitempty"`
+	// Node the policy is rendered on.
+	Node string `protobuf:"bytes,3,opt,name=node" json:"node,omitempty"`
+	// Number of the pods of the node selected by the policy.
+	Pods uint32 `protobuf:"varint,4,opt,name=pods" json:"pods,omitempty"`
+	// Error of the last rendering of the policy on the node, empty if rendered
+	// successfully.
+	Error string `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *PolicyStatus) Reset()                    { *m = PolicyStatus{} }
+func (m *PolicyStatus) String() string            { return proto.CompactTextString(m) }
+func (*PolicyStatus) ProtoMessage()               {}
+func (*PolicyStatus) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *PolicyStatus) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *PolicyStatus) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *PolicyStatus) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *PolicyStatus) GetPods() uint32 {
+	if m != nil {
+		return m.Pods
+	}
+	return 0
+}
+
+func (m *PolicyStatus) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Policy)(nil), "policy.Policy")
 	proto.RegisterType((*Policy_Label)(nil), "policy.Policy.Label")
@@ -580,6 +639,7 @@ func init() {
 	proto.RegisterType((*Policy_Peer_IPBlock)(nil), "policy.Policy.Peer.IPBlock")
 	proto.RegisterType((*Policy_IngressRule)(nil), "policy.Policy.IngressRule")
 	proto.RegisterType((*Policy_EgressRule)(nil), "policy.Policy.EgressRule")
+	proto.RegisterType((*PolicyStatus)(nil), "policy.PolicyStatus")
 	proto.RegisterEnum("policy.Policy_PolicyType", Policy_PolicyType_name, Policy_PolicyType_value)
 	proto.RegisterEnum("policy.Policy_LabelSelector_LabelExpression_Operator", Policy_LabelSelector_LabelExpression_Operator_name, Policy_LabelSelector_LabelExpression_Operator_value)
 	proto.RegisterEnum("policy.Policy_Port_Protocol", Policy_Port_Protocol_name, Policy_Port_Protocol_value)
@@ -589,49 +649,52 @@ func init() {
 func init() { proto.RegisterFile("policy.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 703 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x54, 0xdd, 0x6e, 0xd3, 0x4a,
-	0x10, 0x8e, 0x1d, 0xe7, 0xa7, 0xe3, 0x9e, 0xd6, 0xda, 0x56, 0x55, 0x8e, 0x4f, 0x2e, 0xa2, 0x1c,
-	0x24, 0x02, 0x42, 0x06, 0x05, 0x15, 0x55, 0x88, 0x22, 0xb5, 0xc4, 0xa0, 0xa0, 0xd6, 0x31, 0x9b,
-	0x54, 0x20, 0x6e, 0x2c, 0xc7, 0x5d, 0xc0, 0xaa, 0x93, 0xb5, 0x36, 0x0e, 0x6a, 0x9e, 0xa5, 0xef,
-	0xc1, 0x53, 0xf0, 0x1a, 0xdc, 0xf2, 0x0c, 0x68, 0xc7, 0x8e, 0x5d, 0x42, 0x54, 0x0a, 0x57, 0x9e,
-	0x99, 0xfd, 0xbe, 0xd9, 0x99, 0xcf, 0x33, 0x0b, 0x9b, 0x31, 0x8f, 0xc2, 0x60, 0x61, 0xc5, 0x82,
-	0x27, 0x9c, 0x54, 0x53, 0xaf, 0x7d, 0xb5, 0x09, 0x55, 0x17, 0x4d, 0x42, 0x40, 0x9b, 0xfa, 0x13,
-	0xd6, 0x50, 0x5a, 0x4a, 0x67, 0x83, 0xa2, 0x4d, 0x9a, 0xb0, 0x21, 0xbf, 0xb3, 0xd8, 0x0f, 0x58,
-	0x43, 0xc5, 0x83, 0x22, 0x40, 0xee, 0x43, 0x25, 0xf2, 0xc7, 0x2c, 0x6a, 0x94, 0x5b, 0xe5, 0x8e,
-	0xde, 0xdd, 0xb5, 0xb2, 0x2b, 0xd2, 0x84, 0xd6, 0x89, 0x3c, 0xa3, 0x29, 0x84, 0x3c, 0x02, 0x2d,
-	0xe6, 0xe7, 0xb3, 0x86, 0xd6, 0x52, 0x3a, 0x7a, 0xb7, 0xb9, 0x0e, 0x3a, 0x64, 0x11, 0x0b, 0x12,
-	0x2e, 0x28, 0x22, 0xc9, 0x53, 0xd0, 0x53, 0x90, 0x97, 0x2c, 0x62, 0xd6, 0xa8, 0xb4, 0x94, 0xce,
-	0x56, 0xf7, 0xdf, 0x15, 0x62, 0xfa, 0x19, 0x2d, 0x62, 0x46, 0x21, 0xce, 0x6d, 0x72, 0x08, 0x9b,
-	0xe1, 0xf4, 0xa3, 0x60, 0xb3, 0x99, 0x27, 0xe6, 0x11, 0x6b, 0x54, 0xb1, 0x40, 0x73, 0x85, 0xdc,
-	0x4f, 0x21, 0x74, 0x1e, 0x31, 0xaa, 0x87, 0x85, 0x23, 0xaf, 0x66, 0xd7, 0xd8, 0x35, 0x64, 0xaf,
-	0x5e, 0x6d, 0x17, 0x64, 0x60, 0xb9, 0x6d, 0x3e, 0x84, 0x0a, 0x76, 0x43, 0x0c, 0x28, 0x5f, 0xb0,
-	0x45, 0x26, 0xa7, 0x34, 0xc9, 0x2e, 0x54, 0x3e, 0xfb, 0xd1, 0x7c, 0xa9, 0x64, 0xea, 0x98, 0xdf,
-	0x55, 0xf8, 0xe7, 0xa7, 0xfe, 0xc9, 0x3e, 0xe8, 0x13, 0x3f, 0x09, 0x3e, 0x79, 0xa9, 0xba, 0xca,
-	0x0d, 0xea, 0x02, 0x02, 0xd3, 0x0b, 0xdf, 0x82, 0x91, 0xd2, 0xd8, 0x65, 0x2c, 0xcb, 0x09, 0xf9,
-	0xb4, 0xa1, 0x22, 0xf7, 0xc1, 0x4d, 0x72, 0xa7, 0x9e, 0x9d, 0x73, 0xe8, 0x36, 0x66, 0x29, 0x02,
-	0xe6, 0x57, 0x05, 0xb6, 0x57, 0x40, 0x6b, 0xba, 0x7b, 0x03, 0x75, 0x1e, 0x33, 0xe1, 0x27, 0x5c,
-	0x60, 0x83, 0x5b, 0xdd, 0xfd, 0x3f, 0xb9, 0xd6, 0x1a, 0x64, 0x64, 0x9a, 0xa7, 0x29, 0x04, 0x93,
-	0x03, 0xb6, 0x14, 0xac, 0xfd, 0x1c, 0xea, 0x4b, 0x2c, 0xa9, 0x82, 0xda, 0x77, 0x8c, 0x12, 0x01,
-	0xa8, 0x3a, 0x83, 0x91, 0xd7, 0x77, 0x0c, 0x45, 0xda, 0xf6, 0xbb, 0xfe, 0x70, 0x34, 0x34, 0x54,
-	0x42, 0x60, 0xab, 0x37, 0xb0, 0x87, 0x9e, 0x3c, 0xc4, 0xa0, 0x51, 0x36, 0xbf, 0xa8, 0xa0, 0xb9,
-	0x5c, 0x24, 0xe4, 0x00, 0xea, 0xb8, 0x0d, 0x01, 0x97, 0x23, 0x2c, 0x2b, 0x6e, 0xfe, 0x32, 0x5e,
-	0x22, 0xb1, 0xdc, 0x0c, 0x43, 0x73, 0x34, 0x39, 0x90, 0xd3, 0x2c, 0x12, 0x6c, 0x5f, 0xef, 0xde,
-	0x59, 0xcb, 0xe2, 0x22, 0x71, 0xfc, 0x09, 0x1b, 0x08, 0x67, 0x3e, 0x19, 0x33, 0x9c, 0x6a, 0x91,
-	0x98, 0x57, 0x0a, 0x18, 0xab, 0x47, 0xe4, 0x10, 0x34, 0x9c, 0x71, 0x05, 0x8b, 0xb8, 0x77, 0x9b,
-	0x74, 0x16, 0xce, 0x3c, 0xd2, 0xc8, 0x1e, 0x54, 0xa7, 0x18, 0x44, 0xdd, 0x2b, 0x34, 0xf3, 0xf2,
-	0x8d, 0x2e, 0x17, 0x1b, 0xdd, 0x6e, 0x82, 0x86, 0x1b, 0x22, 0x05, 0x3b, 0x3b, 0x3d, 0xb6, 0xa9,
-	0x51, 0x22, 0x75, 0xd0, 0x9c, 0xa3, 0x53, 0xdb, 0x50, 0xda, 0x4d, 0xa8, 0x2f, 0xbb, 0x25, 0x35,
-	0x28, 0x8f, 0x5e, 0xb8, 0x46, 0x49, 0x1a, 0x67, 0x3d, 0xd7, 0x50, 0xcc, 0x6f, 0x0a, 0x68, 0x2e,
-	0x63, 0x22, 0x5f, 0x66, 0xe5, 0xd6, 0xcb, 0xfc, 0x0c, 0x20, 0x7f, 0x37, 0x66, 0x58, 0xe6, 0xef,
-	0x78, 0xd7, 0xf0, 0xe4, 0x09, 0xd4, 0xc3, 0xd8, 0x1b, 0x47, 0x3c, 0xb8, 0xc0, 0x66, 0xf4, 0xee,
-	0x7f, 0xab, 0x1a, 0x31, 0x26, 0xac, 0xbe, 0x7b, 0x2c, 0x21, 0xb4, 0x16, 0xc6, 0x68, 0x98, 0xfb,
-	0x50, 0xcb, 0x62, 0x52, 0x8b, 0x20, 0x3c, 0x17, 0xcb, 0xd7, 0x4d, 0xda, 0x52, 0x37, 0x76, 0x19,
-	0xb0, 0x38, 0xc1, 0x35, 0xd9, 0xa0, 0x99, 0x67, 0x7a, 0xa0, 0x5f, 0x7b, 0x1a, 0xc8, 0xdd, 0xfc,
-	0x67, 0xcb, 0x5d, 0xda, 0x59, 0xf3, 0x77, 0xd2, 0x7f, 0x2b, 0x81, 0x1f, 0x04, 0x9f, 0x64, 0x4b,
-	0xb7, 0xb3, 0xa6, 0x44, 0x8a, 0x00, 0xf3, 0x3d, 0x80, 0xfd, 0x17, 0xf9, 0xff, 0x07, 0x35, 0xe1,
-	0x37, 0x65, 0x57, 0x13, 0xde, 0x7e, 0x0d, 0x50, 0x3c, 0x8a, 0x44, 0x87, 0x5a, 0xcf, 0x7e, 0x79,
-	0x74, 0x76, 0x32, 0x32, 0x4a, 0xd2, 0xe9, 0x3b, 0xaf, 0xa8, 0x3d, 0x1c, 0x66, 0x5b, 0x92, 0xda,
-	0x2a, 0xd9, 0x03, 0x92, 0x1d, 0x78, 0x47, 0x4e, 0xcf, 0xcb, 0xe2, 0xe5, 0x71, 0x15, 0x07, 0xfe,
-	0xf1, 0x8f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x40, 0x91, 0x36, 0x48, 0x3c, 0x06, 0x00, 0x00,
+	// 749 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x55, 0xdd, 0x6e, 0xd3, 0x4a,
+	0x10, 0x8e, 0x1d, 0xe7, 0x6f, 0x9c, 0xb6, 0xd6, 0x9e, 0xaa, 0xf2, 0xf1, 0xc9, 0x45, 0x94, 0x73,
+	0x74, 0x08, 0x08, 0x05, 0x14, 0x54, 0x54, 0x21, 0x8a, 0xd4, 0x36, 0x06, 0x05, 0xb5, 0x8e, 0xb1,
+	0x53, 0x81, 0xb8, 0xb1, 0x1c, 0x67, 0x81, 0xa8, 0x4e, 0xd6, 0xda, 0x6c, 0x50, 0x73, 0xc3, 0x8b,
+	0x70, 0xcd, 0x7b, 0xf0, 0x00, 0xbc, 0x06, 0xb7, 0x3c, 0x03, 0xda, 0x5d, 0xc7, 0x2e, 0x21, 0xaa,
+	0x4a, 0xaf, 0x3c, 0x33, 0xfb, 0x7d, 0xb3, 0x3b, 0xdf, 0xce, 0xac, 0xa1, 0x9e, 0x90, 0x78, 0x12,
+	0x2d, 0x3b, 0x09, 0x25, 0x8c, 0xa0, 0xb2, 0xf4, 0x5a, 0x5f, 0xea, 0x50, 0x76, 0x85, 0x89, 0x10,
+	0x68, 0xb3, 0x70, 0x8a, 0x4d, 0xa5, 0xa9, 0xb4, 0x6b, 0x9e, 0xb0, 0x51, 0x03, 0x6a, 0xfc, 0x3b,
+	0x4f, 0xc2, 0x08, 0x9b, 0xaa, 0x58, 0xc8, 0x03, 0xe8, 0x1e, 0x94, 0xe2, 0x70, 0x84, 0x63, 0xb3,
+	0xd8, 0x2c, 0xb6, 0xf5, 0xee, 0x6e, 0x27, 0xdd, 0x42, 0x26, 0xec, 0x9c, 0xf2, 0x35, 0x4f, 0x42,
+	0xd0, 0x43, 0xd0, 0x12, 0x32, 0x9e, 0x9b, 0x5a, 0x53, 0x69, 0xeb, 0xdd, 0xc6, 0x26, 0xa8, 0x8f,
+	0x63, 0x1c, 0x31, 0x42, 0x3d, 0x81, 0x44, 0x4f, 0x40, 0x97, 0xa0, 0x80, 0x2d, 0x13, 0x6c, 0x96,
+	0x9a, 0x4a, 0x7b, 0xbb, 0xfb, 0xf7, 0x1a, 0x51, 0x7e, 0x86, 0xcb, 0x04, 0x7b, 0x90, 0x64, 0x36,
+	0x3a, 0x84, 0xfa, 0x64, 0xf6, 0x9e, 0xe2, 0xf9, 0x3c, 0xa0, 0x8b, 0x18, 0x9b, 0x65, 0x71, 0x40,
+	0x6b, 0x8d, 0xdc, 0x97, 0x10, 0x6f, 0x11, 0x63, 0x4f, 0x9f, 0xe4, 0x0e, 0xdf, 0x1a, 0x5f, 0x61,
+	0x57, 0x04, 0x7b, 0x7d, 0x6b, 0x3b, 0x27, 0x03, 0xce, 0x6c, 0xeb, 0x01, 0x94, 0x44, 0x35, 0xc8,
+	0x80, 0xe2, 0x05, 0x5e, 0xa6, 0x72, 0x72, 0x13, 0xed, 0x42, 0xe9, 0x63, 0x18, 0x2f, 0x56, 0x4a,
+	0x4a, 0xc7, 0xfa, 0xa1, 0xc2, 0xd6, 0x2f, 0xf5, 0xa3, 0x7d, 0xd0, 0xa7, 0x21, 0x8b, 0x3e, 0x04,
+	0x52, 0x5d, 0xe5, 0x1a, 0x75, 0x41, 0x00, 0xe5, 0x86, 0xaf, 0xc1, 0x90, 0x34, 0x7c, 0x99, 0xf0,
+	0xe3, 0x4c, 0xc8, 0xcc, 0x54, 0x05, 0xf7, 0xfe, 0x75, 0x72, 0x4b, 0xcf, 0xce, 0x38, 0xde, 0x8e,
+	0xc8, 0x92, 0x07, 0xac, 0x6f, 0x0a, 0xec, 0xac, 0x81, 0x36, 0x54, 0xf7, 0x0a, 0xaa, 0x24, 0xc1,
+	0x34, 0x64, 0x84, 0x8a, 0x02, 0xb7, 0xbb, 0xfb, 0x7f, 0xb2, 0x6d, 0x67, 0x90, 0x92, 0xbd, 0x2c,
+	0x4d, 0x2e, 0x18, 0x6f, 0xb0, 0x95, 0x60, 0xad, 0x67, 0x50, 0x5d, 0x61, 0x51, 0x19, 0xd4, 0xbe,
+	0x63, 0x14, 0x10, 0x40, 0xd9, 0x19, 0x0c, 0x83, 0xbe, 0x63, 0x28, 0xdc, 0xb6, 0xdf, 0xf4, 0xfd,
+	0xa1, 0x6f, 0xa8, 0x08, 0xc1, 0x76, 0x6f, 0x60, 0xfb, 0x01, 0x5f, 0x14, 0x41, 0xa3, 0x68, 0x7d,
+	0x55, 0x41, 0x73, 0x09, 0x65, 0xe8, 0x00, 0xaa, 0x62, 0x1a, 0x22, 0xc2, 0x5b, 0x98, 0x9f, 0xb8,
+	0xf1, 0x5b, 0x7b, 0x51, 0xd6, 0x71, 0x53, 0x8c, 0x97, 0xa1, 0xd1, 0x01, 0xef, 0x66, 0xca, 0x44,
+	0xf9, 0x7a, 0xf7, 0xbf, 0x8d, 0x2c, 0x42, 0x99, 0x13, 0x4e, 0xf1, 0x80, 0x3a, 0x8b, 0xe9, 0x08,
+	0x8b, 0xae, 0xa6, 0xcc, 0xfa, 0xac, 0x80, 0xb1, 0xbe, 0x84, 0x0e, 0x41, 0x13, 0x3d, 0xae, 0x88,
+	0x43, 0xdc, 0xbd, 0x49, 0xba, 0x8e, 0xe8, 0x79, 0x41, 0x43, 0x7b, 0x50, 0x9e, 0x89, 0xa0, 0xd0,
+	0xbd, 0xe4, 0xa5, 0x5e, 0x36, 0xd1, 0xc5, 0x7c, 0xa2, 0x5b, 0x0d, 0xd0, 0xc4, 0x84, 0x70, 0xc1,
+	0xce, 0xcf, 0x8e, 0x6d, 0xcf, 0x28, 0xa0, 0x2a, 0x68, 0xce, 0xd1, 0x99, 0x6d, 0x28, 0xad, 0xff,
+	0xa1, 0xba, 0xaa, 0x16, 0x55, 0xa0, 0x38, 0x3c, 0x71, 0x8d, 0x02, 0x37, 0xce, 0x7b, 0xae, 0xa1,
+	0x70, 0x9c, 0x7f, 0x32, 0x74, 0x0d, 0xd5, 0xfa, 0xae, 0x80, 0xe6, 0x62, 0x4c, 0xb3, 0xb1, 0x56,
+	0x6e, 0x3c, 0xd6, 0x4f, 0x01, 0xb2, 0x17, 0x64, 0x6e, 0xaa, 0x37, 0xe0, 0x5d, 0xc1, 0xa3, 0xc7,
+	0x50, 0x9d, 0x24, 0xc1, 0x28, 0x26, 0xd1, 0x85, 0x28, 0x4b, 0xef, 0xfe, 0xb3, 0xae, 0x16, 0xc6,
+	0xb4, 0xd3, 0x77, 0x8f, 0x39, 0xc4, 0xab, 0x4c, 0x12, 0x61, 0x58, 0xfb, 0x50, 0x49, 0x63, 0x5c,
+	0x95, 0x68, 0x32, 0xa6, 0xab, 0x77, 0x8e, 0xdb, 0x5c, 0x41, 0x7c, 0x19, 0xe1, 0x84, 0x89, 0x81,
+	0xa9, 0x79, 0xa9, 0x67, 0x05, 0xa0, 0x5f, 0x79, 0x24, 0xd0, 0x9d, 0xec, 0xda, 0xf9, 0x54, 0xfd,
+	0xb5, 0xe1, 0x9e, 0xe4, 0x2d, 0x73, 0xe0, 0x3b, 0x4a, 0xa6, 0xa6, 0xba, 0x19, 0x88, 0x79, 0x3b,
+	0x70, 0x80, 0xf5, 0x16, 0xc0, 0xbe, 0x45, 0xfe, 0x7f, 0x41, 0x65, 0xe4, 0xba, 0xec, 0x2a, 0x23,
+	0xad, 0x97, 0x00, 0xf9, 0xf3, 0x88, 0x74, 0xa8, 0xf4, 0xec, 0xe7, 0x47, 0xe7, 0xa7, 0x43, 0xa3,
+	0xc0, 0x9d, 0xbe, 0xf3, 0xc2, 0xb3, 0x7d, 0x3f, 0x9d, 0x17, 0x69, 0xab, 0x68, 0x0f, 0x50, 0xba,
+	0x10, 0x1c, 0x39, 0xbd, 0x20, 0x8d, 0x17, 0x5b, 0x9f, 0xa0, 0x2e, 0x73, 0xf9, 0x2c, 0x64, 0x8b,
+	0xf9, 0x2d, 0x7e, 0x16, 0x9c, 0x41, 0xc6, 0x79, 0x33, 0x92, 0xb1, 0x88, 0x65, 0x3f, 0x85, 0xad,
+	0xb4, 0x3f, 0x76, 0xa1, 0x84, 0x29, 0x25, 0x54, 0x3c, 0xf8, 0x35, 0x4f, 0x3a, 0xa3, 0xb2, 0x18,
+	0xbd, 0x47, 0x3f, 0x07, 0x00, 0x48, 0x11, 0xec, 0x1f, 0xc6, 0x06, 0x00, 0x00,
 }
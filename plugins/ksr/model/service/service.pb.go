@@ -146,6 +146,15 @@ type Service struct {
 	// LoadBalancer and ExternalTrafficPolicy is set to Local.
 	// +optional
 	HealthCheckNodePort int32 `protobuf:"varint,12,opt,name=health_check_node_port,json=healthCheckNodePort" json:"health_check_node_port,omitempty"`
+	// LoadBalancer ingress IPs is a list of IP addresses assigned to the service
+	// by the load-balancer (as reported in the service status).
+	// Only applies to Service Type: LoadBalancer
+	// +optional
+	LoadbalancerIngressIps []string `protobuf:"bytes,13,rep,name=loadbalancer_ingress_ips,json=loadbalancerIngressIps" json:"loadbalancer_ingress_ips,omitempty"`
+	// Timeout in seconds of the ClientIP session affinity (sessionAffinityConfig.clientIP.timeoutSeconds).
+	// Only applies if SessionAffinity is set to ClientIP.
+	// +optional
+	SessionAffinityTimeout int32 `protobuf:"varint,14,opt,name=session_affinity_timeout,json=sessionAffinityTimeout" json:"session_affinity_timeout,omitempty"`
 }
 
 func (m *Service) Reset()                    { *m = Service{} }
@@ -237,6 +246,20 @@ func (m *Service) GetHealthCheckNodePort() int32 {
 	return 0
 }
 
+func (m *Service) GetLoadbalancerIngressIps() []string {
+	if m != nil {
+		return m.LoadbalancerIngressIps
+	}
+	return nil
+}
+
+func (m *Service) GetSessionAffinityTimeout() int32 {
+	if m != nil {
+		return m.SessionAffinityTimeout
+	}
+	return 0
+}
+
 // ServicePort contains information on service's port.
 type Service_ServicePort struct {
 	// The name of this port within the service. This must be a DNS_LABEL.
@@ -349,39 +372,41 @@ func init() {
 func init() { proto.RegisterFile("service.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 530 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x53, 0x5d, 0x6f, 0xd3, 0x30,
-	0x14, 0x25, 0x6d, 0xfa, 0x75, 0xb3, 0x8f, 0xca, 0x7c, 0x2c, 0x2a, 0x63, 0x2a, 0x7b, 0xa1, 0x3c,
-	0x50, 0xa1, 0x4e, 0x42, 0x68, 0xf0, 0x32, 0xd0, 0x84, 0xf2, 0x40, 0x99, 0xd2, 0xb2, 0xd7, 0xc8,
-	0x4b, 0xbd, 0x36, 0x9a, 0xb1, 0x2d, 0xdb, 0xad, 0xc8, 0x3f, 0xe2, 0x95, 0xff, 0xc4, 0x0f, 0x41,
-	0xbe, 0x4e, 0xbb, 0x56, 0x9a, 0x10, 0x4f, 0xb9, 0x3e, 0xe7, 0xdc, 0xdc, 0x13, 0xdf, 0x13, 0xd8,
-	0x37, 0x4c, 0xaf, 0x8a, 0x9c, 0x0d, 0x95, 0x96, 0x56, 0x92, 0x56, 0x75, 0x3c, 0xfd, 0xdd, 0x82,
-	0xd6, 0xc4, 0xd7, 0x84, 0x40, 0x28, 0xe8, 0x0f, 0x16, 0x07, 0xfd, 0x60, 0xd0, 0x49, 0xb1, 0x26,
-	0xc7, 0xd0, 0x71, 0x4f, 0xa3, 0x68, 0xce, 0xe2, 0x1a, 0x12, 0xf7, 0x00, 0x79, 0x0b, 0xa1, 0x92,
-	0xda, 0xc6, 0xf5, 0x7e, 0x7d, 0x10, 0x8d, 0x8e, 0x87, 0xeb, 0x21, 0x93, 0xdd, 0xe7, 0x95, 0xd4,
-	0x36, 0x45, 0x25, 0x39, 0x87, 0xb6, 0x61, 0x9c, 0xe5, 0x56, 0xea, 0x38, 0xc4, 0xae, 0x93, 0x07,
-	0xba, 0xbc, 0xe0, 0x52, 0x58, 0x5d, 0xa6, 0x1b, 0x3d, 0x79, 0x01, 0x90, 0xf3, 0xa5, 0xb1, 0x4c,
-	0x67, 0x85, 0x8a, 0x1b, 0xde, 0x4c, 0x85, 0x24, 0x8a, 0xbc, 0x84, 0xbd, 0xea, 0x4d, 0x99, 0x2d,
-	0x15, 0x8b, 0x9b, 0x28, 0x88, 0x2a, 0x6c, 0x5a, 0x2a, 0xe6, 0x24, 0xec, 0xa7, 0x65, 0x5a, 0x50,
-	0x9e, 0x15, 0xca, 0xc4, 0xad, 0x7e, 0xdd, 0x49, 0xd6, 0x58, 0xa2, 0x0c, 0x79, 0x0d, 0x5d, 0xc3,
-	0x8c, 0x29, 0xa4, 0xc8, 0xe8, 0xed, 0x6d, 0x21, 0x0a, 0x5b, 0xc6, 0x6d, 0x7c, 0xd3, 0x61, 0x85,
-	0x5f, 0x54, 0x30, 0x79, 0x05, 0x87, 0x5c, 0xd2, 0xd9, 0x0d, 0xe5, 0x54, 0xe4, 0xde, 0x54, 0x07,
-	0x95, 0x07, 0xdb, 0x70, 0xa2, 0xc8, 0x47, 0xe8, 0xed, 0x08, 0x8d, 0x5c, 0xea, 0x9c, 0x65, 0x9a,
-	0x8a, 0x39, 0x33, 0x31, 0xa0, 0x89, 0x78, 0x5b, 0x31, 0x41, 0x41, 0x8a, 0x3c, 0x79, 0x07, 0x47,
-	0x1b, 0xd3, 0x56, 0x3b, 0x53, 0x79, 0xa6, 0x24, 0x2f, 0xf2, 0x32, 0x8e, 0x70, 0xdc, 0xd3, 0x35,
-	0x3d, 0xf5, 0xec, 0x15, 0x92, 0xe4, 0x0c, 0x9e, 0x2d, 0x18, 0xe5, 0x76, 0x91, 0xe5, 0x0b, 0x96,
-	0xdf, 0x65, 0x42, 0xce, 0x58, 0x86, 0xeb, 0xda, 0xeb, 0x07, 0x83, 0x46, 0xfa, 0xd8, 0xb3, 0x9f,
-	0x1d, 0x39, 0x96, 0x33, 0xdc, 0x52, 0xef, 0x4f, 0x0d, 0xa2, 0xad, 0xad, 0x3d, 0x98, 0x89, 0x1e,
-	0xb4, 0x31, 0x45, 0xb9, 0xe4, 0x55, 0x24, 0x36, 0x67, 0xa7, 0xaf, 0x12, 0xe1, 0x46, 0xf8, 0x9d,
-	0x27, 0x10, 0x59, 0xaa, 0xe7, 0xcc, 0xfa, 0xe9, 0x61, 0x3f, 0x18, 0x44, 0xa3, 0xc1, 0xbf, 0xc2,
-	0x32, 0x4c, 0x84, 0xfd, 0xa6, 0x27, 0x56, 0x17, 0x62, 0x9e, 0x82, 0x6f, 0x46, 0x3b, 0xcf, 0xa1,
-	0x73, 0xff, 0x19, 0x0d, 0x9c, 0xd1, 0x16, 0x6b, 0xef, 0xbf, 0x02, 0x88, 0xb6, 0x1a, 0xc9, 0x05,
-	0x84, 0x18, 0x04, 0xe7, 0xfd, 0x60, 0xf4, 0xe6, 0x7f, 0x07, 0x0e, 0x5d, 0x54, 0x52, 0x6c, 0x25,
-	0x47, 0xd0, 0x2a, 0x84, 0xcd, 0x56, 0xd4, 0x7f, 0x69, 0x23, 0x6d, 0x16, 0xc2, 0x5e, 0x53, 0xee,
-	0xb2, 0x68, 0x50, 0x8d, 0x5c, 0xdd, 0x67, 0xd1, 0x23, 0xd7, 0x94, 0x9f, 0x9e, 0x40, 0x88, 0x81,
-	0x03, 0x68, 0x8e, 0xbf, 0x7f, 0xfd, 0x74, 0x99, 0x76, 0x1f, 0xb9, 0x7a, 0x32, 0x4d, 0x93, 0xf1,
-	0x97, 0x6e, 0xd0, 0xfb, 0x00, 0xfb, 0x3b, 0x29, 0x27, 0x5d, 0xa8, 0xdf, 0xb1, 0xb2, 0xba, 0x66,
-	0x57, 0x92, 0x27, 0xd0, 0x58, 0x51, 0xbe, 0x5c, 0xff, 0x75, 0xfe, 0x70, 0x5e, 0x7b, 0x1f, 0xdc,
-	0x34, 0xf1, 0xb6, 0xcf, 0xfe, 0x06, 0x00, 0x00, 0xff, 0xff, 0xc4, 0x53, 0xa4, 0x4a, 0xd4, 0x03,
-	0x00, 0x00,
+	// 570 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x53, 0x5f, 0x6f, 0xd3, 0x3e,
+	0x14, 0xfd, 0x65, 0x4d, 0xff, 0xdd, 0x6c, 0x5d, 0xe5, 0x1f, 0x74, 0x56, 0x19, 0x53, 0xd9, 0x0b,
+	0xe5, 0x81, 0x0a, 0x75, 0x12, 0xaa, 0x06, 0x2f, 0x03, 0x4d, 0x28, 0x0f, 0x94, 0x29, 0x2d, 0x7b,
+	0x8d, 0xbc, 0xd4, 0x6b, 0xa3, 0x65, 0x76, 0x64, 0xbb, 0x15, 0xf9, 0x40, 0x48, 0x7c, 0x30, 0x3e,
+	0x08, 0xf2, 0x75, 0xda, 0xb5, 0xd3, 0x84, 0x78, 0xca, 0xf5, 0x3d, 0xe7, 0xe6, 0x1e, 0x9f, 0x9c,
+	0xc0, 0x81, 0xe6, 0x6a, 0x95, 0x26, 0x7c, 0x90, 0x2b, 0x69, 0x24, 0xa9, 0x97, 0xc7, 0xd3, 0x9f,
+	0x0d, 0xa8, 0x4f, 0x5c, 0x4d, 0x08, 0xf8, 0x82, 0xdd, 0x73, 0xea, 0xf5, 0xbc, 0x7e, 0x33, 0xc2,
+	0x9a, 0x1c, 0x43, 0xd3, 0x3e, 0x75, 0xce, 0x12, 0x4e, 0xf7, 0x10, 0x78, 0x68, 0x90, 0x77, 0xe0,
+	0xe7, 0x52, 0x19, 0x5a, 0xe9, 0x55, 0xfa, 0xc1, 0xf0, 0x78, 0xb0, 0x5e, 0x32, 0xd9, 0x7d, 0x5e,
+	0x49, 0x65, 0x22, 0x64, 0x92, 0x73, 0x68, 0x68, 0x9e, 0xf1, 0xc4, 0x48, 0x45, 0x7d, 0x9c, 0x3a,
+	0x79, 0x62, 0xca, 0x11, 0x2e, 0x85, 0x51, 0x45, 0xb4, 0xe1, 0x93, 0x97, 0x00, 0x49, 0xb6, 0xd4,
+	0x86, 0xab, 0x38, 0xcd, 0x69, 0xd5, 0x89, 0x29, 0x3b, 0x61, 0x4e, 0x5e, 0xc1, 0x7e, 0xf9, 0xa6,
+	0xd8, 0x14, 0x39, 0xa7, 0x35, 0x24, 0x04, 0x65, 0x6f, 0x5a, 0xe4, 0xdc, 0x52, 0xf8, 0x0f, 0xc3,
+	0x95, 0x60, 0x59, 0x9c, 0xe6, 0x9a, 0xd6, 0x7b, 0x15, 0x4b, 0x59, 0xf7, 0xc2, 0x5c, 0x93, 0x37,
+	0xd0, 0xd6, 0x5c, 0xeb, 0x54, 0x8a, 0x98, 0xdd, 0xde, 0xa6, 0x22, 0x35, 0x05, 0x6d, 0xe0, 0x9b,
+	0x0e, 0xcb, 0xfe, 0x45, 0xd9, 0x26, 0xaf, 0xe1, 0x30, 0x93, 0x6c, 0x76, 0xc3, 0x32, 0x26, 0x12,
+	0x27, 0xaa, 0x89, 0xcc, 0xd6, 0x76, 0x3b, 0xcc, 0xc9, 0x47, 0xe8, 0xee, 0x10, 0xb5, 0x5c, 0xaa,
+	0x84, 0xc7, 0x8a, 0x89, 0x39, 0xd7, 0x14, 0x50, 0x04, 0xdd, 0x66, 0x4c, 0x90, 0x10, 0x21, 0x4e,
+	0xde, 0xc3, 0xd1, 0x46, 0xb4, 0x51, 0x56, 0x54, 0x12, 0xe7, 0x32, 0x4b, 0x93, 0x82, 0x06, 0xb8,
+	0xee, 0xf9, 0x1a, 0x9e, 0x3a, 0xf4, 0x0a, 0x41, 0x72, 0x06, 0x9d, 0x05, 0x67, 0x99, 0x59, 0xc4,
+	0xc9, 0x82, 0x27, 0x77, 0xb1, 0x90, 0x33, 0x1e, 0xe3, 0xe7, 0xda, 0xef, 0x79, 0xfd, 0x6a, 0xf4,
+	0xbf, 0x43, 0x3f, 0x5b, 0x70, 0x2c, 0x67, 0xf8, 0x95, 0xc8, 0x08, 0xe8, 0xee, 0x9d, 0xc4, 0x5c,
+	0x71, 0xad, 0xd1, 0xad, 0x03, 0x14, 0xda, 0xd9, 0xb9, 0x9c, 0x83, 0xad, 0x71, 0x23, 0xa0, 0x8f,
+	0x8d, 0x8b, 0x4d, 0x7a, 0xcf, 0xe5, 0xd2, 0xd0, 0x16, 0x2e, 0xec, 0x3c, 0x32, 0x70, 0xea, 0xd0,
+	0xee, 0xef, 0x3d, 0x08, 0xb6, 0x92, 0xf2, 0x64, 0x0e, 0xbb, 0xd0, 0xc0, 0xe4, 0x26, 0x32, 0x2b,
+	0x63, 0xb8, 0x39, 0x5b, 0x7e, 0x99, 0x42, 0xbb, 0x05, 0x6b, 0x12, 0x42, 0x60, 0x98, 0x9a, 0x73,
+	0xe3, 0x6e, 0xec, 0xf7, 0xbc, 0x7e, 0x30, 0xec, 0xff, 0x2d, 0xa0, 0x83, 0x50, 0x98, 0x6f, 0x6a,
+	0x62, 0x54, 0x2a, 0xe6, 0x11, 0xb8, 0x61, 0x94, 0xf3, 0x02, 0x9a, 0x0f, 0xd6, 0x55, 0x71, 0x47,
+	0x43, 0x94, 0x7e, 0x75, 0x7f, 0x79, 0x10, 0x6c, 0x0d, 0x92, 0x0b, 0xf0, 0x31, 0x7c, 0x56, 0x7b,
+	0x6b, 0xf8, 0xf6, 0x5f, 0x17, 0x0e, 0x6c, 0x3c, 0x23, 0x1c, 0x25, 0x47, 0x50, 0x4f, 0x85, 0x89,
+	0x57, 0xcc, 0xdd, 0xb4, 0x1a, 0xd5, 0x52, 0x61, 0xae, 0x59, 0x66, 0xf3, 0xaf, 0x91, 0x8d, 0x58,
+	0xc5, 0xe5, 0xdf, 0x75, 0xae, 0x59, 0x76, 0x7a, 0x02, 0x3e, 0x86, 0x1c, 0xa0, 0x36, 0xfe, 0xfe,
+	0xf5, 0xd3, 0x65, 0xd4, 0xfe, 0xcf, 0xd6, 0x93, 0x69, 0x14, 0x8e, 0xbf, 0xb4, 0xbd, 0xee, 0x07,
+	0x38, 0xd8, 0xf9, 0xb3, 0x48, 0x1b, 0x2a, 0x77, 0xbc, 0x28, 0x6d, 0xb6, 0x25, 0x79, 0x06, 0xd5,
+	0x15, 0xcb, 0x96, 0xeb, 0x3f, 0xdd, 0x1d, 0xce, 0xf7, 0x46, 0xde, 0x4d, 0x0d, 0xdd, 0x3e, 0xfb,
+	0x33, 0x00, 0xee, 0xc0, 0x56, 0x30, 0x48, 0x04, 0x00, 0x00,
 }
@@ -15,6 +15,7 @@
 package ksr
 
 import (
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -76,6 +77,8 @@ func TestNamespaceReflector(t *testing.T) {
 	t.Run("addDeleteNamespace", testAddDeleteNamespace)
 	nsTestVars.mockKvBroker.ClearDs()
 	t.Run("updateNamespace", testUpdateeNamespace)
+	nsTestVars.mockKvBroker.ClearDs()
+	t.Run("repairDrift", testRepairDriftNamespace)
 }
 
 func testAddDeleteNamespace(t *testing.T) {
@@ -186,3 +189,54 @@ func testUpdateeNamespace(t *testing.T) {
 	gomega.Expect(nsProtoNew.Label).To(gomega.ContainElement(&proto.Namespace_Label{Key: "privileged", Value: "false"}))
 
 }
+
+func testRepairDriftNamespace(t *testing.T) {
+	newNamespace := func(name string, privileged string) *coreV1.Namespace {
+		ns := &coreV1.Namespace{}
+		ns.Name = name
+		ns.Labels = map[string]string{"privileged": privileged}
+		return ns
+	}
+	nsOK := newNamespace("namespace-ok", "true")
+	nsMissing := newNamespace("namespace-missing", "true")
+	nsStale := newNamespace("namespace-stale", "true")
+
+	MockK8sCache.ListFunc = func() []interface{} {
+		return []interface{}{nsOK, nsMissing, nsStale}
+	}
+	defer func() { MockK8sCache.ListFunc = nil }()
+
+	// The data store drifted from the K8s state: one namespace is missing,
+	// one is outdated and one no longer exists in K8s.
+	nsTestVars.k8sListWatch.Add(nsOK)
+	nsTestVars.mockKvBroker.Put(proto.Key(nsStale.GetName()),
+		nsTestVars.nsReflector.namespaceToProto(newNamespace(nsStale.GetName(), "false")))
+	nsTestVars.mockKvBroker.Put(proto.Key("namespace-extra"),
+		nsTestVars.nsReflector.namespaceToProto(newNamespace("namespace-extra", "true")))
+
+	drift, err := nsTestVars.nsReflector.repairDrift()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(drift).To(gomega.Equal(driftStats{missing: 1, stale: 1, extra: 1}))
+	gomega.Expect(nsTestVars.mockKvBroker.ds).To(gomega.HaveLen(3))
+
+	nsProto := &proto.Namespace{}
+	found, _, err := nsTestVars.mockKvBroker.GetValue(proto.Key(nsStale.GetName()), nsProto)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(found).To(gomega.BeTrue())
+	gomega.Expect(nsProto.Label).To(gomega.ContainElement(&proto.Namespace_Label{Key: "privileged", Value: "true"}))
+
+	// Nothing to repair the second time.
+	drift, err = nsTestVars.nsReflector.repairDrift()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(drift.total()).To(gomega.BeZero())
+
+	// Failed repair marks the reflector out of sync.
+	nsTestVars.mockKvBroker.Delete(proto.Key(nsMissing.GetName()))
+	nsTestVars.mockKvBroker.injectReadWriteError(errors.New("data store down"), 1)
+	_, err = nsTestVars.nsReflector.repairDrift()
+	gomega.Expect(err).ToNot(gomega.BeNil())
+	for !nsTestVars.nsReflector.HasSynced() {
+		time.Sleep(time.Millisecond * 100)
+	}
+	gomega.Expect(nsTestVars.mockKvBroker.ds).To(gomega.HaveLen(3))
+}
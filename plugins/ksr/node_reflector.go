@@ -16,6 +16,7 @@ package ksr
 
 import (
 	"reflect"
+	"sort"
 	"sync"
 
 	coreV1 "k8s.io/api/core/v1"
@@ -125,6 +126,19 @@ func (nr *NodeReflector) nodeToProto(k8sNode *coreV1.Node) *node.Node {
 	nodeProto.Addresses = getNodeAddresses(k8sNode.Status.Addresses)
 	nodeProto.NodeInfo = getNodeInfo(k8sNode.Status.NodeInfo)
 
+	for key, val := range k8sNode.GetLabels() {
+		nodeProto.Label = append(nodeProto.Label, &node.Node_Label{Key: key, Value: val})
+	}
+	// keep the order stable, so that the node is not updated in the data store if the labels did not change
+	sort.Slice(nodeProto.Label, func(i, j int) bool {
+		return nodeProto.Label[i].Key < nodeProto.Label[j].Key
+	})
+
+	for _, taint := range k8sNode.Spec.Taints {
+		nodeProto.Taint = append(nodeProto.Taint,
+			&node.Node_Taint{Key: taint.Key, Value: taint.Value, Effect: string(taint.Effect)})
+	}
+
 	return nodeProto
 }
 
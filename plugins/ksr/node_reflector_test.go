@@ -70,6 +70,10 @@ func TestNodeReflector(t *testing.T) {
 				Generation:      1,
 				CreationTimestamp: metaV1.Date(2018, 01, 14, 18, 53, 37, 0,
 					time.FixedZone("PST", -800)),
+				Labels: map[string]string{
+					"node-role.kubernetes.io/master": "",
+					"kubernetes.io/hostname":         "master",
+				},
 			},
 			Spec: coreV1.NodeSpec{
 				PodCIDR:    "10.20.30.40/24",
@@ -149,6 +153,10 @@ func TestNodeReflector(t *testing.T) {
 			Spec: coreV1.NodeSpec{
 				PodCIDR:    "100.200.210.220/24",
 				ProviderID: "Provider2",
+				Taints: []coreV1.Taint{
+					{Key: "node-role.kubernetes.io/master", Effect: coreV1.TaintEffectNoSchedule},
+					{Key: "dedicated", Value: "db", Effect: coreV1.TaintEffectNoExecute},
+				},
 			},
 			Status: coreV1.NodeStatus{
 				NodeInfo: coreV1.NodeSystemInfo{
@@ -352,6 +360,18 @@ func checkNodeToProtoTranslation(t *testing.T, protoNode *node.Node, k8sNode *co
 	gomega.Expect(protoNode.NodeInfo.OperatingSystem).To(gomega.Equal(k8sNode.Status.NodeInfo.OperatingSystem))
 	gomega.Expect(protoNode.NodeInfo.OsImage).To(gomega.Equal(k8sNode.Status.NodeInfo.OSImage))
 
+	gomega.Expect(protoNode.Label).To(gomega.HaveLen(len(k8sNode.GetLabels())))
+	for _, label := range protoNode.Label {
+		gomega.Expect(label.Value).To(gomega.Equal(k8sNode.GetLabels()[label.Key]))
+	}
+
+	gomega.Expect(protoNode.Taint).To(gomega.HaveLen(len(k8sNode.Spec.Taints)))
+	for i, taint := range protoNode.Taint {
+		gomega.Expect(taint.Key).To(gomega.Equal(k8sNode.Spec.Taints[i].Key))
+		gomega.Expect(taint.Value).To(gomega.Equal(k8sNode.Spec.Taints[i].Value))
+		gomega.Expect(taint.Effect).To(gomega.BeEquivalentTo(k8sNode.Spec.Taints[i].Effect))
+	}
+
 	for i, addr := range protoNode.Addresses {
 		switch addr.Type {
 		case node.NodeAddress_NodeHostName:
@@ -19,26 +19,52 @@
 //go:generate protoc -I ./model/endpoints --go_out=plugins=grpc:./model/endpoints ./model/endpoints/endpoints.proto
 //go:generate protoc -I ./model/node --go_out=plugins=grpc:./model/node ./model/node/node.proto
 //go:generate protoc -I ./model/ksrapi --go_out=plugins=grpc:./model/ksrapi ./model/ksrapi/ksr_nb_api.proto
+//go:generate protoc -I ./model/event --go_out=plugins=grpc:./model/event ./model/event/event.proto
+//go:generate protoc -I ./model/custompolicy --go_out=plugins=grpc:./model/custompolicy ./model/custompolicy/custompolicy.proto
 
 package ksr
 
 import (
 	"fmt"
 	"github.com/contiv/vpp/plugins/ksr/model/ksrapi"
+	"os"
 	"sync"
 	"time"
 
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedCoreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/ligato/cn-infra/config"
 	"github.com/ligato/cn-infra/datasync/kvdbsync"
+	"github.com/ligato/cn-infra/db/keyval"
 	"github.com/ligato/cn-infra/flavors/local"
 	"github.com/ligato/cn-infra/health/statuscheck"
 	"github.com/ligato/cn-infra/health/statuscheck/model/status"
 	"github.com/ligato/cn-infra/logging"
+	prometheusplugin "github.com/ligato/cn-infra/rpc/prometheus"
 	"github.com/ligato/cn-infra/utils/safeclose"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/contiv/vpp/plugins/ksr/model/event"
+)
+
+const (
+	// defaultDriftCheckPeriod is the default period of the drift repair.
+	defaultDriftCheckPeriod = 5 * time.Minute
+
+	// labels of the drift metric
+	reflectorLabel = "reflector"
+	driftLabel     = "drift"
 )
 
 // Plugin watches K8s resources and causes all changes to be reflected in the ETCD
@@ -54,14 +80,53 @@ type Plugin struct {
 
 	StatusMonitor statuscheck.StatusReader
 
-	nsReflector        *NamespaceReflector
-	podReflector       *PodReflector
-	policyReflector    *PolicyReflector
-	serviceReflector   *ServiceReflector
-	endpointsReflector *EndpointsReflector
-	nodeReflector      *NodeReflector
+	nsReflector           *NamespaceReflector
+	podReflector          *PodReflector
+	policyReflector       *PolicyReflector
+	serviceReflector      *ServiceReflector
+	endpointsReflector    *EndpointsReflector
+	sliceReflector        *EndpointSliceReflector
+	nodeReflector         *NodeReflector
+	customPolicyReflector *CustomPolicyReflector
+
+	podAnnotator        *PodAnnotator
+	policyStatusUpdater *PolicyStatusUpdater
+	eventRecorder       *EventRecorder
+
+	// events reported by the agents are recorded with one K8s recorder per node
+	eventBroadcaster record.EventBroadcaster
+	eventSink        watch.Interface
+	eventRecorders   map[string]record.EventRecorder
+	eventLock        sync.Mutex
+
+	// leaderElector is nil if the leader election is disabled.
+	leaderElector *LeaderElector
+
+	driftCheckPeriod time.Duration
+	driftRepairs     *prometheus.CounterVec
 
 	etcdMonitor EtcdMonitor
+
+	// localStore and localPrefix are set if the plugin runs inside the Contiv agent
+	// (see StateStore), the K8s state is then reflected into memory instead of
+	// the data store of Publish.
+	localStore  keyval.KvProtoPlugin
+	localPrefix string
+}
+
+// Config holds the KSR configuration.
+type Config struct {
+	// LeaderElection allows to run multiple KSR replicas, only the elected
+	// leader reflects the K8s state into the data store.
+	LeaderElection LeaderElectionConfig `json:"leader-election"`
+
+	// DriftCheckPeriod is the period of the reconciliation of the data store
+	// with the K8s state, which repairs missing and stale items.
+	DriftCheckPeriod time.Duration `json:"drift-check-period"`
+
+	// Coalescing configures the coalescing and the rate limiting of the data
+	// store updates per reflector.
+	Coalescing CoalescingConfigs `json:"coalescing"`
 }
 
 // EtcdMonitor defines the state data for the Etcd Monitor
@@ -84,23 +149,29 @@ type Deps struct {
 	// broker is used to propagate changes into a key-value datastore.
 	// contiv-ksr uses ETCD as datastore.
 	Publish *kvdbsync.Plugin
+	// Prometheus is optional, used to expose the number of repaired items.
+	Prometheus prometheusplugin.API
 }
 
 // Reflector object types
 const (
-	namespaceObjType = "Namespace"
-	podObjType       = "Pod"
-	policyObjType    = "NetworkPolicy"
-	endpointsObjType = "Endpoints"
-	serviceObjType   = "Service"
-	nodeObjType      = "Node"
+	namespaceObjType    = "Namespace"
+	podObjType          = "Pod"
+	policyObjType       = "NetworkPolicy"
+	endpointsObjType    = "Endpoints"
+	sliceObjType        = "EndpointSlice"
+	serviceObjType      = "Service"
+	nodeObjType         = "Node"
+	customPolicyObjType = "CustomNetworkPolicy"
 )
 
 // Init builds K8s client-set based on the supplied kubeconfig and initializes
 // all reflectors.
 func (plugin *Plugin) Init() error {
 	var err error
-	plugin.Log.SetLevel(logging.DebugLevel)
+	if plugin.localStore == nil {
+		plugin.Log.SetLevel(logging.DebugLevel)
+	}
 	plugin.stopCh = make(chan struct{})
 
 	kubeconfig := plugin.KubeConfig.GetConfigName()
@@ -115,9 +186,39 @@ func (plugin *Plugin) Init() error {
 		return fmt.Errorf("failed to build kubernetes client: %s", err)
 	}
 
-	ksrPrefix := plugin.Publish.ServiceLabel.GetAgentPrefix()
+	config := &Config{}
+	if plugin.PluginConfig != nil {
+		if _, err := plugin.PluginConfig.GetValue(config); err != nil {
+			return fmt.Errorf("failed to load KSR config: %s", err)
+		}
+	}
+	if config.LeaderElection.Enabled && plugin.localStore == nil {
+		identity, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to get identity for the leader election: %s", err)
+		}
+		plugin.leaderElector = NewLeaderElector(plugin.Log.NewLogger("-leader-election"), identity,
+			config.LeaderElection, plugin.k8sClientset)
+	}
+	plugin.driftCheckPeriod = config.DriftCheckPeriod
+	if plugin.driftCheckPeriod == 0 {
+		plugin.driftCheckPeriod = defaultDriftCheckPeriod
+	}
+	if plugin.Prometheus != nil {
+		plugin.driftRepairs = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "contiv",
+			Subsystem: "ksr",
+			Name:      "drift_repairs_total",
+			Help:      "Number of data store items repaired by the reconciliation with the K8s state",
+		}, []string{reflectorLabel, driftLabel})
+		if err := plugin.Prometheus.Register(prometheusplugin.DefaultRegistry, plugin.driftRepairs); err != nil {
+			return err
+		}
+	}
 
-	plugin.etcdMonitor.broker = plugin.Publish.Deps.KvPlugin.NewBroker(ksrPrefix)
+	store, ksrPrefix := plugin.dataStore()
+
+	plugin.etcdMonitor.broker = store.NewBroker(ksrPrefix)
 	plugin.etcdMonitor.status = status.OperationalState_INIT
 	plugin.etcdMonitor.lastRev = 0
 
@@ -126,9 +227,10 @@ func (plugin *Plugin) Init() error {
 			Log:          plugin.Log.NewLogger("-namespace"),
 			K8sClientset: plugin.k8sClientset,
 			K8sListWatch: &k8sCache{},
-			Broker:       plugin.Publish.Deps.KvPlugin.NewBroker(ksrPrefix),
+			Broker:       store.NewBroker(ksrPrefix),
 			dsSynced:     false,
 			objType:      namespaceObjType,
+			Coalescing:   config.Coalescing.forObjType(namespaceObjType),
 		},
 	}
 
@@ -144,9 +246,10 @@ func (plugin *Plugin) Init() error {
 			Log:          plugin.Log.NewLogger("-pod"),
 			K8sClientset: plugin.k8sClientset,
 			K8sListWatch: &k8sCache{},
-			Broker:       plugin.Publish.Deps.KvPlugin.NewBroker(ksrPrefix),
+			Broker:       store.NewBroker(ksrPrefix),
 			dsSynced:     false,
 			objType:      podObjType,
+			Coalescing:   config.Coalescing.forObjType(podObjType),
 		},
 	}
 
@@ -162,9 +265,10 @@ func (plugin *Plugin) Init() error {
 			Log:          plugin.Log.NewLogger("-policy"),
 			K8sClientset: plugin.k8sClientset,
 			K8sListWatch: &k8sCache{},
-			Broker:       plugin.Publish.Deps.KvPlugin.NewBroker(ksrPrefix),
+			Broker:       store.NewBroker(ksrPrefix),
 			dsSynced:     false,
 			objType:      policyObjType,
+			Coalescing:   config.Coalescing.forObjType(policyObjType),
 		},
 	}
 
@@ -180,9 +284,10 @@ func (plugin *Plugin) Init() error {
 			Log:          plugin.Log.NewLogger("-service"),
 			K8sClientset: plugin.k8sClientset,
 			K8sListWatch: &k8sCache{},
-			Broker:       plugin.Publish.Deps.KvPlugin.NewBroker(ksrPrefix),
+			Broker:       store.NewBroker(ksrPrefix),
 			dsSynced:     false,
 			objType:      serviceObjType,
+			Coalescing:   config.Coalescing.forObjType(serviceObjType),
 		},
 	}
 
@@ -193,22 +298,51 @@ func (plugin *Plugin) Init() error {
 		return err
 	}
 
-	plugin.endpointsReflector = &EndpointsReflector{
-		Reflector: Reflector{
-			Log:          plugin.Log.NewLogger("-endpoints"),
-			K8sClientset: plugin.k8sClientset,
-			K8sListWatch: &k8sCache{},
-			Broker:       plugin.Publish.Deps.KvPlugin.NewBroker(ksrPrefix),
-			dsSynced:     false,
-			objType:      endpointsObjType,
-		},
-	}
+	if plugin.endpointSlicesSupported() {
+		// Endpoints of services are reflected from EndpointSlices, which
+		// (unlike Endpoints) are not limited in size.
+		sliceClient, err := newEndpointSliceClient(plugin.k8sClientConfig)
+		if err != nil {
+			return fmt.Errorf("failed to build kubernetes endpoint slice client: %s", err)
+		}
 
-	//plugin.endpointsReflector.Log.SetLevel(logging.DebugLevel)
-	err = plugin.endpointsReflector.Init(plugin.stopCh, &plugin.wg)
-	if err != nil {
-		plugin.Log.WithField("rwErr", err).Error("Failed to initialize Endpoints reflector")
-		return err
+		plugin.sliceReflector = &EndpointSliceReflector{
+			Reflector: Reflector{
+				Log:          plugin.Log.NewLogger("-endpointslice"),
+				K8sClientset: plugin.k8sClientset,
+				K8sListWatch: &k8sCache{},
+				Broker:       store.NewBroker(ksrPrefix),
+				dsSynced:     false,
+				objType:      sliceObjType,
+				Coalescing:   config.Coalescing.forObjType(sliceObjType),
+			},
+			K8sSliceClient: sliceClient,
+		}
+
+		err = plugin.sliceReflector.Init(plugin.stopCh, &plugin.wg)
+		if err != nil {
+			plugin.Log.WithField("rwErr", err).Error("Failed to initialize EndpointSlice reflector")
+			return err
+		}
+	} else {
+		plugin.endpointsReflector = &EndpointsReflector{
+			Reflector: Reflector{
+				Log:          plugin.Log.NewLogger("-endpoints"),
+				K8sClientset: plugin.k8sClientset,
+				K8sListWatch: &k8sCache{},
+				Broker:       store.NewBroker(ksrPrefix),
+				dsSynced:     false,
+				objType:      endpointsObjType,
+				Coalescing:   config.Coalescing.forObjType(endpointsObjType),
+			},
+		}
+
+		//plugin.endpointsReflector.Log.SetLevel(logging.DebugLevel)
+		err = plugin.endpointsReflector.Init(plugin.stopCh, &plugin.wg)
+		if err != nil {
+			plugin.Log.WithField("rwErr", err).Error("Failed to initialize Endpoints reflector")
+			return err
+		}
 	}
 
 	plugin.nodeReflector = &NodeReflector{
@@ -216,9 +350,10 @@ func (plugin *Plugin) Init() error {
 			Log:          plugin.Log.NewLogger("-node"),
 			K8sClientset: plugin.k8sClientset,
 			K8sListWatch: &k8sCache{},
-			Broker:       plugin.Publish.Deps.KvPlugin.NewBroker(ksrPrefix),
+			Broker:       store.NewBroker(ksrPrefix),
 			dsSynced:     false,
 			objType:      nodeObjType,
+			Coalescing:   config.Coalescing.forObjType(nodeObjType),
 		},
 	}
 
@@ -229,25 +364,204 @@ func (plugin *Plugin) Init() error {
 		return err
 	}
 
+	if plugin.customPoliciesSupported() {
+		customPolicyClient, err := newCustomPolicyClient(plugin.k8sClientConfig)
+		if err != nil {
+			return fmt.Errorf("failed to build kubernetes custom policy client: %s", err)
+		}
+
+		plugin.customPolicyReflector = &CustomPolicyReflector{
+			Reflector: Reflector{
+				Log:          plugin.Log.NewLogger("-custompolicy"),
+				K8sClientset: plugin.k8sClientset,
+				K8sListWatch: &k8sCache{},
+				Broker:       store.NewBroker(ksrPrefix),
+				dsSynced:     false,
+				objType:      customPolicyObjType,
+				Coalescing:   config.Coalescing.forObjType(customPolicyObjType),
+			},
+			K8sPolicyClient: customPolicyClient,
+		}
+
+		err = plugin.customPolicyReflector.Init(plugin.stopCh, &plugin.wg)
+		if err != nil {
+			plugin.Log.WithField("rwErr", err).Error("Failed to initialize CustomNetworkPolicy reflector")
+			return err
+		}
+	}
+
+	plugin.podAnnotator = &PodAnnotator{
+		Log:      plugin.Log.NewLogger("-pod-annotator"),
+		Broker:   store.NewBroker(ksrPrefix),
+		Watcher:  store.NewWatcher(ksrPrefix),
+		PatchPod: plugin.patchK8sPod,
+	}
+
+	if plugin.localStore == nil {
+		// inside the agent only the status of this node would be known
+		plugin.policyStatusUpdater = &PolicyStatusUpdater{
+			Log:         plugin.Log.NewLogger("-policy-status"),
+			Broker:      store.NewBroker(ksrPrefix),
+			Watcher:     store.NewWatcher(ksrPrefix),
+			PatchPolicy: plugin.patchK8sPolicy,
+		}
+	}
+
+	plugin.eventBroadcaster = record.NewBroadcaster()
+	plugin.eventRecorders = make(map[string]record.EventRecorder)
+	plugin.eventRecorder = &EventRecorder{
+		Log:         plugin.Log.NewLogger("-event-recorder"),
+		Broker:      store.NewBroker(ksrPrefix),
+		Watcher:     store.NewWatcher(ksrPrefix),
+		RecordEvent: plugin.recordK8sEvent,
+		Active:      plugin.isLeader,
+	}
+
 	return nil
 }
 
 // AfterInit starts all reflectors. They have to be started in AfterInit so that
 // the kvdbsync is fully initialized and ready for publishing when a k8s
-// notification comes.
+// notification comes. The pod annotator and the policy status updater are
+// started here as well, when the data store connection is ready.
 func (plugin *Plugin) AfterInit() error {
 	startReflectors()
-	go plugin.monitorEtcdStatus(plugin.stopCh)
+	if plugin.localStore != nil {
+		// the in-memory store is always up and cannot drift from the K8s state
+		dataStoreUpEvent()
+	} else {
+		if plugin.leaderElector != nil {
+			plugin.wg.Add(1)
+			go func() {
+				defer plugin.wg.Done()
+				plugin.leaderElector.Run(plugin.stopCh)
+			}()
+		}
+		go plugin.monitorEtcdStatus(plugin.stopCh)
+		plugin.wg.Add(1)
+		go plugin.repairDrift()
+	}
+
+	err := plugin.podAnnotator.Init()
+	if err != nil {
+		plugin.Log.WithField("rwErr", err).Error("Failed to initialize Pod annotator")
+		return err
+	}
+
+	if plugin.policyStatusUpdater != nil {
+		err = plugin.policyStatusUpdater.Init()
+		if err != nil {
+			plugin.Log.WithField("rwErr", err).Error("Failed to initialize Policy status updater")
+			return err
+		}
+	}
+
+	plugin.eventSink = plugin.eventBroadcaster.StartRecordingToSink(
+		&typedCoreV1.EventSinkImpl{Interface: plugin.k8sClientset.CoreV1().Events("")})
+	err = plugin.eventRecorder.Init()
+	if err != nil {
+		plugin.Log.WithField("rwErr", err).Error("Failed to initialize Event recorder")
+		return err
+	}
 
 	return nil
 }
 
+// dataStore returns the key-value store into which the K8s state is reflected,
+// together with the prefix of the KSR keys.
+func (plugin *Plugin) dataStore() (keyval.KvProtoPlugin, string) {
+	if plugin.localStore != nil {
+		return plugin.localStore, plugin.localPrefix
+	}
+	return plugin.Publish.Deps.KvPlugin, plugin.Publish.ServiceLabel.GetAgentPrefix()
+}
+
+// endpointSlicesSupported returns true if the K8s API server serves EndpointSlices.
+func (plugin *Plugin) endpointSlicesSupported() bool {
+	resources, err := plugin.k8sClientset.Discovery().ServerResourcesForGroupVersion(endpointSliceGroupVersion.String())
+	if err != nil {
+		plugin.Log.WithField("err", err).Info("EndpointSlices not available, reflecting Endpoints")
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == "endpointslices" {
+			plugin.Log.Info("Reflecting EndpointSlices instead of Endpoints")
+			return true
+		}
+	}
+	return false
+}
+
+// customPoliciesSupported returns true if the CustomNetworkPolicy CRD is registered
+// in the K8s API server.
+func (plugin *Plugin) customPoliciesSupported() bool {
+	resources, err := plugin.k8sClientset.Discovery().ServerResourcesForGroupVersion(customPolicyGroupVersion.String())
+	if err != nil {
+		plugin.Log.WithField("err", err).Info("CustomNetworkPolicy CRD not registered, custom policies are not reflected")
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == customPolicyResource {
+			return true
+		}
+	}
+	return false
+}
+
+// patchK8sPod applies the given JSON merge patch onto the K8s pod.
+func (plugin *Plugin) patchK8sPod(namespace string, name string, patch []byte) error {
+	_, err := plugin.k8sClientset.CoreV1().Pods(namespace).Patch(name, types.MergePatchType, patch)
+	return err
+}
+
+// patchK8sPolicy applies the given JSON merge patch onto the K8s NetworkPolicy.
+func (plugin *Plugin) patchK8sPolicy(namespace string, name string, patch []byte) error {
+	_, err := plugin.k8sClientset.NetworkingV1().NetworkPolicies(namespace).Patch(name, types.MergePatchType, patch)
+	return err
+}
+
+// recordK8sEvent records the event reported by an agent as a Warning K8s Event
+// of the pod or node. Events of the objects that no longer exist are dropped.
+func (plugin *Plugin) recordK8sEvent(ev *event.Event) error {
+	var object runtime.Object
+	var err error
+	switch ev.Kind {
+	case event.KindPod:
+		object, err = plugin.k8sClientset.CoreV1().Pods(ev.Namespace).Get(ev.Name, metaV1.GetOptions{})
+	case event.KindNode:
+		object, err = plugin.k8sClientset.CoreV1().Nodes().Get(ev.Name, metaV1.GetOptions{})
+	default:
+		return fmt.Errorf("unsupported kind of the event object: %s", ev.Kind)
+	}
+	if k8sErrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	plugin.eventLock.Lock()
+	recorder, has := plugin.eventRecorders[ev.Node]
+	if !has {
+		recorder = plugin.eventBroadcaster.NewRecorder(scheme.Scheme,
+			coreV1.EventSource{Component: "contiv-agent", Host: ev.Node})
+		plugin.eventRecorders[ev.Node] = recorder
+	}
+	plugin.eventLock.Unlock()
+
+	recorder.Event(object, coreV1.EventTypeWarning, ev.Reason, ev.Message)
+	return nil
+}
+
 // Close stops all reflectors.
 func (plugin *Plugin) Close() error {
 	close(plugin.stopCh)
-	safeclose.CloseAll(plugin.nsReflector, plugin.podReflector, plugin.policyReflector,
-		plugin.serviceReflector, plugin.endpointsReflector)
 	plugin.wg.Wait()
+	if plugin.eventSink != nil {
+		plugin.eventSink.Stop()
+	}
+	safeclose.CloseAll(plugin.nsReflector, plugin.podReflector, plugin.policyReflector,
+		plugin.serviceReflector, plugin.endpointsReflector, plugin.sliceReflector, plugin.customPolicyReflector)
 	return nil
 }
 
@@ -261,8 +575,15 @@ func (plugin *Plugin) monitorEtcdStatus(closeCh chan struct{}) {
 		case <-time.After(1 * time.Second):
 			sts := plugin.StatusMonitor.GetAllPluginStatus()
 			for k, v := range sts {
-				if k == "etcdv3" {
-					plugin.etcdMonitor.processEtcdMonitorEvent(v.State)
+				// Status of the data store plugin in use (etcd or Consul).
+				if k == "etcdv3" || k == "consul" {
+					state := v.State
+					if !plugin.isLeader() {
+						// Replicas other than the leader keep only their K8s caches
+						// up-to-date, as if the data store was down.
+						state = status.OperationalState_INIT
+					}
+					plugin.etcdMonitor.processEtcdMonitorEvent(state)
 					plugin.etcdMonitor.checkEtcdTransientError()
 					break
 				}
@@ -271,6 +592,49 @@ func (plugin *Plugin) monitorEtcdStatus(closeCh chan struct{}) {
 	}
 }
 
+// repairDrift periodically reconciles the data store with the K8s caches
+// of all reflectors. Transient data store failures would otherwise leave
+// the missing or stale items unrepaired until the next full resync.
+func (plugin *Plugin) repairDrift() {
+	defer plugin.wg.Done()
+	ticker := time.NewTicker(plugin.driftCheckPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-plugin.stopCh:
+			return
+		case <-ticker.C:
+			if !plugin.isLeader() {
+				continue
+			}
+			for _, r := range reflectors {
+				drift, err := r.repairDrift()
+				if err != nil {
+					r.Log.WithField("err", err).Warnf("%s: drift repair failed", r.objType)
+				}
+				if drift.total() > 0 {
+					r.Log.WithFields(logging.Fields{
+						"missing": drift.missing,
+						"stale":   drift.stale,
+						"extra":   drift.extra,
+					}).Warnf("%s: repaired data store items drifted from K8s state", r.objType)
+				}
+				if plugin.driftRepairs != nil {
+					plugin.driftRepairs.WithLabelValues(r.objType, "missing").Add(float64(drift.missing))
+					plugin.driftRepairs.WithLabelValues(r.objType, "stale").Add(float64(drift.stale))
+					plugin.driftRepairs.WithLabelValues(r.objType, "extra").Add(float64(drift.extra))
+				}
+			}
+		}
+	}
+}
+
+// isLeader returns true if this KSR replica should reflect the K8s state
+// into the data store.
+func (plugin *Plugin) isLeader() bool {
+	return plugin.leaderElector == nil || plugin.leaderElector.IsLeader()
+}
+
 // processEtcdMonitorEvent processes ectd plugin's status events and, if an
 // Etcd problem is detected, generates a resync event for all reflectors.
 func (etcdm *EtcdMonitor) processEtcdMonitorEvent(ns status.OperationalState) {
@@ -338,7 +702,7 @@ func getKsrStats() *ksrapi.Stats {
 	stats := ksrapi.Stats{}
 	for _, v := range reflectors {
 		switch v.objType {
-		case endpointsObjType:
+		case endpointsObjType, sliceObjType:
 			stats.EndpointsStats = &v.stats
 		case namespaceObjType:
 			stats.NamespaceStats = &v.stats
@@ -350,6 +714,8 @@ func getKsrStats() *ksrapi.Stats {
 			stats.ServiceStats = &v.stats
 		case nodeObjType:
 			stats.NodeStats = &v.stats
+		case customPolicyObjType:
+			stats.CustomPolicyStats = &v.stats
 		default:
 			v.Log.WithField("ksrObjectType", v.objType).
 				Error("Plugin stats sees unknown reflector object type")
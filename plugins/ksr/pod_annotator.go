@@ -0,0 +1,133 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"encoding/json"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/logging"
+
+	"github.com/contiv/vpp/plugins/ksr/model/pod"
+)
+
+// PodAnnotator applies annotations requested by Contiv agents onto K8s pods.
+// The agents cannot access K8s API, so they publish the requests into the data
+// store (as Pods with the requested annotations, see pod.AnnotationsKey) and
+// KSR patches the pods accordingly.
+type PodAnnotator struct {
+	// Log is the logger of the annotator.
+	Log logging.Logger
+	// Broker is used to read the requests at startup.
+	Broker KeyProtoValBroker
+	// Watcher is used to watch the requests published later.
+	Watcher keyval.ProtoWatcher
+	// PatchPod applies the given merge patch onto the K8s pod.
+	PatchPod func(namespace string, name string, patch []byte) error
+}
+
+// Init applies the requests already present in the data store and starts
+// watching for new requests.
+func (pa *PodAnnotator) Init() error {
+	it, err := pa.Broker.ListValues(pod.AnnotationsKeyPrefix())
+	if err != nil {
+		return err
+	}
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+		request := &pod.Pod{}
+		if err := kv.GetValue(request); err != nil {
+			pa.Log.WithField("key", kv.GetKey()).Error("Failed to read the pod annotation request")
+			continue
+		}
+		pa.annotatePod(request)
+	}
+
+	return pa.Watcher.Watch(pa.handleRequest, nil, pod.AnnotationsKeyPrefix())
+}
+
+// handleRequest applies request to annotate a pod published into the data store.
+// The annotations of a withdrawn request are removed from the pod.
+func (pa *PodAnnotator) handleRequest(resp keyval.ProtoWatchResp) {
+	request := &pod.Pod{}
+	if resp.GetChangeType() == datasync.Delete {
+		found, err := resp.GetPrevValue(request)
+		if err != nil || !found {
+			pa.Log.WithField("key", resp.GetKey()).Warn("Unable to read the withdrawn pod annotation request")
+			return
+		}
+		pa.removeAnnotations(request)
+		return
+	}
+	if err := resp.GetValue(request); err != nil {
+		pa.Log.WithField("key", resp.GetKey()).Error("Failed to read the pod annotation request")
+		return
+	}
+	pa.annotatePod(request)
+}
+
+// annotatePod patches the K8s pod with the annotations from the request.
+func (pa *PodAnnotator) annotatePod(request *pod.Pod) {
+	annotations := map[string]interface{}{}
+	for _, annotation := range request.Annotation {
+		annotations[annotation.Key] = annotation.Value
+	}
+	pa.patchAnnotations(request, annotations)
+}
+
+// removeAnnotations removes the annotations of the withdrawn request from the K8s pod.
+func (pa *PodAnnotator) removeAnnotations(request *pod.Pod) {
+	annotations := map[string]interface{}{}
+	for _, annotation := range request.Annotation {
+		// null removes the key in the merge patch
+		annotations[annotation.Key] = nil
+	}
+	pa.patchAnnotations(request, annotations)
+}
+
+// patchAnnotations applies the given annotations onto the K8s pod of the request.
+func (pa *PodAnnotator) patchAnnotations(request *pod.Pod, annotations map[string]interface{}) {
+	if len(annotations) == 0 {
+		return
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		pa.Log.WithField("pod", request.Name).Error(err)
+		return
+	}
+
+	err = pa.PatchPod(request.Namespace, request.Name, patch)
+	if k8sErrors.IsNotFound(err) {
+		// the pod has been removed together with its annotations
+		return
+	}
+	if err != nil {
+		pa.Log.WithFields(map[string]interface{}{"pod": request.Name, "namespace": request.Namespace}).
+			Errorf("Failed to annotate the pod: %v", err)
+		return
+	}
+	pa.Log.WithFields(map[string]interface{}{"pod": request.Name, "namespace": request.Namespace}).
+		Debug("Pod annotated")
+}
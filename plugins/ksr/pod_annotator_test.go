@@ -0,0 +1,139 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/onsi/gomega"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/flavors/local"
+
+	"github.com/contiv/vpp/plugins/ksr/model/pod"
+)
+
+// mockProtoWatcher is a mock implementation of keyval.ProtoWatcher which
+// only remembers the callback.
+type mockProtoWatcher struct {
+	callback func(keyval.ProtoWatchResp)
+	keys     []string
+}
+
+func (mock *mockProtoWatcher) Watch(respChan func(keyval.ProtoWatchResp), closeChan chan string, key ...string) error {
+	mock.callback = respChan
+	mock.keys = key
+	return nil
+}
+
+// mockProtoWatchResp is a mock implementation of keyval.ProtoWatchResp.
+type mockProtoWatchResp struct {
+	changeType datasync.PutDel
+	key        string
+	value      proto.Message
+	prevValue  proto.Message
+}
+
+func (mock *mockProtoWatchResp) GetChangeType() datasync.PutDel {
+	return mock.changeType
+}
+
+func (mock *mockProtoWatchResp) GetKey() string {
+	return mock.key
+}
+
+func (mock *mockProtoWatchResp) GetValue(value proto.Message) error {
+	proto.Merge(value, mock.value)
+	return nil
+}
+
+func (mock *mockProtoWatchResp) GetRevision() int64 {
+	return 0
+}
+
+func (mock *mockProtoWatchResp) GetPrevValue(prevValue proto.Message) (bool, error) {
+	if mock.prevValue == nil {
+		return false, nil
+	}
+	proto.Merge(prevValue, mock.prevValue)
+	return true, nil
+}
+
+func TestPodAnnotator(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	flavorLocal := &local.FlavorLocal{}
+	flavorLocal.Inject()
+
+	broker := newMockKeyProtoValBroker()
+	watcher := &mockProtoWatcher{}
+	patches := map[string]map[string]map[string]*string{}
+
+	annotator := &PodAnnotator{
+		Log:     flavorLocal.LoggerFor("pod-annotator"),
+		Broker:  broker,
+		Watcher: watcher,
+		PatchPod: func(namespace string, name string, patch []byte) error {
+			data := map[string]map[string]map[string]*string{}
+			gomega.Expect(json.Unmarshal(patch, &data)).To(gomega.Succeed())
+			patches[namespace+"/"+name] = data["metadata"]
+			return nil
+		},
+	}
+
+	// the request published before the start is applied by Init
+	request := &pod.Pod{
+		Name:       "pod1",
+		Namespace:  "default",
+		Annotation: []*pod.Pod_Annotation{{Key: "contivpp.io/memif", Value: "{}"}},
+	}
+	broker.Put(pod.AnnotationsKey(request.Name, request.Namespace), request)
+
+	err := annotator.Init()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(watcher.keys).To(gomega.ConsistOf(pod.AnnotationsKeyPrefix()))
+	gomega.Expect(patches["default/pod1"]["annotations"]).To(gomega.HaveKeyWithValue("contivpp.io/memif", gomega.Equal(proto.String("{}"))))
+
+	// the request published later is applied when watched
+	request = &pod.Pod{
+		Name:       "pod2",
+		Namespace:  "ns1",
+		Annotation: []*pod.Pod_Annotation{{Key: "a", Value: "b"}},
+	}
+	watcher.callback(&mockProtoWatchResp{
+		changeType: datasync.Put,
+		key:        pod.AnnotationsKey(request.Name, request.Namespace),
+		value:      request,
+	})
+	gomega.Expect(patches["ns1/pod2"]["annotations"]).To(gomega.HaveKeyWithValue("a", gomega.Equal(proto.String("b"))))
+
+	// the annotations of the withdrawn request are removed from the pod
+	watcher.callback(&mockProtoWatchResp{
+		changeType: datasync.Delete,
+		key:        pod.AnnotationsKey(request.Name, request.Namespace),
+		prevValue:  request,
+	})
+	gomega.Expect(patches["ns1/pod2"]["annotations"]).To(gomega.HaveKeyWithValue("a", gomega.BeNil()))
+
+	// withdrawn request without the previous value does not patch the pod
+	watcher.callback(&mockProtoWatchResp{
+		changeType: datasync.Delete,
+		key:        pod.AnnotationsKey("pod3", "ns1"),
+	})
+	gomega.Expect(patches).NotTo(gomega.HaveKey("ns1/pod3"))
+}
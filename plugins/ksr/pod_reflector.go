@@ -26,6 +26,13 @@ import (
 	"github.com/contiv/vpp/plugins/ksr/model/pod"
 )
 
+// lastAppliedConfigAnnotation stores the whole pod spec applied by kubectl, it is not reflected
+// to keep the data store small.
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// protocolSCTP is the SCTP protocol, not yet defined by the vendored K8s API.
+const protocolSCTP coreV1.Protocol = "SCTP"
+
 // PodReflector subscribes to K8s cluster to watch for changes in the
 // configuration of k8s pods. Protobuf-modelled changes are published
 // into the selected key-value store.
@@ -128,6 +135,12 @@ func (pr *PodReflector) podToProto(k8sPod *coreV1.Pod) *pod.Pod {
 
 		}
 	}
+	for key, val := range k8sPod.GetAnnotations() {
+		if key == lastAppliedConfigAnnotation {
+			continue
+		}
+		podProto.Annotation = append(podProto.Annotation, &pod.Pod_Annotation{Key: key, Value: val})
+	}
 	podProto.IpAddress = k8sPod.Status.PodIP
 	podProto.HostIpAddress = k8sPod.Status.HostIP
 	for _, container := range k8sPod.Spec.Containers {
@@ -152,6 +165,8 @@ func (pr *PodReflector) containerToProto(container *coreV1.Container) *pod.Pod_C
 			portProto.Protocol = pod.Pod_Container_Port_TCP
 		case coreV1.ProtocolUDP:
 			portProto.Protocol = pod.Pod_Container_Port_UDP
+		case protocolSCTP:
+			portProto.Protocol = pod.Pod_Container_Port_SCTP
 		}
 		portProto.HostIpAddress = port.HostIP
 		containerProto.Port = append(containerProto.Port, portProto)
@@ -75,6 +75,10 @@ func TestPodReflector(t *testing.T) {
 				CreationTimestamp: metav1.Date(2017, 12, 28, 19, 58, 37, 0,
 					time.FixedZone("PST", -800)),
 				Labels: map[string]string{"ksrRun": "my-nginx"},
+				Annotations: map[string]string{
+					"contivpp.io/ip-address":                           "10.1.1.10",
+					"kubectl.kubernetes.io/last-applied-configuration": "{}",
+				},
 			},
 			Spec: coreV1.PodSpec{
 				Containers: []coreV1.Container{
@@ -341,6 +345,10 @@ func testAddDeletePod(t *testing.T) {
 	gomega.Expect(protoPod.Namespace).To(gomega.Equal(k8sPod.GetNamespace()))
 	gomega.Expect(protoPod.Namespace).To(gomega.Equal(k8sPod.GetNamespace()))
 
+	gomega.Expect(protoPod.Annotation).To(gomega.HaveLen(1))
+	gomega.Expect(protoPod.Annotation[0].Key).To(gomega.Equal("contivpp.io/ip-address"))
+	gomega.Expect(protoPod.Annotation[0].Value).To(gomega.Equal("10.1.1.10"))
+
 	gomega.Expect(protoPod.HostIpAddress).To(gomega.Equal(k8sPod.Status.HostIP))
 	gomega.Expect(protoPod.IpAddress).To(gomega.Equal(k8sPod.Status.PodIP))
 
@@ -270,6 +270,8 @@ func (pr *PolicyReflector) portsToProto(ports []coreV1Beta1.NetworkPolicyPort) (
 				portProto.Protocol = policy.Policy_Port_TCP
 			case coreV1.ProtocolUDP:
 				portProto.Protocol = policy.Policy_Port_UDP
+			case protocolSCTP:
+				portProto.Protocol = policy.Policy_Port_SCTP
 			}
 		}
 		// Port number/name
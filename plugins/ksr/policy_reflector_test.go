@@ -65,6 +65,7 @@ func TestPolicyReflector(t *testing.T) {
 	}
 
 	var pprotTCP coreV1.Protocol = "TCP"
+	var pprotSCTP coreV1.Protocol = "SCTP"
 
 	policyTestVars.policyTestData = []coreV1Beta1.NetworkPolicy{
 		// Test data 0: mocks a new object to be added or a "pre-existing"
@@ -131,6 +132,13 @@ func TestPolicyReflector(t *testing.T) {
 									IntVal: 5978,
 								},
 							},
+							{
+								Protocol: &pprotSCTP,
+								Port: &intstr.IntOrString{
+									Type:   intstr.Int,
+									IntVal: 36412,
+								},
+							},
 						},
 						To: []coreV1Beta1.NetworkPolicyPeer{
 							{
@@ -0,0 +1,182 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"encoding/json"
+	"sync"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/logging"
+
+	"github.com/contiv/vpp/plugins/ksr/model/policy"
+)
+
+// PolicyStatusAnnotation is the annotation of K8s NetworkPolicies with
+// the status of their enforcement on the nodes (JSON-encoded PolicyStatusSummary).
+const PolicyStatusAnnotation = "contivpp.io/policy-status"
+
+// PolicyStatusSummary summarizes the status of the enforcement of a policy
+// on all nodes with some pods selected by the policy.
+type PolicyStatusSummary struct {
+	// RenderedNodes is the number of nodes with the policy rendered successfully.
+	RenderedNodes int `json:"renderedNodes"`
+	// FailedNodes is the number of nodes that failed to render the policy.
+	FailedNodes int `json:"failedNodes"`
+	// Pods is the number of pods selected by the policy on all nodes.
+	Pods uint32 `json:"pods"`
+	// Errors maps the failed nodes to their errors.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// PolicyStatusUpdater reflects the status of the policy enforcement published
+// by Contiv agents back into K8s. The agents publish the status of every policy
+// selecting some of their pods into the data store (see policy.StatusKey), KSR
+// aggregates the statuses of all nodes and annotates the K8s NetworkPolicy
+// with the summary, so that users can check from kubectl whether the policy
+// is active on all nodes.
+type PolicyStatusUpdater struct {
+	// Log is the logger of the updater.
+	Log logging.Logger
+	// Broker is used to read the statuses at startup.
+	Broker KeyProtoValBroker
+	// Watcher is used to watch the statuses published later.
+	Watcher keyval.ProtoWatcher
+	// PatchPolicy applies the given merge patch onto the K8s NetworkPolicy.
+	PatchPolicy func(namespace string, name string, patch []byte) error
+
+	sync.Mutex
+	statuses map[policy.ID]map[string]*policy.PolicyStatus // policy -> node -> status
+}
+
+// Init annotates the policies with the statuses already present in the data
+// store and starts watching for changes.
+func (psu *PolicyStatusUpdater) Init() error {
+	psu.Lock()
+	defer psu.Unlock()
+
+	psu.statuses = make(map[policy.ID]map[string]*policy.PolicyStatus)
+	it, err := psu.Broker.ListValues(policy.StatusKeyPrefix())
+	if err != nil {
+		return err
+	}
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+		status := &policy.PolicyStatus{}
+		if err := kv.GetValue(status); err != nil {
+			psu.Log.WithField("key", kv.GetKey()).Error("Failed to read the policy status")
+			continue
+		}
+		psu.setStatus(status)
+	}
+	for policyID := range psu.statuses {
+		psu.annotatePolicy(policyID)
+	}
+
+	return psu.Watcher.Watch(psu.handleStatusChange, nil, policy.StatusKeyPrefix())
+}
+
+// handleStatusChange updates the annotation of the policy whose status has
+// changed on some node.
+func (psu *PolicyStatusUpdater) handleStatusChange(resp keyval.ProtoWatchResp) {
+	psu.Lock()
+	defer psu.Unlock()
+
+	name, namespace, node, err := policy.ParseStatusKey(resp.GetKey())
+	if err != nil {
+		psu.Log.WithField("key", resp.GetKey()).Error(err)
+		return
+	}
+	policyID := policy.ID{Name: name, Namespace: namespace}
+
+	if resp.GetChangeType() == datasync.Delete {
+		delete(psu.statuses[policyID], node)
+	} else {
+		status := &policy.PolicyStatus{}
+		if err := resp.GetValue(status); err != nil {
+			psu.Log.WithField("key", resp.GetKey()).Error("Failed to read the policy status")
+			return
+		}
+		psu.setStatus(status)
+	}
+	psu.annotatePolicy(policyID)
+}
+
+// setStatus stores the status of the policy on a node.
+func (psu *PolicyStatusUpdater) setStatus(status *policy.PolicyStatus) {
+	policyID := policy.ID{Name: status.Name, Namespace: status.Namespace}
+	if _, has := psu.statuses[policyID]; !has {
+		psu.statuses[policyID] = make(map[string]*policy.PolicyStatus)
+	}
+	psu.statuses[policyID][status.Node] = status
+}
+
+// annotatePolicy patches the K8s NetworkPolicy with the summary of its statuses.
+// The annotation is removed once the policy is not rendered on any node.
+func (psu *PolicyStatusUpdater) annotatePolicy(policyID policy.ID) {
+	var value interface{}
+	if nodeStatuses := psu.statuses[policyID]; len(nodeStatuses) > 0 {
+		summary := &PolicyStatusSummary{}
+		for node, status := range nodeStatuses {
+			summary.Pods += status.Pods
+			if status.Error == "" {
+				summary.RenderedNodes++
+				continue
+			}
+			summary.FailedNodes++
+			if summary.Errors == nil {
+				summary.Errors = make(map[string]string)
+			}
+			summary.Errors[node] = status.Error
+		}
+		encoded, err := json.Marshal(summary)
+		if err != nil {
+			psu.Log.WithField("policy", policyID.String()).Error(err)
+			return
+		}
+		value = string(encoded)
+	} else {
+		delete(psu.statuses, policyID)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{PolicyStatusAnnotation: value},
+		},
+	})
+	if err != nil {
+		psu.Log.WithField("policy", policyID.String()).Error(err)
+		return
+	}
+
+	err = psu.PatchPolicy(policyID.Namespace, policyID.Name, patch)
+	if k8sErrors.IsNotFound(err) {
+		// the policy has been removed, the agents will withdraw its statuses
+		return
+	}
+	if err != nil {
+		psu.Log.WithFields(map[string]interface{}{"policy": policyID.Name, "namespace": policyID.Namespace}).
+			Errorf("Failed to annotate the policy: %v", err)
+		return
+	}
+	psu.Log.WithFields(map[string]interface{}{"policy": policyID.Name, "namespace": policyID.Namespace}).
+		Debug("Policy status annotated")
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/flavors/local"
+
+	"github.com/contiv/vpp/plugins/ksr/model/policy"
+)
+
+func TestPolicyStatusUpdater(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	flavorLocal := &local.FlavorLocal{}
+	flavorLocal.Inject()
+
+	broker := newMockKeyProtoValBroker()
+	watcher := &mockProtoWatcher{}
+	annotations := map[string]map[string]*string{}
+
+	updater := &PolicyStatusUpdater{
+		Log:     flavorLocal.LoggerFor("policy-status"),
+		Broker:  broker,
+		Watcher: watcher,
+		PatchPolicy: func(namespace string, name string, patch []byte) error {
+			data := map[string]map[string]map[string]*string{}
+			gomega.Expect(json.Unmarshal(patch, &data)).To(gomega.Succeed())
+			annotations[namespace+"/"+name] = data["metadata"]["annotations"]
+			return nil
+		},
+	}
+	summary := func(policy string) *PolicyStatusSummary {
+		value := annotations[policy][PolicyStatusAnnotation]
+		gomega.Expect(value).ToNot(gomega.BeNil())
+		summary := &PolicyStatusSummary{}
+		gomega.Expect(json.Unmarshal([]byte(*value), summary)).To(gomega.Succeed())
+		return summary
+	}
+
+	// the status published before the start is applied by Init
+	status := &policy.PolicyStatus{Name: "policy1", Namespace: "default", Node: "node1", Pods: 2}
+	broker.Put(policy.StatusKey(status.Name, status.Namespace, status.Node), status)
+
+	err := updater.Init()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(watcher.keys).To(gomega.ConsistOf(policy.StatusKeyPrefix()))
+	gomega.Expect(summary("default/policy1")).To(gomega.Equal(&PolicyStatusSummary{RenderedNodes: 1, Pods: 2}))
+
+	// the status of another node is aggregated
+	status = &policy.PolicyStatus{Name: "policy1", Namespace: "default", Node: "node2", Pods: 1, Error: "failed"}
+	watcher.callback(&mockProtoWatchResp{
+		changeType: datasync.Put,
+		key:        policy.StatusKey(status.Name, status.Namespace, status.Node),
+		value:      status,
+	})
+	gomega.Expect(summary("default/policy1")).To(gomega.Equal(&PolicyStatusSummary{
+		RenderedNodes: 1,
+		FailedNodes:   1,
+		Pods:          3,
+		Errors:        map[string]string{"node2": "failed"},
+	}))
+
+	// withdrawn statuses are removed from the summary
+	watcher.callback(&mockProtoWatchResp{
+		changeType: datasync.Delete,
+		key:        policy.StatusKey("policy1", "default", "node1"),
+	})
+	gomega.Expect(summary("default/policy1").RenderedNodes).To(gomega.Equal(0))
+	gomega.Expect(summary("default/policy1").FailedNodes).To(gomega.Equal(1))
+
+	// the annotation is removed once the policy is not rendered on any node
+	watcher.callback(&mockProtoWatchResp{
+		changeType: datasync.Delete,
+		key:        policy.StatusKey("policy1", "default", "node2"),
+	})
+	gomega.Expect(annotations["default/policy1"]).To(gomega.HaveKey(PolicyStatusAnnotation))
+	gomega.Expect(annotations["default/policy1"][PolicyStatusAnnotation]).To(gomega.BeNil())
+	gomega.Expect(updater.statuses).To(gomega.BeEmpty())
+}
@@ -157,10 +157,21 @@ loop:
 	svcProto.ServiceType = string(svc.Spec.Type)
 	svcProto.ExternalIps = svc.Spec.ExternalIPs
 	svcProto.SessionAffinity = string(svc.Spec.SessionAffinity)
+	if svc.Spec.SessionAffinity == coreV1.ServiceAffinityClientIP {
+		svcProto.SessionAffinityTimeout = coreV1.DefaultClientIPServiceAffinitySeconds
+		if cfg := svc.Spec.SessionAffinityConfig; cfg != nil && cfg.ClientIP != nil && cfg.ClientIP.TimeoutSeconds != nil {
+			svcProto.SessionAffinityTimeout = *cfg.ClientIP.TimeoutSeconds
+		}
+	}
 	svcProto.LoadbalancerIp = svc.Spec.LoadBalancerIP
 	svcProto.LoadbalancerSourceRanges = svc.Spec.LoadBalancerSourceRanges
 	svcProto.ExternalTrafficPolicy = string(svc.Spec.ExternalTrafficPolicy)
 	svcProto.HealthCheckNodePort = svc.Spec.HealthCheckNodePort
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			svcProto.LoadbalancerIngressIps = append(svcProto.LoadbalancerIngressIps, ingress.IP)
+		}
+	}
 
 	return svcProto
 }
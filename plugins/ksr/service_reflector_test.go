@@ -292,6 +292,27 @@ func testUpdateService(t *testing.T) {
 	gomega.Expect(err).To(gomega.BeNil())
 	gomega.Expect(svcProto.ClusterIp).To(gomega.Equal(svcNew.Spec.ClusterIP))
 
+	// Ingress IPs assigned by the load-balancer are reflected from the service status
+	svcOld = svcNew
+	svcNew.Status.LoadBalancer.Ingress = []coreV1.LoadBalancerIngress{{IP: "192.168.100.1"}, {Hostname: "lb.example.com"}}
+	serviceTestVars.k8sListWatch.Update(&svcOld, &svcNew)
+	gomega.Expect(upd + 2).To(gomega.Equal(serviceTestVars.svcReflector.GetStats().Updates))
+
+	svcProto = &service.Service{}
+	_, _, err = serviceTestVars.mockKvBroker.GetValue(service.Key(svcNew.GetName(), svcNew.GetNamespace()), svcProto)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(svcProto.LoadbalancerIngressIps).To(gomega.Equal([]string{"192.168.100.1"}))
+
+	// ClientIP session affinity with the default timeout
+	svcOld = svcNew
+	svcNew.Spec.SessionAffinity = coreV1.ServiceAffinityClientIP
+	serviceTestVars.k8sListWatch.Update(&svcOld, &svcNew)
+
+	svcProto = &service.Service{}
+	_, _, err = serviceTestVars.mockKvBroker.GetValue(service.Key(svcNew.GetName(), svcNew.GetNamespace()), svcProto)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(svcProto.SessionAffinity).To(gomega.Equal("ClientIP"))
+	gomega.Expect(svcProto.SessionAffinityTimeout).To(gomega.BeEquivalentTo(coreV1.DefaultClientIPServiceAffinitySeconds))
 }
 
 func testAddDeleteService(t *testing.T) {
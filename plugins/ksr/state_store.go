@@ -0,0 +1,331 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/config"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/flavors/local"
+
+	"github.com/contiv/vpp/plugins/ksr/model/ksrkey"
+	"github.com/contiv/vpp/plugins/kvstore"
+)
+
+const (
+	// stateSyncTimeout is the maximum time AfterInit waits for the K8s state
+	// to be reflected in the direct K8s API mode.
+	stateSyncTimeout = time.Minute
+
+	// stateSyncCheckPeriod is the period of the checks whether the K8s state is reflected.
+	stateSyncCheckPeriod = 100 * time.Millisecond
+)
+
+// StateStore is the key-value data store of the Contiv agent, through which
+// the agent plugins consume the K8s state reflected by KSR.
+//
+// By default, all calls are delegated to the data store (DataStore), into which
+// the K8s state is reflected by contiv-ksr. In the direct K8s API mode, enabled
+// by the presence of the configuration file of the plugin (a KSR Config, leader
+// election excluded), the KSR reflectors run inside the agent and reflect the
+// K8s state into memory instead. The keys under the K8s prefix of KSR
+// (ksrkey.KsrK8sPrefix) are then served from the memory, including the requests
+// to annotate pods and to record K8s Events, which are handled by the KSR running
+// inside the agent. All other keys are still kept in the data store, which
+// therefore remains mandatory: the state shared by the agents (allocated node
+// IDs, node configs) and the state of each agent surviving its restart (pod IP
+// allocations and reservations, container records). The keys are routed by
+// the key, or by the prefix for listing and watching, therefore a prefix
+// spanning both stores is served by the data store only.
+//
+// The status of the policies is not reflected back into K8s in the direct K8s
+// API mode, since it is aggregated from all nodes.
+type StateStore struct {
+	StateStoreDeps
+
+	ksr       *Plugin
+	memory    *memoryStore
+	k8sPrefix string // full prefix of the keys served from the memory
+}
+
+// StateStoreDeps groups the dependencies of the StateStore.
+type StateStoreDeps struct {
+	// ServiceLabel of the infra deps has to be the one of KSR.
+	local.PluginInfraDeps
+	// KubeConfig with k8s cluster address and access credentials to use
+	// in the direct K8s API mode, the in-cluster config is used if not found.
+	KubeConfig config.PluginConfig
+	// DataStore is the data store shared by the agents.
+	DataStore kvstore.KvStore
+}
+
+// Init starts the KSR reflectors inside the agent if the direct K8s API mode
+// is enabled. Must be called after the data store plugin has been initialized.
+func (s *StateStore) Init() error {
+	if s.PluginConfig == nil || s.PluginConfig.GetConfigName() == "" {
+		return nil
+	}
+	s.Log.Info("Direct K8s API mode enabled, reflecting the K8s state into memory")
+
+	prefix := s.ServiceLabel.GetAgentPrefix()
+	s.memory = newMemoryStore()
+	s.k8sPrefix = prefix + ksrkey.KsrK8sPrefix + "/"
+	s.ksr = &Plugin{
+		Deps: Deps{
+			PluginInfraDeps: s.PluginInfraDeps,
+			KubeConfig:      s.KubeConfig,
+		},
+		localStore:  s.memory,
+		localPrefix: prefix,
+	}
+	return s.ksr.Init()
+}
+
+// AfterInit starts the KSR reflectors and waits until the K8s state is reflected,
+// so that the plugins resynced afterwards see the complete state.
+// The plugin therefore has to be listed in the flavor before the plugins
+// watching the K8s state.
+func (s *StateStore) AfterInit() error {
+	if s.ksr == nil {
+		return nil
+	}
+	if err := s.ksr.AfterInit(); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(stateSyncTimeout)
+	for !ksrHasSynced() {
+		if time.Now().After(deadline) {
+			s.Log.Warn("Timeout waiting for the reflection of the K8s state, continuing with a partial state")
+			break
+		}
+		time.Sleep(stateSyncCheckPeriod)
+	}
+	return nil
+}
+
+// Close stops the KSR reflectors.
+func (s *StateStore) Close() error {
+	if s.ksr == nil {
+		return nil
+	}
+	err := s.ksr.Close()
+	s.memory.close()
+	return err
+}
+
+// NewBroker returns a ProtoBroker prepending the given prefix to all keys.
+func (s *StateStore) NewBroker(keyPrefix string) keyval.ProtoBroker {
+	if s.memory == nil {
+		return s.DataStore.NewBroker(keyPrefix)
+	}
+	return &stateBroker{
+		store:     s,
+		prefix:    keyPrefix,
+		memory:    s.memory.NewBroker(keyPrefix),
+		dataStore: s.DataStore.NewBroker(keyPrefix),
+	}
+}
+
+// NewWatcher returns a ProtoWatcher prepending the given prefix to the watched keys.
+func (s *StateStore) NewWatcher(keyPrefix string) keyval.ProtoWatcher {
+	if s.memory == nil {
+		return s.DataStore.NewWatcher(keyPrefix)
+	}
+	return &stateWatcher{
+		store:     s,
+		prefix:    keyPrefix,
+		memory:    s.memory.NewWatcher(keyPrefix),
+		dataStore: s.DataStore.NewWatcher(keyPrefix),
+	}
+}
+
+// Disabled returns true if the data store is not configured, outside
+// of the direct K8s API mode.
+func (s *StateStore) Disabled() bool {
+	return s.memory == nil && s.DataStore.Disabled()
+}
+
+// PutIfNotExists puts the given key-value item if the key does not exist yet.
+func (s *StateStore) PutIfNotExists(key string, value []byte) (succeeded bool, err error) {
+	if s.isLocal(key) {
+		return s.memory.putIfNotExists(key, value), nil
+	}
+	return s.DataStore.PutIfNotExists(key, value)
+}
+
+// KeepAlive refreshes TTL of the given key of the data store, the keys kept
+// in the memory do not expire.
+func (s *StateStore) KeepAlive(key string) (found bool, err error) {
+	if s.isLocal(key) {
+		return s.memory.get(key) != nil, nil
+	}
+	return s.DataStore.KeepAlive(key)
+}
+
+// DeleteIfNotChanged removes the given key if it has not been modified since
+// the given revision.
+func (s *StateStore) DeleteIfNotChanged(key string, revision int64) (deleted bool, err error) {
+	if s.isLocal(key) {
+		return s.memory.deleteIfNotChanged(key, revision), nil
+	}
+	return s.DataStore.DeleteIfNotChanged(key, revision)
+}
+
+// isLocal returns true if the given key (or prefix) is served from the memory.
+func (s *StateStore) isLocal(key string) bool {
+	return s.memory != nil && strings.HasPrefix(key, s.k8sPrefix)
+}
+
+// stateBroker routes the calls to the broker of the memory or of the data store.
+type stateBroker struct {
+	store     *StateStore
+	prefix    string
+	memory    keyval.ProtoBroker
+	dataStore keyval.ProtoBroker
+}
+
+// route returns the broker serving the given key.
+func (b *stateBroker) route(key string) keyval.ProtoBroker {
+	if b.store.isLocal(b.prefix + key) {
+		return b.memory
+	}
+	return b.dataStore
+}
+
+// Put puts single key-value pair into the store serving the key.
+func (b *stateBroker) Put(key string, data proto.Message, opts ...datasync.PutOption) error {
+	return b.route(key).Put(key, data, opts...)
+}
+
+// NewTxn creates a transaction spanning both stores.
+func (b *stateBroker) NewTxn() keyval.ProtoTxn {
+	return &stateTxn{broker: b}
+}
+
+// GetValue retrieves one item under the provided <key>.
+func (b *stateBroker) GetValue(key string, reqObj proto.Message) (found bool, revision int64, err error) {
+	return b.route(key).GetValue(key, reqObj)
+}
+
+// ListValues returns an iterator over the items stored under the provided <key>.
+func (b *stateBroker) ListValues(key string) (keyval.ProtoKeyValIterator, error) {
+	return b.route(key).ListValues(key)
+}
+
+// ListKeys returns an iterator over the keys with the given <prefix>.
+func (b *stateBroker) ListKeys(prefix string) (keyval.ProtoKeyIterator, error) {
+	return b.route(prefix).ListKeys(prefix)
+}
+
+// Delete removes data stored under the <key>.
+func (b *stateBroker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	return b.route(key).Delete(key, opts...)
+}
+
+// stateTxn splits the transaction between the stores. The transaction
+// of the data store is committed first, the one of the memory cannot fail
+// unless a value cannot be serialized.
+type stateTxn struct {
+	broker    *stateBroker
+	memory    keyval.ProtoTxn
+	dataStore keyval.ProtoTxn
+}
+
+// txn returns the transaction of the store serving the given key.
+func (t *stateTxn) txn(key string) keyval.ProtoTxn {
+	if t.broker.route(key) == t.broker.memory {
+		if t.memory == nil {
+			t.memory = t.broker.memory.NewTxn()
+		}
+		return t.memory
+	}
+	if t.dataStore == nil {
+		t.dataStore = t.broker.dataStore.NewTxn()
+	}
+	return t.dataStore
+}
+
+// Put adds put operation into the transaction.
+func (t *stateTxn) Put(key string, data proto.Message) keyval.ProtoTxn {
+	t.txn(key).Put(key, data)
+	return t
+}
+
+// Delete adds delete operation into the transaction.
+func (t *stateTxn) Delete(key string) keyval.ProtoTxn {
+	t.txn(key).Delete(key)
+	return t
+}
+
+// Commit executes the transactions of both stores.
+func (t *stateTxn) Commit() error {
+	if t.dataStore != nil {
+		if err := t.dataStore.Commit(); err != nil {
+			return err
+		}
+	}
+	if t.memory != nil {
+		return t.memory.Commit()
+	}
+	return nil
+}
+
+// stateWatcher routes the watched keys to the watcher of the memory or of the data store.
+type stateWatcher struct {
+	store     *StateStore
+	prefix    string
+	memory    keyval.ProtoWatcher
+	dataStore keyval.ProtoWatcher
+}
+
+// Watch starts watching of the given keys in the stores serving them.
+func (w *stateWatcher) Watch(resp func(keyval.ProtoWatchResp), closeChan chan string, keys ...string) error {
+	var memoryKeys, dataStoreKeys []string
+	for _, key := range keys {
+		if w.store.isLocal(w.prefix + key) {
+			memoryKeys = append(memoryKeys, key)
+		} else {
+			dataStoreKeys = append(dataStoreKeys, key)
+		}
+	}
+	if len(memoryKeys) == 0 {
+		return w.dataStore.Watch(resp, closeChan, dataStoreKeys...)
+	}
+	if len(dataStoreKeys) == 0 {
+		return w.memory.Watch(resp, closeChan, memoryKeys...)
+	}
+
+	var memoryClose, dataStoreClose chan string
+	if closeChan != nil {
+		memoryClose, dataStoreClose = make(chan string), make(chan string)
+		go func() {
+			for key := range closeChan {
+				if w.store.isLocal(w.prefix + key) {
+					memoryClose <- key
+				} else {
+					dataStoreClose <- key
+				}
+			}
+		}()
+	}
+	if err := w.dataStore.Watch(resp, dataStoreClose, dataStoreKeys...); err != nil {
+		return err
+	}
+	return w.memory.Watch(resp, memoryClose, memoryKeys...)
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ksr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+
+	"github.com/contiv/vpp/plugins/ksr/model/node"
+	"github.com/contiv/vpp/plugins/ksr/model/pod"
+)
+
+const testKsrPrefix = "/vnf-agent/contiv-ksr/"
+
+// testDataStore is the data store of the StateStore in the tests.
+type testDataStore struct {
+	*memoryStore
+	disabled bool
+}
+
+func (ds *testDataStore) Disabled() bool {
+	return ds.disabled
+}
+
+func (ds *testDataStore) PutIfNotExists(key string, value []byte) (succeeded bool, err error) {
+	return ds.putIfNotExists(key, value), nil
+}
+
+func (ds *testDataStore) KeepAlive(key string) (found bool, err error) {
+	return ds.get(key) != nil, nil
+}
+
+func (ds *testDataStore) DeleteIfNotChanged(key string, revision int64) (deleted bool, err error) {
+	return ds.deleteIfNotChanged(key, revision), nil
+}
+
+func TestStateStoreDataStoreOnly(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	ds := &testDataStore{memoryStore: newMemoryStore(), disabled: true}
+	store := &StateStore{StateStoreDeps: StateStoreDeps{DataStore: ds}}
+	gomega.Expect(store.Disabled()).To(gomega.BeTrue())
+
+	// outside of the direct K8s API mode, the K8s state is read from the data store
+	ds.disabled = false
+	gomega.Expect(store.Disabled()).To(gomega.BeFalse())
+	k8sNode := &node.Node{Name: "node1"}
+	gomega.Expect(store.NewBroker(testKsrPrefix).Put(node.Key("node1"), k8sNode)).To(gomega.Succeed())
+	gomega.Expect(ds.get(testKsrPrefix + node.Key("node1"))).ToNot(gomega.BeNil())
+}
+
+func TestStateStoreDirectMode(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	ds := &testDataStore{memoryStore: newMemoryStore(), disabled: true}
+	memory := newMemoryStore()
+	store := &StateStore{
+		StateStoreDeps: StateStoreDeps{DataStore: ds},
+		memory:         memory,
+		k8sPrefix:      testKsrPrefix + "k8s/",
+	}
+	gomega.Expect(store.Disabled()).To(gomega.BeFalse())
+
+	changes := make(chan keyval.ProtoWatchResp, 10)
+	// the changes of different stores are not ordered
+	receive := func(num int) map[string]keyval.ProtoWatchResp {
+		received := make(map[string]keyval.ProtoWatchResp)
+		for i := 0; i < num; i++ {
+			var resp keyval.ProtoWatchResp
+			gomega.Eventually(changes).Should(gomega.Receive(&resp))
+			received[resp.GetKey()] = resp
+		}
+		return received
+	}
+	closeChan := make(chan string)
+	err := store.NewWatcher(testKsrPrefix).Watch(func(resp keyval.ProtoWatchResp) { changes <- resp },
+		closeChan, node.KeyPrefix(), "allocatedIDs/")
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// the K8s state is kept in memory, the rest in the data store
+	broker := store.NewBroker(testKsrPrefix)
+	gomega.Expect(broker.Put(node.Key("node1"), &node.Node{Name: "node1", Pod_CIDR: "10.1.1.0/24"})).To(gomega.Succeed())
+	gomega.Expect(broker.Put("allocatedIDs/1", &node.Node{Name: "node1"})).To(gomega.Succeed())
+	gomega.Expect(memory.get(testKsrPrefix + node.Key("node1"))).ToNot(gomega.BeNil())
+	gomega.Expect(ds.get(testKsrPrefix + node.Key("node1"))).To(gomega.BeNil())
+	gomega.Expect(ds.get(testKsrPrefix + "allocatedIDs/1")).ToNot(gomega.BeNil())
+	gomega.Expect(memory.get(testKsrPrefix + "allocatedIDs/1")).To(gomega.BeNil())
+
+	// changes of both stores are watched
+	received := receive(2)
+	gomega.Expect(received).To(gomega.HaveKey("allocatedIDs/1"))
+	gomega.Expect(received).To(gomega.HaveKey(node.Key("node1")))
+	resp := received[node.Key("node1")]
+	gomega.Expect(resp.GetChangeType()).To(gomega.Equal(datasync.Put))
+	k8sNode := &node.Node{}
+	gomega.Expect(resp.GetValue(k8sNode)).To(gomega.Succeed())
+	gomega.Expect(k8sNode.Pod_CIDR).To(gomega.Equal("10.1.1.0/24"))
+
+	// values are read and listed from the store serving the key
+	found, _, err := broker.GetValue(node.Key("node1"), k8sNode)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(found).To(gomega.BeTrue())
+	it, err := broker.ListValues(node.KeyPrefix())
+	gomega.Expect(err).To(gomega.BeNil())
+	kv, stop := it.GetNext()
+	gomega.Expect(stop).To(gomega.BeFalse())
+	gomega.Expect(kv.GetKey()).To(gomega.Equal(node.Key("node1")))
+	_, stop = it.GetNext()
+	gomega.Expect(stop).To(gomega.BeTrue())
+
+	// the transaction spans both stores
+	err = broker.NewTxn().
+		Put(pod.Key("pod1", "default"), &pod.Pod{Name: "pod1", Namespace: "default"}).
+		Delete(node.Key("node1")).
+		Delete("allocatedIDs/1").
+		Commit()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(memory.get(testKsrPrefix + pod.Key("pod1", "default"))).ToNot(gomega.BeNil())
+	gomega.Expect(memory.get(testKsrPrefix + node.Key("node1"))).To(gomega.BeNil())
+	gomega.Expect(ds.get(testKsrPrefix + "allocatedIDs/1")).To(gomega.BeNil())
+
+	received = receive(2)
+	gomega.Expect(received).To(gomega.HaveKey("allocatedIDs/1"))
+	gomega.Expect(received).To(gomega.HaveKey(node.Key("node1")))
+	resp = received[node.Key("node1")]
+	gomega.Expect(resp.GetChangeType()).To(gomega.Equal(datasync.Delete))
+	prevNode := &node.Node{}
+	prevExists, err := resp.GetPrevValue(prevNode)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(prevExists).To(gomega.BeTrue())
+	gomega.Expect(prevNode.Name).To(gomega.Equal("node1"))
+	gomega.Consistently(changes, 100*time.Millisecond).ShouldNot(gomega.Receive())
+
+	// the keys are created only once
+	created, err := store.PutIfNotExists(testKsrPrefix+"allocatedIDs/2", []byte("{}"))
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(created).To(gomega.BeTrue())
+	created, err = store.PutIfNotExists(testKsrPrefix+"allocatedIDs/2", []byte("{}"))
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(created).To(gomega.BeFalse())
+	gomega.Expect(ds.get(testKsrPrefix + "allocatedIDs/2")).ToNot(gomega.BeNil())
+	gomega.Eventually(changes).Should(gomega.Receive(&resp))
+	gomega.Expect(resp.GetKey()).To(gomega.Equal("allocatedIDs/2"))
+
+	// the watch of the K8s nodes is closed
+	closeChan <- node.KeyPrefix()
+	gomega.Eventually(func() int {
+		memory.Lock()
+		defer memory.Unlock()
+		return len(memory.watches)
+	}).Should(gomega.BeZero())
+	gomega.Expect(broker.Put(node.Key("node2"), &node.Node{Name: "node2"})).To(gomega.Succeed())
+	gomega.Consistently(changes, 100*time.Millisecond).ShouldNot(gomega.Receive())
+
+	store.memory.close()
+}
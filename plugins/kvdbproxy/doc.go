@@ -22,4 +22,9 @@
 //
 // The limitations:
 // 	 - it is not possible to define multiple ignored events for the key.
+//
+// Additionally, the change events can be filtered by key prefixes (include-prefixes, exclude-prefixes)
+// configured for all watchers, and per watcher created by NewFilteredWatcher with a Filter, which
+// may also transform the events. Downstream plugins thus receive a curated change stream instead
+// of re-filtering every event themselves.
 package kvdbproxy
@@ -90,3 +90,55 @@ func TestWatch(t *testing.T) {
 
 	close(ch)
 }
+
+func TestFilteredWatch(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	kvdbMock := NewKvdbsyncMock()
+
+	plugin := Plugin{}
+	plugin.Deps.Log = logging.ForPlugin("proxy", logrus.NewLogRegistry())
+	plugin.Deps.KVDB = kvdbMock
+
+	err := plugin.Init()
+	gomega.Expect(err).To(gomega.BeNil())
+	plugin.filter.ExcludePrefixes = []string{"/abc/prefix/excluded"}
+
+	ch := make(chan datasync.ChangeEvent, 1)
+	watcher := plugin.NewFilteredWatcher(&Filter{
+		IncludePrefixes: []string{"/abc/prefix/included", "/abc/prefix/excluded"},
+		Transform: func(change datasync.ChangeEvent) datasync.ChangeEvent {
+			if change.GetChangeType() == datasync.Delete {
+				return nil
+			}
+			return newChangeEventMock(change.GetKey()+"/transformed", change.GetChangeType())
+		},
+	})
+	watcher.Watch("test", ch, nil, "/abc/prefix")
+
+	// expect the transformed message to be received
+	plugin.Put("/abc/prefix/included/something", nil)
+	select {
+	case change := <-ch:
+		gomega.Expect(change.GetKey()).To(gomega.BeEquivalentTo("/abc/prefix/included/something/transformed"))
+	case <-time.After(100 * time.Millisecond):
+		t.FailNow()
+	}
+
+	// expect the messages to be filtered out: not included, excluded by the configuration,
+	// dropped by the transformation
+	plugin.Put("/abc/prefix/other", nil)
+	plugin.Put("/abc/prefix/excluded/something", nil)
+	plugin.Delete("/abc/prefix/included/something")
+	select {
+	case <-ch:
+		t.FailNow()
+	case <-time.After(100 * time.Millisecond):
+
+	}
+
+	err = plugin.Close()
+	gomega.Expect(err).To(gomega.BeNil())
+
+	close(ch)
+}
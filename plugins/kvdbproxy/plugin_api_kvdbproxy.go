@@ -22,3 +22,22 @@ type Proxy interface {
 	// Delete deletes data from a datastore using the injected kvdbsync plugin.
 	Delete(key string, opts ...datasync.DelOption) (existed bool, err error)
 }
+
+// Transformation is applied to the change events passing a Filter. It returns the event to forward
+// (the same or a modified one), nil to drop the event.
+type Transformation func(change datasync.ChangeEvent) datasync.ChangeEvent
+
+// Filter selects the change events forwarded to a watcher and optionally transforms them.
+// The resync events are not filtered.
+type Filter struct {
+	// IncludePrefixes lists the key prefixes of the forwarded change events, all keys are forwarded
+	// if empty.
+	IncludePrefixes []string `json:"include-prefixes"`
+
+	// ExcludePrefixes lists the key prefixes of the dropped change events. Exclusion takes precedence
+	// over inclusion.
+	ExcludePrefixes []string `json:"exclude-prefixes"`
+
+	// Transform is optional, applied to the change events passing the prefixes.
+	Transform Transformation `json:"-"`
+}
@@ -15,6 +15,7 @@
 package kvdbproxy
 
 import (
+	"strings"
 	"sync"
 
 	"github.com/golang/protobuf/proto"
@@ -31,11 +32,17 @@ import (
 //   - the change events caused by persisting need to be ignored since the change is already applied
 // The limitations:
 // 	 - it is not possible to define multiple ignored events for the key.
+//
+// The change events can be further filtered by key prefixes and transformed, either
+// for all watchers (by the plugin configuration) or for the watchers created by NewFilteredWatcher.
 type Plugin struct {
 	Deps
 	sync.Mutex
 	ignoreList map[string]datasync.PutDel
 	closeChan  chan interface{}
+
+	// filter configured for all watchers
+	filter *Filter
 }
 
 type kvsyncDelegate interface {
@@ -54,10 +61,18 @@ type Deps struct {
 	KVDB kvsyncDelegate
 }
 
-// Init initializes internal members of the plugin.
+// Init initializes internal members of the plugin and loads the configuration
+// of the filter applied to all watchers (Filter without the transformation).
 func (plugin *Plugin) Init() error {
 	plugin.ignoreList = map[string]datasync.PutDel{}
 	plugin.closeChan = make(chan interface{})
+
+	plugin.filter = &Filter{}
+	if plugin.PluginConfig != nil {
+		if _, err := plugin.PluginConfig.GetValue(plugin.filter); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -83,9 +98,36 @@ func (plugin *Plugin) DelIgnoreEntry(key string) {
 }
 
 // Watch forwards the subscription request to the injected kvdbsync plugin. The change events
-// are filtered based on the plugin ignore list. The resync events are untouched.
+// are filtered based on the plugin ignore list and the configured filter. The resync events
+// are untouched.
 func (plugin *Plugin) Watch(resyncName string, changeChan chan datasync.ChangeEvent,
 	resyncChan chan datasync.ResyncEvent, keyPrefixes ...string) (datasync.WatchRegistration, error) {
+	return plugin.watch(resyncName, changeChan, resyncChan, nil, keyPrefixes...)
+}
+
+// NewFilteredWatcher returns a watcher receiving only the change events passing the given filter
+// (in addition to the ignore list and the configured filter). It can be injected into plugins
+// instead of the proxy itself.
+func (plugin *Plugin) NewFilteredWatcher(filter *Filter) datasync.KeyValProtoWatcher {
+	return &filteredWatcher{plugin: plugin, filter: filter}
+}
+
+// filteredWatcher watches the proxy with a filter.
+type filteredWatcher struct {
+	plugin *Plugin
+	filter *Filter
+}
+
+// Watch subscribes the channels for the change events passing the filter.
+func (fw *filteredWatcher) Watch(resyncName string, changeChan chan datasync.ChangeEvent,
+	resyncChan chan datasync.ResyncEvent, keyPrefixes ...string) (datasync.WatchRegistration, error) {
+	return fw.plugin.watch(resyncName, changeChan, resyncChan, fw.filter, keyPrefixes...)
+}
+
+// watch subscribes the channels in the injected kvdbsync plugin, change events are forwarded
+// if not ignored and if passing both the configured filter and the given filter (optional).
+func (plugin *Plugin) watch(resyncName string, changeChan chan datasync.ChangeEvent,
+	resyncChan chan datasync.ResyncEvent, filter *Filter, keyPrefixes ...string) (datasync.WatchRegistration, error) {
 
 	proxyChan := make(chan datasync.ChangeEvent)
 	go func() {
@@ -98,9 +140,11 @@ func (plugin *Plugin) Watch(resyncName string, changeChan chan datasync.ChangeEv
 				if found && op == m.GetChangeType() {
 					plugin.Log.Infof("Change for %v is ignored", m.GetKey())
 					delete(plugin.ignoreList, m.GetKey())
+				} else if forwarded := plugin.filter.apply(filter.apply(m)); forwarded == nil {
+					plugin.Log.Debugf("Change for %v is filtered out", m.GetKey())
 				} else {
 					plugin.Log.Infof("Change for %v is about to be applied", m.GetKey())
-					changeChan <- m
+					changeChan <- forwarded
 				}
 				plugin.Unlock()
 			case <-plugin.closeChan:
@@ -122,3 +166,34 @@ func (plugin *Plugin) Put(key string, data proto.Message, opts ...datasync.PutOp
 func (plugin *Plugin) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
 	return plugin.KVDB.Delete(key, opts...)
 }
+
+// apply returns the change event if it passes the filter (transformed if the transformation
+// is defined), nil otherwise. Nil filter passes all events.
+func (filter *Filter) apply(change datasync.ChangeEvent) datasync.ChangeEvent {
+	if filter == nil || change == nil {
+		return change
+	}
+	key := change.GetKey()
+	if hasPrefix(key, filter.ExcludePrefixes) {
+		change.Done(nil)
+		return nil
+	}
+	if len(filter.IncludePrefixes) > 0 && !hasPrefix(key, filter.IncludePrefixes) {
+		change.Done(nil)
+		return nil
+	}
+	if filter.Transform != nil {
+		return filter.Transform(change)
+	}
+	return change
+}
+
+// hasPrefix returns true if the key starts with one of the prefixes.
+func hasPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,316 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvstore
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+)
+
+// cachedBroker is a ProtoBroker of the selected data store with the local cache.
+// Values read from or written into the data store are written through into
+// the cache. Reads are served from the cache if the data store is not
+// reachable, writes then fail with ErrReadOnly.
+type cachedBroker struct {
+	plugin *Plugin
+	prefix string
+}
+
+// store returns broker of the data store, nil if the data store is not reachable.
+func (b *cachedBroker) store() keyval.ProtoBroker {
+	if !b.plugin.isOnline() {
+		return nil
+	}
+	return b.plugin.selected.NewBroker(b.prefix)
+}
+
+// Put puts single key-value pair into the data store and the cache.
+func (b *cachedBroker) Put(key string, data proto.Message, opts ...datasync.PutOption) error {
+	broker := b.store()
+	if broker == nil {
+		return ErrReadOnly
+	}
+	if err := broker.Put(key, data, opts...); err != nil {
+		return err
+	}
+	b.plugin.cacheValue(b.prefix+key, data, 0)
+	return nil
+}
+
+// NewTxn creates a transaction updating also the cache once committed.
+func (b *cachedBroker) NewTxn() keyval.ProtoTxn {
+	broker := b.store()
+	if broker == nil {
+		return &readOnlyTxn{}
+	}
+	return &cachedTxn{plugin: b.plugin, prefix: b.prefix, txn: broker.NewTxn()}
+}
+
+// GetValue retrieves one item under the provided <key> from the data store,
+// or from the cache if the data store is not reachable.
+func (b *cachedBroker) GetValue(key string, reqObj proto.Message) (found bool, revision int64, err error) {
+	if broker := b.store(); broker != nil {
+		found, revision, err = broker.GetValue(key, reqObj)
+		if err == nil {
+			if found {
+				b.plugin.cacheValue(b.prefix+key, reqObj, revision)
+			} else {
+				b.plugin.cache.delete(b.prefix + key)
+			}
+			return found, revision, nil
+		}
+		b.plugin.Log.Warnf("Failed to read %s from the data store, using the cached value: %v", b.prefix+key, err)
+	}
+
+	entry, found := b.plugin.cache.get(b.prefix + key)
+	if !found {
+		return false, 0, nil
+	}
+	return true, entry.Rev, b.plugin.serializer.Unmarshal(entry.Value, reqObj)
+}
+
+// ListValues returns an iterator over the items stored under the provided <key>
+// in the data store, or in the cache if the data store is not reachable.
+func (b *cachedBroker) ListValues(key string) (keyval.ProtoKeyValIterator, error) {
+	if broker := b.store(); broker != nil {
+		it, err := broker.ListValues(key)
+		if err == nil {
+			return &cachingKeyValIterator{
+				plugin:     b.plugin,
+				prefix:     b.prefix,
+				listPrefix: b.prefix + key,
+				it:         it,
+				listed:     make(map[string]struct{}),
+			}, nil
+		}
+		b.plugin.Log.Warnf("Failed to list %s from the data store, using the cached values: %v", b.prefix+key, err)
+	}
+	return &cachedKeyValIterator{plugin: b.plugin, prefix: b.prefix, entries: b.plugin.cache.list(b.prefix + key)}, nil
+}
+
+// ListKeys returns an iterator over the keys with the given <prefix>
+// in the data store, or in the cache if the data store is not reachable.
+func (b *cachedBroker) ListKeys(prefix string) (keyval.ProtoKeyIterator, error) {
+	if broker := b.store(); broker != nil {
+		it, err := broker.ListKeys(prefix)
+		if err == nil {
+			return it, nil
+		}
+		b.plugin.Log.Warnf("Failed to list keys %s from the data store, using the cached keys: %v", b.prefix+prefix, err)
+	}
+	return &cachedKeyIterator{prefix: b.prefix, entries: b.plugin.cache.list(b.prefix + prefix)}, nil
+}
+
+// Delete removes data stored under the <key> from the data store and the cache.
+func (b *cachedBroker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	broker := b.store()
+	if broker == nil {
+		return false, ErrReadOnly
+	}
+	existed, err = broker.Delete(key, opts...)
+	if err != nil {
+		return existed, err
+	}
+	for _, opt := range opts {
+		if _, withPrefix := opt.(*datasync.WithPrefixOpt); withPrefix {
+			b.plugin.cache.deletePrefix(b.prefix+key, nil)
+			return existed, nil
+		}
+	}
+	b.plugin.cache.delete(b.prefix + key)
+	return existed, nil
+}
+
+// cachedTxn is a transaction of the data store updating the cache once committed.
+type cachedTxn struct {
+	plugin  *Plugin
+	prefix  string
+	txn     keyval.ProtoTxn
+	puts    map[string]proto.Message
+	deletes []string
+}
+
+// Put adds put operation into the transaction.
+func (t *cachedTxn) Put(key string, data proto.Message) keyval.ProtoTxn {
+	if t.puts == nil {
+		t.puts = make(map[string]proto.Message)
+	}
+	t.puts[key] = data
+	t.txn.Put(key, data)
+	return t
+}
+
+// Delete adds delete operation into the transaction.
+func (t *cachedTxn) Delete(key string) keyval.ProtoTxn {
+	delete(t.puts, key)
+	t.deletes = append(t.deletes, key)
+	t.txn.Delete(key)
+	return t
+}
+
+// Commit executes the transaction and updates the cache if successful.
+func (t *cachedTxn) Commit() error {
+	if err := t.txn.Commit(); err != nil {
+		return err
+	}
+	for _, key := range t.deletes {
+		t.plugin.cache.delete(t.prefix + key)
+	}
+	for key, data := range t.puts {
+		t.plugin.cacheValue(t.prefix+key, data, 0)
+	}
+	return nil
+}
+
+// readOnlyTxn is returned when the data store is not reachable.
+type readOnlyTxn struct{}
+
+// Put does nothing, the transaction cannot be committed.
+func (t *readOnlyTxn) Put(key string, data proto.Message) keyval.ProtoTxn {
+	return t
+}
+
+// Delete does nothing, the transaction cannot be committed.
+func (t *readOnlyTxn) Delete(key string) keyval.ProtoTxn {
+	return t
+}
+
+// Commit returns ErrReadOnly.
+func (t *readOnlyTxn) Commit() error {
+	return ErrReadOnly
+}
+
+// cachingKeyValIterator iterates over the values listed from the data store,
+// caching the values as they are read. Once all values are iterated, cached
+// values no longer present in the data store are removed from the cache.
+type cachingKeyValIterator struct {
+	plugin     *Plugin
+	prefix     string
+	listPrefix string
+	it         keyval.ProtoKeyValIterator
+	listed     map[string]struct{}
+	done       bool
+}
+
+// GetNext returns the next item listed from the data store.
+func (it *cachingKeyValIterator) GetNext() (kv keyval.ProtoKeyVal, stop bool) {
+	kv, stop = it.it.GetNext()
+	if stop {
+		if !it.done {
+			it.done = true
+			it.plugin.cache.deletePrefix(it.listPrefix, it.listed)
+		}
+		return kv, stop
+	}
+	key := it.prefix + kv.GetKey()
+	it.listed[key] = struct{}{}
+	return &cachingKeyVal{ProtoKeyVal: kv, plugin: it.plugin, key: key}, false
+}
+
+// Close closes the underlying iterator.
+func (it *cachingKeyValIterator) Close() error {
+	return it.it.Close()
+}
+
+// cachingKeyVal caches the value of the pair once it is read.
+type cachingKeyVal struct {
+	keyval.ProtoKeyVal
+	plugin *Plugin
+	key    string
+}
+
+// GetValue reads the value and writes it into the cache.
+func (kv *cachingKeyVal) GetValue(value proto.Message) error {
+	if err := kv.ProtoKeyVal.GetValue(value); err != nil {
+		return err
+	}
+	kv.plugin.cacheValue(kv.key, value, kv.GetRevision())
+	return nil
+}
+
+// cachedKeyValIterator iterates over the cached values.
+type cachedKeyValIterator struct {
+	plugin  *Plugin
+	prefix  string
+	entries []*cacheEntry
+	index   int
+}
+
+// GetNext returns the next cached item.
+func (it *cachedKeyValIterator) GetNext() (kv keyval.ProtoKeyVal, stop bool) {
+	if it.index >= len(it.entries) {
+		return nil, true
+	}
+	entry := it.entries[it.index]
+	it.index++
+	return &cachedKeyVal{plugin: it.plugin, key: strings.TrimPrefix(entry.Key, it.prefix), entry: entry}, false
+}
+
+// Close does nothing.
+func (it *cachedKeyValIterator) Close() error {
+	return nil
+}
+
+// cachedKeyVal is a key-value pair read from the cache.
+type cachedKeyVal struct {
+	plugin *Plugin
+	key    string
+	entry  *cacheEntry
+}
+
+// GetValue unmarshals the cached value.
+func (kv *cachedKeyVal) GetValue(value proto.Message) error {
+	return kv.plugin.serializer.Unmarshal(kv.entry.Value, value)
+}
+
+// GetPrevValue returns false, the previous value is not known.
+func (kv *cachedKeyVal) GetPrevValue(prevValue proto.Message) (prevValueExist bool, err error) {
+	return false, nil
+}
+
+// GetKey returns the key of the pair.
+func (kv *cachedKeyVal) GetKey() string {
+	return kv.key
+}
+
+// GetRevision returns the revision of the cached value.
+func (kv *cachedKeyVal) GetRevision() int64 {
+	return kv.entry.Rev
+}
+
+// cachedKeyIterator iterates over the cached keys.
+type cachedKeyIterator struct {
+	prefix  string
+	entries []*cacheEntry
+	index   int
+}
+
+// GetNext returns the next cached key.
+func (it *cachedKeyIterator) GetNext() (key string, rev int64, stop bool) {
+	if it.index >= len(it.entries) {
+		return "", 0, true
+	}
+	entry := it.entries[it.index]
+	it.index++
+	return strings.TrimPrefix(entry.Key, it.prefix), entry.Rev, false
+}
+
+// Close does nothing.
+func (it *cachedKeyIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvstore
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+)
+
+// cachedWatcher is a ProtoWatcher of the selected data store keeping
+// the local cache up-to-date with the watched changes.
+// Watches registered while the data store is not reachable are started
+// once the connection is (re)established.
+type cachedWatcher struct {
+	plugin *Plugin
+	prefix string
+}
+
+// Watch starts (or postpones) watching of the given keys.
+func (w *cachedWatcher) Watch(resp func(keyval.ProtoWatchResp), closeChan chan string, keys ...string) error {
+	return w.plugin.startWatch(&cachedWatch{
+		plugin:    w.plugin,
+		prefix:    w.prefix,
+		resp:      resp,
+		closeChan: closeChan,
+		keys:      keys,
+	})
+}
+
+// cachedWatch is a single watch registration.
+type cachedWatch struct {
+	plugin    *Plugin
+	prefix    string
+	resp      func(keyval.ProtoWatchResp)
+	closeChan chan string
+	keys      []string
+}
+
+// start starts watching the data store. With <reconcile> set, the changes
+// made in the data store while it was not reachable are also notified.
+func (w *cachedWatch) start(reconcile bool) error {
+	err := w.plugin.selected.NewWatcher(w.prefix).Watch(w.onChange, w.closeChan, w.keys...)
+	if err != nil || !reconcile {
+		return err
+	}
+	// The watch is started first, a change made during the reconciliation
+	// may therefore be notified twice, but never missed.
+	return w.reconcile()
+}
+
+// onChange updates the cache and propagates the change.
+func (w *cachedWatch) onChange(resp keyval.ProtoWatchResp) {
+	key := w.prefix + resp.GetKey()
+	if resp.GetChangeType() == datasync.Delete {
+		w.plugin.cache.delete(key)
+		w.resp(resp)
+		return
+	}
+	w.resp(&cachingWatchResp{ProtoWatchResp: resp, plugin: w.plugin, key: key})
+}
+
+// reconcile compares the watched values in the data store with the cached
+// values and notifies the differences.
+func (w *cachedWatch) reconcile() error {
+	broker := w.plugin.selected.NewBroker(w.prefix)
+	for _, key := range w.keys {
+		it, err := broker.ListValues(key)
+		if err != nil {
+			return err
+		}
+		listed := make(map[string]struct{})
+		for {
+			kv, stop := it.GetNext()
+			if stop {
+				break
+			}
+			fullKey := w.prefix + kv.GetKey()
+			listed[fullKey] = struct{}{}
+			prev, cached := w.plugin.cache.get(fullKey)
+			if cached && prev.Rev == kv.GetRevision() {
+				continue
+			}
+			w.resp(&reconciledPut{
+				cachingKeyVal: cachingKeyVal{ProtoKeyVal: kv, plugin: w.plugin, key: fullKey},
+				prev:          prev,
+			})
+		}
+		it.Close()
+
+		for _, entry := range w.plugin.cache.list(w.prefix + key) {
+			if _, exists := listed[entry.Key]; exists {
+				continue
+			}
+			w.plugin.cache.delete(entry.Key)
+			w.resp(&reconciledDelete{plugin: w.plugin, key: strings.TrimPrefix(entry.Key, w.prefix), prev: entry})
+		}
+	}
+	return nil
+}
+
+// cachingWatchResp caches the changed value once it is read.
+type cachingWatchResp struct {
+	keyval.ProtoWatchResp
+	plugin *Plugin
+	key    string
+}
+
+// GetValue reads the value and writes it into the cache.
+func (r *cachingWatchResp) GetValue(value proto.Message) error {
+	if err := r.ProtoWatchResp.GetValue(value); err != nil {
+		return err
+	}
+	r.plugin.cacheValue(r.key, value, r.GetRevision())
+	return nil
+}
+
+// reconciledPut notifies value created or changed while the data store
+// was not reachable.
+type reconciledPut struct {
+	cachingKeyVal
+	prev *cacheEntry
+}
+
+// GetChangeType returns datasync.Put.
+func (r *reconciledPut) GetChangeType() datasync.PutDel {
+	return datasync.Put
+}
+
+// GetPrevValue returns the cached value.
+func (r *reconciledPut) GetPrevValue(prevValue proto.Message) (prevValueExist bool, err error) {
+	if r.prev == nil {
+		return false, nil
+	}
+	return true, r.plugin.serializer.Unmarshal(r.prev.Value, prevValue)
+}
+
+// reconciledDelete notifies value removed while the data store
+// was not reachable.
+type reconciledDelete struct {
+	plugin *Plugin
+	key    string
+	prev   *cacheEntry
+}
+
+// GetChangeType returns datasync.Delete.
+func (r *reconciledDelete) GetChangeType() datasync.PutDel {
+	return datasync.Delete
+}
+
+// GetValue does nothing, the value was removed.
+func (r *reconciledDelete) GetValue(value proto.Message) error {
+	return nil
+}
+
+// GetRevision returns the revision of the cached value.
+func (r *reconciledDelete) GetRevision() int64 {
+	return r.prev.Rev
+}
+
+// GetKey returns the key of the removed value.
+func (r *reconciledDelete) GetKey() string {
+	return r.key
+}
+
+// GetPrevValue returns the cached value.
+func (r *reconciledDelete) GetPrevValue(prevValue proto.Message) (prevValueExist bool, err error) {
+	return true, r.plugin.serializer.Unmarshal(r.prev.Value, prevValue)
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kvstore implements plugin selecting the key-value data store used
+// by Contiv from multiple supported data store plugins (etcd, Consul).
+//
+// Every candidate plugin is enabled only if its configuration file is found,
+// the selection is therefore done simply by deploying the configuration file
+// of the desired data store. The plugin must be listed in the flavor after
+// the candidate plugins and before any plugin using the data store.
+//
+// Optionally (see Config), the values read from or written into the data
+// store are cached in a local directory. If the data store is not reachable
+// when the agent starts, the values are served from the cache, writes fail
+// with ErrReadOnly and watches are postponed until the connection
+// is re-established. The changes made in the data store meanwhile are then
+// notified to the watchers. Only data stores implementing Connect (such as
+// ETCDPlugin) can be initialized while not reachable.
+package kvstore
@@ -0,0 +1,270 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/pkg/tlsutil"
+	"github.com/ligato/cn-infra/db/keyval/etcdv3"
+	"github.com/ligato/cn-infra/logging"
+)
+
+const (
+	// defaultReloadPeriod is the default period of checking the credential files for changes.
+	defaultReloadPeriod = 30 * time.Second
+)
+
+// ETCDConfig extends the etcd configuration with the authentication
+// and with the reloading of the rotated credentials.
+type ETCDConfig struct {
+	etcdv3.Config
+
+	// Username and Password authenticate the client if the authentication is enabled in etcd.
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// PasswordFile is a file with the password (e.g. mounted from a k8s secret),
+	// used instead of Password.
+	PasswordFile string `json:"password-file"`
+
+	// ReloadPeriod is the period of checking the certificate, key, CA and password
+	// files for changes. The changed certificates are used for the new connections,
+	// with a rotated password the connection is re-established, all without
+	// restarting the agent.
+	ReloadPeriod time.Duration `json:"reload-period"`
+}
+
+// etcdCredentials holds the current credentials of the etcd client
+// loaded from the configured files.
+type etcdCredentials struct {
+	sync.Mutex
+	log    logging.Logger
+	config *ETCDConfig
+	hosts  []string
+
+	cert     *tls.Certificate
+	roots    *x509.CertPool
+	password string
+	modTimes map[string]time.Time
+	loaded   bool
+}
+
+// newETCDCredentials creates credentials for the given configuration.
+func newETCDCredentials(config *ETCDConfig, log logging.Logger) *etcdCredentials {
+	return &etcdCredentials{log: log, config: config}
+}
+
+// connect loads the credentials and creates a new connection with etcd.
+// The returned client serves the operations not exposed by the connection
+// (keeping the leases alive). With the authentication it is the client
+// of the connection, closed together with it.
+func (c *etcdCredentials) connect() (*etcdv3.BytesConnectionEtcd, *clientv3.Client, error) {
+	clientConfig, err := etcdv3.ConfigToClientv3(&c.config.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := c.reload(); err != nil {
+		return nil, nil, err
+	}
+	if c.hosts == nil {
+		// not to be changed once used by the established connections
+		c.hosts = endpointHosts(clientConfig.Endpoints)
+	}
+	if clientConfig.TLS != nil {
+		clientConfig.TLS = c.tlsConfig(clientConfig.TLS)
+	}
+	if c.config.Username == "" {
+		// the client of the connection is not accessible, a separate one is created
+		client, err := clientv3.New(*clientConfig.Config)
+		if err != nil {
+			return nil, nil, err
+		}
+		connection, err := etcdv3.NewEtcdConnectionWithBytes(*clientConfig, c.log)
+		if err != nil {
+			client.Close()
+			return nil, nil, err
+		}
+		return connection, client, nil
+	}
+
+	// The client of the authenticated connection is re-created with the rotated password.
+	if c.config.OpTimeout != 0 {
+		c.log.Warn("operation-timeout is not supported with authentication, using the default")
+	}
+	clientConfig.Username = c.config.Username
+	clientConfig.Password = c.getPassword()
+	client, err := clientv3.New(*clientConfig.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	connection, err := etcdv3.NewEtcdConnectionUsingClient(client, c.log)
+	return connection, client, err
+}
+
+// reloadPeriod returns the configured period of checking the files for changes.
+func (c *etcdCredentials) reloadPeriod() time.Duration {
+	if c.config.ReloadPeriod != 0 {
+		return c.config.ReloadPeriod
+	}
+	return defaultReloadPeriod
+}
+
+// reload re-reads the credential files if any of them has changed.
+func (c *etcdCredentials) reload() (changed bool, err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	modTimes := make(map[string]time.Time)
+	for _, file := range []string{c.config.Certfile, c.config.Keyfile, c.config.CAfile, c.config.PasswordFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		modTimes[file] = info.ModTime()
+		if modTime, loaded := c.modTimes[file]; !loaded || !modTime.Equal(info.ModTime()) {
+			changed = true
+		}
+	}
+	if !changed && c.loaded {
+		return false, nil
+	}
+
+	// load everything first, not to use a partially rotated set of credentials
+	var (
+		cert     *tls.Certificate
+		roots    *x509.CertPool
+		password = c.config.Password
+	)
+	if c.config.Certfile != "" && c.config.Keyfile != "" {
+		if cert, err = tlsutil.NewCert(c.config.Certfile, c.config.Keyfile, nil); err != nil {
+			return false, err
+		}
+	}
+	if c.config.CAfile != "" {
+		if roots, err = tlsutil.NewCertPool([]string{c.config.CAfile}); err != nil {
+			return false, err
+		}
+	}
+	if c.config.PasswordFile != "" {
+		data, err := ioutil.ReadFile(c.config.PasswordFile)
+		if err != nil {
+			return false, err
+		}
+		password = strings.TrimSpace(string(data))
+	}
+
+	c.cert, c.roots, c.password = cert, roots, password
+	c.modTimes = modTimes
+	c.loaded = true
+	return true, nil
+}
+
+// authenticated returns true if the connection authenticates with a username and password.
+func (c *etcdCredentials) authenticated() bool {
+	return c.config.Username != ""
+}
+
+// getPassword returns the current password.
+func (c *etcdCredentials) getPassword() string {
+	c.Lock()
+	defer c.Unlock()
+	return c.password
+}
+
+// tlsConfig returns the TLS configuration using always the current
+// certificate and CA.
+func (c *etcdCredentials) tlsConfig(static *tls.Config) *tls.Config {
+	tlsConfig := &tls.Config{
+		MinVersion:         static.MinVersion,
+		InsecureSkipVerify: static.InsecureSkipVerify,
+	}
+	if c.cert != nil {
+		tlsConfig.GetClientCertificate = c.getClientCertificate
+	}
+	if !static.InsecureSkipVerify {
+		// the built-in verification would use the CA loaded at the startup
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = c.verifyPeerCertificate
+	}
+	return tlsConfig
+}
+
+// getClientCertificate returns the current client certificate.
+func (c *etcdCredentials) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.Lock()
+	defer c.Unlock()
+	return c.cert, nil
+}
+
+// verifyPeerCertificate verifies the certificate of etcd against the current CA
+// and the hosts of the configured endpoints.
+func (c *etcdCredentials) verifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return errors.New("etcd did not provide a certificate")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+
+	c.Lock()
+	opts := x509.VerifyOptions{Roots: c.roots, Intermediates: x509.NewCertPool()}
+	c.Unlock()
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return err
+	}
+	for _, host := range c.hosts {
+		if certs[0].VerifyHostname(host) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate of etcd is not valid for any of the endpoints %v", c.hosts)
+}
+
+// endpointHosts returns hosts of the given endpoints.
+func endpointHosts(endpoints []string) (hosts []string) {
+	for _, endpoint := range endpoints {
+		if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+			endpoint = u.Host
+		}
+		if host, _, err := net.SplitHostPort(endpoint); err == nil {
+			endpoint = host
+		}
+		hosts = append(hosts, endpoint)
+	}
+	return hosts
+}
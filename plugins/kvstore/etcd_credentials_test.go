@@ -0,0 +1,139 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ligato/cn-infra/db/keyval/etcdv3"
+	"github.com/ligato/cn-infra/logging/logrus"
+	. "github.com/onsi/gomega"
+)
+
+// writeCert generates a certificate signed by <parent> (self-signed if nil)
+// and writes it with its key into <dir>.
+func writeCert(dir, name string, host string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).To(BeNil())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{host},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	Expect(err).To(BeNil())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).To(BeNil())
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	Expect(err).To(BeNil())
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	Expect(ioutil.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0600)).To(BeNil())
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	Expect(ioutil.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600)).To(BeNil())
+	return cert, key
+}
+
+// touch moves the modification time of the file forward.
+func touch(file string) {
+	modTime := time.Now().Add(time.Minute)
+	Expect(os.Chtimes(file, modTime, modTime)).To(BeNil())
+}
+
+func TestCredentialsReload(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "etcd-credentials")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	ca, caKey := writeCert(dir, "ca", "", nil, nil)
+	server, _ := writeCert(dir, "server", "etcd.example.com", ca, caKey)
+	client, _ := writeCert(dir, "client", "agent", ca, caKey)
+	passwordFile := filepath.Join(dir, "password")
+	Expect(ioutil.WriteFile(passwordFile, []byte("secret\n"), 0600)).To(BeNil())
+
+	credentials := newETCDCredentials(&ETCDConfig{
+		Config: etcdv3.Config{
+			Endpoints: []string{"https://etcd.example.com:2379"},
+			Certfile:  filepath.Join(dir, "client.crt"),
+			Keyfile:   filepath.Join(dir, "client.key"),
+			CAfile:    filepath.Join(dir, "ca.crt"),
+		},
+		Username:     "contiv",
+		PasswordFile: passwordFile,
+	}, logrus.DefaultLogger())
+	credentials.hosts = endpointHosts(credentials.config.Endpoints)
+	Expect(credentials.hosts).To(Equal([]string{"etcd.example.com"}))
+
+	changed, err := credentials.reload()
+	Expect(err).To(BeNil())
+	Expect(changed).To(BeTrue())
+	Expect(credentials.getPassword()).To(Equal("secret"))
+	tlsConfig := credentials.tlsConfig(&tls.Config{})
+	cert, err := tlsConfig.GetClientCertificate(nil)
+	Expect(err).To(BeNil())
+	Expect(cert.Certificate[0]).To(Equal(client.Raw))
+	Expect(tlsConfig.VerifyPeerCertificate([][]byte{server.Raw}, nil)).To(BeNil())
+
+	// Nothing has changed.
+	changed, err = credentials.reload()
+	Expect(err).To(BeNil())
+	Expect(changed).To(BeFalse())
+
+	// Rotate the password and the certificates, including the CA.
+	Expect(ioutil.WriteFile(passwordFile, []byte("rotated"), 0600)).To(BeNil())
+	touch(passwordFile)
+	newCA, newCAKey := writeCert(dir, "ca", "", nil, nil)
+	touch(filepath.Join(dir, "ca.crt"))
+	newClient, _ := writeCert(dir, "client", "agent", newCA, newCAKey)
+	touch(filepath.Join(dir, "client.crt"))
+	newServer, _ := writeCert(dir, "server", "etcd.example.com", newCA, newCAKey)
+
+	changed, err = credentials.reload()
+	Expect(err).To(BeNil())
+	Expect(changed).To(BeTrue())
+	Expect(credentials.getPassword()).To(Equal("rotated"))
+	cert, err = tlsConfig.GetClientCertificate(nil)
+	Expect(err).To(BeNil())
+	Expect(cert.Certificate[0]).To(Equal(newClient.Raw))
+	Expect(tlsConfig.VerifyPeerCertificate([][]byte{newServer.Raw}, nil)).To(BeNil())
+	Expect(tlsConfig.VerifyPeerCertificate([][]byte{server.Raw}, nil)).ToNot(BeNil())
+
+	// The certificate has to match the endpoint.
+	otherServer, _ := writeCert(dir, "other", "other.example.com", newCA, newCAKey)
+	Expect(tlsConfig.VerifyPeerCertificate([][]byte{otherServer.Raw}, nil)).ToNot(BeNil())
+}
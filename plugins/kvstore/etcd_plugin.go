@@ -0,0 +1,315 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/db/keyval/etcdv3"
+)
+
+const (
+	// leaseOpTimeout is the timeout of the operations keeping the leases alive.
+	leaseOpTimeout = 3 * time.Second
+)
+
+// connector is implemented by data store plugins able to postpone
+// the connection to the data store after their initialization.
+type connector interface {
+	// Connected returns true if the connection is established.
+	Connected() bool
+
+	// Connect (re)tries to establish the connection.
+	Connect() error
+}
+
+// ETCDPlugin is the etcd plugin which does not fail the initialization
+// if etcd is not reachable. The connection is then established later
+// by the kvstore plugin, which meanwhile serves the values from the local
+// cache.
+//
+// The plugin reads ETCDConfig, i.e. supports also the authentication
+// and the reloading of the rotated credentials.
+type ETCDPlugin struct {
+	etcdv3.Plugin
+	connected   bool
+	credentials *etcdCredentials
+	password    string // password the active connection authenticated with
+
+	sync.RWMutex
+	active  *etcdv3.Plugin   // plugin of the connection with the current credentials
+	client  *clientv3.Client // client of the lease operations
+	retired []*etcdv3.Plugin // plugins of the connections replaced after the rotation
+
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Init tries to connect to etcd. Failure to connect is only logged.
+func (p *ETCDPlugin) Init() error {
+	if err := p.Connect(); err != nil {
+		p.Log.Warnf("Failed to connect to etcd: %v", err)
+	}
+	return nil
+}
+
+// Connected returns true if the connection with etcd is established.
+func (p *ETCDPlugin) Connected() bool {
+	return p.connected || p.Disabled()
+}
+
+// Connect (re)tries to establish the connection with etcd.
+func (p *ETCDPlugin) Connect() error {
+	if p.Connected() {
+		return nil
+	}
+	if p.credentials == nil {
+		config := &ETCDConfig{}
+		found, err := p.PluginConfig.GetValue(config)
+		if !found {
+			// let the etcd plugin mark itself as disabled
+			return p.Plugin.Init()
+		}
+		if err != nil {
+			return err
+		}
+		p.credentials = newETCDCredentials(config, p.Log)
+	}
+
+	connection, client, err := p.credentials.connect()
+	if err != nil {
+		return err
+	}
+	deps := p.Plugin.Deps
+	p.Plugin = *etcdv3.FromExistingConnection(connection, p.ServiceLabel)
+	p.Plugin.Deps = deps
+	if err := p.Plugin.Init(); err != nil {
+		return err
+	}
+	p.active, p.client = &p.Plugin, client
+	p.password = p.credentials.getPassword()
+	p.connected = true
+
+	p.closeCh = make(chan struct{})
+	p.wg.Add(1)
+	go p.reloadCredentials()
+	return nil
+}
+
+// Close closes the connection if it was established.
+func (p *ETCDPlugin) Close() error {
+	if !p.connected {
+		return nil
+	}
+	close(p.closeCh)
+	p.wg.Wait()
+	var err error
+	for _, plugin := range append(p.retired, p.active) {
+		if closeErr := plugin.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	if !p.credentials.authenticated() {
+		// the client of the authenticated connection is closed with the connection
+		p.client.Close()
+	}
+	return err
+}
+
+// NewBroker returns a broker using always the active connection.
+func (p *ETCDPlugin) NewBroker(keyPrefix string) keyval.ProtoBroker {
+	if !p.connected {
+		return p.Plugin.NewBroker(keyPrefix)
+	}
+	return &etcdBroker{plugin: p, prefix: keyPrefix}
+}
+
+// NewWatcher returns a watcher of the active connection. The started watches
+// keep running on their connection also after it has been replaced.
+func (p *ETCDPlugin) NewWatcher(keyPrefix string) keyval.ProtoWatcher {
+	if !p.connected {
+		return p.Plugin.NewWatcher(keyPrefix)
+	}
+	return p.activePlugin().NewWatcher(keyPrefix)
+}
+
+// PutIfNotExists puts the given key-value item if the key does not exist yet.
+func (p *ETCDPlugin) PutIfNotExists(key string, value []byte) (succeeded bool, err error) {
+	if !p.connected {
+		return p.Plugin.PutIfNotExists(key, value)
+	}
+	return p.activePlugin().PutIfNotExists(key, value)
+}
+
+// KeepAlive refreshes the lease the given key is bound to, the key is not rewritten.
+func (p *ETCDPlugin) KeepAlive(key string) (found bool, err error) {
+	if !p.connected {
+		return false, errors.New("the connection to etcd is not established")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), leaseOpTimeout)
+	defer cancel()
+
+	p.RLock()
+	client := p.client
+	p.RUnlock()
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if len(resp.Kvs) == 0 {
+		return false, nil
+	}
+	lease := clientv3.LeaseID(resp.Kvs[0].Lease)
+	if lease == clientv3.NoLease {
+		// the key does not expire
+		return true, nil
+	}
+	_, err = client.KeepAliveOnce(ctx, lease)
+	if err == rpctypes.ErrLeaseNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DeleteIfNotChanged removes the key only if its modification revision equals the given one.
+func (p *ETCDPlugin) DeleteIfNotChanged(key string, revision int64) (deleted bool, err error) {
+	if !p.connected {
+		return false, errors.New("the connection to etcd is not established")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), leaseOpTimeout)
+	defer cancel()
+
+	p.RLock()
+	client := p.client
+	p.RUnlock()
+	resp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", revision)).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// reloadCredentials periodically reloads the rotated credentials.
+func (p *ETCDPlugin) reloadCredentials() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.credentials.reloadPeriod())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.closeCh:
+			return
+		case <-ticker.C:
+			changed, err := p.credentials.reload()
+			if err != nil {
+				p.Log.Errorf("Failed to reload etcd credentials: %v", err)
+				continue
+			}
+			if changed {
+				p.Log.Info("Reloaded rotated etcd credentials")
+			}
+			if p.credentials.authenticated() && p.credentials.getPassword() != p.password {
+				// retried with the next reload if failed
+				if err := p.redial(); err != nil {
+					p.Log.Errorf("Failed to reconnect to etcd with the rotated password: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// redial replaces the active connection with a new one authenticated with
+// the rotated password. The client of a connection must not be changed once
+// in use, therefore the connection cannot be just re-authenticated.
+// The replaced connection is closed together with the plugin, not to break
+// the watches started on it.
+func (p *ETCDPlugin) redial() error {
+	connection, client, err := p.credentials.connect()
+	if err != nil {
+		return err
+	}
+	plugin := etcdv3.FromExistingConnection(connection, p.ServiceLabel)
+	plugin.Deps = p.Plugin.Deps
+	if err := plugin.Init(); err != nil {
+		connection.Close()
+		return err
+	}
+	p.password = p.credentials.getPassword()
+
+	p.Lock()
+	defer p.Unlock()
+	p.retired = append(p.retired, p.active)
+	p.active, p.client = plugin, client
+	p.Log.Info("Reconnected to etcd with the rotated password")
+	return nil
+}
+
+// activePlugin returns the plugin of the active connection.
+func (p *ETCDPlugin) activePlugin() *etcdv3.Plugin {
+	p.RLock()
+	defer p.RUnlock()
+	return p.active
+}
+
+// etcdBroker delegates all calls to a broker of the active connection.
+type etcdBroker struct {
+	plugin *ETCDPlugin
+	prefix string
+}
+
+// store returns broker of the active connection.
+func (b *etcdBroker) store() keyval.ProtoBroker {
+	return b.plugin.activePlugin().NewBroker(b.prefix)
+}
+
+// Put puts the value into etcd.
+func (b *etcdBroker) Put(key string, data proto.Message, opts ...datasync.PutOption) error {
+	return b.store().Put(key, data, opts...)
+}
+
+// NewTxn creates a transaction of the active connection.
+func (b *etcdBroker) NewTxn() keyval.ProtoTxn {
+	return b.store().NewTxn()
+}
+
+// GetValue reads the value from etcd.
+func (b *etcdBroker) GetValue(key string, reqObj proto.Message) (found bool, revision int64, err error) {
+	return b.store().GetValue(key, reqObj)
+}
+
+// ListValues lists the values from etcd.
+func (b *etcdBroker) ListValues(key string) (keyval.ProtoKeyValIterator, error) {
+	return b.store().ListValues(key)
+}
+
+// ListKeys lists the keys from etcd.
+func (b *etcdBroker) ListKeys(prefix string) (keyval.ProtoKeyIterator, error) {
+	return b.store().ListKeys(prefix)
+}
+
+// Delete removes the value from etcd.
+func (b *etcdBroker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	return b.store().Delete(key, opts...)
+}
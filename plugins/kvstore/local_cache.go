@@ -0,0 +1,164 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvstore
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ligato/cn-infra/logging"
+)
+
+const (
+	// cacheFileSuffix is the suffix of the files with cached entries.
+	cacheFileSuffix = ".json"
+)
+
+// cacheEntry is a single cached key-value pair.
+type cacheEntry struct {
+	Key   string
+	Value []byte // serialized the same way as in the data store
+	Rev   int64
+}
+
+// localCache is a write-through cache of the key-value pairs read from or
+// written into the data store, persisted in a local directory so that it
+// survives the restart of the agent. Each entry is stored in a separate file
+// named by the hash of the key.
+type localCache struct {
+	sync.Mutex
+	log     logging.Logger
+	dir     string
+	entries map[string]*cacheEntry
+}
+
+// newLocalCache creates the cache directory if needed and loads the entries
+// persisted by the previous run.
+func newLocalCache(dir string, log logging.Logger) (*localCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	cache := &localCache{log: log, dir: dir, entries: make(map[string]*cacheEntry)}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), cacheFileSuffix) {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entry := &cacheEntry{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			log.Warnf("Skipping corrupted cache file %s: %v", file.Name(), err)
+			continue
+		}
+		cache.entries[entry.Key] = entry
+	}
+	return cache, nil
+}
+
+// get returns the cached entry of the key.
+func (c *localCache) get(key string) (entry *cacheEntry, found bool) {
+	c.Lock()
+	defer c.Unlock()
+	entry, found = c.entries[key]
+	return entry, found
+}
+
+// list returns all cached entries with the given key prefix, ordered by key.
+func (c *localCache) list(prefix string) []*cacheEntry {
+	c.Lock()
+	defer c.Unlock()
+	var entries []*cacheEntry
+	for key, entry := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// put creates or overwrites the cached entry of the key.
+func (c *localCache) put(key string, value []byte, rev int64) {
+	c.Lock()
+	defer c.Unlock()
+	if entry, cached := c.entries[key]; cached && entry.Rev == rev && string(entry.Value) == string(value) {
+		return
+	}
+	entry := &cacheEntry{Key: key, Value: value, Rev: rev}
+	data, err := json.Marshal(entry)
+	if err == nil {
+		// Write into a temporary file first, so that the entry is never corrupted.
+		tmpPath := c.filePath(key) + ".tmp"
+		err = ioutil.WriteFile(tmpPath, data, 0600)
+		if err == nil {
+			err = os.Rename(tmpPath, c.filePath(key))
+		}
+	}
+	if err != nil {
+		c.log.Warnf("Failed to cache the value of the key %s: %v", key, err)
+	}
+	c.entries[key] = entry
+}
+
+// delete removes the cached entry of the key.
+func (c *localCache) delete(key string) {
+	c.Lock()
+	defer c.Unlock()
+	c.deleteEntry(key)
+}
+
+// deletePrefix removes all cached entries with the given key prefix,
+// except for the keys in <keep>.
+func (c *localCache) deletePrefix(prefix string, keep map[string]struct{}) {
+	c.Lock()
+	defer c.Unlock()
+	for key := range c.entries {
+		if _, kept := keep[key]; strings.HasPrefix(key, prefix) && !kept {
+			c.deleteEntry(key)
+		}
+	}
+}
+
+// deleteEntry removes the entry from memory and from the disk.
+// The method must be called with acquired mutex.
+func (c *localCache) deleteEntry(key string) {
+	if _, cached := c.entries[key]; !cached {
+		return
+	}
+	delete(c.entries, key)
+	if err := os.Remove(c.filePath(key)); err != nil && !os.IsNotExist(err) {
+		c.log.Warnf("Failed to remove the cached value of the key %s: %v", key, err)
+	}
+}
+
+// filePath returns the path of the file with the entry of the key.
+func (c *localCache) filePath(key string) string {
+	hash := sha1.Sum([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(hash[:])+cacheFileSuffix)
+}
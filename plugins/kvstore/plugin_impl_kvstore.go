@@ -0,0 +1,307 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvstore
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/flavors/local"
+)
+
+const (
+	// reconnectPeriod is the period of the attempts to reconnect to the data store.
+	reconnectPeriod = 5 * time.Second
+
+	// probeKey is a key used to probe the data store state.
+	probeKey = "/probe-kvstore-connection"
+)
+
+// ErrReadOnly is returned for write operations while the data store is not
+// reachable and the values are served from the local cache.
+var ErrReadOnly = errors.New("key-value data store is not reachable, the local cache is read-only")
+
+// KvStore is a key-value data store plugin able to put a value only
+// if the key does not exist yet.
+type KvStore interface {
+	keyval.KvProtoPlugin
+
+	// PutIfNotExists puts the given key-value item if the key does not exist yet.
+	PutIfNotExists(key string, value []byte) (succeeded bool, err error)
+
+	// KeepAlive refreshes TTL of the item put with datasync.WithTTL, without rewriting
+	// the item and without binding it to a new lease. found is false if the item does
+	// not exist, e.g. it has already expired.
+	KeepAlive(key string) (found bool, err error)
+
+	// DeleteIfNotChanged removes the given key only if it has not been modified
+	// since the given revision, as returned by the reads of the key.
+	DeleteIfNotChanged(key string, revision int64) (deleted bool, err error)
+}
+
+// Config represents configuration for the kvstore plugin.
+type Config struct {
+	// CacheDir is the directory of the local cache of the data store values.
+	// The cache is disabled if empty.
+	CacheDir string `json:"cache-dir"`
+}
+
+// Plugin delegates all calls to the first of the data store plugins that
+// is enabled, i.e. has its configuration file present.
+//
+// If the local cache is configured, values read from or written into
+// the data store are also persisted locally. If the data store is not
+// reachable, the values are served from the cache in a read-only mode until
+// the connection is re-established.
+type Plugin struct {
+	Deps
+	selected KvStore
+
+	cache      *localCache
+	serializer keyval.Serializer
+
+	sync.Mutex
+	online  bool
+	pending []*cachedWatch // watches to start once online
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Deps groups the dependencies of the Plugin.
+type Deps struct {
+	local.PluginInfraDeps
+
+	// Stores are the candidate data store plugins in the order of preference.
+	Stores []KvStore
+}
+
+// Init selects the data store plugin. Must be called after all the candidate
+// plugins have been initialized. Fails if more than one plugin is enabled.
+func (p *Plugin) Init() error {
+	p.selected = nil
+	for _, store := range p.Stores {
+		if store.Disabled() {
+			continue
+		}
+		if p.selected != nil {
+			return errors.New("multiple key-value data stores are configured, exactly one is expected")
+		}
+		p.selected = store
+	}
+	if p.selected == nil {
+		p.Log.Warn("No key-value data store is configured")
+		return nil
+	}
+	p.Log.Infof("Using key-value data store %v", p.selected)
+
+	config := &Config{}
+	if p.PluginConfig != nil {
+		if _, err := p.PluginConfig.GetValue(config); err != nil {
+			return err
+		}
+	}
+	if config.CacheDir == "" {
+		// Without the cache the data store has to be reachable.
+		if c, ok := p.selected.(connector); ok {
+			return c.Connect()
+		}
+		return nil
+	}
+
+	var err error
+	p.cache, err = newLocalCache(config.CacheDir, p.Log)
+	if err != nil {
+		return err
+	}
+	p.serializer = &keyval.SerializerJSON{}
+	p.closeCh = make(chan struct{})
+	p.online = p.probe()
+	if !p.online {
+		p.Log.Warnf("Key-value data store is not reachable, serving values from the local cache %s", config.CacheDir)
+		p.wg.Add(1)
+		go p.reconnect()
+	}
+	return nil
+}
+
+// Close stops the attempts to reconnect to the data store,
+// the selected data store is closed by its own plugin.
+func (p *Plugin) Close() error {
+	if p.closeCh != nil {
+		close(p.closeCh)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// NewBroker returns a ProtoBroker of the selected data store.
+func (p *Plugin) NewBroker(keyPrefix string) keyval.ProtoBroker {
+	if p.cache == nil {
+		return p.selected.NewBroker(keyPrefix)
+	}
+	return &cachedBroker{plugin: p, prefix: keyPrefix}
+}
+
+// NewWatcher returns a ProtoWatcher of the selected data store.
+func (p *Plugin) NewWatcher(keyPrefix string) keyval.ProtoWatcher {
+	if p.cache == nil {
+		return p.selected.NewWatcher(keyPrefix)
+	}
+	return &cachedWatcher{plugin: p, prefix: keyPrefix}
+}
+
+// Disabled returns true if none of the data stores is configured.
+func (p *Plugin) Disabled() bool {
+	return p.selected == nil
+}
+
+// PutIfNotExists puts the given key-value item into the selected data store
+// if the key does not exist yet.
+func (p *Plugin) PutIfNotExists(key string, value []byte) (succeeded bool, err error) {
+	if p.selected == nil {
+		return false, errors.New("no key-value data store is configured")
+	}
+	if p.cache == nil {
+		return p.selected.PutIfNotExists(key, value)
+	}
+	if !p.isOnline() {
+		return false, ErrReadOnly
+	}
+	succeeded, err = p.selected.PutIfNotExists(key, value)
+	if succeeded {
+		p.cache.put(key, value, 0)
+	}
+	return succeeded, err
+}
+
+// KeepAlive refreshes TTL of the item of the selected data store.
+func (p *Plugin) KeepAlive(key string) (found bool, err error) {
+	if p.selected == nil {
+		return false, errors.New("no key-value data store is configured")
+	}
+	if p.cache != nil && !p.isOnline() {
+		return false, ErrReadOnly
+	}
+	return p.selected.KeepAlive(key)
+}
+
+// DeleteIfNotChanged removes the key from the selected data store if it has not
+// been modified since the given revision.
+func (p *Plugin) DeleteIfNotChanged(key string, revision int64) (deleted bool, err error) {
+	if p.selected == nil {
+		return false, errors.New("no key-value data store is configured")
+	}
+	if p.cache == nil {
+		return p.selected.DeleteIfNotChanged(key, revision)
+	}
+	if !p.isOnline() {
+		return false, ErrReadOnly
+	}
+	deleted, err = p.selected.DeleteIfNotChanged(key, revision)
+	if deleted {
+		p.cache.delete(key)
+	}
+	return deleted, err
+}
+
+// IsOnline returns true if the data store is reachable, i.e. the values
+// are not served from the local cache.
+func (p *Plugin) IsOnline() bool {
+	return p.cache == nil || p.isOnline()
+}
+
+// isOnline returns true if the data store was reachable.
+func (p *Plugin) isOnline() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.online
+}
+
+// probe returns true if the data store is reachable.
+func (p *Plugin) probe() bool {
+	if c, ok := p.selected.(connector); ok && !c.Connected() {
+		if err := c.Connect(); err != nil {
+			p.Log.Debugf("Failed to connect to the data store: %v", err)
+			return false
+		}
+	}
+	it, err := p.selected.NewBroker(keyval.Root).ListKeys(probeKey)
+	if err != nil {
+		p.Log.Debugf("Failed to probe the data store: %v", err)
+		return false
+	}
+	it.Close()
+	return true
+}
+
+// reconnect periodically probes the data store until it is reachable again.
+func (p *Plugin) reconnect() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(reconnectPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.closeCh:
+			return
+		case <-ticker.C:
+			if p.probe() {
+				p.Log.Info("Key-value data store is reachable again")
+				p.goOnline()
+				return
+			}
+		}
+	}
+}
+
+// goOnline switches from the cache to the data store and starts the postponed
+// watches, notifying the changes made while the data store was not reachable.
+func (p *Plugin) goOnline() {
+	p.Lock()
+	p.online = true
+	pending := p.pending
+	p.pending = nil
+	p.Unlock()
+
+	for _, watch := range pending {
+		if err := watch.start(true); err != nil {
+			p.Log.Errorf("Failed to start watching %v: %v", watch.keys, err)
+		}
+	}
+}
+
+// startWatch starts the watch if the data store is reachable, postpones it otherwise.
+func (p *Plugin) startWatch(watch *cachedWatch) error {
+	p.Lock()
+	if !p.online {
+		p.pending = append(p.pending, watch)
+		p.Unlock()
+		return nil
+	}
+	p.Unlock()
+	return watch.start(false)
+}
+
+// cacheValue writes the serialized value into the cache.
+func (p *Plugin) cacheValue(key string, value proto.Message, rev int64) {
+	data, err := p.serializer.Marshal(value)
+	if err != nil {
+		p.Log.Warnf("Failed to serialize the value of the key %s: %v", key, err)
+		return
+	}
+	p.cache.put(key, data, rev)
+}
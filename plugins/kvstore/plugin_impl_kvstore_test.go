@@ -0,0 +1,303 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvstore
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/contiv/vpp/plugins/contiv/model/node"
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/cn-infra/logging/logrus"
+	. "github.com/onsi/gomega"
+)
+
+var errUnreachable = errors.New("unreachable")
+
+// fakeStore is a data store recording the keys put into it.
+// The values are kept in the same structure as used by the cache.
+type fakeStore struct {
+	keyval.KvProtoPlugin
+	disabled    bool
+	unreachable bool
+	keys        []string
+	values      *Plugin
+	rev         int64
+	watches     []func(keyval.ProtoWatchResp)
+}
+
+func newFakeStore(t *testing.T) *fakeStore {
+	values := &Plugin{serializer: &keyval.SerializerJSON{}}
+	values.cache = newTestCache(t)
+	return &fakeStore{values: values}
+}
+
+func (s *fakeStore) NewBroker(prefix string) keyval.ProtoBroker {
+	return &fakeBroker{store: s, prefix: prefix}
+}
+
+func (s *fakeStore) NewWatcher(prefix string) keyval.ProtoWatcher {
+	return s
+}
+
+func (s *fakeStore) Watch(resp func(keyval.ProtoWatchResp), closeChan chan string, keys ...string) error {
+	s.watches = append(s.watches, resp)
+	return nil
+}
+
+func (s *fakeStore) Disabled() bool {
+	return s.disabled
+}
+
+func (s *fakeStore) PutIfNotExists(key string, value []byte) (succeeded bool, err error) {
+	s.keys = append(s.keys, key)
+	return true, nil
+}
+
+func (s *fakeStore) KeepAlive(key string) (found bool, err error) {
+	return true, nil
+}
+
+func (s *fakeStore) DeleteIfNotChanged(key string, revision int64) (deleted bool, err error) {
+	return true, nil
+}
+
+// fakeBroker implements the subset of ProtoBroker used by the tests.
+type fakeBroker struct {
+	keyval.ProtoBroker
+	store  *fakeStore
+	prefix string
+}
+
+func (b *fakeBroker) Put(key string, data proto.Message, opts ...datasync.PutOption) error {
+	if b.store.unreachable {
+		return errUnreachable
+	}
+	b.store.rev++
+	b.store.values.cacheValue(b.prefix+key, data, b.store.rev)
+	return nil
+}
+
+func (b *fakeBroker) GetValue(key string, reqObj proto.Message) (found bool, revision int64, err error) {
+	if b.store.unreachable {
+		return false, 0, errUnreachable
+	}
+	entry, found := b.store.values.cache.get(b.prefix + key)
+	if !found {
+		return false, 0, nil
+	}
+	return true, entry.Rev, b.store.values.serializer.Unmarshal(entry.Value, reqObj)
+}
+
+func (b *fakeBroker) ListValues(key string) (keyval.ProtoKeyValIterator, error) {
+	if b.store.unreachable {
+		return nil, errUnreachable
+	}
+	return &cachedKeyValIterator{plugin: b.store.values, prefix: b.prefix, entries: b.store.values.cache.list(b.prefix + key)}, nil
+}
+
+func (b *fakeBroker) ListKeys(prefix string) (keyval.ProtoKeyIterator, error) {
+	if b.store.unreachable {
+		return nil, errUnreachable
+	}
+	return &cachedKeyIterator{prefix: b.prefix, entries: b.store.values.cache.list(b.prefix + prefix)}, nil
+}
+
+func (b *fakeBroker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	if b.store.unreachable {
+		return false, errUnreachable
+	}
+	_, existed = b.store.values.cache.get(b.prefix + key)
+	b.store.values.cache.delete(b.prefix + key)
+	return existed, nil
+}
+
+// fakeConfig provides the kvstore configuration.
+type fakeConfig struct {
+	Config
+}
+
+func (c *fakeConfig) GetValue(data interface{}) (found bool, err error) {
+	*data.(*Config) = c.Config
+	return true, nil
+}
+
+func (c *fakeConfig) GetConfigName() string {
+	return "kvstore.conf"
+}
+
+func newTestCache(t *testing.T) *localCache {
+	dir, err := ioutil.TempDir("", "kvstore")
+	Expect(err).To(BeNil())
+	cache, err := newLocalCache(dir, logrus.DefaultLogger())
+	Expect(err).To(BeNil())
+	return cache
+}
+
+func newCachedPlugin(cacheDir string, store KvStore) *Plugin {
+	plugin := newPlugin(store)
+	plugin.PluginConfig = &fakeConfig{Config{CacheDir: cacheDir}}
+	return plugin
+}
+
+func newPlugin(stores ...KvStore) *Plugin {
+	plugin := &Plugin{}
+	plugin.Log = logging.ForPlugin("kvstore", logrus.NewLogRegistry())
+	plugin.Stores = stores
+	return plugin
+}
+
+func TestSelection(t *testing.T) {
+	RegisterTestingT(t)
+
+	etcd := &fakeStore{disabled: true}
+	consul := &fakeStore{}
+	plugin := newPlugin(etcd, consul)
+	Expect(plugin.Init()).To(BeNil())
+	Expect(plugin.Disabled()).To(BeFalse())
+	succeeded, err := plugin.PutIfNotExists("key", nil)
+	Expect(err).To(BeNil())
+	Expect(succeeded).To(BeTrue())
+	Expect(consul.keys).To(Equal([]string{"key"}))
+	Expect(etcd.keys).To(BeEmpty())
+
+	// No data store configured.
+	plugin = newPlugin(&fakeStore{disabled: true}, &fakeStore{disabled: true})
+	Expect(plugin.Init()).To(BeNil())
+	Expect(plugin.Disabled()).To(BeTrue())
+	_, err = plugin.PutIfNotExists("key", nil)
+	Expect(err).ToNot(BeNil())
+
+	// Ambiguous configuration.
+	plugin = newPlugin(&fakeStore{}, &fakeStore{})
+	Expect(plugin.Init()).ToNot(BeNil())
+}
+
+func TestLocalCache(t *testing.T) {
+	RegisterTestingT(t)
+
+	cache := newTestCache(t)
+	defer os.RemoveAll(cache.dir)
+
+	cache.put("/a/1", []byte("1"), 1)
+	cache.put("/a/2", []byte("2"), 2)
+	cache.put("/b/1", []byte("3"), 3)
+	cache.delete("/a/2")
+
+	// The entries are restored from the disk.
+	restored, err := newLocalCache(cache.dir, logrus.DefaultLogger())
+	Expect(err).To(BeNil())
+	entries := restored.list("/")
+	Expect(entries).To(HaveLen(2))
+	Expect(entries[0]).To(Equal(&cacheEntry{Key: "/a/1", Value: []byte("1"), Rev: 1}))
+	Expect(entries[1]).To(Equal(&cacheEntry{Key: "/b/1", Value: []byte("3"), Rev: 3}))
+
+	restored.deletePrefix("/", map[string]struct{}{"/b/1": {}})
+	_, found := restored.get("/a/1")
+	Expect(found).To(BeFalse())
+	_, found = restored.get("/b/1")
+	Expect(found).To(BeTrue())
+}
+
+func TestDisconnectedOperation(t *testing.T) {
+	RegisterTestingT(t)
+
+	store := newFakeStore(t)
+	defer os.RemoveAll(store.values.cache.dir)
+	cacheDir, err := ioutil.TempDir("", "kvstore")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(cacheDir)
+
+	// The first run writes through the cache.
+	plugin := newCachedPlugin(cacheDir, store)
+	Expect(plugin.Init()).To(BeNil())
+	Expect(plugin.IsOnline()).To(BeTrue())
+	broker := plugin.NewBroker("/prefix/")
+	Expect(broker.Put("a", &node.NodeInfo{Name: "a"})).To(BeNil())
+	Expect(broker.Put("b", &node.NodeInfo{Name: "b"})).To(BeNil())
+	Expect(plugin.Close()).To(BeNil())
+
+	// Restart while the data store is not reachable.
+	store.unreachable = true
+	plugin = newCachedPlugin(cacheDir, store)
+	Expect(plugin.Init()).To(BeNil())
+	defer plugin.Close()
+	Expect(plugin.IsOnline()).To(BeFalse())
+	broker = plugin.NewBroker("/prefix/")
+
+	value := &node.NodeInfo{}
+	found, _, err := broker.GetValue("a", value)
+	Expect(err).To(BeNil())
+	Expect(found).To(BeTrue())
+	Expect(value.Name).To(Equal("a"))
+
+	it, err := broker.ListValues("")
+	Expect(err).To(BeNil())
+	var keys []string
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+		keys = append(keys, kv.GetKey())
+	}
+	Expect(keys).To(Equal([]string{"a", "b"}))
+
+	Expect(broker.Put("c", &node.NodeInfo{})).To(Equal(ErrReadOnly))
+	_, err = plugin.PutIfNotExists("/prefix/c", nil)
+	Expect(err).To(Equal(ErrReadOnly))
+
+	// The watch is postponed until the data store is reachable.
+	var changes []keyval.ProtoWatchResp
+	err = plugin.NewWatcher("/prefix/").Watch(func(resp keyval.ProtoWatchResp) {
+		changes = append(changes, resp)
+	}, nil, "")
+	Expect(err).To(BeNil())
+	Expect(store.watches).To(BeEmpty())
+
+	// Changes made in the data store meanwhile are notified once reconnected.
+	store.unreachable = false
+	storeBroker := store.NewBroker("/prefix/")
+	Expect(storeBroker.Put("b", &node.NodeInfo{Name: "b2"})).To(BeNil())
+	storeBroker.Delete("a")
+	Expect(plugin.probe()).To(BeTrue())
+	plugin.goOnline()
+	Expect(plugin.IsOnline()).To(BeTrue())
+	Expect(store.watches).To(HaveLen(1))
+
+	Expect(changes).To(HaveLen(2))
+	Expect(changes[0].GetChangeType()).To(Equal(datasync.Put))
+	Expect(changes[0].GetKey()).To(Equal("b"))
+	Expect(changes[0].GetValue(value)).To(BeNil())
+	Expect(value.Name).To(Equal("b2"))
+	prevExists, err := changes[0].GetPrevValue(value)
+	Expect(err).To(BeNil())
+	Expect(prevExists).To(BeTrue())
+	Expect(value.Name).To(Equal("b"))
+	Expect(changes[1].GetChangeType()).To(Equal(datasync.Delete))
+	Expect(changes[1].GetKey()).To(Equal("a"))
+
+	// The cache is updated.
+	_, found = plugin.cache.get("/prefix/a")
+	Expect(found).To(BeFalse())
+	entry, found := plugin.cache.get("/prefix/b")
+	Expect(found).To(BeTrue())
+	Expect(entry.Rev).To(Equal(store.rev))
+}
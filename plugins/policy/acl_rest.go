@@ -0,0 +1,88 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"net/http"
+
+	"github.com/unrolled/render"
+)
+
+const (
+	// aclsURL is the URL of the REST API listing ACLs rendered into VPP.
+	aclsURL = "/contiv/v1/acls"
+
+	// policyAuditURL is the URL of the REST API listing traffic permitted only
+	// thanks to the audit mode.
+	policyAuditURL = "/contiv/v1/policy-audit"
+
+	// policyStatsURL is the URL of the REST API listing hit counters of the rules
+	// rendered for policies.
+	policyStatsURL = "/contiv/v1/policy-stats"
+)
+
+// registerHandlers registers REST handlers inspecting the rendered policies:
+//   - List ACLs configured in VPP:
+//     > curl -X GET http://localhost:<port>/contiv/v1/acls
+//   - List violations of policies rendered in the audit mode:
+//     > curl -X GET http://localhost:<port>/contiv/v1/policy-audit
+//   - List connections permitted and denied by the rules of policies:
+//     > curl -X GET http://localhost:<port>/contiv/v1/policy-stats
+func (p *Plugin) registerHandlers() {
+	p.HTTPHandlers.RegisterHTTPHandler(aclsURL, p.aclsHandler, "GET")
+	p.HTTPHandlers.RegisterHTTPHandler(policyAuditURL, p.policyAuditHandler, "GET")
+	p.HTTPHandlers.RegisterHTTPHandler(policyStatsURL, p.policyStatsHandler, "GET")
+}
+
+// aclsHandler processes requests to list the ACLs configured in VPP.
+func (p *Plugin) aclsHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		acls, err := p.VPP.DumpACL()
+		if err != nil {
+			p.Log.Errorf("Unable to dump ACLs: %v", err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, acls)
+	}
+}
+
+// policyAuditHandler processes requests to list violations of policies rendered
+// in the audit mode.
+func (p *Plugin) policyAuditHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		_, violations, err := p.dumpRuleStats()
+		if err != nil {
+			p.Log.Errorf("Unable to read policy violations: %v", err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, violations)
+	}
+}
+
+// policyStatsHandler processes requests to list hit counters of the rules
+// rendered for policies.
+func (p *Plugin) policyStatsHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		policyStats, _, err := p.dumpRuleStats()
+		if err != nil {
+			p.Log.Errorf("Unable to read rule hit counters: %v", err)
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+		formatter.JSON(w, http.StatusOK, policyStats)
+	}
+}
@@ -19,7 +19,9 @@ package cache
 import (
 	"github.com/ligato/cn-infra/datasync"
 
+	"github.com/contiv/vpp/plugins/ksr/model/custompolicy"
 	nsmodel "github.com/contiv/vpp/plugins/ksr/model/namespace"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
 )
@@ -83,6 +85,15 @@ type PolicyCacheAPI interface {
 
 	// ListAllNamespaces returns IDs of all known namespaces.
 	ListAllNamespaces() (namespaces []nsmodel.ID)
+
+	// LookupCustomPolicy returns data of a given custom network policy.
+	LookupCustomPolicy(policy custompolicy.ID) (found bool, data *custompolicy.CustomNetworkPolicy)
+
+	// ListAllCustomPolicies returns IDs of all custom network policies.
+	ListAllCustomPolicies() (policies []custompolicy.ID)
+
+	// LookupNode returns data of a given node.
+	LookupNode(node nodemodel.ID) (found bool, data *nodemodel.Node)
 }
 
 // PolicyCacheWatcher defines interface that a PolicyCache watcher must implement.
@@ -118,4 +129,25 @@ type PolicyCacheWatcher interface {
 	// UpdateNamespace is called by Policy Cache when data of a namespace were
 	// modified.
 	UpdateNamespace(oldNs, newNs *nsmodel.Namespace) error
+
+	// AddCustomPolicy is called by Policy Cache when a new custom network policy
+	// is created.
+	AddCustomPolicy(policy *custompolicy.CustomNetworkPolicy) error
+
+	// DelCustomPolicy is called by Policy Cache after a custom network policy
+	// was removed.
+	DelCustomPolicy(policy *custompolicy.CustomNetworkPolicy) error
+
+	// UpdateCustomPolicy is called by Policy Cache when data of a custom network
+	// policy were modified.
+	UpdateCustomPolicy(oldPolicy, newPolicy *custompolicy.CustomNetworkPolicy) error
+
+	// AddNode is called by Policy Cache when a new node is added.
+	AddNode(node *nodemodel.Node) error
+
+	// DelNode is called by Policy Cache after a node was removed.
+	DelNode(node *nodemodel.Node) error
+
+	// UpdateNode is called by Policy Cache when data of a node were modified.
+	UpdateNode(oldNode, newNode *nodemodel.Node) error
 }
@@ -7,7 +7,9 @@ import (
 	"github.com/ligato/cn-infra/datasync"
 	"github.com/ligato/cn-infra/logging"
 
+	"github.com/contiv/vpp/plugins/ksr/model/custompolicy"
 	nsmodel "github.com/contiv/vpp/plugins/ksr/model/namespace"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
 	"github.com/contiv/vpp/plugins/policy/cache/namespaceidx"
@@ -31,6 +33,10 @@ type PolicyCache struct {
 	configuredPods       *podidx.ConfigIndex
 	configuredNamespaces *namespaceidx.ConfigIndex
 	watchers             []PolicyCacheWatcher
+
+	// cluster-scoped data, small enough not to need an index
+	customPolicies map[custompolicy.ID]*custompolicy.CustomNetworkPolicy
+	nodes          map[nodemodel.ID]*nodemodel.Node
 }
 
 // Deps lists dependencies of PolicyCache.
@@ -44,6 +50,8 @@ func (pc *PolicyCache) Init() error {
 	pc.configuredPolicies = policyidx.NewConfigIndex(pc.Log, pc.PluginName, "policies")
 	pc.configuredPods = podidx.NewConfigIndex(pc.Log, pc.PluginName, "pods")
 	pc.configuredNamespaces = namespaceidx.NewConfigIndex(pc.Log, pc.PluginName, "namespaces")
+	pc.customPolicies = make(map[custompolicy.ID]*custompolicy.CustomNetworkPolicy)
+	pc.nodes = make(map[nodemodel.ID]*nodemodel.Node)
 
 	pc.watchers = []PolicyCacheWatcher{}
 	return nil
@@ -257,3 +265,24 @@ func (pc *PolicyCache) ListAllNamespaces() (namespaces []nsmodel.ID) {
 
 	return namespaces
 }
+
+// LookupCustomPolicy returns data of a given custom network policy.
+func (pc *PolicyCache) LookupCustomPolicy(policy custompolicy.ID) (found bool, data *custompolicy.CustomNetworkPolicy) {
+	data, found = pc.customPolicies[policy]
+	return found, data
+}
+
+// ListAllCustomPolicies returns IDs of all custom network policies.
+func (pc *PolicyCache) ListAllCustomPolicies() (policies []custompolicy.ID) {
+	policies = []custompolicy.ID{}
+	for policy := range pc.customPolicies {
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
+// LookupNode returns data of a given node.
+func (pc *PolicyCache) LookupNode(node nodemodel.ID) (found bool, data *nodemodel.Node) {
+	data, found = pc.nodes[node]
+	return found, data
+}
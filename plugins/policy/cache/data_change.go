@@ -19,7 +19,9 @@ package cache
 import (
 	"github.com/ligato/cn-infra/datasync"
 
+	"github.com/contiv/vpp/plugins/ksr/model/custompolicy"
 	namespacemodel "github.com/contiv/vpp/plugins/ksr/model/namespace"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
 )
@@ -170,6 +172,94 @@ func (pc *PolicyCache) changePropagateEvent(dataChngEv datasync.ChangeEvent) err
 
 			}
 		}
+		return nil
+	}
+
+	// Propagate Custom Policy CHANGE event
+	_, err = custompolicy.ParseCustomPolicyFromKey(key)
+	if err == nil {
+		var value, prevValue custompolicy.CustomNetworkPolicy
+
+		if err = dataChngEv.GetValue(&value); err != nil {
+			return err
+		}
+
+		if diff, err = dataChngEv.GetPrevValue(&prevValue); err != nil {
+			return err
+		}
+
+		if datasync.Delete == dataChngEv.GetChangeType() {
+			delete(pc.customPolicies, custompolicy.GetID(&prevValue))
+
+			for _, watcher := range pc.watchers {
+				if err := watcher.DelCustomPolicy(&prevValue); err != nil {
+					return err
+				}
+			}
+
+		} else if diff {
+			delete(pc.customPolicies, custompolicy.GetID(&prevValue))
+			pc.customPolicies[custompolicy.GetID(&value)] = &value
+
+			for _, watcher := range pc.watchers {
+				if err := watcher.UpdateCustomPolicy(&prevValue, &value); err != nil {
+					return err
+				}
+			}
+
+		} else {
+			pc.customPolicies[custompolicy.GetID(&value)] = &value
+
+			for _, watcher := range pc.watchers {
+				if err := watcher.AddCustomPolicy(&value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// Propagate Node CHANGE event
+	_, err = nodemodel.ParseNodeFromKey(key)
+	if err == nil {
+		var value, prevValue nodemodel.Node
+
+		if err = dataChngEv.GetValue(&value); err != nil {
+			return err
+		}
+
+		if diff, err = dataChngEv.GetPrevValue(&prevValue); err != nil {
+			return err
+		}
+
+		if datasync.Delete == dataChngEv.GetChangeType() {
+			delete(pc.nodes, nodemodel.GetID(&prevValue))
+
+			for _, watcher := range pc.watchers {
+				if err := watcher.DelNode(&prevValue); err != nil {
+					return err
+				}
+			}
+
+		} else if diff {
+			delete(pc.nodes, nodemodel.GetID(&prevValue))
+			pc.nodes[nodemodel.GetID(&value)] = &value
+
+			for _, watcher := range pc.watchers {
+				if err := watcher.UpdateNode(&prevValue, &value); err != nil {
+					return err
+				}
+			}
+
+		} else {
+			pc.nodes[nodemodel.GetID(&value)] = &value
+
+			for _, watcher := range pc.watchers {
+				if err := watcher.AddNode(&value); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
 	return nil
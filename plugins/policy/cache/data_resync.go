@@ -19,7 +19,9 @@ package cache
 import (
 	"github.com/ligato/cn-infra/datasync"
 
+	"github.com/contiv/vpp/plugins/ksr/model/custompolicy"
 	namespacemodel "github.com/contiv/vpp/plugins/ksr/model/namespace"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
 
@@ -31,6 +33,9 @@ type DataResyncEvent struct {
 	Namespaces []*namespacemodel.Namespace
 	Pods       []*podmodel.Pod
 	Policies   []*policymodel.Policy
+
+	CustomPolicies []*custompolicy.CustomNetworkPolicy
+	Nodes          []*nodemodel.Node
 }
 
 // NewDataResyncEvent creates an empty instance of DataResyncEvent.
@@ -39,6 +44,9 @@ func NewDataResyncEvent() *DataResyncEvent {
 		Namespaces: []*namespacemodel.Namespace{},
 		Pods:       []*podmodel.Pod{},
 		Policies:   []*policymodel.Policy{},
+
+		CustomPolicies: []*custompolicy.CustomNetworkPolicy{},
+		Nodes:          []*nodemodel.Node{},
 	}
 }
 
@@ -49,6 +57,8 @@ func (pc *PolicyCache) resyncParseEvent(resyncEv datasync.ResyncEvent) *DataResy
 	var numPod int
 
 	event := NewDataResyncEvent()
+	pc.customPolicies = make(map[custompolicy.ID]*custompolicy.CustomNetworkPolicy)
+	pc.nodes = make(map[nodemodel.ID]*nodemodel.Node)
 
 	for key, resyncData := range resyncEv.GetValues() {
 		pc.Log.Debug("Received RESYNC key ", key)
@@ -102,6 +112,30 @@ func (pc *PolicyCache) resyncParseEvent(resyncEv datasync.ResyncEvent) *DataResy
 				}
 				continue
 			}
+
+			// Parse custom policy RESYNC event
+			_, err = custompolicy.ParseCustomPolicyFromKey(key)
+			if err == nil {
+				value := &custompolicy.CustomNetworkPolicy{}
+				err = evData.GetValue(value)
+				if err == nil {
+					event.CustomPolicies = append(event.CustomPolicies, value)
+					pc.customPolicies[custompolicy.GetID(value)] = value
+				}
+				continue
+			}
+
+			// Parse node RESYNC event
+			_, err = nodemodel.ParseNodeFromKey(key)
+			if err == nil {
+				value := &nodemodel.Node{}
+				err = evData.GetValue(value)
+				if err == nil {
+					event.Nodes = append(event.Nodes, value)
+					pc.nodes[nodemodel.GetID(value)] = value
+				}
+				continue
+			}
 		}
 	}
 
@@ -109,6 +143,8 @@ func (pc *PolicyCache) resyncParseEvent(resyncEv datasync.ResyncEvent) *DataResy
 		"num-policies": numPolicy,
 		"num-pods":     numPod,
 		"num-ns":       numNs,
+		"num-custom":   len(event.CustomPolicies),
+		"num-nodes":    len(event.Nodes),
 	}).Debug("Parsed RESYNC event")
 
 	return event
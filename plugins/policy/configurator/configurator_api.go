@@ -168,7 +168,7 @@ type PolicyType int
 const (
 
 	// PolicyIngress tells policy to apply to ingress only.
-	PolicyIngress = iota
+	PolicyIngress PolicyType = iota
 
 	// PolicyEgress tells policy to apply to egress only.
 	PolicyEgress
@@ -213,7 +213,7 @@ func (mt MatchType) String() string {
 	return "INVALID"
 }
 
-// ProtocolType is either TCP or UDP.
+// ProtocolType is either TCP, UDP or SCTP.
 type ProtocolType int
 
 const (
@@ -222,6 +222,9 @@ const (
 
 	// UDP protocol.
 	UDP
+
+	// SCTP protocol.
+	SCTP
 )
 
 // String converts ProtocolType into a human-readable string.
@@ -231,11 +234,24 @@ func (pt ProtocolType) String() string {
 		return "TCP"
 	case UDP:
 		return "UDP"
+	case SCTP:
+		return "SCTP"
 	}
 	return "INVALID"
 }
 
-// Port represent a TCP or UDP port.
+// rendererProtocol returns the protocol type as defined by the renderer API.
+func (pt ProtocolType) rendererProtocol() renderer.ProtocolType {
+	switch pt {
+	case UDP:
+		return renderer.UDP
+	case SCTP:
+		return renderer.SCTP
+	}
+	return renderer.TCP
+}
+
+// Port represent a TCP, UDP or SCTP port.
 // Number=0 represents all ports for a given protocol.
 type Port struct {
 	Protocol ProtocolType
@@ -244,10 +260,7 @@ type Port struct {
 
 // String return a human-readable string representation of the Port.
 func (port Port) String() string {
-	protocol := "TCP"
-	if port.Protocol == UDP {
-		protocol = "UDP"
-	}
+	protocol := port.Protocol.String()
 	if port.Number == 0 {
 		return protocol + ":ANY"
 	}
@@ -17,10 +17,14 @@
 package configurator
 
 import (
+	"bytes"
 	"net"
 	"sort"
+	"time"
 
 	"github.com/ligato/cn-infra/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/trace"
 
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	"github.com/contiv/vpp/plugins/policy/cache"
@@ -28,6 +32,9 @@ import (
 	"github.com/contiv/vpp/plugins/policy/utils"
 )
 
+// traceFamily is the family of the traces of the policy rendering.
+const traceFamily = "contiv.policy"
+
 // PolicyConfigurator translates a set of Contiv Policies into ingress and
 // egress lists of Contiv Rules (n-tuples with the most basic policy rule
 // definition) and applies them into the target vswitch via registered
@@ -42,12 +49,23 @@ type PolicyConfigurator struct {
 	renderers         []renderer.PolicyRendererAPI
 	parallelRendering bool
 	podIPAddresses    PodIPAddresses
+	podPolicies       map[podmodel.ID]ContivPolicies
 }
 
 // Deps lists dependencies of PolicyConfigurator.
 type Deps struct {
 	Log   logging.Logger
 	Cache cache.PolicyCacheAPI
+
+	// RenderDuration is optional, observes the duration of transaction commits if set.
+	RenderDuration prometheus.Histogram
+
+	// AuditMode enables the audit (log-only) mode for all pods, AuditNamespaces
+	// only for pods of the listed namespaces. In the audit mode, the traffic
+	// not allowed by the policies is permitted by rules with IDs ending with
+	// renderer.AuditRuleSuffix, instead of being denied.
+	AuditMode       bool
+	AuditNamespaces map[string]struct{}
 }
 
 // PolicyConfiguratorTxn represents a single transaction of the policy configurator.
@@ -67,6 +85,7 @@ type ContivPolicies []*ContivPolicy
 // for a performance optimization.
 type ProcessedPolicySet struct {
 	policies ContivPolicies // ordered
+	audit    bool
 	ingress  ContivRules
 	egress   ContivRules
 }
@@ -82,9 +101,16 @@ func (pc *PolicyConfigurator) Init(parallelRendering bool) error {
 	pc.renderers = []renderer.PolicyRendererAPI{}
 	pc.parallelRendering = parallelRendering
 	pc.podIPAddresses = make(PodIPAddresses)
+	pc.podPolicies = make(map[podmodel.ID]ContivPolicies)
 	return nil
 }
 
+// GetPodPolicies returns the policies last committed for the given pod.
+// Must not be called concurrently with Commit().
+func (pc *PolicyConfigurator) GetPodPolicies(pod podmodel.ID) ContivPolicies {
+	return pc.podPolicies[pod]
+}
+
 // RegisterRenderer registers a new renderer.
 // The renderer will be receiving rules for all pods in this K8s node.
 // It is up to the render to possibly filter out rules for pods without
@@ -127,6 +153,18 @@ func (pct *PolicyConfiguratorTxn) Configure(pod podmodel.ID, policies []*ContivP
 
 // Commit proceeds with the reconfiguration.
 func (pct *PolicyConfiguratorTxn) Commit() error {
+	if pct.configurator.RenderDuration != nil {
+		start := time.Now()
+		defer func() {
+			pct.configurator.RenderDuration.Observe(time.Since(start).Seconds())
+		}()
+	}
+
+	// Trace the rendering (browsable on /debug/requests of the agent).
+	tr := trace.New(traceFamily, "Commit")
+	defer tr.Finish()
+	tr.LazyPrintf("rendering policies of %d pods (resync=%t)", len(pct.config), pct.resync)
+
 	// Remember processed sets of policies between iterations so that the same
 	// set will not be processed more than once.
 	processed := []ProcessedPolicySet{}
@@ -141,10 +179,19 @@ func (pct *PolicyConfiguratorTxn) Commit() error {
 
 		// Get target pod configuration.
 		podIPNet, hadIPAddr := pct.podIPAddresses[pod]
-		found, podData := pct.configurator.Cache.LookupPod(pod)
+		var found bool
+		var podData *podmodel.Pod
+		if pod == renderer.HostEndpoint {
+			// The host endpoint has no IP address and it is configured only
+			// while there are some policies assigned to it.
+			found = len(unorderedPolicies) > 0
+		} else {
+			found, podData = pct.configurator.Cache.LookupPod(pod)
+			found = found && podData.IpAddress != ""
+		}
 
 		// Handle removed pod.
-		if !found || podData.IpAddress == "" {
+		if !found {
 			if hadIPAddr {
 				pct.Log.WithField("pod", pod).Debug("Removing policies from the pod.")
 				delPodConfig = true
@@ -157,10 +204,12 @@ func (pct *PolicyConfiguratorTxn) Commit() error {
 
 		if !delPodConfig {
 			// Get pod IP address (expressed as one-host subnet).
-			podIPNet = utils.GetOneHostSubnet(podData.IpAddress)
-			if podIPNet == nil {
-				pct.Log.WithField("pod", pod).Warn("Pod has invalid IP address assigned")
-				continue
+			if podData != nil {
+				podIPNet = utils.GetOneHostSubnet(podData.IpAddress)
+				if podIPNet == nil {
+					pct.Log.WithField("pod", pod).Warn("Pod has invalid IP address assigned")
+					continue
+				}
 			}
 			pct.podIPAddresses[pod] = podIPNet
 
@@ -169,9 +218,10 @@ func (pct *PolicyConfiguratorTxn) Commit() error {
 			sort.Sort(policies)
 
 			// Check if this set was already processed.
+			audit := pct.configurator.isAudited(pod)
 			alreadyProcessed := false
 			for _, policySet := range processed {
-				if policySet.policies.Equals(policies) {
+				if policySet.audit == audit && policySet.policies.Equals(policies) {
 					ingress = policySet.ingress
 					egress = policySet.egress
 					alreadyProcessed = true
@@ -182,12 +232,13 @@ func (pct *PolicyConfiguratorTxn) Commit() error {
 			if !alreadyProcessed {
 				// Direction in policies is from the pod point of view, whereas rules
 				// are evaluated from the vswitch perspective.
-				egress = pct.generateRules(MatchIngress, policies)
-				ingress = pct.generateRules(MatchEgress, policies)
+				egress = pct.generateRules(MatchIngress, policies, audit)
+				ingress = pct.generateRules(MatchEgress, policies, audit)
 				// Remember already processed set of policies.
 				processed = append(processed,
 					ProcessedPolicySet{
 						policies: policies,
+						audit:    audit,
 						ingress:  ingress,
 						egress:   egress,
 					})
@@ -207,17 +258,19 @@ func (pct *PolicyConfiguratorTxn) Commit() error {
 		}
 	}
 
+	tr.LazyPrintf("rules generated for %d sets of policies", len(processed))
+
 	// Commit all renderer transactions.
 	var wasError error
 	rndrChan := make(chan error)
-	for _, rTxn := range rendererTxns {
+	for idx, rTxn := range rendererTxns {
 		if pct.configurator.parallelRendering {
-			go func(txn renderer.Txn) {
-				err := txn.Commit()
+			go func(idx int, txn renderer.Txn) {
+				err := commitRendererTxn(tr, idx, txn)
 				rndrChan <- err
-			}(rTxn)
+			}(idx, rTxn)
 		} else {
-			err := rTxn.Commit()
+			err := commitRendererTxn(tr, idx, rTxn)
 			if err != nil {
 				wasError = err
 			}
@@ -234,18 +287,52 @@ func (pct *PolicyConfiguratorTxn) Commit() error {
 
 	// Save changes to the configurator.
 	pct.configurator.podIPAddresses = pct.podIPAddresses.Copy()
+	if pct.resync {
+		pct.configurator.podPolicies = make(map[podmodel.ID]ContivPolicies)
+	}
+	for pod, policies := range pct.config {
+		if _, configured := pct.podIPAddresses[pod]; configured && len(policies) > 0 {
+			pct.configurator.podPolicies[pod] = policies.Copy()
+		} else {
+			delete(pct.configurator.podPolicies, pod)
+		}
+	}
 
 	return wasError
 }
 
+// commitRendererTxn commits the transaction of the renderer with the given index, the duration
+// of the commit is recorded into the trace.
+func commitRendererTxn(tr trace.Trace, idx int, txn renderer.Txn) error {
+	start := time.Now()
+	err := txn.Commit()
+	if err != nil {
+		tr.LazyPrintf("renderer %d failed after %v: %v", idx, time.Since(start), err)
+		tr.SetError()
+		return err
+	}
+	tr.LazyPrintf("renderer %d committed in %v", idx, time.Since(start))
+	return nil
+}
+
 // PeerPod represents the opposite pod in the policy rule.
 type PeerPod struct {
 	ID    podmodel.ID
 	IPNet *net.IPNet
 }
 
+// isAudited returns true if policies of the given pod are rendered in the audit mode.
+func (pc *PolicyConfigurator) isAudited(pod podmodel.ID) bool {
+	if pc.AuditMode {
+		return true
+	}
+	_, audited := pc.AuditNamespaces[pod.Namespace]
+	return audited
+}
+
 // Generate a list of ingress or egress rules implementing a given list of policies.
-func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies ContivPolicies) ContivRules {
+// In the audit mode, the traffic not matched by the policies is permitted instead of denied.
+func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies ContivPolicies, audit bool) ContivRules {
 	rules := ContivRules{}
 	hasPolicy := false
 	allAllowed := false
@@ -264,6 +351,9 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 			}
 
 			// Collect IP addresses of all pod peers.
+			// Peers are not rendered individually, their addresses are merged
+			// with the subnets of IP blocks into the minimal set of subnets
+			// (pods of the same node typically have contiguous addresses).
 			peers := []PeerPod{}
 			for _, peer := range match.Pods {
 				found, peerData := pct.configurator.Cache.LookupPod(peer)
@@ -285,8 +375,11 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 				peers = append(peers, PeerPod{ID: peer, IPNet: peerIPNet})
 			}
 
-			// Collect all subnets from IPBlocks.
+			// Collect all subnets from IPBlocks (and pod peers).
 			allSubnets := []*net.IPNet{}
+			for _, peer := range peers {
+				allSubnets = append(allSubnets, peer.IPNet)
+			}
 			for _, block := range match.IPBlocks {
 				subnets := []*net.IPNet{&block.Network}
 				for _, except := range block.Except {
@@ -298,6 +391,7 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 				}
 				allSubnets = append(allSubnets, subnets...)
 			}
+			allSubnets = mergeSubnets(allSubnets)
 
 			// Handle undefined set of pods and IP blocks.
 			// = match anything on L3
@@ -305,7 +399,7 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 				if len(match.Ports) == 0 {
 					// = match anything on L3 & L4
 					ruleTCPAny := &renderer.ContivRule{
-						ID:          policy.ID.String() + "-TCP:ANY",
+						ID:          policy.ID.String() + renderer.PolicyRuleSeparator + "TCP:ANY",
 						Action:      renderer.ActionPermit,
 						SrcNetwork:  &net.IPNet{},
 						DestNetwork: &net.IPNet{},
@@ -314,7 +408,7 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 						DestPort:    0,
 					}
 					ruleUDPAny := &renderer.ContivRule{
-						ID:          policy.ID.String() + "-UDP:ANY",
+						ID:          policy.ID.String() + renderer.PolicyRuleSeparator + "UDP:ANY",
 						Action:      renderer.ActionPermit,
 						SrcNetwork:  &net.IPNet{},
 						DestNetwork: &net.IPNet{},
@@ -322,36 +416,41 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 						SrcPort:     0,
 						DestPort:    0,
 					}
-					rules = pct.appendRules(rules, ruleTCPAny, ruleUDPAny)
+					ruleSCTPAny := &renderer.ContivRule{
+						ID:          policy.ID.String() + renderer.PolicyRuleSeparator + "SCTP:ANY",
+						Action:      renderer.ActionPermit,
+						SrcNetwork:  &net.IPNet{},
+						DestNetwork: &net.IPNet{},
+						Protocol:    renderer.SCTP,
+						SrcPort:     0,
+						DestPort:    0,
+					}
+					rules = pct.appendRules(rules, ruleTCPAny, ruleUDPAny, ruleSCTPAny)
 					allAllowed = true
 				} else {
 					// = match by L4
 					for _, port := range match.Ports {
 						rule := &renderer.ContivRule{
-							ID:          policy.ID.String() + "-" + port.String(),
+							ID:          policy.ID.String() + renderer.PolicyRuleSeparator + port.String(),
 							Action:      renderer.ActionPermit,
 							SrcNetwork:  &net.IPNet{},
 							DestNetwork: &net.IPNet{},
 							SrcPort:     0,
 							DestPort:    port.Number,
 						}
-						if port.Protocol == TCP {
-							rule.Protocol = renderer.TCP
-						} else {
-							rule.Protocol = renderer.UDP
-						}
+						rule.Protocol = port.Protocol.rendererProtocol()
 						rules = pct.appendRules(rules, rule)
 					}
 				}
 			}
 
-			// Combine pod peers with ports.
-			for _, peer := range peers {
+			// Combine subnets with ports.
+			for _, subnet := range allSubnets {
 				if len(match.Ports) == 0 {
-					// Match all ports.
+					// Handle subnet with no ports.
 					// = match by L3
 					ruleTCPAny := &renderer.ContivRule{
-						ID:          policy.ID.String() + "-" + peer.ID.String() + "-TCP:ANY",
+						ID:          policy.ID.String() + renderer.PolicyRuleSeparator + subnet.String() + "-TCP:ANY",
 						Action:      renderer.ActionPermit,
 						Protocol:    renderer.TCP,
 						SrcNetwork:  &net.IPNet{},
@@ -360,7 +459,7 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 						DestPort:    0,
 					}
 					ruleUDPAny := &renderer.ContivRule{
-						ID:          policy.ID.String() + "-" + peer.ID.String() + "-UDP:ANY",
+						ID:          policy.ID.String() + renderer.PolicyRuleSeparator + subnet.String() + "-UDP:ANY",
 						Action:      renderer.ActionPermit,
 						Protocol:    renderer.UDP,
 						SrcNetwork:  &net.IPNet{},
@@ -368,59 +467,10 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 						SrcPort:     0,
 						DestPort:    0,
 					}
-					if direction == MatchIngress {
-						ruleTCPAny.SrcNetwork = peer.IPNet
-						ruleUDPAny.SrcNetwork = peer.IPNet
-					} else {
-						ruleTCPAny.DestNetwork = peer.IPNet
-						ruleUDPAny.DestNetwork = peer.IPNet
-					}
-					rules = pct.appendRules(rules, ruleTCPAny, ruleUDPAny)
-				} else {
-					// Combine each port with the peer.
-					// = match by L3 & L4
-					for _, port := range match.Ports {
-						rule := &renderer.ContivRule{
-							ID:          policy.ID.String() + "-" + peer.ID.String() + "-" + port.String(),
-							Action:      renderer.ActionPermit,
-							SrcNetwork:  &net.IPNet{},
-							DestNetwork: &net.IPNet{},
-							SrcPort:     0,
-							DestPort:    port.Number,
-						}
-						if direction == MatchIngress {
-							rule.SrcNetwork = peer.IPNet
-						} else {
-							rule.DestNetwork = peer.IPNet
-						}
-						if port.Protocol == TCP {
-							rule.Protocol = renderer.TCP
-						} else {
-							rule.Protocol = renderer.UDP
-						}
-						rules = pct.appendRules(rules, rule)
-					}
-				}
-			}
-
-			// Combine IPBlocks with ports.
-			for _, subnet := range allSubnets {
-				if len(match.Ports) == 0 {
-					// Handle IPBlock with no ports.
-					// = match by L3
-					ruleTCPAny := &renderer.ContivRule{
-						ID:          policy.ID.String() + "-" + subnet.String() + "-TCP:ANY",
-						Action:      renderer.ActionPermit,
-						Protocol:    renderer.TCP,
-						SrcNetwork:  &net.IPNet{},
-						DestNetwork: &net.IPNet{},
-						SrcPort:     0,
-						DestPort:    0,
-					}
-					ruleUDPAny := &renderer.ContivRule{
-						ID:          policy.ID.String() + "-" + subnet.String() + "-UDP:ANY",
+					ruleSCTPAny := &renderer.ContivRule{
+						ID:          policy.ID.String() + renderer.PolicyRuleSeparator + subnet.String() + "-SCTP:ANY",
 						Action:      renderer.ActionPermit,
-						Protocol:    renderer.UDP,
+						Protocol:    renderer.SCTP,
 						SrcNetwork:  &net.IPNet{},
 						DestNetwork: &net.IPNet{},
 						SrcPort:     0,
@@ -429,17 +479,19 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 					if direction == MatchIngress {
 						ruleTCPAny.SrcNetwork = subnet
 						ruleUDPAny.SrcNetwork = subnet
+						ruleSCTPAny.SrcNetwork = subnet
 					} else {
 						ruleTCPAny.DestNetwork = subnet
 						ruleUDPAny.DestNetwork = subnet
+						ruleSCTPAny.DestNetwork = subnet
 					}
-					rules = pct.appendRules(rules, ruleTCPAny, ruleUDPAny)
+					rules = pct.appendRules(rules, ruleTCPAny, ruleUDPAny, ruleSCTPAny)
 				} else {
-					// Combine each port with the block.
+					// Combine each port with the subnet.
 					// = match by L3 & L4
 					for _, port := range match.Ports {
 						rule := &renderer.ContivRule{
-							ID:          policy.ID.String() + "-" + subnet.String() + "-" + port.String(),
+							ID:          policy.ID.String() + renderer.PolicyRuleSeparator + subnet.String() + "-" + port.String(),
 							Action:      renderer.ActionPermit,
 							SrcNetwork:  &net.IPNet{},
 							DestNetwork: &net.IPNet{},
@@ -451,11 +503,7 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 						} else {
 							rule.DestNetwork = subnet
 						}
-						if port.Protocol == TCP {
-							rule.Protocol = renderer.TCP
-						} else {
-							rule.Protocol = renderer.UDP
-						}
+						rule.Protocol = port.Protocol.rendererProtocol()
 						rules = pct.appendRules(rules, rule)
 					}
 				}
@@ -483,16 +531,33 @@ func (pct *PolicyConfiguratorTxn) generateRules(direction MatchType, policies Co
 			SrcPort:     0,
 			DestPort:    0,
 		}
-		rules = pct.appendRules(rules, ruleTCPNone, ruleUDPNone)
+		ruleSCTPNone := &renderer.ContivRule{
+			ID:          "SCTP:NONE",
+			Action:      renderer.ActionDeny,
+			SrcNetwork:  &net.IPNet{},
+			DestNetwork: &net.IPNet{},
+			Protocol:    renderer.SCTP,
+			SrcPort:     0,
+			DestPort:    0,
+		}
+		if audit {
+			for _, rule := range []*renderer.ContivRule{ruleTCPNone, ruleUDPNone, ruleSCTPNone} {
+				rule.ID = rule.Protocol.String() + renderer.AuditRuleSuffix
+				rule.Action = renderer.ActionPermit
+			}
+		}
+		rules = pct.appendRules(rules, ruleTCPNone, ruleUDPNone, ruleSCTPNone)
 	}
 
 	return rules
 }
 
 // Append rule into the list if it is not there already.
+// Rules matching the same traffic as a rule already in the list (e.g. generated
+// for different policies) are skipped as well.
 func (pct *PolicyConfiguratorTxn) appendRule(rules []*renderer.ContivRule, newRule *renderer.ContivRule) []*renderer.ContivRule {
 	for _, rule := range rules {
-		if rule.ID == newRule.ID {
+		if rule.ID == newRule.ID || sameTraffic(rule, newRule) {
 			pct.Log.WithField("rule", newRule).Debug("Skipping duplicate rule")
 			return rules
 		}
@@ -500,6 +565,16 @@ func (pct *PolicyConfiguratorTxn) appendRule(rules []*renderer.ContivRule, newRu
 	return append(rules, newRule)
 }
 
+// sameTraffic returns true if both rules match the same traffic with the same action.
+func sameTraffic(rule1, rule2 *renderer.ContivRule) bool {
+	return rule1.Action == rule2.Action &&
+		rule1.Protocol == rule2.Protocol &&
+		rule1.SrcPort == rule2.SrcPort &&
+		rule1.DestPort == rule2.DestPort &&
+		utils.CompareIPNets(rule1.SrcNetwork, rule2.SrcNetwork) == 0 &&
+		utils.CompareIPNets(rule1.DestNetwork, rule2.DestNetwork) == 0
+}
+
 // Append rules into the list. Skip those which are already there.
 func (pct *PolicyConfiguratorTxn) appendRules(rules []*renderer.ContivRule, newRules ...*renderer.ContivRule) []*renderer.ContivRule {
 	for _, newRule := range newRules {
@@ -604,3 +679,63 @@ func subtractSubnet(net1, net2 *net.IPNet) []*net.IPNet {
 
 	return result
 }
+
+// Function returns the minimal ordered list of non-overlapping subnets covering
+// exactly the same IPs as the given (possibly overlapping) subnets.
+func mergeSubnets(subnets []*net.IPNet) []*net.IPNet {
+	// Normalize subnets and order them by the network address, larger first.
+	normalized := []*net.IPNet{}
+	for _, subnet := range subnets {
+		ip := subnet.IP
+		if ip4 := ip.To4(); ip4 != nil && len(subnet.Mask) == net.IPv4len {
+			ip = ip4
+		}
+		normalized = append(normalized, &net.IPNet{IP: ip.Mask(subnet.Mask), Mask: subnet.Mask})
+	}
+	sort.Slice(normalized, func(i, j int) bool {
+		if len(normalized[i].IP) != len(normalized[j].IP) {
+			return len(normalized[i].IP) < len(normalized[j].IP)
+		}
+		if cmp := bytes.Compare(normalized[i].IP, normalized[j].IP); cmp != 0 {
+			return cmp < 0
+		}
+		iMaskSize, _ := normalized[i].Mask.Size()
+		jMaskSize, _ := normalized[j].Mask.Size()
+		return iMaskSize < jMaskSize
+	})
+
+	// Remove subnets contained in other subnets.
+	result := []*net.IPNet{}
+	for _, subnet := range normalized {
+		if len(result) > 0 {
+			last := result[len(result)-1]
+			if len(last.IP) == len(subnet.IP) && last.Contains(subnet.IP) {
+				continue
+			}
+		}
+		result = append(result, subnet)
+	}
+
+	// Repeatedly merge pairs of sibling subnets into their parent.
+	for merged := true; merged; {
+		merged = false
+		for idx := 0; idx < len(result)-1; idx++ {
+			net1, net2 := result[idx], result[idx+1]
+			maskSize, bits := net1.Mask.Size()
+			if maskSize == 0 || len(net1.IP) != len(net2.IP) {
+				continue
+			}
+			if maskSize2, _ := net2.Mask.Size(); maskSize2 != maskSize {
+				continue
+			}
+			parent := &net.IPNet{Mask: net.CIDRMask(maskSize-1, bits)}
+			parent.IP = net1.IP.Mask(parent.Mask)
+			if !parent.IP.Equal(net1.IP) || !parent.Contains(net2.IP) {
+				continue
+			}
+			result = append(append(result[:idx], parent), result[idx+2:]...)
+			merged = true
+		}
+	}
+	return result
+}
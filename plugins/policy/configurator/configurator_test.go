@@ -140,6 +140,97 @@ func TestSinglePolicySinglePod(t *testing.T) {
 	gomega.Expect(action).To(gomega.BeEquivalentTo(DeniedTraffic))
 }
 
+func TestSCTPPolicySinglePod(t *testing.T) {
+	gomega.RegisterTestingT(t)
+	logger := logrus.DefaultLogger()
+	logger.SetLevel(logging.DebugLevel)
+	logger.Debug("TestSCTPPolicySinglePod")
+
+	// Prepare input data.
+	const (
+		namespace = "default"
+		pod1Name  = "pod1"
+		pod2Name  = "pod2"
+		pod1IP    = "192.168.1.1"
+		pod2IP    = "192.168.1.2"
+	)
+	pod1 := podmodel.ID{Name: pod1Name, Namespace: namespace}
+	pod2 := podmodel.ID{Name: pod2Name, Namespace: namespace}
+
+	policy1 := &ContivPolicy{
+		ID:   policymodel.ID{Name: "policy1", Namespace: namespace},
+		Type: PolicyIngress,
+		Matches: []Match{
+			{
+				Type: MatchIngress,
+				Pods: []podmodel.ID{
+					pod2,
+				},
+				Ports: []Port{
+					{Protocol: SCTP, Number: 36412},
+				},
+			},
+			{
+				Type: MatchIngress,
+				IPBlocks: []IPBlock{
+					{
+						Network: parseIPNet("10.0.0.0/8"),
+					},
+				},
+			},
+		},
+	}
+	pod1Policies := []*ContivPolicy{policy1}
+
+	// Initialize mocks.
+	cache := NewMockPolicyCache()
+	cache.AddPodConfig(pod1, pod1IP)
+	cache.AddPodConfig(pod2, pod2IP)
+
+	renderer := NewMockRenderer("A", logger)
+
+	// Initialize configurator.
+	configurator := &PolicyConfigurator{
+		Deps: Deps{
+			Log:   logger,
+			Cache: cache,
+		},
+	}
+	configurator.Init(false)
+
+	// Register one renderer.
+	err := configurator.RegisterRenderer(renderer)
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// Run single transaction.
+	txn := configurator.NewTxn(false)
+	txn.Configure(pod1, pod1Policies)
+	err = txn.Commit()
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// Test with fake traffic.
+
+	// Allowed by policy1.
+	action := renderer.TestTraffic(pod1, EgressTraffic,
+		parseIP(pod2IP), parseIP(pod1IP), rendererAPI.SCTP, 123, 36412)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(AllowedTraffic))
+
+	// Allowed by policy1 - all protocols and ports allowed from the IP block.
+	action = renderer.TestTraffic(pod1, EgressTraffic,
+		parseIP("10.1.1.1"), parseIP(pod1IP), rendererAPI.SCTP, 123, 38412)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(AllowedTraffic))
+
+	// Blocked by policy1 - SCTP:38412 not allowed.
+	action = renderer.TestTraffic(pod1, EgressTraffic,
+		parseIP(pod2IP), parseIP(pod1IP), rendererAPI.SCTP, 123, 38412)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(DeniedTraffic))
+
+	// Blocked by policy1 - TCP not allowed.
+	action = renderer.TestTraffic(pod1, EgressTraffic,
+		parseIP(pod2IP), parseIP(pod1IP), rendererAPI.TCP, 123, 36412)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(DeniedTraffic))
+}
+
 func TestSinglePolicyWithIPBlockSinglePod(t *testing.T) {
 	gomega.RegisterTestingT(t)
 	logger := logrus.DefaultLogger()
@@ -1465,3 +1556,380 @@ func TestMultiplePodsSpecialCases(t *testing.T) {
 		parseIP("10.5.10.10"), parseIP(pod3IP), rendererAPI.TCP, 123, 9000)
 	gomega.Expect(action).To(gomega.BeEquivalentTo(DeniedTraffic))
 }
+
+func TestMergeSubnets(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	subnetStrings := func(subnets []*net.IPNet) []string {
+		strs := []string{}
+		for _, subnet := range subnets {
+			strs = append(strs, subnet.String())
+		}
+		return strs
+	}
+	subnets := func(addrs ...string) []*net.IPNet {
+		result := []*net.IPNet{}
+		for _, addr := range addrs {
+			subnet := parseIPNet(addr)
+			result = append(result, &subnet)
+		}
+		return result
+	}
+
+	// contained and duplicate subnets are removed
+	merged := mergeSubnets(subnets("10.10.1.0/24", "10.10.0.0/16", "10.10.0.0/16", "192.168.0.0/24"))
+	gomega.Expect(subnetStrings(merged)).To(gomega.Equal([]string{"10.10.0.0/16", "192.168.0.0/24"}))
+
+	// siblings are merged into their parent, recursively
+	merged = mergeSubnets(subnets("10.0.3.0/24", "10.0.0.0/24", "10.0.2.0/23", "10.0.1.0/24"))
+	gomega.Expect(subnetStrings(merged)).To(gomega.Equal([]string{"10.0.0.0/22"}))
+
+	// CIDR with exceptions subtracted and re-merged with a block covering the exceptions
+	cidr := parseIPNet("10.0.0.0/16")
+	except := parseIPNet("10.0.10.0/24")
+	remainder := subtractSubnet(&cidr, &except)
+	gomega.Expect(remainder).To(gomega.HaveLen(8))
+	merged = mergeSubnets(append(remainder, subnets("10.0.10.0/24")...))
+	gomega.Expect(subnetStrings(merged)).To(gomega.Equal([]string{"10.0.0.0/16"}))
+
+	// non-adjacent subnets of the same size are left unchanged
+	merged = mergeSubnets(subnets("10.0.1.0/24", "10.0.2.0/24"))
+	gomega.Expect(subnetStrings(merged)).To(gomega.Equal([]string{"10.0.1.0/24", "10.0.2.0/24"}))
+}
+
+func TestSinglePolicyAuditedNamespace(t *testing.T) {
+	gomega.RegisterTestingT(t)
+	logger := logrus.DefaultLogger()
+	logger.SetLevel(logging.DebugLevel)
+	logger.Debug("TestSinglePolicyAuditedNamespace")
+
+	// Prepare input data.
+	const (
+		namespace1 = "default"
+		namespace2 = "audited"
+		pod1Name   = "pod1"
+		pod2Name   = "pod2"
+		pod3Name   = "pod3"
+		pod1IP     = "192.168.1.1"
+		pod2IP     = "192.168.1.2"
+		pod3IP     = "192.168.1.3"
+	)
+	pod1 := podmodel.ID{Name: pod1Name, Namespace: namespace1}
+	pod2 := podmodel.ID{Name: pod2Name, Namespace: namespace1}
+	pod3 := podmodel.ID{Name: pod3Name, Namespace: namespace2}
+
+	policy1 := &ContivPolicy{
+		ID:   policymodel.ID{Name: "policy1", Namespace: namespace1},
+		Type: PolicyIngress,
+		Matches: []Match{
+			{
+				Type: MatchIngress,
+				Pods: []podmodel.ID{
+					pod2,
+				},
+				Ports: []Port{
+					{Protocol: TCP, Number: 80},
+				},
+			},
+		},
+	}
+	policies := []*ContivPolicy{policy1}
+
+	// Initialize mocks.
+	cache := NewMockPolicyCache()
+	cache.AddPodConfig(pod1, pod1IP)
+	cache.AddPodConfig(pod2, pod2IP)
+	cache.AddPodConfig(pod3, pod3IP)
+
+	renderer := NewMockRenderer("A", logger)
+
+	// Initialize configurator.
+	configurator := &PolicyConfigurator{
+		Deps: Deps{
+			Log:             logger,
+			Cache:           cache,
+			AuditNamespaces: map[string]struct{}{namespace2: {}},
+		},
+	}
+	configurator.Init(false)
+
+	// Register one renderer.
+	err := configurator.RegisterRenderer(renderer)
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// Run single transaction with the same policies for enforced and audited pod.
+	txn := configurator.NewTxn(false)
+
+	txn.Configure(pod1, policies)
+	txn.Configure(pod3, policies)
+
+	err = txn.Commit()
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// Test with fake traffic.
+
+	// Allowed by policy1 for both pods.
+	action := renderer.TestTraffic(pod1, EgressTraffic,
+		parseIP(pod2IP), parseIP(pod1IP), rendererAPI.TCP, 123, 80)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(AllowedTraffic))
+	action = renderer.TestTraffic(pod3, EgressTraffic,
+		parseIP(pod2IP), parseIP(pod3IP), rendererAPI.TCP, 123, 80)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(AllowedTraffic))
+
+	// Blocked by policy1 for the enforced pod - TCP:100 not allowed.
+	action = renderer.TestTraffic(pod1, EgressTraffic,
+		parseIP(pod2IP), parseIP(pod1IP), rendererAPI.TCP, 789, 100)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(DeniedTraffic))
+
+	// Only audited for the pod from the audited namespace.
+	action = renderer.TestTraffic(pod3, EgressTraffic,
+		parseIP(pod2IP), parseIP(pod3IP), rendererAPI.TCP, 789, 100)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(AllowedTraffic))
+	action = renderer.TestTraffic(pod3, EgressTraffic,
+		parseIP(pod1IP), parseIP(pod3IP), rendererAPI.UDP, 123, 80)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(AllowedTraffic))
+}
+
+func TestRulesMappedToPolicies(t *testing.T) {
+	gomega.RegisterTestingT(t)
+	logger := logrus.DefaultLogger()
+	logger.SetLevel(logging.DebugLevel)
+	logger.Debug("TestRulesMappedToPolicies")
+
+	// Prepare input data.
+	const (
+		namespace = "default"
+		pod1Name  = "pod1"
+		pod2Name  = "pod2"
+		pod1IP    = "192.168.1.1"
+		pod2IP    = "192.168.1.2"
+	)
+	pod1 := podmodel.ID{Name: pod1Name, Namespace: namespace}
+	pod2 := podmodel.ID{Name: pod2Name, Namespace: namespace}
+
+	policy1 := &ContivPolicy{
+		ID:   policymodel.ID{Name: "allow-web-from-pod2", Namespace: namespace},
+		Type: PolicyIngress,
+		Matches: []Match{
+			{
+				Type: MatchIngress,
+				Pods: []podmodel.ID{
+					pod2,
+				},
+				Ports: []Port{
+					{Protocol: TCP, Number: 80},
+				},
+			},
+		},
+	}
+	pod1Policies := []*ContivPolicy{policy1}
+
+	// Initialize mocks.
+	cache := NewMockPolicyCache()
+	cache.AddPodConfig(pod1, pod1IP)
+	cache.AddPodConfig(pod2, pod2IP)
+
+	// Initialize configurator.
+	configurator := &PolicyConfigurator{
+		Deps: Deps{
+			Log:   logger,
+			Cache: cache,
+		},
+	}
+	configurator.Init(false)
+
+	// Generate rules.
+	txn := configurator.NewTxn(false).(*PolicyConfiguratorTxn)
+	rules := txn.generateRules(MatchIngress, pod1Policies, false)
+	gomega.Expect(rules).To(gomega.HaveLen(4))
+
+	// Permit rule is mapped to the policy.
+	policyID, fromPolicy := rules[0].PolicyID()
+	gomega.Expect(fromPolicy).To(gomega.BeTrue())
+	gomega.Expect(policyID).To(gomega.BeEquivalentTo(policy1.ID))
+
+	// Deny-the-rest rules do not originate from a single policy.
+	for _, rule := range rules[1:] {
+		gomega.Expect(rule.Action).To(gomega.BeEquivalentTo(rendererAPI.ActionDeny))
+		_, fromPolicy = rule.PolicyID()
+		gomega.Expect(fromPolicy).To(gomega.BeFalse())
+	}
+
+	// Committed policies are remembered for each pod.
+	txn.Configure(pod1, pod1Policies)
+	txn.Configure(pod2, []*ContivPolicy{})
+	err := txn.Commit()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(configurator.GetPodPolicies(pod1)).To(gomega.HaveLen(1))
+	gomega.Expect(configurator.GetPodPolicies(pod1)[0].ID).To(gomega.BeEquivalentTo(policy1.ID))
+	gomega.Expect(configurator.GetPodPolicies(pod2)).To(gomega.BeEmpty())
+}
+
+func TestRulesCompaction(t *testing.T) {
+	gomega.RegisterTestingT(t)
+	logger := logrus.DefaultLogger()
+	logger.SetLevel(logging.DebugLevel)
+	logger.Debug("TestRulesCompaction")
+
+	// Prepare input data.
+	const (
+		namespace = "default"
+		pod1Name  = "pod1"
+		pod2Name  = "pod2"
+		pod3Name  = "pod3"
+		pod1IP    = "192.168.1.1"
+		pod2IP    = "192.168.1.2"
+		pod3IP    = "192.168.1.3"
+	)
+	pod1 := podmodel.ID{Name: pod1Name, Namespace: namespace}
+	pod2 := podmodel.ID{Name: pod2Name, Namespace: namespace}
+	pod3 := podmodel.ID{Name: pod3Name, Namespace: namespace}
+
+	// Two policies allowing the same traffic, one via pod selector, the other via IP block.
+	policy1 := &ContivPolicy{
+		ID:   policymodel.ID{Name: "policy1", Namespace: namespace},
+		Type: PolicyIngress,
+		Matches: []Match{
+			{
+				Type: MatchIngress,
+				Pods: []podmodel.ID{
+					pod2, pod3,
+				},
+				Ports: []Port{
+					{Protocol: TCP, Number: 80},
+				},
+			},
+		},
+	}
+	policy2 := &ContivPolicy{
+		ID:   policymodel.ID{Name: "policy2", Namespace: namespace},
+		Type: PolicyIngress,
+		Matches: []Match{
+			{
+				Type: MatchIngress,
+				IPBlocks: []IPBlock{
+					{Network: parseIPNet("192.168.1.2/31")},
+				},
+				Ports: []Port{
+					{Protocol: TCP, Number: 80},
+				},
+			},
+		},
+	}
+	pod1Policies := ContivPolicies{policy1, policy2}
+
+	// Initialize mocks.
+	cache := NewMockPolicyCache()
+	cache.AddPodConfig(pod1, pod1IP)
+	cache.AddPodConfig(pod2, pod2IP)
+	cache.AddPodConfig(pod3, pod3IP)
+
+	// Initialize configurator.
+	configurator := &PolicyConfigurator{
+		Deps: Deps{
+			Log:   logger,
+			Cache: cache,
+		},
+	}
+	configurator.Init(false)
+
+	// Generate rules.
+	txn := configurator.NewTxn(false).(*PolicyConfiguratorTxn)
+	rules := txn.generateRules(MatchIngress, pod1Policies, false)
+
+	// Peers are merged into one subnet, the rule of policy2 is a duplicate.
+	gomega.Expect(rules).To(gomega.HaveLen(4))
+	peersSubnet := parseIPNet("192.168.1.2/31")
+	gomega.Expect(rules[0].Action).To(gomega.BeEquivalentTo(rendererAPI.ActionPermit))
+	gomega.Expect(rules[0].SrcNetwork.String()).To(gomega.Equal(peersSubnet.String()))
+	gomega.Expect(rules[0].Protocol).To(gomega.BeEquivalentTo(rendererAPI.TCP))
+	gomega.Expect(rules[0].DestPort).To(gomega.BeEquivalentTo(80))
+	policyID, _ := rules[0].PolicyID()
+	gomega.Expect(policyID).To(gomega.BeEquivalentTo(policy1.ID))
+}
+
+func TestHostEndpoint(t *testing.T) {
+	gomega.RegisterTestingT(t)
+	logger := logrus.DefaultLogger()
+	logger.SetLevel(logging.DebugLevel)
+	logger.Debug("TestHostEndpoint")
+
+	// Prepare input data.
+	const (
+		hostIP = "10.20.0.2"
+		peerIP = "10.20.0.1"
+		podIP  = "192.168.1.1"
+	)
+	host := rendererAPI.HostEndpoint
+
+	policy1 := &ContivPolicy{
+		ID:   policymodel.ID{Name: "allow-ssh"},
+		Type: PolicyIngress,
+		Matches: []Match{
+			{
+				Type: MatchIngress,
+				IPBlocks: []IPBlock{
+					{Network: parseIPNet("10.20.0.0/24")},
+				},
+				Ports: []Port{
+					{Protocol: TCP, Number: 22},
+				},
+			},
+		},
+	}
+
+	// Initialize mocks (host endpoint is not in the cache).
+	cache := NewMockPolicyCache()
+	renderer := NewMockRenderer("A", logger)
+
+	// Initialize configurator.
+	configurator := &PolicyConfigurator{
+		Deps: Deps{
+			Log:   logger,
+			Cache: cache,
+		},
+	}
+	configurator.Init(false)
+
+	// Register one renderer.
+	err := configurator.RegisterRenderer(renderer)
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// Host endpoint without policies is not configured.
+	txn := configurator.NewTxn(false)
+	txn.Configure(host, []*ContivPolicy{})
+	err = txn.Commit()
+	gomega.Expect(err).To(gomega.BeNil())
+	action := renderer.TestTraffic(host, EgressTraffic,
+		parseIP(podIP), parseIP(hostIP), rendererAPI.TCP, 123, 80)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(UnmatchedTraffic))
+
+	// Apply policy to the host endpoint.
+	txn = configurator.NewTxn(false)
+	txn.Configure(host, []*ContivPolicy{policy1})
+	err = txn.Commit()
+	gomega.Expect(err).To(gomega.BeNil())
+	ip, _ := renderer.GetPodIP(host)
+	gomega.Expect(ip).To(gomega.BeEmpty())
+	gomega.Expect(configurator.GetPodPolicies(host)).To(gomega.HaveLen(1))
+
+	// Allowed by policy1.
+	action = renderer.TestTraffic(host, EgressTraffic,
+		parseIP(peerIP), parseIP(hostIP), rendererAPI.TCP, 123, 22)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(AllowedTraffic))
+
+	// Not allowed by policy1.
+	action = renderer.TestTraffic(host, EgressTraffic,
+		parseIP(podIP), parseIP(hostIP), rendererAPI.TCP, 123, 22)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(DeniedTraffic))
+
+	// Remove policies from the host endpoint.
+	txn = configurator.NewTxn(false)
+	txn.Configure(host, []*ContivPolicy{})
+	err = txn.Commit()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(configurator.GetPodPolicies(host)).To(gomega.BeEmpty())
+	action = renderer.TestTraffic(host, EgressTraffic,
+		parseIP(podIP), parseIP(hostIP), rendererAPI.TCP, 123, 22)
+	gomega.Expect(action).To(gomega.BeEquivalentTo(UnmatchedTraffic))
+}
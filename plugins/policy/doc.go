@@ -37,6 +37,10 @@
 //     - watches ETCD for changes written by KSR
 //     - propagates datasync events into the Policy Cache without any processing
 //     - postpones RESYNC until the Contiv plugin has finalized its RESYNC
+//     - publishes the status of every policy selecting some pods of the node
+//       (number of the pods, rendering error) into ETCD, from where KSR
+//       reflects it into the "contivpp.io/policy-status" annotation
+//       of the K8s NetworkPolicy
 //
 //  2. Policy Processor
 //     - implements the PolicyCacheWatcher interface
@@ -53,6 +57,13 @@
 //           * changed/added/removed port names
 //           * changed policy in any way
 //           * pod migrated between hosts
+//        - only pods affected by the change are re-configured: pods selected
+//          by the policies that either apply to the changed pod or select
+//          it as a peer (or a definer of named ports); pod updates that change
+//          neither IP address, labels nor container ports (e.g. status updates)
+//          and policy updates with the same content are ignored
+//        - renderers then re-program only the rule tables whose content
+//          has changed
 //     - handles pod migration
 //        - learns IP subnet assigned to the node from the Contiv plugin
 //        - unlike Policy Configurator, the processor is aware of the
@@ -63,14 +74,39 @@
 //        - converts K8s Network Policies into less-abstract ContivPolicy type
 //          used by the PolicyConfigurator
 //           * evaluates Label Selectors
-//           * translates port names into numbers
+//           * translates port names into numbers using the container ports
+//             of the pods selected by the policy (ingress) or of the destination
+//             pods (egress); a name may resolve to several numbers
 //           * expands namespaces into pods
+//           * resolves the traffic directions the policy applies to: without
+//             policyTypes, ingress always and egress only if the policy has
+//             egress rules (the same as defined by K8s)
+//     - cluster-wide CustomNetworkPolicies (contivpp.io/v1, reflected by KSR)
+//       are applied to the host endpoint of the node (renderer.HostEndpoint,
+//       the host stack connected with VPP via the host interconnect) if they
+//       select the node by its labels; peers are IP blocks or DNS names and
+//       ports are numeric; the host endpoint is re-processed on RESYNC, on any
+//       change of custom policies and when labels of this node change
+//     - DNS names (FQDN peers of custom policies) are resolved by the plugin
+//       every FQDNRefreshInterval using the resolver of the host (DNS responses
+//       are not snooped in VPP and the TTLs of the records are not available),
+//       each resolved address is rendered as a single-address IP block and
+//       expires FQDNAddressTTL after it was last returned; the host endpoint
+//       is re-processed when the address set of a name changes; names not
+//       resolved yet match nothing
+//       TODO: DNS names in pod policies (K8s network policies do not define
+//       such peers)
+//     - the rules HostEndpointIngress/HostEndpointEgress of the plugin
+//       configuration are applied to the host endpoint of every node as one
+//       additional policy
 //
 //  3. Policy Configurator
 //     - for a given pod, translates a set of Contiv Policies into ingress and
 //       egress lists of Contiv Rules (n-tuples with the most basic policy rule
 //       definition; order matters) and applies them into the target vswitch via
 //       registered renderers
+//        - egress rules of the pod (policy point of view) are applied
+//          on the traffic entering the vswitch from the pod and vice versa
 //     - allows to register multiple renderers for different network stacks
 //     - uses the cache and the Contiv plugin to get the IP address and
 //       the interface name associated with a pod, respectively
@@ -79,14 +115,56 @@
 //          details)
 //     - for the best performance, creates a shortest possible sequence of rules
 //       that implement a given policy
+//        - IP blocks (CIDR with exceptions) and IP addresses of peer pods
+//          are translated into the minimal set of non-overlapping subnets,
+//          i.e. contiguous addresses of pods are matched by a single rule
+//        - rules matching the same traffic as a preceding rule (e.g. generated
+//          for another policy) are skipped
+//        - ports are not compacted into ranges: Contiv rules (and the session
+//          rules of the VPP TCP stack) match single ports only
 //     - for the sake of renderers that install rules into per-interface tables
 //       (as opposed to one or more global tables), the configurator ensures
 //       that the same set of policies always results in the same list of rules,
 //       allowing renderers to group and share them across multiple interfaces
 //       (if supported by the destination network stack)
+//     - pods of namespaces listed in the option AuditNamespaces of the plugin
+//       configuration (or all pods with the option AuditMode) have their
+//       policies rendered in the audit (log-only) mode: the closing deny rules
+//       are replaced with permit rules whose IDs end with ":AUDIT"; hit counters
+//       of these rules are read from VPP by the ACL renderer and exposed
+//       as the number of connections that would be denied via REST API
+//       (/contiv/v1/policy-audit) and Prometheus (contiv_policy_audit_violations)
+//     - IDs of rules generated for a single policy start with the policy ID
+//       ("<namespace>/<name>|"), which allows to map hit counters of the rules
+//       read by the ACL renderer back to policies (connections allowed by
+//       multiple policies are attributed to the first one); connections denied
+//       for not being allowed by any policy are attributed to all policies
+//       isolating the pod in the given direction; exposed via REST API
+//       (/contiv/v1/policy-stats) and Prometheus (contiv_policy_rule_hits)
 //
 //  4. Policy Renderer
 //     - applies a list of Contiv Rules into the destination network stack
+//     - in the default-deny bootstrap mode (option DefaultDenyBootstrap),
+//       the plugin watches the container index of the Contiv plugin and the ACL
+//       renderer denies all traffic of every newly connected pod until the rules
+//       of the pod are rendered; the processor then processes every pod of
+//       the node once it gets an IP address, even if no policy applies to it
+//     - the ACL renderer applies the rules of the host endpoint on the host
+//       interconnect interface, the VPPTCP renderer ignores the host endpoint
+//     - SCTP rules are not rendered: the VPP TCP stack does not terminate
+//       SCTP and the ACLs of the VPP agent cannot match protocols other
+//       than TCP, UDP and ICMP; SCTP traffic of pods with policies is denied
+//       by the implicit deny rule of the ACLs
+//     - on RESYNC, both renderers dump the rules installed in VPP into their
+//       caches and apply only the difference against the rendered
+//       configuration; the ACL renderer dumps the ACLs via the binary API
+//       together with their names and interfaces, VPP does not keep the IDs
+//       of the rules, they are therefore taken from the rendered rules
+//       the dumped ACLs are equivalent to
+//     - after the restart of the agent, the ACLs are already removed from VPP
+//       by the RESYNC of the ACL plugin of the VPP agent (it deletes all ACLs
+//       and re-creates only those configured via its own data store), pods
+//       with policies are left without ACLs until their rules are re-rendered
 //
 // Caches
 // -------
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+const (
+	// defaultFQDNRefreshInterval is the default period of resolving DNS names
+	// referenced by policies.
+	defaultFQDNRefreshInterval = 30 * time.Second
+
+	// fqdnLookupTimeout limits the time spent resolving one DNS name.
+	fqdnLookupTimeout = 5 * time.Second
+)
+
+// refreshFQDNs periodically resolves the DNS names referenced by policies
+// and passes the addresses to the policy processor. The names are resolved
+// without holding the resync lock, so that slow DNS does not delay
+// the processing of K8s state changes.
+func (p *Plugin) refreshFQDNs(interval time.Duration) {
+	p.wg.Add(1)
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.resyncLock.Lock()
+			fqdns := p.processor.FQDNs()
+			p.resyncLock.Unlock()
+			if len(fqdns) == 0 {
+				continue
+			}
+
+			resolved := make(map[string][]net.IP)
+			for _, fqdn := range fqdns {
+				addrs, err := p.lookupFQDN(fqdn)
+				if err != nil {
+					p.Log.Warnf("Failed to resolve %s referenced by policies: %v", fqdn, err)
+					continue
+				}
+				resolved[fqdn] = addrs
+			}
+
+			p.resyncLock.Lock()
+			if p.resynced {
+				if err := p.processor.UpdateFQDNs(resolved, time.Now()); err != nil {
+					p.Log.Error(err)
+				}
+			}
+			p.resyncLock.Unlock()
+
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// lookupFQDN resolves the given DNS name using the resolver of the host.
+func (p *Plugin) lookupFQDN(fqdn string) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(p.ctx, fqdnLookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
+	"github.com/contiv/vpp/plugins/policy/configurator"
+)
+
+// hostEndpointPolicyName is the name of the policy built from the host endpoint
+// rules of the plugin configuration.
+const hostEndpointPolicyName = "contiv-host-endpoint"
+
+// hostEndpointConfigPolicy builds Contiv policy from the host endpoint rules
+// of the plugin configuration. Returns nil if no rules are defined.
+func hostEndpointConfigPolicy(config *Config) (*configurator.ContivPolicy, error) {
+	hasIngress := len(config.HostEndpointIngress) > 0
+	hasEgress := len(config.HostEndpointEgress) > 0
+	if !hasIngress && !hasEgress {
+		return nil, nil
+	}
+
+	policy := &configurator.ContivPolicy{
+		ID:      policymodel.ID{Name: hostEndpointPolicyName},
+		Type:    configurator.PolicyAll,
+		Matches: []configurator.Match{},
+	}
+	if !hasEgress {
+		policy.Type = configurator.PolicyIngress
+	} else if !hasIngress {
+		policy.Type = configurator.PolicyEgress
+	}
+
+	for _, rule := range config.HostEndpointIngress {
+		match, err := hostEndpointRuleMatch(configurator.MatchIngress, rule)
+		if err != nil {
+			return nil, err
+		}
+		policy.Matches = append(policy.Matches, match)
+	}
+	for _, rule := range config.HostEndpointEgress {
+		match, err := hostEndpointRuleMatch(configurator.MatchEgress, rule)
+		if err != nil {
+			return nil, err
+		}
+		policy.Matches = append(policy.Matches, match)
+	}
+	return policy, nil
+}
+
+// hostEndpointRuleMatch translates host endpoint rule into Contiv match.
+func hostEndpointRuleMatch(matchType configurator.MatchType, rule HostEndpointRule) (configurator.Match, error) {
+	match := configurator.Match{Type: matchType}
+	if rule.Protocol == "" && rule.Port == 0 {
+		// Not restricted by protocol and port.
+		return hostEndpointRulePeers(match, rule)
+	}
+
+	port := configurator.Port{Number: rule.Port}
+	switch strings.ToUpper(rule.Protocol) {
+	case "", "TCP":
+		port.Protocol = configurator.TCP
+	case "UDP":
+		port.Protocol = configurator.UDP
+	case "SCTP":
+		port.Protocol = configurator.SCTP
+	default:
+		return match, fmt.Errorf("invalid protocol of the host endpoint rule: %s", rule.Protocol)
+	}
+	match.Ports = []configurator.Port{port}
+	return hostEndpointRulePeers(match, rule)
+}
+
+// hostEndpointRulePeers fills the peers of the match from the networks of the rule.
+func hostEndpointRulePeers(match configurator.Match, rule HostEndpointRule) (configurator.Match, error) {
+	if len(rule.Networks) > 0 {
+		match.IPBlocks = []configurator.IPBlock{}
+		for _, network := range rule.Networks {
+			_, ipNet, err := net.ParseCIDR(network)
+			if err != nil {
+				return match, fmt.Errorf("invalid network of the host endpoint rule %s: %v", network, err)
+			}
+			match.IPBlocks = append(match.IPBlocks, configurator.IPBlock{Network: *ipNet, Except: []net.IPNet{}})
+		}
+	}
+	return match, nil
+}
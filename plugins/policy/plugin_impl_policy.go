@@ -17,12 +17,16 @@ package policy
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/ligato/cn-infra/datasync"
 	"github.com/ligato/cn-infra/datasync/resync"
 	"github.com/ligato/cn-infra/flavors/local"
 	"github.com/ligato/cn-infra/logging"
+	prometheusplugin "github.com/ligato/cn-infra/rpc/prometheus"
+	"github.com/ligato/cn-infra/rpc/rest"
 	"github.com/ligato/cn-infra/utils/safeclose"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/ligato/vpp-agent/clientv1/linux"
 	"github.com/ligato/vpp-agent/clientv1/linux/localclient"
@@ -30,28 +34,37 @@ import (
 	"github.com/ligato/vpp-agent/plugins/govppmux"
 
 	"github.com/contiv/vpp/plugins/contiv"
+	"github.com/contiv/vpp/plugins/contiv/containeridx"
+	"github.com/contiv/vpp/plugins/kvstore"
 	"github.com/contiv/vpp/plugins/policy/cache"
 	"github.com/contiv/vpp/plugins/policy/configurator"
 	"github.com/contiv/vpp/plugins/policy/processor"
 	"github.com/contiv/vpp/plugins/policy/renderer/acl"
 	"github.com/contiv/vpp/plugins/policy/renderer/vpptcp"
 
+	"github.com/contiv/vpp/plugins/ksr/model/custompolicy"
 	nsmodel "github.com/contiv/vpp/plugins/ksr/model/namespace"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
 )
 
 // Plugin watches configuration of K8s resources (as reflected by KSR into ETCD)
-// for changes in policies, pods and namespaces and applies rules into extendable
-// set of network stacks.
+// for changes in policies, pods, namespaces, custom policies and nodes and
+// applies rules into extendable set of network stacks.
 type Plugin struct {
 	Deps
 
 	resyncChan chan datasync.ResyncEvent
 	changeChan chan datasync.ChangeEvent
 
+	// changes of the configured containers, watched in the default-deny bootstrap mode
+	containerChan chan containeridx.ChangeEvent
+
 	watchConfigReg datasync.WatchRegistration
 
+	config *Config
+
 	resyncLock sync.Mutex
 	ctx        context.Context
 	cancel     context.CancelFunc
@@ -60,6 +73,7 @@ type Plugin struct {
 	// delay resync until the contiv plugin has been re-synchronized.
 	pendingResync  datasync.ResyncEvent
 	pendingChanges []datasync.ChangeEvent
+	resynced       bool
 
 	// Policy Plugin consists of multiple layers.
 	// The plugin itself is layer 1.
@@ -79,6 +93,65 @@ type Plugin struct {
 	//  -> VPPTCP Renderer
 	vppTCPRenderer *vpptcp.Renderer
 	// New renderers should come here ...
+
+	// Statistics of the rendered rules, read from the ACL hit counters.
+	policyHits      *prometheus.GaugeVec
+	auditViolations *prometheus.GaugeVec
+
+	// statusPublisher is nil if KVStore is not injected.
+	statusPublisher *statusPublisher
+}
+
+// Config holds the configuration of the policy plugin.
+type Config struct {
+	// AuditMode renders policies of all pods in the audit (log-only) mode: the traffic
+	// that would be denied by the policies is permitted, but counted and reported
+	// via REST API and Prometheus, allowing to dry-run new policies before enforcement.
+	AuditMode bool
+
+	// AuditNamespaces lists namespaces whose pods have their policies rendered
+	// in the audit mode (all pods if AuditMode is enabled).
+	AuditNamespaces []string
+
+	// HostEndpointIngress and HostEndpointEgress list rules allowing the traffic
+	// of the host endpoint (the host stack connected with VPP via the host
+	// interconnect) in the given direction, from the host point of view.
+	// The rules apply on every node, together with the CustomNetworkPolicies
+	// selecting the node. The traffic of the host endpoint in a direction with
+	// some rules defined, which is not allowed by any rule or policy, is denied.
+	HostEndpointIngress []HostEndpointRule
+	HostEndpointEgress  []HostEndpointRule
+
+	// DefaultDenyBootstrap enables the default-deny bootstrap mode: all traffic
+	// of the pods connected to this node (including the pods restored after
+	// the restart of the agent) is denied until the policies of the pod are
+	// rendered, closing the window when the traffic is allowed during
+	// the startup of the agent or of the pod. Pods of the kube-system namespace
+	// are not affected.
+	DefaultDenyBootstrap bool
+
+	// FQDNRefreshInterval is the interval (in seconds, 30 by default) of resolving
+	// the DNS names referenced by CustomNetworkPolicies into IP addresses.
+	FQDNRefreshInterval uint32
+
+	// FQDNAddressTTL is the time (in seconds, 300 by default) for which an address
+	// of a DNS name stays allowed after it was last returned by the resolver.
+	FQDNAddressTTL uint32
+}
+
+// HostEndpointRule allows the traffic of the host endpoint with the given peers
+// and port.
+type HostEndpointRule struct {
+	// Protocol is one of TCP, UDP, SCTP (TCP if empty and Port is set).
+	// Rule with neither protocol nor port matches all traffic with the peers.
+	Protocol string
+
+	// Port is the port of the host (ingress) or of the peer (egress),
+	// 0 matches all ports.
+	Port uint16
+
+	// Networks are CIDRs of the peers, empty list matches all peers.
+	Networks []string
 }
 
 // Deps defines dependencies of policy plugin.
@@ -89,6 +162,16 @@ type Deps struct {
 	Contiv  contiv.API                  /* for GetIfName() */
 	VPP     defaultplugins.API          /* for DumpACLs() */
 	GoVPP   govppmux.API                /* for VPPTCP Renderer */
+
+	// Prometheus is optional, used to expose the duration of policy rendering.
+	Prometheus prometheusplugin.API
+
+	// HTTPHandlers is optional, used to expose REST API inspecting the rendered ACLs.
+	HTTPHandlers rest.HTTPHandlers
+
+	// KVStore is optional, used to publish the status of the policy enforcement
+	// on this node for KSR to reflect it into K8s.
+	KVStore kvstore.KvStore
 }
 
 // Init initializes policy layers and caches and starts watching ETCD for K8s configuration.
@@ -99,6 +182,18 @@ func (p *Plugin) Init() error {
 	p.resyncChan = make(chan datasync.ResyncEvent)
 	p.changeChan = make(chan datasync.ChangeEvent)
 
+	p.config = &Config{}
+	if p.PluginConfig != nil {
+		_, err = p.PluginConfig.GetValue(p.config)
+		if err != nil {
+			return err
+		}
+	}
+	auditNamespaces := make(map[string]struct{})
+	for _, namespace := range p.config.AuditNamespaces {
+		auditNamespaces[namespace] = struct{}{}
+	}
+
 	// Inject dependencies between layers.
 	p.policyCache = &cache.PolicyCache{
 		Deps: cache.Deps{
@@ -110,22 +205,72 @@ func (p *Plugin) Init() error {
 
 	p.configurator = &configurator.PolicyConfigurator{
 		Deps: configurator.Deps{
-			Log:   p.Log.NewLogger("-policyConfigurator"),
-			Cache: p.policyCache,
+			Log:             p.Log.NewLogger("-policyConfigurator"),
+			Cache:           p.policyCache,
+			AuditMode:       p.config.AuditMode,
+			AuditNamespaces: auditNamespaces,
 		},
 	}
+	if p.Prometheus != nil {
+		renderDuration := prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "contiv",
+			Subsystem: "policy",
+			Name:      "render_duration_seconds",
+			Help:      "Duration of rendering of the policy configuration into all registered renderers",
+		})
+		if err := p.Prometheus.Register(prometheusplugin.DefaultRegistry, renderDuration); err != nil {
+			return err
+		}
+		p.configurator.RenderDuration = renderDuration
+
+		p.policyHits = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "contiv",
+			Subsystem: "policy",
+			Name:      "rule_hits",
+			Help:      "Number of connections of pods permitted or denied by the rules rendered for policies",
+		}, []string{namespaceLabel, policyLabel, podLabel, directionLabel, actionLabel})
+		if err := p.Prometheus.Register(prometheusplugin.DefaultRegistry, p.policyHits); err != nil {
+			return err
+		}
+
+		p.auditViolations = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "contiv",
+			Subsystem: "policy",
+			Name:      "audit_violations",
+			Help:      "Number of connections that would be denied by the policies rendered in the audit mode",
+		}, []string{namespaceLabel, podLabel, directionLabel})
+		if err := p.Prometheus.Register(prometheusplugin.DefaultRegistry, p.auditViolations); err != nil {
+			return err
+		}
+	}
 	p.configurator.Log.SetLevel(logging.DebugLevel)
 
+	hostEndpointPolicy, err := hostEndpointConfigPolicy(p.config)
+	if err != nil {
+		return err
+	}
 	p.processor = &processor.PolicyProcessor{
 		Deps: processor.Deps{
-			Log:          p.Log.NewLogger("-policyProcessor"),
-			Contiv:       p.Contiv,
-			Cache:        p.policyCache,
-			Configurator: p.configurator,
+			Log:                p.Log.NewLogger("-policyProcessor"),
+			Contiv:             p.Contiv,
+			Cache:              p.policyCache,
+			Configurator:       p.configurator,
+			ServiceLabel:       p.ServiceLabel,
+			HostEndpointPolicy: hostEndpointPolicy,
+			DefaultDeny:        p.config.DefaultDenyBootstrap,
+			FQDNAddressTTL:     time.Duration(p.config.FQDNAddressTTL) * time.Second,
 		},
 	}
 	p.processor.Log.SetLevel(logging.DebugLevel)
 
+	if p.KVStore != nil {
+		p.statusPublisher = newStatusPublisher(p.KVStore, p.ServiceLabel.GetAgentLabel())
+	}
+
+	aclStatsCh, err := p.GoVPP.NewAPIChannel()
+	if err != nil {
+		return err
+	}
 	p.aclRenderer = &acl.Renderer{
 		Deps: acl.Deps{
 			Log:        p.Log.NewLogger("-aclRenderer"),
@@ -135,6 +280,7 @@ func (p *Plugin) Init() error {
 			ACLTxnFactory: func() linux.DataChangeDSL {
 				return localclient.DataChangeRequest(p.PluginName)
 			},
+			GoVPPChan: aclStatsCh,
 		},
 	}
 	p.aclRenderer.Log.SetLevel(logging.DebugLevel)
@@ -172,7 +318,23 @@ func (p *Plugin) Init() error {
 
 	p.ctx, p.cancel = context.WithCancel(context.Background())
 
+	if p.config.DefaultDenyBootstrap {
+		p.containerChan = make(chan containeridx.ChangeEvent, 100)
+		err = p.Contiv.GetContainerIndex().Watch(p.PluginName, containeridx.ToChan(p.containerChan))
+		if err != nil {
+			return err
+		}
+	}
+
 	go p.watchEvents()
+	fqdnRefreshInterval := defaultFQDNRefreshInterval
+	if p.config.FQDNRefreshInterval != 0 {
+		fqdnRefreshInterval = time.Duration(p.config.FQDNRefreshInterval) * time.Second
+	}
+	go p.refreshFQDNs(fqdnRefreshInterval)
+	if p.Prometheus != nil {
+		go p.collectRuleStats(statsCollectInterval)
+	}
 	err = p.subscribeWatcher()
 	if err != nil {
 		return err
@@ -184,18 +346,23 @@ func (p *Plugin) Init() error {
 // AfterInit registers to the ResyncOrchestrator. The registration is done in this phase
 // in order to ensure that the resync for this plugin is triggered only after
 // resync of the Contiv plugin has finished.
+// REST handlers are registered here as well, if the HTTP server is available.
 func (p *Plugin) AfterInit() error {
 	if p.Resync != nil {
 		reg := p.Resync.Register(string(p.PluginName))
 		go p.handleResync(reg.StatusChan())
 	}
+	if p.HTTPHandlers != nil {
+		p.registerHandlers()
+	}
 	return nil
 }
 
 func (p *Plugin) subscribeWatcher() (err error) {
 	p.watchConfigReg, err = p.Watcher.
 		Watch("K8s policies", p.changeChan, p.resyncChan,
-			nsmodel.KeyPrefix(), podmodel.KeyPrefix(), policymodel.KeyPrefix())
+			nsmodel.KeyPrefix(), podmodel.KeyPrefix(), policymodel.KeyPrefix(),
+			custompolicy.KeyPrefix(), nodemodel.KeyPrefix())
 	return err
 }
 
@@ -221,10 +388,19 @@ func (p *Plugin) watchEvents() {
 				p.Log.WithField("config", dataChngEv).Info("Delaying data-change")
 			} else {
 				err := p.policyCache.Update(dataChngEv)
+				p.publishPolicyStatus(err)
 				dataChngEv.Done(err)
 			}
 			p.resyncLock.Unlock()
 
+		case containerEv := <-p.containerChan:
+			p.resyncLock.Lock()
+			err := p.handleContainerChange(containerEv)
+			if err != nil {
+				p.Log.Error(err)
+			}
+			p.resyncLock.Unlock()
+
 		case <-p.ctx.Done():
 			p.Log.Debug("Stop watching events")
 			return
@@ -250,6 +426,8 @@ func (p *Plugin) handleResync(resyncChan chan resync.StatusEvent) {
 					}
 					p.pendingResync = nil
 					p.pendingChanges = []datasync.ChangeEvent{}
+					p.resynced = true
+					p.publishPolicyStatus(err)
 				}
 				p.resyncLock.Unlock()
 			}
@@ -263,6 +441,31 @@ func (p *Plugin) handleResync(resyncChan chan resync.StatusEvent) {
 	}
 }
 
+// handleContainerChange denies all traffic of a newly connected pod until its
+// policies are rendered (default-deny bootstrap mode). The pod is processed
+// right away if it is already known with an IP address.
+func (p *Plugin) handleContainerChange(ev containeridx.ChangeEvent) error {
+	if ev.Value == nil || ev.Value.PodNamespace == "kube-system" {
+		return nil
+	}
+	pod := podmodel.ID{Name: ev.Value.PodName, Namespace: ev.Value.PodNamespace}
+	if ev.Del {
+		return p.aclRenderer.CancelBootstrapDeny(pod)
+	}
+	err := p.aclRenderer.BootstrapDeny(pod)
+	if err != nil {
+		return err
+	}
+	if !p.resynced {
+		// all known pods are processed by the RESYNC
+		return nil
+	}
+	if found, podData := p.policyCache.LookupPod(pod); found && podData.IpAddress != "" {
+		return p.processor.Process(false, []podmodel.ID{pod})
+	}
+	return nil
+}
+
 // Close stops the processor and watching.
 func (p *Plugin) Close() error {
 	p.cancel()
@@ -0,0 +1,206 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"time"
+
+	"github.com/contiv/vpp/plugins/policy/configurator"
+	"github.com/contiv/vpp/plugins/policy/renderer"
+)
+
+const (
+	// statsCollectInterval is the period of reading rule hit counters from VPP.
+	statsCollectInterval = 10 * time.Second
+
+	// labels of the exported metrics
+	namespaceLabel = "namespace"
+	podLabel       = "pod"
+	policyLabel    = "policy"
+	directionLabel = "direction"
+	actionLabel    = "action"
+
+	// directions of the traffic (from the pod point of view)
+	ingressDirection = "ingress"
+	egressDirection  = "egress"
+
+	// actions of the rules
+	permitAction = "permit"
+	denyAction   = "deny"
+	auditAction  = "audit"
+)
+
+// PolicyRuleStats is the number of connections of a pod matched by a rule
+// rendered for a policy.
+// The traffic denied for not being allowed by any policy of the pod
+// is attributed to each policy isolating the pod in the given direction.
+type PolicyRuleStats struct {
+	Namespace string `json:"namespace"`
+	Policy    string `json:"policy"`
+	Pod       string `json:"pod"`
+
+	// Direction is "ingress" or "egress", from the pod point of view.
+	Direction string `json:"direction"`
+
+	// Action is "permit", "deny" or "audit" (would be denied if not in the audit mode).
+	Action string `json:"action"`
+
+	// Rule describes the matched rule.
+	Rule string `json:"rule"`
+
+	// Connections is the number of connections (not packets) matched by the rule
+	// since the (re)installation of the rules.
+	Connections uint64 `json:"connections"`
+}
+
+// AuditViolation summarizes the traffic of a pod that would be denied
+// by the policies if they were not rendered in the audit mode.
+type AuditViolation struct {
+	PodNamespace string `json:"podNamespace"`
+	PodName      string `json:"podName"`
+
+	// Direction is "ingress" or "egress", from the pod point of view.
+	Direction string `json:"direction"`
+
+	// Protocol is one of TCP, UDP, SCTP.
+	Protocol string `json:"protocol"`
+
+	// Connections is the number of connections (not packets) permitted
+	// only thanks to the audit mode, since the (re)installation of the rules.
+	Connections uint64 `json:"connections"`
+}
+
+// dumpRuleStats reads the hit counters of the rendered rules and maps them
+// back to policies and pods.
+func (p *Plugin) dumpRuleStats() (policyStats []*PolicyRuleStats, violations []*AuditViolation, err error) {
+	// Do not read counters while the rules are being re-rendered.
+	p.resyncLock.Lock()
+	defer p.resyncLock.Unlock()
+
+	stats, err := p.aclRenderer.DumpRuleStats()
+	if err != nil {
+		return nil, nil, err
+	}
+	policyStats = []*PolicyRuleStats{}
+	violations = []*AuditViolation{}
+	for _, ruleStats := range stats {
+		// Ingress of the vswitch is the egress of the pod.
+		direction := ingressDirection
+		if ruleStats.Ingress {
+			direction = egressDirection
+		}
+		action := permitAction
+		if ruleStats.Rule.Action == renderer.ActionDeny {
+			action = denyAction
+		} else if ruleStats.Rule.IsAudit() {
+			action = auditAction
+		}
+		ruleDescr := ruleStats.Rule.String()
+
+		for _, pod := range ruleStats.Pods {
+			if action == auditAction {
+				violations = append(violations, &AuditViolation{
+					PodNamespace: pod.Namespace,
+					PodName:      pod.Name,
+					Direction:    direction,
+					Protocol:     ruleStats.Rule.Protocol.String(),
+					Connections:  ruleStats.Hits,
+				})
+			}
+			if policy, fromPolicy := ruleStats.Rule.PolicyID(); fromPolicy {
+				policyStats = append(policyStats, &PolicyRuleStats{
+					Namespace:   policy.Namespace,
+					Policy:      policy.Name,
+					Pod:         pod.Name,
+					Direction:   direction,
+					Action:      action,
+					Rule:        ruleDescr,
+					Connections: ruleStats.Hits,
+				})
+				continue
+			}
+			if action == permitAction {
+				// e.g. allow-all rules added by the renderer
+				continue
+			}
+			for _, policy := range p.configurator.GetPodPolicies(pod) {
+				if !isolatesDirection(policy, direction) {
+					continue
+				}
+				policyStats = append(policyStats, &PolicyRuleStats{
+					Namespace:   policy.ID.Namespace,
+					Policy:      policy.ID.Name,
+					Pod:         pod.Name,
+					Direction:   direction,
+					Action:      action,
+					Rule:        ruleDescr,
+					Connections: ruleStats.Hits,
+				})
+			}
+		}
+	}
+	return policyStats, violations, nil
+}
+
+// isolatesDirection returns true if the policy isolates pods in the given direction.
+func isolatesDirection(policy *configurator.ContivPolicy, direction string) bool {
+	switch policy.Type {
+	case configurator.PolicyIngress:
+		return direction == ingressDirection
+	case configurator.PolicyEgress:
+		return direction == egressDirection
+	}
+	return true
+}
+
+// collectRuleStats periodically exposes rule hit counters via Prometheus.
+func (p *Plugin) collectRuleStats(interval time.Duration) {
+	p.wg.Add(1)
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			policyStats, violations, err := p.dumpRuleStats()
+			if err != nil {
+				p.Log.Warnf("Failed to read rule hit counters: %v", err)
+				continue
+			}
+			p.policyHits.Reset()
+			for _, stats := range policyStats {
+				p.policyHits.With(map[string]string{
+					namespaceLabel: stats.Namespace,
+					policyLabel:    stats.Policy,
+					podLabel:       stats.Pod,
+					directionLabel: stats.Direction,
+					actionLabel:    stats.Action,
+				}).Add(float64(stats.Connections))
+			}
+			p.auditViolations.Reset()
+			for _, violation := range violations {
+				p.auditViolations.With(map[string]string{
+					namespaceLabel: violation.PodNamespace,
+					podLabel:       violation.PodName,
+					directionLabel: violation.Direction,
+				}).Add(float64(violation.Connections))
+			}
+
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/servicelabel"
+
+	"github.com/contiv/vpp/flavors/ksr"
+	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
+	"github.com/contiv/vpp/plugins/kvstore"
+)
+
+// policyRenderingFailedReason is the reason of the K8s Event reporting a failed rendering of the policies.
+const policyRenderingFailedReason = "PolicyRenderingFailed"
+
+// statusPublisher publishes the status of the enforcement of the policies
+// on this node into the data store under the KSR prefix. KSR aggregates
+// the statuses of all nodes and reflects them back into K8s.
+type statusPublisher struct {
+	broker keyval.ProtoBroker
+	node   string
+
+	// published statuses, nil until the statuses of a previous run are loaded
+	published map[policymodel.ID]*policymodel.PolicyStatus
+}
+
+// newStatusPublisher creates new instance of statusPublisher for the given node.
+func newStatusPublisher(store kvstore.KvStore, nodeName string) *statusPublisher {
+	return &statusPublisher{
+		broker: store.NewBroker(servicelabel.GetDifferentAgentPrefix(ksr.MicroserviceLabel)),
+		node:   nodeName,
+	}
+}
+
+// publishPolicyStatus publishes the status of every policy selecting some pods
+// of this node. The error of the last processing of the policies (if any)
+// is reported for all of them, since the rendering is not transactional
+// per policy. The error is also reported as a K8s Event of the node.
+func (p *Plugin) publishPolicyStatus(processErr error) {
+	if processErr != nil {
+		p.Contiv.ReportK8sEvent("", "", policyRenderingFailedReason,
+			fmt.Sprintf("Rendering of network policies failed, pods may be isolated or exposed: %v", processErr))
+	}
+	if p.statusPublisher == nil {
+		return
+	}
+	var errStr string
+	if processErr != nil {
+		errStr = processErr.Error()
+	}
+	statuses := make(map[policymodel.ID]*policymodel.PolicyStatus)
+	for _, policyID := range p.policyCache.ListAllPolicies() {
+		found, policyData := p.policyCache.LookupPolicy(policyID)
+		if !found {
+			continue
+		}
+		pods := p.processor.LookupLocalPodsByPolicy(policyData)
+		if len(pods) == 0 {
+			continue
+		}
+		statuses[policyID] = &policymodel.PolicyStatus{
+			Name:      policyID.Name,
+			Namespace: policyID.Namespace,
+			Node:      p.statusPublisher.node,
+			Pods:      uint32(len(pods)),
+			Error:     errStr,
+		}
+	}
+	if err := p.statusPublisher.publish(statuses); err != nil {
+		p.Log.Warnf("Failed to publish the policy status: %v", err)
+	}
+}
+
+// publish puts the changed statuses into the data store and removes
+// the statuses of the policies no longer rendered on the node.
+func (sp *statusPublisher) publish(statuses map[policymodel.ID]*policymodel.PolicyStatus) error {
+	if sp.published == nil {
+		if err := sp.loadPublished(); err != nil {
+			return err
+		}
+	}
+	for policyID, status := range statuses {
+		if published, has := sp.published[policyID]; has && proto.Equal(published, status) {
+			continue
+		}
+		if err := sp.broker.Put(policymodel.StatusKey(status.Name, status.Namespace, sp.node), status); err != nil {
+			return err
+		}
+		sp.published[policyID] = status
+	}
+	for policyID := range sp.published {
+		if _, has := statuses[policyID]; has {
+			continue
+		}
+		if _, err := sp.broker.Delete(policymodel.StatusKey(policyID.Name, policyID.Namespace, sp.node)); err != nil {
+			return err
+		}
+		delete(sp.published, policyID)
+	}
+	return nil
+}
+
+// loadPublished reads the statuses published for this node before the restart
+// of the agent.
+func (sp *statusPublisher) loadPublished() error {
+	it, err := sp.broker.ListValues(policymodel.StatusKeyPrefix())
+	if err != nil {
+		return err
+	}
+	published := make(map[policymodel.ID]*policymodel.PolicyStatus)
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			break
+		}
+		status := &policymodel.PolicyStatus{}
+		if err := kv.GetValue(status); err != nil {
+			return err
+		}
+		if status.Node != sp.node {
+			continue
+		}
+		published[policymodel.ID{Name: status.Name, Namespace: status.Namespace}] = status
+	}
+	sp.published = published
+	return nil
+}
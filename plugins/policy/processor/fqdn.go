@@ -0,0 +1,105 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"time"
+)
+
+// DefaultFQDNAddressTTL is used as the expiry of the addresses resolved for DNS
+// names referenced by policies if the Deps do not define FQDNAddressTTL.
+const DefaultFQDNAddressTTL = 5 * time.Minute
+
+// FQDNs returns the DNS names referenced by the custom policies applied to the host
+// endpoint of this node, i.e. the names that should be periodically resolved
+// and passed to UpdateFQDNs.
+func (pp *PolicyProcessor) FQDNs() []string {
+	fqdns := []string{}
+	for fqdn := range pp.fqdnAddrs {
+		fqdns = append(fqdns, fqdn)
+	}
+	sort.Strings(fqdns)
+	return fqdns
+}
+
+// UpdateFQDNs merges the addresses of DNS names resolved at the given time into
+// the address sets of the names and re-processes the host endpoint if an address
+// set has changed.
+func (pp *PolicyProcessor) UpdateFQDNs(resolved map[string][]net.IP, now time.Time) error {
+	if !pp.mergeFQDNs(resolved, now) {
+		return nil
+	}
+	pp.Log.WithField("fqdns", pp.fqdnAddrs).Info("Addresses of DNS names referenced by policies have changed")
+	return pp.ProcessHostEndpoint()
+}
+
+// mergeFQDNs merges the resolved addresses into the address sets of the watched
+// DNS names. Address that was not returned by the resolver for FQDNAddressTTL
+// is removed from the set, connections to an address are therefore not broken
+// when a name is served by a rotating set of addresses.
+// Returns true if an address set has changed.
+func (pp *PolicyProcessor) mergeFQDNs(resolved map[string][]net.IP, now time.Time) (changed bool) {
+	ttl := pp.FQDNAddressTTL
+	if ttl == 0 {
+		ttl = DefaultFQDNAddressTTL
+	}
+	for fqdn, addrs := range pp.fqdnAddrs {
+		for _, ip := range resolved[fqdn] {
+			key := ip.String()
+			if _, known := addrs[key]; !known {
+				changed = true
+			}
+			addrs[key] = now.Add(ttl)
+		}
+		for key, expiry := range addrs {
+			if now.After(expiry) {
+				delete(addrs, key)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// watchFQDNs updates the set of DNS names referenced by the policies, address
+// sets of the names no longer referenced are dropped.
+func (pp *PolicyProcessor) watchFQDNs(fqdns map[string]struct{}) {
+	for fqdn := range pp.fqdnAddrs {
+		if _, referenced := fqdns[fqdn]; !referenced {
+			delete(pp.fqdnAddrs, fqdn)
+		}
+	}
+	for fqdn := range fqdns {
+		if _, watched := pp.fqdnAddrs[fqdn]; !watched {
+			pp.fqdnAddrs[fqdn] = make(map[string]time.Time)
+		}
+	}
+}
+
+// lookupFQDN returns the current address set of the given DNS name, sorted
+// to render the same rules for the same set.
+func (pp *PolicyProcessor) lookupFQDN(fqdn string) []net.IP {
+	var addrs []net.IP
+	for key := range pp.fqdnAddrs[fqdn] {
+		addrs = append(addrs, net.ParseIP(key))
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i].To16(), addrs[j].To16()) < 0
+	})
+	return addrs
+}
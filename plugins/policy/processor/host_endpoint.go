@@ -0,0 +1,259 @@
+// Copyright (c) 2018 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"net"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/logging"
+
+	"github.com/contiv/vpp/plugins/ksr/model/custompolicy"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
+	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
+	config "github.com/contiv/vpp/plugins/policy/configurator"
+	"github.com/contiv/vpp/plugins/policy/renderer"
+)
+
+// ProcessHostEndpoint re-calculates the set of Contiv policies for the host
+// endpoint of this node from the custom network policies selecting the node
+// and from the host endpoint policy of the plugin configuration.
+func (pp *PolicyProcessor) ProcessHostEndpoint() error {
+	txn := pp.Configurator.NewTxn(false)
+	pp.configureHostEndpoint(txn)
+	return txn.Commit()
+}
+
+// configureHostEndpoint adds the policies of the host endpoint into the given
+// transaction.
+func (pp *PolicyProcessor) configureHostEndpoint(txn config.Txn) {
+	policies := []*config.ContivPolicy{}
+	if pp.HostEndpointPolicy != nil {
+		policies = append(policies, pp.HostEndpointPolicy)
+	}
+
+	var nodeLabels []*nodemodel.Node_Label
+	found, node := pp.Cache.LookupNode(nodemodel.ID(pp.ServiceLabel.GetAgentLabel()))
+	if found {
+		nodeLabels = node.Label
+	}
+
+	var selected []*custompolicy.CustomNetworkPolicy
+	fqdns := make(map[string]struct{})
+	for _, policyID := range pp.Cache.ListAllCustomPolicies() {
+		found, policy := pp.Cache.LookupCustomPolicy(policyID)
+		if !found || !isNodeSelected(nodeLabels, policy.Nodes) {
+			continue
+		}
+		selected = append(selected, policy)
+		for _, rules := range [][]*custompolicy.CustomNetworkPolicy_Rule{policy.IngressRule, policy.EgressRule} {
+			for _, rule := range rules {
+				for _, fqdn := range rule.Fqdn {
+					fqdns[fqdn] = struct{}{}
+				}
+			}
+		}
+	}
+	pp.watchFQDNs(fqdns)
+	for _, policy := range selected {
+		policies = append(policies, pp.convertCustomPolicy(policy))
+	}
+
+	pp.Log.Infof("Host endpoint sent to Configurator w/ Policies: %+v", policies)
+	txn.Configure(renderer.HostEndpoint, policies)
+}
+
+// convertCustomPolicy translates custom network policy into Contiv policy.
+func (pp *PolicyProcessor) convertCustomPolicy(policy *custompolicy.CustomNetworkPolicy) *config.ContivPolicy {
+	contivPolicy := &config.ContivPolicy{
+		// Custom policies are cluster-scoped, i.e. without namespace.
+		ID:      policymodel.ID{Name: policy.Name},
+		Type:    getCustomPolicyType(policy),
+		Matches: []config.Match{},
+	}
+	for _, rule := range policy.IngressRule {
+		contivPolicy.Matches = append(contivPolicy.Matches, pp.convertCustomPolicyRule(policy, config.MatchIngress, rule))
+	}
+	for _, rule := range policy.EgressRule {
+		contivPolicy.Matches = append(contivPolicy.Matches, pp.convertCustomPolicyRule(policy, config.MatchEgress, rule))
+	}
+	return contivPolicy
+}
+
+// convertCustomPolicyRule translates a rule of custom network policy into Contiv match.
+func (pp *PolicyProcessor) convertCustomPolicyRule(policy *custompolicy.CustomNetworkPolicy,
+	matchType config.MatchType, rule *custompolicy.CustomNetworkPolicy_Rule) config.Match {
+
+	match := config.Match{Type: matchType, Ports: []config.Port{}}
+	for _, port := range rule.Port {
+		protocol := config.TCP
+		switch port.Protocol {
+		case custompolicy.CustomNetworkPolicy_Port_UDP:
+			protocol = config.UDP
+		case custompolicy.CustomNetworkPolicy_Port_SCTP:
+			protocol = config.SCTP
+		}
+		match.Ports = append(match.Ports, config.Port{Protocol: protocol, Number: uint16(port.Port)})
+	}
+	if len(rule.Peer) == 0 && len(rule.Fqdn) == 0 {
+		// Pods=nil & IPBlocks=nil: match all peers.
+		return match
+	}
+	match.IPBlocks = []config.IPBlock{}
+	for _, peer := range rule.Peer {
+		block, err := convertIPBlock(&policymodel.Policy_Peer_IPBlock{Cidr: peer.Cidr, Except: peer.Except})
+		if err != nil {
+			pp.Log.WithFields(logging.Fields{
+				"policy":    policy.Name,
+				"direction": matchType,
+			}).Warnf("Skipping IPBlock: %v", err)
+			continue
+		}
+		match.IPBlocks = append(match.IPBlocks, block)
+	}
+	// DNS names not resolved yet match nothing.
+	for _, fqdn := range rule.Fqdn {
+		for _, ip := range pp.lookupFQDN(fqdn) {
+			maskLen := net.IPv6len * 8
+			if ip.To4() != nil {
+				ip = ip.To4()
+				maskLen = net.IPv4len * 8
+			}
+			match.IPBlocks = append(match.IPBlocks, config.IPBlock{
+				Network: net.IPNet{IP: ip, Mask: net.CIDRMask(maskLen, maskLen)},
+			})
+		}
+	}
+	return match
+}
+
+// getCustomPolicyType returns the traffic directions that the given custom
+// policy applies to, with the same defaults as for K8s network policies.
+func getCustomPolicyType(policy *custompolicy.CustomNetworkPolicy) config.PolicyType {
+	switch policy.PolicyType {
+	case custompolicy.CustomNetworkPolicy_INGRESS:
+		return config.PolicyIngress
+	case custompolicy.CustomNetworkPolicy_EGRESS:
+		return config.PolicyEgress
+	case custompolicy.CustomNetworkPolicy_INGRESS_AND_EGRESS:
+		return config.PolicyAll
+	}
+	if len(policy.EgressRule) > 0 {
+		return config.PolicyAll
+	}
+	return config.PolicyIngress
+}
+
+// isNodeSelected returns true if the node with the given labels is selected
+// by the node selector of a custom policy. An empty selector matches all nodes.
+func isNodeSelected(nodeLabels []*nodemodel.Node_Label, selector *custompolicy.CustomNetworkPolicy_LabelSelector) bool {
+	labels := make(map[string]string)
+	for _, label := range nodeLabels {
+		labels[label.Key] = label.Value
+	}
+	for _, matchLabel := range selector.GetMatchLabel() {
+		if value, hasLabel := labels[matchLabel.Key]; !hasLabel || value != matchLabel.Value {
+			return false
+		}
+	}
+	for _, expression := range selector.GetMatchExpression() {
+		value, hasLabel := labels[expression.Key]
+		inValues := false
+		for _, exprValue := range expression.Value {
+			if hasLabel && value == exprValue {
+				inValues = true
+			}
+		}
+		switch expression.Operator {
+		case custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_IN:
+			if !inValues {
+				return false
+			}
+		case custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_NOT_IN:
+			if inValues {
+				return false
+			}
+		case custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_EXISTS:
+			if !hasLabel {
+				return false
+			}
+		case custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_DOES_NOT_EXIST:
+			if hasLabel {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// AddCustomPolicy processes the event of newly added custom policy.
+func (pp *PolicyProcessor) AddCustomPolicy(policy *custompolicy.CustomNetworkPolicy) error {
+	pp.Log.WithField("policy", policy).Info("Custom policy was added")
+	return pp.ProcessHostEndpoint()
+}
+
+// DelCustomPolicy processes the event of a removed custom policy.
+func (pp *PolicyProcessor) DelCustomPolicy(policy *custompolicy.CustomNetworkPolicy) error {
+	pp.Log.WithField("policy", policy).Info("Custom policy was deleted")
+	return pp.ProcessHostEndpoint()
+}
+
+// UpdateCustomPolicy processes the event of changed custom policy data.
+func (pp *PolicyProcessor) UpdateCustomPolicy(oldPolicy, newPolicy *custompolicy.CustomNetworkPolicy) error {
+	pp.Log.WithFields(logging.Fields{
+		"new-policy": newPolicy,
+		"old-policy": oldPolicy,
+	}).Info("Custom policy was updated")
+
+	if proto.Equal(oldPolicy, newPolicy) {
+		pp.Log.WithField("policy", newPolicy).Debug("Custom policy has not changed")
+		return nil
+	}
+	return pp.ProcessHostEndpoint()
+}
+
+// AddNode processes the event of newly added node. Only the labels of this node
+// are relevant for policies.
+func (pp *PolicyProcessor) AddNode(node *nodemodel.Node) error {
+	if node.Name != pp.ServiceLabel.GetAgentLabel() {
+		return nil
+	}
+	pp.Log.WithField("node", node).Info("This node was added")
+	return pp.ProcessHostEndpoint()
+}
+
+// DelNode processes the event of a removed node.
+func (pp *PolicyProcessor) DelNode(node *nodemodel.Node) error {
+	if node.Name != pp.ServiceLabel.GetAgentLabel() {
+		return nil
+	}
+	pp.Log.WithField("node", node).Info("This node was deleted")
+	return pp.ProcessHostEndpoint()
+}
+
+// UpdateNode processes the event of changed node data.
+func (pp *PolicyProcessor) UpdateNode(oldNode, newNode *nodemodel.Node) error {
+	if newNode.Name != pp.ServiceLabel.GetAgentLabel() {
+		return nil
+	}
+	if proto.Equal(&nodemodel.Node{Label: oldNode.Label}, &nodemodel.Node{Label: newNode.Label}) {
+		return nil
+	}
+	pp.Log.WithFields(logging.Fields{
+		"new-node": newNode,
+		"old-node": oldNode,
+	}).Info("Labels of this node were updated")
+	return pp.ProcessHostEndpoint()
+}
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"fmt"
 	"net"
 
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
@@ -17,6 +18,7 @@ func (pp *PolicyProcessor) calculateMatches(policyData *policymodel.Policy) []co
 	ingressRules := policyData.IngressRule
 	egressRules := policyData.EgressRule
 	namespace := policyData.Namespace
+	var policyPods []podmodel.ID
 
 	if len(ingressRules) != 0 {
 
@@ -52,32 +54,23 @@ func (pp *PolicyProcessor) calculateMatches(policyData *policymodel.Policy) []co
 				if ingressIPBlock == nil {
 					continue
 				}
-				_, ingressCIDR, _ := net.ParseCIDR(ingressIPBlock.Cidr)
-
-				ingressIPBlockEx := []net.IPNet{}
-				ingressIPBlockExcepts := ingressIPBlock.Except
-				for _, ingressIPBlockExcept := range ingressIPBlockExcepts {
-					_, ingressExcept, _ := net.ParseCIDR(ingressIPBlockExcept)
-					ingressIPBlockEx = append(ingressIPBlockEx, *ingressExcept)
+				block, err := convertIPBlock(ingressIPBlock)
+				if err != nil {
+					pp.Log.WithField("policy", policymodel.GetID(policyData)).Warnf("Skipping ingress IPBlock: %v", err)
+					continue
 				}
-				ingressIPBlocks = append(ingressIPBlocks, config.IPBlock{
-					Network: *ingressCIDR,
-					Except:  ingressIPBlockEx,
-				})
+				ingressIPBlocks = append(ingressIPBlocks, block)
 			}
 
+			// Named ports of ingress rules are defined by the pods the policy is assigned to.
 			ingressRulePorts := ingressRule.Port
-			for _, ingressRulePort := range ingressRulePorts {
-				ingressPortProtocol := config.TCP
-				if ingressRulePort.Protocol == policymodel.Policy_Port_UDP {
-					ingressPortProtocol = config.UDP
-				}
-				// todo: translate form name to port number
-				ingressPortNumber := uint16(ingressRulePort.Port.Number)
-				ingressPorts = append(ingressPorts, config.Port{
-					Protocol: ingressPortProtocol,
-					Number:   ingressPortNumber,
-				})
+			if hasNamedPort(ingressRulePorts) && policyPods == nil {
+				policyPods = pp.getPodsAssignedToPolicy(policyData)
+			}
+			ingressPorts = pp.convertPorts(ingressRulePorts, policyPods)
+			if len(ingressRulePorts) > 0 && len(ingressPorts) == 0 {
+				// None of the named ports is defined, the rule matches nothing.
+				continue
 			}
 
 			matches = append(matches, config.Match{
@@ -122,34 +115,24 @@ func (pp *PolicyProcessor) calculateMatches(policyData *policymodel.Policy) []co
 				if egressIPBlock == nil {
 					continue
 				}
-
-				_, egressCIDR, _ := net.ParseCIDR(egressIPBlock.Cidr)
-
-				egressIPBlockEx := []net.IPNet{}
-				egressIPBlockExcepts := egressIPBlock.Except
-				for _, ingressIPBlockExcept := range egressIPBlockExcepts {
-					_, ingressExcept, _ := net.ParseCIDR(ingressIPBlockExcept)
-					egressIPBlockEx = append(egressIPBlockEx, *ingressExcept)
+				block, err := convertIPBlock(egressIPBlock)
+				if err != nil {
+					pp.Log.WithField("policy", policymodel.GetID(policyData)).Warnf("Skipping egress IPBlock: %v", err)
+					continue
 				}
-				egressIPBlocks = append(egressIPBlocks, config.IPBlock{
-					Network: *egressCIDR,
-					Except:  egressIPBlockEx,
-				})
+				egressIPBlocks = append(egressIPBlocks, block)
 			}
 
+			// Named ports of egress rules are defined by the destination pods.
 			egressRulePorts := egressRule.Port
-			// Egress ports to appropriate type
-			for _, egressRulePort := range egressRulePorts {
-				egressPortProtocol := config.TCP
-				if egressRulePort.Protocol == policymodel.Policy_Port_UDP {
-					egressPortProtocol = config.UDP
-				}
-				// todo: translate form name to port number
-				egressPortNumber := uint16(egressRulePort.Port.Number)
-				egressPorts = append(egressPorts, config.Port{
-					Protocol: egressPortProtocol,
-					Number:   egressPortNumber,
-				})
+			destPods := egressPods
+			if egressPods == nil && hasNamedPort(egressRulePorts) {
+				destPods = pp.Cache.ListAllPods()
+			}
+			egressPorts = pp.convertPorts(egressRulePorts, destPods)
+			if len(egressRulePorts) > 0 && len(egressPorts) == 0 {
+				// None of the named ports is defined, the rule matches nothing.
+				continue
 			}
 
 			matches = append(matches, config.Match{
@@ -163,6 +146,97 @@ func (pp *PolicyProcessor) calculateMatches(policyData *policymodel.Policy) []co
 	return matches
 }
 
+// convertPorts translates ports of a policy rule into the format used by the configurator.
+// Named ports are resolved against the container ports of the given pods. A name may
+// translate into multiple port numbers if the pods define it differently. Names not
+// defined by any of the pods are left out.
+func (pp *PolicyProcessor) convertPorts(rulePorts []*policymodel.Policy_Port, pods []podmodel.ID) []config.Port {
+	ports := []config.Port{}
+	added := make(map[config.Port]struct{})
+	addPort := func(port config.Port) {
+		if _, duplicate := added[port]; !duplicate {
+			added[port] = struct{}{}
+			ports = append(ports, port)
+		}
+	}
+
+	for _, rulePort := range rulePorts {
+		protocol := config.TCP
+		switch rulePort.Protocol {
+		case policymodel.Policy_Port_UDP:
+			protocol = config.UDP
+		case policymodel.Policy_Port_SCTP:
+			protocol = config.SCTP
+		}
+		if rulePort.GetPort().GetType() != policymodel.Policy_Port_PortNameOrNumber_NAME {
+			addPort(config.Port{Protocol: protocol, Number: uint16(rulePort.GetPort().GetNumber())})
+			continue
+		}
+		for _, podID := range pods {
+			found, pod := pp.Cache.LookupPod(podID)
+			if !found {
+				continue
+			}
+			for _, number := range getNamedPort(pod, rulePort.Port.Name, protocol) {
+				addPort(config.Port{Protocol: protocol, Number: number})
+			}
+		}
+	}
+	return ports
+}
+
+// hasNamedPort returns true if at least one of the given ports is referenced by name.
+func hasNamedPort(rulePorts []*policymodel.Policy_Port) bool {
+	for _, rulePort := range rulePorts {
+		if rulePort.GetPort().GetType() == policymodel.Policy_Port_PortNameOrNumber_NAME {
+			return true
+		}
+	}
+	return false
+}
+
+// getNamedPort returns numbers of the container ports of the pod with the given name and protocol.
+func getNamedPort(pod *podmodel.Pod, name string, protocol config.ProtocolType) (numbers []uint16) {
+	for _, container := range pod.Container {
+		for _, port := range container.Port {
+			if port.Name != name || port.ContainerPort <= 0 {
+				continue
+			}
+			portProtocol := config.TCP
+			switch port.Protocol {
+			case podmodel.Pod_Container_Port_UDP:
+				portProtocol = config.UDP
+			case podmodel.Pod_Container_Port_SCTP:
+				portProtocol = config.SCTP
+			}
+			if portProtocol == protocol {
+				numbers = append(numbers, uint16(port.ContainerPort))
+			}
+		}
+	}
+	return numbers
+}
+
+// convertIPBlock translates IPBlock from the K8s data model into the format used
+// by the configurator. Returns error if the CIDR or any of the exceptions is not
+// a valid network address.
+func convertIPBlock(ipBlock *policymodel.Policy_Peer_IPBlock) (config.IPBlock, error) {
+	block := config.IPBlock{Except: []net.IPNet{}}
+	_, cidr, err := net.ParseCIDR(ipBlock.Cidr)
+	if err != nil {
+		return block, fmt.Errorf("invalid CIDR %s: %v", ipBlock.Cidr, err)
+	}
+	block.Network = *cidr
+	for _, exceptStr := range ipBlock.Except {
+		_, except, err := net.ParseCIDR(exceptStr)
+		if err != nil {
+			return block, fmt.Errorf("invalid exception %s from CIDR %s: %v", exceptStr, ipBlock.Cidr, err)
+		}
+		block.Except = append(block.Except, *except)
+	}
+	return block, nil
+}
+
 // calculateLabelSelectorMatches returns true if all
 func (pp *PolicyProcessor) calculateLabelSelectorMatches(
 	pod *podmodel.Pod,
@@ -18,8 +18,11 @@ package processor
 
 import (
 	"net"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/cn-infra/servicelabel"
 
 	"github.com/contiv/vpp/plugins/contiv"
 	nsmodel "github.com/contiv/vpp/plugins/ksr/model/namespace"
@@ -44,6 +47,9 @@ import (
 type PolicyProcessor struct {
 	Deps
 	podIPAddressMap map[podmodel.ID]net.IP
+
+	// addresses of DNS names referenced by policies with their expiry times
+	fqdnAddrs map[string]map[string]time.Time
 }
 
 // Deps lists dependencies of Policy Processor.
@@ -52,11 +58,27 @@ type Deps struct {
 	Cache        cache.PolicyCacheAPI
 	Contiv       contiv.API /* to get the Host IP */
 	Configurator config.PolicyConfiguratorAPI
+	ServiceLabel servicelabel.ReaderAPI /* to get the node name */
+
+	// HostEndpointPolicy is optional, applied to the host endpoint of every node
+	// in addition to the custom policies selecting the node.
+	HostEndpointPolicy *config.ContivPolicy
+
+	// DefaultDeny should be enabled in the default-deny bootstrap mode: pods
+	// of this node are denied all traffic until they are processed, therefore
+	// every pod is processed once it gets an IP address, even without policies.
+	DefaultDeny bool
+
+	// FQDNAddressTTL is the time for which an address resolved for a DNS name
+	// referenced by policies stays allowed after it was last returned by the resolver
+	// (DefaultFQDNAddressTTL if zero).
+	FQDNAddressTTL time.Duration
 }
 
 // Init initializes the Policy Processor.
 func (pp *PolicyProcessor) Init() error {
 	pp.podIPAddressMap = make(map[podmodel.ID]net.IP)
+	pp.fqdnAddrs = make(map[string]map[string]time.Time)
 	pp.Cache.Watch(pp)
 	return nil
 }
@@ -64,6 +86,7 @@ func (pp *PolicyProcessor) Init() error {
 // Process re-calculates the set of Contiv policies for pods with outdated
 // configuration. The order at which the pods are reconfigured or the order
 // of policies listed for a given pod are all irrelevant.
+// With <resync> enabled, the host endpoint is re-processed as well.
 func (pp *PolicyProcessor) Process(resync bool, pods []podmodel.ID) error {
 	txn := pp.Configurator.NewTxn(false)
 	processedPolicies := make(map[policymodel.ID]*config.ContivPolicy)
@@ -84,28 +107,13 @@ func (pp *PolicyProcessor) Process(resync bool, pods []podmodel.ID) error {
 		for _, policy := range policiesByPod {
 			if contivPolicy, alreadyProcessed = processedPolicies[policy]; !alreadyProcessed {
 
-				var policyType config.PolicyType
 				found, policyData := pp.Cache.LookupPolicy(policy)
 
 				if !found {
 					continue
 				}
 
-				switch policyData.PolicyType {
-				case policymodel.Policy_INGRESS:
-					policyType = 0
-					break
-				case policymodel.Policy_EGRESS:
-					policyType = 1
-					break
-				case policymodel.Policy_INGRESS_AND_EGRESS:
-					policyType = 2
-					break
-				default:
-					policyType = 0
-					break
-				}
-
+				policyType := getPolicyType(policyData)
 				matches := pp.calculateMatches(policyData)
 
 				contivPolicy = &config.ContivPolicy{
@@ -127,11 +135,14 @@ func (pp *PolicyProcessor) Process(resync bool, pods []podmodel.ID) error {
 			Infof("Pod sent to Configurator: %+v, w/ Policies: %+v", pod, policies)
 		txn.Configure(pod, policies)
 	}
+	if resync {
+		pp.configureHostEndpoint(txn)
+	}
 	return txn.Commit()
 }
 
 // Resync processes the RESYNC event by re-calculating the policies for all
-// known pods.
+// known pods and for the host endpoint.
 func (pp *PolicyProcessor) Resync(data *cache.DataResyncEvent) error {
 	return pp.Process(true, pp.Cache.ListAllPods())
 }
@@ -151,6 +162,13 @@ func (pp *PolicyProcessor) AddPod(podID podmodel.ID, pod *podmodel.Pod) error {
 		return nil
 	}
 
+	if pp.DefaultDeny {
+		// Open the traffic allowed for the pod.
+		hostPods := pp.filterHostPods([]podmodel.ID{podID})
+		if len(hostPods) > 0 {
+			return pp.Process(false, hostPods)
+		}
+	}
 	return nil
 }
 
@@ -202,6 +220,12 @@ func (pp *PolicyProcessor) UpdatePod(podID podmodel.ID, oldPod, newPod *podmodel
 		return nil
 	}
 
+	// No action if the change is not relevant for policies (e.g. only the pod status changed).
+	if !podPolicyDataChanged(oldPod, newPod) {
+		pp.Log.WithField("update-pod", newPod).Debug("Pod data relevant for policies have not changed")
+		return nil
+	}
+
 	if newPod.IpAddress != "" {
 		pp.podIPAddressMap[podID] = net.ParseIP(newPod.IpAddress)
 	} else {
@@ -227,6 +251,10 @@ func (pp *PolicyProcessor) UpdatePod(podID podmodel.ID, oldPod, newPod *podmodel
 			pods = append(pods, pp.getPodsAssignedToPolicy(policy)...)
 		}
 	}
+	if pp.DefaultDeny && newPod.IpAddress != "" && newPod.IpAddress != oldPod.IpAddress {
+		// Open the traffic allowed for the pod.
+		pods = append(pods, podID)
+	}
 	strPods := utils.RemoveDuplicates(utils.StringPodID(pods))
 	pods = utils.UnstringPodID(strPods)
 
@@ -314,6 +342,11 @@ func (pp *PolicyProcessor) UpdatePolicy(oldPolicy, newPolicy *policymodel.Policy
 		return nil
 	}
 
+	if proto.Equal(oldPolicy, newPolicy) {
+		pp.Log.WithField("policy", newPolicy).Debug("Policy has not changed")
+		return nil
+	}
+
 	// Get all matching pods before the change and now.
 	pods := []podmodel.ID{}
 	pods = append(pods, pp.getPodsAssignedToPolicy(oldPolicy)...)
@@ -390,6 +423,12 @@ func (pp *PolicyProcessor) Close() error {
 	return nil
 }
 
+// LookupLocalPodsByPolicy returns pods deployed on the current node that have
+// the given policy assigned.
+func (pp *PolicyProcessor) LookupLocalPodsByPolicy(policy *policymodel.Policy) []podmodel.ID {
+	return pp.filterHostPods(pp.getPodsAssignedToPolicy(policy))
+}
+
 // filterHostPods filters out pods from the passed list which are not deployed
 // on the current node.
 func (pp *PolicyProcessor) filterHostPods(pods []podmodel.ID) []podmodel.ID {
@@ -422,6 +461,25 @@ func (pp *PolicyProcessor) filterHostPods(pods []podmodel.ID) []podmodel.ID {
 	return hostPods
 }
 
+// getPolicyType returns the traffic directions that the given policy applies to.
+// If policyTypes are not specified, the policy always applies to the ingress
+// traffic and to the egress traffic only if it has some egress rules, the same
+// as defined by K8s.
+func getPolicyType(policy *policymodel.Policy) config.PolicyType {
+	switch policy.PolicyType {
+	case policymodel.Policy_INGRESS:
+		return config.PolicyIngress
+	case policymodel.Policy_EGRESS:
+		return config.PolicyEgress
+	case policymodel.Policy_INGRESS_AND_EGRESS:
+		return config.PolicyAll
+	}
+	if len(policy.EgressRule) > 0 {
+		return config.PolicyAll
+	}
+	return config.PolicyIngress
+}
+
 // getPodsAssignedToPolicy returns all pods that have the given policy assigned.
 func (pp *PolicyProcessor) getPodsAssignedToPolicy(policy *policymodel.Policy) (pods []podmodel.ID) {
 	namespace := policy.Namespace
@@ -450,69 +508,124 @@ func (pp *PolicyProcessor) getPoliciesAssignedToPod(pod *podmodel.Pod) (policies
 	// Select policies that match pod's labels.
 	for _, dataPolicy := range dataPolicies {
 		dataPolicyID := policymodel.GetID(dataPolicy)
-		if len(dataPolicy.IngressRule) == 0 {
-			// If Ingress Rule is an empty array, policy matches the PodSelector.
+		if pp.definesNamedPorts(pod, dataPolicy) {
+			// Container ports of the pod may change the resolution of the named ports.
 			policies[dataPolicyID] = dataPolicy
-		} else {
-			for _, ingressRules := range dataPolicy.IngressRule {
-				for _, ingressRule := range ingressRules.From {
-					matchLabels := []*policymodel.Policy_Label{}
-					matchExpressions := []*policymodel.Policy_LabelSelector_LabelExpression{}
-
-					if ingressRule.Pods != nil {
-						matchLabels = ingressRule.Pods.MatchLabel
-						matchExpressions = ingressRule.Pods.MatchExpression
-					}
-					isMatchPodSelector := pp.calculateLabelSelectorMatches(pod, matchLabels, matchExpressions, dataPolicy.Namespace)
-
-					if ingressRule.Namespaces != nil {
-						matchLabels = ingressRule.Namespaces.MatchLabel
-						matchExpressions = ingressRule.Namespaces.MatchExpression
-					}
+		}
+		if pp.isSelectedByPolicy(pod, dataPolicy) {
+			// Policy is applied to the pod itself.
+			policies[dataPolicyID] = dataPolicy
+		}
+		for _, ingressRules := range dataPolicy.IngressRule {
+			for _, ingressRule := range ingressRules.From {
+				matchLabels := []*policymodel.Policy_Label{}
+				matchExpressions := []*policymodel.Policy_LabelSelector_LabelExpression{}
+
+				if ingressRule.Pods != nil {
+					matchLabels = ingressRule.Pods.MatchLabel
+					matchExpressions = ingressRule.Pods.MatchExpression
+				}
+				isMatchPodSelector := pp.calculateLabelSelectorMatches(pod, matchLabels, matchExpressions, dataPolicy.Namespace)
 
-					isMatchNamespaceSelector := pp.isNamespaceMatchLabel(pod, matchLabels)
-					if !isMatchPodSelector && !isMatchNamespaceSelector {
-						continue
-					}
+				if ingressRule.Namespaces != nil {
+					matchLabels = ingressRule.Namespaces.MatchLabel
+					matchExpressions = ingressRule.Namespaces.MatchExpression
+				}
 
-					policies[dataPolicyID] = dataPolicy
+				isMatchNamespaceSelector := pp.isNamespaceMatchLabel(pod, matchLabels)
+				if !isMatchPodSelector && !isMatchNamespaceSelector {
+					continue
 				}
+
+				policies[dataPolicyID] = dataPolicy
 			}
 		}
 
-		if len(dataPolicy.EgressRule) == 0 {
-			// If Egress Rule is an empty array, policy matches the PodSelector.
-			policies[dataPolicyID] = dataPolicy
-		} else {
-			for _, egressRules := range dataPolicy.EgressRule {
-				for _, egressRule := range egressRules.To {
-					matchLabels := []*policymodel.Policy_Label{}
-					matchExpressions := []*policymodel.Policy_LabelSelector_LabelExpression{}
+		for _, egressRules := range dataPolicy.EgressRule {
+			for _, egressRule := range egressRules.To {
+				matchLabels := []*policymodel.Policy_Label{}
+				matchExpressions := []*policymodel.Policy_LabelSelector_LabelExpression{}
 
-					if egressRule.Pods != nil {
-						matchLabels = egressRule.Pods.MatchLabel
-						matchExpressions = egressRule.Pods.MatchExpression
-					}
-					isMatchPodSelector := pp.calculateLabelSelectorMatches(pod, matchLabels, matchExpressions, dataPolicy.Namespace)
-
-					if egressRule.Namespaces != nil {
-						matchLabels = egressRule.Namespaces.MatchLabel
-						matchExpressions = egressRule.Namespaces.MatchExpression
-					}
-					isMatchNamespaceSelector := pp.isNamespaceMatchLabel(pod, matchLabels)
+				if egressRule.Pods != nil {
+					matchLabels = egressRule.Pods.MatchLabel
+					matchExpressions = egressRule.Pods.MatchExpression
+				}
+				isMatchPodSelector := pp.calculateLabelSelectorMatches(pod, matchLabels, matchExpressions, dataPolicy.Namespace)
 
-					if !isMatchPodSelector && !isMatchNamespaceSelector {
-						continue
-					}
+				if egressRule.Namespaces != nil {
+					matchLabels = egressRule.Namespaces.MatchLabel
+					matchExpressions = egressRule.Namespaces.MatchExpression
+				}
+				isMatchNamespaceSelector := pp.isNamespaceMatchLabel(pod, matchLabels)
 
-					policies[dataPolicyID] = dataPolicy
+				if !isMatchPodSelector && !isMatchNamespaceSelector {
+					continue
 				}
+
+				policies[dataPolicyID] = dataPolicy
 			}
 		}
 	}
 	return policies
 }
 
+// isSelectedByPolicy returns true if the given pod is selected by the pod selector
+// of the policy, i.e. the policy is applied to the pod.
+func (pp *PolicyProcessor) isSelectedByPolicy(pod *podmodel.Pod, policy *policymodel.Policy) bool {
+	return pod.Namespace == policy.Namespace &&
+		pp.calculateLabelSelectorMatches(pod, policy.GetPods().GetMatchLabel(),
+			policy.GetPods().GetMatchExpression(), policy.Namespace)
+}
+
+// podPolicyDataChanged returns true if the pod data relevant for policies
+// (IP address, labels and container ports) differ between the two versions of the pod.
+func podPolicyDataChanged(oldPod, newPod *podmodel.Pod) bool {
+	if oldPod.IpAddress != newPod.IpAddress {
+		return true
+	}
+	if len(oldPod.Label) != len(newPod.Label) {
+		return true
+	}
+	oldLabels := make(map[string]string)
+	for _, label := range oldPod.Label {
+		oldLabels[label.Key] = label.Value
+	}
+	for _, label := range newPod.Label {
+		if value, hasLabel := oldLabels[label.Key]; !hasLabel || value != label.Value {
+			return true
+		}
+	}
+	if len(oldPod.Container) != len(newPod.Container) {
+		return true
+	}
+	for idx := range oldPod.Container {
+		if !proto.Equal(oldPod.Container[idx], newPod.Container[idx]) {
+			return true
+		}
+	}
+	return false
+}
+
+// definesNamedPorts returns true if named ports of the given policy may be defined
+// by the given pod which is selected by the policy itself (ingress) or is a possible
+// destination of an egress rule without peers. Pods selected as peers of the policy
+// rules are handled separately.
+func (pp *PolicyProcessor) definesNamedPorts(pod *podmodel.Pod, policy *policymodel.Policy) bool {
+	for _, ingressRule := range policy.IngressRule {
+		if hasNamedPort(ingressRule.Port) && pod.Namespace == policy.Namespace &&
+			pp.calculateLabelSelectorMatches(pod, policy.GetPods().GetMatchLabel(),
+				policy.GetPods().GetMatchExpression(), policy.Namespace) {
+			return true
+		}
+	}
+	for _, egressRule := range policy.EgressRule {
+		if hasNamedPort(egressRule.Port) && len(egressRule.To) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // getPoliciesAssignedToNamespace returns all policies currently assigned to a namespace.
 func (pp *PolicyProcessor) getPoliciesAssignedToNamespace(ns *nsmodel.Namespace) (policies map[policymodel.ID]*policymodel.Policy) {
 	policies = make(map[policymodel.ID]*policymodel.Policy)
@@ -1 +1,347 @@
+/*
+ * // Copyright (c) 2018 Cisco and/or its affiliates.
+ * //
+ * // Licensed under the Apache License, Version 2.0 (the "License");
+ * // you may not use this file except in compliance with the License.
+ * // You may obtain a copy of the License at:
+ * //
+ * //     http://www.apache.org/licenses/LICENSE-2.0
+ * //
+ * // Unless required by applicable law or agreed to in writing, software
+ * // distributed under the License is distributed on an "AS IS" BASIS,
+ * // WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * // See the License for the specific language governing permissions and
+ * // limitations under the License.
+ */
+
 package processor
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/onsi/gomega"
+
+	. "github.com/contiv/vpp/mock/policycache"
+	"github.com/contiv/vpp/plugins/ksr/model/custompolicy"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
+	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
+	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
+	config "github.com/contiv/vpp/plugins/policy/configurator"
+)
+
+func TestGetPolicyType(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	egressRules := []*policymodel.Policy_EgressRule{
+		{Port: []*policymodel.Policy_Port{{Protocol: policymodel.Policy_Port_TCP}}},
+	}
+
+	// explicit policyTypes are respected
+	gomega.Expect(getPolicyType(&policymodel.Policy{PolicyType: policymodel.Policy_INGRESS,
+		EgressRule: egressRules})).To(gomega.BeEquivalentTo(config.PolicyIngress))
+	gomega.Expect(getPolicyType(&policymodel.Policy{PolicyType: policymodel.Policy_EGRESS})).
+		To(gomega.BeEquivalentTo(config.PolicyEgress))
+	gomega.Expect(getPolicyType(&policymodel.Policy{PolicyType: policymodel.Policy_INGRESS_AND_EGRESS})).
+		To(gomega.BeEquivalentTo(config.PolicyAll))
+
+	// without policyTypes, egress applies only if there are egress rules
+	gomega.Expect(getPolicyType(&policymodel.Policy{})).To(gomega.BeEquivalentTo(config.PolicyIngress))
+	gomega.Expect(getPolicyType(&policymodel.Policy{EgressRule: egressRules})).
+		To(gomega.BeEquivalentTo(config.PolicyAll))
+}
+
+func TestConvertIPBlock(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	block, err := convertIPBlock(&policymodel.Policy_Peer_IPBlock{
+		Cidr:   "172.17.0.0/16",
+		Except: []string{"172.17.1.0/24", "172.17.2.0/24"},
+	})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(block.Network.String()).To(gomega.Equal("172.17.0.0/16"))
+	gomega.Expect(block.Except).To(gomega.HaveLen(2))
+	gomega.Expect(block.Except[1].String()).To(gomega.Equal("172.17.2.0/24"))
+
+	// invalid addresses are reported instead of being silently ignored
+	_, err = convertIPBlock(&policymodel.Policy_Peer_IPBlock{Cidr: "172.17.0.0"})
+	gomega.Expect(err).ToNot(gomega.BeNil())
+	_, err = convertIPBlock(&policymodel.Policy_Peer_IPBlock{
+		Cidr:   "172.17.0.0/16",
+		Except: []string{"172.17.1.0/33"},
+	})
+	gomega.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestConvertNamedPorts(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	pod1 := podmodel.ID{Name: "web1", Namespace: "default"}
+	pod2 := podmodel.ID{Name: "web2", Namespace: "default"}
+	cache := NewMockPolicyCache()
+	cache.AddPodConfig(pod1, "10.1.1.1")
+	cache.AddPodConfig(pod2, "10.1.1.2")
+	_, pod1Data := cache.LookupPod(pod1)
+	pod1Data.Container = []*podmodel.Pod_Container{
+		{Name: "nginx", Port: []*podmodel.Pod_Container_Port{
+			{Name: "http", ContainerPort: 8080},
+			{Name: "dns", ContainerPort: 53, Protocol: podmodel.Pod_Container_Port_UDP},
+		}},
+	}
+	_, pod2Data := cache.LookupPod(pod2)
+	pod2Data.Container = []*podmodel.Pod_Container{
+		{Name: "nginx", Port: []*podmodel.Pod_Container_Port{{Name: "http", ContainerPort: 9090}}},
+		{Name: "sidecar", Port: []*podmodel.Pod_Container_Port{{Name: "metrics", ContainerPort: 9100}}},
+	}
+
+	pp := &PolicyProcessor{
+		Deps: Deps{
+			Log:   logrus.DefaultLogger(),
+			Cache: cache,
+		},
+	}
+	namedPort := func(protocol policymodel.Policy_Port_Protocol, name string) *policymodel.Policy_Port {
+		return &policymodel.Policy_Port{
+			Protocol: protocol,
+			Port: &policymodel.Policy_Port_PortNameOrNumber{
+				Type: policymodel.Policy_Port_PortNameOrNumber_NAME,
+				Name: name,
+			},
+		}
+	}
+
+	// names are resolved against all the given pods, numbers are kept
+	ports := pp.convertPorts([]*policymodel.Policy_Port{
+		namedPort(policymodel.Policy_Port_TCP, "http"),
+		{Protocol: policymodel.Policy_Port_TCP, Port: &policymodel.Policy_Port_PortNameOrNumber{Number: 443}},
+	}, []podmodel.ID{pod1, pod2})
+	gomega.Expect(ports).To(gomega.ConsistOf(
+		config.Port{Protocol: config.TCP, Number: 8080},
+		config.Port{Protocol: config.TCP, Number: 9090},
+		config.Port{Protocol: config.TCP, Number: 443}))
+
+	// the protocol has to match as well
+	ports = pp.convertPorts([]*policymodel.Policy_Port{
+		namedPort(policymodel.Policy_Port_UDP, "dns"),
+		namedPort(policymodel.Policy_Port_TCP, "dns"),
+	}, []podmodel.ID{pod1, pod2})
+	gomega.Expect(ports).To(gomega.Equal([]config.Port{{Protocol: config.UDP, Number: 53}}))
+
+	// undefined names are left out
+	ports = pp.convertPorts([]*policymodel.Policy_Port{
+		namedPort(policymodel.Policy_Port_TCP, "metrics"),
+	}, []podmodel.ID{pod1})
+	gomega.Expect(ports).To(gomega.BeEmpty())
+
+	// missing port matches all ports of the protocol
+	ports = pp.convertPorts([]*policymodel.Policy_Port{{Protocol: policymodel.Policy_Port_UDP}}, nil)
+	gomega.Expect(ports).To(gomega.Equal([]config.Port{{Protocol: config.UDP, Number: 0}}))
+}
+
+func TestPodPolicyDataChanged(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	pod := func(ip string, labels map[string]string, port int32) *podmodel.Pod {
+		pod := &podmodel.Pod{Name: "web", Namespace: "default", IpAddress: ip}
+		for key, value := range labels {
+			pod.Label = append(pod.Label, &podmodel.Pod_Label{Key: key, Value: value})
+		}
+		pod.Container = []*podmodel.Pod_Container{
+			{Name: "nginx", Port: []*podmodel.Pod_Container_Port{{Name: "http", ContainerPort: port}}},
+		}
+		return pod
+	}
+	labels := map[string]string{"app": "web", "tier": "frontend"}
+	oldPod := pod("10.1.1.1", labels, 80)
+
+	// unrelated changes (e.g. the host IP, order of labels) are ignored
+	newPod := pod("10.1.1.1", labels, 80)
+	newPod.HostIpAddress = "192.168.16.1"
+	newPod.Label[0], newPod.Label[1] = newPod.Label[1], newPod.Label[0]
+	gomega.Expect(podPolicyDataChanged(oldPod, newPod)).To(gomega.BeFalse())
+
+	gomega.Expect(podPolicyDataChanged(oldPod, pod("10.1.1.2", labels, 80))).To(gomega.BeTrue())
+	gomega.Expect(podPolicyDataChanged(oldPod, pod("10.1.1.1", map[string]string{"app": "web"}, 80))).
+		To(gomega.BeTrue())
+	gomega.Expect(podPolicyDataChanged(oldPod, pod("10.1.1.1", map[string]string{"app": "web", "tier": "backend"}, 80))).
+		To(gomega.BeTrue())
+	gomega.Expect(podPolicyDataChanged(oldPod, pod("10.1.1.1", labels, 8080))).To(gomega.BeTrue())
+}
+
+func TestIsSelectedByPolicy(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	pp := &PolicyProcessor{
+		Deps: Deps{
+			Log:   logrus.DefaultLogger(),
+			Cache: NewMockPolicyCache(),
+		},
+	}
+	pod := &podmodel.Pod{Name: "web", Namespace: "default",
+		Label: []*podmodel.Pod_Label{{Key: "app", Value: "web"}}}
+
+	// deny-all policy selecting all pods of the namespace
+	denyAll := &policymodel.Policy{Name: "deny-all", Namespace: "default"}
+	gomega.Expect(pp.isSelectedByPolicy(pod, denyAll)).To(gomega.BeTrue())
+	denyAll.Namespace = "other"
+	gomega.Expect(pp.isSelectedByPolicy(pod, denyAll)).To(gomega.BeFalse())
+
+	// policy selecting pods by labels
+	policy := &policymodel.Policy{Name: "db", Namespace: "default",
+		Pods: &policymodel.Policy_LabelSelector{
+			MatchLabel: []*policymodel.Policy_Label{{Key: "app", Value: "db"}},
+		}}
+	gomega.Expect(pp.isSelectedByPolicy(pod, policy)).To(gomega.BeFalse())
+	policy.Pods.MatchLabel[0].Value = "web"
+	gomega.Expect(pp.isSelectedByPolicy(pod, policy)).To(gomega.BeTrue())
+}
+
+func TestIsNodeSelected(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	nodeLabels := []*nodemodel.Node_Label{{Key: "role", Value: "edge"}, {Key: "zone", Value: "a"}}
+	expr := func(key string, op custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_Operator,
+		values ...string) *custompolicy.CustomNetworkPolicy_LabelSelector {
+		return &custompolicy.CustomNetworkPolicy_LabelSelector{
+			MatchExpression: []*custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression{
+				{Key: key, Operator: op, Value: values},
+			},
+		}
+	}
+
+	// empty selector matches all nodes
+	gomega.Expect(isNodeSelected(nodeLabels, nil)).To(gomega.BeTrue())
+	gomega.Expect(isNodeSelected(nil, &custompolicy.CustomNetworkPolicy_LabelSelector{})).To(gomega.BeTrue())
+
+	// match labels
+	selector := &custompolicy.CustomNetworkPolicy_LabelSelector{
+		MatchLabel: []*custompolicy.CustomNetworkPolicy_Label{{Key: "role", Value: "edge"}},
+	}
+	gomega.Expect(isNodeSelected(nodeLabels, selector)).To(gomega.BeTrue())
+	selector.MatchLabel[0].Value = "worker"
+	gomega.Expect(isNodeSelected(nodeLabels, selector)).To(gomega.BeFalse())
+
+	// match expressions
+	in := custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_IN
+	notIn := custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_NOT_IN
+	exists := custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_EXISTS
+	doesNotExist := custompolicy.CustomNetworkPolicy_LabelSelector_LabelExpression_DOES_NOT_EXIST
+	gomega.Expect(isNodeSelected(nodeLabels, expr("zone", in, "a", "b"))).To(gomega.BeTrue())
+	gomega.Expect(isNodeSelected(nodeLabels, expr("zone", in, "b"))).To(gomega.BeFalse())
+	gomega.Expect(isNodeSelected(nodeLabels, expr("zone", notIn, "b"))).To(gomega.BeTrue())
+	gomega.Expect(isNodeSelected(nodeLabels, expr("zone", notIn, "a"))).To(gomega.BeFalse())
+	gomega.Expect(isNodeSelected(nodeLabels, expr("role", exists))).To(gomega.BeTrue())
+	gomega.Expect(isNodeSelected(nodeLabels, expr("gpu", exists))).To(gomega.BeFalse())
+	gomega.Expect(isNodeSelected(nodeLabels, expr("gpu", doesNotExist))).To(gomega.BeTrue())
+	gomega.Expect(isNodeSelected(nodeLabels, expr("role", doesNotExist))).To(gomega.BeFalse())
+}
+
+func TestConvertCustomPolicy(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	pp := &PolicyProcessor{
+		Deps: Deps{
+			Log:   logrus.DefaultLogger(),
+			Cache: NewMockPolicyCache(),
+		},
+	}
+	policy := &custompolicy.CustomNetworkPolicy{
+		Name: "allow-ssh",
+		IngressRule: []*custompolicy.CustomNetworkPolicy_Rule{
+			{
+				Port: []*custompolicy.CustomNetworkPolicy_Port{{Port: 22}},
+				Peer: []*custompolicy.CustomNetworkPolicy_IPBlock{
+					{Cidr: "192.168.0.0/16", Except: []string{"192.168.1.0/24"}},
+					{Cidr: "invalid"},
+				},
+			},
+		},
+		EgressRule: []*custompolicy.CustomNetworkPolicy_Rule{
+			{Port: []*custompolicy.CustomNetworkPolicy_Port{
+				{Protocol: custompolicy.CustomNetworkPolicy_Port_UDP, Port: 53}}},
+		},
+	}
+
+	contivPolicy := pp.convertCustomPolicy(policy)
+	gomega.Expect(contivPolicy.ID).To(gomega.Equal(policymodel.ID{Name: "allow-ssh"}))
+	gomega.Expect(contivPolicy.Type).To(gomega.BeEquivalentTo(config.PolicyAll))
+	gomega.Expect(contivPolicy.Matches).To(gomega.HaveLen(2))
+
+	ingress := contivPolicy.Matches[0]
+	gomega.Expect(ingress.Type).To(gomega.BeEquivalentTo(config.MatchIngress))
+	gomega.Expect(ingress.Pods).To(gomega.BeNil())
+	gomega.Expect(ingress.IPBlocks).To(gomega.HaveLen(1))
+	gomega.Expect(ingress.IPBlocks[0].Network.String()).To(gomega.Equal("192.168.0.0/16"))
+	gomega.Expect(ingress.Ports).To(gomega.Equal([]config.Port{{Protocol: config.TCP, Number: 22}}))
+
+	// rule without peers matches all destinations
+	egress := contivPolicy.Matches[1]
+	gomega.Expect(egress.Type).To(gomega.BeEquivalentTo(config.MatchEgress))
+	gomega.Expect(egress.Pods).To(gomega.BeNil())
+	gomega.Expect(egress.IPBlocks).To(gomega.BeNil())
+	gomega.Expect(egress.Ports).To(gomega.Equal([]config.Port{{Protocol: config.UDP, Number: 53}}))
+
+	// explicit policy type
+	policy.PolicyType = custompolicy.CustomNetworkPolicy_INGRESS
+	gomega.Expect(getCustomPolicyType(policy)).To(gomega.BeEquivalentTo(config.PolicyIngress))
+}
+
+func TestFQDNPeers(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	pp := &PolicyProcessor{
+		Deps: Deps{
+			Log:            logrus.DefaultLogger(),
+			Cache:          NewMockPolicyCache(),
+			FQDNAddressTTL: time.Minute,
+		},
+	}
+	pp.Init()
+	policy := &custompolicy.CustomNetworkPolicy{
+		Name: "allow-api",
+		EgressRule: []*custompolicy.CustomNetworkPolicy_Rule{
+			{
+				Peer: []*custompolicy.CustomNetworkPolicy_IPBlock{{Cidr: "10.0.0.0/8"}},
+				Fqdn: []string{"api.example.com"},
+			},
+		},
+	}
+	pp.watchFQDNs(map[string]struct{}{"api.example.com": {}})
+	gomega.Expect(pp.FQDNs()).To(gomega.Equal([]string{"api.example.com"}))
+
+	// name not resolved yet matches nothing
+	egress := pp.convertCustomPolicy(policy).Matches[0]
+	gomega.Expect(egress.IPBlocks).To(gomega.HaveLen(1))
+
+	// resolved addresses are matched as single-address blocks
+	now := time.Now()
+	gomega.Expect(pp.mergeFQDNs(map[string][]net.IP{
+		"api.example.com":   {net.ParseIP("1.2.3.5"), net.ParseIP("1.2.3.4"), net.ParseIP("2001:db8::1")},
+		"other.example.com": {net.ParseIP("5.6.7.8")},
+	}, now)).To(gomega.BeTrue())
+	egress = pp.convertCustomPolicy(policy).Matches[0]
+	gomega.Expect(egress.IPBlocks).To(gomega.HaveLen(4))
+	gomega.Expect(egress.IPBlocks[1].Network.String()).To(gomega.Equal("1.2.3.4/32"))
+	gomega.Expect(egress.IPBlocks[2].Network.String()).To(gomega.Equal("1.2.3.5/32"))
+	gomega.Expect(egress.IPBlocks[3].Network.String()).To(gomega.Equal("2001:db8::1/128"))
+
+	// the same set resolved again is not a change
+	gomega.Expect(pp.mergeFQDNs(map[string][]net.IP{
+		"api.example.com": {net.ParseIP("1.2.3.4"), net.ParseIP("1.2.3.5"), net.ParseIP("2001:db8::1")},
+	}, now.Add(30*time.Second))).To(gomega.BeFalse())
+
+	// addresses not returned for FQDNAddressTTL expire
+	gomega.Expect(pp.mergeFQDNs(map[string][]net.IP{
+		"api.example.com": {net.ParseIP("1.2.3.4")},
+	}, now.Add(80*time.Second))).To(gomega.BeFalse())
+	gomega.Expect(pp.mergeFQDNs(map[string][]net.IP{
+		"api.example.com": {net.ParseIP("1.2.3.4")},
+	}, now.Add(2*time.Minute))).To(gomega.BeTrue())
+	gomega.Expect(pp.lookupFQDN("api.example.com")).To(gomega.Equal([]net.IP{net.ParseIP("1.2.3.4")}))
+
+	// names no longer referenced are dropped
+	pp.watchFQDNs(map[string]struct{}{})
+	gomega.Expect(pp.FQDNs()).To(gomega.BeEmpty())
+}
@@ -17,13 +17,18 @@
 package acl
 
 import (
+	"bytes"
+	"fmt"
 	"net"
+	"strings"
 
+	govpp "git.fd.io/govpp.git/api"
 	"github.com/golang/protobuf/proto"
 
 	"github.com/ligato/cn-infra/logging"
 	"github.com/ligato/vpp-agent/clientv1/linux"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins"
+	acl_api "github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/acl"
 	vpp_acl "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
 
 	"github.com/contiv/vpp/plugins/contiv"
@@ -40,6 +45,10 @@ type Renderer struct {
 
 	cache         *cache.ContivRuleCache
 	podInterfaces PodInterfaces
+
+	// interfaces with all traffic denied until rules are rendered for them
+	// (default-deny bootstrap mode)
+	bootstrapIfs map[string]struct{}
 }
 
 // Deps lists dependencies of Renderer.
@@ -47,8 +56,9 @@ type Deps struct {
 	Log           logging.Logger
 	LogFactory    logging.LogFactory /* optional */
 	Contiv        contiv.API         /* for GetIfName() */
-	VPP           defaultplugins.API /* for DumpACLs() */
+	VPP           defaultplugins.API /* for DumpACLs() and GetSwIfIndexes() */
 	ACLTxnFactory func() (dsl linux.DataChangeDSL)
+	GoVPPChan     *govpp.Channel /* optional, for DumpRuleStats() and the ACL dump of RESYNC */
 }
 
 // RendererTxn represents a single transaction of Renderer.
@@ -81,9 +91,45 @@ func (r *Renderer) Init() error {
 	}
 	r.cache.Init()
 	r.podInterfaces = make(PodInterfaces)
+	r.bootstrapIfs = make(map[string]struct{})
 	return nil
 }
 
+// BootstrapDeny denies all traffic of the given pod until the rules of the pod
+// are rendered by a transaction (default-deny bootstrap mode).
+func (r *Renderer) BootstrapDeny(pod podmodel.ID) error {
+	ifName, found := r.Contiv.GetIfName(pod.Namespace, pod.Name)
+	if !found {
+		r.Log.WithField("pod", pod).Warn("Unable to get the interface assigned to the Pod")
+		return nil
+	}
+	r.Log.WithField("pod", pod).Debug("Denying all traffic of the pod until its rules are rendered")
+	r.podInterfaces[pod] = ifName
+	r.bootstrapIfs[ifName] = struct{}{}
+
+	txn := r.NewTxn(false).(*RendererTxn)
+	txn.config[ifName] = &InterfaceConfig{ingress: txn.denyAllRules(), egress: txn.denyAllRules()}
+	return txn.Commit()
+}
+
+// CancelBootstrapDeny removes the rules installed by BootstrapDeny for a removed pod
+// whose rules have not been rendered yet.
+func (r *Renderer) CancelBootstrapDeny(pod podmodel.ID) error {
+	ifName, found := r.podInterfaces[pod]
+	if !found {
+		return nil
+	}
+	if _, bootstrap := r.bootstrapIfs[ifName]; !bootstrap {
+		return nil
+	}
+	delete(r.bootstrapIfs, ifName)
+	delete(r.podInterfaces, pod)
+
+	txn := r.NewTxn(false).(*RendererTxn)
+	txn.config[ifName] = &InterfaceConfig{ingress: []*renderer.ContivRule{}, egress: []*renderer.ContivRule{}}
+	return txn.Commit()
+}
+
 // NewTxn starts a new transaction. The rendering executes only after Commit()
 // is called. Rollback is not yet supported however.
 // If <resync> is enabled, the supplied configuration will completely
@@ -113,6 +159,10 @@ func (art *RendererTxn) Render(pod podmodel.ID, podIP *net.IPNet, ingress []*ren
 
 	// Get the target interface.
 	ifName, found := art.renderer.podInterfaces[pod] /* first query local cache */
+	if !found && pod == renderer.HostEndpoint {
+		ifName = art.renderer.Contiv.GetHostInterconnectIfName()
+		found = ifName != ""
+	}
 	if !found {
 		ifName, found = art.renderer.Contiv.GetIfName(pod.Namespace, pod.Name) /* next query Contiv plugin */
 		if !found {
@@ -121,6 +171,11 @@ func (art *RendererTxn) Render(pod podmodel.ID, podIP *net.IPNet, ingress []*ren
 		}
 	}
 	art.renderer.podInterfaces[pod] = ifName
+	delete(art.renderer.bootstrapIfs, ifName)
+
+	// Filter out rules that cannot be expressed with ACLs of the VPP agent.
+	ingress = art.filterUnsupported(pod, ingress)
+	egress = art.filterUnsupported(pod, egress)
 
 	// Empty list of rules should allow all the traffic.
 	// We need to add allow-all rules explicitly so that for each allowed SYN packet
@@ -147,6 +202,8 @@ func (art *RendererTxn) Commit() error {
 		if err != nil {
 			return err
 		}
+		art.restoreDumpedRules(dumpIngress, true)
+		art.restoreDumpedRules(dumpEgress, false)
 		err = art.cache.Resync(dumpIngress, dumpEgress)
 		if err != nil {
 			return err
@@ -167,6 +224,10 @@ func (art *RendererTxn) Commit() error {
 		txnInterfaces := txn.AllInterfaces()
 		emptyList := []*renderer.ContivRule{}
 		for ifName := range art.cache.AllInterfaces() {
+			if _, bootstrap := art.renderer.bootstrapIfs[ifName]; bootstrap {
+				// keep denying until the rules of the pod are rendered
+				continue
+			}
 			if !txnInterfaces.Has(ifName) {
 				txn.Update(ifName, emptyList, emptyList)
 			}
@@ -223,6 +284,45 @@ func (art *RendererTxn) allowAllRules() []*renderer.ContivRule {
 	return []*renderer.ContivRule{ruleTCPAny, ruleUDPAny}
 }
 
+// denyAllRules returns Contiv rules that deny all the traffic (other protocols
+// are denied by the implicit deny rule of the ACL).
+func (art *RendererTxn) denyAllRules() []*renderer.ContivRule {
+	ruleTCPAny := &renderer.ContivRule{
+		ID:          "TCP:DENY",
+		Action:      renderer.ActionDeny,
+		SrcNetwork:  &net.IPNet{},
+		DestNetwork: &net.IPNet{},
+		Protocol:    renderer.TCP,
+	}
+	ruleUDPAny := &renderer.ContivRule{
+		ID:          "UDP:DENY",
+		Action:      renderer.ActionDeny,
+		SrcNetwork:  &net.IPNet{},
+		DestNetwork: &net.IPNet{},
+		Protocol:    renderer.UDP,
+	}
+	return []*renderer.ContivRule{ruleTCPAny, ruleUDPAny}
+}
+
+// filterUnsupported removes SCTP rules from the list. The VPP agent can install
+// ACL rules only for TCP, UDP and ICMP, rules of any other protocol would match
+// all IP traffic instead. SCTP traffic is therefore denied by the implicit deny
+// rule of the ACLs installed for the pod.
+func (art *RendererTxn) filterUnsupported(pod podmodel.ID, rules []*renderer.ContivRule) []*renderer.ContivRule {
+	filtered := []*renderer.ContivRule{}
+	for _, rule := range rules {
+		if rule.Protocol == renderer.SCTP {
+			art.renderer.Log.WithFields(logging.Fields{
+				"pod":  pod,
+				"rule": rule,
+			}).Warn("Skipping SCTP rule not supported by ACLs")
+			continue
+		}
+		filtered = append(filtered, rule)
+	}
+	return filtered
+}
+
 // Remove lists with no rules since empty list of rules is equivalent to no ACL.
 func (art *RendererTxn) filterEmpty(changes []*cache.TxnChange) []*cache.TxnChange {
 	filtered := []*cache.TxnChange{}
@@ -241,11 +341,21 @@ func (art *RendererTxn) dumpVppACLConfig() (ingress, egress []*cache.ContivRuleL
 	ingress = []*cache.ContivRuleList{}
 	egress = []*cache.ContivRuleList{}
 
-	aclDump, err := art.vpp.DumpACL()
+	aclDump, err := art.dumpACLs()
 	if err != nil {
 		return ingress, egress, err
 	}
 	for _, acl := range aclDump {
+		if strings.HasPrefix(acl.AclName, contiv.PodConfigACLNamePrefix) {
+			// applied via the pod network config API, not managed by the renderer
+			continue
+		}
+		if !strings.HasPrefix(acl.AclName, cache.Ingress.String()+"-") &&
+			!strings.HasPrefix(acl.AclName, cache.Egress.String()+"-") {
+			// not created by the renderer, skip
+			art.Log.WithField("aclName", acl.AclName).Warn("Skipping ACL not created by the renderer")
+			continue
+		}
 		isIngress := true
 		ruleList := &cache.ContivRuleList{}
 		// ID
@@ -393,6 +503,208 @@ func (art *RendererTxn) dumpVppACLConfig() (ingress, egress []*cache.ContivRuleL
 	return ingress, egress, nil
 }
 
+// dumpACLs dumps the ACLs installed in VPP. If the GoVPP channel is available,
+// the ACLs are dumped via the binary API directly - the ACL dump of the VPP agent
+// returns the ACLs without their names and reports the first egress ACL of each
+// interface as ingress.
+func (art *RendererTxn) dumpACLs() ([]*vpp_acl.AccessLists_Acl, error) {
+	goVPPChan := art.renderer.GoVPPChan
+	if goVPPChan == nil {
+		return art.vpp.DumpACL()
+	}
+
+	acls := []*vpp_acl.AccessLists_Acl{}
+	aclByIndex := make(map[uint32]*vpp_acl.AccessLists_Acl)
+	reqCtx := goVPPChan.SendMultiRequest(&acl_api.ACLDump{ACLIndex: ^uint32(0)})
+	for {
+		msg := &acl_api.ACLDetails{}
+		stop, err := reqCtx.ReceiveReply(msg)
+		if stop {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		acl := &vpp_acl.AccessLists_Acl{
+			AclName:    string(bytes.Trim(msg.Tag, "\x00")),
+			Interfaces: &vpp_acl.AccessLists_Acl_Interfaces{},
+		}
+		for i := range msg.R {
+			acl.Rules = append(acl.Rules, aclRuleFromVpp(&msg.R[i]))
+		}
+		acls = append(acls, acl)
+		aclByIndex[msg.ACLIndex] = acl
+	}
+
+	swIfIndexes := art.vpp.GetSwIfIndexes()
+	if swIfIndexes == nil {
+		return nil, fmt.Errorf("interface index of the VPP agent is not available")
+	}
+	reqCtx = goVPPChan.SendMultiRequest(&acl_api.ACLInterfaceListDump{SwIfIndex: ^uint32(0)})
+	for {
+		msg := &acl_api.ACLInterfaceListDetails{}
+		stop, err := reqCtx.ReceiveReply(msg)
+		if stop {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ifName, _, found := swIfIndexes.LookupName(msg.SwIfIndex)
+		if !found {
+			continue
+		}
+		for i, aclIndex := range msg.Acls {
+			acl, known := aclByIndex[aclIndex]
+			if !known {
+				continue
+			}
+			if i < int(msg.NInput) {
+				acl.Interfaces.Ingress = append(acl.Interfaces.Ingress, ifName)
+			} else {
+				acl.Interfaces.Egress = append(acl.Interfaces.Egress, ifName)
+			}
+		}
+	}
+	return acls, nil
+}
+
+// aclRuleFromVpp converts a rule of an ACL dumped via the binary API into the model
+// of the VPP agent. The rules installed in VPP have no names.
+func aclRuleFromVpp(vppRule *acl_api.ACLRule) *vpp_acl.AccessLists_Acl_Rule {
+	const (
+		protoTCP = 6
+		protoUDP = 17
+	)
+	aclRule := &vpp_acl.AccessLists_Acl_Rule{}
+	aclRule.Actions = &vpp_acl.AccessLists_Acl_Rule_Actions{}
+	switch vppRule.IsPermit {
+	case 0:
+		aclRule.Actions.AclAction = vpp_acl.AclAction_DENY
+	case 1:
+		aclRule.Actions.AclAction = vpp_acl.AclAction_PERMIT
+	default:
+		aclRule.Actions.AclAction = vpp_acl.AclAction_REFLECT
+	}
+	ipRule := &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule{}
+	ipRule.Ip = &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Ip{
+		SourceNetwork:      vppNetwork(vppRule.SrcIPAddr, vppRule.SrcIPPrefixLen, vppRule.IsIpv6 == 1),
+		DestinationNetwork: vppNetwork(vppRule.DstIPAddr, vppRule.DstIPPrefixLen, vppRule.IsIpv6 == 1),
+	}
+	switch vppRule.Proto {
+	case protoTCP:
+		ipRule.Tcp = &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Tcp{
+			SourcePortRange: &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Tcp_SourcePortRange{
+				LowerPort: uint32(vppRule.SrcportOrIcmptypeFirst),
+				UpperPort: uint32(vppRule.SrcportOrIcmptypeLast),
+			},
+			DestinationPortRange: &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Tcp_DestinationPortRange{
+				LowerPort: uint32(vppRule.DstportOrIcmpcodeFirst),
+				UpperPort: uint32(vppRule.DstportOrIcmpcodeLast),
+			},
+		}
+	case protoUDP:
+		ipRule.Udp = &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Udp{
+			SourcePortRange: &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Udp_SourcePortRange{
+				LowerPort: uint32(vppRule.SrcportOrIcmptypeFirst),
+				UpperPort: uint32(vppRule.SrcportOrIcmptypeLast),
+			},
+			DestinationPortRange: &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Udp_DestinationPortRange{
+				LowerPort: uint32(vppRule.DstportOrIcmpcodeFirst),
+				UpperPort: uint32(vppRule.DstportOrIcmpcodeLast),
+			},
+		}
+	default:
+		ipRule.Other = &vpp_acl.AccessLists_Acl_Rule_Matches_IpRule_Other{Protocol: uint32(vppRule.Proto)}
+	}
+	aclRule.Matches = &vpp_acl.AccessLists_Acl_Rule_Matches{IpRule: ipRule}
+	return aclRule
+}
+
+// vppNetwork returns the network of an ACL rule dumped via the binary API in the CIDR
+// notation, empty string for a rule matching any address.
+func vppNetwork(addr []byte, prefixLen uint8, isIPv6 bool) string {
+	if prefixLen == 0 {
+		return ""
+	}
+	ipNet := &net.IPNet{IP: net.IP(addr[:net.IPv4len]), Mask: net.CIDRMask(int(prefixLen), net.IPv4len*8)}
+	if isIPv6 {
+		ipNet = &net.IPNet{IP: net.IP(addr[:net.IPv6len]), Mask: net.CIDRMask(int(prefixLen), net.IPv6len*8)}
+	}
+	return ipNet.String()
+}
+
+// restoreDumpedRules replaces the rules of the lists dumped from VPP with the rules
+// of the transaction they are equivalent to. VPP does not keep the IDs of the rules,
+// without the replacement the lists would never match the rendered configuration
+// and every RESYNC would re-create all ACLs. Rules of the lists with no equivalent
+// in the transaction get IDs unique to the list, so that they are removed.
+func (art *RendererTxn) restoreDumpedRules(lists []*cache.ContivRuleList, ingress bool) {
+	restored := [][]*renderer.ContivRule{}
+	for _, list := range lists {
+		var rules []*renderer.ContivRule
+		for ifName := range list.Interfaces {
+			config, inTxn := art.config[ifName]
+			if !inTxn {
+				continue
+			}
+			candidate := config.egress
+			if ingress {
+				candidate = config.ingress
+			}
+			if equivalentRules(list.Rules, candidate) {
+				rules = candidate
+				break
+			}
+		}
+		for _, other := range restored {
+			if rules != nil && equivalentRules(rules, other) {
+				// already restored for another list
+				rules = nil
+			}
+		}
+		if rules != nil {
+			list.Rules = rules
+			restored = append(restored, rules)
+			continue
+		}
+		for i, rule := range list.Rules {
+			if rule.ID == "" {
+				rule.ID = fmt.Sprintf("%s/%d", list.ID, i)
+			}
+		}
+	}
+}
+
+// equivalentRules returns true if the given lists contain the same rules,
+// ignoring the IDs of the rules.
+func equivalentRules(a, b []*renderer.ContivRule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Action != b[i].Action || a[i].Protocol != b[i].Protocol ||
+			a[i].SrcPort != b[i].SrcPort || a[i].DestPort != b[i].DestPort ||
+			networkString(a[i].SrcNetwork) != networkString(b[i].SrcNetwork) ||
+			networkString(a[i].DestNetwork) != networkString(b[i].DestNetwork) {
+			return false
+		}
+	}
+	return true
+}
+
+// networkString returns the network in the CIDR notation, empty string for
+// a network matching any address.
+func networkString(ipNet *net.IPNet) string {
+	if ipNet == nil || len(ipNet.IP) == 0 {
+		return ""
+	}
+	if ones, _ := ipNet.Mask.Size(); ones == 0 {
+		return ""
+	}
+	return ipNet.String()
+}
+
 // render Contiv Rule changes into the equivalent ACL configuration changes.
 func (art *RendererTxn) renderChanges(putDsl linux.PutDSL, deleteDsl linux.DeleteDSL, changes []*cache.TxnChange, ingress bool) {
 	for _, change := range changes {
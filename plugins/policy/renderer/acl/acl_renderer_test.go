@@ -21,11 +21,17 @@ import (
 	"strings"
 	"testing"
 
+	govppmock "git.fd.io/govpp.git/adapter/mock"
+	govpp "git.fd.io/govpp.git/core"
 	"github.com/onsi/gomega"
 
 	"github.com/ligato/cn-infra/logging"
 	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/ligato/vpp-agent/idxvpp/nametoidx"
+	acl_api "github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/acl"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/vpe"
 	acl_model "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/ifplugin/ifaceidx"
 
 	. "github.com/contiv/vpp/mock/contiv"
 	. "github.com/contiv/vpp/mock/defaultplugins"
@@ -214,6 +220,24 @@ func allowAll() []*renderer.ContivRule {
 	return []*renderer.ContivRule{ruleTCPAny, ruleUDPAny}
 }
 
+func denyAll() []*renderer.ContivRule {
+	ruleTCPAny := &renderer.ContivRule{
+		ID:          "TCP:DENY",
+		Action:      renderer.ActionDeny,
+		SrcNetwork:  &net.IPNet{},
+		DestNetwork: &net.IPNet{},
+		Protocol:    renderer.TCP,
+	}
+	ruleUDPAny := &renderer.ContivRule{
+		ID:          "UDP:DENY",
+		Action:      renderer.ActionDeny,
+		SrcNetwork:  &net.IPNet{},
+		DestNetwork: &net.IPNet{},
+		Protocol:    renderer.UDP,
+	}
+	return []*renderer.ContivRule{ruleTCPAny, ruleUDPAny}
+}
+
 func TestSingleContivRuleOneInterface(t *testing.T) {
 	gomega.RegisterTestingT(t)
 	logger := logrus.DefaultLogger()
@@ -290,6 +314,78 @@ func TestSingleContivRuleOneInterface(t *testing.T) {
 	gomega.Expect(txnTracker.CommittedTxns).To(gomega.HaveLen(1))
 }
 
+func TestSCTPContivRuleOneInterface(t *testing.T) {
+	gomega.RegisterTestingT(t)
+	logger := logrus.DefaultLogger()
+	logger.SetLevel(logging.DebugLevel)
+	logger.Debug("TestSCTPContivRuleOneInterface")
+
+	// Prepare input data.
+	const (
+		namespace  = "default"
+		pod1Name   = "pod1"
+		pod1IfName = "afpacket1"
+	)
+	pod1 := podmodel.ID{Name: pod1Name, Namespace: namespace}
+
+	ruleSCTP := &renderer.ContivRule{
+		ID:          "permit-s1ap",
+		Action:      renderer.ActionPermit,
+		SrcNetwork:  ipNetwork("192.168.0.0/24"),
+		DestNetwork: ipNetwork(""),
+		Protocol:    renderer.SCTP,
+		SrcPort:     0,
+		DestPort:    36412,
+	}
+	ruleTCP := &renderer.ContivRule{
+		ID:          "deny-http",
+		Action:      renderer.ActionDeny,
+		SrcNetwork:  ipNetwork("192.168.0.0/24"),
+		DestNetwork: ipNetwork(""),
+		Protocol:    renderer.TCP,
+		SrcPort:     0,
+		DestPort:    80,
+	}
+	ingress := []*renderer.ContivRule{}
+	egress := []*renderer.ContivRule{ruleSCTP, ruleTCP}
+	ifSet := cache.NewInterfaceSet(pod1IfName)
+
+	// Prepare mocks.
+	contiv := NewMockContiv()
+	contiv.SetPodIfName(pod1, pod1IfName)
+
+	txnTracker := localclient.NewTxnTracker(nil)
+
+	// Prepare ACL Renderer.
+	aclRenderer := &Renderer{
+		Deps: Deps{
+			Log:           logger,
+			Contiv:        contiv,
+			VPP:           NewMockVppPlugin(),
+			ACLTxnFactory: txnTracker.NewLinuxDataChangeTxn,
+		},
+	}
+	aclRenderer.Init()
+
+	// Execute Renderer transaction.
+	aclRenderer.NewTxn(false).Render(pod1, nil, ingress, egress).Commit()
+
+	// Verify that the SCTP rule was not rendered.
+	gomega.Expect(txnTracker.CommittedTxns).To(gomega.HaveLen(1))
+	putIngress, putEgress, deleted := parseACLOps(txnTracker.CommittedTxns[0].LinuxDataChangeTxn.Ops)
+	gomega.Expect(deleted).To(gomega.HaveLen(0))
+	verifyACL(putIngress.GetACL(pod1IfName), "", ifSet, cache.NewInterfaceSet(), allowAll()...)
+	verifyACL(putEgress.GetACL(pod1IfName), "", cache.NewInterfaceSet(), ifSet, ruleTCP)
+
+	// Only SCTP rules - nothing to render.
+	aclRenderer.NewTxn(false).Render(pod1, nil, ingress, []*renderer.ContivRule{ruleSCTP}).Commit()
+	gomega.Expect(txnTracker.CommittedTxns).To(gomega.HaveLen(2))
+	putIngress, putEgress, deleted = parseACLOps(txnTracker.CommittedTxns[1].LinuxDataChangeTxn.Ops)
+	gomega.Expect(putIngress).To(gomega.HaveLen(0))
+	gomega.Expect(putEgress).To(gomega.HaveLen(0))
+	gomega.Expect(deleted).To(gomega.HaveLen(2))
+}
+
 func TestSingleContivRuleMultipleInterfaces(t *testing.T) {
 	gomega.RegisterTestingT(t)
 	logger := logrus.DefaultLogger()
@@ -956,3 +1052,260 @@ func TestMultipleContivRulesMultipleInterfacesWithResync(t *testing.T) {
 	verifyACL(putEgress.GetACL(pod1IfName), egACLB, cache.NewInterfaceSet(), ifSetEgB, egressB...)
 	verifyACL(putEgress.GetACL(pod2IfName), egACLB, cache.NewInterfaceSet(), ifSetEgB, egressB...)
 }
+
+func TestParseAppliedEntries(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	output := []byte(`Stats counters enabled for interface ACLs: 1
+ sw_if_index 3:
+  acl_lookup_context: 0
+  applied acls: 0,1
+  applied mask info entries:
+    0: 0000000000000000 0000000000000000 00000000ffffffff 0000000000000000 0000000000000000 0000000000000000 refcount 2
+  applied hash entries: 3
+    0: acl 0 rule 0 action 2 bitmask-ready rule 0 next 1 prev -1 tail 1 hitcount 3
+    1: acl 1 rule 0 action 0 bitmask-ready rule 0 next 0 prev 0 tail 0 hitcount 0
+    2: acl 1 rule 1 action 1 bitmask-ready rule 1 next 0 prev 0 tail 0 hitcount 12
+ sw_if_index 4:
+  applied hash entries: 1
+    0: acl 0 rule 0 action 2 bitmask-ready rule 0 next 0 prev 0 tail 0 hitcount 4
+`)
+	hits := parseAppliedEntries(output)
+	gomega.Expect(hits).To(gomega.HaveLen(3))
+	gomega.Expect(hits[aclEntryKey{aclIndex: 0, ruleIndex: 0}]).To(gomega.BeEquivalentTo(7))
+	gomega.Expect(hits[aclEntryKey{aclIndex: 1, ruleIndex: 0}]).To(gomega.BeEquivalentTo(0))
+	gomega.Expect(hits[aclEntryKey{aclIndex: 1, ruleIndex: 1}]).To(gomega.BeEquivalentTo(12))
+}
+
+func TestBootstrapDeny(t *testing.T) {
+	gomega.RegisterTestingT(t)
+	logger := logrus.DefaultLogger()
+	logger.SetLevel(logging.DebugLevel)
+	logger.Debug("TestBootstrapDeny")
+
+	// Prepare input data.
+	const (
+		namespace  = "default"
+		pod1Name   = "pod1"
+		pod2Name   = "pod2"
+		pod1IfName = "afpacket1"
+		pod2IfName = "afpacket2"
+	)
+	pod1 := podmodel.ID{Name: pod1Name, Namespace: namespace}
+	pod2 := podmodel.ID{Name: pod2Name, Namespace: namespace}
+	pod1IfSet := cache.NewInterfaceSet(pod1IfName)
+	pod2IfSet := cache.NewInterfaceSet(pod2IfName)
+	emptyList := []*renderer.ContivRule{}
+
+	// Prepare mocks.
+	contiv := NewMockContiv()
+	contiv.SetPodIfName(pod1, pod1IfName)
+	contiv.SetPodIfName(pod2, pod2IfName)
+
+	txnTracker := localclient.NewTxnTracker(nil)
+
+	// Prepare ACL Renderer.
+	aclRenderer := &Renderer{
+		Deps: Deps{
+			Log:           logger,
+			Contiv:        contiv,
+			VPP:           NewMockVppPlugin(),
+			ACLTxnFactory: txnTracker.NewLinuxDataChangeTxn,
+		},
+	}
+	aclRenderer.Init()
+
+	// All traffic of the new pods is denied.
+	err := aclRenderer.BootstrapDeny(pod1)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(txnTracker.CommittedTxns).To(gomega.HaveLen(1))
+	putIngress, putEgress, deleted := parseACLOps(txnTracker.CommittedTxns[0].LinuxDataChangeTxn.Ops)
+	gomega.Expect(deleted).To(gomega.HaveLen(0))
+	verifyACL(putIngress.GetACL(pod1IfName), "", pod1IfSet, cache.NewInterfaceSet(), denyAll()...)
+	verifyACL(putEgress.GetACL(pod1IfName), "", cache.NewInterfaceSet(), pod1IfSet, denyAll()...)
+
+	err = aclRenderer.BootstrapDeny(pod2)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(txnTracker.CommittedTxns).To(gomega.HaveLen(2))
+
+	// Traffic of pod1 is opened once its (empty) rules are rendered.
+	err = aclRenderer.NewTxn(false).Render(pod1, nil, emptyList, emptyList).Commit()
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(txnTracker.CommittedTxns).To(gomega.HaveLen(3))
+	putIngress, putEgress, _ = parseACLOps(txnTracker.CommittedTxns[2].LinuxDataChangeTxn.Ops)
+	verifyACL(putIngress.GetACL(pod2IfName), "", pod2IfSet, cache.NewInterfaceSet(), denyAll()...)
+	verifyACL(putEgress.GetACL(pod2IfName), "", cache.NewInterfaceSet(), pod2IfSet, denyAll()...)
+	gomega.Expect(putIngress.GetACL(pod1IfName)).To(gomega.BeNil())
+	gomega.Expect(putEgress.GetACL(pod1IfName)).To(gomega.BeNil())
+
+	// Rendered pod is not affected by the cancellation.
+	err = aclRenderer.CancelBootstrapDeny(pod1)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(txnTracker.CommittedTxns).To(gomega.HaveLen(3))
+
+	// Removed pod2 without rendered rules.
+	err = aclRenderer.CancelBootstrapDeny(pod2)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(txnTracker.CommittedTxns).To(gomega.HaveLen(4))
+	putIngress, putEgress, deleted = parseACLOps(txnTracker.CommittedTxns[3].LinuxDataChangeTxn.Ops)
+	gomega.Expect(putIngress).To(gomega.HaveLen(0))
+	gomega.Expect(putEgress).To(gomega.HaveLen(0))
+	gomega.Expect(deleted).To(gomega.HaveLen(2))
+}
+
+// vppPluginWithIfIndexes extends the VPP plugin mock with the index of interfaces.
+type vppPluginWithIfIndexes struct {
+	*MockVppPlugin
+	swIfIndexes ifaceidx.SwIfIndex
+}
+
+// GetSwIfIndexes returns the index of interfaces.
+func (vpp *vppPluginWithIfIndexes) GetSwIfIndexes() ifaceidx.SwIfIndex {
+	return vpp.swIfIndexes
+}
+
+func aclTag(name string) []byte {
+	tag := make([]byte, 64)
+	copy(tag, name)
+	return tag
+}
+
+func aclAddr(ip ...byte) []byte {
+	addr := make([]byte, 16)
+	copy(addr, ip)
+	return addr
+}
+
+func TestResyncWithACLsDumpedFromVPP(t *testing.T) {
+	gomega.RegisterTestingT(t)
+	logger := logrus.DefaultLogger()
+	logger.SetLevel(logging.DebugLevel)
+	logger.Debug("TestResyncWithACLsDumpedFromVPP")
+
+	// Prepare input data.
+	const (
+		namespace    = "default"
+		pod1Name     = "pod1"
+		pod1IfName   = "tap1"
+		pod2IfName   = "tap2"
+		ingressACL   = "ingress-0A1B2C3D4E"
+		egressACL    = "egress-0A1B2C3D4E"
+		staleACL     = "ingress-5F6A7B8C9D"
+		anyPort      = ^uint16(0)
+		pod1IfIndex  = 1
+		pod2IfIndex  = 2
+		protocolTCP  = 6
+		protocolUDP  = 17
+		actionDeny   = 0
+		actionPermit = 2
+	)
+	pod1 := podmodel.ID{Name: pod1Name, Namespace: namespace}
+	inRule := &renderer.ContivRule{
+		ID:          "deny-ssh",
+		Action:      renderer.ActionDeny,
+		SrcNetwork:  ipNetwork(""),
+		DestNetwork: ipNetwork("192.168.1.0/24"),
+		Protocol:    renderer.TCP,
+		DestPort:    22,
+	}
+	egRule := &renderer.ContivRule{
+		ID:          "allow-dns",
+		Action:      renderer.ActionPermit,
+		SrcNetwork:  ipNetwork("10.0.0.0/8"),
+		DestNetwork: ipNetwork(""),
+		Protocol:    renderer.UDP,
+		DestPort:    53,
+	}
+
+	// ACLs installed in VPP before the restart of the agent: rules of pod1 and a stale ACL
+	// of a removed pod; the names of the rules are not kept by VPP.
+	aclDetails := []*acl_api.ACLDetails{
+		{ACLIndex: 0, Tag: aclTag(ingressACL), Count: 1, R: []acl_api.ACLRule{{
+			IsPermit: actionDeny, SrcIPAddr: aclAddr(), DstIPAddr: aclAddr(192, 168, 1, 0), DstIPPrefixLen: 24,
+			Proto: protocolTCP, SrcportOrIcmptypeLast: anyPort, DstportOrIcmpcodeFirst: 22, DstportOrIcmpcodeLast: 22,
+		}}},
+		{ACLIndex: 1, Tag: aclTag(egressACL), Count: 1, R: []acl_api.ACLRule{{
+			IsPermit: actionPermit, SrcIPAddr: aclAddr(10), SrcIPPrefixLen: 8, DstIPAddr: aclAddr(),
+			Proto: protocolUDP, SrcportOrIcmptypeLast: anyPort, DstportOrIcmpcodeFirst: 53, DstportOrIcmpcodeLast: 53,
+		}}},
+		{ACLIndex: 2, Tag: aclTag(staleACL), Count: 1, R: []acl_api.ACLRule{{
+			IsPermit: actionDeny, SrcIPAddr: aclAddr(), DstIPAddr: aclAddr(),
+			Proto: protocolTCP, SrcportOrIcmptypeLast: anyPort, DstportOrIcmpcodeFirst: 80, DstportOrIcmpcodeLast: 80,
+		}}},
+	}
+	ifDetails := []*acl_api.ACLInterfaceListDetails{
+		{SwIfIndex: pod1IfIndex, Count: 2, NInput: 1, Acls: []uint32{0, 1}},
+		{SwIfIndex: pod2IfIndex, Count: 1, NInput: 1, Acls: []uint32{2}},
+	}
+
+	// Prepare mocks.
+	contiv := NewMockContiv()
+	contiv.SetPodIfName(pod1, pod1IfName)
+	swIfIndexes := ifaceidx.NewSwIfIndex(nametoidx.NewNameToIdx(logger, "test", "sw_if_indexes", ifaceidx.IndexMetadata))
+	swIfIndexes.RegisterName(pod1IfName, pod1IfIndex, nil)
+	swIfIndexes.RegisterName(pod2IfName, pod2IfIndex, nil)
+
+	vppMock := &govppmock.VppAdapter{}
+	vppMock.RegisterBinAPITypes(acl_api.Types)
+	vppMock.RegisterBinAPITypes(vpe.Types)
+	vppMock.MockReplyHandler(func(request govppmock.MessageDTO) (reply []byte, msgID uint16, prepared bool) {
+		// the details of the dumps are queued, so that more of them are replied to a single request
+		switch request.MsgName {
+		case "acl_dump":
+			for _, msg := range aclDetails {
+				vppMock.MockReply(msg)
+			}
+			vppMock.MockReply(&vpe.ControlPingReply{})
+		case "acl_interface_list_dump":
+			for _, msg := range ifDetails {
+				vppMock.MockReply(msg)
+			}
+			vppMock.MockReply(&vpe.ControlPingReply{})
+		}
+		return nil, 0, false
+	})
+	conn, err := govpp.Connect(vppMock)
+	gomega.Expect(err).To(gomega.BeNil())
+	defer conn.Disconnect()
+	goVPPChan, err := conn.NewAPIChannel()
+	gomega.Expect(err).To(gomega.BeNil())
+	defer goVPPChan.Close()
+
+	txnTracker := localclient.NewTxnTracker(nil)
+
+	// Prepare ACL Renderer.
+	aclRenderer := &Renderer{
+		Deps: Deps{
+			Log:           logger,
+			Contiv:        contiv,
+			VPP:           &vppPluginWithIfIndexes{MockVppPlugin: NewMockVppPlugin(), swIfIndexes: swIfIndexes},
+			ACLTxnFactory: txnTracker.NewLinuxDataChangeTxn,
+			GoVPPChan:     goVPPChan,
+		},
+	}
+	aclRenderer.Init()
+
+	// Execute RESYNC with the rules of pod1 unchanged.
+	err = aclRenderer.NewTxn(true).
+		Render(pod1, nil, []*renderer.ContivRule{inRule}, []*renderer.ContivRule{egRule}).
+		Commit()
+	gomega.Expect(err).To(gomega.BeNil())
+
+	// Only the stale ACL is removed, the ACLs of pod1 are left as they are.
+	gomega.Expect(txnTracker.PendingTxns).To(gomega.HaveLen(0))
+	gomega.Expect(txnTracker.CommittedTxns).To(gomega.HaveLen(1))
+	putIngress, putEgress, deleted := parseACLOps(txnTracker.CommittedTxns[0].LinuxDataChangeTxn.Ops)
+	gomega.Expect(putIngress).To(gomega.HaveLen(0))
+	gomega.Expect(putEgress).To(gomega.HaveLen(0))
+	gomega.Expect(deleted).To(gomega.HaveLen(1))
+	gomega.Expect(deleted.Has(staleACL)).To(gomega.BeTrue())
+
+	// The cache holds the dumped ACLs with the rules of the transaction.
+	ingress, egress := aclRenderer.cache.LookupByInterface(pod1IfName)
+	gomega.Expect(ingress).ToNot(gomega.BeNil())
+	gomega.Expect(ingress.ID).To(gomega.BeEquivalentTo(ingressACL))
+	gomega.Expect(ingress.Rules).To(gomega.Equal([]*renderer.ContivRule{inRule}))
+	gomega.Expect(egress).ToNot(gomega.BeNil())
+	gomega.Expect(egress.ID).To(gomega.BeEquivalentTo(egressACL))
+	gomega.Expect(egress.Rules).To(gomega.Equal([]*renderer.ContivRule{egRule}))
+}
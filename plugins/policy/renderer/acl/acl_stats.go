@@ -0,0 +1,165 @@
+/*
+ * // Copyright (c) 2017 Cisco and/or its affiliates.
+ * //
+ * // Licensed under the Apache License, Version 2.0 (the "License");
+ * // you may not use this file except in compliance with the License.
+ * // You may obtain a copy of the License at:
+ * //
+ * //     http://www.apache.org/licenses/LICENSE-2.0
+ * //
+ * // Unless required by applicable law or agreed to in writing, software
+ * // distributed under the License is distributed on an "AS IS" BASIS,
+ * // WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * // See the License for the specific language governing permissions and
+ * // limitations under the License.
+ */
+
+package acl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	acl_api "github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/acl"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/vpe"
+
+	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
+	"github.com/contiv/vpp/plugins/policy/renderer"
+	"github.com/contiv/vpp/plugins/policy/renderer/acl/cache"
+)
+
+// showAppliedTablesCmd is the VPP CLI command printing the ACL entries applied
+// on interfaces, including their hit counters.
+const showAppliedTablesCmd = "show acl-plugin tables applied"
+
+// appliedEntryRegexp matches an applied ACL entry printed by showAppliedTablesCmd, e.g.:
+//
+//	0: acl 2 rule 1 action 2 bitmask-ready rule 0 next 0 prev 0 tail 0 hitcount 15
+var appliedEntryRegexp = regexp.MustCompile(`acl (\d+) rule (\d+) .*hitcount (\d+)`)
+
+// RuleStats contains the hit counter of a rendered Contiv rule.
+// With reflective ACLs, only the first packet of each connection is matched
+// against the rules, the counter thus approximates the number of connections.
+type RuleStats struct {
+	// Rule is the Contiv rule as rendered into the ACL.
+	Rule *renderer.ContivRule
+
+	// Ingress is true if the rule is applied on the traffic entering VPP
+	// from the pods (vswitch point of view).
+	Ingress bool
+
+	// Pods lists the pods whose interfaces have the rule applied.
+	Pods []podmodel.ID
+
+	// Hits is the number of times the rule was matched, summed over all interfaces.
+	Hits uint64
+}
+
+// aclEntryKey identifies an entry (rule) of an ACL installed in VPP.
+type aclEntryKey struct {
+	aclIndex  uint32
+	ruleIndex uint32
+}
+
+// DumpRuleStats reads hit counters of the rendered ACL rules from VPP and maps
+// them back to Contiv rules and pods.
+// Rules that were never matched are not included.
+// The method must not run concurrently with the transactions of the renderer.
+func (r *Renderer) DumpRuleStats() ([]*RuleStats, error) {
+	if r.GoVPPChan == nil {
+		return nil, fmt.Errorf("GoVPP channel is not available")
+	}
+
+	// Learn the names of the ACLs installed in VPP.
+	aclNames := make(map[uint32]string)
+	req := &acl_api.ACLDump{ACLIndex: ^uint32(0)}
+	reqCtx := r.GoVPPChan.SendMultiRequest(req)
+	for {
+		msg := &acl_api.ACLDetails{}
+		stop, err := reqCtx.ReceiveReply(msg)
+		if stop {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		aclNames[msg.ACLIndex] = string(bytes.Trim(msg.Tag, "\x00"))
+	}
+
+	// Read the hit counters.
+	cliReq := &vpe.CliInband{
+		Cmd:    []byte(showAppliedTablesCmd),
+		Length: uint32(len(showAppliedTablesCmd)),
+	}
+	cliReply := &vpe.CliInbandReply{}
+	if err := r.GoVPPChan.SendRequest(cliReq).ReceiveReply(cliReply); err != nil {
+		return nil, err
+	}
+	if cliReply.Retval != 0 {
+		return nil, fmt.Errorf("%s returned %d", cliReply.GetMessageName(), cliReply.Retval)
+	}
+	hits := parseAppliedEntries(cliReply.Reply)
+
+	// Map the counters to the rule lists of the cache.
+	ifPods := make(map[string]podmodel.ID)
+	for pod, ifName := range r.podInterfaces {
+		ifPods[ifName] = pod
+	}
+	ruleLists := make(map[string]*cache.ContivRuleList)
+	ingressLists := make(map[string]struct{})
+	for ifName := range r.cache.AllInterfaces() {
+		ingress, egress := r.cache.LookupByInterface(ifName)
+		if ingress != nil {
+			ruleLists[ingress.ID] = ingress
+			ingressLists[ingress.ID] = struct{}{}
+		}
+		if egress != nil {
+			ruleLists[egress.ID] = egress
+		}
+	}
+
+	stats := []*RuleStats{}
+	for entry, count := range hits {
+		ruleList, known := ruleLists[aclNames[entry.aclIndex]]
+		if !known || count == 0 || int(entry.ruleIndex) >= len(ruleList.Rules) {
+			continue
+		}
+		ruleStats := &RuleStats{
+			Rule: ruleList.Rules[entry.ruleIndex],
+			Hits: count,
+			Pods: []podmodel.ID{},
+		}
+		_, ruleStats.Ingress = ingressLists[ruleList.ID]
+		for ifName := range ruleList.Interfaces {
+			if pod, isPod := ifPods[ifName]; isPod {
+				ruleStats.Pods = append(ruleStats.Pods, pod)
+			}
+		}
+		stats = append(stats, ruleStats)
+	}
+	return stats, nil
+}
+
+// parseAppliedEntries parses the output of showAppliedTablesCmd into hit counters
+// of ACL entries, summed over all interfaces with the entry applied.
+func parseAppliedEntries(output []byte) map[aclEntryKey]uint64 {
+	hits := make(map[aclEntryKey]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		match := appliedEntryRegexp.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		aclIndex, err1 := strconv.ParseUint(match[1], 10, 32)
+		ruleIndex, err2 := strconv.ParseUint(match[2], 10, 32)
+		count, err3 := strconv.ParseUint(match[3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		hits[aclEntryKey{aclIndex: uint32(aclIndex), ruleIndex: uint32(ruleIndex)}] += count
+	}
+	return hits
+}
@@ -20,8 +20,10 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
+	policymodel "github.com/contiv/vpp/plugins/ksr/model/policy"
 	"github.com/contiv/vpp/plugins/policy/utils"
 )
 
@@ -58,6 +60,13 @@ type Txn interface {
 	Commit() error
 }
 
+// HostEndpoint is a pseudo-pod representing the host stack of this node,
+// connected with the vswitch via the host interconnect. Rules are rendered
+// for the host endpoint the same way as for pods, with the host in the role
+// of the pod. The IP address passed to Render() for the host endpoint is nil.
+// Renderers unable to filter the host traffic should ignore the host endpoint.
+var HostEndpoint = podmodel.ID{Name: "host-endpoint"}
+
 // ContivRule is an n-tuple with the most basic policy rule definition that the
 // destination network stack must support.
 type ContivRule struct {
@@ -102,6 +111,38 @@ func (cr *ContivRule) String() string {
 		cr.ID, cr.Action, srcNet, cr.Protocol, srcPort, dstNet, cr.Protocol, dstPort)
 }
 
+// AuditRuleSuffix is the suffix of IDs of the rules that permit traffic
+// which would be denied by the policies if they were not rendered
+// in the audit (log-only) mode.
+const AuditRuleSuffix = ":AUDIT"
+
+// IsAudit returns true if the rule permits traffic only because the policies
+// are rendered in the audit mode.
+func (cr *ContivRule) IsAudit() bool {
+	return cr.Action == ActionPermit && strings.HasSuffix(cr.ID, AuditRuleSuffix)
+}
+
+// PolicyRuleSeparator separates the ID of the policy from the rest of the ID
+// of a rule generated for a single policy: "<namespace>/<name>|<peer>-<port>".
+const PolicyRuleSeparator = "|"
+
+// PolicyID returns the ID of the policy the rule was generated for.
+// <fromPolicy> is false for rules not originating from a single policy,
+// such as the rules denying the traffic not allowed by any policy.
+func (cr *ContivRule) PolicyID() (policy policymodel.ID, fromPolicy bool) {
+	sepIdx := strings.Index(cr.ID, PolicyRuleSeparator)
+	if sepIdx == -1 {
+		return policy, false
+	}
+	nsSepIdx := strings.Index(cr.ID[:sepIdx], "/")
+	if nsSepIdx == -1 {
+		return policy, false
+	}
+	policy.Namespace = cr.ID[:nsSepIdx]
+	policy.Name = cr.ID[nsSepIdx+1 : sepIdx]
+	return policy, true
+}
+
 // Copy creates a deep copy of the Contiv rule.
 func (cr *ContivRule) Copy() *ContivRule {
 	crCopy := &ContivRule{}
@@ -163,7 +204,7 @@ func (at ActionType) String() string {
 	return "INVALID"
 }
 
-// ProtocolType is either TCP or UDP.
+// ProtocolType is either TCP, UDP or SCTP.
 type ProtocolType int
 
 const (
@@ -172,6 +213,9 @@ const (
 
 	// UDP protocol.
 	UDP
+
+	// SCTP protocol.
+	SCTP
 )
 
 // String converts ProtocolType into a human-readable string.
@@ -181,6 +225,8 @@ func (at ProtocolType) String() string {
 		return "TCP"
 	case UDP:
 		return "UDP"
+	case SCTP:
+		return "SCTP"
 	}
 	return "INVALID"
 }
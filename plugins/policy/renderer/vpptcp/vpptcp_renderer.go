@@ -222,6 +222,11 @@ func (r *Renderer) updateRules(add, remove []*cache.SessionRule) error {
 // The existing rules are replaced.
 // Te actual change is performed only after the commit.
 func (art *RendererTxn) Render(pod podmodel.ID, podIP *net.IPNet, ingress []*renderer.ContivRule, egress []*renderer.ContivRule) renderer.Txn {
+	if pod == renderer.HostEndpoint {
+		// The host stack does not use the VPP TCP stack.
+		return art
+	}
+
 	// Get the target namespace index.
 	nsIndex, found := art.renderer.Contiv.GetNsIndex(pod.Namespace, pod.Name)
 	if !found {
@@ -237,10 +242,23 @@ func (art *RendererTxn) Render(pod podmodel.ID, podIP *net.IPNet, ingress []*ren
 	}).Debug("VPPTCP RendererTxn Render()")
 
 	// Add the rules into the transaction.
-	art.cacheTxn.Update(nsIndex, podIP, ingress, egress)
+	art.cacheTxn.Update(nsIndex, podIP, filterSessionRules(ingress), filterSessionRules(egress))
 	return art
 }
 
+// filterSessionRules returns only the rules of the protocols terminated by the VPP
+// TCP stack (TCP, UDP). Traffic of other protocols (SCTP) bypasses the stack
+// and the session rules.
+func filterSessionRules(rules []*renderer.ContivRule) []*renderer.ContivRule {
+	filtered := []*renderer.ContivRule{}
+	for _, rule := range rules {
+		if rule.Protocol == renderer.TCP || rule.Protocol == renderer.UDP {
+			filtered = append(filtered, rule)
+		}
+	}
+	return filtered
+}
+
 // Commit proceeds with the rendering. A minimalistic set of changes is
 // calculated using ContivRuleCache and applied via binary API using govpp.
 func (art *RendererTxn) Commit() error {
@@ -0,0 +1,96 @@
+/*
+ * // Copyright (c) 2018 Cisco and/or its affiliates.
+ * //
+ * // Licensed under the Apache License, Version 2.0 (the "License");
+ * // you may not use this file except in compliance with the License.
+ * // You may obtain a copy of the License at:
+ * //
+ * //     http://www.apache.org/licenses/LICENSE-2.0
+ * //
+ * // Unless required by applicable law or agreed to in writing, software
+ * // distributed under the License is distributed on an "AS IS" BASIS,
+ * // WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * // See the License for the specific language governing permissions and
+ * // limitations under the License.
+ */
+
+package configurator
+
+import (
+	"net"
+)
+
+// exportAdvertisedAddrs returns the list of external IPs of the service for which
+// this node should advertise host routes to the BGP peers.
+func (sc *ServiceConfigurator) exportAdvertisedAddrs(service *ContivService) []net.IP {
+	addrs := []net.IP{}
+	if !service.OwnsExternalIPs {
+		return addrs
+	}
+	for _, externalIP := range service.ExternalIPs.List() {
+		if externalIP.To4() == nil {
+			// only IPv4 routes are advertised
+			continue
+		}
+		addrs = append(addrs, externalIP)
+	}
+	return addrs
+}
+
+// syncAdvertisedAddrs updates the set of advertised external IPs so that <have>
+// becomes <want>. The same external IP may be shared by multiple services,
+// the addresses are therefore reference-counted.
+func (sc *ServiceConfigurator) syncAdvertisedAddrs(have []net.IP, want []net.IP) {
+	haveSet := NewIPAddresses(have...)
+	wantSet := NewIPAddresses(want...)
+
+	// Withdraw obsolete addresses.
+	for _, addr := range haveSet.List() {
+		if wantSet.Has(addr) {
+			continue
+		}
+		sc.advertisedAddrs[addr.String()]--
+		if sc.advertisedAddrs[addr.String()] > 0 {
+			continue
+		}
+		delete(sc.advertisedAddrs, addr.String())
+		sc.Contiv.AdvertiseRoute(hostPrefix(addr), false)
+	}
+
+	// Advertise new addresses.
+	for _, addr := range wantSet.List() {
+		if haveSet.Has(addr) {
+			continue
+		}
+		sc.advertisedAddrs[addr.String()]++
+		if sc.advertisedAddrs[addr.String()] > 1 {
+			continue
+		}
+		sc.Contiv.AdvertiseRoute(hostPrefix(addr), true)
+	}
+}
+
+// resyncAdvertisedAddrs replaces the set of advertised external IPs with
+// the external IPs owned by this node.
+func (sc *ServiceConfigurator) resyncAdvertisedAddrs(services []*ContivService) {
+	advertisedAddrs := make(map[string]int)
+	for _, service := range services {
+		for _, addr := range sc.exportAdvertisedAddrs(service) {
+			advertisedAddrs[addr.String()]++
+		}
+	}
+	for addr := range sc.advertisedAddrs {
+		if _, wanted := advertisedAddrs[addr]; !wanted {
+			sc.Contiv.AdvertiseRoute(hostPrefix(net.ParseIP(addr)), false)
+		}
+	}
+	for addr := range advertisedAddrs {
+		sc.Contiv.AdvertiseRoute(hostPrefix(net.ParseIP(addr)), true)
+	}
+	sc.advertisedAddrs = advertisedAddrs
+}
+
+// hostPrefix returns the /32 prefix of the given IPv4 address.
+func hostPrefix(addr net.IP) *net.IPNet {
+	return &net.IPNet{IP: addr.To4(), Mask: net.CIDRMask(32, 32)}
+}
@@ -68,13 +68,48 @@ type ContivService struct {
 	// ID should uniquely identify service across all namespaces.
 	ID svcmodel.ID
 
-	// TrafficPolicy decides if traffic is routed cluster-wide or node-local only.
+	// TrafficPolicy decides if external traffic (arriving via node ports
+	// or external IPs) is routed cluster-wide or node-local only.
 	TrafficPolicy TrafficPolicyType
 
-	// ExternalIPs is a set of all IP addresses on which the service
-	// should be exposed on this node.
+	// SessionAffinityTimeout is the timeout in seconds for which connections
+	// of the same client are sent to the same backend (0 = no session affinity).
+	SessionAffinityTimeout uint32
+
+	// ClusterIP is the virtual IP address on which the service is exposed
+	// inside the cluster (nil for headless services).
+	// Traffic to the cluster IP is always load-balanced across all backends.
+	ClusterIP net.IP
+
+	// ExternalIPs is a set of all external IP addresses (including LoadBalancer
+	// ingress IPs) on which the service should be exposed on this node.
 	ExternalIPs *IPAddresses
 
+	// Headless is true for services without a cluster IP (clusterIP: None).
+	// Headless services are resolved by DNS directly to the backend pods and
+	// are not NATed at all.
+	Headless bool
+
+	// DSR is true if the service is in the Direct Server Return mode: the traffic
+	// destined to the cluster IP and the external IPs is routed to backends without
+	// translation and the replies bypass this node. Node ports are still NATed.
+	DSR bool
+
+	// TopologyAware is true if the traffic of the service is load-balanced with
+	// the preference of backends close to this node: the local backends if there
+	// are any, otherwise the backends in the same topology zone if there are any,
+	// otherwise all backends.
+	TopologyAware bool
+
+	// NodeLocalDNS is true if the DNS traffic (port 53) destined to the cluster IP
+	// is steered to the node-local DNS cache instead of being load-balanced across
+	// the backends.
+	NodeLocalDNS bool
+
+	// OwnsExternalIPs is true if this node was selected to answer ARP requests
+	// for the external IPs of the service.
+	OwnsExternalIPs bool
+
 	// Ports is a map of all ports exposed for this service.
 	Ports map[string]*ServicePort
 
@@ -127,8 +162,8 @@ func (cs ContivService) String() string {
 		}
 		idx++
 	}
-	return fmt.Sprintf("ContivService %s <Traffic-Policy:%s ExternalIPs:[%s] Backends:{%s}>",
-		cs.ID.String(), cs.TrafficPolicy.String(), externalIPs, allBackends)
+	return fmt.Sprintf("ContivService %s <Traffic-Policy:%s Session-Affinity-Timeout:%d Headless:%t DSR:%t Topology-Aware:%t Node-Local-DNS:%t ClusterIP:%s ExternalIPs:[%s] Backends:{%s}>",
+		cs.ID.String(), cs.TrafficPolicy.String(), cs.SessionAffinityTimeout, cs.Headless, cs.DSR, cs.TopologyAware, cs.NodeLocalDNS, cs.ClusterIP, externalIPs, allBackends)
 }
 
 // String converts TrafficPolicyType into a human-readable string.
@@ -167,7 +202,7 @@ func (sp ServicePort) String() string {
 	return fmt.Sprintf("%d:%d/%s", sp.Port, sp.NodePort, sp.Protocol.String())
 }
 
-// ProtocolType is either TCP or UDP.
+// ProtocolType is either TCP, UDP or SCTP.
 type ProtocolType int
 
 const (
@@ -176,6 +211,9 @@ const (
 
 	// UDP protocol.
 	UDP ProtocolType = 17
+
+	// SCTP protocol.
+	SCTP ProtocolType = 132
 )
 
 // String converts ProtocolType into a human-readable string.
@@ -185,20 +223,23 @@ func (pt ProtocolType) String() string {
 		return "TCP"
 	case UDP:
 		return "UDP"
+	case SCTP:
+		return "SCTP"
 	}
 	return "INVALID"
 }
 
 // ServiceBackend represents a single service backend.
 type ServiceBackend struct {
-	IP    net.IP /* internal IP address of the backend */
-	Port  uint16 /* backend-local port on which the service listens */
-	Local bool   /* true if the backend is deployed on this node  */
+	IP       net.IP /* internal IP address of the backend */
+	Port     uint16 /* backend-local port on which the service listens */
+	Local    bool   /* true if the backend is deployed on this node  */
+	SameZone bool   /* true if the backend is deployed in the topology zone of this node */
 }
 
 // String converts Backend into a human-readable string.
 func (sb ServiceBackend) String() string {
-	return fmt.Sprintf("<IP:%s Port:%d, Local:%t>", sb.IP, sb.Port, sb.Local)
+	return fmt.Sprintf("<IP:%s Port:%d, Local:%t, SameZone:%t>", sb.IP, sb.Port, sb.Local, sb.SameZone)
 }
 
 // IPAddresses is a set of IP addresses.
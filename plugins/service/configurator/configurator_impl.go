@@ -34,19 +34,48 @@ const LocalVsRemoteProbRatio uint8 = 2
 // ServiceConfigurator implements ServiceConfiguratorAPI.
 type ServiceConfigurator struct {
 	Deps
+
+	// reference counts of the addresses VPP answers ARP requests for
+	proxyARPAddrs map[string]int
+
+	// proxy ARP/NDP ranges installed by the configurator
+	proxyARPRanges map[string]IPRange
+
+	// reference counts of the external IPs advertised to the BGP peers
+	advertisedAddrs map[string]int
+
+	// routes of the services in the Direct Server Return mode installed by the configurator
+	dsrRoutes []*DSRRoute
+
+	// address of the node-local DNS cache routed via the host interconnect (nil if none)
+	nodeLocalDNSRoute net.IP
+
+	// true once the NAT44 session tables of VPP were checked against NATSessions
+	natSessionsChecked bool
 }
 
 // Deps lists dependencies of ServiceConfigurator.
 type Deps struct {
 	Log              logging.Logger
-	Contiv           contiv.API         /* to get the Node IP */
+	Contiv           contiv.API         /* to get the Node IP and to advertise external IPs */
 	VPP              defaultplugins.API /* interface indexes */
 	GoVPPChan        *govpp.Channel     /* until supported in vpp-agent, we call NAT binary APIs directly */
 	GoVPPChanBufSize int
+	ProxyARP         bool             /* answer ARP requests for the external IPs owned by this node */
+	NATSessions      NATSessionConfig /* expected sizing of the NAT44 session tables */
+
+	ProxyARPRanges     []IPRange /* answer ARP/NDP requests for these ranges */
+	ProxyARPPodNetwork bool      /* answer ARP/NDP requests for the pod network of this node */
+
+	NodeLocalDNS net.IP /* address of the node-local DNS cache in the host stack (nil if not used) */
 }
 
 // Init initializes service configurator.
 func (sc *ServiceConfigurator) Init() error {
+	sc.proxyARPAddrs = make(map[string]int)
+	sc.proxyARPRanges = make(map[string]IPRange)
+	sc.advertisedAddrs = make(map[string]int)
+	sc.dsrRoutes = []*DSRRoute{}
 	return nil
 }
 
@@ -55,6 +84,8 @@ func (sc *ServiceConfigurator) AddService(service *ContivService) error {
 	sc.Log.WithFields(logging.Fields{
 		"service": service,
 	}).Debug("ServiceConfigurator - AddService()")
+	sc.checkSessionAffinity(service)
+	sc.checkPortProtocols(service)
 
 	natMaps, err := sc.exportNATMappings(service)
 	if err != nil {
@@ -67,6 +98,19 @@ func (sc *ServiceConfigurator) AddService(service *ContivService) error {
 		sc.Log.Error(err)
 		return err
 	}
+
+	err = sc.syncProxyARPAddrs([]net.IP{}, sc.exportProxyARPAddrs(service))
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
+	sc.syncAdvertisedAddrs([]net.IP{}, sc.exportAdvertisedAddrs(service))
+
+	err = sc.syncDSRRoutes([]*DSRRoute{}, sc.exportDSRRoutes(service))
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
 	return nil
 }
 
@@ -77,6 +121,8 @@ func (sc *ServiceConfigurator) UpdateService(oldService, newService *ContivServi
 		"oldService": oldService,
 		"newService": newService,
 	}).Debug("ServiceConfigurator - UpdateService()")
+	sc.checkSessionAffinity(newService)
+	sc.checkPortProtocols(newService)
 
 	oldNatMaps, err := sc.exportNATMappings(oldService)
 	if err != nil {
@@ -95,6 +141,19 @@ func (sc *ServiceConfigurator) UpdateService(oldService, newService *ContivServi
 		sc.Log.Error(err)
 		return err
 	}
+
+	err = sc.syncProxyARPAddrs(sc.exportProxyARPAddrs(oldService), sc.exportProxyARPAddrs(newService))
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
+	sc.syncAdvertisedAddrs(sc.exportAdvertisedAddrs(oldService), sc.exportAdvertisedAddrs(newService))
+
+	err = sc.syncDSRRoutes(sc.exportDSRRoutes(oldService), sc.exportDSRRoutes(newService))
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
 	return nil
 }
 
@@ -116,6 +175,19 @@ func (sc *ServiceConfigurator) DeleteService(service *ContivService) error {
 		sc.Log.Error(err)
 		return err
 	}
+
+	err = sc.syncProxyARPAddrs(sc.exportProxyARPAddrs(service), []net.IP{})
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
+	sc.syncAdvertisedAddrs(sc.exportAdvertisedAddrs(service), []net.IP{})
+
+	err = sc.syncDSRRoutes(sc.exportDSRRoutes(service), []*DSRRoute{})
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
 	return nil
 }
 
@@ -207,21 +279,19 @@ func (sc *ServiceConfigurator) Resync(resyncEv *ResyncEventData) error {
 		"resyncEv": resyncEv,
 	}).Debug("ServiceConfigurator - Resync()")
 
-	/*
-		// Try to get Node IP.
-		nodeIP, err := sc.getNodeIP()
-		if err != nil {
-			sc.Log.Error(err)
-			return err
-		}
+	// Try to get Node IP.
+	nodeIP, err := sc.getNodeIP()
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
 
-		// Dump NAT address pool.
-		natPoolDump, _, err := sc.dumpAddressPool()
-		if err != nil {
-			sc.Log.Error(err)
-			return err
-		}
-	*/
+	// Dump twice-NAT address pool.
+	twiceNatPoolDump, err := sc.dumpAddressPool(true)
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
 
 	// Dump currently installed NAT mappings.
 	natMapDump, err := sc.dumpNATMappings()
@@ -237,6 +307,14 @@ func (sc *ServiceConfigurator) Resync(resyncEv *ResyncEventData) error {
 		return err
 	}
 
+	// Check the sizing of the NAT44 session tables (given by the startup config of VPP).
+	if !sc.natSessionsChecked {
+		if err := sc.checkNATSessionConfig(); err != nil {
+			sc.Log.Warnf("Failed to check NAT44 session tables: %v", err)
+		}
+		sc.natSessionsChecked = true
+	}
+
 	// Enable NAT44 forwarding.
 	err = sc.enableNat44Forwarding()
 	if err != nil {
@@ -244,26 +322,33 @@ func (sc *ServiceConfigurator) Resync(resyncEv *ResyncEventData) error {
 		return err
 	}
 
-	/*
-		// Make sure Node IP is the only addres in the NAT address pool.
-		nodeIPInstalled := false
-		for _, addr := range natPoolDump.List() {
-			if addr.Equal(nodeIP) {
-				nodeIPInstalled = true
-			} else {
-				err = sc.setNATAddress(addr, false)
-				if err != nil {
-					sc.Log.Error(err)
-					return err
-				}
-
+	// Make sure Node IP is the only address in the twice-NAT address pool.
+	// The pool is used to translate the source address of the external traffic
+	// load-balanced across the whole cluster.
+	nodeIPInstalled := false
+	for _, addr := range twiceNatPoolDump.List() {
+		if addr.Equal(nodeIP) {
+			nodeIPInstalled = true
+		} else {
+			err = sc.setNATAddress(addr, true, false)
+			if err != nil {
+				sc.Log.Error(err)
+				return err
 			}
 		}
-	*/
+	}
+	if !nodeIPInstalled {
+		err = sc.setNATAddress(nodeIP, true, true)
+		if err != nil {
+			sc.Log.Error(err)
+			return err
+		}
+	}
 
 	// Export and update NAT Mappings.
 	natMaps := []*NATMapping{}
 	for _, svc := range resyncEv.Services {
+		sc.checkSessionAffinity(svc)
 		exportedMaps, err := sc.exportNATMappings(svc)
 		if err != nil {
 			sc.Log.Error(err)
@@ -277,6 +362,30 @@ func (sc *ServiceConfigurator) Resync(resyncEv *ResyncEventData) error {
 		return err
 	}
 
+	// Update the addresses VPP answers ARP requests for.
+	err = sc.resyncProxyARP(resyncEv.Services)
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
+
+	// Update the external IPs advertised to the BGP peers.
+	sc.resyncAdvertisedAddrs(resyncEv.Services)
+
+	// Update routes of the services in the Direct Server Return mode.
+	err = sc.resyncDSRRoutes(resyncEv.Services)
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
+
+	// Update the route to the node-local DNS cache.
+	err = sc.resyncNodeLocalDNSRoute()
+	if err != nil {
+		sc.Log.Error(err)
+		return err
+	}
+
 	// Update local backend interfaces.
 	err = sc.UpdateLocalBackendIfs(backendIfsDump, resyncEv.BackendIfs)
 	if err != nil {
@@ -297,6 +406,25 @@ func (sc *ServiceConfigurator) Resync(resyncEv *ResyncEventData) error {
 // exportNATMappings exports the corresponding list of NAT mappings from a Contiv service.
 func (sc *ServiceConfigurator) exportNATMappings(service *ContivService) ([]*NATMapping, error) {
 	mappings := []*NATMapping{}
+	if service.Headless {
+		// Backends of headless services are accessed directly.
+		return mappings, nil
+	}
+
+	// Export NAT mappings for the cluster IP.
+	// Services in the Direct Server Return mode are routed to backends without translation.
+	if service.ClusterIP != nil && !service.DSR {
+		// Add one mapping for each port.
+		for portName, port := range service.Ports {
+			if port.Port == 0 {
+				continue
+			}
+			mapping := sc.exportNATMapping(service, portName, service.ClusterIP, port.Port, false)
+			if mapping != nil {
+				mappings = append(mappings, mapping)
+			}
+		}
+	}
 
 	// Export NAT mappings for NodePort services.
 	if service.HasNodePort() {
@@ -311,80 +439,112 @@ func (sc *ServiceConfigurator) exportNATMappings(service *ContivService) ([]*NAT
 			if port.NodePort == 0 {
 				continue
 			}
-			mapping := NewNATMapping()
-			mapping.ExternalIP = nodeIP
-			mapping.ExternalPort = port.NodePort
-			mapping.Protocol = port.Protocol
-			for _, backend := range service.Backends[portName] {
-				if service.TrafficPolicy != ClusterWide && !backend.Local {
-					// Do not NAT+LB remote backends.
-					continue
-				}
-				local := &NATMappingLocal{
-					Address: backend.IP,
-					Port:    backend.Port,
-				}
-				if backend.Local {
-					local.Probability = LocalVsRemoteProbRatio
-				} else {
-					local.Probability = 1
-				}
-				mapping.Locals = append(mapping.Locals, local)
-			}
-			if len(mapping.Locals) == 0 {
-				continue
-			}
-			if len(mapping.Locals) == 1 {
-				// For single backend we use "1" to represent the probability
-				// (not really configured).
-				mapping.Locals[0].Probability = 1
+			mapping := sc.exportNATMapping(service, portName, nodeIP, port.NodePort, true)
+			if mapping != nil {
+				mappings = append(mappings, mapping)
 			}
-			mappings = append(mappings, mapping)
 		}
 	}
 
 	// Export NAT mappings for external IPs.
-	for _, externalIP := range service.ExternalIPs.List() {
+	externalIPs := service.ExternalIPs.List()
+	if service.DSR {
+		externalIPs = nil
+	}
+	for _, externalIP := range externalIPs {
 		// Add one mapping for each port.
 		for portName, port := range service.Ports {
 			if port.Port == 0 {
 				continue
 			}
-			mapping := NewNATMapping()
-			mapping.ExternalIP = externalIP
-			mapping.ExternalPort = port.Port
-			mapping.Protocol = port.Protocol
-			for _, backend := range service.Backends[portName] {
-				if service.TrafficPolicy != ClusterWide && !backend.Local {
-					// Do not NAT+LB remote backends.
-					continue
-				}
-				local := &NATMappingLocal{
-					Address: backend.IP,
-					Port:    backend.Port,
-				}
-				if backend.Local {
-					local.Probability = LocalVsRemoteProbRatio
-				} else {
-					local.Probability = 1
-				}
-				mapping.Locals = append(mapping.Locals, local)
-			}
-			if len(mapping.Locals) == 0 {
-				continue
-			}
-			if len(mapping.Locals) == 1 {
-				// For single backend we use "1" to represent the probability
-				// (not really configured).
-				mapping.Locals[0].Probability = 1
+			mapping := sc.exportNATMapping(service, portName, externalIP, port.Port, true)
+			if mapping != nil {
+				mappings = append(mappings, mapping)
 			}
-			mappings = append(mappings, mapping)
 		}
 	}
 
 	return mappings, nil
 }
 
+// exportNATMapping exports NAT mapping of the given service port exposed on the given IP address and port.
+// Returns nil if there are no backends to load-balance the traffic across.
+// Traffic of topology-aware services is load-balanced only across the backends closest to this node.
+// DNS queries of the DNS service steered to the node-local DNS cache are translated to the cache.
+// For external traffic (node ports and external IPs) the traffic policy of the service applies:
+//   - node-local: only local backends are load-balanced and the source address is preserved
+//   - cluster-wide: all backends are load-balanced and the source address is translated to the Node IP,
+//     otherwise the response from a remote backend would not be routed back through this node
+func (sc *ServiceConfigurator) exportNATMapping(service *ContivService, portName string,
+	externalIP net.IP, externalPort uint16, external bool) *NATMapping {
+
+	if service.Ports[portName].Protocol == SCTP {
+		// Not supported by the NAT44 plugin.
+		return nil
+	}
+	nodeLocal := external && service.TrafficPolicy == NodeLocal
+	if !external && service.NodeLocalDNS && sc.NodeLocalDNS != nil && service.Ports[portName].Port == dnsPort {
+		return sc.exportNodeLocalDNSMapping(service, portName)
+	}
+
+	mapping := NewNATMapping()
+	mapping.ExternalIP = externalIP
+	mapping.ExternalPort = externalPort
+	mapping.Protocol = service.Ports[portName].Protocol
+	mapping.TwiceNAT = external && !nodeLocal
+	backends := service.Backends[portName]
+	if service.TopologyAware && !nodeLocal {
+		backends = topologyBackends(backends)
+	}
+	for _, backend := range backends {
+		if nodeLocal && !backend.Local {
+			// Do not NAT+LB remote backends.
+			continue
+		}
+		local := &NATMappingLocal{
+			Address: backend.IP,
+			Port:    backend.Port,
+		}
+		if backend.Local {
+			local.Probability = LocalVsRemoteProbRatio
+		} else {
+			local.Probability = 1
+		}
+		mapping.Locals = append(mapping.Locals, local)
+	}
+	if len(mapping.Locals) == 0 {
+		return nil
+	}
+	if len(mapping.Locals) == 1 {
+		// For single backend we use "1" to represent the probability
+		// (not really configured).
+		mapping.Locals[0].Probability = 1
+	}
+	return mapping
+}
+
+// topologyBackends selects the backends closest to this node: the local backends
+// if there are any, otherwise the backends in the same topology zone if there are any,
+// otherwise all backends.
+func topologyBackends(backends []*ServiceBackend) []*ServiceBackend {
+	var local, sameZone []*ServiceBackend
+	for _, backend := range backends {
+		if backend.Local {
+			local = append(local, backend)
+		}
+		if backend.SameZone {
+			sameZone = append(sameZone, backend)
+		}
+	}
+	if len(local) > 0 {
+		return local
+	}
+	if len(sameZone) > 0 {
+		return sameZone
+	}
+	return backends
+}
+
 // Close deallocates resources held by the configurator.
 func (sc *ServiceConfigurator) Close() error {
 	return nil
@@ -392,6 +552,37 @@ func (sc *ServiceConfigurator) Close() error {
 
 /**** Helper methods ****/
 
+// checkSessionAffinity warns about the session affinity requested for the service.
+// The load-balancing static mappings (nat44_add_del_lb_static_mapping) have no affinity
+// option, the backend is selected for each new connection independently.
+// TODO: make the load-balancing mappings sticky per client IP
+func (sc *ServiceConfigurator) checkSessionAffinity(service *ContivService) {
+	if service.SessionAffinityTimeout == 0 {
+		return
+	}
+	sc.Log.WithFields(logging.Fields{
+		"service": service.ID,
+		"timeout": service.SessionAffinityTimeout,
+	}).Warn("ClientIP session affinity is not supported by VPP/NAT, connections are load-balanced independently")
+}
+
+// checkPortProtocols warns about the service ports that cannot be load-balanced.
+// NAT44 translates only TCP, UDP and ICMP (the protocols of its static mappings),
+// SCTP ports are therefore reachable only via DSR routes (cluster and external IPs
+// of services in the Direct Server Return mode).
+// TODO: configure NAT mappings for SCTP ports
+func (sc *ServiceConfigurator) checkPortProtocols(service *ContivService) {
+	for portName, port := range service.Ports {
+		if port.Protocol != SCTP || (service.DSR && port.NodePort == 0) {
+			continue
+		}
+		sc.Log.WithFields(logging.Fields{
+			"service": service.ID,
+			"port":    portName,
+		}).Warn("SCTP is not supported by VPP/NAT, the service port is not load-balanced")
+	}
+}
+
 func (sc *ServiceConfigurator) getNodeIP() (net.IP, error) {
 	nodeIP := sc.Contiv.GetNodeIP()
 	if nodeIP == nil {
@@ -17,11 +17,15 @@
 package configurator
 
 import (
+	"net"
 	"testing"
 
 	"github.com/ligato/cn-infra/logging"
 	"github.com/ligato/cn-infra/logging/logrus"
 	"github.com/onsi/gomega"
+
+	"github.com/contiv/vpp/mock/contiv"
+	"github.com/contiv/vpp/plugins/service/configurator/bin_api/nat"
 )
 
 func TestSomething(t *testing.T) {
@@ -30,3 +34,372 @@ func TestSomething(t *testing.T) {
 	logger.SetLevel(logging.DebugLevel)
 	logger.Debug("TestSomething")
 }
+
+func nodePortService(trafficPolicy TrafficPolicyType) *ContivService {
+	service := NewContivService()
+	service.TrafficPolicy = trafficPolicy
+	service.ClusterIP = net.ParseIP("10.96.0.10")
+	service.Ports["http"] = &ServicePort{Protocol: TCP, Port: 80, NodePort: 30080}
+	service.Backends["http"] = []*ServiceBackend{
+		{IP: net.ParseIP("10.1.1.3"), Port: 8080, Local: true},
+		{IP: net.ParseIP("10.1.2.3"), Port: 8080, Local: false},
+	}
+	return service
+}
+
+func findNATMapping(mappings []*NATMapping, externalIP string, externalPort uint16) *NATMapping {
+	for _, mapping := range mappings {
+		if mapping.ExternalIP.Equal(net.ParseIP(externalIP)) && mapping.ExternalPort == externalPort {
+			return mapping
+		}
+	}
+	return nil
+}
+
+func TestExportNodePortMappings(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	contivMock := contiv.NewMockContiv()
+	contivMock.SetNodeIP(net.ParseIP("192.168.16.1"))
+	sc := &ServiceConfigurator{
+		Deps: Deps{
+			Log:    logrus.DefaultLogger(),
+			Contiv: contivMock,
+		},
+	}
+
+	// cluster-wide: node port load-balanced across all backends with the source translated
+	mappings, err := sc.exportNATMappings(nodePortService(ClusterWide))
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(mappings).To(gomega.HaveLen(2))
+	clusterIP := findNATMapping(mappings, "10.96.0.10", 80)
+	gomega.Expect(clusterIP).ToNot(gomega.BeNil())
+	gomega.Expect(clusterIP.Locals).To(gomega.HaveLen(2))
+	gomega.Expect(clusterIP.TwiceNAT).To(gomega.BeFalse())
+	nodePort := findNATMapping(mappings, "192.168.16.1", 30080)
+	gomega.Expect(nodePort).ToNot(gomega.BeNil())
+	gomega.Expect(nodePort.Protocol).To(gomega.Equal(TCP))
+	gomega.Expect(nodePort.Locals).To(gomega.HaveLen(2))
+	gomega.Expect(nodePort.TwiceNAT).To(gomega.BeTrue())
+
+	// node-local: node port load-balanced across local backends only, cluster IP is not affected
+	mappings, err = sc.exportNATMappings(nodePortService(NodeLocal))
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(mappings).To(gomega.HaveLen(2))
+	clusterIP = findNATMapping(mappings, "10.96.0.10", 80)
+	gomega.Expect(clusterIP.Locals).To(gomega.HaveLen(2))
+	nodePort = findNATMapping(mappings, "192.168.16.1", 30080)
+	gomega.Expect(nodePort.TwiceNAT).To(gomega.BeFalse())
+	gomega.Expect(nodePort.Locals).To(gomega.HaveLen(1))
+	gomega.Expect(nodePort.Locals[0].Address.Equal(net.ParseIP("10.1.1.3"))).To(gomega.BeTrue())
+	gomega.Expect(nodePort.Locals[0].Probability).To(gomega.BeEquivalentTo(1))
+
+	// node-local without local backends: the node port is not exposed
+	service := nodePortService(NodeLocal)
+	service.Backends["http"] = service.Backends["http"][1:]
+	mappings, err = sc.exportNATMappings(service)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(mappings).To(gomega.HaveLen(1))
+	gomega.Expect(findNATMapping(mappings, "192.168.16.1", 30080)).To(gomega.BeNil())
+
+	// node IP is required for node ports
+	contivMock.SetNodeIP(nil)
+	_, err = sc.exportNATMappings(nodePortService(ClusterWide))
+	gomega.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestExportProxyARPAddrs(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	service := nodePortService(ClusterWide)
+	service.ExternalIPs.Add(net.ParseIP("192.168.100.10"))
+	service.ExternalIPs.Add(net.ParseIP("2001:db8::10"))
+	service.OwnsExternalIPs = true
+
+	// disabled
+	sc := &ServiceConfigurator{Deps: Deps{Log: logrus.DefaultLogger()}}
+	gomega.Expect(sc.exportProxyARPAddrs(service)).To(gomega.BeEmpty())
+
+	// only IPv4 external IPs, not the cluster IP
+	sc.ProxyARP = true
+	addrs := sc.exportProxyARPAddrs(service)
+	gomega.Expect(addrs).To(gomega.HaveLen(1))
+	gomega.Expect(addrs[0].Equal(net.ParseIP("192.168.100.10"))).To(gomega.BeTrue())
+
+	// external IPs owned by another node
+	service.OwnsExternalIPs = false
+	gomega.Expect(sc.exportProxyARPAddrs(service)).To(gomega.BeEmpty())
+}
+
+func TestParseIPRanges(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	ranges, err := ParseIPRanges([]string{"192.168.100.10", "192.168.100.0/28", "192.168.200.1-192.168.200.20",
+		"2001:db8::/120"})
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(ranges).To(gomega.HaveLen(4))
+	gomega.Expect(ranges[0].String()).To(gomega.Equal("192.168.100.10"))
+	gomega.Expect(ranges[1].String()).To(gomega.Equal("192.168.100.0-192.168.100.15"))
+	gomega.Expect(ranges[2].String()).To(gomega.Equal("192.168.200.1-192.168.200.20"))
+	gomega.Expect(ranges[3].IsIPv6()).To(gomega.BeTrue())
+	gomega.Expect(ranges[3].Addresses()).To(gomega.HaveLen(256))
+
+	// invalid ranges
+	for _, invalid := range []string{"host", "192.168.200.20-192.168.200.1", "192.168.200.1-2001:db8::1",
+		"2001:db8::/64"} {
+		_, err = ParseIPRanges([]string{invalid})
+		gomega.Expect(err).NotTo(gomega.BeNil(), invalid)
+	}
+}
+
+func TestExportProxyARPRanges(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	contivMock := contiv.NewMockContiv()
+	contivMock.SetPodNetwork("10.1.1.0/24")
+	ranges, _ := ParseIPRanges([]string{"192.168.100.0/28"})
+	sc := &ServiceConfigurator{Deps: Deps{Log: logrus.DefaultLogger(), Contiv: contivMock, ProxyARPRanges: ranges}}
+	gomega.Expect(sc.exportProxyARPRanges()).To(gomega.HaveLen(1))
+
+	// with the pod network of the node
+	sc.ProxyARPPodNetwork = true
+	ranges = sc.exportProxyARPRanges()
+	gomega.Expect(ranges).To(gomega.HaveLen(2))
+	gomega.Expect(ranges[1].String()).To(gomega.Equal("10.1.1.0-10.1.1.255"))
+}
+
+func TestAdvertisedAddrs(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	contivMock := contiv.NewMockContiv()
+	sc := &ServiceConfigurator{Deps: Deps{Log: logrus.DefaultLogger(), Contiv: contivMock}}
+	sc.Init()
+
+	service1 := nodePortService(ClusterWide)
+	service1.ExternalIPs.Add(net.ParseIP("192.168.100.10"))
+	service1.ExternalIPs.Add(net.ParseIP("2001:db8::10"))
+	service2 := nodePortService(ClusterWide)
+	service2.ExternalIPs.Add(net.ParseIP("192.168.100.10"))
+	service2.OwnsExternalIPs = true
+
+	// external IPs owned by another node are not advertised
+	sc.syncAdvertisedAddrs([]net.IP{}, sc.exportAdvertisedAddrs(service1))
+	gomega.Expect(contivMock.GetAdvertisedRoutes()).To(gomega.BeEmpty())
+
+	// only IPv4 external IPs are advertised
+	service1.OwnsExternalIPs = true
+	sc.syncAdvertisedAddrs([]net.IP{}, sc.exportAdvertisedAddrs(service1))
+	gomega.Expect(contivMock.GetAdvertisedRoutes()).To(gomega.HaveLen(1))
+	gomega.Expect(contivMock.GetAdvertisedRoutes()).To(gomega.HaveKey("192.168.100.10/32"))
+
+	// the route is withdrawn once no service with the external IP is left
+	sc.syncAdvertisedAddrs([]net.IP{}, sc.exportAdvertisedAddrs(service2))
+	sc.syncAdvertisedAddrs(sc.exportAdvertisedAddrs(service1), []net.IP{})
+	gomega.Expect(contivMock.GetAdvertisedRoutes()).To(gomega.HaveKey("192.168.100.10/32"))
+	sc.syncAdvertisedAddrs(sc.exportAdvertisedAddrs(service2), []net.IP{})
+	gomega.Expect(contivMock.GetAdvertisedRoutes()).To(gomega.BeEmpty())
+
+	// resync
+	contivMock.AdvertiseRoute(hostPrefix(net.ParseIP("192.168.100.20")), true)
+	sc.advertisedAddrs["192.168.100.20"] = 1
+	sc.resyncAdvertisedAddrs([]*ContivService{service1, service2})
+	gomega.Expect(contivMock.GetAdvertisedRoutes()).To(gomega.HaveLen(1))
+	gomega.Expect(contivMock.GetAdvertisedRoutes()).To(gomega.HaveKey("192.168.100.10/32"))
+	gomega.Expect(sc.advertisedAddrs["192.168.100.10"]).To(gomega.BeEquivalentTo(2))
+}
+
+func TestExportDSRRoutes(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	contivMock := contiv.NewMockContiv()
+	contivMock.SetNodeIP(net.ParseIP("192.168.16.1"))
+	sc := &ServiceConfigurator{
+		Deps: Deps{
+			Log:    logrus.DefaultLogger(),
+			Contiv: contivMock,
+		},
+	}
+
+	service := nodePortService(ClusterWide)
+	service.ExternalIPs.Add(net.ParseIP("192.168.100.10"))
+	gomega.Expect(sc.exportDSRRoutes(service)).To(gomega.BeEmpty())
+
+	// cluster IP and external IPs are routed to the local backend, only the node port is NATed
+	service.DSR = true
+	mappings, err := sc.exportNATMappings(service)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(mappings).To(gomega.HaveLen(1))
+	gomega.Expect(mappings[0].ExternalPort).To(gomega.BeEquivalentTo(30080))
+	routes := sc.exportDSRRoutes(service)
+	gomega.Expect(routes).To(gomega.HaveLen(2))
+	gomega.Expect(hasDSRRoute(routes, &DSRRoute{VIP: net.ParseIP("10.96.0.10"), NextHop: net.ParseIP("10.1.1.3")})).To(gomega.BeTrue())
+	gomega.Expect(hasDSRRoute(routes, &DSRRoute{VIP: net.ParseIP("192.168.100.10"), NextHop: net.ParseIP("10.1.1.3")})).To(gomega.BeTrue())
+
+	// without local backends the traffic is routed towards the remote ones
+	service.Backends["http"] = service.Backends["http"][1:]
+	routes = sc.exportDSRRoutes(service)
+	gomega.Expect(routes).To(gomega.HaveLen(2))
+	gomega.Expect(routes[0].NextHop.Equal(net.ParseIP("10.1.2.3"))).To(gomega.BeTrue())
+
+	// unless the traffic policy is node-local
+	service.TrafficPolicy = NodeLocal
+	gomega.Expect(sc.exportDSRRoutes(service)).To(gomega.BeEmpty())
+
+	// removal from the list of installed routes
+	removed := removeDSRRoute(routes, routes[0])
+	gomega.Expect(removed).To(gomega.HaveLen(1))
+	gomega.Expect(removed[0].Equal(routes[1])).To(gomega.BeTrue())
+}
+
+func TestExportSCTPService(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	contivMock := contiv.NewMockContiv()
+	contivMock.SetNodeIP(net.ParseIP("192.168.16.1"))
+	sc := &ServiceConfigurator{
+		Deps: Deps{
+			Log:    logrus.DefaultLogger(),
+			Contiv: contivMock,
+		},
+	}
+
+	service := nodePortService(ClusterWide)
+	service.Ports["s1ap"] = &ServicePort{Protocol: SCTP, Port: 36412}
+	service.Backends["s1ap"] = []*ServiceBackend{
+		{IP: net.ParseIP("10.1.1.3"), Port: 36412, Local: true},
+	}
+
+	// SCTP ports are not NATed
+	mappings, err := sc.exportNATMappings(service)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(mappings).To(gomega.HaveLen(2))
+	gomega.Expect(findNATMapping(mappings, "10.96.0.10", 36412)).To(gomega.BeNil())
+
+	// but can be routed in the DSR mode
+	service.DSR = true
+	routes := sc.exportDSRRoutes(service)
+	gomega.Expect(routes).To(gomega.HaveLen(1))
+	gomega.Expect(routes[0].VIP.Equal(net.ParseIP("10.96.0.10"))).To(gomega.BeTrue())
+}
+
+func TestExportTopologyAwareMappings(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	contivMock := contiv.NewMockContiv()
+	contivMock.SetNodeIP(net.ParseIP("192.168.16.1"))
+	sc := &ServiceConfigurator{
+		Deps: Deps{
+			Log:    logrus.DefaultLogger(),
+			Contiv: contivMock,
+		},
+	}
+
+	service := nodePortService(ClusterWide)
+	service.TopologyAware = true
+	service.Backends["http"] = append(service.Backends["http"],
+		&ServiceBackend{IP: net.ParseIP("10.1.3.3"), Port: 8080, SameZone: true})
+
+	// the local backend is preferred
+	mappings, err := sc.exportNATMappings(service)
+	gomega.Expect(err).To(gomega.BeNil())
+	clusterIP := findNATMapping(mappings, "10.96.0.10", 80)
+	gomega.Expect(clusterIP.Locals).To(gomega.HaveLen(1))
+	gomega.Expect(clusterIP.Locals[0].Address.Equal(net.ParseIP("10.1.1.3"))).To(gomega.BeTrue())
+	nodePort := findNATMapping(mappings, "192.168.16.1", 30080)
+	gomega.Expect(nodePort.Locals).To(gomega.HaveLen(1))
+	gomega.Expect(nodePort.TwiceNAT).To(gomega.BeTrue())
+
+	// then the backends in the same zone
+	service.Backends["http"] = service.Backends["http"][1:]
+	mappings, err = sc.exportNATMappings(service)
+	gomega.Expect(err).To(gomega.BeNil())
+	clusterIP = findNATMapping(mappings, "10.96.0.10", 80)
+	gomega.Expect(clusterIP.Locals).To(gomega.HaveLen(1))
+	gomega.Expect(clusterIP.Locals[0].Address.Equal(net.ParseIP("10.1.3.3"))).To(gomega.BeTrue())
+
+	// the same applies to the DSR routes
+	service.DSR = true
+	routes := sc.exportDSRRoutes(service)
+	gomega.Expect(routes).To(gomega.HaveLen(1))
+	gomega.Expect(routes[0].NextHop.Equal(net.ParseIP("10.1.3.3"))).To(gomega.BeTrue())
+	service.DSR = false
+
+	// with no close backends all of them are load-balanced
+	service.Backends["http"] = service.Backends["http"][:1]
+	service.Backends["http"] = append(service.Backends["http"],
+		&ServiceBackend{IP: net.ParseIP("10.1.4.3"), Port: 8080})
+	service.Backends["http"][0].SameZone = false
+	mappings, err = sc.exportNATMappings(service)
+	gomega.Expect(err).To(gomega.BeNil())
+	clusterIP = findNATMapping(mappings, "10.96.0.10", 80)
+	gomega.Expect(clusterIP.Locals).To(gomega.HaveLen(2))
+}
+
+func TestExportNodeLocalDNSMappings(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	sc := &ServiceConfigurator{
+		Deps: Deps{
+			Log:          logrus.DefaultLogger(),
+			Contiv:       contiv.NewMockContiv(),
+			NodeLocalDNS: net.ParseIP("169.254.20.10"),
+		},
+	}
+
+	service := NewContivService()
+	service.ClusterIP = net.ParseIP("10.96.0.10")
+	service.NodeLocalDNS = true
+	service.Ports["dns"] = &ServicePort{Protocol: UDP, Port: 53}
+	service.Ports["dns-tcp"] = &ServicePort{Protocol: TCP, Port: 53}
+	service.Ports["metrics"] = &ServicePort{Protocol: TCP, Port: 9153}
+	for port, number := range map[string]uint16{"dns": 53, "dns-tcp": 53, "metrics": 9153} {
+		service.Backends[port] = []*ServiceBackend{
+			{IP: net.ParseIP("10.1.1.3"), Port: number, Local: true},
+			{IP: net.ParseIP("10.1.2.3"), Port: number},
+		}
+	}
+
+	// DNS queries are translated to the node-local cache, other ports are load-balanced
+	mappings, err := sc.exportNATMappings(service)
+	gomega.Expect(err).To(gomega.BeNil())
+	gomega.Expect(mappings).To(gomega.HaveLen(3))
+	for _, mapping := range mappings {
+		if mapping.ExternalPort == 53 {
+			gomega.Expect(mapping.Locals).To(gomega.HaveLen(1))
+			gomega.Expect(mapping.Locals[0].Address.Equal(net.ParseIP("169.254.20.10"))).To(gomega.BeTrue())
+			gomega.Expect(mapping.Locals[0].Port).To(gomega.BeEquivalentTo(53))
+		} else {
+			gomega.Expect(mapping.Locals).To(gomega.HaveLen(2))
+		}
+	}
+
+	// without the cache address the service is load-balanced as usual
+	sc.NodeLocalDNS = nil
+	mappings, err = sc.exportNATMappings(service)
+	gomega.Expect(err).To(gomega.BeNil())
+	for _, mapping := range mappings {
+		gomega.Expect(mapping.Locals).To(gomega.HaveLen(2))
+	}
+}
+
+func TestNATSessionConfig(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	config := NATSessionConfig{
+		TranslationHashBuckets: 1048576,
+		MaxTranslationsPerUser: 10000,
+	}
+	gomega.Expect(config.StartupConfig()).To(gomega.Equal(
+		"nat {\n    translation hash buckets 1048576\n    max translations per user 10000\n}"))
+
+	// only the configured values are checked
+	vppConfig := &nat.NatShowConfigReply{
+		TranslationBuckets:     1048576,
+		UserBuckets:            128,
+		MaxTranslationsPerUser: 100,
+	}
+	gomega.Expect(config.mismatches(vppConfig)).To(gomega.Equal([]string{
+		"MaxTranslationsPerUser: configured 10000, VPP uses 100"}))
+	vppConfig.MaxTranslationsPerUser = 10000
+	gomega.Expect(config.mismatches(vppConfig)).To(gomega.BeEmpty())
+}
@@ -0,0 +1,220 @@
+/*
+ * // Copyright (c) 2018 Cisco and/or its affiliates.
+ * //
+ * // Licensed under the Apache License, Version 2.0 (the "License");
+ * // you may not use this file except in compliance with the License.
+ * // You may obtain a copy of the License at:
+ * //
+ * //     http://www.apache.org/licenses/LICENSE-2.0
+ * //
+ * // Unless required by applicable law or agreed to in writing, software
+ * // distributed under the License is distributed on an "AS IS" BASIS,
+ * // WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * // See the License for the specific language governing permissions and
+ * // limitations under the License.
+ */
+
+package configurator
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/ip"
+)
+
+// DSRRoute represents a single path of the multipath route steering the traffic
+// of a service in the Direct Server Return mode towards one of its backends.
+type DSRRoute struct {
+	VIP     net.IP
+	NextHop net.IP
+}
+
+// String converts a DSR route into a human-readable string.
+func (dr DSRRoute) String() string {
+	return fmt.Sprintf("DSR-Route <VIP:%s NextHop:%s>", dr.VIP.String(), dr.NextHop.String())
+}
+
+// Equal compares this route with another for equality.
+func (dr *DSRRoute) Equal(dr2 *DSRRoute) bool {
+	return dr.VIP.Equal(dr2.VIP) && dr.NextHop.Equal(dr2.NextHop)
+}
+
+// exportDSRRoutes exports the routes of a service in the Direct Server Return mode.
+// The cluster IP and the external IPs are routed without translation directly to the backends,
+// which need to accept the traffic destined to these IPs and reply from them.
+// If the service has local backends, the traffic is load-balanced (per flow) across them only,
+// otherwise it is routed towards the remote backends via the nodes they are deployed on.
+// This way the traffic arriving at a node with local backends is never routed further.
+// For topology-aware services, the remote backends in the same topology zone are preferred.
+func (sc *ServiceConfigurator) exportDSRRoutes(service *ContivService) []*DSRRoute {
+	routes := []*DSRRoute{}
+	if !service.DSR {
+		return routes
+	}
+
+	local := NewIPAddresses()
+	remote := NewIPAddresses()
+	sameZone := NewIPAddresses()
+	for _, backends := range service.Backends {
+		for _, backend := range backends {
+			if backend.IP.To4() == nil {
+				// TODO: IPv6 support
+				continue
+			}
+			if backend.Local {
+				local.Add(backend.IP)
+			} else {
+				remote.Add(backend.IP)
+				if backend.SameZone {
+					sameZone.Add(backend.IP)
+				}
+			}
+		}
+	}
+	nextHops := local
+	if len(local.List()) == 0 {
+		if service.TrafficPolicy == NodeLocal {
+			return routes
+		}
+		nextHops = remote
+		if service.TopologyAware && len(sameZone.List()) > 0 {
+			nextHops = sameZone
+		}
+	}
+
+	vips := service.ExternalIPs.Copy()
+	if service.ClusterIP != nil {
+		vips.Add(service.ClusterIP)
+	}
+	for _, vip := range vips.List() {
+		if vip.To4() == nil {
+			continue
+		}
+		for _, nextHop := range nextHops.List() {
+			routes = append(routes, &DSRRoute{VIP: vip, NextHop: nextHop})
+		}
+	}
+	return routes
+}
+
+// syncDSRRoutes updates the DSR routes in VPP so that <have> becomes <want>.
+func (sc *ServiceConfigurator) syncDSRRoutes(have []*DSRRoute, want []*DSRRoute) error {
+	// Remove obsolete routes.
+	for _, haveRoute := range have {
+		if hasDSRRoute(want, haveRoute) {
+			continue
+		}
+		if err := sc.setDSRRoute(haveRoute, false); err != nil {
+			sc.Log.WithFields(logging.Fields{
+				"err":   err,
+				"route": haveRoute.String(),
+			}).Error("Failed to remove DSR route")
+			return err
+		}
+		sc.dsrRoutes = removeDSRRoute(sc.dsrRoutes, haveRoute)
+	}
+
+	// Add new routes.
+	for _, wantRoute := range want {
+		if hasDSRRoute(have, wantRoute) {
+			continue
+		}
+		if err := sc.setDSRRoute(wantRoute, true); err != nil {
+			sc.Log.WithFields(logging.Fields{
+				"err":   err,
+				"route": wantRoute.String(),
+			}).Error("Failed to add DSR route")
+			return err
+		}
+		sc.dsrRoutes = append(sc.dsrRoutes, wantRoute)
+	}
+	return nil
+}
+
+// resyncDSRRoutes replaces the DSR routes with the routes of the given services.
+// The routes are not dumped from VPP (they cannot be told apart from the other routes),
+// only the routes installed since the start of the agent can be removed.
+func (sc *ServiceConfigurator) resyncDSRRoutes(services []*ContivService) error {
+	want := []*DSRRoute{}
+	for _, service := range services {
+		want = append(want, sc.exportDSRRoutes(service)...)
+	}
+	for _, route := range sc.dsrRoutes {
+		if !hasDSRRoute(want, route) {
+			if err := sc.setDSRRoute(route, false); err != nil {
+				// The route may have already been removed (e.g. by VPP restart) thus the error is ignored.
+				sc.Log.WithFields(logging.Fields{
+					"route": route.String(),
+					"err":   err,
+				}).Debug("Failed to remove DSR route")
+			}
+		}
+	}
+	for _, route := range want {
+		// Adding an already installed path is a no-op for VPP.
+		if err := sc.setDSRRoute(route, true); err != nil {
+			return err
+		}
+	}
+	sc.dsrRoutes = want
+	return nil
+}
+
+// setDSRRoute adds or removes a path of the multipath route of a DSR service.
+// The next hop (backend IP) is resolved recursively via the routes to pods.
+func (sc *ServiceConfigurator) setDSRRoute(route *DSRRoute, isAdd bool) error {
+	op := "remove"
+	req := &ip.IPAddDelRoute{
+		NextHopSwIfIndex: ^uint32(0),
+		IsMultipath:      1,
+		NextHopWeight:    1,
+		DstAddressLength: net.IPv4len * 8,
+	}
+	if isAdd {
+		req.IsAdd = 1
+		op = "add"
+	}
+	req.DstAddress = make([]byte, net.IPv4len)
+	copy(req.DstAddress, route.VIP.To4())
+	req.NextHopAddress = make([]byte, net.IPv4len)
+	copy(req.NextHopAddress, route.NextHop.To4())
+	reply := &ip.IPAddDelRouteReply{}
+
+	err := sc.GoVPPChan.SendRequest(req).ReceiveReply(reply)
+	if reply.Retval != 0 {
+		return fmt.Errorf("attempt to %s DSR route returned non zero error code (%v)",
+			op, reply.Retval)
+	}
+	if err != nil {
+		return err
+	}
+
+	sc.Log.WithFields(logging.Fields{
+		"route": route.String(),
+		"isAdd": isAdd,
+	}).Debug("DSR route was updated")
+	return nil
+}
+
+// hasDSRRoute returns true if the given route is in the list.
+func hasDSRRoute(routes []*DSRRoute, route *DSRRoute) bool {
+	for _, route2 := range routes {
+		if route2.Equal(route) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDSRRoute returns the list without the given route.
+func removeDSRRoute(routes []*DSRRoute, route *DSRRoute) []*DSRRoute {
+	newRoutes := []*DSRRoute{}
+	for _, route2 := range routes {
+		if !route2.Equal(route) {
+			newRoutes = append(newRoutes, route2)
+		}
+	}
+	return newRoutes
+}
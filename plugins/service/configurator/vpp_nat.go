@@ -33,6 +33,11 @@ type NATMapping struct {
 	ExternalPort uint16
 	Protocol     ProtocolType
 	Locals       []*NATMappingLocal
+
+	// TwiceNAT enables translation of the source address to an address
+	// from the twice-NAT pool (i.e. the Node IP) to get the response from
+	// a remote backend routed back through this node.
+	TwiceNAT bool
 }
 
 // NewNATMapping is a constructor for NATMapping.
@@ -51,8 +56,8 @@ func (nm NATMapping) String() string {
 			locals += ", "
 		}
 	}
-	return fmt.Sprintf("NAT-Mapping <ExternalIP:%s ExternalPort:%d Protocol:%s Locals:[%s]>",
-		nm.ExternalIP.String(), nm.ExternalPort, nm.Protocol.String(), locals)
+	return fmt.Sprintf("NAT-Mapping <ExternalIP:%s ExternalPort:%d Protocol:%s TwiceNAT:%t Locals:[%s]>",
+		nm.ExternalIP.String(), nm.ExternalPort, nm.Protocol.String(), nm.TwiceNAT, locals)
 }
 
 // NATMappingLocal represents a single backend for VPP NAT mapping.
@@ -103,7 +108,8 @@ func (nm *NATMapping) Equal(nm2 *NATMapping) bool {
 	// Compare the rest of the attributes.
 	return nm.ExternalIP.Equal(nm2.ExternalIP) &&
 		nm.ExternalPort == nm2.ExternalPort &&
-		nm.Protocol == nm2.Protocol
+		nm.Protocol == nm2.Protocol &&
+		nm.TwiceNAT == nm2.TwiceNAT
 }
 
 // Equal compares this local with another for equality.
@@ -188,20 +194,23 @@ func (sc *ServiceConfigurator) setInterfaceNATFeature(ifName string, isInside bo
 	return nil
 }
 
-// setNATAddress adds or removes given IP to/from the pool of NAT addresses.
-func (sc *ServiceConfigurator) setNATAddress(address net.IP, isAdd bool) error {
+// setNATAddress adds or removes given IP to/from the pool of NAT addresses
+// (or the pool of twice-NAT addresses if twiceNat is true).
+func (sc *ServiceConfigurator) setNATAddress(address net.IP, twiceNat bool, isAdd bool) error {
 	if address.To4() == nil {
 		// TODO: IPv6 support
 		return fmt.Errorf("'%s' is not IPv4 address", address.String())
 	}
 
 	req := &nat.Nat44AddDelAddressRange{
-		VrfID:    ^uint32(0),
-		TwiceNat: 0,
+		VrfID: ^uint32(0),
 	}
 	if isAdd {
 		req.IsAdd = 1
 	}
+	if twiceNat {
+		req.TwiceNat = 1
+	}
 	req.FirstIPAddress = make([]byte, net.IPv4len)
 	copy(req.FirstIPAddress, address.To4())
 	req.LastIPAddress = make([]byte, net.IPv4len)
@@ -253,7 +262,7 @@ func (sc *ServiceConfigurator) setNATMapping(mapping *NATMapping, isAdd bool) er
 			VrfID:             0,
 			Out2inOnly:        1,
 			AddrOnly:          0,
-			TwiceNat:          0,
+			TwiceNat:          boolToUint8(mapping.TwiceNAT),
 			Protocol:          uint8(mapping.Protocol),
 			ExternalPort:      mapping.ExternalPort,
 			ExternalSwIfIndex: ^uint32(0),
@@ -281,7 +290,7 @@ func (sc *ServiceConfigurator) setNATMapping(mapping *NATMapping, isAdd bool) er
 	req := &nat.Nat44AddDelLbStaticMapping{
 		VrfID:        0,
 		Out2inOnly:   1,
-		TwiceNat:     0,
+		TwiceNat:     boolToUint8(mapping.TwiceNAT),
 		Protocol:     uint8(mapping.Protocol),
 		ExternalPort: mapping.ExternalPort,
 		LocalNum:     uint8(len(mapping.Locals)),
@@ -373,8 +382,8 @@ func (sc *ServiceConfigurator) syncNATMappings(have []*NATMapping, want []*NATMa
 /***** Dumps *****/
 
 // dumpAddressPool returns all addresses currently installed in the NAT plugin's
-// address pool.
-func (sc *ServiceConfigurator) dumpAddressPool() (pool *IPAddresses, err error) {
+// address pool (or the twice-NAT address pool if twiceNat is true).
+func (sc *ServiceConfigurator) dumpAddressPool(twiceNat bool) (pool *IPAddresses, err error) {
 	pool = NewIPAddresses()
 	req := &nat.Nat44AddressDump{}
 	reqContext := sc.GoVPPChan.SendMultiRequest(req)
@@ -391,7 +400,7 @@ func (sc *ServiceConfigurator) dumpAddressPool() (pool *IPAddresses, err error)
 		}
 		addr := make(net.IP, net.IPv4len)
 		copy(addr, msg.IPAddress[:])
-		if msg.TwiceNat == 0 {
+		if (msg.TwiceNat == 1) == twiceNat {
 			pool.Add(addr)
 		}
 	}
@@ -415,7 +424,7 @@ func (sc *ServiceConfigurator) dumpNATMappings() ([]*NATMapping, error) {
 		if stop {
 			break
 		}
-		if msg.Out2inOnly == 0 || msg.TwiceNat == 1 ||
+		if msg.Out2inOnly == 0 ||
 			(msg.Protocol != uint8(TCP) && msg.Protocol != uint8(UDP)) {
 			// Mapping not installed by this plugin.
 			continue
@@ -426,6 +435,7 @@ func (sc *ServiceConfigurator) dumpNATMappings() ([]*NATMapping, error) {
 		copy(mapping.ExternalIP, msg.ExternalAddr)
 		mapping.ExternalPort = msg.ExternalPort
 		mapping.Protocol = ProtocolType(msg.Protocol)
+		mapping.TwiceNAT = msg.TwiceNat == 1
 
 		// Construct the list of locals
 		for _, msgLocal := range msg.Locals {
@@ -454,7 +464,7 @@ func (sc *ServiceConfigurator) dumpNATMappings() ([]*NATMapping, error) {
 			break
 		}
 		if msg.Out2inOnly == 0 || msg.AddrOnly == 1 || msg.ExternalSwIfIndex != ^uint32(0) ||
-			msg.TwiceNat == 1 || (msg.Protocol != uint8(TCP) && msg.Protocol != uint8(UDP)) {
+			(msg.Protocol != uint8(TCP) && msg.Protocol != uint8(UDP)) {
 			// Mapping not installed by this plugin.
 			continue
 		}
@@ -464,6 +474,7 @@ func (sc *ServiceConfigurator) dumpNATMappings() ([]*NATMapping, error) {
 		copy(mapping.ExternalIP, msg.ExternalIPAddress)
 		mapping.ExternalPort = msg.ExternalPort
 		mapping.Protocol = ProtocolType(msg.Protocol)
+		mapping.TwiceNAT = msg.TwiceNat == 1
 
 		// Construct the single local.
 		local := &NATMappingLocal{
@@ -514,3 +525,11 @@ func (sc *ServiceConfigurator) dumpNATInterfaces() (frontend, backend Interfaces
 
 	return frontend, backend, nil
 }
+
+// boolToUint8 converts a boolean flag into the representation used by the binary API.
+func boolToUint8(flag bool) uint8 {
+	if flag {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+/*
+ * // Copyright (c) 2018 Cisco and/or its affiliates.
+ * //
+ * // Licensed under the Apache License, Version 2.0 (the "License");
+ * // you may not use this file except in compliance with the License.
+ * // You may obtain a copy of the License at:
+ * //
+ * //     http://www.apache.org/licenses/LICENSE-2.0
+ * //
+ * // Unless required by applicable law or agreed to in writing, software
+ * // distributed under the License is distributed on an "AS IS" BASIS,
+ * // WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * // See the License for the specific language governing permissions and
+ * // limitations under the License.
+ */
+
+package configurator
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/contiv/vpp/plugins/service/configurator/bin_api/nat"
+)
+
+// NATSessionConfig holds the sizing of the NAT44 session tables of VPP.
+// The sizing can be set only in the "nat" section of the VPP startup config (there is
+// no binary API for it), the configurator checks that the running VPP matches it.
+// Zero values are not checked.
+type NATSessionConfig struct {
+	// TranslationHashBuckets is the number of buckets of the session hash tables.
+	TranslationHashBuckets uint32
+
+	// TranslationHashMemory is the memory size (in bytes) of the session hash tables.
+	TranslationHashMemory uint32
+
+	// UserHashBuckets is the number of buckets of the hash table of the inside
+	// addresses (users) with sessions.
+	UserHashBuckets uint32
+
+	// UserHashMemory is the memory size (in bytes) of the hash table of the users.
+	UserHashMemory uint32
+
+	// MaxTranslationsPerUser is the max. number of sessions of one inside address,
+	// i.e. of one pod, the oldest session of the pod is recycled over the limit.
+	MaxTranslationsPerUser uint32
+}
+
+// StartupConfig returns the "nat" section of the VPP startup config with the sizing.
+func (c NATSessionConfig) StartupConfig() string {
+	var buf bytes.Buffer
+	buf.WriteString("nat {\n")
+	for _, param := range []struct {
+		name  string
+		value uint32
+	}{
+		{"translation hash buckets", c.TranslationHashBuckets},
+		{"translation hash memory", c.TranslationHashMemory},
+		{"user hash buckets", c.UserHashBuckets},
+		{"user hash memory", c.UserHashMemory},
+		{"max translations per user", c.MaxTranslationsPerUser},
+	} {
+		if param.value != 0 {
+			fmt.Fprintf(&buf, "    %s %d\n", param.name, param.value)
+		}
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// mismatches returns the differences between the sizing and the NAT config of the running VPP.
+func (c NATSessionConfig) mismatches(vppConfig *nat.NatShowConfigReply) []string {
+	var mismatches []string
+	for _, param := range []struct {
+		name       string
+		configured uint32
+		running    uint32
+	}{
+		{"TranslationHashBuckets", c.TranslationHashBuckets, vppConfig.TranslationBuckets},
+		{"TranslationHashMemory", c.TranslationHashMemory, vppConfig.TranslationMemorySize},
+		{"UserHashBuckets", c.UserHashBuckets, vppConfig.UserBuckets},
+		{"UserHashMemory", c.UserHashMemory, vppConfig.UserMemorySize},
+		{"MaxTranslationsPerUser", c.MaxTranslationsPerUser, vppConfig.MaxTranslationsPerUser},
+	} {
+		if param.configured != 0 && param.configured != param.running {
+			mismatches = append(mismatches,
+				fmt.Sprintf("%s: configured %d, VPP uses %d", param.name, param.configured, param.running))
+		}
+	}
+	return mismatches
+}
+
+// checkNATSessionConfig compares the sizing of the NAT44 session tables of the running VPP
+// with the configured one and reports the startup config needed to apply the configured sizing.
+func (sc *ServiceConfigurator) checkNATSessionConfig() error {
+	req := &nat.NatShowConfig{}
+	reply := &nat.NatShowConfigReply{}
+	err := sc.GoVPPChan.SendRequest(req).ReceiveReply(reply)
+	if err != nil {
+		return err
+	}
+	if reply.Retval != 0 {
+		return fmt.Errorf("attempt to read NAT config returned non zero error code (%v)", reply.Retval)
+	}
+	sc.Log.Infof("NAT44 sessions: %d translation buckets, %d user buckets, max. %d translations per user",
+		reply.TranslationBuckets, reply.UserBuckets, reply.MaxTranslationsPerUser)
+
+	if mismatches := sc.NATSessions.mismatches(reply); len(mismatches) > 0 {
+		sc.Log.Warnf("NAT44 session tables of VPP do not match the configuration (%v), "+
+			"add the following section to the VPP startup config and restart VPP:\n%s",
+			mismatches, sc.NATSessions.StartupConfig())
+	}
+	return nil
+}
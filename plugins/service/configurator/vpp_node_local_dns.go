@@ -0,0 +1,108 @@
+/*
+ * // Copyright (c) 2018 Cisco and/or its affiliates.
+ * //
+ * // Licensed under the Apache License, Version 2.0 (the "License");
+ * // you may not use this file except in compliance with the License.
+ * // You may obtain a copy of the License at:
+ * //
+ * //     http://www.apache.org/licenses/LICENSE-2.0
+ * //
+ * // Unless required by applicable law or agreed to in writing, software
+ * // distributed under the License is distributed on an "AS IS" BASIS,
+ * // WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * // See the License for the specific language governing permissions and
+ * // limitations under the License.
+ */
+
+package configurator
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/ip"
+)
+
+// dnsPort is the port of the DNS service steered to the node-local DNS cache.
+const dnsPort = 53
+
+// exportNodeLocalDNSMapping exports NAT mapping translating the DNS queries destined
+// to the cluster IP of the DNS service to the node-local DNS cache, which listens
+// on the same port in the host stack.
+func (sc *ServiceConfigurator) exportNodeLocalDNSMapping(service *ContivService, portName string) *NATMapping {
+	mapping := NewNATMapping()
+	mapping.ExternalIP = service.ClusterIP
+	mapping.ExternalPort = dnsPort
+	mapping.Protocol = service.Ports[portName].Protocol
+	mapping.Locals = append(mapping.Locals, &NATMappingLocal{
+		Address:     sc.NodeLocalDNS,
+		Port:        dnsPort,
+		Probability: 1,
+	})
+	return mapping
+}
+
+// resyncNodeLocalDNSRoute routes the address of the node-local DNS cache via the host
+// interconnect, replacing the route to the previously configured address.
+func (sc *ServiceConfigurator) resyncNodeLocalDNSRoute() error {
+	if sc.nodeLocalDNSRoute != nil && !sc.nodeLocalDNSRoute.Equal(sc.NodeLocalDNS) {
+		if err := sc.setNodeLocalDNSRoute(sc.nodeLocalDNSRoute, false); err != nil {
+			// The route may have already been removed (e.g. by VPP restart) thus the error is ignored.
+			sc.Log.WithFields(logging.Fields{
+				"address": sc.nodeLocalDNSRoute,
+				"err":     err,
+			}).Debug("Failed to remove route to the node-local DNS cache")
+		}
+		sc.nodeLocalDNSRoute = nil
+	}
+	if sc.NodeLocalDNS == nil {
+		return nil
+	}
+	// Adding an already installed route is a no-op for VPP.
+	if err := sc.setNodeLocalDNSRoute(sc.NodeLocalDNS, true); err != nil {
+		return err
+	}
+	sc.nodeLocalDNSRoute = sc.NodeLocalDNS
+	return nil
+}
+
+// setNodeLocalDNSRoute adds or removes the route of the given address via the host interconnect.
+// The route has no next hop, the host answers ARP requests for its local addresses
+// on any interface.
+func (sc *ServiceConfigurator) setNodeLocalDNSRoute(address net.IP, isAdd bool) error {
+	ifName := sc.Contiv.GetHostInterconnectIfName()
+	ifIndex, _, exists := sc.VPP.GetSwIfIndexes().LookupIdx(ifName)
+	if !exists {
+		return fmt.Errorf("failed to get interface index corresponding to interface name: %s", ifName)
+	}
+
+	op := "remove"
+	req := &ip.IPAddDelRoute{
+		NextHopSwIfIndex: ifIndex,
+		DstAddressLength: net.IPv4len * 8,
+	}
+	if isAdd {
+		req.IsAdd = 1
+		op = "add"
+	}
+	req.DstAddress = make([]byte, net.IPv4len)
+	copy(req.DstAddress, address.To4())
+	req.NextHopAddress = make([]byte, net.IPv4len)
+	reply := &ip.IPAddDelRouteReply{}
+
+	err := sc.GoVPPChan.SendRequest(req).ReceiveReply(reply)
+	if reply.Retval != 0 {
+		return fmt.Errorf("attempt to %s route to the node-local DNS cache returned non zero error code (%v)",
+			op, reply.Retval)
+	}
+	if err != nil {
+		return err
+	}
+
+	sc.Log.WithFields(logging.Fields{
+		"address": address,
+		"isAdd":   isAdd,
+	}).Debug("Route to the node-local DNS cache was updated")
+	return nil
+}
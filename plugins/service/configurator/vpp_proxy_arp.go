@@ -0,0 +1,377 @@
+/*
+ * // Copyright (c) 2018 Cisco and/or its affiliates.
+ * //
+ * // Licensed under the Apache License, Version 2.0 (the "License");
+ * // you may not use this file except in compliance with the License.
+ * // You may obtain a copy of the License at:
+ * //
+ * //     http://www.apache.org/licenses/LICENSE-2.0
+ * //
+ * // Unless required by applicable law or agreed to in writing, software
+ * // distributed under the License is distributed on an "AS IS" BASIS,
+ * // WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * // See the License for the specific language governing permissions and
+ * // limitations under the License.
+ */
+
+package configurator
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/ip"
+)
+
+// maxNDProxyRangeSize is the max. number of addresses of an IPv6 proxy range. VPP proxies
+// neighbor discovery only for individual addresses, IPv6 ranges are therefore expanded.
+const maxNDProxyRangeSize = 256
+
+// IPRange is a range of IP addresses (of the same family) VPP answers ARP or neighbor
+// solicitation requests for.
+type IPRange struct {
+	Low  net.IP
+	High net.IP
+}
+
+// String converts the range into a human-readable string.
+func (r IPRange) String() string {
+	if r.Low.Equal(r.High) {
+		return r.Low.String()
+	}
+	return r.Low.String() + "-" + r.High.String()
+}
+
+// IsIPv6 returns true for a range of IPv6 addresses.
+func (r IPRange) IsIPv6() bool {
+	return r.Low.To4() == nil
+}
+
+// Addresses returns the addresses of the range, at most maxNDProxyRangeSize+1 of them.
+func (r IPRange) Addresses() []net.IP {
+	addrs := []net.IP{}
+	for addr := r.Low; bytes.Compare(addr, r.High) <= 0; addr = nextIP(addr) {
+		addrs = append(addrs, addr)
+		if len(addrs) > maxNDProxyRangeSize {
+			break
+		}
+	}
+	return addrs
+}
+
+// ParseIPRanges parses proxy ARP/NDP ranges, each given as a single address, a subnet (CIDR)
+// or as two addresses separated by "-".
+func ParseIPRanges(ranges []string) ([]IPRange, error) {
+	parsed := []IPRange{}
+	for _, value := range ranges {
+		var r IPRange
+		if _, subnet, err := net.ParseCIDR(value); err == nil {
+			r = subnetRange(subnet)
+		} else {
+			bounds := strings.SplitN(value, "-", 2)
+			r.Low = net.ParseIP(strings.TrimSpace(bounds[0]))
+			r.High = r.Low
+			if len(bounds) == 2 {
+				r.High = net.ParseIP(strings.TrimSpace(bounds[1]))
+			}
+			if r.Low == nil || r.High == nil {
+				return nil, fmt.Errorf("invalid IP range %q", value)
+			}
+		}
+		if r.Low.To4() != nil {
+			r.Low, r.High = r.Low.To4(), r.High.To4()
+		} else {
+			r.Low, r.High = r.Low.To16(), r.High.To16()
+		}
+		if r.High == nil || len(r.Low) != len(r.High) || bytes.Compare(r.Low, r.High) > 0 {
+			return nil, fmt.Errorf("invalid IP range %q", value)
+		}
+		if r.IsIPv6() && len(r.Addresses()) > maxNDProxyRangeSize {
+			return nil, fmt.Errorf("IPv6 range %q has more than %d addresses", value, maxNDProxyRangeSize)
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+// subnetRange returns the range of all addresses of the given subnet.
+func subnetRange(subnet *net.IPNet) IPRange {
+	low := subnet.IP.Mask(subnet.Mask)
+	high := make(net.IP, len(low))
+	for i := range low {
+		high[i] = low[i] | ^subnet.Mask[i]
+	}
+	return IPRange{Low: low, High: high}
+}
+
+// nextIP returns the address following the given one.
+func nextIP(addr net.IP) net.IP {
+	next := make(net.IP, len(addr))
+	copy(next, addr)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// exportProxyARPAddrs returns the list of external IPs of the service for which
+// this node should answer ARP requests.
+func (sc *ServiceConfigurator) exportProxyARPAddrs(service *ContivService) []net.IP {
+	addrs := []net.IP{}
+	if !sc.ProxyARP || !service.OwnsExternalIPs {
+		return addrs
+	}
+	for _, externalIP := range service.ExternalIPs.List() {
+		if externalIP.To4() == nil {
+			// TODO: IPv6 support (neighbor advertisements)
+			continue
+		}
+		addrs = append(addrs, externalIP)
+	}
+	return addrs
+}
+
+// syncProxyARPAddrs updates the set of addresses VPP answers ARP requests for
+// so that <have> becomes <want>. The same external IP may be shared by multiple
+// services, the addresses are therefore reference-counted.
+func (sc *ServiceConfigurator) syncProxyARPAddrs(have []net.IP, want []net.IP) error {
+	haveSet := NewIPAddresses(have...)
+	wantSet := NewIPAddresses(want...)
+
+	// Remove obsolete addresses.
+	for _, addr := range haveSet.List() {
+		if wantSet.Has(addr) {
+			continue
+		}
+		sc.proxyARPAddrs[addr.String()]--
+		if sc.proxyARPAddrs[addr.String()] > 0 {
+			continue
+		}
+		delete(sc.proxyARPAddrs, addr.String())
+		if err := sc.setProxyARPAddr(addr, false); err != nil {
+			return err
+		}
+	}
+
+	// Add new addresses.
+	for _, addr := range wantSet.List() {
+		if haveSet.Has(addr) {
+			continue
+		}
+		sc.proxyARPAddrs[addr.String()]++
+		if sc.proxyARPAddrs[addr.String()] > 1 {
+			continue
+		}
+		if err := sc.setProxyARPAddr(addr, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportProxyARPRanges returns the configured proxy ARP/NDP ranges, including the pod network
+// of this node if enabled.
+func (sc *ServiceConfigurator) exportProxyARPRanges() []IPRange {
+	ranges := append([]IPRange{}, sc.ProxyARPRanges...)
+	if sc.ProxyARPPodNetwork {
+		if podNetwork := sc.Contiv.GetPodNetwork(); podNetwork != nil {
+			ranges = append(ranges, subnetRange(podNetwork))
+		}
+	}
+	return ranges
+}
+
+// resyncProxyARP enables proxy ARP on the physical interfaces and replaces the set of addresses
+// VPP answers ARP requests for with the external IPs owned by this node and with the configured ranges.
+// VPP does not allow to dump the proxy ARP ranges, therefore only the addresses installed since
+// the start of the agent can be removed. With proxy ARP disabled (by the reload of the configuration)
+// the installed addresses are removed.
+func (sc *ServiceConfigurator) resyncProxyARP(services []*ContivService) error {
+	ranges := sc.exportProxyARPRanges()
+	if sc.ProxyARP || len(ranges) > 0 {
+		for _, physIf := range sc.Contiv.GetPhysicalIfNames() {
+			if err := sc.setInterfaceProxyARP(physIf, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	proxyARPAddrs := make(map[string]int)
+	for _, service := range services {
+		for _, addr := range sc.exportProxyARPAddrs(service) {
+			proxyARPAddrs[addr.String()]++
+		}
+	}
+	for addr := range sc.proxyARPAddrs {
+		if _, wanted := proxyARPAddrs[addr]; !wanted {
+			if err := sc.setProxyARPAddr(net.ParseIP(addr), false); err != nil {
+				// The address may have already been removed (e.g. by VPP restart) thus the error is ignored.
+				sc.Log.WithFields(logging.Fields{
+					"address": addr,
+					"err":     err,
+				}).Debug("Failed to remove proxy ARP address")
+			}
+		}
+	}
+	for addr := range proxyARPAddrs {
+		// Adding an already configured address is a no-op for VPP.
+		if err := sc.setProxyARPAddr(net.ParseIP(addr), true); err != nil {
+			return err
+		}
+	}
+	sc.proxyARPAddrs = proxyARPAddrs
+	return sc.syncProxyARPRanges(ranges)
+}
+
+// syncProxyARPRanges replaces the installed proxy ARP/NDP ranges with the given ones.
+// IPv4 ranges are installed as proxy ARP ranges, IPv6 ranges as NDP proxy entries of their
+// addresses on the physical interfaces.
+func (sc *ServiceConfigurator) syncProxyARPRanges(ranges []IPRange) error {
+	wanted := make(map[string]IPRange)
+	for _, r := range ranges {
+		wanted[r.String()] = r
+	}
+	for key, r := range sc.proxyARPRanges {
+		if _, isWanted := wanted[key]; isWanted {
+			continue
+		}
+		if err := sc.setProxyARPRange(r, false); err != nil {
+			// The range may have already been removed (e.g. by VPP restart) thus the error is ignored.
+			sc.Log.WithFields(logging.Fields{
+				"range": key,
+				"err":   err,
+			}).Debug("Failed to remove proxy ARP range")
+		}
+		delete(sc.proxyARPRanges, key)
+	}
+	for key, r := range wanted {
+		if _, installed := sc.proxyARPRanges[key]; installed {
+			continue
+		}
+		if err := sc.setProxyARPRange(r, true); err != nil {
+			return err
+		}
+		sc.proxyARPRanges[key] = r
+	}
+	return nil
+}
+
+// setProxyARPRange adds or removes the given range to/from the proxy ARP ranges (IPv4)
+// or the NDP proxy entries of the physical interfaces (IPv6).
+func (sc *ServiceConfigurator) setProxyARPRange(r IPRange, isAdd bool) error {
+	if !r.IsIPv6() {
+		return sc.setProxyARPAddrRange(r.Low, r.High, isAdd)
+	}
+	for _, physIf := range sc.Contiv.GetPhysicalIfNames() {
+		for _, addr := range r.Addresses() {
+			if err := sc.setNDProxyAddr(physIf, addr, isAdd); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setProxyARPAddr adds or removes the given IP address to/from the proxy ARP ranges.
+func (sc *ServiceConfigurator) setProxyARPAddr(address net.IP, isAdd bool) error {
+	return sc.setProxyARPAddrRange(address, address, isAdd)
+}
+
+// setProxyARPAddrRange adds or removes the given range of IPv4 addresses to/from the proxy ARP ranges.
+func (sc *ServiceConfigurator) setProxyARPAddrRange(low, high net.IP, isAdd bool) error {
+	req := &ip.ProxyArpAddDel{
+		VrfID: 0,
+	}
+	if isAdd {
+		req.IsAdd = 1
+	}
+	req.LowAddress = make([]byte, net.IPv4len)
+	copy(req.LowAddress, low.To4())
+	req.HiAddress = make([]byte, net.IPv4len)
+	copy(req.HiAddress, high.To4())
+	reply := &ip.ProxyArpAddDelReply{}
+
+	rangeStr := IPRange{Low: low, High: high}.String()
+	err := sc.GoVPPChan.SendRequest(req).ReceiveReply(reply)
+	if reply.Retval != 0 {
+		return fmt.Errorf("attempt to set proxy ARP for '%s' returned non zero error code (%v)",
+			rangeStr, reply.Retval)
+	}
+	if err != nil {
+		return err
+	}
+
+	sc.Log.WithFields(logging.Fields{
+		"address": rangeStr,
+		"isAdd":   isAdd,
+	}).Debug("Proxy ARP address was updated")
+	return nil
+}
+
+// setNDProxyAddr adds or removes NDP proxy entry of the given IPv6 address on the given interface.
+func (sc *ServiceConfigurator) setNDProxyAddr(ifName string, address net.IP, isAdd bool) error {
+	ifIndex, _, exists := sc.VPP.GetSwIfIndexes().LookupIdx(ifName)
+	if !exists {
+		return fmt.Errorf("failed to get interface index corresponding to interface name: %s", ifName)
+	}
+
+	req := &ip.IP6ndProxyAddDel{
+		SwIfIndex: ifIndex,
+	}
+	if !isAdd {
+		req.IsDel = 1
+	}
+	req.Address = make([]byte, net.IPv6len)
+	copy(req.Address, address.To16())
+	reply := &ip.IP6ndProxyAddDelReply{}
+
+	err := sc.GoVPPChan.SendRequest(req).ReceiveReply(reply)
+	if reply.Retval != 0 {
+		return fmt.Errorf("attempt to set NDP proxy for '%s' on interface '%s' returned non zero error code (%v)",
+			address.String(), ifName, reply.Retval)
+	}
+	if err != nil {
+		return err
+	}
+
+	sc.Log.WithFields(logging.Fields{
+		"address":   address.String(),
+		"interface": ifName,
+		"isAdd":     isAdd,
+	}).Debug("NDP proxy address was updated")
+	return nil
+}
+
+// setInterfaceProxyARP enables or disables proxy ARP on the given interface.
+func (sc *ServiceConfigurator) setInterfaceProxyARP(ifName string, enable bool) error {
+	ifIndex, _, exists := sc.VPP.GetSwIfIndexes().LookupIdx(ifName)
+	if !exists {
+		return fmt.Errorf("failed to get interface index corresponding to interface name: %s", ifName)
+	}
+
+	req := &ip.ProxyArpIntfcEnableDisable{
+		SwIfIndex: ifIndex,
+	}
+	if enable {
+		req.EnableDisable = 1
+	}
+	reply := &ip.ProxyArpIntfcEnableDisableReply{}
+
+	err := sc.GoVPPChan.SendRequest(req).ReceiveReply(reply)
+	if reply.Retval != 0 {
+		return fmt.Errorf("attempt to enable proxy ARP for interface '%s' returned non zero error code (%v)",
+			ifName, reply.Retval)
+	}
+	if err != nil {
+		return err
+	}
+
+	sc.Log.Debugf("Proxy ARP was enabled for interface '%s'", ifName)
+	return nil
+}
@@ -21,7 +21,9 @@
 // in the direction from the up to the bottom. Each layer obtains service-related
 // data from the layer above and outputs them processed in some way into
 // the layer below. On the top there are K8s state data for endpoints and
-// services as reflected into ETCD by KSR. With each layer the abstraction level
+// services as reflected into ETCD by KSR (in clusters serving EndpointSlices,
+// KSR merges all slices of a service into a single instance of the endpoints
+// model, one subset per slice). With each layer the abstraction level
 // decreases until at the very bottom the corresponding set of NAT rules
 // is calculated and installed into the VPP via vpp-agent.
 //
@@ -31,7 +33,8 @@
 //	1. Service Plugin:
 //     - implements the Plugin interface for CN-Infra
 //     - initializes all layers, performs dependency injection
-//     - watches ETCD for changes written by KSR for endpoints and services
+//     - watches ETCD for changes written by KSR for endpoints, services, pods
+//       and nodes
 //     - propagates datasync events into the Service Processor without
 //       any pre-processing
 //     - postpones RESYNC until the Contiv plugin has finalized its RESYNC
@@ -46,8 +49,17 @@
 //     - combines endpoint data with service data into a less abstract service
 //       representation denoted as "ContivService":
 //         * service port is matched with endpoint port by the assigned name
-//         * based on the service type, collects all external IP addresses,
-//           i.e. addresses on which the service should be exposed
+//         * based on the service type, collects the cluster IP and all external
+//           IP addresses, i.e. addresses on which the service should be exposed
+//         * node ports are exposed on the Node IP
+//         * external IPs include LoadBalancer ingress IPs; the first node (by name)
+//           hosting a backend of the service is selected to own them
+//         * backends are marked as local and as deployed in the topology zone
+//           of this node, learned from the labels of the nodes
+//         * endpoints without the node name (host-network endpoints such as
+//           the API servers of multiple masters) are matched with the nodes
+//           by the node IP addresses, an endpoint of an unknown IP is considered
+//           to be local
 //     - maintains the set of interfaces connecting frontends (physical
 //	     interfaces and pods that do not run any service) and backends (pods
 //       which act as replicas of some service)
@@ -61,9 +73,54 @@
 //     - translates ContivService into the corresponding NAT configuration
 //     - applies out2in and in2out VPP/NAT's features on interfaces connecting
 //       frontends and backends, respectivelly
+//     - external traffic (node ports and external IPs) is subject to the external
+//       traffic policy of the service:
+//         * Local: load-balanced across node-local backends only, the source
+//           IP address is preserved
+//         * Cluster: load-balanced across all backends, the source IP address
+//           is translated to the Node IP (twice-NAT) so that the response
+//           from a remote backend is routed back through this node
+//     - ClientIP session affinity is not supported by the NAT plugin of the VPP
+//       version in use, connections of the same client may be load-balanced
+//       to different backends
+//     - services listed in the option DSRServices of the plugin configuration
+//       are in the Direct Server Return mode: their cluster IP and external IPs
+//       are routed (multipath, per flow) to the backends without translation
+//       and the replies bypass the load-balancing node
+//     - services listed in the option TopologyAwareServices of the plugin
+//       configuration are load-balanced across the node-local backends if there
+//       are any, otherwise across the backends on nodes in the same topology zone
+//       (node label topology.kubernetes.io/zone) if there are any, otherwise
+//       across all backends
+//     - with the option NodeLocalDNS of the plugin configuration, the DNS traffic
+//       (port 53) destined to the cluster IP of the DNS service (NodeLocalDNSService,
+//       kube-system/kube-dns by default) is translated to the node-local DNS cache
+//       listening on the given address in the host stack, which is routed via
+//       the host interconnect; the cache has to forward the cache misses to another
+//       service, its queries to the DNS service would be steered back to itself
+//     - headless services (clusterIP: None) are not NATed at all, DNS resolves
+//       them directly to the backend pods; remote pods are reached via routes
+//       to the pod subnets of other nodes, whose next hops (VXLAN BVIs)
+//       are resolved statically by the Contiv plugin without ARP flooding
+//     - SCTP ports are not translated, NAT44 handles only TCP, UDP and ICMP;
+//       SCTP services can be reached only in the DSR mode (via the cluster IP
+//       and the external IPs)
+//     - optionally (option ProxyARP of the plugin configuration), VPP answers
+//       ARP requests for the external IPs owned by this node
+//     - optionally, VPP answers ARP requests (neighbor solicitations for IPv6)
+//       for the ranges listed in the option ProxyARPRanges and for the pod
+//       network of this node (option ProxyARPPodNetwork)
+//     - if BGP is configured for the Contiv plugin, host routes to the IPv4
+//       external IPs owned by this node are advertised to the BGP peers
+//     - the sizing of the NAT44 session tables is given by the "nat" section
+//       of the VPP startup config, the configurator only checks on the first
+//       resync that it matches the option NATSessions of the plugin
+//       configuration
 //     - for each change, calculates the minimal diff, i.e. the smallest set
 //       of binary API request that need to be executed to get the NAT
 //       configuration in-sync with the state of K8s services
+//     - on SIGHUP, re-reads the plugin configuration and re-synchronizes
+//       the services with the new settings (ServiceProcessor.Reconfigure)
 //
 //
 // Diagram
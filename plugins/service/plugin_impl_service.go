@@ -16,7 +16,13 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/ligato/cn-infra/datasync"
 	"github.com/ligato/cn-infra/datasync/resync"
@@ -32,13 +38,14 @@ import (
 	"github.com/contiv/vpp/plugins/service/processor"
 
 	epmodel "github.com/contiv/vpp/plugins/ksr/model/endpoints"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	svcmodel "github.com/contiv/vpp/plugins/ksr/model/service"
 	"time"
 )
 
 // Plugin watches configuration of K8s resources (as reflected by KSR into ETCD)
-// for changes in services, endpoints and pods and updates the NAT configuration
+// for changes in services, endpoints, pods and nodes and updates the NAT configuration
 // in the VPP accordingly.
 type Plugin struct {
 	Deps
@@ -57,10 +64,64 @@ type Plugin struct {
 	pendingResync  datasync.ResyncEvent
 	pendingChanges []datasync.ChangeEvent
 
+	config       *Config
 	processor    *processor.ServiceProcessor
 	configurator *configurator.ServiceConfigurator
 }
 
+// Config holds the configuration of the service plugin.
+type Config struct {
+	// ProxyARP enables answering of ARP requests for external IPs and LoadBalancer
+	// ingress IPs of services. For each service, ARP requests are answered only
+	// by the node selected to own its external IPs (the first node by name hosting
+	// a backend of the service), as needed e.g. for MetalLB in layer 2 mode.
+	ProxyARP bool
+
+	// ProxyARPRanges lists ranges of addresses (single IPs, subnets or "low-high" ranges)
+	// VPP answers ARP requests (IPv4) or neighbor solicitations (IPv6, at most 256 addresses
+	// per range) for on the physical interfaces, e.g. the ranges of the external IPs of services,
+	// so that clients on the same L2 segment can reach them without routing configuration.
+	ProxyARPRanges []string
+
+	// ProxyARPPodNetwork enables answering of ARP requests for the pod network of this node,
+	// making the pods reachable from the L2 segment of the node.
+	ProxyARPPodNetwork bool
+
+	// DSRServices lists services (as namespace/name) in the Direct Server Return mode.
+	// The traffic destined to the cluster IP and the external IPs of these services is routed
+	// to backends without NAT, the backends need to accept it on the service IPs and ports
+	// (e.g. with the IPs assigned to the loopback) and their replies bypass the load-balancing node.
+	DSRServices []string
+
+	// TopologyAwareServices lists services (as namespace/name) load-balanced with the preference
+	// of backends close to the node: the node-local backends if there are any, otherwise the backends
+	// on nodes in the same topology zone (from the node label topology.kubernetes.io/zone),
+	// otherwise all backends. This reduces the cross-node traffic of chatty east-west services.
+	TopologyAwareServices []string
+
+	// NATSessions is the expected sizing of the NAT44 session tables of VPP, including
+	// the max. number of sessions per pod. VPP reads the sizing from its startup config,
+	// a mismatch is reported together with the startup config section to apply.
+	NATSessions configurator.NATSessionConfig
+
+	// NodeLocalDNS enables steering of the DNS queries of pods to a node-local DNS cache
+	// (e.g. NodeLocal DNSCache running in the host network of every node) listening
+	// on this IPv4 address in the host stack: the DNS traffic destined to the cluster IP
+	// of NodeLocalDNSService is translated to this address instead of being load-balanced
+	// across the DNS pods of the cluster.
+	NodeLocalDNS string
+
+	// NodeLocalDNSService is the DNS service (as namespace/name) steered to the node-local
+	// DNS cache, kube-system/kube-dns by default.
+	NodeLocalDNSService string
+}
+
+// defaultNodeLocalDNSService is the DNS service steered to the node-local DNS cache by default.
+const defaultNodeLocalDNSService = "kube-system/kube-dns"
+
+// serviceConfigFailedReason is the reason of the K8s Event reporting a failed programming of the services.
+const serviceConfigFailedReason = "ServiceConfigFailed"
+
 // Deps defines dependencies of the service plugin.
 type Deps struct {
 	local.PluginInfraDeps
@@ -81,6 +142,23 @@ func (p *Plugin) Init() error {
 	p.resyncChan = make(chan datasync.ResyncEvent)
 	p.changeChan = make(chan datasync.ChangeEvent)
 
+	p.config = &Config{}
+	if p.PluginConfig != nil {
+		_, err = p.PluginConfig.GetValue(p.config)
+		if err != nil {
+			return err
+		}
+	}
+
+	proxyARPRanges, err := configurator.ParseIPRanges(p.config.ProxyARPRanges)
+	if err != nil {
+		return fmt.Errorf("invalid proxy ARP ranges: %v", err)
+	}
+	nodeLocalDNS, nodeLocalDNSService, err := parseNodeLocalDNS(p.config)
+	if err != nil {
+		return err
+	}
+
 	const goVPPChanBufSize = 1 << 12
 	goVppCh, err := p.GoVPP.NewAPIChannelBuffered(goVPPChanBufSize, goVPPChanBufSize)
 	if err != nil {
@@ -94,16 +172,35 @@ func (p *Plugin) Init() error {
 			VPP:              p.VPP,
 			GoVPPChan:        goVppCh,
 			GoVPPChanBufSize: goVPPChanBufSize,
+			ProxyARP:         p.config.ProxyARP,
+			NATSessions:      p.config.NATSessions,
+
+			ProxyARPRanges:     proxyARPRanges,
+			ProxyARPPodNetwork: p.config.ProxyARPPodNetwork,
+			NodeLocalDNS:       nodeLocalDNS,
 		},
 	}
 	p.configurator.Log.SetLevel(logging.DebugLevel)
 
+	dsrServices, err := parseServiceIDs(p.config.DSRServices)
+	if err != nil {
+		return fmt.Errorf("invalid DSR services: %v", err)
+	}
+	topologyAwareServices, err := parseServiceIDs(p.config.TopologyAwareServices)
+	if err != nil {
+		return fmt.Errorf("invalid topology-aware services: %v", err)
+	}
+
 	p.processor = &processor.ServiceProcessor{
 		Deps: processor.Deps{
 			Log:          p.Log.NewLogger("-serviceProcessor"),
 			ServiceLabel: p.ServiceLabel,
 			Contiv:       p.Contiv,
 			Configurator: p.configurator,
+			DSRServices:  dsrServices,
+
+			TopologyAwareServices: topologyAwareServices,
+			NodeLocalDNSService:   nodeLocalDNSService,
 		},
 	}
 	p.processor.Log.SetLevel(logging.DebugLevel)
@@ -114,6 +211,9 @@ func (p *Plugin) Init() error {
 	p.ctx, p.cancel = context.WithCancel(context.Background())
 
 	go p.watchEvents()
+	if p.PluginConfig != nil {
+		go p.watchConfigReload()
+	}
 	err = p.subscribeWatcher()
 	if err != nil {
 		return err
@@ -133,10 +233,54 @@ func (p *Plugin) AfterInit() error {
 	return nil
 }
 
+// parseServiceIDs parses a list of services given as namespace/name into a set of service IDs.
+func parseServiceIDs(services []string) (map[svcmodel.ID]struct{}, error) {
+	ids := make(map[svcmodel.ID]struct{})
+	for _, service := range services {
+		id, err := parseServiceID(service)
+		if err != nil {
+			return nil, err
+		}
+		ids[id] = struct{}{}
+	}
+	return ids, nil
+}
+
+// parseServiceID parses a service given as namespace/name into a service ID.
+func parseServiceID(service string) (svcmodel.ID, error) {
+	nsName := strings.SplitN(service, "/", 2)
+	if len(nsName) != 2 {
+		return svcmodel.ID{}, fmt.Errorf("service %s is not in the format namespace/name", service)
+	}
+	return svcmodel.ID{Namespace: nsName[0], Name: nsName[1]}, nil
+}
+
+// parseNodeLocalDNS parses the address of the node-local DNS cache and the DNS service
+// steered to it. Returns nil address and empty service ID if the cache is not used.
+func parseNodeLocalDNS(config *Config) (net.IP, svcmodel.ID, error) {
+	if config.NodeLocalDNS == "" {
+		return nil, svcmodel.ID{}, nil
+	}
+	address := net.ParseIP(config.NodeLocalDNS)
+	if address == nil || address.To4() == nil {
+		return nil, svcmodel.ID{}, fmt.Errorf("invalid node-local DNS address %s (IPv4 expected)",
+			config.NodeLocalDNS)
+	}
+	service := config.NodeLocalDNSService
+	if service == "" {
+		service = defaultNodeLocalDNSService
+	}
+	id, err := parseServiceID(service)
+	if err != nil {
+		return nil, svcmodel.ID{}, fmt.Errorf("invalid node-local DNS service: %v", err)
+	}
+	return address.To4(), id, nil
+}
+
 func (p *Plugin) subscribeWatcher() (err error) {
 	p.watchConfigReg, err = p.Watcher.
 		Watch("K8s services", p.changeChan, p.resyncChan,
-			epmodel.KeyPrefix(), podmodel.KeyPrefix(), svcmodel.KeyPrefix())
+			epmodel.KeyPrefix(), podmodel.KeyPrefix(), svcmodel.KeyPrefix(), nodemodel.KeyPrefix())
 	return err
 }
 
@@ -162,6 +306,7 @@ func (p *Plugin) watchEvents() {
 				p.Log.WithField("config", dataChngEv).Info("Delaying data-change")
 			} else {
 				err := p.processor.Update(dataChngEv)
+				p.reportFailure(err)
 				dataChngEv.Done(err)
 			}
 			p.resyncLock.Unlock()
@@ -173,6 +318,77 @@ func (p *Plugin) watchEvents() {
 	}
 }
 
+// reportFailure reports the failed programming of the services as a K8s Event of the node.
+func (p *Plugin) reportFailure(err error) {
+	if err != nil {
+		p.Contiv.ReportK8sEvent("", "", serviceConfigFailedReason,
+			fmt.Sprintf("Programming of services failed, some services may not be reachable: %v", err))
+	}
+}
+
+// watchConfigReload reloads the config file on SIGHUP until the plugin is closed.
+func (p *Plugin) watchConfigReload() {
+	p.wg.Add(1)
+	defer p.wg.Done()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+
+	for {
+		select {
+		case <-sigChan:
+			if err := p.reloadConfig(); err != nil {
+				p.Log.Errorf("Reload of the configuration failed: %v", err)
+			}
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// reloadConfig re-reads the config file and re-configures the services with the new settings
+// (proxy ARP, DSR and topology-aware services, node-local DNS) without restart of the agent.
+func (p *Plugin) reloadConfig() error {
+	config := &Config{}
+	if _, err := p.PluginConfig.GetValue(config); err != nil {
+		return err
+	}
+	dsrServices, err := parseServiceIDs(config.DSRServices)
+	if err != nil {
+		return fmt.Errorf("invalid DSR services: %v", err)
+	}
+	topologyAwareServices, err := parseServiceIDs(config.TopologyAwareServices)
+	if err != nil {
+		return fmt.Errorf("invalid topology-aware services: %v", err)
+	}
+	proxyARPRanges, err := configurator.ParseIPRanges(config.ProxyARPRanges)
+	if err != nil {
+		return fmt.Errorf("invalid proxy ARP ranges: %v", err)
+	}
+	nodeLocalDNS, nodeLocalDNSService, err := parseNodeLocalDNS(config)
+	if err != nil {
+		return err
+	}
+	p.Log.WithField("config", config).Info("Reloading configuration")
+
+	p.resyncLock.Lock()
+	defer p.resyncLock.Unlock()
+	p.config = config
+	p.configurator.ProxyARP = config.ProxyARP
+	p.configurator.ProxyARPRanges = proxyARPRanges
+	p.configurator.ProxyARPPodNetwork = config.ProxyARPPodNetwork
+	p.processor.DSRServices = dsrServices
+	p.processor.TopologyAwareServices = topologyAwareServices
+	p.configurator.NodeLocalDNS = nodeLocalDNS
+	p.processor.NodeLocalDNSService = nodeLocalDNSService
+	if p.pendingResync != nil {
+		// the new settings are applied by the delayed resync
+		return nil
+	}
+	return p.processor.Reconfigure()
+}
+
 func (p *Plugin) handleResync(resyncChan chan resync.StatusEvent) {
 	// block until NodeIP is set
 	for {
@@ -206,6 +422,7 @@ func (p *Plugin) handleResync(resyncChan chan resync.StatusEvent) {
 			}
 			if err != nil {
 				p.Log.Error(err)
+				p.reportFailure(err)
 			}
 			ev.Ack()
 		case <-p.ctx.Done():
@@ -20,6 +20,7 @@ import (
 	"github.com/ligato/cn-infra/datasync"
 
 	epmodel "github.com/contiv/vpp/plugins/ksr/model/endpoints"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	svcmodel "github.com/contiv/vpp/plugins/ksr/model/service"
 )
@@ -91,5 +92,19 @@ func (sc *ServiceProcessor) propagateDataChangeEv(dataChngEv datasync.ChangeEven
 		return sc.processNewService(&value)
 	}
 
+	// Process Node CHANGE event
+	nodeName, err := nodemodel.ParseNodeFromKey(key)
+	if err == nil {
+		var value nodemodel.Node
+
+		if datasync.Delete == dataChngEv.GetChangeType() {
+			return sc.processDeletedNode(nodeName)
+		}
+		if err = dataChngEv.GetValue(&value); err != nil {
+			return err
+		}
+		return sc.processUpdatedNode(&value)
+	}
+
 	return nil
 }
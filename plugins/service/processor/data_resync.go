@@ -23,6 +23,7 @@ import (
 	"github.com/ligato/cn-infra/logging"
 
 	epmodel "github.com/contiv/vpp/plugins/ksr/model/endpoints"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	svcmodel "github.com/contiv/vpp/plugins/ksr/model/service"
 )
@@ -33,6 +34,7 @@ type ResyncEventData struct {
 	Pods      []*podmodel.Pod
 	Endpoints []*epmodel.Endpoints
 	Services  []*svcmodel.Service
+	Nodes     []*nodemodel.Node
 }
 
 // NewResyncEventData creates an empty instance of ResyncEventData.
@@ -41,6 +43,7 @@ func NewResyncEventData() *ResyncEventData {
 		Pods:      []*podmodel.Pod{},
 		Endpoints: []*epmodel.Endpoints{},
 		Services:  []*svcmodel.Service{},
+		Nodes:     []*nodemodel.Node{},
 	}
 }
 
@@ -67,16 +70,24 @@ func (red ResyncEventData) String() string {
 			services += ", "
 		}
 	}
-	return fmt.Sprintf("ResyncEventData <Pods:[%s] Endpoint:[%s] Services:[%s]>",
-		pods, endpoints, services)
+	nodes := ""
+	for idx, node := range red.Nodes {
+		nodes += node.String()
+		if idx < len(red.Nodes)-1 {
+			nodes += ", "
+		}
+	}
+	return fmt.Sprintf("ResyncEventData <Pods:[%s] Endpoint:[%s] Services:[%s] Nodes:[%s]>",
+		pods, endpoints, services, nodes)
 }
 
 func (sc *ServiceProcessor) parseResyncEv(resyncEv datasync.ResyncEvent) *ResyncEventData {
 	var (
-		numPod int
-		numEps int
-		numSvc int
-		err    error
+		numPod  int
+		numEps  int
+		numSvc  int
+		numNode int
+		err     error
 	)
 
 	event := NewResyncEventData()
@@ -127,6 +138,18 @@ func (sc *ServiceProcessor) parseResyncEv(resyncEv datasync.ResyncEvent) *Resync
 				}
 				continue
 			}
+
+			// Parse node RESYNC event
+			_, err = nodemodel.ParseNodeFromKey(key)
+			if err == nil {
+				value := &nodemodel.Node{}
+				err := evData.GetValue(value)
+				if err == nil {
+					event.Nodes = append(event.Nodes, value)
+					numNode++
+				}
+				continue
+			}
 		}
 	}
 
@@ -134,6 +157,7 @@ func (sc *ServiceProcessor) parseResyncEv(resyncEv datasync.ResyncEvent) *Resync
 		"num-pods":      numPod,
 		"num-endpoints": numEps,
 		"num-services":  numSvc,
+		"num-nodes":     numNode,
 	}).Debug("Parsed RESYNC event")
 
 	return event
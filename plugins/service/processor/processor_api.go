@@ -44,4 +44,9 @@ type ServiceProcessorAPI interface {
 	// The cache content is fully replaced and the configurator receives a full
 	// snapshot of Contiv Services at the present state to be (re)installed.
 	Resync(resyncEv datasync.ResyncEvent) error
+
+	// Reconfigure re-builds the Contiv Services from the cached state (e.g. after
+	// the configuration of the processor has changed) and the configurator
+	// receives a full snapshot of them to be (re)installed.
+	Reconfigure() error
 }
@@ -24,19 +24,29 @@ import (
 
 	"github.com/contiv/vpp/plugins/contiv"
 	epmodel "github.com/contiv/vpp/plugins/ksr/model/endpoints"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
 	podmodel "github.com/contiv/vpp/plugins/ksr/model/pod"
 	svcmodel "github.com/contiv/vpp/plugins/ksr/model/service"
 	"github.com/contiv/vpp/plugins/service/configurator"
 	"github.com/ligato/cn-infra/servicelabel"
 )
 
+const (
+	// topologyZoneLabel is the node label with the name of the topology zone of the node.
+	topologyZoneLabel = "topology.kubernetes.io/zone"
+	// betaTopologyZoneLabel is the deprecated variant of topologyZoneLabel.
+	betaTopologyZoneLabel = "failure-domain.beta.kubernetes.io/zone"
+)
+
 // ServiceProcessor implements ServiceProcessorAPI.
 type ServiceProcessor struct {
 	Deps
 
 	/* internal maps */
-	services map[svcmodel.ID]*Service
-	localEps map[podmodel.ID]*LocalEndpoint
+	services  map[svcmodel.ID]*Service
+	localEps  map[podmodel.ID]*LocalEndpoint
+	nodeZones map[string]string /* node name -> topology zone */
+	nodeIPs   map[string]string /* node IP address -> node name */
 
 	/* local frontend and backend interfaces */
 	frontendIfs configurator.Interfaces
@@ -49,6 +59,10 @@ type Deps struct {
 	ServiceLabel servicelabel.ReaderAPI
 	Contiv       contiv.API /* to get all interface names and pod IP network */
 	Configurator configurator.ServiceConfiguratorAPI
+	DSRServices  map[svcmodel.ID]struct{} /* services in the Direct Server Return mode */
+
+	TopologyAwareServices map[svcmodel.ID]struct{} /* services preferring close backends */
+	NodeLocalDNSService   svcmodel.ID              /* DNS service steered to the node-local DNS cache */
 }
 
 // LocalEndpoint represents a node-local endpoint.
@@ -67,6 +81,8 @@ func (sp *ServiceProcessor) Init() error {
 func (sp *ServiceProcessor) reset() error {
 	sp.services = make(map[svcmodel.ID]*Service)
 	sp.localEps = make(map[podmodel.ID]*LocalEndpoint)
+	sp.nodeZones = make(map[string]string)
+	sp.nodeIPs = make(map[string]string)
 	sp.frontendIfs = configurator.NewInterfaces()
 	sp.backendIfs = configurator.NewInterfaces()
 	return nil
@@ -90,6 +106,24 @@ func (sp *ServiceProcessor) Resync(resyncEv datasync.ResyncEvent) error {
 	return sp.processResyncEvent(resyncEvData)
 }
 
+// Reconfigure re-builds the Contiv Services from the cached state of K8s
+// pods, endpoints and services, e.g. after the sets of DSR and topology-aware
+// services have changed, and passes them to the configurator as a resync.
+func (sp *ServiceProcessor) Reconfigure() error {
+	sp.Log.Debug("ServiceProcessor - Reconfigure()")
+
+	confResyncEv := configurator.NewResyncEventData()
+	for _, svc := range sp.services {
+		svc.Refresh()
+		if contivSvc := svc.GetContivService(); contivSvc != nil {
+			confResyncEv.Services = append(confResyncEv.Services, contivSvc)
+		}
+	}
+	confResyncEv.FrontendIfs = sp.frontendIfs
+	confResyncEv.BackendIfs = sp.backendIfs
+	return sp.Configurator.Resync(confResyncEv)
+}
+
 func (sp *ServiceProcessor) processUpdatedPod(pod *podmodel.Pod) error {
 	sp.Log.WithFields(logging.Fields{
 		"pod": *pod,
@@ -235,6 +269,142 @@ func (sp *ServiceProcessor) processDeletedService(serviceID svcmodel.ID) error {
 	return sp.configureService(svc, oldContivSvc, oldBackends)
 }
 
+func (sp *ServiceProcessor) processUpdatedNode(node *nodemodel.Node) error {
+	sp.Log.WithFields(logging.Fields{
+		"node": *node,
+	}).Debug("ServiceProcessor - processUpdatedNode()")
+
+	// Host-network endpoints (e.g. API servers of the masters) are matched
+	// with the nodes by the node addresses.
+	ipsChanged := sp.setNodeIPs(node.Name, nodeIPAddresses(node))
+	zoneChanged := sp.setNodeZone(node.Name, nodeZone(node))
+	return sp.refreshNodeServices(ipsChanged, zoneChanged)
+}
+
+func (sp *ServiceProcessor) processDeletedNode(nodeName string) error {
+	sp.Log.WithFields(logging.Fields{
+		"nodeName": nodeName,
+	}).Debug("ServiceProcessor - processDeletedNode()")
+
+	ipsChanged := sp.setNodeIPs(nodeName, nil)
+	zoneChanged := sp.setNodeZone(nodeName, "")
+	return sp.refreshNodeServices(ipsChanged, zoneChanged)
+}
+
+// refreshNodeServices refreshes the services affected by the change of a node:
+// all services if the node IPs changed, the topology-aware ones if only the zone changed.
+func (sp *ServiceProcessor) refreshNodeServices(ipsChanged, zoneChanged bool) error {
+	if ipsChanged {
+		return sp.refreshServices(nil)
+	}
+	if zoneChanged {
+		return sp.refreshTopologyAwareServices()
+	}
+	return nil
+}
+
+// setNodeZone replaces the topology zone of the given node (empty if none), returns true if it changed.
+func (sp *ServiceProcessor) setNodeZone(nodeName string, zone string) bool {
+	if zone == sp.nodeZones[nodeName] {
+		return false
+	}
+	if zone != "" {
+		sp.nodeZones[nodeName] = zone
+	} else {
+		delete(sp.nodeZones, nodeName)
+	}
+	return true
+}
+
+// setNodeIPs replaces the IP addresses of the given node, returns true if they changed.
+func (sp *ServiceProcessor) setNodeIPs(nodeName string, ips []string) bool {
+	old := make(map[string]struct{})
+	for ip, name := range sp.nodeIPs {
+		if name == nodeName {
+			old[ip] = struct{}{}
+			delete(sp.nodeIPs, ip)
+		}
+	}
+	changed := false
+	for _, ip := range ips {
+		if _, had := old[ip]; !had {
+			changed = true
+		}
+		sp.nodeIPs[ip] = nodeName
+	}
+	for ip := range old {
+		if sp.nodeIPs[ip] != nodeName {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// endpointNode returns the name of the node an endpoint without the node name
+// is deployed on, these are typically host-network endpoints outside of
+// the control of the kubelet, e.g. the API servers of the masters. The node
+// is found by the endpoint IP, the endpoint is considered to be deployed on this
+// node if the IP does not belong to any known node.
+func (sp *ServiceProcessor) endpointNode(epIP net.IP) string {
+	if nodeName, found := sp.nodeIPs[epIP.String()]; found {
+		return nodeName
+	}
+	return sp.ServiceLabel.GetAgentLabel()
+}
+
+// refreshTopologyAwareServices re-configures topology-aware services after
+// a change in the topology zones of the nodes.
+func (sp *ServiceProcessor) refreshTopologyAwareServices() error {
+	return sp.refreshServices(sp.TopologyAwareServices)
+}
+
+// refreshServices re-computes and re-configures the given services, or all
+// services if nil.
+func (sp *ServiceProcessor) refreshServices(services map[svcmodel.ID]struct{}) error {
+	var wasErr error
+	for svcID, svc := range sp.services {
+		if _, selected := services[svcID]; services != nil && !selected {
+			continue
+		}
+		oldContivSvc := svc.GetContivService()
+		oldBackends := svc.GetLocalBackends()
+		svc.Refresh()
+		if err := sp.configureService(svc, oldContivSvc, oldBackends); err != nil {
+			wasErr = err
+		}
+	}
+	return wasErr
+}
+
+// nodeIPAddresses returns the IP addresses of the given node.
+func nodeIPAddresses(node *nodemodel.Node) []string {
+	var ips []string
+	for _, address := range node.Addresses {
+		switch address.Type {
+		case nodemodel.NodeAddress_NodeInternalIP, nodemodel.NodeAddress_NodeExternalIP:
+			if ip := net.ParseIP(address.Address); ip != nil {
+				ips = append(ips, ip.String())
+			}
+		}
+	}
+	return ips
+}
+
+// nodeZone returns the topology zone of the given node, read from the node labels.
+// Returns empty string if the node is not assigned to any zone.
+func nodeZone(node *nodemodel.Node) string {
+	var zone string
+	for _, label := range node.Label {
+		switch label.Key {
+		case topologyZoneLabel:
+			return label.Value
+		case betaTopologyZoneLabel:
+			zone = label.Value
+		}
+	}
+	return zone
+}
+
 // configureService makes all the calls to configurator necessary to get K8s state
 // data of a given service in-sync with VPP NAT configuration.
 func (sp *ServiceProcessor) configureService(svc *Service, oldContivSvc *configurator.ContivService, oldBackends []podmodel.ID) error {
@@ -362,6 +532,14 @@ func (sp *ServiceProcessor) processResyncEvent(resyncEv *ResyncEventData) error
 		sp.frontendIfs.Add(ifName)
 	}
 
+	// Learn topology zones and IP addresses of the nodes.
+	for _, node := range resyncEv.Nodes {
+		if zone := nodeZone(node); zone != "" {
+			sp.nodeZones[node.Name] = zone
+		}
+		sp.setNodeIPs(node.Name, nodeIPAddresses(node))
+	}
+
 	// Combine the service metadata with endpoints.
 	for _, eps := range resyncEv.Endpoints {
 		svcID := svcmodel.ID{Namespace: eps.Namespace, Name: eps.Name}
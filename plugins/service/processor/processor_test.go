@@ -17,11 +17,19 @@
 package processor
 
 import (
+	"fmt"
+	"net"
 	"testing"
 
 	"github.com/ligato/cn-infra/logging"
 	"github.com/ligato/cn-infra/logging/logrus"
+	"github.com/ligato/cn-infra/servicelabel"
 	"github.com/onsi/gomega"
+
+	epmodel "github.com/contiv/vpp/plugins/ksr/model/endpoints"
+	nodemodel "github.com/contiv/vpp/plugins/ksr/model/node"
+	svcmodel "github.com/contiv/vpp/plugins/ksr/model/service"
+	"github.com/contiv/vpp/plugins/service/configurator"
 )
 
 func TestSomething(t *testing.T) {
@@ -30,3 +38,391 @@ func TestSomething(t *testing.T) {
 	logger.SetLevel(logging.DebugLevel)
 	logger.Debug("TestSomething")
 }
+
+func TestServiceExternalIPs(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	newService := func(nodeName string) *Service {
+		sp := &ServiceProcessor{
+			Deps: Deps{
+				Log:          logrus.DefaultLogger(),
+				ServiceLabel: &servicelabel.Plugin{MicroserviceLabel: nodeName},
+			},
+		}
+		svc := NewService(sp)
+		svc.SetMetadata(&svcmodel.Service{
+			Name:                   "web",
+			Namespace:              "default",
+			ClusterIp:              "10.96.0.10",
+			ServiceType:            "LoadBalancer",
+			SessionAffinity:        "ClientIP",
+			SessionAffinityTimeout: 600,
+			ExternalIps:            []string{"192.168.100.10"},
+			LoadbalancerIngressIps: []string{"192.168.100.20"},
+			Port: []*svcmodel.Service_ServicePort{
+				{Name: "http", Protocol: "TCP", Port: 80, NodePort: 30080},
+			},
+		})
+		svc.SetEndpoints(&epmodel.Endpoints{
+			Name:      "web",
+			Namespace: "default",
+			EndpointSubsets: []*epmodel.EndpointSubset{
+				{
+					Addresses: []*epmodel.EndpointSubset_EndpointAddress{
+						{Ip: "10.1.2.3", NodeName: "node2"},
+						{Ip: "10.1.3.3", NodeName: "node3"},
+					},
+					Ports: []*epmodel.EndpointSubset_EndpointPort{
+						{Name: "http", Port: 8080, Protocol: "TCP"},
+					},
+				},
+			},
+		})
+		return svc
+	}
+
+	// both external IPs and LoadBalancer ingress IPs are exposed
+	contivSvc := newService("node2").GetContivService()
+	gomega.Expect(contivSvc).ToNot(gomega.BeNil())
+	gomega.Expect(contivSvc.ClusterIP.Equal(net.ParseIP("10.96.0.10"))).To(gomega.BeTrue())
+	gomega.Expect(contivSvc.ExternalIPs.List()).To(gomega.HaveLen(2))
+	gomega.Expect(contivSvc.ExternalIPs.Has(net.ParseIP("192.168.100.10"))).To(gomega.BeTrue())
+	gomega.Expect(contivSvc.ExternalIPs.Has(net.ParseIP("192.168.100.20"))).To(gomega.BeTrue())
+	gomega.Expect(contivSvc.Backends["http"]).To(gomega.HaveLen(2))
+	gomega.Expect(contivSvc.TrafficPolicy).To(gomega.Equal(configurator.ClusterWide))
+	gomega.Expect(contivSvc.SessionAffinityTimeout).To(gomega.BeEquivalentTo(600))
+
+	// external IPs are owned by the first node hosting a backend
+	gomega.Expect(contivSvc.OwnsExternalIPs).To(gomega.BeTrue())
+	gomega.Expect(newService("node3").GetContivService().OwnsExternalIPs).To(gomega.BeFalse())
+	gomega.Expect(newService("node1").GetContivService().OwnsExternalIPs).To(gomega.BeFalse())
+}
+
+func TestServiceLocalTrafficPolicy(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	sp := &ServiceProcessor{
+		Deps: Deps{
+			Log:          logrus.DefaultLogger(),
+			ServiceLabel: &servicelabel.Plugin{MicroserviceLabel: "node1"},
+		},
+	}
+	svc := NewService(sp)
+	svc.SetMetadata(&svcmodel.Service{
+		Name:                  "ingress",
+		Namespace:             "default",
+		ClusterIp:             "10.96.0.20",
+		ServiceType:           "NodePort",
+		ExternalTrafficPolicy: "Local",
+		Port: []*svcmodel.Service_ServicePort{
+			{Name: "http", Protocol: "TCP", Port: 80, NodePort: 30080},
+		},
+	})
+	svc.SetEndpoints(&epmodel.Endpoints{
+		Name:      "ingress",
+		Namespace: "default",
+		EndpointSubsets: []*epmodel.EndpointSubset{
+			{
+				Addresses: []*epmodel.EndpointSubset_EndpointAddress{
+					{
+						Ip:        "10.1.1.3",
+						NodeName:  "node1",
+						TargetRef: &epmodel.ObjectReference{Kind: "Pod", Namespace: "default", Name: "ingress-1"},
+					},
+					{
+						Ip:        "10.1.2.3",
+						NodeName:  "node2",
+						TargetRef: &epmodel.ObjectReference{Kind: "Pod", Namespace: "default", Name: "ingress-2"},
+					},
+				},
+				Ports: []*epmodel.EndpointSubset_EndpointPort{
+					{Name: "http", Port: 8080, Protocol: "TCP"},
+				},
+			},
+		},
+	})
+
+	// the configurator gets all backends, marked as local or remote, to load-balance
+	// the cluster IP across all of them and the node port across the local ones only
+	contivSvc := svc.GetContivService()
+	gomega.Expect(contivSvc.TrafficPolicy).To(gomega.Equal(configurator.NodeLocal))
+	gomega.Expect(contivSvc.Ports["http"].NodePort).To(gomega.BeEquivalentTo(30080))
+	gomega.Expect(contivSvc.Backends["http"]).To(gomega.HaveLen(2))
+	locals := 0
+	for _, backend := range contivSvc.Backends["http"] {
+		if backend.Local {
+			locals++
+			gomega.Expect(backend.IP.Equal(net.ParseIP("10.1.1.3"))).To(gomega.BeTrue())
+		}
+	}
+	gomega.Expect(locals).To(gomega.Equal(1))
+	gomega.Expect(svc.GetLocalBackends()).To(gomega.HaveLen(1))
+	gomega.Expect(svc.GetLocalBackends()[0].Name).To(gomega.Equal("ingress-1"))
+}
+
+func TestHeadlessService(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	sp := &ServiceProcessor{
+		Deps: Deps{
+			Log:          logrus.DefaultLogger(),
+			ServiceLabel: &servicelabel.Plugin{MicroserviceLabel: "node1"},
+		},
+	}
+	svc := NewService(sp)
+	svc.SetMetadata(&svcmodel.Service{
+		Name:        "db",
+		Namespace:   "default",
+		ClusterIp:   "None",
+		ServiceType: "ClusterIP",
+		ExternalIps: []string{"192.168.100.10"},
+		Port: []*svcmodel.Service_ServicePort{
+			{Name: "sql", Protocol: "TCP", Port: 5432},
+		},
+	})
+	svc.SetEndpoints(&epmodel.Endpoints{
+		Name:      "db",
+		Namespace: "default",
+		EndpointSubsets: []*epmodel.EndpointSubset{
+			{
+				Addresses: []*epmodel.EndpointSubset_EndpointAddress{
+					{
+						Ip:        "10.1.1.3",
+						NodeName:  "node1",
+						TargetRef: &epmodel.ObjectReference{Kind: "Pod", Namespace: "default", Name: "db-0"},
+					},
+					{
+						Ip:        "10.1.2.3",
+						NodeName:  "node2",
+						TargetRef: &epmodel.ObjectReference{Kind: "Pod", Namespace: "default", Name: "db-1"},
+					},
+				},
+				Ports: []*epmodel.EndpointSubset_EndpointPort{
+					{Name: "sql", Port: 5432, Protocol: "TCP"},
+				},
+			},
+		},
+	})
+
+	// headless services are neither exposed nor NATed on the backend side
+	contivSvc := svc.GetContivService()
+	gomega.Expect(contivSvc.Headless).To(gomega.BeTrue())
+	gomega.Expect(contivSvc.ClusterIP).To(gomega.BeNil())
+	gomega.Expect(contivSvc.ExternalIPs.List()).To(gomega.BeEmpty())
+	gomega.Expect(contivSvc.Backends["sql"]).To(gomega.HaveLen(2))
+	gomega.Expect(svc.GetLocalBackends()).To(gomega.BeEmpty())
+}
+
+func TestTopologyAwareService(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	svcID := svcmodel.ID{Namespace: "default", Name: "backend"}
+	sp := &ServiceProcessor{
+		Deps: Deps{
+			Log:          logrus.DefaultLogger(),
+			ServiceLabel: &servicelabel.Plugin{MicroserviceLabel: "node1"},
+
+			TopologyAwareServices: map[svcmodel.ID]struct{}{svcID: {}},
+		},
+	}
+	sp.reset()
+	for _, node := range []*nodemodel.Node{
+		{Name: "node1", Label: []*nodemodel.Node_Label{{Key: "topology.kubernetes.io/zone", Value: "zone-a"}}},
+		{Name: "node2", Label: []*nodemodel.Node_Label{{Key: "failure-domain.beta.kubernetes.io/zone", Value: "zone-a"}}},
+		{Name: "node3", Label: []*nodemodel.Node_Label{{Key: "topology.kubernetes.io/zone", Value: "zone-b"}}},
+	} {
+		sp.nodeZones[node.Name] = nodeZone(node)
+	}
+
+	svc := NewService(sp)
+	svc.SetMetadata(&svcmodel.Service{
+		Name:        "backend",
+		Namespace:   "default",
+		ClusterIp:   "10.96.0.30",
+		ServiceType: "ClusterIP",
+		Port: []*svcmodel.Service_ServicePort{
+			{Name: "grpc", Protocol: "TCP", Port: 9000},
+		},
+	})
+	svc.SetEndpoints(&epmodel.Endpoints{
+		Name:      "backend",
+		Namespace: "default",
+		EndpointSubsets: []*epmodel.EndpointSubset{
+			{
+				Addresses: []*epmodel.EndpointSubset_EndpointAddress{
+					{Ip: "10.1.2.3", NodeName: "node2"},
+					{Ip: "10.1.3.3", NodeName: "node3"},
+				},
+				Ports: []*epmodel.EndpointSubset_EndpointPort{
+					{Name: "grpc", Port: 9000, Protocol: "TCP"},
+				},
+			},
+		},
+	})
+
+	// backends on nodes in the zone of this node are marked
+	contivSvc := svc.GetContivService()
+	gomega.Expect(contivSvc.TopologyAware).To(gomega.BeTrue())
+	gomega.Expect(contivSvc.Backends["grpc"]).To(gomega.HaveLen(2))
+	for _, backend := range contivSvc.Backends["grpc"] {
+		gomega.Expect(backend.Local).To(gomega.BeFalse())
+		gomega.Expect(backend.SameZone).To(gomega.Equal(backend.IP.Equal(net.ParseIP("10.1.2.3"))))
+	}
+
+	// without the zone of this node no backend is in the same zone
+	delete(sp.nodeZones, "node1")
+	svc.Refresh()
+	for _, backend := range svc.GetContivService().Backends["grpc"] {
+		gomega.Expect(backend.SameZone).To(gomega.BeFalse())
+	}
+}
+
+func TestNodeZoneAndAddresses(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	sp := &ServiceProcessor{
+		Deps: Deps{
+			Log:          logrus.DefaultLogger(),
+			ServiceLabel: &servicelabel.Plugin{MicroserviceLabel: "node1"},
+		},
+	}
+	sp.reset()
+
+	// node joining after the resync gets both its addresses and its zone recorded
+	node := &nodemodel.Node{
+		Name:  "node2",
+		Label: []*nodemodel.Node_Label{{Key: "topology.kubernetes.io/zone", Value: "zone-a"}},
+		Addresses: []*nodemodel.NodeAddress{
+			{Type: nodemodel.NodeAddress_NodeInternalIP, Address: "192.168.16.2"},
+		},
+	}
+	gomega.Expect(sp.processUpdatedNode(node)).To(gomega.Succeed())
+	gomega.Expect(sp.nodeIPs).To(gomega.HaveKeyWithValue("192.168.16.2", "node2"))
+	gomega.Expect(sp.nodeZones).To(gomega.HaveKeyWithValue("node2", "zone-a"))
+
+	// deleted node is removed from both
+	gomega.Expect(sp.processDeletedNode("node2")).To(gomega.Succeed())
+	gomega.Expect(sp.nodeIPs).To(gomega.BeEmpty())
+	gomega.Expect(sp.nodeZones).To(gomega.BeEmpty())
+}
+
+func TestHostNetworkEndpoints(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	sp := &ServiceProcessor{
+		Deps: Deps{
+			Log:          logrus.DefaultLogger(),
+			ServiceLabel: &servicelabel.Plugin{MicroserviceLabel: "master2"},
+		},
+	}
+	sp.reset()
+	for i, name := range []string{"master1", "master2", "master3"} {
+		sp.setNodeIPs(name, nodeIPAddresses(&nodemodel.Node{
+			Name: name,
+			Addresses: []*nodemodel.NodeAddress{
+				{Type: nodemodel.NodeAddress_NodeHostName, Address: name},
+				{Type: nodemodel.NodeAddress_NodeInternalIP, Address: fmt.Sprintf("192.168.16.%d", i+1)},
+			},
+		}))
+	}
+
+	// the API servers of the masters are host-network endpoints without the node name
+	svc := NewService(sp)
+	svc.SetMetadata(&svcmodel.Service{
+		Name:        "kubernetes",
+		Namespace:   "default",
+		ClusterIp:   "10.96.0.1",
+		ServiceType: "ClusterIP",
+		Port: []*svcmodel.Service_ServicePort{
+			{Name: "https", Protocol: "TCP", Port: 443},
+		},
+	})
+	svc.SetEndpoints(&epmodel.Endpoints{
+		Name:      "kubernetes",
+		Namespace: "default",
+		EndpointSubsets: []*epmodel.EndpointSubset{
+			{
+				Addresses: []*epmodel.EndpointSubset_EndpointAddress{
+					{Ip: "192.168.16.1"},
+					{Ip: "192.168.16.2"},
+					{Ip: "192.168.16.3"},
+				},
+				Ports: []*epmodel.EndpointSubset_EndpointPort{
+					{Name: "https", Port: 6443, Protocol: "TCP"},
+				},
+			},
+		},
+	})
+
+	// only the API server of this master is local, the external IPs are owned by the first master
+	contivSvc := svc.GetContivService()
+	gomega.Expect(contivSvc.Backends["https"]).To(gomega.HaveLen(3))
+	for _, backend := range contivSvc.Backends["https"] {
+		gomega.Expect(backend.Local).To(gomega.Equal(backend.IP.Equal(net.ParseIP("192.168.16.2"))))
+	}
+	gomega.Expect(contivSvc.OwnsExternalIPs).To(gomega.BeFalse())
+
+	// endpoints of unknown nodes are considered to be local
+	gomega.Expect(sp.setNodeIPs("master3", nil)).To(gomega.BeTrue())
+	gomega.Expect(sp.setNodeIPs("master1", []string{"192.168.16.1"})).To(gomega.BeFalse())
+	svc.Refresh()
+	for _, backend := range svc.GetContivService().Backends["https"] {
+		gomega.Expect(backend.Local).To(gomega.Equal(!backend.IP.Equal(net.ParseIP("192.168.16.1"))))
+	}
+}
+
+// resyncRecorder is a configurator recording the resync events.
+type resyncRecorder struct {
+	configurator.ServiceConfiguratorAPI
+	resyncEvs []*configurator.ResyncEventData
+}
+
+func (r *resyncRecorder) Resync(resyncEv *configurator.ResyncEventData) error {
+	r.resyncEvs = append(r.resyncEvs, resyncEv)
+	return nil
+}
+
+func TestReconfigure(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	svcID := svcmodel.ID{Namespace: "default", Name: "web"}
+	recorder := &resyncRecorder{}
+	sp := &ServiceProcessor{
+		Deps: Deps{
+			Log:          logrus.DefaultLogger(),
+			ServiceLabel: &servicelabel.Plugin{MicroserviceLabel: "node1"},
+			Configurator: recorder,
+		},
+	}
+	sp.reset()
+	svc := sp.getService(svcID)
+	svc.SetMetadata(&svcmodel.Service{
+		Name:        "web",
+		Namespace:   "default",
+		ClusterIp:   "10.96.0.10",
+		ServiceType: "ClusterIP",
+		Port: []*svcmodel.Service_ServicePort{
+			{Name: "http", Protocol: "TCP", Port: 80},
+		},
+	})
+	svc.SetEndpoints(&epmodel.Endpoints{
+		Name:      "web",
+		Namespace: "default",
+		EndpointSubsets: []*epmodel.EndpointSubset{
+			{
+				Addresses: []*epmodel.EndpointSubset_EndpointAddress{{Ip: "10.1.2.3", NodeName: "node2"}},
+				Ports:     []*epmodel.EndpointSubset_EndpointPort{{Name: "http", Port: 8080, Protocol: "TCP"}},
+			},
+		},
+	})
+	sp.getService(svcmodel.ID{Namespace: "default", Name: "no-endpoints"})
+	gomega.Expect(svc.GetContivService().DSR).To(gomega.BeFalse())
+
+	// the services are re-built with the new set of DSR services and resynced
+	sp.DSRServices = map[svcmodel.ID]struct{}{svcID: {}}
+	gomega.Expect(sp.Reconfigure()).To(gomega.Succeed())
+	gomega.Expect(recorder.resyncEvs).To(gomega.HaveLen(1))
+	gomega.Expect(recorder.resyncEvs[0].Services).To(gomega.HaveLen(1))
+	gomega.Expect(recorder.resyncEvs[0].Services[0].ID).To(gomega.Equal(svcID))
+	gomega.Expect(recorder.resyncEvs[0].Services[0].DSR).To(gomega.BeTrue())
+}
@@ -94,21 +94,34 @@ func (s *Service) Refresh() {
 	} else {
 		s.contivSvc.TrafficPolicy = configurator.ClusterWide
 	}
+	_, s.contivSvc.DSR = s.sp.DSRServices[s.contivSvc.ID]
+	_, s.contivSvc.TopologyAware = s.sp.TopologyAwareServices[s.contivSvc.ID]
+	s.contivSvc.NodeLocalDNS = s.contivSvc.ID == s.sp.NodeLocalDNSService
+	if s.meta.SessionAffinity == "ClientIP" {
+		s.contivSvc.SessionAffinityTimeout = uint32(s.meta.SessionAffinityTimeout)
+	}
 
 	// Collect all IP addresses on which the service should be exposed.
-	if s.meta.ClusterIp != "" && s.meta.ClusterIp != "None" {
+	s.contivSvc.Headless = s.meta.ClusterIp == "None"
+	if s.meta.ClusterIp != "" && !s.contivSvc.Headless {
 		clusterIP := net.ParseIP(s.meta.ClusterIp)
 		if clusterIP != nil {
-			s.contivSvc.ExternalIPs.Add(clusterIP)
+			s.contivSvc.ClusterIP = clusterIP
 		} else {
 			s.sp.Log.WithFields(logging.Fields{
 				"service":   s.contivSvc.ID,
-				"clusterIP": clusterIP,
+				"clusterIP": s.meta.ClusterIp,
 			}).Warn("Failed to parse clusterIP")
 		}
 	}
 
-	for _, externalIPStr := range s.meta.ExternalIps {
+	externalIPs := append([]string{}, s.meta.ExternalIps...)
+	externalIPs = append(externalIPs, s.meta.LoadbalancerIngressIps...)
+	if s.contivSvc.Headless {
+		// Headless services are not exposed, the same as with kube-proxy.
+		externalIPs = nil
+	}
+	for _, externalIPStr := range externalIPs {
 		externalIP := net.ParseIP(externalIPStr)
 		if externalIP != nil {
 			s.contivSvc.ExternalIPs.Add(externalIP)
@@ -126,9 +139,12 @@ func (s *Service) Refresh() {
 			Port:     uint16(port.GetPort()),
 			NodePort: uint16(port.GetNodePort()),
 		}
-		if port.GetProtocol() == "TCP" {
+		switch port.GetProtocol() {
+		case "TCP":
 			sp.Protocol = configurator.TCP
-		} else {
+		case "SCTP":
+			sp.Protocol = configurator.SCTP
+		default:
 			sp.Protocol = configurator.UDP
 		}
 		s.contivSvc.Ports[port.Name] = sp
@@ -138,11 +154,12 @@ func (s *Service) Refresh() {
 	for port := range s.contivSvc.Ports {
 		s.contivSvc.Backends[port] = []*configurator.ServiceBackend{}
 	}
+	ownerNode := ""
+	localZone := s.sp.nodeZones[s.sp.ServiceLabel.GetAgentLabel()]
 	for _, epSubSet := range s.endpoints.GetEndpointSubsets() {
 		epPorts := epSubSet.GetPorts()
 		epAddrs := epSubSet.GetAddresses()
 		for _, epAddr := range epAddrs {
-			var local bool
 			epIP := net.ParseIP(epAddr.GetIp())
 			if epIP == nil {
 				s.sp.Log.WithFields(logging.Fields{
@@ -151,9 +168,14 @@ func (s *Service) Refresh() {
 				}).Warn("Failed to parse endpoint IP")
 				continue
 			}
-			if epAddr.GetNodeName() == "" || epAddr.GetNodeName() == s.sp.ServiceLabel.GetAgentLabel() {
-				local = true
+			nodeName := epAddr.GetNodeName()
+			if nodeName == "" {
+				nodeName = s.sp.endpointNode(epIP)
 			}
+			if ownerNode == "" || nodeName < ownerNode {
+				ownerNode = nodeName
+			}
+			local := nodeName == s.sp.ServiceLabel.GetAgentLabel()
 			for _, epPort := range epPorts {
 				port := epPort.GetName()
 				if _, exposedPort := s.contivSvc.Ports[port]; exposedPort {
@@ -161,11 +183,13 @@ func (s *Service) Refresh() {
 					sb.IP = epIP
 					sb.Port = uint16(epPort.GetPort())
 					sb.Local = local
+					sb.SameZone = localZone != "" && s.sp.nodeZones[nodeName] == localZone
 					s.contivSvc.Backends[port] = append(s.contivSvc.Backends[port], sb)
 				}
 			}
-			if local {
+			if local && !s.contivSvc.Headless {
 				// Get interface name and add it to the set of local backends.
+				// Backends of headless services are accessed without NAT.
 				targetPod := epAddr.GetTargetRef()
 				if targetPod.GetKind() == "Pod" {
 					s.localBackends = append(s.localBackends,
@@ -175,5 +199,9 @@ func (s *Service) Refresh() {
 		}
 	}
 
+	// External IPs are owned by the first node (in the alphabetical order) hosting
+	// a backend of the service. Every node makes the same choice.
+	s.contivSvc.OwnsExternalIPs = ownerNode != "" && ownerNode == s.sp.ServiceLabel.GetAgentLabel()
+
 	s.refreshed = true
 }
@@ -1,3 +1,5 @@
 // Package statscollector implements plugin that collects the statistics
-// from vpp interfaces and publishes them to prometheus.
+// from vpp interfaces and publishes them to prometheus. Interfaces are labeled
+// with the namespace and the name of the pod they belong to. The error counters
+// of VPP graph nodes ("show errors") are periodically read and published as well.
 package statscollector
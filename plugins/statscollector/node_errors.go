@@ -0,0 +1,130 @@
+package statscollector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/bin_api/vpe"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// showErrorsCmd is the VPP CLI command printing the error counters of the graph nodes
+	showErrorsCmd = "show errors"
+
+	// default period of reading of the error counters
+	defaultNodeErrorsInterval = 10 * time.Second
+
+	vppNodeLabel = "vppNode"
+	reasonLabel  = "reason"
+
+	nodeErrorsMetric = "nodeErrors"
+)
+
+// nodeErrorKey identifies an error counter of a VPP graph node.
+type nodeErrorKey struct {
+	node   string
+	reason string
+}
+
+// initNodeErrors creates and registers the vector of the error counters of VPP graph nodes.
+func (p *Plugin) initNodeErrors() error {
+	p.nodeErrors = map[nodeErrorKey]struct{}{}
+	p.nodeErrorsVec = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: nodeErrorsMetric,
+		Help: "Number of packets counted by the error counter of VPP graph node",
+		ConstLabels: prometheus.Labels{
+			nodeLabel: p.ServiceLabel.GetAgentLabel(),
+		},
+	}, []string{vppNodeLabel, reasonLabel})
+	return p.Prometheus.Register(prometheusStatsPath, p.nodeErrorsVec)
+}
+
+// startNodeErrorsCollection opens the channel to VPP and starts reading of the error counters.
+// GoVPP is initialized after this plugin, therefore the channel cannot be opened in Init.
+func (p *Plugin) startNodeErrorsCollection() error {
+	var err error
+	p.vppChan, err = p.GoVPP.NewAPIChannel()
+	if err != nil {
+		return err
+	}
+	go p.collectNodeErrors(defaultNodeErrorsInterval)
+	return nil
+}
+
+// collectNodeErrors periodically reads the error counters of VPP graph nodes and publishes them into prometheus.
+func (p *Plugin) collectNodeErrors(interval time.Duration) {
+	for {
+		select {
+		case <-p.closeCh:
+			return
+		case <-time.After(interval):
+			counters, err := p.readNodeErrors()
+			if err != nil {
+				p.Log.Warnf("Failed to read error counters of VPP nodes: %v", err)
+				continue
+			}
+			p.updateNodeErrors(counters)
+		}
+	}
+}
+
+// readNodeErrors reads the error counters of VPP graph nodes via VPP CLI.
+func (p *Plugin) readNodeErrors() (map[nodeErrorKey]uint64, error) {
+	req := &vpe.CliInband{
+		Cmd:    []byte(showErrorsCmd),
+		Length: uint32(len(showErrorsCmd)),
+	}
+	reply := &vpe.CliInbandReply{}
+	if err := p.vppChan.SendRequest(req).ReceiveReply(reply); err != nil {
+		return nil, err
+	}
+	if reply.Retval != 0 {
+		return nil, fmt.Errorf("%s returned %d", reply.GetMessageName(), reply.Retval)
+	}
+	return parseNodeErrors(reply.Reply), nil
+}
+
+// updateNodeErrors publishes the error counters, gauges of the counters no longer printed by VPP (e.g. after
+// "clear errors") are removed.
+func (p *Plugin) updateNodeErrors(counters map[nodeErrorKey]uint64) {
+	p.Lock()
+	defer p.Unlock()
+
+	for key, count := range counters {
+		p.nodeErrorsVec.WithLabelValues(key.node, key.reason).Set(float64(count))
+		p.nodeErrors[key] = struct{}{}
+	}
+	for key := range p.nodeErrors {
+		if _, found := counters[key]; !found {
+			p.nodeErrorsVec.DeleteLabelValues(key.node, key.reason)
+			delete(p.nodeErrors, key)
+		}
+	}
+}
+
+// parseNodeErrors parses the output of "show errors". Each counter is printed on a separate line
+// as "<count> <node> <reason>". With worker threads, the counters are printed per thread and the counters
+// of the same node and reason are summed up.
+func parseNodeErrors(output []byte) map[nodeErrorKey]uint64 {
+	counters := map[nodeErrorKey]uint64{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		count, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			// header lines
+			continue
+		}
+		key := nodeErrorKey{node: fields[1], reason: strings.Join(fields[2:], " ")}
+		counters[key] += count
+	}
+	return counters
+}
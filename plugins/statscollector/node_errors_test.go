@@ -0,0 +1,26 @@
+package statscollector
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+const showErrorsOutput = `Thread 0 (vpp_main):
+   Count                    Node                  Reason
+         7                ip4-glean               ARP requests sent
+         2             ip4-icmp-input             unknown type
+Thread 1 (vpp_wk_0):
+   Count                    Node                  Reason
+         3                ip4-glean               ARP requests sent
+`
+
+func TestParseNodeErrors(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	counters := parseNodeErrors([]byte(showErrorsOutput))
+	gomega.Expect(counters).To(gomega.Equal(map[nodeErrorKey]uint64{
+		{node: "ip4-glean", reason: "ARP requests sent"}: 10,
+		{node: "ip4-icmp-input", reason: "unknown type"}: 2,
+	}))
+}
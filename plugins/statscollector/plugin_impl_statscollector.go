@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"git.fd.io/govpp.git/api"
 	"github.com/contiv/vpp/plugins/contiv"
 	"github.com/contiv/vpp/plugins/contiv/containeridx"
 	"github.com/golang/protobuf/proto"
@@ -12,6 +13,7 @@ import (
 	"github.com/ligato/cn-infra/flavors/local"
 	prometheusplugin "github.com/ligato/cn-infra/rpc/prometheus"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/ligato/vpp-agent/plugins/govppmux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -47,6 +49,10 @@ type Plugin struct {
 	closeCh   chan interface{}
 	gaugeVecs map[string]*prometheus.GaugeVec
 	podIfs    map[string] /*pod namespace*/ map[string] /*pod name*/ []string /*stats keys*/
+
+	vppChan       *api.Channel
+	nodeErrorsVec *prometheus.GaugeVec
+	nodeErrors    map[nodeErrorKey]struct{} // error counters currently published
 }
 
 type stats struct {
@@ -65,6 +71,9 @@ type Deps struct {
 
 	// Prometheus plugin used to stream statistics
 	Prometheus prometheusplugin.API
+
+	// GoVPP is optional, used to read the error counters of VPP graph nodes
+	GoVPP govppmux.API
 }
 
 // Init initializes the plugin resources
@@ -119,6 +128,11 @@ func (p *Plugin) Init() error {
 			}
 		}
 
+		if p.GoVPP != nil {
+			if err = p.initNodeErrors(); err != nil {
+				return err
+			}
+		}
 	}
 
 	go p.PrintStats()
@@ -126,8 +140,15 @@ func (p *Plugin) Init() error {
 	return nil
 }
 
-// AfterInit subscribes for monitoring of changes in ContainerIndex
+// AfterInit subscribes for monitoring of changes in ContainerIndex and starts reading
+// of the error counters of VPP graph nodes.
 func (p *Plugin) AfterInit() error {
+	if p.nodeErrorsVec != nil {
+		if err := p.startNodeErrorsCollection(); err != nil {
+			return err
+		}
+	}
+
 	// watch containerIDX and remove gauges of pods that have been deleted
 	return p.Contiv.GetContainerIndex().Watch(p.PluginName, func(event containeridx.ChangeEvent) {
 		p.Lock()
@@ -172,6 +193,9 @@ func (p *Plugin) AfterInit() error {
 // Close cleans up the plugin resources
 func (p *Plugin) Close() error {
 	close(p.closeCh)
+	if p.vppChan != nil {
+		p.vppChan.Close()
+	}
 	return nil
 }
 